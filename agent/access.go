@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+// accessPolicy is the role policy of cfg. The deprecated trace and
+// takeover owners count as admins.
+func accessPolicy(cfg *config.Config) channels.AccessPolicy {
+	a := cfg.Channels.Access
+	return channels.AccessPolicy{
+		Admins:        cfg.AdminSenders(),
+		Readonly:      a.Readonly,
+		AdminTools:    a.AdminToolsValue(),
+		ReadonlyTools: a.ReadonlyToolsValue(),
+	}
+}
+
+// deniedTools returns the tools the sender of a turn may not use under
+// cfg. Cron turns run as their "cron:ID" sender, so they get the user role
+// unless an entry names them.
+func deniedTools(cfg *config.Config, channel, senderID string) map[string]bool {
+	p := accessPolicy(cfg)
+	return p.Denied(p.Role(channel, senderID), tools.ToolNames)
+}
+
+// isAdmin reports whether an admin entry names the sender, for the chat
+// commands only admins may use.
+func isAdmin(cfg *config.Config, channel, senderID string) bool {
+	return accessPolicy(cfg).IsAdmin(channel, senderID)
+}
@@ -11,21 +11,33 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mosaxiv/clawlet/chaos"
 	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/contacts"
+	"github.com/mosaxiv/clawlet/fswatch"
+	"github.com/mosaxiv/clawlet/llamaserver"
 	"github.com/mosaxiv/clawlet/llm"
 	"github.com/mosaxiv/clawlet/memory"
 	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/postprocess"
 	"github.com/mosaxiv/clawlet/session"
 	"github.com/mosaxiv/clawlet/skills"
+	"github.com/mosaxiv/clawlet/storage"
 	"github.com/mosaxiv/clawlet/tools"
+	"github.com/mosaxiv/clawlet/turns"
 )
 
 type Options struct {
 	Config       *config.Config
 	WorkspaceDir string
 	SessionKey   string
-	MaxIters     int
-	Verbose      bool
+	// Sessions is where the session is stored (default: files in
+	// ~/.clawlet/sessions).
+	Sessions storage.Store
+	MaxIters int
+	// Chaos, when set, fails some LLM calls on purpose; see package chaos.
+	Chaos   *chaos.Injector
+	Verbose bool
 }
 
 type Agent struct {
@@ -37,9 +49,16 @@ type Agent struct {
 
 	llm   *llm.Client
 	tools *tools.Registry
+	post  *postprocess.Pipeline
 
-	sessionDir string
-	sess       *session.Session
+	sessions storage.Store
+	sess     *session.Session
+
+	watch     *fswatch.Watcher
+	stopWatch context.CancelFunc
+
+	llamaServer *llamaserver.Server
+	memSearch   *memory.IndexManager
 
 	consolidationMu      sync.Mutex
 	consolidationRunning bool
@@ -62,9 +81,12 @@ func New(opts Options) (*Agent, error) {
 	if err := paths.EnsureStateDirs(); err != nil {
 		return nil, err
 	}
-	sdir := paths.SessionsDir()
+	sstore := opts.Sessions
+	if sstore == nil {
+		sstore = session.FileStore(paths.SessionsDir())
+	}
 
-	sess, err := session.Load(sdir, opts.SessionKey)
+	sess, err := session.LoadFrom(sstore, opts.SessionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -72,21 +94,48 @@ func New(opts Options) (*Agent, error) {
 		sess = session.New(opts.SessionKey)
 	}
 
+	braveKey, nativeSearch, err := webSearchSetup(opts.Config)
+	if err != nil {
+		return nil, err
+	}
 	c := &llm.Client{
-		Provider:    opts.Config.LLM.Provider,
-		BaseURL:     opts.Config.LLM.BaseURL,
-		APIKey:      opts.Config.LLM.APIKey,
-		Model:       opts.Config.LLM.Model,
-		MaxTokens:   opts.Config.Agents.Defaults.MaxTokensValue(),
-		Temperature: opts.Config.Agents.Defaults.Temperature,
-		Headers:     opts.Config.LLM.Headers,
+		Provider:     opts.Config.LLM.Provider,
+		BaseURL:      opts.Config.LLM.BaseURL,
+		APIKey:       opts.Config.LLM.APIKey,
+		Model:        opts.Config.LLM.Model,
+		MaxTokens:    opts.Config.Agents.Defaults.MaxTokensValue(),
+		Temperature:  opts.Config.Agents.Defaults.Temperature,
+		Headers:      opts.Config.LLM.Headers,
+		WebSearch:    nativeSearch,
+		KeepAlive:    opts.Config.LLM.Ollama.KeepAlive,
+		AutoPull:     opts.Config.LLM.Ollama.AutoPull,
+		PullProgress: logPullProgress(),
+	}
+	useChaos(c, opts.Chaos)
+	llamaSrv, err := useLlamaServer(opts.Config.LLM.LlamaServer, c)
+	if err != nil {
+		return nil, err
 	}
 
+	if err := tools.ValidateToolNames(opts.Config.Tools.Aliases, opts.Config.Tools.Rename); err != nil {
+		return nil, err
+	}
+	if err := validateLanguagePolicy(opts.Config.Agents.Defaults.Language); err != nil {
+		return nil, err
+	}
+	post, err := postprocess.New(opts.Config.Agents.Defaults.PostProcess)
+	if err != nil {
+		return nil, err
+	}
 	treg := &tools.Registry{
 		WorkspaceDir:           wsAbs,
+		Aliases:                opts.Config.Tools.Aliases,
+		Renames:                opts.Config.Tools.Rename,
 		RestrictToWorkspace:    opts.Config.Tools.RestrictToWorkspaceValue(),
+		IsolateSkills:          opts.Config.Tools.Skills.Isolate,
 		ExecTimeout:            time.Duration(opts.Config.Tools.Exec.TimeoutSec) * time.Second,
-		BraveAPIKey:            opts.Config.Tools.Web.BraveAPIKey,
+		DiffMaxLines:           opts.Config.Tools.Diffs.MaxLinesValue(),
+		BraveAPIKey:            braveKey,
 		WebFetchAllowedDomains: append([]string(nil), opts.Config.Tools.Web.AllowedDomains...),
 		WebFetchBlockedDomains: append([]string(nil), opts.Config.Tools.Web.BlockedDomains...),
 		WebFetchMaxResponse:    opts.Config.Tools.Web.MaxResponseBytes,
@@ -97,12 +146,33 @@ func New(opts Options) (*Agent, error) {
 			return l.Load(name)
 		},
 	}
-	treg.SkillRegistry, treg.SkillSearchDefaultLimit = buildSkillRegistry(opts.Config)
+	treg.SkillRegistry, treg.SkillSearchDefaultLimit, err = buildSkillRegistry(opts.Config)
+	if err != nil {
+		return nil, err
+	}
 	memMgr, err := memory.NewIndexManager(opts.Config, wsAbs)
 	if err != nil {
 		return nil, err
 	}
 	treg.MemorySearch = memMgr
+	treg.Contacts = contacts.NewStore(contacts.Path(wsAbs))
+	treg.Journal, err = newJournal(opts.Config.Agents.Defaults.Journal, wsAbs)
+	if err != nil {
+		return nil, err
+	}
+	treg.Conversation = func(string) []session.Message {
+		return sess.History(0)
+	}
+	watch, err := newWorkspaceWatcher(opts.Config.Agents.Defaults.WorkspaceWatch, wsAbs)
+	if err != nil {
+		return nil, err
+	}
+	stopWatch := func() {}
+	if watch != nil {
+		var watchCtx context.Context
+		watchCtx, stopWatch = context.WithCancel(context.Background())
+		go watch.Run(watchCtx)
+	}
 
 	return &Agent{
 		cfg:          opts.Config,
@@ -112,15 +182,36 @@ func New(opts Options) (*Agent, error) {
 		verbose:      opts.Verbose,
 		llm:          c,
 		tools:        treg,
-		sessionDir:   sdir,
+		post:         post,
+		sessions:     sstore,
 		sess:         sess,
+		watch:        watch,
+		stopWatch:    stopWatch,
+		llamaServer:  llamaSrv,
+		memSearch:    memMgr,
 	}, nil
 }
 
+// Close stops the workspace watcher and a llama-server started for the agent.
+func (a *Agent) Close() {
+	if a.stopWatch != nil {
+		a.stopWatch()
+	}
+	if a.llamaServer != nil {
+		_ = a.llamaServer.Close()
+	}
+}
+
 func (a *Agent) Process(ctx context.Context, input string) (string, error) {
+	if rc, ok := parseRememberCommand(input); ok {
+		return runRememberCommand(a.workspace, a.sess.Key, a.sess.History(0), rc), nil
+	}
 	a.scheduleConsolidation()
 
-	sys := a.systemPrompt()
+	mem := memoryContext(ctx, a.cfg.Agents.Defaults.MemoryContext, a.workspace, a.memSearch, input, a.verbose)
+	sys := a.systemPrompt(mem)
+	sys += changedFilesNote(a.watch, a.sess.UpdatedAt, a.cfg.Agents.Defaults.WorkspaceWatch.MaxFilesValue())
+	defer a.watch.Busy()()
 	history := a.sess.History(a.memoryWindow)
 	messages := make([]llm.Message, 0, 1+len(history)+1)
 	messages = append(messages, llm.Message{Role: "system", Content: sys})
@@ -130,14 +221,39 @@ func (a *Agent) Process(ctx context.Context, input string) (string, error) {
 	messages = append(messages, llm.Message{Role: "user", Content: input})
 
 	toolsDefs := a.tools.Definitions()
+	if a.verbose {
+		logContextBudget(os.Stderr, "", measureContext(sys, mem, messages[1:len(messages)-1], messages[len(messages)-1], toolsDefs, a.llm.MaxTokens))
+	}
+	skill := &tools.ActiveSkill{}
+	messages = injectSkillShortcut(ctx, a.tools, tools.Context{
+		Channel:    "cli",
+		ChatID:     "direct",
+		SessionKey: a.sess.Key,
+		Skill:      skill,
+	}, messages)
+	rec := recordTurn(a.cfg.Agents.Defaults.Record, turns.Turn{
+		SessionKey: a.sess.Key,
+		Channel:    "cli",
+		ChatID:     "direct",
+		Model:      a.llm.Model,
+		Messages:   messages,
+	})
 
 	var final string
+	var trace toolTrace
 	toolsUsed := make([]string, 0, 8)
+	srcs := &tools.Sources{}
+	edits := &tools.Edits{}
+	bufs := &tools.Buffers{}
+	failures := &tools.Failures{}
 	for iter := 0; iter < a.maxIters; iter++ {
 		res, err := a.llm.Chat(ctx, messages, toolsDefs)
+		rec.Response(res, err)
 		if err != nil {
+			saveTurn(a.cfg.Agents.Defaults.Record, a.sessions, rec, "", err, a.verbose)
 			return "", err
 		}
+		addCitations(srcs, res.Citations)
 
 		if res.HasToolCalls() {
 			for _, tc := range res.ToolCalls {
@@ -147,14 +263,21 @@ func (a *Agent) Process(ctx context.Context, input string) (string, error) {
 				if a.verbose {
 					fmt.Fprintf(os.Stderr, "tool: %s %s\n", tc.Name, previewJSON(tc.Arguments, 200))
 				}
+				start := time.Now()
 				out, err := a.tools.Execute(ctx, tools.Context{
 					Channel:    "cli",
 					ChatID:     "direct",
 					SessionKey: a.sess.Key,
+					Sources:    srcs,
+					Edits:      edits,
+					Skill:      skill,
+					Buffers:    bufs,
 				}, tc.Name, tc.Arguments)
 				if err != nil {
-					return "error: " + err.Error()
+					out = failures.Record(tc.Name, err)
 				}
+				trace.add(tc, out, err != nil, time.Since(start))
+				rec.Tool(tc, out)
 				return out
 			})
 			continue
@@ -163,13 +286,21 @@ func (a *Agent) Process(ctx context.Context, input string) (string, error) {
 		final = res.Content
 		break
 	}
+	if strings.TrimSpace(final) != "" {
+		final = enforceLanguage(ctx, a.llm, a.cfg.Agents.Defaults.Language, "cli", "direct", input, final)
+		final = appendCitations(a.cfg.Agents.Defaults.Citations, "cli", final, srcs)
+		final = appendDiffs(a.cfg.Tools.Diffs, final, edits)
+		final = a.post.Apply("cli", final)
+	}
+	final = failureReply(final, failures)
 	if strings.TrimSpace(final) == "" {
 		final = "(no response)"
 	}
 
+	turnID := saveTurn(a.cfg.Agents.Defaults.Record, a.sessions, rec, final, nil, a.verbose)
 	a.sess.Add("user", input)
-	a.sess.AddWithTools("assistant", final, toolsUsed)
-	_ = session.Save(a.sessionDir, a.sess)
+	a.sess.AddReplyTrace("assistant", final, toolsUsed, turnID, trace)
+	_ = session.SaveTo(a.sessions, a.sess)
 	return final, nil
 }
 
@@ -211,13 +342,13 @@ func (a *Agent) scheduleConsolidation() {
 		if !done {
 			return
 		}
-		if err := session.Save(a.sessionDir, a.sess); err != nil && a.verbose {
+		if err := session.SaveTo(a.sessions, a.sess); err != nil && a.verbose {
 			fmt.Fprintf(os.Stderr, "consolidation save error: %v\n", err)
 		}
 	}()
 }
 
-func (a *Agent) systemPrompt() string {
+func (a *Agent) systemPrompt(mem string) string {
 	now := time.Now().Format("2006-01-02 15:04 (Mon)")
 	ws := a.workspace
 	rt := fmt.Sprintf("%s/%s Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
@@ -236,6 +367,11 @@ func (a *Agent) systemPrompt() string {
 	if a.cfg.Tools.RestrictToWorkspaceValue() {
 		b.WriteString("## Safety\nTools are restricted to the workspace directory.\n\n")
 	}
+	b.WriteString(languageInstruction(a.cfg.Agents.Defaults.Language, "cli", "direct"))
+	b.WriteString(toolFailureInstruction)
+	if a.tools.Journal != nil {
+		b.WriteString(journalInstruction)
+	}
 
 	// Bootstrap files from workspace (optional).
 	for _, fn := range []string{"AGENTS.md", "SOUL.md", "USER.md", "TOOLS.md", "IDENTITY.md"} {
@@ -250,8 +386,7 @@ func (a *Agent) systemPrompt() string {
 		}
 	}
 
-	// Memory (long-term + today's notes)
-	mem := memory.New(ws).GetContext()
+	// Memory (long-term + today's notes, or the relevant parts of them)
 	if strings.TrimSpace(mem) != "" {
 		b.WriteString("# Memory\n\n")
 		b.WriteString(mem)
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/metrics"
+)
+
+// SupportsBatch reports whether the configured provider has a batch API.
+func (l *Loop) SupportsBatch() bool {
+	return llm.SupportsBatch(l.llm.Provider)
+}
+
+// SubmitBatch queues a one-shot turn for content on the provider's batch
+// API and returns the batch ID for BatchResult. The turn sees the system
+// prompt for channel and chatID but no tools or history, since a batch is
+// answered in a single request.
+func (l *Loop) SubmitBatch(ctx context.Context, content, channel, chatID string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", errors.New("batch: empty message")
+	}
+	mem := memoryContext(ctx, l.cfg.Agents.Defaults.MemoryContext, l.workspace, l.memSearch, content, l.verbose)
+	return l.llm.SubmitBatch(ctx, []llm.Message{
+		{Role: "system", Content: l.buildSystemPrompt(channel, chatID, mem)},
+		{Role: "user", Content: content},
+	})
+}
+
+// BatchResult returns the reply of a batch queued with SubmitBatch, for
+// channel; see llm.Client.BatchResult.
+func (l *Loop) BatchResult(ctx context.Context, id, channel string) (string, bool, error) {
+	res, done, err := l.llm.BatchResult(ctx, id)
+	if done {
+		metrics.RecordLLMCall(providerName(l.llm.Provider), err)
+	}
+	if err != nil || !done {
+		return "", done, err
+	}
+	return l.post.Apply(channel, res.Content), true, nil
+}
@@ -0,0 +1,18 @@
+package agent
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mosaxiv/clawlet/chaos"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+// useChaos routes c's requests through inj, which fails some of them on
+// purpose; a nil inj leaves c alone.
+func useChaos(c *llm.Client, inj *chaos.Injector) {
+	if inj == nil {
+		return
+	}
+	c.HTTP = inj.HTTP(&http.Client{Timeout: 120 * time.Second})
+}
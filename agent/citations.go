@@ -0,0 +1,21 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+// appendCitations adds the turn's sources to reply when citations are on for
+// channel. Sources the reply already links to are not repeated.
+func appendCitations(c config.CitationsConfig, channel, reply string, srcs *tools.Sources) string {
+	if !c.EnabledFor(channel) {
+		return reply
+	}
+	footer := tools.FormatSources(reply, srcs.List(), c.MaxSourcesValue())
+	if footer == "" {
+		return reply
+	}
+	return strings.TrimRight(reply, " \n\t") + "\n\n" + footer
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+func TestAppendCitations_PerChannel(t *testing.T) {
+	c := config.CitationsConfig{Enabled: true, Channels: map[string]bool{"whatsapp": false}}
+	srcs := &tools.Sources{}
+	srcs.Add("Go", "https://go.dev/")
+
+	if got := appendCitations(c, "slack", "Answer.\n", srcs); got != "Answer.\n\nSources:\n1. Go — https://go.dev/" {
+		t.Fatalf("slack: %q", got)
+	}
+	if got := appendCitations(c, "whatsapp", "Answer.", srcs); got != "Answer." {
+		t.Fatalf("whatsapp: %q", got)
+	}
+	if got := appendCitations(config.CitationsConfig{}, "slack", "Answer.", srcs); got != "Answer." {
+		t.Fatalf("disabled: %q", got)
+	}
+	if got := appendCitations(c, "slack", "Answer.", &tools.Sources{}); got != "Answer." {
+		t.Fatalf("no sources: %q", got)
+	}
+}
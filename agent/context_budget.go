@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+const (
+	// imagePartTokens is a rough per-image cost; providers bill images by
+	// size and detail, not by their base64 length.
+	imagePartTokens = 1000
+	// memoryCrowdingTokens is the memory size below which it is never worth
+	// a warning, however short the history.
+	memoryCrowdingTokens = 2048
+)
+
+// contextBudget is the estimated token make-up of the prompt of a turn.
+type contextBudget struct {
+	System   int // system prompt without memory
+	Memory   int
+	History  int
+	Messages int // number of history messages
+	Input    int
+	Tools    int
+	Reply    int // maxTokens reserved for the reply
+}
+
+func measureContext(system, mem string, history []llm.Message, input llm.Message, defs []llm.ToolDefinition, reply int) contextBudget {
+	b := contextBudget{
+		Memory:   estimateTokens(mem),
+		Messages: len(history),
+		Input:    messageTokens(input),
+		Reply:    reply,
+	}
+	b.System = max(estimateTokens(system)-b.Memory, 0)
+	for _, m := range history {
+		b.History += messageTokens(m)
+	}
+	if len(defs) > 0 {
+		raw, _ := json.Marshal(defs)
+		b.Tools = estimateTokens(string(raw))
+	}
+	return b
+}
+
+func (b contextBudget) Total() int {
+	return b.System + b.Memory + b.History + b.Input + b.Tools
+}
+
+func (b contextBudget) String() string {
+	return fmt.Sprintf("context: ~%d tokens (system %d, memory %d, history %d in %d messages, input %d, tools %d), reply budget %d",
+		b.Total(), b.System, b.Memory, b.History, b.Messages, b.Input, b.Tools, b.Reply)
+}
+
+// crowdingWarning returns a hint when memory outweighs the conversation
+// history, so that most of the prompt is notes rather than the chat.
+func (b contextBudget) crowdingWarning() string {
+	if b.Memory < memoryCrowdingTokens || b.Memory <= b.History {
+		return ""
+	}
+	return fmt.Sprintf("context warning: memory (~%d tokens) is larger than the conversation history (~%d tokens); "+
+		"trim memory/MEMORY.md or today's notes (each is cut at 64KB, about 16k tokens)", b.Memory, b.History)
+}
+
+// logContextBudget prints the budget and any warning for a verbose run.
+func logContextBudget(w io.Writer, label string, b contextBudget) {
+	if label != "" {
+		label = " (" + label + ")"
+	}
+	fmt.Fprintf(w, "%s%s\n", b, label)
+	if warn := b.crowdingWarning(); warn != "" {
+		fmt.Fprintf(w, "%s%s\n", warn, label)
+	}
+}
+
+// estimateTokens approximates tokens as 4 bytes each, close enough for
+// English text and JSON to compare the parts of a prompt.
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+func messageTokens(m llm.Message) int {
+	n := estimateTokens(m.Content)
+	for _, p := range m.Parts {
+		switch p.Type {
+		case llm.ContentPartTypeImage:
+			n += imagePartTokens
+		default:
+			n += estimateTokens(p.Text)
+		}
+	}
+	for _, tc := range m.ToolCalls {
+		n += estimateTokens(tc.Function.Name) + estimateTokens(tc.Function.Arguments)
+	}
+	return n
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+func TestMeasureContext(t *testing.T) {
+	mem := strings.Repeat("m", 400)
+	system := strings.Repeat("s", 200) + mem
+	history := []llm.Message{
+		{Role: "user", Content: strings.Repeat("u", 40)},
+		{Role: "assistant", Content: strings.Repeat("a", 40), ToolCalls: []llm.ToolCallPayload{{Function: llm.ToolCallPayloadFunc{Name: "read", Arguments: `{"path":"x"}`}}}},
+	}
+	input := llm.Message{Role: "user", Parts: []llm.ContentPart{
+		{Type: llm.ContentPartTypeText, Text: "look"},
+		{Type: llm.ContentPartTypeImage, Data: strings.Repeat("A", 100000)},
+	}}
+	b := measureContext(system, mem, history, input, nil, 8192)
+
+	if b.System != 50 || b.Memory != 100 || b.Messages != 2 || b.Tools != 0 || b.Reply != 8192 {
+		t.Fatalf("unexpected budget: %+v", b)
+	}
+	if b.History != 10+10+1+3 {
+		t.Fatalf("history=%d", b.History)
+	}
+	if b.Input != 1+imagePartTokens {
+		t.Fatalf("input=%d", b.Input)
+	}
+	if b.crowdingWarning() != "" {
+		t.Fatalf("small memory should not warn")
+	}
+}
+
+func TestContextBudget_CrowdingWarning(t *testing.T) {
+	var out bytes.Buffer
+	logContextBudget(&out, "telegram:1", contextBudget{Memory: 5000, History: 800, Messages: 4, Reply: 1024})
+	got := out.String()
+	if !strings.Contains(got, "context: ~5800 tokens") || !strings.Contains(got, "(telegram:1)") {
+		t.Fatalf("budget line: %q", got)
+	}
+	if !strings.Contains(got, "context warning: memory (~5000 tokens)") {
+		t.Fatalf("expected warning: %q", got)
+	}
+
+	out.Reset()
+	logContextBudget(&out, "", contextBudget{Memory: 5000, History: 6000})
+	if strings.Contains(out.String(), "warning") {
+		t.Fatalf("history larger than memory should not warn: %q", out.String())
+	}
+}
@@ -0,0 +1,21 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+// appendDiffs adds the diffs of the files changed during the turn to reply
+// when tools.diffs.showUser is on.
+func appendDiffs(c config.FileDiffsConfig, reply string, edits *tools.Edits) string {
+	if !c.ShowUser {
+		return reply
+	}
+	diffs := edits.List()
+	if len(diffs) == 0 {
+		return reply
+	}
+	return strings.TrimRight(reply, " \n\t") + "\n\n```diff\n" + strings.Join(diffs, "\n") + "\n```"
+}
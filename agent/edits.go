@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// editedPrefix marks the text of an edited message answered as a new one.
+const editedPrefix = "[Edited] "
+
+// applyEdit prepares an edited message (Delivery.IsEdit) according to the
+// channel's edits policy. Under "correct", when the edit is of the last
+// message answered, that turn is dropped so it runs again and the reply
+// keeps IsEdit to replace the earlier answer. Otherwise the message is
+// answered as a new one marked as edited. It reports false when the edit
+// is ignored.
+func (l *Loop) applyEdit(sessionKey string, msg bus.InboundMessage) (bus.InboundMessage, bool, error) {
+	switch l.cfg.Channels.EditsFor(msg.Channel) {
+	case config.EditsIgnore:
+		return msg, false, nil
+	case config.EditsCorrect:
+		sess, err := l.sessions.GetOrCreate(sessionKey)
+		if err != nil {
+			return msg, false, err
+		}
+		if sess.DropLastTurn(msg.Delivery.MessageID) {
+			return msg, true, l.sessions.Save(sess)
+		}
+	}
+	msg.Delivery.IsEdit = false
+	if c := strings.TrimSpace(msg.Content); c != "" {
+		msg.Content = editedPrefix + c
+	}
+	return msg, true, nil
+}
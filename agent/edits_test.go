@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestApplyEdit(t *testing.T) {
+	cfg := config.Default()
+	cfg.Channels.Telegram.Edits = config.EditsCorrect
+	l := &Loop{cfg: cfg, sessions: session.NewManager(t.TempDir())}
+	sess, _ := l.sessions.GetOrCreate("telegram:1")
+	sess.AddFromMessage("u", "10", "wether in pairs")
+	sess.Add("assistant", "Which pairs?")
+
+	edit := func(id string) bus.InboundMessage {
+		return bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "weather in Paris", Delivery: bus.Delivery{MessageID: id, IsEdit: true}}
+	}
+
+	// An edit of an older message cannot be corrected; it is annotated.
+	got, ok, err := l.applyEdit("telegram:1", edit("9"))
+	if err != nil || !ok || got.Delivery.IsEdit || got.Content != "[Edited] weather in Paris" {
+		t.Fatalf("older edit: ok=%v err=%v msg=%+v", ok, err, got)
+	}
+
+	got, ok, err = l.applyEdit("telegram:1", edit("10"))
+	if err != nil || !ok || !got.Delivery.IsEdit || got.Content != "weather in Paris" {
+		t.Fatalf("correction: ok=%v err=%v msg=%+v", ok, err, got)
+	}
+	if h := sess.History(0); len(h) != 0 {
+		t.Fatalf("last turn should be dropped: %+v", h)
+	}
+
+	cfg.Channels.Telegram.Edits = config.EditsIgnore
+	if _, ok, _ := l.applyEdit("telegram:1", edit("10")); ok {
+		t.Fatal("ignored edit should not be processed")
+	}
+}
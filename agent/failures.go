@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+// toolFailureInstruction is the system prompt section for the final-reply
+// convention after failed tool calls.
+const toolFailureInstruction = "## Tool failures\n" +
+	"A failed tool call returns `error: ...` and a `failure:` object with kind, retryable and hint. " +
+	"Retry only when retryable is true, and at most once. " +
+	"If you still cannot do what was asked, say so in one sentence with the reason, " +
+	"e.g. \"I couldn't fetch that page (DNS failure).\"\n\n"
+
+// failureReply stands in for an empty final reply when a tool failed during
+// the turn, so the user learns what went wrong instead of getting silence.
+func failureReply(reply string, failures *tools.Failures) string {
+	if strings.TrimSpace(reply) != "" {
+		return reply
+	}
+	f, ok := failures.Last()
+	if !ok {
+		return reply
+	}
+	return "I couldn't finish that: " + f.Tool + " failed (" + f.Describe() + ")."
+}
@@ -0,0 +1,22 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+func TestFailureReply(t *testing.T) {
+	fs := &tools.Failures{}
+	if got := failureReply("", fs); got != "" {
+		t.Fatalf("no failures: %q", got)
+	}
+	fs.Record("web_fetch", errors.New("brave http 503: down"))
+	if got := failureReply("Here you go.", fs); got != "Here you go." {
+		t.Fatalf("kept reply: %q", got)
+	}
+	if got := failureReply(" ", fs); got != "I couldn't finish that: web_fetch failed (HTTP 503)." {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/faq"
+	"github.com/mosaxiv/clawlet/memory"
+	"github.com/mosaxiv/clawlet/metrics"
+)
+
+func newFAQ(cfg *config.Config, workspace string) (*faq.Matcher, error) {
+	c := cfg.Agents.Defaults.FAQ
+	if !c.Enabled {
+		return nil, nil
+	}
+	path := c.FileValue()
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workspace, path)
+	}
+	var e faq.Embedder
+	if c.Embeddings {
+		emb, err := memory.NewEmbedder(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("faq: %w", err)
+		}
+		e = emb
+	}
+	return faq.New(path, c.ThresholdValue(), e), nil
+}
+
+// answerFromFAQ answers a plain text message from the FAQ when it matches
+// confidently. The exchange is added to the session like any other turn.
+func (l *Loop) answerFromFAQ(ctx context.Context, msg bus.InboundMessage, sessionKey string) (string, bool) {
+	if l.faq == nil || msg.Kind != bus.MessageKindText || len(msg.Attachments) > 0 || msg.Delivery.IsEdit {
+		return "", false
+	}
+	start := time.Now()
+	answer, score, ok, err := l.faq.Match(ctx, msg.Content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "faq error: %v\n", err)
+		return "", false
+	}
+	if !ok {
+		return "", false
+	}
+	if l.verbose {
+		fmt.Fprintf(os.Stderr, "faq answer (%s, score %.2f)\n", sessionKey, score)
+	}
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return "", false
+	}
+	sess.AddFromMessage(msg.SenderID, msg.Delivery.MessageID, strings.TrimSpace(msg.Content))
+	sess.AddReply("assistant", answer, nil, "")
+	_ = l.sessions.Save(sess)
+	metrics.Inc("clawlet_faq_answers_total")
+	metrics.RecordTurn(time.Since(start), true)
+	return answer, true
+}
+
+func newRecentAnswers(cfg *config.Config) (*faq.Recent, error) {
+	c := cfg.Agents.Defaults.Duplicates
+	if !c.Enabled {
+		return nil, nil
+	}
+	emb, err := memory.NewEmbedder(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("duplicates: %w", err)
+	}
+	return faq.NewRecent(emb, time.Duration(c.WindowHoursValue())*time.Hour, c.ThresholdValue()), nil
+}
+
+// duplicateCandidate reports whether msg is a group question that may
+// repeat an earlier one.
+func (l *Loop) duplicateCandidate(msg bus.InboundMessage) bool {
+	return l.recent != nil && !msg.Delivery.IsDirect && msg.Kind == bus.MessageKindText &&
+		len(msg.Attachments) == 0 && !msg.Delivery.IsEdit && faq.IsQuestion(msg.Content)
+}
+
+// answerDuplicate answers a group question that nearly repeats one answered
+// in the chat recently by quoting the earlier answer. Otherwise it returns
+// the question's embedding, for rememberAnswer once the turn has answered
+// it.
+func (l *Loop) answerDuplicate(ctx context.Context, msg bus.InboundMessage, sessionKey string) (string, []float64, bool) {
+	if !l.duplicateCandidate(msg) {
+		return "", nil, false
+	}
+	earlier, vec, ok, err := l.recent.Find(ctx, msg.Channel+":"+msg.ChatID, msg.SenderID, strings.TrimSpace(msg.Content))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "duplicates error: %v\n", err)
+		return "", nil, false
+	}
+	if !ok {
+		return "", vec, false
+	}
+	if l.verbose {
+		fmt.Fprintf(os.Stderr, "duplicate question (%s): %q\n", sessionKey, earlier.Question)
+	}
+	answer := duplicateReply(earlier)
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return "", nil, false
+	}
+	sess.AddFromMessage(msg.SenderID, msg.Delivery.MessageID, strings.TrimSpace(msg.Content))
+	sess.AddReply("assistant", answer, nil, "")
+	_ = l.sessions.Save(sess)
+	metrics.Inc("clawlet_duplicate_answers_total")
+	return answer, nil, true
+}
+
+// rememberAnswer keeps a group question the turn answered, embedded as vec,
+// for answerDuplicate.
+func (l *Loop) rememberAnswer(msg bus.InboundMessage, vec []float64, answer string) {
+	if l.recent == nil || vec == nil {
+		return
+	}
+	l.recent.Add(msg.Channel+":"+msg.ChatID, msg.SenderID, strings.TrimSpace(msg.Content), vec, answer)
+}
+
+// duplicateReply quotes the earlier question and its answer.
+func duplicateReply(a faq.Answered) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "This was answered earlier (%s):\n> %s\n\n", a.At.Format("Jan 2 15:04"), a.Question)
+	b.WriteString(a.Answer)
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/mosaxiv/clawlet/forget"
+)
+
+// parseForgetCommand recognises "/forget-me [confirm]". Without confirm the
+// command only lists what would be deleted.
+func parseForgetCommand(text string) (confirm bool, ok bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) == 0 {
+		return false, false
+	}
+	cmd, _, _ := strings.Cut(fields[0], "@")
+	if !strings.EqualFold(cmd, "/forget-me") {
+		return false, false
+	}
+	return len(fields) > 1 && strings.EqualFold(fields[1], "confirm"), true
+}
+
+func forgetReply(rep forget.Report) string {
+	if rep.Empty() {
+		return "Nothing is stored about you."
+	}
+	if rep.DryRun {
+		return rep.Summary() + "\n\n" + forgetMemoryNote + "\n\nSend /forget-me confirm to delete this."
+	}
+	return rep.Summary() + "\n\n" + forgetMemoryNote
+}
+
+// forgetMemoryNote tells the sender that free-text memory is left alone;
+// forget.Run only lists the lines that mention them.
+const forgetMemoryNote = "Memory notes in MEMORY.md, HISTORY.md and the daily notes are not deleted by /forget-me; an admin has to review them."
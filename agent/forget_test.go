@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/forget"
+)
+
+func TestParseForgetCommand(t *testing.T) {
+	cases := []struct {
+		in          string
+		ok, confirm bool
+	}{
+		{in: "forget me", ok: false},
+		{in: "/forget", ok: false},
+		{in: "/forget-me", ok: true},
+		{in: "/forget-me@clawbot CONFIRM", ok: true, confirm: true},
+		{in: "/forget-me please", ok: true},
+	}
+	for _, tc := range cases {
+		confirm, ok := parseForgetCommand(tc.in)
+		if ok != tc.ok || confirm != tc.confirm {
+			t.Fatalf("%q: got ok=%v confirm=%v", tc.in, ok, confirm)
+		}
+	}
+}
+
+func TestForgetReply_SaysMemoryNotesAreKept(t *testing.T) {
+	rep := forget.Report{Review: []string{"memory/MEMORY.md:3"}}
+	for _, dryRun := range []bool{true, false} {
+		rep.DryRun = dryRun
+		if got := forgetReply(rep); !strings.Contains(got, "not deleted") {
+			t.Fatalf("dryRun=%v: %q", dryRun, got)
+		}
+	}
+	if got := forgetReply(forget.Report{}); got != "Nothing is stored about you." {
+		t.Fatalf("empty: %q", got)
+	}
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+// A fork copies a chat's conversation into its own session so alternatives
+// can be explored without touching the main history. Routing is kept in the
+// main session's metadata: where the channel can continue the fork in a
+// thread, forkThreadsMeta maps the thread to the fork; elsewhere
+// forkActiveMeta sends every new message to the fork until "/fork main".
+const (
+	forkActiveMeta  = "fork_active"
+	forkThreadsMeta = "fork_threads"
+	forkKeySep      = "#fork-"
+)
+
+// forkCommand is a parsed "/fork [main | label]" message.
+type forkCommand struct {
+	Main  bool
+	Label string
+}
+
+func parseForkCommand(text string) (forkCommand, bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) == 0 {
+		return forkCommand{}, false
+	}
+	cmd, _, _ := strings.Cut(fields[0], "@")
+	if !strings.EqualFold(cmd, "/fork") {
+		return forkCommand{}, false
+	}
+	if len(fields) == 2 && strings.EqualFold(fields[1], "main") {
+		return forkCommand{Main: true}, true
+	}
+	return forkCommand{Label: strings.Join(fields[1:], " ")}, true
+}
+
+// forkThread returns the thread a fork started by msg continues in, or ""
+// when the channel has no thread to offer.
+func forkThread(msg bus.InboundMessage) string {
+	switch msg.Channel {
+	case "slack":
+		// Channel replies are threaded under the message; DMs are not.
+		if !msg.Delivery.IsDirect {
+			return strings.TrimSpace(msg.Delivery.ThreadID)
+		}
+	case "matrix":
+		if t := strings.TrimSpace(msg.Delivery.ThreadID); t != "" {
+			return t
+		}
+		return strings.TrimSpace(msg.Delivery.MessageID)
+	}
+	return ""
+}
+
+// resolveFork returns the session key for a message in base's chat.
+func resolveFork(base *session.Session, threadID string) string {
+	if threadID != "" {
+		if v, ok := base.Meta(forkThreadsMeta); ok {
+			if m, ok := v.(map[string]any); ok {
+				if k, ok := m[threadID].(string); ok && k != "" {
+					return k
+				}
+			}
+		}
+	}
+	if v, ok := base.Meta(forkActiveMeta); ok {
+		if k, ok := v.(string); ok && k != "" {
+			return k
+		}
+	}
+	return base.Key
+}
+
+// runFork handles /fork for a chat whose main session is base and whose
+// message was routed to currentKey. It returns the reply and where to send it.
+func (l *Loop) runFork(msg bus.InboundMessage, base *session.Session, currentKey string, fc forkCommand) (string, bus.Delivery) {
+	delivery := msg.Delivery
+	if fc.Main {
+		if _, ok := base.Meta(forkActiveMeta); !ok {
+			return "Already in the main conversation.", delivery
+		}
+		base.SetMeta(forkActiveMeta, nil)
+		if err := l.sessions.Save(base); err != nil {
+			return "error: " + err.Error(), delivery
+		}
+		return "Back to the main conversation.", delivery
+	}
+
+	src := base
+	if currentKey != base.Key {
+		s, err := l.sessions.GetOrCreate(currentKey)
+		if err != nil {
+			return "error: " + err.Error(), delivery
+		}
+		src = s
+	}
+	fork := src.Fork(base.Key + forkKeySep + strconv.FormatInt(time.Now().UnixMilli(), 36))
+	fork.SetMeta("forked_from", src.Key)
+	if fc.Label != "" {
+		fork.SetMeta("fork_label", fc.Label)
+	}
+	if err := l.sessions.Save(fork); err != nil {
+		return "error: " + err.Error(), delivery
+	}
+
+	name := "this conversation"
+	if fc.Label != "" {
+		name = strconv.Quote(fc.Label)
+	}
+	reply := "Forked " + name + " (" + strconv.Itoa(len(fork.Messages)) + " messages). "
+	if thread := forkThread(msg); thread != "" {
+		threads := map[string]any{}
+		if v, ok := base.Meta(forkThreadsMeta); ok {
+			if m, ok := v.(map[string]any); ok {
+				maps.Copy(threads, m)
+			}
+		}
+		threads[thread] = fork.Key
+		base.SetMeta(forkThreadsMeta, threads)
+		delivery.ThreadID = thread
+		reply += "Continue in this thread; the main conversation is not affected."
+	} else {
+		base.SetMeta(forkActiveMeta, fork.Key)
+		reply += "New messages go to the fork; send /fork main to return to the main conversation."
+	}
+	if err := l.sessions.Save(base); err != nil {
+		return "error: " + err.Error(), delivery
+	}
+	return reply, delivery
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestParseForkCommand(t *testing.T) {
+	cases := []struct {
+		in   string
+		ok   bool
+		want forkCommand
+	}{
+		{in: "fork it", ok: false},
+		{in: "/forking", ok: false},
+		{in: "/fork", ok: true},
+		{in: "/fork@clawbot MAIN", ok: true, want: forkCommand{Main: true}},
+		{in: "/fork main street", ok: true, want: forkCommand{Label: "main street"}},
+	}
+	for _, tc := range cases {
+		got, ok := parseForkCommand(tc.in)
+		if ok != tc.ok || got != tc.want {
+			t.Fatalf("%q: got %+v,%v want %+v,%v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestRunFork_RoutesChat(t *testing.T) {
+	l := &Loop{sessions: session.NewManager(t.TempDir())}
+	base, _ := l.sessions.GetOrCreate("telegram:1")
+	base.AddFrom("1", "plan a trip")
+	base.Add("assistant", "Paris?")
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "/fork rome"}
+	reply, _ := l.runFork(msg, base, base.Key, forkCommand{Label: "rome"})
+	if !strings.Contains(reply, "/fork main") {
+		t.Fatalf("reply=%q", reply)
+	}
+	forkKey := resolveFork(base, "")
+	if !strings.HasPrefix(forkKey, "telegram:1#fork-") {
+		t.Fatalf("active fork=%q", forkKey)
+	}
+	fork, _ := l.sessions.GetOrCreate(forkKey)
+	if len(fork.History(0)) != 2 {
+		t.Fatalf("fork history=%+v", fork.History(0))
+	}
+
+	l.runFork(msg, base, forkKey, forkCommand{Main: true})
+	if got := resolveFork(base, ""); got != base.Key {
+		t.Fatalf("after /fork main: %q", got)
+	}
+}
+
+func TestRunFork_SlackThread(t *testing.T) {
+	l := &Loop{sessions: session.NewManager(t.TempDir())}
+	base, _ := l.sessions.GetOrCreate("slack:C1")
+	msg := bus.InboundMessage{Channel: "slack", ChatID: "C1", Content: "/fork", Delivery: bus.Delivery{MessageID: "1.0", ThreadID: "1.0"}}
+	_, delivery := l.runFork(msg, base, base.Key, forkCommand{})
+	if delivery.ThreadID != "1.0" {
+		t.Fatalf("delivery=%+v", delivery)
+	}
+	if got := resolveFork(base, "1.0"); !strings.HasPrefix(got, "slack:C1#fork-") {
+		t.Fatalf("thread route=%q", got)
+	}
+	if got := resolveFork(base, "2.0"); got != base.Key {
+		t.Fatalf("other thread route=%q", got)
+	}
+}
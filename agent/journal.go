@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/memory"
+)
+
+// journalInstruction is the system prompt section added when the journal
+// tool is available.
+const journalInstruction = "## Daily Note\n" +
+	"Record tasks, summaries of notable conversations and decisions (with the reason) in today's note " +
+	"with the journal tool, one entry per call. Do not edit memory/YYYY-MM-DD.md directly.\n\n"
+
+// newJournal returns the daily-note journal, or nil when it is off. A
+// template path is relative to the workspace.
+func newJournal(cfg config.JournalConfig, workspace string) (*memory.Journal, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	j := &memory.Journal{Store: memory.New(workspace), Sections: cfg.SectionsValue()}
+	if cfg.Template == "" {
+		return j, nil
+	}
+	p := cfg.Template
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(workspace, p)
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("journal template: %w", err)
+	}
+	j.Template = string(b)
+	j.Sections = memory.TemplateSections(j.Template)
+	if len(j.Sections) == 0 {
+		return nil, fmt.Errorf("journal template %s has no \"## \" sections", cfg.Template)
+	}
+	return j, nil
+}
@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestNewJournal(t *testing.T) {
+	ws := t.TempDir()
+	if j, err := newJournal(config.JournalConfig{}, ws); j != nil || err != nil {
+		t.Fatalf("disabled: %v %v", j, err)
+	}
+	j, err := newJournal(config.JournalConfig{Enabled: true}, ws)
+	if err != nil || !slices.Equal(j.Sections, []string{"Tasks", "Conversations", "Decisions", "Notes"}) {
+		t.Fatalf("defaults: %+v %v", j, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(ws, "daily.md"), []byte("# {{date}}\n\n## Work\n\n## Home\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	j, err = newJournal(config.JournalConfig{Enabled: true, Template: "daily.md", Sections: []string{"ignored"}}, ws)
+	if err != nil || !slices.Equal(j.Sections, []string{"Work", "Home"}) {
+		t.Fatalf("template: %+v %v", j, err)
+	}
+	if _, err := newJournal(config.JournalConfig{Enabled: true, Template: "missing.md"}, ws); err == nil {
+		t.Fatal("expected missing template error")
+	}
+}
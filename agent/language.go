@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+func validateLanguagePolicy(c config.LanguageConfig) error {
+	switch strings.ToLower(strings.TrimSpace(c.Mode)) {
+	case "", config.LanguageModeMirror:
+		return nil
+	case config.LanguageModeFixed:
+		if strings.TrimSpace(c.Language) == "" {
+			return fmt.Errorf("agents.defaults.language.mode=fixed requires language")
+		}
+		return nil
+	default:
+		return fmt.Errorf("agents.defaults.language.mode must be %q or %q, got %q", config.LanguageModeMirror, config.LanguageModeFixed, c.Mode)
+	}
+}
+
+// languageInstruction is the system prompt section for the chat's language
+// policy, or "" when no policy applies.
+func languageInstruction(c config.LanguageConfig, channel, chatID string) string {
+	mode, lang := c.Resolve(channel, chatID)
+	switch mode {
+	case config.LanguageModeMirror:
+		return "## Language\nReply in the same language as the user's latest message, even if earlier messages, memory or tool output use another language.\n\n"
+	case config.LanguageModeFixed:
+		return "## Language\nAlways reply in " + lang + ", regardless of the language the user writes in. Keep code, commands, file paths and quoted text unchanged.\n\n"
+	}
+	return ""
+}
+
+// enforceLanguage runs the optional post-check pass: the reply is returned
+// unchanged if it already matches the policy, otherwise translated. Errors
+// keep the original reply.
+func enforceLanguage(ctx context.Context, c *llm.Client, policy config.LanguageConfig, channel, chatID, userText, reply string) string {
+	if !policy.Translate || c == nil || strings.TrimSpace(reply) == "" {
+		return reply
+	}
+	mode, lang := policy.Resolve(channel, chatID)
+	var system, input string
+	switch mode {
+	case config.LanguageModeFixed:
+		system = "You check the language of assistant replies. If the reply is already written in " + lang + ", output it exactly as given. Otherwise translate it into " + lang + "."
+		input = reply
+	case config.LanguageModeMirror:
+		if strings.TrimSpace(userText) == "" {
+			return reply
+		}
+		system = "You check the language of assistant replies. If <reply> is already written in the same language as <user_message>, output the reply exactly as given. Otherwise translate the reply into the language of <user_message>."
+		input = "<user_message>\n" + userText + "\n</user_message>\n<reply>\n" + reply + "\n</reply>"
+	default:
+		return reply
+	}
+	system += " Preserve formatting, Markdown, code blocks, URLs and names. Output only the reply text."
+	res, err := c.Chat(ctx, []llm.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: input},
+	}, nil)
+	if err != nil {
+		return reply
+	}
+	out := strings.TrimSpace(res.Content)
+	if out == "" {
+		return reply
+	}
+	return out
+}
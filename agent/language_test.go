@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+func TestLanguageInstruction_ResolvesPerChat(t *testing.T) {
+	policy := config.LanguageConfig{
+		Mode: config.LanguageModeMirror,
+		Chats: map[string]string{
+			"telegram:42": "Japanese",
+			"slack":       "German",
+		},
+	}
+	if got := languageInstruction(policy, "telegram", "42"); !strings.Contains(got, "Always reply in Japanese") {
+		t.Fatalf("expected chat override, got %q", got)
+	}
+	if got := languageInstruction(policy, "slack", "C1"); !strings.Contains(got, "Always reply in German") {
+		t.Fatalf("expected channel override, got %q", got)
+	}
+	if got := languageInstruction(policy, "telegram", "7"); !strings.Contains(got, "same language as the user's latest message") {
+		t.Fatalf("expected mirror default, got %q", got)
+	}
+	if got := languageInstruction(config.LanguageConfig{}, "telegram", "7"); got != "" {
+		t.Fatalf("expected no instruction by default, got %q", got)
+	}
+}
+
+func TestValidateLanguagePolicy(t *testing.T) {
+	if err := validateLanguagePolicy(config.LanguageConfig{Mode: "fixed"}); err == nil {
+		t.Fatalf("expected error for fixed without language")
+	}
+	if err := validateLanguagePolicy(config.LanguageConfig{Mode: "auto"}); err == nil {
+		t.Fatalf("expected error for unknown mode")
+	}
+	if err := validateLanguagePolicy(config.LanguageConfig{Mode: "fixed", Language: "French"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestEnforceLanguage_TranslatesWhenEnabled(t *testing.T) {
+	var gotSystem string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Messages) > 0 {
+			gotSystem = req.Messages[0].Content
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]any{"role": "assistant", "content": "Bonjour"}}},
+		})
+	}))
+	defer srv.Close()
+	c := &llm.Client{Provider: "openai", BaseURL: srv.URL, APIKey: "k", Model: "m", HTTP: srv.Client()}
+
+	policy := config.LanguageConfig{Mode: config.LanguageModeFixed, Language: "French"}
+	if got := enforceLanguage(context.Background(), c, policy, "cli", "direct", "hi", "Hello"); got != "Hello" {
+		t.Fatalf("expected no pass without translate, got %q", got)
+	}
+	policy.Translate = true
+	if got := enforceLanguage(context.Background(), c, policy, "cli", "direct", "hi", "Hello"); got != "Bonjour" {
+		t.Fatalf("expected translated reply, got %q", got)
+	}
+	if !strings.Contains(gotSystem, "French") {
+		t.Fatalf("expected target language in prompt, got %q", gotSystem)
+	}
+}
@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llamaserver"
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/paths"
+)
+
+// useLlamaServer points c at a managed llama-server when one is configured
+// and returns it (nil otherwise). The process starts on c's first request.
+func useLlamaServer(cfg config.LlamaServerConfig, c *llm.Client) (*llamaserver.Server, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	switch c.Provider {
+	case "", "openai", "ollama":
+	default:
+		return nil, fmt.Errorf("llm.llamaServer needs an OpenAI-compatible provider (openai/ or local/ model), got %q", c.Provider)
+	}
+	srv := llamaserver.New(llamaserver.Options{
+		Command:        cfg.Command,
+		Model:          cfg.Model,
+		Listen:         cfg.ListenValue(),
+		Args:           cfg.Args,
+		StartupTimeout: time.Duration(cfg.StartupTimeoutSec) * time.Second,
+		HealthInterval: time.Duration(cfg.HealthIntervalSec) * time.Second,
+		LogPath:        paths.LlamaServerLogPath(),
+	})
+	c.BaseURL = srv.BaseURL()
+	c.Prepare = srv.Ensure
+	return srv, nil
+}
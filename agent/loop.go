@@ -2,6 +2,8 @@ package agent
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,15 +11,26 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mosaxiv/clawlet/broadcast"
 	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/chaos"
 	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/contacts"
 	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/faq"
+	"github.com/mosaxiv/clawlet/forget"
+	"github.com/mosaxiv/clawlet/fswatch"
+	"github.com/mosaxiv/clawlet/llamaserver"
 	"github.com/mosaxiv/clawlet/llm"
 	"github.com/mosaxiv/clawlet/media"
 	"github.com/mosaxiv/clawlet/memory"
+	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/postprocess"
+	"github.com/mosaxiv/clawlet/presence"
 	"github.com/mosaxiv/clawlet/session"
 	"github.com/mosaxiv/clawlet/skills"
 	"github.com/mosaxiv/clawlet/tools"
+	"github.com/mosaxiv/clawlet/turns"
 )
 
 type Loop struct {
@@ -33,8 +46,25 @@ type Loop struct {
 
 	llm   *llm.Client
 	tools *tools.Registry
-
-	cron *cron.Service
+	post  *postprocess.Pipeline
+	// transcriber turns audio attachments into text; nil when none is set up.
+	transcriber media.Transcriber
+	// speaker reads replies aloud as voice notes; nil when none is set up.
+	speaker media.Speaker
+
+	cron  *cron.Service
+	watch *fswatch.Watcher
+
+	llamaServer *llamaserver.Server
+	memSearch   *memory.IndexManager
+	// presence queues messages while the agent is away; nil keeps it
+	// always present.
+	presence *presence.Tracker
+	// faq answers common questions without an LLM call; nil when off.
+	faq *faq.Matcher
+	// recent holds the group questions answered lately, to answer repeats
+	// of them; nil when off.
+	recent *faq.Recent
 
 	verbose bool
 
@@ -51,7 +81,10 @@ type LoopOptions struct {
 	Skills       *skills.Loader
 	Cron         *cron.Service
 	Spawn        func(ctx context.Context, task, label, originChannel, originChatID string) (string, error)
-	Verbose      bool
+	Presence     *presence.Tracker
+	// Chaos, when set, fails some LLM calls on purpose; see package chaos.
+	Chaos   *chaos.Injector
+	Verbose bool
 }
 
 func NewLoop(opts LoopOptions) (*Loop, error) {
@@ -86,21 +119,48 @@ func NewLoop(opts LoopOptions) (*Loop, error) {
 		sloader = skills.New(ws)
 	}
 
+	braveKey, nativeSearch, err := webSearchSetup(opts.Config)
+	if err != nil {
+		return nil, err
+	}
 	client := &llm.Client{
-		Provider:    opts.Config.LLM.Provider,
-		BaseURL:     opts.Config.LLM.BaseURL,
-		APIKey:      opts.Config.LLM.APIKey,
-		Model:       model,
-		MaxTokens:   opts.Config.Agents.Defaults.MaxTokensValue(),
-		Temperature: opts.Config.Agents.Defaults.Temperature,
-		Headers:     opts.Config.LLM.Headers,
+		Provider:     opts.Config.LLM.Provider,
+		BaseURL:      opts.Config.LLM.BaseURL,
+		APIKey:       opts.Config.LLM.APIKey,
+		Model:        model,
+		MaxTokens:    opts.Config.Agents.Defaults.MaxTokensValue(),
+		Temperature:  opts.Config.Agents.Defaults.Temperature,
+		Headers:      opts.Config.LLM.Headers,
+		WebSearch:    nativeSearch,
+		KeepAlive:    opts.Config.LLM.Ollama.KeepAlive,
+		AutoPull:     opts.Config.LLM.Ollama.AutoPull,
+		PullProgress: logPullProgress(),
+	}
+	useChaos(client, opts.Chaos)
+	llamaSrv, err := useLlamaServer(opts.Config.LLM.LlamaServer, client)
+	if err != nil {
+		return nil, err
 	}
 
+	if err := tools.ValidateToolNames(opts.Config.Tools.Aliases, opts.Config.Tools.Rename); err != nil {
+		return nil, err
+	}
+	if err := validateLanguagePolicy(opts.Config.Agents.Defaults.Language); err != nil {
+		return nil, err
+	}
+	post, err := postprocess.New(opts.Config.Agents.Defaults.PostProcess)
+	if err != nil {
+		return nil, err
+	}
 	treg := &tools.Registry{
 		WorkspaceDir:           ws,
+		Aliases:                opts.Config.Tools.Aliases,
+		Renames:                opts.Config.Tools.Rename,
 		RestrictToWorkspace:    opts.Config.Tools.RestrictToWorkspaceValue(),
+		IsolateSkills:          opts.Config.Tools.Skills.Isolate,
 		ExecTimeout:            time.Duration(opts.Config.Tools.Exec.TimeoutSec) * time.Second,
-		BraveAPIKey:            opts.Config.Tools.Web.BraveAPIKey,
+		DiffMaxLines:           opts.Config.Tools.Diffs.MaxLinesValue(),
+		BraveAPIKey:            braveKey,
 		WebFetchAllowedDomains: append([]string(nil), opts.Config.Tools.Web.AllowedDomains...),
 		WebFetchBlockedDomains: append([]string(nil), opts.Config.Tools.Web.BlockedDomains...),
 		WebFetchMaxResponse:    opts.Config.Tools.Web.MaxResponseBytes,
@@ -117,12 +177,59 @@ func NewLoop(opts LoopOptions) (*Loop, error) {
 			return sloader.Load(name)
 		},
 	}
-	treg.SkillRegistry, treg.SkillSearchDefaultLimit = buildSkillRegistry(opts.Config)
+	if tz := strings.TrimSpace(opts.Config.Cron.Timezone); tz != "" {
+		if treg.ReminderLocation, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("cron.timezone: %w", err)
+		}
+	}
+	treg.SkillRegistry, treg.SkillSearchDefaultLimit, err = buildSkillRegistry(opts.Config)
+	if err != nil {
+		return nil, err
+	}
 	memMgr, err := memory.NewIndexManager(opts.Config, ws)
 	if err != nil {
 		return nil, err
 	}
 	treg.MemorySearch = memMgr
+	treg.Contacts = contacts.NewStore(contacts.Path(ws))
+	treg.Recipients = func(group string) ([]broadcast.Recipient, error) {
+		return broadcast.Resolve(opts.Config.Channels.Recipients, group, treg.Contacts)
+	}
+	treg.Journal, err = newJournal(opts.Config.Agents.Defaults.Journal, ws)
+	if err != nil {
+		return nil, err
+	}
+	treg.Sent = tools.NewSentLog(paths.SentMessagesPath())
+	treg.Conversation = func(sessionKey string) []session.Message {
+		sess, err := smgr.GetOrCreate(sessionKey)
+		if err != nil {
+			return nil
+		}
+		return sess.History(0)
+	}
+	faqMatcher, err := newFAQ(opts.Config, ws)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := newRecentAnswers(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+	watch, err := newWorkspaceWatcher(opts.Config.Agents.Defaults.WorkspaceWatch, ws)
+	if err != nil {
+		return nil, err
+	}
+	transcriber, err := media.NewTranscriber(opts.Config.Tools.Media.Transcription, client)
+	if err != nil {
+		return nil, err
+	}
+	speaker, err := media.NewSpeaker(opts.Config.Tools.Media.Speech)
+	if err != nil {
+		return nil, err
+	}
+	if speaker != nil {
+		treg.Speak = speaker.Speak
+	}
 
 	return &Loop{
 		cfg:          opts.Config,
@@ -135,11 +242,27 @@ func NewLoop(opts LoopOptions) (*Loop, error) {
 		skills:       sloader,
 		llm:          client,
 		tools:        treg,
+		post:         post,
+		transcriber:  transcriber,
+		speaker:      speaker,
 		cron:         opts.Cron,
+		watch:        watch,
+		llamaServer:  llamaSrv,
+		memSearch:    memMgr,
+		presence:     opts.Presence,
+		faq:          faqMatcher,
+		recent:       recent,
 		verbose:      opts.Verbose,
 	}, nil
 }
 
+// Close stops a llama-server started for the loop.
+func (l *Loop) Close() {
+	if l.llamaServer != nil {
+		_ = l.llamaServer.Close()
+	}
+}
+
 func (l *Loop) SetSpawn(fn func(ctx context.Context, task, label, originChannel, originChatID string) (string, error)) {
 	if l == nil || l.tools == nil {
 		return
@@ -148,6 +271,12 @@ func (l *Loop) SetSpawn(fn func(ctx context.Context, task, label, originChannel,
 }
 
 func (l *Loop) Run(ctx context.Context) error {
+	if l.watch != nil {
+		go l.watch.Run(ctx)
+	}
+	if l.presence != nil {
+		go l.releaseWhenBack(ctx)
+	}
 	for {
 		msg, err := l.bus.ConsumeInbound(ctx)
 		if err != nil {
@@ -171,7 +300,7 @@ func (l *Loop) Run(ctx context.Context) error {
 
 func (l *Loop) ProcessDirect(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
 	userText := strings.TrimSpace(content)
-	return l.processDirect(ctx, llm.Message{Role: "user", Content: content}, userText, sessionKey, channel, chatID)
+	return l.processDirect(ctx, llm.Message{Role: "user", Content: content}, userText, sessionKey, channel, chatID, "", "", nil)
 }
 
 func (l *Loop) processInbound(ctx context.Context, msg bus.InboundMessage) (string, bus.OutboundMessage, error) {
@@ -184,15 +313,99 @@ func (l *Loop) processInbound(ctx context.Context, msg bus.InboundMessage) (stri
 		}
 		// Route response back to origin session.
 		sk := originCh + ":" + originChat
-		res, err := l.processDirect(ctx, llm.Message{Role: "user", Content: msg.Content}, msg.Content, sk, originCh, originChat)
+		res, err := l.processDirect(ctx, llm.Message{Role: "user", Content: msg.Content}, msg.Content, sk, originCh, originChat, "", "", nil)
 		return res, bus.OutboundMessage{Channel: originCh, ChatID: originChat, Content: res}, err
 	}
 
+	if msg.Kind == bus.MessageKindStatus {
+		// Delivery statuses are for observers of the bus.
+		return "", bus.OutboundMessage{}, nil
+	}
+
 	sessionKey := msg.SessionKey
 	if strings.TrimSpace(sessionKey) == "" {
 		sessionKey = msg.Channel + ":" + msg.ChatID
 	}
-	userInput, err := media.PrepareInbound(ctx, l.llm, l.cfg.Tools.Media, msg)
+	base, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return "", bus.OutboundMessage{}, err
+	}
+	sessionKey = resolveFork(base, msg.Delivery.ThreadID)
+	if msg.Kind == bus.MessageKindReaction {
+		// Reactions are context for the next turn, not a turn of their own.
+		return "", bus.OutboundMessage{}, l.recordReaction(sessionKey, msg)
+	}
+	if msg.Kind == bus.MessageKindPoll {
+		return "", bus.OutboundMessage{}, l.recordPoll(sessionKey, msg)
+	}
+	if msg.Delivery.IsEdit {
+		var ok bool
+		if msg, ok, err = l.applyEdit(sessionKey, msg); err != nil || !ok {
+			return "", bus.OutboundMessage{}, err
+		}
+	}
+	if fc, ok := parseForkCommand(msg.Content); ok {
+		res, delivery := l.runFork(msg, base, sessionKey, fc)
+		return res, bus.OutboundMessage{
+			Channel:  msg.Channel,
+			ChatID:   msg.ChatID,
+			Content:  res,
+			Delivery: delivery,
+		}, nil
+	}
+	if confirm, ok := parseForgetCommand(msg.Content); ok {
+		res := l.forgetSender(msg.Channel, msg.SenderID, confirm)
+		return res, bus.OutboundMessage{
+			Channel:  msg.Channel,
+			ChatID:   msg.ChatID,
+			Content:  res,
+			Delivery: msg.Delivery,
+		}, nil
+	}
+	if rc, ok := parseRememberCommand(msg.Content); ok {
+		var res string
+		if sess, err := l.sessions.GetOrCreate(sessionKey); err != nil {
+			res = "error: " + err.Error()
+		} else {
+			res = runRememberCommand(l.workspace, sessionKey, sess.History(0), rc)
+		}
+		return res, bus.OutboundMessage{
+			Channel:  msg.Channel,
+			ChatID:   msg.ChatID,
+			Content:  res,
+			Delivery: msg.Delivery,
+		}, nil
+	}
+	if name, ok := parseSessionCommand(msg.Content); ok {
+		out := bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Delivery: msg.Delivery}
+		if name == "trace" {
+			out.Content, out.Attachments = l.runTraceCommand(msg, sessionKey)
+		} else {
+			out.Content = l.runSessionCommand(name, sessionKey)
+		}
+		return out.Content, out, nil
+	}
+	if tc, ok := parseTakeoverCommand(msg.Content); ok {
+		res := l.runTakeoverCommand(msg, base.Key, tc)
+		return res, bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: res, Delivery: msg.Delivery}, nil
+	}
+	if isPaused(base) {
+		// A human has taken the chat over.
+		return "", bus.OutboundMessage{}, l.logWhilePaused(sessionKey, msg)
+	}
+	if out, held, err := l.holdWhileAway(msg, sessionKey); held || err != nil {
+		return out.Content, out, err
+	}
+	if res, ok := l.answerFromFAQ(ctx, msg, sessionKey); ok {
+		return res, bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: res, Delivery: msg.Delivery}, nil
+	}
+	dup, questionVec, ok := l.answerDuplicate(ctx, msg, sessionKey)
+	if ok {
+		return dup, bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: dup, Delivery: msg.Delivery}, nil
+	}
+	l.working(ctx, msg, true)
+	defer l.working(ctx, msg, false)
+	userInput, err := media.PrepareInbound(ctx, l.llm, l.transcriber, l.cfg.Tools.Media, msg)
 	if err != nil {
 		return "", bus.OutboundMessage{}, err
 	}
@@ -200,16 +413,54 @@ func (l *Loop) processInbound(ctx context.Context, msg bus.InboundMessage) (stri
 	if sessionText == "" {
 		sessionText = strings.TrimSpace(msg.Content)
 	}
-	res, err := l.processDirect(ctx, userInput.UserMessage, sessionText, sessionKey, msg.Channel, msg.ChatID)
-	return res, bus.OutboundMessage{
+	var stream *replyStream
+	if !msg.Delivery.IsEdit {
+		// A corrected reply replaces the earlier answer in one edit.
+		stream = newReplyStream(ctx, l.cfg.Agents.Defaults.Streaming, l.bus, msg)
+	}
+	res, err := l.processDirect(ctx, userInput.UserMessage, sessionText, sessionKey, msg.Channel, msg.ChatID, msg.SenderID, msg.Delivery.MessageID, stream)
+	out := bus.OutboundMessage{
 		Channel:  msg.Channel,
 		ChatID:   msg.ChatID,
 		Content:  res,
 		Delivery: msg.Delivery,
-	}, err
+		StreamID: stream.id(),
+	}
+	if err == nil {
+		l.rememberAnswer(msg, questionVec, res)
+		out.Suggestions = suggestFollowUps(ctx, l.llm, l.cfg.Agents.Defaults.Suggestions, msg.Channel, sessionText, res)
+		l.replyWithVoice(ctx, msg, res)
+	}
+	return res, out, err
+}
+
+// forgetSender handles /forget-me: the sender can only erase their own data
+// on the channel the command came from.
+func (l *Loop) forgetSender(channel, senderID string, confirm bool) string {
+	if strings.TrimSpace(senderID) == "" {
+		return "error: sender is unknown on this channel"
+	}
+	rep, err := forget.Run(forget.Env{
+		Sessions:  l.sessions.Store,
+		Turns:     l.sessions.Store,
+		Workspace: l.workspace,
+		Cron:      l.cron,
+		Presence:  l.presence,
+	}, forget.Target{Sender: senderID, Channel: channel}, !confirm)
+	if confirm {
+		l.sessions.Invalidate()
+	}
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return forgetReply(rep)
 }
 
-func (l *Loop) processDirect(ctx context.Context, userMessage llm.Message, sessionUserText, sessionKey, channel, chatID string) (string, error) {
+// processDirect runs a turn. messageID is the chat app's ID of the message
+// being answered, if any. With stream set, the reply text is published as
+// it is generated.
+func (l *Loop) processDirect(ctx context.Context, userMessage llm.Message, sessionUserText, sessionKey, channel, chatID, senderID, messageID string, stream *replyStream) (_ string, err error) {
+	defer observeTurn(time.Now(), &err)
 	sess, err := l.sessions.GetOrCreate(sessionKey)
 	if err != nil {
 		return "", err
@@ -218,35 +469,79 @@ func (l *Loop) processDirect(ctx context.Context, userMessage llm.Message, sessi
 
 	history := sess.History(l.memoryWindow)
 	messages := make([]llm.Message, 0, 1+len(history)+1)
-	system := l.buildSystemPrompt(channel, chatID)
+	mem := memoryContext(ctx, l.cfg.Agents.Defaults.MemoryContext, l.workspace, l.memSearch, sessionUserText, l.verbose)
+	system := l.buildSystemPrompt(channel, chatID, mem)
+	system += changedFilesNote(l.watch, sess.UpdatedAt, l.cfg.Agents.Defaults.WorkspaceWatch.MaxFilesValue())
+	defer l.watch.Busy()()
 	messages = append(messages, llm.Message{Role: "system", Content: system})
 	for _, m := range history {
 		messages = append(messages, llm.Message{Role: m.Role, Content: m.Content})
 	}
 	messages = append(messages, userMessage)
 
-	toolsDefs := l.tools.Definitions()
+	denied := deniedTools(l.cfg, channel, senderID)
+	toolsDefs := l.tools.DefinitionsFor(denied)
+	if l.verbose {
+		logContextBudget(os.Stderr, sessionKey, measureContext(system, mem, messages[1:len(messages)-1], userMessage, toolsDefs, l.llm.MaxTokens))
+	}
+	skill := &tools.ActiveSkill{}
+	messages = injectSkillShortcut(ctx, l.tools, tools.Context{
+		Channel:    channel,
+		ChatID:     chatID,
+		SessionKey: sessionKey,
+		MessageID:  messageID,
+		Skill:      skill,
+		Denied:     denied,
+	}, messages)
+	rec := recordTurn(l.cfg.Agents.Defaults.Record, turns.Turn{
+		SessionKey: sessionKey,
+		Channel:    channel,
+		ChatID:     chatID,
+		Sender:     senderID,
+		Model:      l.model,
+		Messages:   messages,
+	})
 
 	var final string
+	var trace toolTrace
 	toolsUsed := make([]string, 0, 8)
+	srcs := &tools.Sources{}
+	edits := &tools.Edits{}
+	bufs := &tools.Buffers{}
+	sends := tools.NewTurnSends(turnKey(channel, chatID, messageID, sessionUserText))
+	failures := &tools.Failures{}
 	for iter := 0; iter < l.maxIters; iter++ {
-		res, err := l.llm.Chat(ctx, messages, toolsDefs)
+		res, err := l.chat(ctx, messages, toolsDefs, stream.onText())
+		rec.Response(res, err)
 		if err != nil {
+			saveTurn(l.cfg.Agents.Defaults.Record, l.sessions.Store, rec, "", err, l.verbose)
 			return "", err
 		}
+		addCitations(srcs, res.Citations)
 		if res.HasToolCalls() {
+			stream.reset()
 			for _, tc := range res.ToolCalls {
 				toolsUsed = append(toolsUsed, tc.Name)
 			}
 			messages = appendToolRound(messages, res.Content, res.ToolCalls, func(tc llm.ToolCall) string {
+				start := time.Now()
 				out, err := l.tools.Execute(ctx, tools.Context{
 					Channel:    channel,
 					ChatID:     chatID,
 					SessionKey: sessionKey,
+					MessageID:  messageID,
+					Sources:    srcs,
+					Edits:      edits,
+					Skill:      skill,
+					Denied:     denied,
+					Buffers:    bufs,
+					Sends:      sends,
 				}, tc.Name, tc.Arguments)
 				if err != nil {
-					return "error: " + err.Error()
+					out = failures.Record(tc.Name, err)
 				}
+				trace.add(tc, out, err != nil, time.Since(start))
+				rec.Tool(tc, out)
 				return out
 			})
 			continue
@@ -254,16 +549,35 @@ func (l *Loop) processDirect(ctx context.Context, userMessage llm.Message, sessi
 		final = res.Content
 		break
 	}
+	if strings.TrimSpace(final) != "" {
+		final = enforceLanguage(ctx, l.llm, l.cfg.Agents.Defaults.Language, channel, chatID, sessionUserText, final)
+		final = appendCitations(l.cfg.Agents.Defaults.Citations, channel, final, srcs)
+		final = appendDiffs(l.cfg.Tools.Diffs, final, edits)
+		final = l.post.Apply(channel, final)
+	}
+	final = failureReply(final, failures)
 	if strings.TrimSpace(final) == "" {
 		final = "(no response)"
 	}
 
-	sess.Add("user", sessionUserText)
-	sess.AddWithTools("assistant", final, toolsUsed)
+	turnID := saveTurn(l.cfg.Agents.Defaults.Record, l.sessions.Store, rec, final, nil, l.verbose)
+	sess.AddFromMessage(senderID, messageID, sessionUserText)
+	sess.AddReplyTrace("assistant", final, toolsUsed, turnID, trace)
 	_ = l.sessions.Save(sess)
 	return final, nil
 }
 
+// turnKey identifies a turn across runs: a message the bus delivers again
+// after a crash gets the same key, while an edit of it (same ID, new text)
+// does not. Turns not started by a chat message have none.
+func turnKey(channel, chatID, messageID, text string) string {
+	if strings.TrimSpace(messageID) == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(channel + "\x00" + chatID + "\x00" + messageID + "\x00" + text))
+	return hex.EncodeToString(sum[:12])
+}
+
 func (l *Loop) scheduleConsolidation(sessionKey string, sess *session.Session) {
 	if l == nil || sess == nil {
 		return
@@ -298,7 +612,7 @@ func (l *Loop) scheduleConsolidation(sessionKey string, sess *session.Session) {
 	}()
 }
 
-func (l *Loop) buildSystemPrompt(channel, chatID string) string {
+func (l *Loop) buildSystemPrompt(channel, chatID, mem string) string {
 	// Keep it simple and deterministic. Add progressive skill summary.
 	var b strings.Builder
 	b.WriteString("# clawlet\n\n")
@@ -307,7 +621,12 @@ func (l *Loop) buildSystemPrompt(channel, chatID string) string {
 	b.WriteString("IMPORTANT: When replying to the current conversation, respond with plain text. Do not call the message tool.\n")
 	b.WriteString("Only use the message tool when you must send to a different channel/chat_id.\n\n")
 	b.WriteString("## Current Time\n")
-	b.WriteString(time.Now().Format("2006-01-02 15:04 (Mon)") + "\n\n")
+	if loc := l.tools.ReminderLocation; loc != nil {
+		// The user's time, which reminders are read in.
+		b.WriteString(time.Now().In(loc).Format("2006-01-02 15:04 (Mon)") + " " + loc.String() + "\n\n")
+	} else {
+		b.WriteString(time.Now().Format("2006-01-02 15:04 (Mon)") + "\n\n")
+	}
 	b.WriteString("## Workspace\n")
 	b.WriteString(l.workspace + "\n\n")
 	if l.cfg.Tools.RestrictToWorkspaceValue() {
@@ -317,6 +636,11 @@ func (l *Loop) buildSystemPrompt(channel, chatID string) string {
 		b.WriteString("## Current Session\n")
 		b.WriteString("Channel: " + channel + "\nChat ID: " + chatID + "\n\n")
 	}
+	b.WriteString(languageInstruction(l.cfg.Agents.Defaults.Language, channel, chatID))
+	b.WriteString(toolFailureInstruction)
+	if l.tools.Journal != nil {
+		b.WriteString(journalInstruction)
+	}
 
 	// Bootstrap files from workspace (optional).
 	for _, fn := range []string{"AGENTS.md", "SOUL.md", "USER.md", "TOOLS.md", "IDENTITY.md"} {
@@ -331,8 +655,7 @@ func (l *Loop) buildSystemPrompt(channel, chatID string) string {
 		}
 	}
 
-	// Memory (long-term + today's notes)
-	mem := memory.New(l.workspace).GetContext()
+	// Memory (long-term + today's notes, or the relevant parts of them)
 	if strings.TrimSpace(mem) != "" {
 		b.WriteString("# Memory\n\n")
 		b.WriteString(mem)
@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/memory"
+)
+
+// memoryContext returns the memory for the system prompt: all of it, or with
+// memoryContext.mode "relevant" the parts that match the user's message.
+// Without memory search, or when the search fails, it falls back to all.
+func memoryContext(ctx context.Context, cfg config.MemoryContextConfig, workspace string, search *memory.IndexManager, query string, verbose bool) string {
+	store := memory.New(workspace)
+	if !cfg.Relevant() || search == nil {
+		return store.GetContext()
+	}
+	mem, err := store.RelevantContext(ctx, search, query, cfg.MaxTokensValue())
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "memory context error: %v\n", err)
+		}
+		return store.GetContext()
+	}
+	return mem
+}
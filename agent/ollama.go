@@ -0,0 +1,29 @@
+package agent
+
+import (
+	"log"
+
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+// logPullProgress logs an automatic Ollama model pull: each new status, and
+// layer downloads in 10% steps.
+func logPullProgress() func(llm.PullProgress) {
+	var status string
+	step := -1
+	return func(p llm.PullProgress) {
+		if p.Status != status {
+			status, step = p.Status, -1
+			if p.Total == 0 {
+				log.Printf("ollama: %s", p.Status)
+			}
+		}
+		if p.Total <= 0 {
+			return
+		}
+		if s := int(p.Completed * 10 / p.Total); s != step {
+			step = s
+			log.Printf("ollama: %s %d%% of %d MB", p.Status, s*10, p.Total>>20)
+		}
+	}
+}
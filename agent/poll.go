@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// recordPoll keeps the latest votes of a poll in the session as a user
+// note, replacing the note of the previous update, without replying.
+func (l *Loop) recordPoll(sessionKey string, msg bus.InboundMessage) error {
+	note := pollNote(msg.Poll)
+	if note == "" {
+		return nil
+	}
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return err
+	}
+	sess.ReplaceFromMessage(msg.SenderID, "poll:"+msg.Poll.ID, note)
+	return l.sessions.Save(sess)
+}
+
+// pollNote renders poll results like
+// `[Poll "Lunch?" (3 votes): Pizza 2, Sushi 1]`.
+func pollNote(p bus.Poll) string {
+	if strings.TrimSpace(p.ID) == "" || len(p.Options) == 0 {
+		return ""
+	}
+	total := 0
+	results := make([]string, len(p.Options))
+	for i, opt := range p.Options {
+		n := 0
+		if i < len(p.Votes) {
+			n = p.Votes[i]
+		}
+		total += n
+		results[i] = fmt.Sprintf("%s %d", strings.TrimSpace(opt), n)
+	}
+	state := "votes"
+	if total == 1 {
+		state = "vote"
+	}
+	if p.Closed {
+		state += ", closed"
+	}
+	return fmt.Sprintf("[Poll %q (%d %s): %s]", strings.TrimSpace(p.Question), total, state, strings.Join(results, ", "))
+}
@@ -0,0 +1,23 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+func TestPollNote(t *testing.T) {
+	tests := []struct {
+		p    bus.Poll
+		want string
+	}{
+		{bus.Poll{ID: "7", Question: "Lunch?", Options: []string{"Pizza", "Sushi"}, Votes: []int{2, 1}}, `[Poll "Lunch?" (3 votes): Pizza 2, Sushi 1]`},
+		{bus.Poll{ID: "7", Question: "Lunch?", Options: []string{"Pizza", "Sushi"}, Votes: []int{1}, Closed: true}, `[Poll "Lunch?" (1 vote, closed): Pizza 1, Sushi 0]`},
+		{bus.Poll{Question: "Lunch?", Options: []string{"Pizza"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := pollNote(tt.p); got != tt.want {
+			t.Fatalf("pollNote(%+v)=%q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// presenceCheckInterval is how often queued messages are released once the
+// agent is back.
+const presenceCheckInterval = time.Minute
+
+// holdWhileAway queues msg while the agent is away, unless it is urgent.
+// The reply is the away notice the first time a chat writes in an away
+// period, and empty after that.
+func (l *Loop) holdWhileAway(msg bus.InboundMessage, sessionKey string) (bus.OutboundMessage, bool, error) {
+	if l.presence == nil {
+		return bus.OutboundMessage{}, false, nil
+	}
+	away, notice := l.presence.Away(time.Now())
+	if !away || l.presence.Urgent(msg.Content) {
+		return bus.OutboundMessage{}, false, nil
+	}
+	msg.SessionKey = sessionKey
+	notify, err := l.presence.Hold(msg)
+	if err != nil || !notify {
+		return bus.OutboundMessage{}, true, err
+	}
+	return bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: notice, Delivery: msg.Delivery}, true, nil
+}
+
+// releaseWhenBack puts the messages queued while away back on the bus once
+// the agent is back.
+func (l *Loop) releaseWhenBack(ctx context.Context) {
+	t := time.NewTicker(presenceCheckInterval)
+	defer t.Stop()
+	for {
+		msgs, err := l.presence.Release(time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "presence queue error: %v\n", err)
+		}
+		for _, m := range msgs {
+			if strings.TrimSpace(m.Content) == "" && len(m.Attachments) == 0 {
+				continue
+			}
+			// The message waited for the agent rather than sitting in a
+			// chat app's backlog, so its age counts from its release and
+			// bus.maxAgeSec does not skip it.
+			m.Delivery.SentAt = time.Now()
+			if err := l.bus.PublishInbound(ctx, m); err != nil {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/presence"
+)
+
+func TestHoldWhileAway(t *testing.T) {
+	dir := t.TempDir()
+	manual := filepath.Join(dir, "presence.json")
+	tr, err := presence.New(config.PresenceConfig{Notice: "Back at 9."}, manual, filepath.Join(dir, "queue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &Loop{presence: tr}
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "lunch tomorrow?"}
+
+	if _, held, err := l.holdWhileAway(msg, "telegram:1"); held || err != nil {
+		t.Fatalf("present: held=%v err=%v", held, err)
+	}
+	if err := presence.SaveManual(manual, &presence.Manual{Away: true}); err != nil {
+		t.Fatal(err)
+	}
+	out, held, err := l.holdWhileAway(msg, "telegram:1")
+	if !held || err != nil || out.Content != "Back at 9." || out.ChatID != "1" {
+		t.Fatalf("first: held=%v err=%v out=%+v", held, err, out)
+	}
+	if out, held, _ := l.holdWhileAway(msg, "telegram:1"); !held || out.Content != "" {
+		t.Fatalf("second message should be held without a notice: %+v", out)
+	}
+	if _, held, _ := l.holdWhileAway(bus.InboundMessage{Content: "urgent: server down"}, "telegram:1"); held {
+		t.Fatal("urgent message was held")
+	}
+	if tr.Queued() != 2 {
+		t.Fatalf("queued=%d", tr.Queued())
+	}
+}
+
+func TestReleaseWhenBack_NotStale(t *testing.T) {
+	dir := t.TempDir()
+	manual := filepath.Join(dir, "presence.json")
+	tr, err := presence.New(config.PresenceConfig{Notice: "Back at 9."}, manual, filepath.Join(dir, "queue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bus.New(4)
+	var expired []bus.InboundMessage
+	b.SetMaxAge(time.Hour, func(m bus.InboundMessage) { expired = append(expired, m) })
+	l := &Loop{presence: tr, bus: b}
+	if err := presence.SaveManual(manual, &presence.Manual{Away: true}); err != nil {
+		t.Fatal(err)
+	}
+	// Held at the start of an away period that outlasts the max age.
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "lunch tomorrow?", Delivery: bus.Delivery{SentAt: time.Now().Add(-3 * time.Hour)}}
+	if _, held, err := l.holdWhileAway(msg, "telegram:1"); !held || err != nil {
+		t.Fatalf("held=%v err=%v", held, err)
+	}
+	if err := presence.SaveManual(manual, &presence.Manual{Away: false}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go l.releaseWhenBack(ctx)
+	got, err := b.ConsumeInbound(ctx)
+	if err != nil || got.Content != "lunch tomorrow?" {
+		t.Fatalf("got %+v %v", got, err)
+	}
+	if len(expired) != 0 {
+		t.Fatalf("released message skipped as stale: %+v", expired)
+	}
+}
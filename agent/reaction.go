@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// recordReaction adds an inbound reaction to the session as a short user
+// note, so the next turn sees it without the bot answering a bare emoji.
+func (l *Loop) recordReaction(sessionKey string, msg bus.InboundMessage) error {
+	note := reactionNote(msg.Reaction)
+	if note == "" {
+		return nil
+	}
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return err
+	}
+	sess.AddFrom(msg.SenderID, note)
+	return l.sessions.Save(sess)
+}
+
+// reactionNote renders a reaction like "[Reaction 👍 to message 42]".
+func reactionNote(r bus.Reaction) string {
+	emoji := strings.TrimSpace(r.Emoji)
+	if emoji == "" {
+		return ""
+	}
+	verb, prep := "Reaction", "to"
+	if r.Removed {
+		verb, prep = "Removed reaction", "from"
+	}
+	note := "[" + verb + " " + emoji
+	if id := strings.TrimSpace(r.MessageID); id != "" {
+		note += " " + prep + " message " + id
+	}
+	return note + "]"
+}
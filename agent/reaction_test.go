@@ -0,0 +1,24 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+func TestReactionNote(t *testing.T) {
+	tests := []struct {
+		r    bus.Reaction
+		want string
+	}{
+		{bus.Reaction{Emoji: "👍", MessageID: "42"}, "[Reaction 👍 to message 42]"},
+		{bus.Reaction{Emoji: "🎉", MessageID: "42", Removed: true}, "[Removed reaction 🎉 from message 42]"},
+		{bus.Reaction{Emoji: "❤️"}, "[Reaction ❤️]"},
+		{bus.Reaction{MessageID: "42"}, ""},
+	}
+	for _, tt := range tests {
+		if got := reactionNote(tt.r); got != tt.want {
+			t.Fatalf("reactionNote(%+v)=%q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/storage"
+	"github.com/mosaxiv/clawlet/turns"
+)
+
+// recordTurn starts a recording when turn recording is enabled; the nil
+// recorder it returns otherwise records nothing.
+func recordTurn(cfg config.RecordConfig, t turns.Turn) *turns.Recorder {
+	if !cfg.Enabled {
+		return nil
+	}
+	return turns.NewRecorder(t)
+}
+
+// saveTurn stores the finished recording and drops the oldest beyond the
+// configured limit. It returns the turn ID, or "" when nothing was saved.
+func saveTurn(cfg config.RecordConfig, st storage.Store, rec *turns.Recorder, reply string, err error, verbose bool) string {
+	t := rec.Finish(reply, err)
+	if t == nil || st == nil {
+		return ""
+	}
+	if err := turns.Save(st, t); err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "turn record error (%s): %v\n", t.ID, err)
+		}
+		return ""
+	}
+	if err := turns.Prune(st, cfg.MaxTurnsValue()); err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "turn prune error: %v\n", err)
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "turn: %s\n", t.ID)
+	}
+	return t.ID
+}
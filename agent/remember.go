@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/memory"
+	"github.com/mosaxiv/clawlet/session"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+// rememberCommand is a parsed "/remember [N] [#tag ...] [title]" message.
+type rememberCommand struct {
+	Last  int
+	Tags  []string
+	Title string
+}
+
+// parseRememberCommand recognises the /remember chat command. Telegram style
+// "/remember@botname" is accepted as well.
+func parseRememberCommand(text string) (rememberCommand, bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) == 0 {
+		return rememberCommand{}, false
+	}
+	cmd, _, _ := strings.Cut(fields[0], "@")
+	if !strings.EqualFold(cmd, "/remember") {
+		return rememberCommand{}, false
+	}
+	var rc rememberCommand
+	var title []string
+	for i, f := range fields[1:] {
+		if n, err := strconv.Atoi(f); err == nil && i == 0 && n > 0 {
+			rc.Last = n
+			continue
+		}
+		if strings.HasPrefix(f, "#") && len(f) > 1 {
+			rc.Tags = append(rc.Tags, f)
+			continue
+		}
+		title = append(title, f)
+	}
+	rc.Title = strings.Join(title, " ")
+	return rc, true
+}
+
+// runRememberCommand snapshots the session into a memory note without an LLM
+// round and returns the reply for the user.
+func runRememberCommand(workspace, sessionKey string, msgs []session.Message, rc rememberCommand) string {
+	note := memory.Note{
+		Title:      rc.Title,
+		Tags:       rc.Tags,
+		Source:     sessionKey,
+		Transcript: tools.NoteTranscript(msgs, rc.Last),
+	}
+	if len(note.Transcript) == 0 {
+		return "Nothing to remember yet: this conversation is empty."
+	}
+	path, err := memory.New(workspace).SaveNote(note, time.Now())
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return "Saved " + strconv.Itoa(len(note.Transcript)) + " messages to " + path
+}
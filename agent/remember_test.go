@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestParseRememberCommand(t *testing.T) {
+	cases := []struct {
+		in   string
+		ok   bool
+		want rememberCommand
+	}{
+		{in: "hello", ok: false},
+		{in: "/remembering", ok: false},
+		{in: "/remember", ok: true},
+		{in: "/remember@clawbot 10 #work #ideas Q3 roadmap", ok: true, want: rememberCommand{Last: 10, Tags: []string{"#work", "#ideas"}, Title: "Q3 roadmap"}},
+		{in: "/remember Top 5 books", ok: true, want: rememberCommand{Title: "Top 5 books"}},
+	}
+	for _, tc := range cases {
+		got, ok := parseRememberCommand(tc.in)
+		if ok != tc.ok || !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%q: got %+v,%v want %+v,%v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestRunRememberCommand(t *testing.T) {
+	ws := t.TempDir()
+	if got := runRememberCommand(ws, "cli:direct", nil, rememberCommand{}); !strings.Contains(got, "empty") {
+		t.Fatalf("got %q", got)
+	}
+	msgs := []session.Message{{Role: "user", Content: "a"}, {Role: "assistant", Content: "b"}}
+	got := runRememberCommand(ws, "cli:direct", msgs, rememberCommand{Title: "t"})
+	if !strings.HasPrefix(got, "Saved 2 messages to memory/notes/") {
+		t.Fatalf("got %q", got)
+	}
+}
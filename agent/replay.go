@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/tools"
+	"github.com/mosaxiv/clawlet/turns"
+)
+
+var errNotRecorded = errors.New("tool call not in the recording and live tools are off")
+
+// ReplayOptions selects what a replay takes from the recording.
+type ReplayOptions struct {
+	// Live asks the model again instead of using the recorded responses.
+	Live bool
+	// LiveTools runs tool calls the recording has no result for. Without it
+	// they fail, so a replay never has side effects.
+	LiveTools bool
+}
+
+// Replay re-runs a recorded turn from its recorded prompt and returns what
+// happened this time. Tool calls that match a recorded call get the recorded
+// output. The session is not changed, and Reply is the model's final answer
+// without post-processing.
+func (a *Agent) Replay(ctx context.Context, t *turns.Turn, opts ReplayOptions) (*turns.Turn, error) {
+	player := turns.NewPlayer(t)
+	chat := player.Chat
+	model := t.Model
+	if opts.Live {
+		chat = a.llm.Chat
+		model = a.llm.Model
+	}
+	messages := append([]llm.Message(nil), t.Messages...)
+	toolsDefs := a.tools.Definitions()
+	rec := turns.NewRecorder(turns.Turn{
+		ID:         t.ID,
+		SessionKey: t.SessionKey,
+		Channel:    t.Channel,
+		ChatID:     t.ChatID,
+		Sender:     t.Sender,
+		Model:      model,
+		Messages:   messages,
+	})
+
+	var final string
+	srcs := &tools.Sources{}
+	edits := &tools.Edits{}
+	bufs := &tools.Buffers{}
+	skill := &tools.ActiveSkill{}
+	failures := &tools.Failures{}
+	for iter := 0; iter < a.maxIters; iter++ {
+		res, err := chat(ctx, messages, toolsDefs)
+		rec.Response(res, err)
+		if err != nil {
+			return rec.Finish("", err), err
+		}
+		if res.HasToolCalls() {
+			messages = appendToolRound(messages, res.Content, res.ToolCalls, func(tc llm.ToolCall) string {
+				out, ok := player.Tool(tc.Name, tc.Arguments)
+				if !ok {
+					err := errNotRecorded
+					if opts.LiveTools {
+						out, err = a.tools.Execute(ctx, tools.Context{
+							Channel:    t.Channel,
+							ChatID:     t.ChatID,
+							SessionKey: t.SessionKey,
+							Sources:    srcs,
+							Edits:      edits,
+							Skill:      skill,
+							Buffers:    bufs,
+						}, tc.Name, tc.Arguments)
+					}
+					if err != nil {
+						out = failures.Record(tc.Name, err)
+					}
+				}
+				rec.Tool(tc, out)
+				return out
+			})
+			continue
+		}
+		final = res.Content
+		break
+	}
+	return rec.Finish(final, nil), nil
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/tools"
+	"github.com/mosaxiv/clawlet/turns"
+)
+
+func recordedTurn() *turns.Turn {
+	return &turns.Turn{
+		ID:       "t1",
+		Model:    "recorded-model",
+		Messages: []llm.Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "what is in notes.txt?"}},
+		Steps: []turns.Step{
+			{ToolCalls: []turns.ToolCall{{ID: "c1", Name: "read_file", Arguments: json.RawMessage(`{"path":"notes.txt"}`), Output: "buy milk"}}},
+			{Content: "It says: buy milk"},
+		},
+	}
+}
+
+func TestReplay_Recorded(t *testing.T) {
+	a := &Agent{maxIters: 5, llm: &llm.Client{}, tools: &tools.Registry{WorkspaceDir: t.TempDir()}}
+	got, err := a.Replay(context.Background(), recordedTurn(), ReplayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// notes.txt does not exist in the workspace; the recorded output is used.
+	if got.FinalContent() != "It says: buy milk" || got.Steps[0].ToolCalls[0].Output != "buy milk" || got.Model != "recorded-model" {
+		t.Fatalf("replay=%+v", got)
+	}
+}
+
+func TestReplay_LiveModelWithoutLiveTools(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[
+				{"id":"x1","type":"function","function":{"name":"read_file","arguments":"{\"path\": \"notes.txt\"}"}},
+				{"id":"x2","type":"function","function":{"name":"read_file","arguments":"{\"path\":\"secret.txt\"}"}}]}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"done"}}]}`))
+	}))
+	defer srv.Close()
+
+	a := &Agent{
+		maxIters: 5,
+		llm:      &llm.Client{Provider: "openai", BaseURL: srv.URL, Model: "live-model"},
+		tools:    &tools.Registry{WorkspaceDir: t.TempDir()},
+	}
+	got, err := a.Replay(context.Background(), recordedTurn(), ReplayOptions{Live: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := got.Steps[0].ToolCalls
+	if len(tc) != 2 || tc[0].Output != "buy milk" || !strings.Contains(tc[1].Output, "not in the recording") {
+		t.Fatalf("tool calls=%+v", tc)
+	}
+	if got.FinalContent() != "done" || got.Model != "live-model" {
+		t.Fatalf("replay=%+v", got)
+	}
+}
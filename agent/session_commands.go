@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseSessionCommand recognises the bare "/reset", "/status" and "/trace"
+// commands ("/status@botname" as well) and returns the name without the
+// slash.
+func parseSessionCommand(text string) (string, bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) != 1 {
+		return "", false
+	}
+	cmd, _, _ := strings.Cut(fields[0], "@")
+	switch cmd = strings.ToLower(cmd); cmd {
+	case "/reset", "/status", "/trace":
+		return cmd[1:], true
+	}
+	return "", false
+}
+
+// runSessionCommand handles /reset, which empties the conversation history
+// (memory notes are kept), and /status.
+func (l *Loop) runSessionCommand(name, sessionKey string) string {
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	if name == "reset" {
+		sess.Clear()
+		if err := l.sessions.Save(sess); err != nil {
+			return "error: " + err.Error()
+		}
+		return "Started a new conversation. Memory notes are kept."
+	}
+	status := fmt.Sprintf("Model: %s\nConversation: %d messages (the model sees the last %d)", l.model, len(sess.History(0)), l.memoryWindow)
+	if label, ok := sess.Meta("fork_label"); ok {
+		status += fmt.Sprintf("\nFork: %v", label)
+	}
+	if isPaused(sess) {
+		status += "\nPaused: a human has taken this chat over (/resume hands it back)"
+	}
+	return status
+}
@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestParseSessionCommand(t *testing.T) {
+	for in, want := range map[string]string{"/reset": "reset", "/STATUS@clawbot": "status", "/trace": "trace", "/reset now": "", "/resets": "", "reset": ""} {
+		got, ok := parseSessionCommand(in)
+		if got != want || ok != (want != "") {
+			t.Fatalf("%q: got %q,%v", in, got, ok)
+		}
+	}
+}
+
+func TestRunSessionCommand(t *testing.T) {
+	l := &Loop{sessions: session.NewManager(t.TempDir()), model: "gpt-test", memoryWindow: 50}
+	sess, _ := l.sessions.GetOrCreate("discord:1")
+	sess.AddFrom("u", "hi")
+	sess.Add("assistant", "hello")
+
+	if got := l.runSessionCommand("status", "discord:1"); !strings.Contains(got, "gpt-test") || !strings.Contains(got, "2 messages") {
+		t.Fatalf("status=%q", got)
+	}
+	l.runSessionCommand("reset", "discord:1")
+	l.sessions.Invalidate()
+	sess, _ = l.sessions.GetOrCreate("discord:1")
+	if n := len(sess.History(0)); n != 0 {
+		t.Fatalf("history after reset: %d", n)
+	}
+}
@@ -1,22 +1,48 @@
 package agent
 
 import (
+	"fmt"
+
 	"github.com/mosaxiv/clawlet/config"
 	"github.com/mosaxiv/clawlet/tools"
 )
 
-func buildSkillRegistry(cfg *config.Config) (tools.SkillRegistry, int) {
+func buildSkillRegistry(cfg *config.Config) (tools.SkillRegistry, int, error) {
 	if cfg == nil || !cfg.Tools.Skills.EnabledValue() {
-		return nil, 0
+		return nil, 0, nil
+	}
+	sc := cfg.Tools.Skills
+	multi := tools.NewMultiSkillRegistry()
+	if sc.Registry.EnabledValue() {
+		_ = multi.Add("clawhub", tools.NewClawHubRegistry(tools.ClawHubRegistryConfig{
+			BaseURL:          sc.Registry.BaseURL,
+			AuthToken:        sc.Registry.AuthToken,
+			SearchPath:       sc.Registry.SearchPath,
+			SkillsPath:       sc.Registry.SkillsPath,
+			DownloadPath:     sc.Registry.DownloadPath,
+			TimeoutSec:       sc.Registry.TimeoutSec,
+			MaxZipBytes:      sc.Registry.MaxZipBytes,
+			MaxResponseBytes: sc.Registry.MaxResponseBytes,
+		}))
+	}
+	for i, idx := range sc.Indexes {
+		if idx.URL == "" {
+			return nil, 0, fmt.Errorf("tools.skills.indexes[%d]: url is required", i)
+		}
+		reg := tools.NewIndexRegistry(tools.IndexRegistryConfig{
+			Name:             idx.Name,
+			URL:              idx.URL,
+			AuthToken:        idx.AuthToken,
+			TimeoutSec:       sc.Registry.TimeoutSec,
+			MaxZipBytes:      sc.Registry.MaxZipBytes,
+			MaxResponseBytes: sc.Registry.MaxResponseBytes,
+		})
+		if err := multi.Add(idx.Name, reg); err != nil {
+			return nil, 0, fmt.Errorf("tools.skills.indexes[%d]: %w", i, err)
+		}
+	}
+	if multi.Len() == 0 {
+		return nil, 0, nil
 	}
-	return tools.NewClawHubRegistry(tools.ClawHubRegistryConfig{
-		BaseURL:          cfg.Tools.Skills.Registry.BaseURL,
-		AuthToken:        cfg.Tools.Skills.Registry.AuthToken,
-		SearchPath:       cfg.Tools.Skills.Registry.SearchPath,
-		SkillsPath:       cfg.Tools.Skills.Registry.SkillsPath,
-		DownloadPath:     cfg.Tools.Skills.Registry.DownloadPath,
-		TimeoutSec:       cfg.Tools.Skills.Registry.TimeoutSec,
-		MaxZipBytes:      cfg.Tools.Skills.Registry.MaxZipBytes,
-		MaxResponseBytes: cfg.Tools.Skills.Registry.MaxResponseBytes,
-	}), cfg.Tools.Skills.MaxResults
+	return multi, sc.MaxResults, nil
 }
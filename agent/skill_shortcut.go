@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+// skillShortcutRe matches a message starting with "!<skill>", e.g.
+// "!github list my PRs".
+var skillShortcutRe = regexp.MustCompile(`^!([A-Za-z0-9][A-Za-z0-9._-]*)(?:\s|$)`)
+
+// skillShortcutCallID is the tool call ID of an injected read_skill round.
+const skillShortcutCallID = "skill_shortcut"
+
+func parseSkillShortcut(text string) (string, bool) {
+	m := skillShortcutRe.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// injectSkillShortcut loads the skill named by a "!<skill>" message before
+// the turn: it appends a read_skill call and its result after the user
+// message, as if the model had made it, so the skill's instructions are
+// always in context. Messages naming no known skill are left alone.
+func injectSkillShortcut(ctx context.Context, treg *tools.Registry, tctx tools.Context, messages []llm.Message) []llm.Message {
+	if len(messages) == 0 {
+		return messages
+	}
+	name, ok := parseSkillShortcut(messages[len(messages)-1].Content)
+	if !ok {
+		return messages
+	}
+	args, _ := json.Marshal(map[string]string{"name": name})
+	out, err := treg.Execute(ctx, tctx, "read_skill", args)
+	if err != nil {
+		return messages
+	}
+	tool := treg.ExposedName("read_skill")
+	return append(messages,
+		llm.Message{Role: "assistant", ToolCalls: []llm.ToolCallPayload{{
+			ID:       skillShortcutCallID,
+			Type:     "function",
+			Function: llm.ToolCallPayloadFunc{Name: tool, Arguments: string(args)},
+		}}},
+		llm.Message{Role: "tool", ToolCallID: skillShortcutCallID, Name: tool, Content: out},
+	)
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+func TestParseSkillShortcut(t *testing.T) {
+	for in, want := range map[string]string{
+		"!github list my PRs": "github",
+		"  !web-search":       "web-search",
+		"!github\nlist":       "github",
+		"github":              "",
+		"! github":            "",
+		"!!github":            "",
+		"hello !github":       "",
+	} {
+		got, ok := parseSkillShortcut(in)
+		if got != want || ok != (want != "") {
+			t.Fatalf("parseSkillShortcut(%q) = %q, %v", in, got, ok)
+		}
+	}
+}
+
+func TestInjectSkillShortcut(t *testing.T) {
+	treg := &tools.Registry{
+		WorkspaceDir: t.TempDir(),
+		Renames:      map[string]string{"read_skill": "load_skill"},
+		ReadSkill: func(name string) (string, bool) {
+			return "# GitHub skill", name == "github"
+		},
+	}
+	base := []llm.Message{{Role: "system", Content: "sys"}}
+	skill := &tools.ActiveSkill{}
+
+	msgs := injectSkillShortcut(context.Background(), treg, tools.Context{Skill: skill}, append(base, llm.Message{Role: "user", Content: "!github list my PRs"}))
+	if len(msgs) != 4 {
+		t.Fatalf("messages=%+v", msgs)
+	}
+	call, result := msgs[2], msgs[3]
+	if len(call.ToolCalls) != 1 || call.ToolCalls[0].Function.Name != "load_skill" || call.ToolCalls[0].Function.Arguments != `{"name":"github"}` {
+		t.Fatalf("call=%+v", call)
+	}
+	if result.Role != "tool" || result.ToolCallID != call.ToolCalls[0].ID || !strings.HasPrefix(result.Content, "# GitHub skill") {
+		t.Fatalf("result=%+v", result)
+	}
+	if skill.Name() != "github" {
+		t.Fatalf("active skill = %q", skill.Name())
+	}
+
+	for _, text := range []string{"!unknown do it", "list my PRs"} {
+		if msgs := injectSkillShortcut(context.Background(), treg, tools.Context{}, append(base, llm.Message{Role: "user", Content: text})); len(msgs) != 2 {
+			t.Fatalf("%q injected: %+v", text, msgs)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/metrics"
+)
+
+// chat is l.llm.ChatStream, counted toward the provider's error budget.
+// A call cut short by ctx is not the provider's fault and is not counted.
+func (l *Loop) chat(ctx context.Context, messages []llm.Message, defs []llm.ToolDefinition, onText func(string)) (*llm.ChatResult, error) {
+	res, err := l.llm.ChatStream(ctx, messages, defs, onText)
+	if ctx.Err() == nil {
+		metrics.RecordLLMCall(providerName(l.llm.Provider), err)
+	}
+	return res, err
+}
+
+// observeTurn counts a turn that started at start toward the reply SLOs;
+// err points at the turn's result.
+func observeTurn(start time.Time, err *error) {
+	metrics.RecordTurn(time.Since(start), *err == nil)
+}
+
+func providerName(p string) string {
+	switch p = strings.ToLower(strings.TrimSpace(p)); p {
+	case "":
+		return "openai"
+	case "local":
+		return "ollama"
+	default:
+		return p
+	}
+}
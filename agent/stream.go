@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// replyStream publishes the text of a reply while the model generates it,
+// as partial outbound messages at most interval apart. The final reply is
+// sent with the same StreamID.
+type replyStream struct {
+	ctx      context.Context
+	bus      *bus.Bus
+	msg      bus.OutboundMessage
+	interval time.Duration
+	text     strings.Builder
+	last     time.Time
+}
+
+// newReplyStream returns nil when streaming is off for the channel of in.
+func newReplyStream(ctx context.Context, cfg config.StreamingConfig, b *bus.Bus, in bus.InboundMessage) *replyStream {
+	if b == nil || !cfg.EnabledFor(in.Channel) {
+		return nil
+	}
+	return &replyStream{
+		ctx: ctx,
+		bus: b,
+		msg: bus.OutboundMessage{
+			Channel:  in.Channel,
+			ChatID:   in.ChatID,
+			Delivery: in.Delivery,
+			StreamID: randID(),
+			Partial:  true,
+		},
+		interval: time.Duration(cfg.IntervalMsValue()) * time.Millisecond,
+	}
+}
+
+// onText is the callback for llm.Client.ChatStream; nil when not streaming.
+func (s *replyStream) onText() func(string) {
+	if s == nil {
+		return nil
+	}
+	return s.add
+}
+
+func (s *replyStream) add(piece string) {
+	s.text.WriteString(piece)
+	if time.Since(s.last) < s.interval || strings.TrimSpace(s.text.String()) == "" {
+		return
+	}
+	s.last = time.Now()
+	m := s.msg
+	m.Content = s.text.String()
+	_ = s.bus.PublishOutbound(s.ctx, m)
+}
+
+// reset starts over for the next model round: what a round that ended in
+// tool calls said is replaced by the next round's text.
+func (s *replyStream) reset() {
+	if s != nil {
+		s.text.Reset()
+	}
+}
+
+func (s *replyStream) id() string {
+	if s == nil {
+		return ""
+	}
+	return s.msg.StreamID
+}
@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestReplyStream(t *testing.T) {
+	in := bus.InboundMessage{Channel: "telegram", ChatID: "42"}
+	if s := newReplyStream(context.Background(), config.StreamingConfig{}, bus.New(4), in); s != nil || s.onText() != nil || s.id() != "" {
+		t.Fatal("streaming should be off by default")
+	}
+
+	b := bus.New(8)
+	cfg := config.StreamingConfig{Enabled: true, IntervalMs: 60000}
+	s := newReplyStream(context.Background(), cfg, b, in)
+	add := s.onText()
+	add("  ")
+	add("Hel")
+	add("lo") // within the interval: not published
+	s.reset()
+	s.last = s.last.AddDate(0, 0, -1)
+	add("Done")
+
+	for _, want := range []string{"  Hel", "Done"} {
+		msg, err := b.ConsumeOutbound(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Content != want || !msg.Partial || msg.StreamID != s.id() || msg.ChatID != "42" {
+			t.Fatalf("got %+v, want content %q", msg, want)
+		}
+	}
+}
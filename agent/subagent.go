@@ -64,13 +64,17 @@ func (m *SubagentManager) runSubagent(ctx context.Context, task string) (string,
 	if l == nil || l.llm == nil || l.cfg == nil {
 		return "", fmt.Errorf("subagent loop not configured")
 	}
+	defer l.watch.Busy()()
 
 	// Subagent tools: a restricted subset (no message, no spawn, no cron).
 	treg := &tools.Registry{
 		WorkspaceDir:        l.workspace,
 		RestrictToWorkspace: l.cfg.Tools.RestrictToWorkspaceValue(),
 		ExecTimeout:         l.tools.ExecTimeout,
+		DiffMaxLines:        l.tools.DiffMaxLines,
 		BraveAPIKey:         l.tools.BraveAPIKey,
+		Aliases:             l.tools.Aliases,
+		Renames:             l.tools.Renames,
 		AllowTools: []string{
 			"read_file",
 			"write_file",
@@ -91,8 +95,10 @@ func (m *SubagentManager) runSubagent(ctx context.Context, task string) (string,
 
 	const maxIters = 15
 	var final string
+	failures := &tools.Failures{}
+	bufs := &tools.Buffers{}
 	for range maxIters {
-		res, err := l.llm.Chat(ctx, messages, toolsDefs)
+		res, err := l.chat(ctx, messages, toolsDefs, nil)
 		if err != nil {
 			return "", err
 		}
@@ -102,9 +108,10 @@ func (m *SubagentManager) runSubagent(ctx context.Context, task string) (string,
 					Channel:    "cli",
 					ChatID:     "subagent",
 					SessionKey: "",
+					Buffers:    bufs,
 				}, tc.Name, tc.Arguments)
 				if err != nil {
-					return "error: " + err.Error()
+					return failures.Record(tc.Name, err)
 				}
 				return out
 			})
@@ -113,6 +120,7 @@ func (m *SubagentManager) runSubagent(ctx context.Context, task string) (string,
 		final = res.Content
 		break
 	}
+	final = failureReply(final, failures)
 	if strings.TrimSpace(final) == "" {
 		final = "(no response)"
 	}
@@ -132,6 +140,7 @@ You are a subagent spawned by the main agent to complete a specific task.
 2. Do not initiate conversations or take on side tasks
 3. Be concise but informative
 4. Do not use tools that are not available
+5. If a tool fails, retry only when its failure says retryable; otherwise report what failed and why
 
 ## Workspace
 %s
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+// suggestionChannels render OutboundMessage.Suggestions.
+var suggestionChannels = map[string]bool{"telegram": true, "slack": true, "whatsapp": true, "grpc": true, "cli": true, "instagram": true}
+
+// maxSuggestionRunes keeps suggestions within Slack's button label limit.
+const maxSuggestionRunes = 75
+
+// suggestFollowUps asks the model for short follow-up questions the user
+// might send next. Any error yields no suggestions.
+func suggestFollowUps(ctx context.Context, c *llm.Client, policy config.SuggestionsConfig, channel, userText, reply string) []string {
+	if c == nil || !suggestionChannels[config.ChannelType(channel)] || !policy.EnabledFor(channel) {
+		return nil
+	}
+	if strings.TrimSpace(userText) == "" || strings.TrimSpace(reply) == "" {
+		return nil
+	}
+	n := policy.MaxValue()
+	system := "Suggest up to " + strconv.Itoa(n) + " short follow-up messages the user is likely to send next, written from the user's point of view in the language of the conversation. " +
+		"Each must be under " + strconv.Itoa(maxSuggestionRunes) + " characters. Output only a JSON array of strings; output [] if no follow-up is useful."
+	res, err := c.Chat(ctx, []llm.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: "<user_message>\n" + userText + "\n</user_message>\n<assistant_reply>\n" + reply + "\n</assistant_reply>"},
+	}, nil)
+	if err != nil {
+		return nil
+	}
+	return parseSuggestions(res.Content, n)
+}
+
+// parseSuggestions reads a JSON array (optionally wrapped in prose or a code
+// fence), falling back to one suggestion per line.
+func parseSuggestions(s string, max int) []string {
+	var items []string
+	if i, j := strings.Index(s, "["), strings.LastIndex(s, "]"); i >= 0 && j > i {
+		_ = json.Unmarshal([]byte(s[i:j+1]), &items)
+	}
+	if items == nil {
+		for _, line := range strings.Split(s, "\n") {
+			line = strings.TrimLeft(strings.TrimSpace(line), "-*•0123456789.) ")
+			if line != "" && !strings.HasPrefix(line, "```") {
+				items = append(items, line)
+			}
+		}
+	}
+	var out []string
+	seen := map[string]bool{}
+	for _, it := range items {
+		it = strings.Trim(strings.TrimSpace(it), `"`)
+		key := strings.ToLower(it)
+		if it == "" || seen[key] || utf8.RuneCountInString(it) > maxSuggestionRunes {
+			continue
+		}
+		seen[key] = true
+		out = append(out, it)
+		if len(out) == max {
+			break
+		}
+	}
+	return out
+}
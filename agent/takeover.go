@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+// pausedMeta is the session metadata set while a human has taken the chat
+// over.
+const pausedMeta = "paused"
+
+type takeoverCommand struct {
+	pause  bool
+	target string // session key; empty for the chat the command came from
+}
+
+// parseTakeoverCommand recognises "/pause [session]" and "/resume [session]",
+// where session is a key like "telegram:123456789".
+func parseTakeoverCommand(text string) (takeoverCommand, bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) == 0 || len(fields) > 2 {
+		return takeoverCommand{}, false
+	}
+	cmd, _, _ := strings.Cut(fields[0], "@")
+	var tc takeoverCommand
+	switch strings.ToLower(cmd) {
+	case "/pause":
+		tc.pause = true
+	case "/resume":
+	default:
+		return takeoverCommand{}, false
+	}
+	if len(fields) == 2 {
+		tc.target = fields[1]
+	}
+	return tc, true
+}
+
+func (l *Loop) runTakeoverCommand(msg bus.InboundMessage, sessionKey string, tc takeoverCommand) string {
+	if !isAdmin(l.cfg, msg.Channel, msg.SenderID) {
+		return "Only admins can pause the agent in a chat."
+	}
+	target := tc.target
+	if target == "" {
+		target = sessionKey
+	}
+	if err := l.SetPaused(target, tc.pause); err != nil {
+		return "error: " + err.Error()
+	}
+	if tc.pause {
+		return fmt.Sprintf("Paused the agent in %s. Messages there are still logged; /resume %s hands the chat back.", target, target)
+	}
+	return fmt.Sprintf("The agent answers in %s again.", target)
+}
+
+// SetPaused pauses the agent's replies in the session sessionKey, so a human
+// can take the chat over, or resumes them. Messages that arrive while paused
+// are added to the session without a reply.
+func (l *Loop) SetPaused(sessionKey string, paused bool) error {
+	sessionKey = strings.TrimSpace(sessionKey)
+	if sessionKey == "" {
+		return fmt.Errorf("session is required")
+	}
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return err
+	}
+	var v any
+	if paused {
+		v = true
+	}
+	sess.SetMeta(pausedMeta, v)
+	return l.sessions.Save(sess)
+}
+
+func isPaused(sess *session.Session) bool {
+	v, _ := sess.Meta(pausedMeta)
+	paused, _ := v.(bool)
+	return paused
+}
+
+// logWhilePaused adds msg to the session so the agent has the whole
+// conversation once it is resumed.
+func (l *Loop) logWhilePaused(sessionKey string, msg bus.InboundMessage) error {
+	content := strings.TrimSpace(strings.Join([]string{msg.Content, bus.AttachmentNote(msg.Attachments)}, "\n"))
+	if content == "" {
+		return nil
+	}
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return err
+	}
+	sess.AddFromMessage(msg.SenderID, msg.Delivery.MessageID, content)
+	return l.sessions.Save(sess)
+}
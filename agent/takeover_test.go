@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestParseTakeoverCommand(t *testing.T) {
+	for in, want := range map[string]*takeoverCommand{
+		"/pause":                    {pause: true},
+		"/Resume@clawbot":           {},
+		"/pause telegram:42":        {pause: true, target: "telegram:42"},
+		"/pause telegram:42 please": nil,
+		"/paused":                   nil,
+	} {
+		got, ok := parseTakeoverCommand(in)
+		if ok != (want != nil) || (ok && got != *want) {
+			t.Fatalf("%q: got %+v,%v", in, got, ok)
+		}
+	}
+}
+
+func TestTakeover_PauseLogsAndResumes(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Takeover.Owners = []string{"telegram:1"}
+	l := &Loop{cfg: cfg, sessions: session.NewManager(t.TempDir())}
+	owner := bus.InboundMessage{Channel: "telegram", SenderID: "1|alice", ChatID: "1"}
+
+	if got := l.runTakeoverCommand(bus.InboundMessage{Channel: "telegram", SenderID: "2"}, "telegram:2", takeoverCommand{pause: true}); !strings.Contains(got, "Only admins") {
+		t.Fatalf("non-owner: %q", got)
+	}
+	if got := l.runTakeoverCommand(owner, "telegram:1", takeoverCommand{pause: true, target: "telegram:2"}); !strings.Contains(got, "Paused") {
+		t.Fatalf("pause: %q", got)
+	}
+	sess, _ := l.sessions.GetOrCreate("telegram:2")
+	if !isPaused(sess) {
+		t.Fatal("session not paused")
+	}
+	if err := l.logWhilePaused("telegram:2", bus.InboundMessage{SenderID: "2", Content: "is anyone there?"}); err != nil {
+		t.Fatal(err)
+	}
+	if h := sess.History(0); len(h) != 1 || !strings.Contains(h[0].Content, "is anyone there?") {
+		t.Fatalf("history %+v", h)
+	}
+
+	l.runTakeoverCommand(owner, "telegram:1", takeoverCommand{target: "telegram:2"})
+	l.sessions.Invalidate()
+	sess, _ = l.sessions.GetOrCreate("telegram:2")
+	if isPaused(sess) {
+		t.Fatal("session still paused after resume")
+	}
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+// traceFieldMax bounds the arguments and result kept per traced tool call,
+// so the session transcript stays small.
+const traceFieldMax = 600
+
+// toolTrace collects the tool calls of a turn for the session transcript.
+type toolTrace []session.ToolTrace
+
+func (t *toolTrace) add(tc llm.ToolCall, out string, failed bool, took time.Duration) {
+	*t = append(*t, session.ToolTrace{
+		Name:       tc.Name,
+		Arguments:  truncateRunes(strings.TrimSpace(string(tc.Arguments)), traceFieldMax),
+		Result:     truncateRunes(strings.TrimSpace(out), traceFieldMax),
+		Failed:     failed,
+		DurationMs: took.Milliseconds(),
+	})
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+// runTraceCommand handles /trace: a summary of the tool calls behind the
+// last reply, with the arguments and results attached as trace.md.
+func (l *Loop) runTraceCommand(msg bus.InboundMessage, sessionKey string) (string, []bus.Attachment) {
+	if !l.cfg.Agents.Defaults.Trace.Public && !isAdmin(l.cfg, msg.Channel, msg.SenderID) {
+		return "error: /trace is limited to the admins in channels.access.admins", nil
+	}
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return "error: " + err.Error(), nil
+	}
+	history := sess.History(0)
+	for i := len(history) - 1; i >= 0; i-- {
+		if m := history[i]; m.Role == "assistant" {
+			if len(m.Trace) == 0 {
+				return "The last reply used no tools.", nil
+			}
+			summary, detail := formatTrace(m.Trace)
+			return summary, []bus.Attachment{{
+				Name:      "trace.md",
+				MIMEType:  "text/markdown",
+				Kind:      "file",
+				Data:      []byte(detail),
+				SizeBytes: int64(len(detail)),
+			}}
+		}
+	}
+	return "There is no reply to trace yet.", nil
+}
+
+// formatTrace returns a line per call and the full trace as Markdown.
+func formatTrace(trace []session.ToolTrace) (summary, detail string) {
+	var s, d strings.Builder
+	fmt.Fprintf(&s, "Last reply: %d tool calls", len(trace))
+	d.WriteString("# Tool calls\n")
+	for i, t := range trace {
+		head := fmt.Sprintf("%d. %s (%s)", i+1, t.Name, time.Duration(t.DurationMs)*time.Millisecond)
+		if t.Failed {
+			head += " failed"
+		}
+		s.WriteString("\n" + head)
+		fmt.Fprintf(&d, "\n## %s\n\nArguments:\n\n```json\n%s\n```\n\nResult:\n\n```\n%s\n```\n", head, t.Arguments, t.Result)
+	}
+	return s.String(), d.String()
+}
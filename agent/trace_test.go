@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestRunTraceCommand(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Trace.Owners = []string{"telegram:1"}
+	l := &Loop{cfg: cfg, sessions: session.NewManager(t.TempDir())}
+	sess, _ := l.sessions.GetOrCreate("telegram:9")
+
+	var trace toolTrace
+	trace.add(llm.ToolCall{Name: "web_fetch", Arguments: []byte(`{"url":"https://example.com"}`)}, strings.Repeat("x", 1000), false, 1200*time.Millisecond)
+	trace.add(llm.ToolCall{Name: "write_file", Arguments: []byte(`{"path":"a.txt"}`)}, "error: denied", true, 3*time.Millisecond)
+	sess.AddFrom("1", "fetch it")
+	sess.AddReplyTrace("assistant", "done", []string{"web_fetch", "write_file"}, "", trace)
+
+	owner := bus.InboundMessage{Channel: "telegram", SenderID: "1|alice"}
+	got, atts := l.runTraceCommand(owner, "telegram:9")
+	if got != "Last reply: 2 tool calls\n1. web_fetch (1.2s)\n2. write_file (3ms) failed" {
+		t.Fatalf("summary=%q", got)
+	}
+	if len(atts) != 1 || !strings.Contains(string(atts[0].Data), `{"url":"https://example.com"}`) || !strings.Contains(string(atts[0].Data), strings.Repeat("x", traceFieldMax)+"…") {
+		t.Fatalf("attachment=%+v", atts)
+	}
+
+	if got, atts := l.runTraceCommand(bus.InboundMessage{Channel: "telegram", SenderID: "2"}, "telegram:9"); !strings.HasPrefix(got, "error:") || atts != nil {
+		t.Fatalf("non-owner got %q", got)
+	}
+
+	// The trace survives a save and reload.
+	if err := l.sessions.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	l.sessions.Invalidate()
+	if got, _ := l.runTraceCommand(owner, "telegram:9"); !strings.Contains(got, "write_file") {
+		t.Fatalf("after reload: %q", got)
+	}
+}
+
+func TestRunTraceCommand_Admins(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Channels.Access.Admins = []string{"telegram:1"}
+	l := &Loop{cfg: cfg, sessions: session.NewManager(t.TempDir())}
+	if got, _ := l.runTraceCommand(bus.InboundMessage{Channel: "telegram", SenderID: "1"}, "telegram:1"); strings.HasPrefix(got, "error:") {
+		t.Fatalf("admin got %q", got)
+	}
+	// The local cli sender is not an admin unless listed.
+	if got, _ := l.runTraceCommand(bus.InboundMessage{Channel: "cli", SenderID: "local"}, "cli:direct"); !strings.HasPrefix(got, "error:") {
+		t.Fatalf("cli got %q", got)
+	}
+	cfg.Agents.Defaults.Trace.Public = true
+	if got, _ := l.runTraceCommand(bus.InboundMessage{Channel: "cli", SenderID: "local"}, "cli:direct"); strings.HasPrefix(got, "error:") {
+		t.Fatalf("public: cli got %q", got)
+	}
+}
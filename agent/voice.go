@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/media"
+)
+
+// replyWithVoice answers a voice message with a voice note of the reply,
+// sent ahead of the text. A failure only costs the voice note.
+func (l *Loop) replyWithVoice(ctx context.Context, msg bus.InboundMessage, reply string) {
+	cfg := l.cfg.Tools.Media.Speech
+	if l.speaker == nil || !cfg.RepliesValue() || !hasVoice(msg.Attachments) {
+		return
+	}
+	text := media.SpeechText(reply, cfg.MaxCharsValue())
+	if text == "" {
+		return
+	}
+	voice, err := l.speaker.Speak(ctx, text)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "voice reply error (%s): %v\n", msg.SessionKey, err)
+		return
+	}
+	_ = l.bus.PublishOutbound(ctx, bus.OutboundMessage{
+		Channel:     msg.Channel,
+		ChatID:      msg.ChatID,
+		Delivery:    msg.Delivery,
+		Attachments: []bus.Attachment{voice},
+	})
+}
+
+func hasVoice(attachments []bus.Attachment) bool {
+	for _, a := range attachments {
+		if a.Kind == "audio" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+type fakeSpeaker struct{ spoken []string }
+
+func (f *fakeSpeaker) Speak(ctx context.Context, text string) (bus.Attachment, error) {
+	f.spoken = append(f.spoken, text)
+	return bus.Attachment{Name: "voice.ogg", MIMEType: "audio/ogg", Kind: "audio", Data: []byte("OggS")}, nil
+}
+
+func TestReplyWithVoice(t *testing.T) {
+	speaker := &fakeSpeaker{}
+	l := &Loop{cfg: config.Default(), bus: bus.New(4), speaker: speaker}
+	voice := bus.InboundMessage{Channel: "telegram", ChatID: "1", Attachments: []bus.Attachment{{Kind: "audio"}}, Delivery: bus.Delivery{MessageID: "7"}}
+
+	l.replyWithVoice(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "hi"}, "Hello!")
+	l.replyWithVoice(context.Background(), voice, "**Hello!**")
+	if len(speaker.spoken) != 1 || speaker.spoken[0] != "Hello!" {
+		t.Fatalf("spoken=%q", speaker.spoken)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, err := l.bus.ConsumeOutbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.ChatID != "1" || out.Delivery.MessageID != "7" || len(out.Attachments) != 1 || out.Attachments[0].Name != "voice.ogg" {
+		t.Fatalf("out=%+v", out)
+	}
+
+	off := false
+	l.cfg.Tools.Media.Speech.Replies = &off
+	l.replyWithVoice(context.Background(), voice, "Hello again")
+	if len(speaker.spoken) != 1 {
+		t.Fatalf("replies off: spoken=%q", speaker.spoken)
+	}
+}
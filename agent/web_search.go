@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+// webSearchSetup resolves tools.web.search: the Brave key for the web_search
+// tool, or whether the provider's own search is used instead. With native
+// search the web_search tool is not offered.
+func webSearchSetup(cfg *config.Config) (braveKey string, native bool, err error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Tools.Web.Search)) {
+	case "", config.WebSearchBrave:
+		return cfg.Tools.Web.BraveAPIKey, false, nil
+	case config.WebSearchNative:
+		if !llm.SupportsWebSearch(cfg.LLM.Provider) {
+			return "", false, fmt.Errorf("tools.web.search=native is not supported by llm provider %q", cfg.LLM.Provider)
+		}
+		return "", true, nil
+	default:
+		return "", false, fmt.Errorf("tools.web.search must be %q or %q, got %q", config.WebSearchBrave, config.WebSearchNative, cfg.Tools.Web.Search)
+	}
+}
+
+// addCitations records the pages a provider-native search cited, so they
+// are listed like web_search results.
+func addCitations(srcs *tools.Sources, cits []llm.Citation) {
+	for _, c := range cits {
+		srcs.Add(c.Title, c.URL)
+	}
+}
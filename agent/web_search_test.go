@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestWebSearchSetup(t *testing.T) {
+	cfg := config.Default()
+	cfg.Tools.Web.BraveAPIKey = "brave"
+	cfg.LLM.Provider = "anthropic"
+	if key, native, err := webSearchSetup(cfg); err != nil || key != "brave" || native {
+		t.Fatalf("default: key=%q native=%v err=%v", key, native, err)
+	}
+	cfg.Tools.Web.Search = config.WebSearchNative
+	if key, native, err := webSearchSetup(cfg); err != nil || key != "" || !native {
+		t.Fatalf("native: key=%q native=%v err=%v", key, native, err)
+	}
+	cfg.LLM.Provider = "ollama"
+	if _, _, err := webSearchSetup(cfg); err == nil {
+		t.Fatal("native search accepted for ollama")
+	}
+	cfg.Tools.Web.Search = "bing"
+	if _, _, err := webSearchSetup(cfg); err == nil {
+		t.Fatal("unknown backend accepted")
+	}
+}
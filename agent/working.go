@@ -0,0 +1,18 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// working tells msg's chat that a reply is under way, or no longer is, so
+// the channel keeps its typing indicator up in between.
+func (l *Loop) working(ctx context.Context, msg bus.InboundMessage, on bool) {
+	_ = l.bus.PublishOutbound(ctx, bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Kind:    bus.MessageKindWorking,
+		Working: on,
+	})
+}
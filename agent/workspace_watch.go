@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/fswatch"
+)
+
+// newWorkspaceWatcher returns nil when the watcher is off.
+func newWorkspaceWatcher(c config.WorkspaceWatchConfig, workspace string) (*fswatch.Watcher, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	w, err := fswatch.New(workspace, c.ExcludeValue())
+	if err != nil {
+		return nil, fmt.Errorf("workspace watch: %w", err)
+	}
+	return w, nil
+}
+
+// changedFilesNote tells the model which files changed outside its tools
+// since lastTurn, listing at most max of them. It returns "" when nothing
+// changed.
+func changedFilesNote(w *fswatch.Watcher, lastTurn time.Time, max int) string {
+	changes := w.Since(lastTurn)
+	if len(changes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Files changed since last turn\n")
+	b.WriteString("These workspace files were changed outside your tools. Re-read them before relying on earlier contents.\n")
+	for i, c := range changes {
+		if i == max {
+			fmt.Fprintf(&b, "- ... and %d more\n", len(changes)-max)
+			break
+		}
+		fmt.Fprintf(&b, "- %s (%s)\n", c.Path, c.Op)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
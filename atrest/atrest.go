@@ -0,0 +1,258 @@
+// Package atrest encrypts state files (session transcripts) at rest with
+// AES-256-GCM. The key comes from a key file or is derived from a passphrase
+// with PBKDF2. Files without the header are treated as plaintext, so
+// existing state keeps loading and is encrypted on its next write.
+package atrest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	magic         = "CLWENC1"
+	kindKey       = 'k'
+	kindPass      = 'p'
+	saltSize      = 16
+	keySize       = 32
+	kdfIterations = 600_000
+)
+
+// ErrNoKey is returned when reading an encrypted file without a configured key.
+var ErrNoKey = errors.New("file is encrypted but no encryption key is configured (set encryption in config)")
+
+type Cipher struct {
+	kind       byte
+	key        []byte // key file mode
+	passphrase string
+
+	mu      sync.Mutex
+	derived map[string][]byte // salt -> key, passphrase mode
+	salt    []byte            // salt used for writes, passphrase mode
+}
+
+// NewKeyFile loads a 32-byte key stored raw, hex- or base64-encoded.
+func NewKeyFile(path string) (*Cipher, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("key file %s: %w", path, err)
+	}
+	return &Cipher{kind: kindKey, key: key}, nil
+}
+
+func NewPassphrase(passphrase string) (*Cipher, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is empty")
+	}
+	return &Cipher{kind: kindPass, passphrase: passphrase, derived: map[string][]byte{}}, nil
+}
+
+func parseKey(b []byte) ([]byte, error) {
+	if len(b) == keySize {
+		return b, nil
+	}
+	s := strings.TrimSpace(string(b))
+	if k, err := hex.DecodeString(s); err == nil && len(k) == keySize {
+		return k, nil
+	}
+	if k, err := base64.StdEncoding.DecodeString(s); err == nil && len(k) == keySize {
+		return k, nil
+	}
+	return nil, fmt.Errorf("want %d bytes (raw, hex or base64)", keySize)
+}
+
+// IsEncrypted reports whether data carries the encryption header.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+// Seal encrypts plain. Layout: magic | kind | [salt] | nonce | ciphertext,
+// with everything before the ciphertext authenticated.
+func (c *Cipher) Seal(plain []byte) ([]byte, error) {
+	header := []byte(magic + string(c.kind))
+	var key []byte
+	if c.kind == kindPass {
+		salt, err := c.writeSalt()
+		if err != nil {
+			return nil, err
+		}
+		header = append(header, salt...)
+		key = c.deriveKey(salt)
+	} else {
+		key = c.key
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header = append(header, nonce...)
+	return aead.Seal(header, nonce, plain, header), nil
+}
+
+// Open decrypts data written by Seal; plaintext input is returned unchanged.
+func (c *Cipher) Open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	rest := data[len(magic):]
+	if len(rest) < 1 {
+		return nil, errors.New("truncated encrypted file")
+	}
+	kind, rest := rest[0], rest[1:]
+	if kind != c.kind {
+		if kind == kindPass {
+			return nil, errors.New("file was encrypted with a passphrase, but a key file is configured")
+		}
+		return nil, errors.New("file was encrypted with a key file, but a passphrase is configured")
+	}
+	var key []byte
+	if kind == kindPass {
+		if len(rest) < saltSize {
+			return nil, errors.New("truncated encrypted file")
+		}
+		key = c.deriveKey(rest[:saltSize])
+		rest = rest[saltSize:]
+	} else {
+		key = c.key
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("truncated encrypted file")
+	}
+	nonce, ct := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	header := data[:len(data)-len(ct)]
+	plain, err := aead.Open(nil, nonce, ct, header)
+	if err != nil {
+		return nil, errors.New("decrypt failed: wrong key or corrupted file")
+	}
+	return plain, nil
+}
+
+func (c *Cipher) writeSalt() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		c.salt = salt
+	}
+	return c.salt, nil
+}
+
+// deriveKey caches derivations per salt; PBKDF2 is deliberately slow and
+// sessions are rewritten on every turn.
+func (c *Cipher) deriveKey(salt []byte) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if k, ok := c.derived[string(salt)]; ok {
+		return k
+	}
+	k, err := pbkdf2.Key(sha256.New, c.passphrase, salt, kdfIterations, keySize)
+	if err != nil {
+		// Only fails for invalid parameters, which are constants here.
+		panic(err)
+	}
+	c.derived[string(salt)] = k
+	return k
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultCipher *Cipher
+)
+
+// SetDefault configures the cipher used by ReadFile and WriteFile; nil
+// disables encryption for new writes.
+func SetDefault(c *Cipher) {
+	defaultMu.Lock()
+	defaultCipher = c
+	defaultMu.Unlock()
+}
+
+func Default() *Cipher {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultCipher
+}
+
+// Decode decrypts data with the default cipher; plaintext passes through.
+func Decode(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	c := Default()
+	if c == nil {
+		return nil, ErrNoKey
+	}
+	return c.Open(data)
+}
+
+// Encode encrypts data with the default cipher, if one is configured.
+func Encode(data []byte) ([]byte, error) {
+	if c := Default(); c != nil {
+		return c.Seal(data)
+	}
+	return data, nil
+}
+
+// ReadFile reads and decrypts path.
+func ReadFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := Decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plain, nil
+}
+
+// WriteFile encrypts data (when configured) and atomically replaces path.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	out, err := Encode(data)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,107 @@
+package atrest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewKeyFile_Formats(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, keySize)
+	for name, content := range map[string][]byte{
+		"raw":    key,
+		"hex":    []byte(hex.EncodeToString(key) + "\n"),
+		"base64": []byte(base64.StdEncoding.EncodeToString(key)),
+	} {
+		path := filepath.Join(t.TempDir(), "key")
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		c, err := NewKeyFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(c.key, key) {
+			t.Fatalf("%s: wrong key", name)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "short")
+	_ = os.WriteFile(path, []byte("abcd"), 0o600)
+	if _, err := NewKeyFile(path); err == nil {
+		t.Fatal("expected error for short key")
+	}
+}
+
+func TestSealOpen_RoundTrip(t *testing.T) {
+	keyCipher := &Cipher{kind: kindKey, key: bytes.Repeat([]byte{1}, keySize)}
+	passCipher, err := NewPassphrase("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := []byte(`{"role":"user","content":"secret"}` + "\n")
+	for _, c := range []*Cipher{keyCipher, passCipher} {
+		enc, err := c.Seal(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncrypted(enc) || bytes.Contains(enc, []byte("secret")) {
+			t.Fatal("ciphertext leaks plaintext")
+		}
+		got, err := c.Open(enc)
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Fatalf("open: %q %v", got, err)
+		}
+		enc[len(enc)-1] ^= 1
+		if _, err := c.Open(enc); err == nil {
+			t.Fatal("tampered file must not decrypt")
+		}
+	}
+
+	// A fresh cipher with the same passphrase derives the key from the salt.
+	enc, _ := passCipher.Seal(plain)
+	other, _ := NewPassphrase("correct horse")
+	if got, err := other.Open(enc); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("reopen: %v", err)
+	}
+	wrong, _ := NewPassphrase("wrong")
+	if _, err := wrong.Open(enc); err == nil {
+		t.Fatal("wrong passphrase must fail")
+	}
+	if _, err := keyCipher.Open(enc); err == nil {
+		t.Fatal("key mode mismatch must fail")
+	}
+}
+
+func TestReadWriteFile_Default(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+
+	// Plaintext is readable with or without a key.
+	if err := WriteFile(path, []byte("plain\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	SetDefault(&Cipher{kind: kindKey, key: bytes.Repeat([]byte{2}, keySize)})
+	if got, err := ReadFile(path); err != nil || string(got) != "plain\n" {
+		t.Fatalf("plaintext read: %q %v", got, err)
+	}
+
+	if err := WriteFile(path, []byte("hidden\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	if !IsEncrypted(raw) {
+		t.Fatal("file not encrypted")
+	}
+	if got, err := ReadFile(path); err != nil || string(got) != "hidden\n" {
+		t.Fatalf("read: %q %v", got, err)
+	}
+
+	SetDefault(nil)
+	if _, err := ReadFile(path); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("expected ErrNoKey, got %v", err)
+	}
+}
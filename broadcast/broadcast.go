@@ -0,0 +1,120 @@
+// Package broadcast sends one message to every member of a recipient group
+// (channels.recipients in the config), for announcements. Each member gets
+// a copy of their own, personalized with their name; pacing the copies is
+// left to the channel manager, which applies each chat app's rate limits.
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/contacts"
+)
+
+// Recipient is a resolved member of a group.
+type Recipient struct {
+	Channel string
+	ChatID  string
+	// Name fills in {{name}}; it may be empty.
+	Name string
+}
+
+// placeholder matches {{name}} and {{first_name}}, with an optional
+// fallback for members without a name: {{name|there}}.
+var placeholder = regexp.MustCompile(`\{\{\s*(name|first_name)\s*(?:\|([^{}]*))?\}\}`)
+
+// Validate rejects groups with members that name neither a contact nor a
+// channel and chat ID.
+func Validate(groups map[string][]config.RecipientConfig) error {
+	for name, members := range groups {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("recipient group with an empty name")
+		}
+		if len(members) == 0 {
+			return fmt.Errorf("recipient group %s is empty", name)
+		}
+		for i, m := range members {
+			if strings.TrimSpace(m.Contact) == "" && (strings.TrimSpace(m.Channel) == "" || strings.TrimSpace(m.ChatID) == "") {
+				return fmt.Errorf("recipient group %s, member %d: set contact, or channel and chatID", name, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve lists the members of group, once per chat. Members given as a
+// contact are looked up in book, on their channel if one is set.
+func Resolve(groups map[string][]config.RecipientConfig, group string, book *contacts.Store) ([]Recipient, error) {
+	members, ok := groups[strings.TrimSpace(group)]
+	if !ok {
+		return nil, fmt.Errorf("unknown recipient group %q", group)
+	}
+	var out []Recipient
+	seen := map[string]bool{}
+	for _, m := range members {
+		r := Recipient{
+			Channel: strings.TrimSpace(m.Channel),
+			ChatID:  strings.TrimSpace(m.ChatID),
+			Name:    strings.TrimSpace(m.Name),
+		}
+		if contact := strings.TrimSpace(m.Contact); contact != "" && r.ChatID == "" {
+			if book == nil {
+				return nil, errors.New("contacts not configured")
+			}
+			c, err := book.Resolve(contact)
+			if err != nil {
+				return nil, fmt.Errorf("recipient group %s: %w", group, err)
+			}
+			if r.Channel, r.ChatID, err = c.Target(r.Channel); err != nil {
+				return nil, fmt.Errorf("recipient group %s: %w", group, err)
+			}
+			if r.Name == "" {
+				r.Name = c.Name
+			}
+		}
+		if key := r.Channel + ":" + r.ChatID; !seen[key] {
+			seen[key] = true
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// Personalize fills in the placeholders of text for r.
+func Personalize(text string, r Recipient) string {
+	return placeholder.ReplaceAllStringFunc(text, func(m string) string {
+		sub := placeholder.FindStringSubmatch(m)
+		name := r.Name
+		if sub[1] == "first_name" {
+			name, _, _ = strings.Cut(name, " ")
+		}
+		if name == "" {
+			return strings.TrimSpace(sub[2])
+		}
+		return name
+	})
+}
+
+// Send publishes a personalized copy of msg to each recipient and returns
+// how many were published. It keeps going past failures, which are
+// returned joined.
+func Send(ctx context.Context, publish func(context.Context, bus.OutboundMessage) error, msg bus.OutboundMessage, recipients []Recipient) (int, error) {
+	var errs []error
+	sent := 0
+	for _, r := range recipients {
+		m := msg
+		m.Channel, m.ChatID = r.Channel, r.ChatID
+		m.Content = Personalize(msg.Content, r)
+		if err := publish(ctx, m); err != nil {
+			errs = append(errs, fmt.Errorf("%s:%s: %w", r.Channel, r.ChatID, err))
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/contacts"
+)
+
+func TestPersonalize(t *testing.T) {
+	cases := []struct {
+		text, name, want string
+	}{
+		{"Hi {{name}}!", "Ada Lovelace", "Hi Ada Lovelace!"},
+		{"Hi {{ first_name }}!", "Ada Lovelace", "Hi Ada!"},
+		{"Hi {{first_name|there}}!", "", "Hi there!"},
+		{"Hi {{name}}!", "", "Hi !"},
+		{"Hi {{other}}", "Ada", "Hi {{other}}"},
+	}
+	for _, c := range cases {
+		if got := Personalize(c.text, Recipient{Name: c.name}); got != c.want {
+			t.Errorf("Personalize(%q, %q) = %q, want %q", c.text, c.name, got, c.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	book := contacts.NewStore(filepath.Join(t.TempDir(), contacts.FileName))
+	if _, err := book.Upsert(contacts.Contact{Name: "Bob Smith", Addresses: map[string]string{"telegram": "111", "discord": "222"}}); err != nil {
+		t.Fatal(err)
+	}
+	groups := map[string][]config.RecipientConfig{
+		"team": {
+			{Contact: "bob smith", Channel: "telegram"},
+			{Channel: "slack", ChatID: "C1", Name: "Carol"},
+			{Channel: "telegram", ChatID: "111"},
+		},
+		"broken": {{Contact: "bob smith"}},
+	}
+	got, err := Resolve(groups, "team", book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Recipient{
+		{Channel: "telegram", ChatID: "111", Name: "Bob Smith"},
+		{Channel: "slack", ChatID: "C1", Name: "Carol"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Resolve = %+v", got)
+	}
+	if _, err := Resolve(groups, "broken", book); err == nil {
+		t.Fatal("expected an error for a contact on several channels")
+	}
+	if _, err := Resolve(groups, "nobody", book); err == nil {
+		t.Fatal("expected an error for an unknown group")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(map[string][]config.RecipientConfig{"ok": {{Contact: "bob"}, {Channel: "slack", ChatID: "C1"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(map[string][]config.RecipientConfig{"bad": {{Channel: "slack"}}}); err == nil {
+		t.Fatal("expected an error for a member without a chat")
+	}
+	if err := Validate(map[string][]config.RecipientConfig{"empty": nil}); err == nil {
+		t.Fatal("expected an error for an empty group")
+	}
+}
+
+func TestSend(t *testing.T) {
+	var got []bus.OutboundMessage
+	publish := func(ctx context.Context, msg bus.OutboundMessage) error {
+		if msg.ChatID == "down" {
+			return errors.New("unavailable")
+		}
+		got = append(got, msg)
+		return nil
+	}
+	recipients := []Recipient{
+		{Channel: "telegram", ChatID: "1", Name: "Ada"},
+		{Channel: "slack", ChatID: "down"},
+		{Channel: "slack", ChatID: "C1"},
+	}
+	n, err := Send(context.Background(), publish, bus.OutboundMessage{Content: "Hello {{name|all}}"}, recipients)
+	if n != 2 || err == nil {
+		t.Fatalf("Send = %d, %v", n, err)
+	}
+	if got[0].Content != "Hello Ada" || got[0].Channel != "telegram" || got[1].Content != "Hello all" || got[1].ChatID != "C1" {
+		t.Fatalf("published %+v", got)
+	}
+}
@@ -2,7 +2,12 @@ package bus
 
 import (
 	"context"
+	"errors"
+	"log"
+	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Delivery struct {
@@ -10,6 +15,16 @@ type Delivery struct {
 	ReplyToID string
 	ThreadID  string
 	IsDirect  bool
+	// IsEdit marks an inbound message as the new text of MessageID, which
+	// the sender edited. On the reply to a corrected turn it asks the
+	// channel to edit its earlier answer to MessageID instead of sending a
+	// new message.
+	IsEdit bool
+	// SentAt is when the message was sent (or edited), as the chat app
+	// reports it; PublishInbound sets it to the time of publishing when the
+	// channel does not. A message held while the agent was away gets the
+	// time it was released.
+	SentAt time.Time
 }
 
 type Attachment struct {
@@ -24,6 +39,37 @@ type Attachment struct {
 	Headers   map[string]string
 }
 
+// ReadData returns the attachment's bytes, from Data or else LocalPath.
+func (a Attachment) ReadData() ([]byte, error) {
+	if len(a.Data) > 0 {
+		return a.Data, nil
+	}
+	if a.LocalPath == "" {
+		return nil, errors.New("attachment has no data")
+	}
+	return os.ReadFile(a.LocalPath)
+}
+
+// IsVoiceNote reports whether a is Ogg audio, which Telegram and WhatsApp
+// play as a voice note rather than a file.
+func (a Attachment) IsVoiceNote() bool {
+	mimeType, _, _ := strings.Cut(strings.ToLower(a.MIMEType), ";")
+	return a.Kind == "audio" && strings.TrimSpace(mimeType) == "audio/ogg"
+}
+
+// AttachmentNote lists attachment names for channels that can only send
+// text, e.g. "[attached: report.pdf, chart.png]".
+func AttachmentNote(atts []Attachment) string {
+	if len(atts) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(atts))
+	for _, a := range atts {
+		names = append(names, a.Name)
+	}
+	return "[attached: " + strings.Join(names, ", ") + "]"
+}
+
 func InferAttachmentKind(mimeType string) string {
 	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
 	switch {
@@ -38,6 +84,110 @@ func InferAttachmentKind(mimeType string) string {
 	}
 }
 
+// MessageKind tells what a message carries. The zero value is an ordinary
+// message with text and attachments.
+type MessageKind string
+
+const (
+	MessageKindText     MessageKind = ""
+	MessageKindReaction MessageKind = "reaction"
+	MessageKindPoll     MessageKind = "poll"
+	// MessageKindWorking is outbound only: the agent has started (Working
+	// true) or finished (Working false) a reply to the chat.
+	MessageKindWorking MessageKind = "working"
+	// MessageKindStatus is inbound only: what became of a message the agent
+	// sent, see Status.
+	MessageKindStatus MessageKind = "status"
+)
+
+// Reaction is an emoji reaction to a message, carried by messages of
+// MessageKindReaction.
+type Reaction struct {
+	Emoji     string
+	MessageID string // the message reacted to
+	Removed   bool   // inbound only: the sender took the reaction back
+}
+
+// Poll is a native poll, carried by messages of MessageKindPoll: outbound
+// to create one, inbound to report its votes.
+type Poll struct {
+	Question      string
+	Options       []string
+	MultiSelect   bool // voters may pick several options
+	DurationHours int  // how long voting stays open where the app needs it; 0 is the app's default
+
+	// Inbound only.
+	ID     string // the chat app's ID of the poll
+	Votes  []int  // votes per option, in the order of Options
+	Closed bool
+}
+
+// Delivery states of an outbound message, as reported by Status.
+const (
+	StatusSent      = "sent"
+	StatusDelivered = "delivered"
+	StatusRead      = "read"
+	StatusFailed    = "failed"
+)
+
+// Status reports the delivery of an outbound message, carried by inbound
+// messages of MessageKindStatus. They are for observers; the agent does not
+// answer them.
+type Status struct {
+	MessageID string // the chat app's ID of the sent message
+	State     string // StatusSent, StatusDelivered, StatusRead or StatusFailed
+	// Resent is set on a failed status when the message was sent again; the
+	// new copy reports its own statuses under ResentID.
+	Resent   bool
+	ResentID string
+}
+
+// Card is a structured block sent with a message, such as a search result:
+// Discord renders it as an embed, other channels as text after the
+// message.
+type Card struct {
+	Title       string
+	URL         string // Title links here
+	Description string
+	Fields      []CardField
+	Footer      string
+	Color       int // 0xRRGGBB; 0 is the app's default
+}
+
+// CardField is a labelled value of a Card.
+type CardField struct {
+	Name   string
+	Value  string
+	Inline bool // may sit next to other inline fields
+}
+
+// CardText renders cards for channels that can only send text.
+func CardText(cards []Card) string {
+	var blocks []string
+	for _, c := range cards {
+		var lines []string
+		if title := strings.TrimSpace(c.Title); title != "" {
+			lines = append(lines, "**"+title+"**")
+		}
+		if url := strings.TrimSpace(c.URL); url != "" {
+			lines = append(lines, url)
+		}
+		if d := strings.TrimSpace(c.Description); d != "" {
+			lines = append(lines, d)
+		}
+		for _, f := range c.Fields {
+			lines = append(lines, strings.TrimSpace(f.Name)+": "+strings.TrimSpace(f.Value))
+		}
+		if footer := strings.TrimSpace(c.Footer); footer != "" {
+			lines = append(lines, "_"+footer+"_")
+		}
+		if len(lines) > 0 {
+			blocks = append(blocks, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
 type InboundMessage struct {
 	Channel     string
 	SenderID    string
@@ -46,6 +196,10 @@ type InboundMessage struct {
 	Attachments []Attachment
 	SessionKey  string // usually "channel:chat_id"
 	Delivery    Delivery
+	Kind        MessageKind
+	Reaction    Reaction
+	Poll        Poll
+	Status      Status
 }
 
 type OutboundMessage struct {
@@ -54,11 +208,77 @@ type OutboundMessage struct {
 	Content  string
 	ReplyTo  string
 	Delivery Delivery
+	// Suggestions are follow-up prompts rendered as buttons (or a numbered
+	// list) where the channel supports them. Choosing one sends its text as
+	// the user's next message.
+	Suggestions []string
+	// Attachments are uploaded natively where the channel supports it;
+	// Content then serves as the caption.
+	Attachments []Attachment
+	// Cards are shown after Content, as embeds where the channel supports
+	// them.
+	Cards []Card
+	// StreamID groups the messages of a reply streamed while it is being
+	// generated. Partial messages carry the text so far and go only to
+	// channels that can edit a sent message; the final message has the same
+	// StreamID, Partial false and the whole reply.
+	StreamID string
+	Partial  bool
+	// Kind MessageKindReaction reacts to Reaction.MessageID with
+	// Reaction.Emoji instead of sending Content, and MessageKindPoll sends
+	// Poll; channels that cannot drop them. MessageKindWorking keeps a
+	// typing indicator up in the chat while Working is true.
+	Kind     MessageKind
+	Reaction Reaction
+	Poll     Poll
+	Working  bool
+
+	// ack identifies the broker entry to acknowledge once the message has
+	// been sent; see Bus.AckOutbound.
+	ack string
+}
+
+// Broker moves messages between clawlet instances. A Bus created with
+// NewWithBroker delegates to it instead of in-process channels.
+type Broker interface {
+	PublishInbound(ctx context.Context, msg InboundMessage) error
+	ConsumeInbound(ctx context.Context) (InboundMessage, error)
+	PublishOutbound(ctx context.Context, msg OutboundMessage) error
+	// ConsumeOutbound leaves the message pending until AckOutbound, so it
+	// is redelivered if the instance stops before sending it.
+	ConsumeOutbound(ctx context.Context) (OutboundMessage, error)
+	AckOutbound(ctx context.Context, msg OutboundMessage) error
+	Close() error
+}
+
+// InboundWatcher is implemented by brokers that can show observers the
+// inbound messages published by every instance, not only this one.
+type InboundWatcher interface {
+	// WatchInbound calls fn with each inbound message published from now
+	// on until ctx is done or the broker fails.
+	WatchInbound(ctx context.Context, fn func(InboundMessage)) error
 }
 
 type Bus struct {
 	in  chan InboundMessage
 	out chan OutboundMessage
+
+	broker Broker
+
+	lastInbound sync.Map // channel -> time.Time
+	filter      InboundFilter
+
+	maxAge  time.Duration
+	expired func(InboundMessage)
+
+	inStats  queueCounters
+	outStats queueCounters
+
+	subMu      sync.Mutex
+	subs       map[chan InboundMessage]struct{}
+	watchOnce  sync.Once
+	stopWatch  context.CancelFunc
+	watchClose chan struct{}
 }
 
 func New(buffer int) *Bus {
@@ -71,16 +291,164 @@ func New(buffer int) *Bus {
 	}
 }
 
-func (b *Bus) PublishInbound(ctx context.Context, msg InboundMessage) error {
+// NewWithBroker returns a bus that exchanges messages through br, so several
+// instances can share channels and sessions.
+func NewWithBroker(br Broker) *Bus {
+	return &Bus{broker: br}
+}
+
+// Close releases the broker, if any.
+func (b *Bus) Close() error {
+	b.subMu.Lock()
+	stop, done := b.stopWatch, b.watchClose
+	b.subMu.Unlock()
+	if stop != nil {
+		stop()
+		<-done
+	}
+	if b.broker != nil {
+		return b.broker.Close()
+	}
+	return nil
+}
+
+// InboundFilter sees each inbound message before it is published. It may
+// change the message; returning false drops it.
+type InboundFilter func(ctx context.Context, msg *InboundMessage) bool
+
+// SetInboundFilter installs f. Call it before the channels start.
+func (b *Bus) SetInboundFilter(f InboundFilter) {
+	b.filter = f
+}
+
+// SetMaxAge makes ConsumeInbound skip messages sent more than maxAge ago,
+// such as the backlog a chat app delivers after an outage, handing each to
+// expired (which may be nil) instead. Status messages are never skipped.
+// Call it before the agent starts.
+func (b *Bus) SetMaxAge(maxAge time.Duration, expired func(InboundMessage)) {
+	b.maxAge = maxAge
+	b.expired = expired
+}
+
+// stale reports whether msg is older than the max age.
+func (b *Bus) stale(msg InboundMessage) bool {
+	if b.maxAge <= 0 || msg.Kind == MessageKindStatus || msg.Delivery.SentAt.IsZero() {
+		return false
+	}
+	return time.Since(msg.Delivery.SentAt) > b.maxAge
+}
+
+func (b *Bus) PublishInbound(ctx context.Context, msg InboundMessage) (err error) {
+	b.lastInbound.Store(msg.Channel, time.Now())
+	if b.filter != nil && !b.filter(ctx, &msg) {
+		return nil
+	}
+	defer b.inStats.observe(time.Now(), &err)
+	if msg.Delivery.SentAt.IsZero() {
+		msg.Delivery.SentAt = time.Now()
+	}
+	if b.broker != nil {
+		if err := b.broker.PublishInbound(ctx, msg); err != nil {
+			return err
+		}
+		if _, ok := b.broker.(InboundWatcher); !ok {
+			b.notify(msg)
+		}
+		return nil
+	}
 	select {
 	case b.in <- msg:
+		b.notify(msg)
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
 	}
 }
 
-func (b *Bus) PublishOutbound(ctx context.Context, msg OutboundMessage) error {
+// Subscribe returns a copy of every inbound message published through b
+// from now on, for observers such as analytics or a dashboard; they do not
+// take messages from the agent. With a broker that is an InboundWatcher,
+// that includes the messages of the other instances. A subscriber more than
+// buffer messages behind misses messages rather than holding up the
+// channels. cancel unsubscribes and closes msgs.
+func (b *Bus) Subscribe(buffer int) (msgs <-chan InboundMessage, cancel func()) {
+	if w, ok := b.broker.(InboundWatcher); ok {
+		b.watchOnce.Do(func() { b.watch(w) })
+	}
+	ch := make(chan InboundMessage, max(buffer, 1))
+	b.subMu.Lock()
+	if b.subs == nil {
+		b.subs = map[chan InboundMessage]struct{}{}
+	}
+	b.subs[ch] = struct{}{}
+	b.subMu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.subMu.Lock()
+			delete(b.subs, ch)
+			b.subMu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// watch feeds the subscribers from w until Close, starting over after
+// broker errors.
+func (b *Bus) watch(w InboundWatcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	b.subMu.Lock()
+	b.stopWatch, b.watchClose = cancel, done
+	b.subMu.Unlock()
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			if err := w.WatchInbound(ctx, b.notify); err != nil && ctx.Err() == nil {
+				log.Printf("bus: watching inbound messages failed: %v", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+			}
+		}
+	}()
+}
+
+func (b *Bus) notify(msg InboundMessage) {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// LastInbound reports when channel last published an inbound message in this
+// process; zero if it has not.
+func (b *Bus) LastInbound(channel string) time.Time {
+	if v, ok := b.lastInbound.Load(channel); ok {
+		return v.(time.Time)
+	}
+	return time.Time{}
+}
+
+// AckOutbound reports that msg, from ConsumeOutbound, has been sent (or
+// given up on). With a broker it stays pending until then.
+func (b *Bus) AckOutbound(ctx context.Context, msg OutboundMessage) error {
+	if b.broker == nil {
+		return nil
+	}
+	return b.broker.AckOutbound(ctx, msg)
+}
+
+func (b *Bus) PublishOutbound(ctx context.Context, msg OutboundMessage) (err error) {
+	defer b.outStats.observe(time.Now(), &err)
+	if b.broker != nil {
+		return b.broker.PublishOutbound(ctx, msg)
+	}
 	select {
 	case b.out <- msg:
 		return nil
@@ -90,6 +458,21 @@ func (b *Bus) PublishOutbound(ctx context.Context, msg OutboundMessage) error {
 }
 
 func (b *Bus) ConsumeInbound(ctx context.Context) (InboundMessage, error) {
+	for {
+		msg, err := b.consumeInbound(ctx)
+		if err != nil || !b.stale(msg) {
+			return msg, err
+		}
+		if b.expired != nil {
+			b.expired(msg)
+		}
+	}
+}
+
+func (b *Bus) consumeInbound(ctx context.Context) (InboundMessage, error) {
+	if b.broker != nil {
+		return b.broker.ConsumeInbound(ctx)
+	}
 	select {
 	case msg := <-b.in:
 		return msg, nil
@@ -99,6 +482,9 @@ func (b *Bus) ConsumeInbound(ctx context.Context) (InboundMessage, error) {
 }
 
 func (b *Bus) ConsumeOutbound(ctx context.Context) (OutboundMessage, error) {
+	if b.broker != nil {
+		return b.broker.ConsumeOutbound(ctx)
+	}
 	select {
 	case msg := <-b.out:
 		return msg, nil
@@ -0,0 +1,93 @@
+package bus
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubscribe_FansOutInbound(t *testing.T) {
+	b := New(4)
+	ctx := context.Background()
+	obs, cancel := b.Subscribe(1)
+	defer cancel()
+
+	_ = b.PublishInbound(ctx, InboundMessage{Channel: "telegram", Content: "one"})
+	_ = b.PublishInbound(ctx, InboundMessage{Channel: "telegram", Content: "two"}) // observer is full
+
+	// The agent still gets every message.
+	for _, want := range []string{"one", "two"} {
+		if msg, _ := b.ConsumeInbound(ctx); msg.Content != want {
+			t.Fatalf("consumed %q, want %q", msg.Content, want)
+		}
+	}
+	if msg := <-obs; msg.Content != "one" {
+		t.Fatalf("observed %q", msg.Content)
+	}
+	select {
+	case msg := <-obs:
+		t.Fatalf("slow observer should have missed %q", msg.Content)
+	default:
+	}
+
+	cancel()
+	if _, ok := <-obs; ok {
+		t.Fatal("channel should be closed")
+	}
+	_ = b.PublishInbound(ctx, InboundMessage{Channel: "telegram", Content: "three"})
+}
+
+func TestStats_CountsDepthAndDrops(t *testing.T) {
+	b := New(1)
+	ctx := context.Background()
+	if err := b.PublishInbound(ctx, InboundMessage{Channel: "webhook", Content: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	// The queue is full: the next publish times out and is dropped.
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := b.PublishInbound(short, InboundMessage{Channel: "webhook", Content: "two"}); err == nil {
+		t.Fatal("publish to a full queue succeeded")
+	}
+	st := b.Stats()
+	in := st.Inbound
+	if in.Depth != 1 || in.Capacity != 1 || in.Published != 1 || in.Dropped != 1 || in.MaxPublishWait < 20*time.Millisecond {
+		t.Fatalf("inbound stats = %+v", in)
+	}
+	var out strings.Builder
+	if err := st.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`clawlet_bus_queue_depth{direction="inbound"} 1`,
+		`clawlet_bus_dropped_total{direction="inbound"} 1`,
+		`clawlet_bus_publish_wait_seconds_count{direction="inbound"} 2`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics lack %s:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestMaxAge_SkipsStaleMessages(t *testing.T) {
+	b := New(8)
+	var expired []string
+	b.SetMaxAge(time.Hour, func(m InboundMessage) { expired = append(expired, m.Content) })
+	ctx := context.Background()
+	old := Delivery{SentAt: time.Now().Add(-2 * time.Hour)}
+	_ = b.PublishInbound(ctx, InboundMessage{Channel: "telegram", Content: "old", Delivery: old})
+	_ = b.PublishInbound(ctx, InboundMessage{Channel: "telegram", Kind: MessageKindStatus, Delivery: old})
+	_ = b.PublishInbound(ctx, InboundMessage{Channel: "telegram", Content: "new"})
+
+	if m, err := b.ConsumeInbound(ctx); err != nil || m.Kind != MessageKindStatus {
+		t.Fatalf("status messages never expire: %+v %v", m, err)
+	}
+	m, err := b.ConsumeInbound(ctx)
+	if err != nil || m.Content != "new" || m.Delivery.SentAt.IsZero() {
+		t.Fatalf("got %+v %v", m, err)
+	}
+	if len(expired) != 1 || expired[0] != "old" {
+		t.Fatalf("expired = %v", expired)
+	}
+}
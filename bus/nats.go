@@ -0,0 +1,460 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	DefaultNATSPrefix     = "clawlet"
+	DefaultNATSPartitions = 16
+	DefaultNATSAckWait    = 30 * time.Second
+
+	natsMaxMsgsPerSubject = 10000
+	natsPoll              = time.Second
+	// natsOutAckPending bounds the replies an outbound consumer has out
+	// before they are acknowledged.
+	natsOutAckPending = 256
+)
+
+type NATSOptions struct {
+	// URL is a nats:// or tls:// URL, optionally with credentials; several
+	// servers of a cluster may be given separated by commas.
+	URL    string
+	Prefix string
+	// InstanceID must be unique per instance and stable across restarts, so
+	// a restarted instance resumes its own pending replies.
+	InstanceID string
+	// Partitions shard inbound messages by session; all instances must use
+	// the same value.
+	Partitions int
+	// Channels are the chat channels running on this instance. Replies for
+	// chats first seen elsewhere are sent by whichever instance runs the
+	// channel.
+	Channels []string
+	// AckWait is how long a message an instance took may go without word
+	// from it before JetStream hands it to another instance.
+	AckWait time.Duration
+}
+
+// NATSBroker shares the bus over NATS JetStream. Inbound messages are
+// partitioned by session key into durable consumers that hand out one
+// message at a time, so a partition's sessions are handled by one agent at a
+// time, on whichever instance pulls next. A message an instance holds is kept
+// in progress while the instance lives; if it stops, JetStream redelivers the
+// message after AckWait. Replies are routed back to the instance that
+// received the message, or to any instance running the channel.
+//
+// Delivery is at least once: an inbound message is acknowledged when the
+// next one is consumed, and an outbound one by AckOutbound once it is sent,
+// as with RedisBroker.
+type NATSBroker struct {
+	opts   NATSOptions
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	stream string
+	in     []jetstream.Consumer // by partition
+	out    []jetstream.Consumer
+
+	wakeIn  chan struct{}
+	wakeOut chan struct{}
+
+	mu         sync.Mutex
+	origin     map[string]string
+	inPending  jetstream.Msg            // the message being handled
+	outPending map[string]jetstream.Msg // replies being sent, by ack subject
+	nextPart   int
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewNATSBroker(ctx context.Context, opts NATSOptions) (*NATSBroker, error) {
+	if strings.TrimSpace(opts.URL) == "" {
+		return nil, errors.New("nats broker: url is required")
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultNATSPrefix
+	}
+	if strings.ContainsAny(opts.Prefix, " \t*>") {
+		return nil, fmt.Errorf("nats broker: prefix %q may not contain spaces, * or >", opts.Prefix)
+	}
+	if opts.Partitions <= 0 {
+		opts.Partitions = DefaultNATSPartitions
+	}
+	if opts.AckWait <= 0 {
+		opts.AckWait = DefaultNATSAckWait
+	}
+	if opts.InstanceID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("nats broker: instance id: %w", err)
+		}
+		opts.InstanceID = host
+	}
+	nc, err := nats.Connect(opts.URL, nats.Name("clawlet "+opts.InstanceID), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("nats broker: %w", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("nats broker: %w", err)
+	}
+	b := &NATSBroker{
+		opts:    opts,
+		nc:      nc,
+		js:      js,
+		stream:  strings.ToUpper(natsToken(opts.Prefix)),
+		wakeIn:  make(chan struct{}, 1),
+		wakeOut: make(chan struct{}, 1),
+		origin:  map[string]string{},
+
+		outPending: map[string]jetstream.Msg{},
+	}
+	if err := b.setup(ctx); err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("nats broker: %w", err)
+	}
+	hctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+	go b.progressLoop(hctx)
+	return b, nil
+}
+
+// setup creates the stream and the consumers this instance reads, and
+// subscribes to the subjects whose messages should wake a waiting reader.
+func (b *NATSBroker) setup(ctx context.Context) error {
+	_, err := b.js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:              b.stream,
+		Subjects:          []string{b.opts.Prefix + ".in.*", b.opts.Prefix + ".out.>"},
+		Retention:         jetstream.LimitsPolicy,
+		Storage:           jetstream.FileStorage,
+		Discard:           jetstream.DiscardOld,
+		MaxMsgsPerSubject: natsMaxMsgsPerSubject,
+	})
+	// A stream created with other settings, say by an older version, is
+	// used as it is.
+	if err != nil && !errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		return err
+	}
+	for p := range b.opts.Partitions {
+		c, err := b.ensureConsumer(ctx, b.inConsumer(p), b.inSubject(p), 1)
+		if err != nil {
+			return err
+		}
+		b.in = append(b.in, c)
+	}
+	outs := map[string]string{natsToken("out-inst-" + b.opts.InstanceID): b.outSubject("inst", b.opts.InstanceID)}
+	for _, ch := range b.opts.Channels {
+		outs[natsToken("out-ch-"+ch)] = b.outSubject("ch", ch)
+	}
+	for name, subject := range outs {
+		c, err := b.ensureConsumer(ctx, name, subject, natsOutAckPending)
+		if err != nil {
+			return err
+		}
+		b.out = append(b.out, c)
+		if _, err := b.nc.Subscribe(subject, func(*nats.Msg) { wake(b.wakeOut) }); err != nil {
+			return err
+		}
+	}
+	_, err = b.nc.Subscribe(b.opts.Prefix+".in.*", func(*nats.Msg) { wake(b.wakeIn) })
+	return err
+}
+
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// ensureConsumer creates a durable pull consumer that has at most
+// maxPending messages out at a time.
+func (b *NATSBroker) ensureConsumer(ctx context.Context, name, subject string, maxPending int) (jetstream.Consumer, error) {
+	return b.js.CreateOrUpdateConsumer(ctx, b.stream, jetstream.ConsumerConfig{
+		Durable:       name,
+		FilterSubject: subject,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       b.opts.AckWait,
+		MaxAckPending: maxPending,
+		MaxDeliver:    -1,
+	})
+}
+
+// natsToken makes s usable as one subject token or consumer name.
+func natsToken(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func (b *NATSBroker) inConsumer(p int) string { return "in-" + strconv.Itoa(p) }
+func (b *NATSBroker) inSubject(p int) string  { return b.opts.Prefix + ".in." + strconv.Itoa(p) }
+
+func (b *NATSBroker) outSubject(kind, name string) string {
+	if kind == "inst" {
+		name = natsToken(name)
+	}
+	return b.opts.Prefix + ".out." + kind + "." + name
+}
+
+func (b *NATSBroker) PublishInbound(ctx context.Context, msg InboundMessage) error {
+	data, err := b.marshal(func(inline bool) any {
+		m := msg
+		if inline {
+			m.Attachments = inlineAttachments(m.Attachments)
+		}
+		return inboundEnvelope{Origin: b.opts.InstanceID, Msg: m}
+	})
+	if err != nil {
+		return err
+	}
+	return b.publish(ctx, b.inSubject(partitionFor(inboundSessionKey(msg), b.opts.Partitions)), data)
+}
+
+func (b *NATSBroker) PublishOutbound(ctx context.Context, msg OutboundMessage) error {
+	data, err := b.marshal(func(inline bool) any {
+		m := msg
+		if inline {
+			m.Attachments = inlineAttachments(m.Attachments)
+		}
+		return m
+	})
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	origin := b.origin[msg.Channel+":"+msg.ChatID]
+	b.mu.Unlock()
+	subject := b.outSubject("ch", msg.Channel)
+	if origin != "" {
+		subject = b.outSubject("inst", origin)
+	}
+	return b.publish(ctx, subject, data)
+}
+
+// marshal encodes a message with its local files inlined, unless that
+// makes it larger than the server accepts; then the files keep their path.
+func (b *NATSBroker) marshal(msg func(inline bool) any) ([]byte, error) {
+	data, err := json.Marshal(msg(true))
+	if err != nil {
+		return nil, err
+	}
+	if limit := b.nc.MaxPayload(); limit > 0 && len(data) > int(limit) {
+		return json.Marshal(msg(false))
+	}
+	return data, nil
+}
+
+// publish stores data in the stream and waits for JetStream to confirm it.
+func (b *NATSBroker) publish(ctx context.Context, subject string, data []byte) error {
+	_, err := b.js.Publish(ctx, subject, data)
+	return err
+}
+
+func (b *NATSBroker) ConsumeInbound(ctx context.Context) (InboundMessage, error) {
+	for {
+		b.mu.Lock()
+		done := b.inPending
+		b.inPending = nil
+		start := b.nextPart
+		b.mu.Unlock()
+		b.ack(done)
+		consumers := make([]jetstream.Consumer, b.opts.Partitions)
+		for i := range consumers {
+			consumers[i] = b.in[(start+i)%b.opts.Partitions]
+		}
+		m, i, err := b.next(ctx, consumers, b.wakeIn)
+		if err != nil {
+			return InboundMessage{}, err
+		}
+		b.mu.Lock()
+		b.nextPart = (start + i + 1) % b.opts.Partitions
+		b.inPending = m
+		b.mu.Unlock()
+		var env inboundEnvelope
+		if err := json.Unmarshal(m.Data(), &env); err != nil {
+			log.Printf("bus: dropping malformed inbound message on %s: %v", m.Subject(), err)
+			continue
+		}
+		if env.Origin != "" {
+			b.rememberOrigin(env.Msg.Channel+":"+env.Msg.ChatID, env.Origin)
+		}
+		return env.Msg, nil
+	}
+}
+
+func (b *NATSBroker) ConsumeOutbound(ctx context.Context) (OutboundMessage, error) {
+	for {
+		m, _, err := b.next(ctx, b.out, b.wakeOut)
+		if err != nil {
+			return OutboundMessage{}, err
+		}
+		var msg OutboundMessage
+		if err := json.Unmarshal(m.Data(), &msg); err != nil {
+			log.Printf("bus: dropping malformed outbound message on %s: %v", m.Subject(), err)
+			b.ack(m)
+			continue
+		}
+		msg.ack = m.Reply()
+		b.mu.Lock()
+		b.outPending[msg.ack] = m
+		b.mu.Unlock()
+		return msg, nil
+	}
+}
+
+// AckOutbound acknowledges msg, from ConsumeOutbound, once it is sent.
+func (b *NATSBroker) AckOutbound(ctx context.Context, msg OutboundMessage) error {
+	b.mu.Lock()
+	m := b.outPending[msg.ack]
+	delete(b.outPending, msg.ack)
+	b.mu.Unlock()
+	if m == nil {
+		return nil
+	}
+	return m.Ack()
+}
+
+// next pulls the first message available from consumers, in order. When
+// none has one, it waits for a wake-up or polls again after natsPoll; a
+// consumer whose message is out with another instance has none to give
+// until that one is acknowledged.
+func (b *NATSBroker) next(ctx context.Context, consumers []jetstream.Consumer, wakeup <-chan struct{}) (jetstream.Msg, int, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		for i, c := range consumers {
+			m, err := fetchOne(c)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, 0, ctx.Err()
+				}
+				log.Printf("bus: nats pull from %s failed: %v", c.CachedInfo().Name, err)
+				break
+			}
+			if m != nil {
+				return m, i, nil
+			}
+		}
+		t := time.NewTimer(natsPoll)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, 0, ctx.Err()
+		case <-wakeup:
+		case <-t.C:
+		}
+		t.Stop()
+	}
+}
+
+// fetchOne returns the consumer's next message, or nil if it has none to
+// give right now.
+func fetchOne(c jetstream.Consumer) (jetstream.Msg, error) {
+	batch, err := c.FetchNoWait(1)
+	if err != nil {
+		return nil, err
+	}
+	var m jetstream.Msg
+	for msg := range batch.Messages() {
+		m = msg
+	}
+	if m == nil {
+		return nil, batch.Error()
+	}
+	return m, nil
+}
+
+func (b *NATSBroker) ack(m jetstream.Msg) {
+	if m == nil {
+		return
+	}
+	if err := m.Ack(); err != nil {
+		log.Printf("bus: nats ack failed: %v", err)
+	}
+}
+
+// progressLoop tells JetStream the messages being handled are still in
+// progress, so they are not redelivered while a long turn runs.
+func (b *NATSBroker) progressLoop(ctx context.Context) {
+	defer close(b.done)
+	t := time.NewTicker(b.opts.AckWait / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		b.mu.Lock()
+		var pending []jetstream.Msg
+		if b.inPending != nil {
+			pending = append(pending, b.inPending)
+		}
+		for _, m := range b.outPending {
+			pending = append(pending, m)
+		}
+		b.mu.Unlock()
+		for _, m := range pending {
+			if err := m.InProgress(); err != nil {
+				log.Printf("bus: nats progress failed: %v", err)
+			}
+		}
+	}
+}
+
+// WatchInbound subscribes to the inbound subjects outside JetStream, so fn
+// sees all instances' messages and none are taken from the agents.
+func (b *NATSBroker) WatchInbound(ctx context.Context, fn func(InboundMessage)) error {
+	sub, err := b.nc.Subscribe(b.opts.Prefix+".in.*", func(m *nats.Msg) {
+		var env inboundEnvelope
+		if json.Unmarshal(m.Data, &env) == nil {
+			fn(env.Msg)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *NATSBroker) rememberOrigin(chat, origin string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.origin[chat]; !ok && len(b.origin) >= maxOriginRoutes {
+		clear(b.origin)
+	}
+	b.origin[chat] = origin
+}
+
+// Close stops renewing the messages in progress. Unacknowledged messages are
+// redelivered after AckWait.
+func (b *NATSBroker) Close() error {
+	b.cancel()
+	<-b.done
+	b.nc.Close()
+	return nil
+}
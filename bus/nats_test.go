@@ -0,0 +1,294 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// natsServer is an embedded JetStream server that can be restarted on the
+// same port and store.
+type natsServer struct {
+	t    *testing.T
+	opts server.Options
+	srv  *server.Server
+}
+
+func startNATS(t *testing.T) (*natsServer, string) {
+	t.Helper()
+	s := &natsServer{t: t, opts: server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	}}
+	s.start()
+	// Later restarts keep the port.
+	s.opts.Port = s.srv.Addr().(*net.TCPAddr).Port
+	t.Cleanup(func() { s.srv.Shutdown() })
+	return s, s.srv.ClientURL()
+}
+
+func (s *natsServer) start() {
+	s.t.Helper()
+	opts := s.opts
+	srv, err := server.NewServer(&opts)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		s.t.Fatal("nats server not ready")
+	}
+	s.srv = srv
+}
+
+// restart stops the server, dropping every connection, and starts it again.
+func (s *natsServer) restart() {
+	s.t.Helper()
+	s.srv.Shutdown()
+	s.srv.WaitForShutdown()
+	s.start()
+}
+
+func newTestNATSBroker(t *testing.T, url, id string, channels ...string) *NATSBroker {
+	t.Helper()
+	b, err := NewNATSBroker(context.Background(), NATSOptions{
+		URL:        url,
+		InstanceID: id,
+		Partitions: 4,
+		Channels:   channels,
+		AckWait:    300 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNATSBroker_RoutesRepliesToOrigin(t *testing.T) {
+	_, url := startNATS(t)
+	a := newTestNATSBroker(t, url, "a", "telegram")
+	defer a.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := a.PublishInbound(ctx, InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	in, err := a.ConsumeInbound(ctx)
+	if err != nil || in.Content != "hi" || in.ChatID != "1" {
+		t.Fatalf("inbound: %+v %v", in, err)
+	}
+	if err := a.PublishOutbound(ctx, OutboundMessage{Channel: "telegram", ChatID: "1", Content: "reply"}); err != nil {
+		t.Fatal(err)
+	}
+	// A chat never seen by this instance goes to the channel's consumer.
+	if err := a.PublishOutbound(ctx, OutboundMessage{Channel: "telegram", ChatID: "2", Content: "push"}); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for range 2 {
+		out, err := a.ConsumeOutbound(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[out.Content] = true
+	}
+	if !got["reply"] || !got["push"] {
+		t.Fatalf("outbound: %v", got)
+	}
+}
+
+func TestNATSBroker_HoldsPartitionUntilAcked(t *testing.T) {
+	_, url := startNATS(t)
+	a := newTestNATSBroker(t, url, "a")
+	defer a.Close()
+	b := newTestNATSBroker(t, url, "b")
+	defer b.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, text := range []string{"first", "second"} {
+		if err := a.PublishInbound(ctx, InboundMessage{Channel: "slack", ChatID: "C1", Content: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if in, err := a.ConsumeInbound(ctx); err != nil || in.Content != "first" {
+		t.Fatalf("a: %+v %v", in, err)
+	}
+	// a's turn outlasts the ack wait; while it runs, the session's next
+	// message is not handed to b.
+	short, cancelShort := context.WithTimeout(ctx, time.Second)
+	defer cancelShort()
+	if in, err := b.ConsumeInbound(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("b got %+v, %v while a held the partition", in, err)
+	}
+	if in, err := a.ConsumeInbound(ctx); err != nil || in.Content != "second" {
+		t.Fatalf("a: %+v %v", in, err)
+	}
+}
+
+func TestNATSBroker_FailoverRedeliversPending(t *testing.T) {
+	_, url := startNATS(t)
+	a := newTestNATSBroker(t, url, "a")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := a.PublishInbound(ctx, InboundMessage{Channel: "slack", ChatID: "C1", Content: "turn"}); err != nil {
+		t.Fatal(err)
+	}
+	if in, err := a.ConsumeInbound(ctx); err != nil || in.Content != "turn" {
+		t.Fatalf("a: %+v %v", in, err)
+	}
+	// a stops before finishing the turn; the message is not acknowledged.
+	_ = a.Close()
+
+	b := newTestNATSBroker(t, url, "b")
+	defer b.Close()
+	if in, err := b.ConsumeInbound(ctx); err != nil || in.Content != "turn" {
+		t.Fatalf("b: %+v %v", in, err)
+	}
+}
+
+func TestNATSBroker_KeepsRepliesPendingUntilAcked(t *testing.T) {
+	_, url := startNATS(t)
+	a := newTestNATSBroker(t, url, "a", "telegram")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, text := range []string{"one", "two"} {
+		if err := a.PublishOutbound(ctx, OutboundMessage{Channel: "telegram", ChatID: "1", Content: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out []OutboundMessage
+	for range 2 {
+		m, err := a.ConsumeOutbound(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, m)
+	}
+	if err := a.AckOutbound(ctx, out[0]); err != nil {
+		t.Fatal(err)
+	}
+	_ = a.Close()
+
+	// "two" was never sent; it comes back once the ack wait runs out.
+	a = newTestNATSBroker(t, url, "a", "telegram")
+	defer a.Close()
+	if m, err := a.ConsumeOutbound(ctx); err != nil || m.Content != "two" {
+		t.Fatalf("got %+v %v", m, err)
+	}
+}
+
+func TestNATSBroker_ResumesAfterServerDrop(t *testing.T) {
+	f, url := startNATS(t)
+	a := newTestNATSBroker(t, url, "a")
+	defer a.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, text := range []string{"first", "second"} {
+		if err := a.PublishInbound(ctx, InboundMessage{Channel: "slack", ChatID: "C1", Content: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if in, err := a.ConsumeInbound(ctx); err != nil || in.Content != "first" {
+		t.Fatalf("first: %+v %v", in, err)
+	}
+	f.restart()
+
+	// The broker reconnects: "first" is acknowledged over the new
+	// connection and "second" follows it, not a redelivered "first".
+	if in, err := a.ConsumeInbound(ctx); err != nil || in.Content != "second" {
+		t.Fatalf("second: %+v %v", in, err)
+	}
+	if err := a.PublishInbound(ctx, InboundMessage{Channel: "slack", ChatID: "C1", Content: "third"}); err != nil {
+		t.Fatal(err)
+	}
+	if in, err := a.ConsumeInbound(ctx); err != nil || in.Content != "third" {
+		t.Fatalf("third: %+v %v", in, err)
+	}
+	for _, c := range a.in {
+		info, err := c.Info(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.NumAckPending > 1 {
+			t.Fatalf("consumer %s has %d messages out", info.Name, info.NumAckPending)
+		}
+	}
+}
+
+func TestNATSBroker_RedeliversAfterAckWait(t *testing.T) {
+	_, url := startNATS(t)
+	a := newTestNATSBroker(t, url, "a")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := a.PublishInbound(ctx, InboundMessage{Channel: "slack", ChatID: "C1", Content: "turn"}); err != nil {
+		t.Fatal(err)
+	}
+	if in, err := a.ConsumeInbound(ctx); err != nil || in.Content != "turn" {
+		t.Fatalf("a: %+v %v", in, err)
+	}
+	// a keeps the message in progress while it lives...
+	time.Sleep(2 * a.opts.AckWait)
+	b := newTestNATSBroker(t, url, "b")
+	defer b.Close()
+	short, cancelShort := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancelShort()
+	if in, err := b.ConsumeInbound(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("b got %+v, %v while a renewed the message", in, err)
+	}
+	// ...and once it stops, the message goes out again after the ack wait.
+	_ = a.Close()
+	if in, err := b.ConsumeInbound(ctx); err != nil || in.Content != "turn" {
+		t.Fatalf("b: %+v %v", in, err)
+	}
+}
+
+func TestNATSToken(t *testing.T) {
+	if got := natsToken("out-ch-telegram.work"); got != "out-ch-telegram_work" {
+		t.Fatalf("natsToken = %q", got)
+	}
+}
+
+func TestNATSBroker_SubscribersSeeEveryInstance(t *testing.T) {
+	_, url := startNATS(t)
+	a := NewWithBroker(newTestNATSBroker(t, url, "a"))
+	defer a.Close()
+	b := NewWithBroker(newTestNATSBroker(t, url, "b"))
+	defer b.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	obs, stop := a.Subscribe(4)
+	defer stop()
+	time.Sleep(50 * time.Millisecond) // let the subscription reach the server
+	for _, bus := range []*Bus{a, b} {
+		if err := bus.PublishInbound(ctx, InboundMessage{Channel: "slack", ChatID: "C1", Content: "via " + bus.broker.(*NATSBroker).opts.InstanceID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := map[string]bool{}
+	for len(got) < 2 {
+		select {
+		case m := <-obs:
+			got[m.Content] = true
+		case <-ctx.Done():
+			t.Fatalf("observed %v", got)
+		}
+	}
+	if !got["via a"] || !got["via b"] {
+		t.Fatalf("observed %v", got)
+	}
+}
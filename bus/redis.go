@@ -0,0 +1,633 @@
+package bus
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultRedisPrefix     = "clawlet"
+	DefaultRedisPartitions = 16
+	DefaultRedisLeaseTTL   = 15 * time.Second
+
+	redisStreamMaxLen    = 10000
+	redisBlock           = time.Second
+	maxInlineAttachBytes = 20 << 20
+	maxOriginRoutes      = 10000
+)
+
+type RedisOptions struct {
+	// URL is a redis:// or rediss:// (TLS) URL, optionally with a user,
+	// password and database number.
+	URL    string
+	Prefix string
+	// InstanceID must be unique per instance and stable across restarts, so
+	// a restarted instance resumes its own pending replies.
+	InstanceID string
+	// Partitions shard inbound messages by session; all instances must use
+	// the same value.
+	Partitions int
+	// Channels are the chat channels running on this instance. Replies for
+	// chats first seen elsewhere are sent by whichever instance runs the
+	// channel.
+	Channels []string
+	LeaseTTL time.Duration
+}
+
+// RedisBroker shares the bus over Redis Streams. Inbound messages are
+// partitioned by session key; each partition is consumed by the instance
+// holding its lease, which gives session affinity and fails over when an
+// instance stops renewing. Replies are routed back to the instance that
+// received the message, or to any instance running the channel.
+//
+// Delivery is at least once: an inbound entry is acknowledged when the next
+// message is consumed, so an instance dying mid-turn leaves it for the next
+// owner, and an outbound entry by AckOutbound once it has been sent.
+type RedisBroker struct {
+	opts RedisOptions
+	rdb  *redis.Client
+
+	mu         sync.Mutex
+	owned      map[int]bool
+	claim      map[int]bool // newly acquired partitions to take over
+	origin     map[string]string
+	inPending  *streamEntry
+	outStreams []string
+	// outCursor walks the outbound entries an earlier run left pending for
+	// this instance; it is nil once they have all been handed out again.
+	outCursor map[string]string
+	// outQueue holds entries read but not handed out yet: COUNT applies to
+	// each stream of a read.
+	outQueue []*streamEntry
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type streamEntry struct {
+	stream string
+	id     string
+	data   string
+}
+
+type inboundEnvelope struct {
+	Origin string         `json:"origin"`
+	Msg    InboundMessage `json:"msg"`
+}
+
+func NewRedisBroker(ctx context.Context, opts RedisOptions) (*RedisBroker, error) {
+	if strings.TrimSpace(opts.URL) == "" {
+		return nil, errors.New("redis broker: url is required")
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultRedisPrefix
+	}
+	if opts.Partitions <= 0 {
+		opts.Partitions = DefaultRedisPartitions
+	}
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = DefaultRedisLeaseTTL
+	}
+	if opts.InstanceID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("redis broker: instance id: %w", err)
+		}
+		opts.InstanceID = host
+	}
+	ropts, err := redis.ParseURL(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("redis broker: %w", err)
+	}
+	b := &RedisBroker{
+		opts:   opts,
+		rdb:    redis.NewClient(ropts),
+		owned:  map[int]bool{},
+		claim:  map[int]bool{},
+		origin: map[string]string{},
+
+		outCursor: map[string]string{},
+	}
+	if err := b.rdb.Ping(ctx).Err(); err != nil {
+		_ = b.rdb.Close()
+		return nil, fmt.Errorf("redis broker: %w", err)
+	}
+	b.outStreams = []string{b.key("out", "inst", opts.InstanceID)}
+	for _, ch := range opts.Channels {
+		b.outStreams = append(b.outStreams, b.key("out", "ch", ch))
+	}
+	for _, s := range b.outStreams {
+		if err := b.ensureGroup(ctx, s, b.senderGroup()); err != nil {
+			_ = b.rdb.Close()
+			return nil, err
+		}
+	}
+	lctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+	b.renewLeases(lctx)
+	go b.leaseLoop(lctx)
+	return b, nil
+}
+
+func (b *RedisBroker) key(parts ...string) string {
+	return b.opts.Prefix + ":" + strings.Join(parts, ":")
+}
+
+func (b *RedisBroker) workerGroup() string { return b.opts.Prefix + "-workers" }
+func (b *RedisBroker) senderGroup() string { return b.opts.Prefix + "-senders" }
+
+func (b *RedisBroker) inStream(p int) string { return b.key("in", strconv.Itoa(p)) }
+
+// partitionFor maps a session to a partition; it must be identical on every
+// instance.
+func partitionFor(sessionKey string, partitions int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(sessionKey))
+	return int(h.Sum32() % uint32(partitions))
+}
+
+func inboundSessionKey(msg InboundMessage) string {
+	if k := strings.TrimSpace(msg.SessionKey); k != "" {
+		return k
+	}
+	return msg.Channel + ":" + msg.ChatID
+}
+
+func (b *RedisBroker) ensureGroup(ctx context.Context, stream, group string) error {
+	err := b.rdb.XGroupCreateMkStream(ctx, stream, group, "0").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+func (b *RedisBroker) PublishInbound(ctx context.Context, msg InboundMessage) error {
+	msg.Attachments = inlineAttachments(msg.Attachments)
+	data, err := json.Marshal(inboundEnvelope{Origin: b.opts.InstanceID, Msg: msg})
+	if err != nil {
+		return err
+	}
+	return b.add(ctx, b.inStream(partitionFor(inboundSessionKey(msg), b.opts.Partitions)), data)
+}
+
+func (b *RedisBroker) PublishOutbound(ctx context.Context, msg OutboundMessage) error {
+	msg.Attachments = inlineAttachments(msg.Attachments)
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	origin := b.origin[msg.Channel+":"+msg.ChatID]
+	b.mu.Unlock()
+	stream := b.key("out", "ch", msg.Channel)
+	if origin != "" {
+		stream = b.key("out", "inst", origin)
+	}
+	return b.add(ctx, stream, data)
+}
+
+func (b *RedisBroker) add(ctx context.Context, stream string, data []byte) error {
+	return b.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: redisStreamMaxLen,
+		Approx: true,
+		Values: []string{"m", string(data)},
+	}).Err()
+}
+
+// inlineAttachments copies local files into Data: other instances cannot
+// read this host's files. Files over maxInlineAttachBytes keep their path.
+func inlineAttachments(atts []Attachment) []Attachment {
+	if len(atts) == 0 {
+		return atts
+	}
+	atts = slices.Clone(atts)
+	for i, a := range atts {
+		if a.LocalPath == "" || len(a.Data) > 0 {
+			continue
+		}
+		if a.SizeBytes > maxInlineAttachBytes {
+			continue
+		}
+		data, err := os.ReadFile(a.LocalPath)
+		if err != nil || len(data) > maxInlineAttachBytes {
+			continue
+		}
+		atts[i].Data = data
+		atts[i].LocalPath = ""
+	}
+	return atts
+}
+
+func (b *RedisBroker) ConsumeInbound(ctx context.Context) (InboundMessage, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return InboundMessage{}, err
+		}
+		b.ack(ctx, &b.inPending, b.workerGroup())
+		streams := b.ownedStreams(ctx)
+		if len(streams) == 0 {
+			if err := sleepCtx(ctx, redisBlock); err != nil {
+				return InboundMessage{}, err
+			}
+			continue
+		}
+		e, err := b.read(ctx, b.workerGroup(), streams)
+		if err != nil {
+			log.Printf("bus: redis inbound read failed: %v", err)
+			if err := sleepCtx(ctx, redisBlock); err != nil {
+				return InboundMessage{}, err
+			}
+			continue
+		}
+		if e == nil {
+			continue
+		}
+		b.mu.Lock()
+		b.inPending = e
+		b.mu.Unlock()
+		var env inboundEnvelope
+		if err := json.Unmarshal([]byte(e.data), &env); err != nil {
+			log.Printf("bus: dropping malformed inbound entry %s: %v", e.id, err)
+			continue
+		}
+		if env.Origin != "" {
+			b.rememberOrigin(env.Msg.Channel+":"+env.Msg.ChatID, env.Origin)
+		}
+		return env.Msg, nil
+	}
+}
+
+func (b *RedisBroker) ConsumeOutbound(ctx context.Context) (OutboundMessage, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return OutboundMessage{}, err
+		}
+		e, err := b.readOutbound(ctx)
+		if err != nil {
+			log.Printf("bus: redis outbound read failed: %v", err)
+			if err := sleepCtx(ctx, redisBlock); err != nil {
+				return OutboundMessage{}, err
+			}
+			continue
+		}
+		if e == nil {
+			continue
+		}
+		var msg OutboundMessage
+		if err := json.Unmarshal([]byte(e.data), &msg); err != nil {
+			log.Printf("bus: dropping malformed outbound entry %s: %v", e.id, err)
+			b.xack(ctx, e, b.senderGroup())
+			continue
+		}
+		msg.ack = e.stream + " " + e.id
+		return msg, nil
+	}
+}
+
+// AckOutbound acknowledges msg, from ConsumeOutbound, once it is sent.
+func (b *RedisBroker) AckOutbound(ctx context.Context, msg OutboundMessage) error {
+	i := strings.LastIndexByte(msg.ack, ' ')
+	if i < 0 {
+		return nil
+	}
+	return b.rdb.XAck(ctx, msg.ack[:i], b.senderGroup(), msg.ack[i+1:]).Err()
+}
+
+// readOutbound returns the entries an earlier run of this instance left
+// pending first, then new ones. Entries handed out stay pending until
+// AckOutbound, so several can be out at once and the pending list is walked
+// only once.
+func (b *RedisBroker) readOutbound(ctx context.Context) (*streamEntry, error) {
+	if len(b.outQueue) == 0 && b.outCursor != nil {
+		starts := make([]string, len(b.outStreams))
+		for i, s := range b.outStreams {
+			starts[i] = cmp.Or(b.outCursor[s], "0")
+		}
+		es, err := b.readFrom(ctx, b.senderGroup(), b.outStreams, starts)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range es {
+			b.outCursor[e.stream] = e.id
+		}
+		if len(es) == 0 {
+			b.outCursor = nil
+		}
+		b.outQueue = es
+	}
+	if len(b.outQueue) == 0 {
+		starts := make([]string, len(b.outStreams))
+		for i := range starts {
+			starts[i] = ">"
+		}
+		es, err := b.readFrom(ctx, b.senderGroup(), b.outStreams, starts)
+		if err != nil {
+			return nil, err
+		}
+		b.outQueue = es
+	}
+	if len(b.outQueue) == 0 {
+		return nil, nil
+	}
+	e := b.outQueue[0]
+	b.outQueue = b.outQueue[1:]
+	return e, nil
+}
+
+func (b *RedisBroker) ack(ctx context.Context, pending **streamEntry, group string) {
+	b.mu.Lock()
+	e := *pending
+	*pending = nil
+	b.mu.Unlock()
+	if e == nil {
+		return
+	}
+	b.xack(ctx, e, group)
+}
+
+func (b *RedisBroker) xack(ctx context.Context, e *streamEntry, group string) {
+	if err := b.rdb.XAck(ctx, e.stream, group, e.id).Err(); err != nil {
+		log.Printf("bus: redis ack %s failed: %v", e.id, err)
+	}
+}
+
+// ownedStreams returns the inbound streams of leased partitions, first
+// taking over entries left pending by a previous owner.
+func (b *RedisBroker) ownedStreams(ctx context.Context) []string {
+	b.mu.Lock()
+	var parts, claim []int
+	for p := range b.owned {
+		parts = append(parts, p)
+	}
+	for p := range b.claim {
+		claim = append(claim, p)
+	}
+	clear(b.claim)
+	b.mu.Unlock()
+	slices.Sort(parts)
+	for _, p := range claim {
+		s := b.inStream(p)
+		if err := b.ensureGroup(ctx, s, b.workerGroup()); err != nil {
+			log.Printf("bus: redis group %s: %v", s, err)
+			continue
+		}
+		err := b.rdb.XAutoClaimJustID(ctx, &redis.XAutoClaimArgs{
+			Stream:   s,
+			Group:    b.workerGroup(),
+			Consumer: b.opts.InstanceID,
+			Start:    "0-0",
+			Count:    1000,
+		}).Err()
+		if err != nil {
+			log.Printf("bus: redis claim %s: %v", s, err)
+		}
+	}
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		out = append(out, b.inStream(p))
+	}
+	return out
+}
+
+// read returns this consumer's oldest pending entry, or blocks briefly for
+// a new one. A nil entry means nothing arrived. Entries of other streams
+// read along with it stay pending and come first next time.
+func (b *RedisBroker) read(ctx context.Context, group string, streams []string) (*streamEntry, error) {
+	for _, start := range []string{"0", ">"} {
+		starts := make([]string, len(streams))
+		for i := range starts {
+			starts[i] = start
+		}
+		if es, err := b.readFrom(ctx, group, streams, starts); err != nil || len(es) > 0 {
+			if err != nil {
+				return nil, err
+			}
+			return es[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// readFrom reads up to one entry of each of streams after the given IDs.
+// ">" asks for new entries and blocks for a while; an ID reads this
+// consumer's pending entries after it.
+func (b *RedisBroker) readFrom(ctx context.Context, group string, streams, starts []string) ([]*streamEntry, error) {
+	block := time.Duration(-1)
+	if slices.Contains(starts, ">") {
+		block = redisBlock
+	}
+	res, err := b.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: b.opts.InstanceID,
+		Streams:  append(slices.Clone(streams), starts...),
+		Count:    1,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []*streamEntry
+	for _, s := range res {
+		for _, m := range s.Messages {
+			// Pending entries trimmed from the stream have no fields; they
+			// are returned with empty data so the caller acknowledges them.
+			data, _ := m.Values["m"].(string)
+			out = append(out, &streamEntry{stream: s.Stream, id: m.ID, data: data})
+		}
+	}
+	return out, nil
+}
+
+// WatchInbound reads every inbound stream without a consumer group, so fn
+// sees all instances' messages and none are taken from the agents.
+func (b *RedisBroker) WatchInbound(ctx context.Context, fn func(InboundMessage)) error {
+	// Entry IDs start with the server's time in milliseconds.
+	now, err := b.rdb.Time(ctx).Result()
+	if err != nil {
+		return err
+	}
+	streams := make([]string, b.opts.Partitions)
+	last := make([]string, b.opts.Partitions)
+	for p := range streams {
+		streams[p] = b.inStream(p)
+		last[p] = strconv.FormatInt(now.UnixMilli(), 10) + "-0"
+	}
+	for {
+		res, err := b.rdb.XRead(ctx, &redis.XReadArgs{
+			Streams: append(slices.Clone(streams), last...),
+			Block:   redisBlock,
+		}).Result()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, s := range res {
+			p := slices.Index(streams, s.Stream)
+			for _, m := range s.Messages {
+				last[p] = m.ID
+				data, _ := m.Values["m"].(string)
+				var env inboundEnvelope
+				if json.Unmarshal([]byte(data), &env) == nil {
+					fn(env.Msg)
+				}
+			}
+		}
+	}
+}
+
+func (b *RedisBroker) rememberOrigin(chat, origin string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.origin[chat]; !ok && len(b.origin) >= maxOriginRoutes {
+		clear(b.origin)
+	}
+	b.origin[chat] = origin
+}
+
+func (b *RedisBroker) leaseLoop(ctx context.Context) {
+	defer close(b.done)
+	t := time.NewTicker(b.opts.LeaseTTL / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			b.releaseAll()
+			return
+		case <-t.C:
+			b.renewLeases(ctx)
+		}
+	}
+}
+
+var (
+	renewLeaseScript   = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`)
+	releaseLeaseScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`)
+)
+
+// renewLeases heartbeats membership, keeps owned partitions and acquires
+// free ones up to an even share of the live instances.
+func (b *RedisBroker) renewLeases(ctx context.Context) {
+	ttl := b.opts.LeaseTTL.Milliseconds()
+	now := time.Now().UnixMilli()
+	members := b.key("members")
+	if err := b.rdb.ZAdd(ctx, members, redis.Z{Score: float64(now), Member: b.opts.InstanceID}).Err(); err != nil {
+		log.Printf("bus: redis heartbeat failed: %v", err)
+		return
+	}
+	_ = b.rdb.ZRemRangeByScore(ctx, members, "-inf", strconv.FormatInt(now-ttl, 10)).Err()
+	live := int64(1)
+	if n, err := b.rdb.ZCard(ctx, members).Result(); err == nil && n > 0 {
+		live = n
+	}
+	share := (b.opts.Partitions + int(live) - 1) / int(live)
+
+	b.mu.Lock()
+	var owned []int
+	for p := range b.owned {
+		owned = append(owned, p)
+	}
+	b.mu.Unlock()
+	slices.Sort(owned)
+
+	kept := 0
+	for _, p := range owned {
+		lease := b.key("lease", strconv.Itoa(p))
+		if kept >= share {
+			_ = releaseLeaseScript.Run(ctx, b.rdb, []string{lease}, b.opts.InstanceID).Err()
+			b.setOwned(p, false)
+			continue
+		}
+		n, err := renewLeaseScript.Run(ctx, b.rdb, []string{lease}, b.opts.InstanceID, ttl).Int()
+		if err != nil || n != 1 {
+			b.setOwned(p, false)
+			continue
+		}
+		kept++
+	}
+	for p := 0; p < b.opts.Partitions && kept < share; p++ {
+		b.mu.Lock()
+		mine := b.owned[p]
+		b.mu.Unlock()
+		if mine {
+			continue
+		}
+		ok, err := b.rdb.SetNX(ctx, b.key("lease", strconv.Itoa(p)), b.opts.InstanceID, b.opts.LeaseTTL).Result()
+		if err != nil || !ok {
+			continue
+		}
+		b.mu.Lock()
+		b.owned[p] = true
+		b.claim[p] = true
+		b.mu.Unlock()
+		kept++
+	}
+}
+
+func (b *RedisBroker) setOwned(p int, owned bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if owned {
+		b.owned[p] = true
+		return
+	}
+	delete(b.owned, p)
+	delete(b.claim, p)
+}
+
+func (b *RedisBroker) releaseAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	b.mu.Lock()
+	var owned []int
+	for p := range b.owned {
+		owned = append(owned, p)
+	}
+	clear(b.owned)
+	b.mu.Unlock()
+	for _, p := range owned {
+		_ = releaseLeaseScript.Run(ctx, b.rdb, []string{b.key("lease", strconv.Itoa(p))}, b.opts.InstanceID).Err()
+	}
+	_ = b.rdb.ZRem(ctx, b.key("members"), b.opts.InstanceID).Err()
+}
+
+// Close releases this instance's partitions so others take over without
+// waiting for the leases to expire. Unacknowledged entries stay pending.
+func (b *RedisBroker) Close() error {
+	b.cancel()
+	<-b.done
+	return b.rdb.Close()
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
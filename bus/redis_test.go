@@ -0,0 +1,245 @@
+package bus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func startRedis(t *testing.T) (*miniredis.Miniredis, string) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	return m, "redis://" + m.Addr()
+}
+
+// pendingInbound counts the inbound entries b's instances took and have
+// not acknowledged.
+func pendingInbound(t *testing.T, b *RedisBroker) int64 {
+	t.Helper()
+	var n int64
+	for p := range b.opts.Partitions {
+		res, err := b.rdb.XPending(context.Background(), b.inStream(p), b.workerGroup()).Result()
+		if err != nil {
+			continue // no group yet
+		}
+		n += res.Count
+	}
+	return n
+}
+
+func newTestBroker(t *testing.T, url, id string, channels ...string) *RedisBroker {
+	t.Helper()
+	b, err := NewRedisBroker(context.Background(), RedisOptions{
+		URL:        url,
+		InstanceID: id,
+		Partitions: 4,
+		Channels:   channels,
+		LeaseTTL:   300 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRedisBroker_RoutesRepliesToOrigin(t *testing.T) {
+	_, url := startRedis(t)
+	a := newTestBroker(t, url, "a", "telegram")
+	defer a.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := a.PublishInbound(ctx, InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	in, err := a.ConsumeInbound(ctx)
+	if err != nil || in.Content != "hi" || in.ChatID != "1" {
+		t.Fatalf("inbound: %+v %v", in, err)
+	}
+	if err := a.PublishOutbound(ctx, OutboundMessage{Channel: "telegram", ChatID: "1", Content: "reply"}); err != nil {
+		t.Fatal(err)
+	}
+	// A chat never seen by this instance goes to the channel stream.
+	if err := a.PublishOutbound(ctx, OutboundMessage{Channel: "telegram", ChatID: "2", Content: "push"}); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for range 2 {
+		out, err := a.ConsumeOutbound(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[out.Content] = true
+	}
+	if !got["reply"] || !got["push"] {
+		t.Fatalf("outbound: %v", got)
+	}
+}
+
+func TestRedisBroker_FailoverRedeliversPending(t *testing.T) {
+	_, url := startRedis(t)
+	a := newTestBroker(t, url, "a")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := a.PublishInbound(ctx, InboundMessage{Channel: "slack", ChatID: "C1", Content: "turn"}); err != nil {
+		t.Fatal(err)
+	}
+	if in, err := a.ConsumeInbound(ctx); err != nil || in.Content != "turn" {
+		t.Fatalf("a: %+v %v", in, err)
+	}
+	// a stops before finishing the turn; the entry stays unacknowledged.
+	_ = a.Close()
+
+	b := newTestBroker(t, url, "b")
+	defer b.Close()
+	in, err := b.ConsumeInbound(ctx)
+	if err != nil || in.Content != "turn" {
+		t.Fatalf("b: %+v %v", in, err)
+	}
+	// Consuming the next message acknowledges the previous one.
+	go func() { _, _ = b.ConsumeInbound(ctx) }()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		pending := pendingInbound(t, b)
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("entry not acknowledged (%d pending)", pending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRedisBroker_KeepsRepliesPendingUntilAcked(t *testing.T) {
+	_, url := startRedis(t)
+	a := newTestBroker(t, url, "a", "telegram")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, text := range []string{"one", "two", "three"} {
+		if err := a.PublishOutbound(ctx, OutboundMessage{Channel: "telegram", ChatID: "1", Content: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Several replies can be out at once; only "one" gets sent.
+	var out []OutboundMessage
+	for range 3 {
+		m, err := a.ConsumeOutbound(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, m)
+	}
+	if err := a.AckOutbound(ctx, out[0]); err != nil {
+		t.Fatal(err)
+	}
+	_ = a.Close()
+
+	// The restarted instance resends the replies that were not sent.
+	a = newTestBroker(t, url, "a", "telegram")
+	defer a.Close()
+	for _, want := range []string{"two", "three"} {
+		m, err := a.ConsumeOutbound(ctx)
+		if err != nil || m.Content != want {
+			t.Fatalf("got %+v %v, want %q", m, err, want)
+		}
+	}
+}
+
+func TestRedisBroker_ResumesAfterServerDrop(t *testing.T) {
+	f, url := startRedis(t)
+	a := newTestBroker(t, url, "a")
+	defer a.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, text := range []string{"first", "second"} {
+		if err := a.PublishInbound(ctx, InboundMessage{Channel: "slack", ChatID: "C1", Content: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if in, err := a.ConsumeInbound(ctx); err != nil || in.Content != "first" {
+		t.Fatalf("first: %+v %v", in, err)
+	}
+	// The server restarts with its data; "first" is acknowledged over a new
+	// connection and "second" follows.
+	f.Restart()
+	if in, err := a.ConsumeInbound(ctx); err != nil || in.Content != "second" {
+		t.Fatalf("second: %+v %v", in, err)
+	}
+	if pending := pendingInbound(t, a); pending != 1 {
+		t.Fatalf("%d entries pending, want only the one being handled", pending)
+	}
+}
+
+func TestRedisBroker_SplitsPartitions(t *testing.T) {
+	_, url := startRedis(t)
+	a := newTestBroker(t, url, "a")
+	defer a.Close()
+	b := newTestBroker(t, url, "b")
+	defer b.Close()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		a.mu.Lock()
+		na := len(a.owned)
+		a.mu.Unlock()
+		b.mu.Lock()
+		nb := len(b.owned)
+		b.mu.Unlock()
+		if na == 2 && nb == 2 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("partitions a=%d b=%d", na, nb)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestPartitionFor_Stable(t *testing.T) {
+	p := partitionFor("telegram:42", 16)
+	for range 10 {
+		if partitionFor("telegram:42", 16) != p {
+			t.Fatal("partition changed")
+		}
+	}
+	if p < 0 || p >= 16 {
+		t.Fatalf("partition %d out of range", p)
+	}
+}
+
+func TestRedisBroker_SubscribersSeeEveryInstance(t *testing.T) {
+	_, url := startRedis(t)
+	a := NewWithBroker(newTestBroker(t, url, "a"))
+	defer a.Close()
+	b := NewWithBroker(newTestBroker(t, url, "b"))
+	defer b.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	obs, stop := a.Subscribe(4)
+	defer stop()
+	time.Sleep(50 * time.Millisecond) // let the watch start reading
+	for _, bus := range []*Bus{a, b} {
+		if err := bus.PublishInbound(ctx, InboundMessage{Channel: "slack", ChatID: "C1", Content: "via " + bus.broker.(*RedisBroker).opts.InstanceID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := map[string]bool{}
+	for len(got) < 2 {
+		select {
+		case m := <-obs:
+			got[m.Content] = true
+		case <-ctx.Done():
+			t.Fatalf("observed %v", got)
+		}
+	}
+	if !got["via a"] || !got["via b"] {
+		t.Fatalf("observed %v", got)
+	}
+}
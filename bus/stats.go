@@ -0,0 +1,122 @@
+package bus
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Stats describes the load on a bus, so saturation shows before publishes
+// start timing out.
+type Stats struct {
+	Inbound  QueueStats `json:"inbound"`
+	Outbound QueueStats `json:"outbound"`
+}
+
+// QueueStats describes one direction of a bus since the process started.
+type QueueStats struct {
+	// Depth is how many messages wait to be consumed, and Capacity how
+	// many fit before publishes block. Both are -1 with a broker.
+	Depth    int `json:"depth"`
+	Capacity int `json:"capacity"`
+	// Published counts the messages published and Dropped the publishes
+	// that failed: timed out waiting for room, canceled, or refused by the
+	// broker. Callers often ignore the error, so these are lost messages.
+	Published int64 `json:"published"`
+	Dropped   int64 `json:"dropped"`
+	// PublishWait is the total time publishes took and MaxPublishWait the
+	// longest one; a growing average means the consumer is falling behind.
+	PublishWait    time.Duration `json:"publishWaitNs"`
+	MaxPublishWait time.Duration `json:"maxPublishWaitNs"`
+}
+
+// queueCounters collects the QueueStats of one direction.
+type queueCounters struct {
+	published atomic.Int64
+	dropped   atomic.Int64
+	waitNS    atomic.Int64
+	maxWaitNS atomic.Int64
+}
+
+// observe records a publish that started at start; err points at its
+// result.
+func (q *queueCounters) observe(start time.Time, err *error) {
+	d := int64(time.Since(start))
+	q.waitNS.Add(d)
+	for {
+		cur := q.maxWaitNS.Load()
+		if d <= cur || q.maxWaitNS.CompareAndSwap(cur, d) {
+			break
+		}
+	}
+	if *err != nil {
+		q.dropped.Add(1)
+	} else {
+		q.published.Add(1)
+	}
+}
+
+func (q *queueCounters) stats(depth, capacity int) QueueStats {
+	return QueueStats{
+		Depth:          depth,
+		Capacity:       capacity,
+		Published:      q.published.Load(),
+		Dropped:        q.dropped.Load(),
+		PublishWait:    time.Duration(q.waitNS.Load()),
+		MaxPublishWait: time.Duration(q.maxWaitNS.Load()),
+	}
+}
+
+// Stats returns the bus's queue depths and publish counters.
+func (b *Bus) Stats() Stats {
+	if b.broker != nil {
+		return Stats{
+			Inbound:  b.inStats.stats(-1, -1),
+			Outbound: b.outStats.stats(-1, -1),
+		}
+	}
+	return Stats{
+		Inbound:  b.inStats.stats(len(b.in), cap(b.in)),
+		Outbound: b.outStats.stats(len(b.out), cap(b.out)),
+	}
+}
+
+// WriteText writes s in the Prometheus text exposition format.
+func (s Stats) WriteText(w io.Writer) error {
+	var b strings.Builder
+	dirs := []struct {
+		name string
+		q    QueueStats
+	}{{"inbound", s.Inbound}, {"outbound", s.Outbound}}
+	if s.Inbound.Depth >= 0 {
+		b.WriteString("# TYPE clawlet_bus_queue_depth gauge\n")
+		for _, d := range dirs {
+			fmt.Fprintf(&b, "clawlet_bus_queue_depth{direction=%q} %d\n", d.name, d.q.Depth)
+		}
+		b.WriteString("# TYPE clawlet_bus_queue_capacity gauge\n")
+		for _, d := range dirs {
+			fmt.Fprintf(&b, "clawlet_bus_queue_capacity{direction=%q} %d\n", d.name, d.q.Capacity)
+		}
+	}
+	b.WriteString("# TYPE clawlet_bus_published_total counter\n")
+	for _, d := range dirs {
+		fmt.Fprintf(&b, "clawlet_bus_published_total{direction=%q} %d\n", d.name, d.q.Published)
+	}
+	b.WriteString("# TYPE clawlet_bus_dropped_total counter\n")
+	for _, d := range dirs {
+		fmt.Fprintf(&b, "clawlet_bus_dropped_total{direction=%q} %d\n", d.name, d.q.Dropped)
+	}
+	b.WriteString("# TYPE clawlet_bus_publish_wait_seconds summary\n")
+	for _, d := range dirs {
+		fmt.Fprintf(&b, "clawlet_bus_publish_wait_seconds_sum{direction=%q} %g\n", d.name, d.q.PublishWait.Seconds())
+		fmt.Fprintf(&b, "clawlet_bus_publish_wait_seconds_count{direction=%q} %d\n", d.name, d.q.Published+d.q.Dropped)
+	}
+	b.WriteString("# TYPE clawlet_bus_publish_wait_max_seconds gauge\n")
+	for _, d := range dirs {
+		fmt.Fprintf(&b, "clawlet_bus_publish_wait_max_seconds{direction=%q} %g\n", d.name, d.q.MaxPublishWait.Seconds())
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
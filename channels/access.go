@@ -0,0 +1,101 @@
+package channels
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Sender roles, as named in the "access" settings. They decide which tools
+// the agent may use in a turn a sender started; whether the sender is
+// answered at all is up to each channel's AllowList.
+const (
+	RoleAdmin    = "admin"
+	RoleUser     = "user"
+	RoleReadonly = "readonly"
+)
+
+// AccessPolicy gives each sender a role. Admins and Readonly hold
+// "channel:senderID" entries, e.g. "telegram:123456789"; both parts may use
+// the wildcards of path.Match, as in "slack:*".
+type AccessPolicy struct {
+	Admins   []string
+	Readonly []string
+	// AdminTools are the tools only admins may use.
+	AdminTools []string
+	// ReadonlyTools are the only tools readonly senders may use.
+	ReadonlyTools []string
+}
+
+// Role returns the role of a sender. Everyone is admin while neither Admins
+// nor Readonly is set, and so are the turns the gateway starts itself
+// (heartbeat, subagent results), which run on the cli channel without a
+// sender. Admin wins over readonly; any other sender is a user.
+func (p AccessPolicy) Role(channel, senderID string) string {
+	senderID = strings.TrimSpace(senderID)
+	if (channel == "cli" && senderID == "") || (len(p.Admins) == 0 && len(p.Readonly) == 0) {
+		return RoleAdmin
+	}
+	if senderID == "" {
+		return RoleUser
+	}
+	switch {
+	case p.IsAdmin(channel, senderID):
+		return RoleAdmin
+	case matchSender(p.Readonly, channel+":", senderID):
+		return RoleReadonly
+	default:
+		return RoleUser
+	}
+}
+
+// IsAdmin reports whether an entry of Admins names the sender. Unlike
+// Role, it does not treat everyone as admin while the lists are empty;
+// chat commands such as /trace and /pause use it.
+func (p AccessPolicy) IsAdmin(channel, senderID string) bool {
+	senderID = strings.TrimSpace(senderID)
+	return senderID != "" && matchSender(p.Admins, channel+":", senderID)
+}
+
+// Denied returns the tools out of all that a role may not use, or nil when
+// it may use them all.
+func (p AccessPolicy) Denied(role string, all []string) map[string]bool {
+	var denied map[string]bool
+	deny := func(name string) {
+		if denied == nil {
+			denied = map[string]bool{}
+		}
+		denied[name] = true
+	}
+	for _, name := range all {
+		switch role {
+		case RoleAdmin:
+		case RoleReadonly:
+			if !slices.Contains(p.ReadonlyTools, name) || slices.Contains(p.AdminTools, name) {
+				deny(name)
+			}
+		default:
+			if slices.Contains(p.AdminTools, name) {
+				deny(name)
+			}
+		}
+	}
+	return denied
+}
+
+// Validate rejects malformed entries; the gateway checks this at startup.
+func (p AccessPolicy) Validate() error {
+	for _, list := range [][]string{p.Admins, p.Readonly} {
+		for _, entry := range list {
+			channel, sender, ok := strings.Cut(entry, ":")
+			if !ok || strings.TrimSpace(channel) == "" || strings.TrimSpace(sender) == "" {
+				return fmt.Errorf("invalid sender %q (use channel:senderID)", entry)
+			}
+			if _, err := path.Match(entry, ""); err != nil {
+				return fmt.Errorf("invalid sender %q: %w", entry, err)
+			}
+		}
+	}
+	return nil
+}
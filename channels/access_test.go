@@ -0,0 +1,100 @@
+package channels
+
+import "testing"
+
+func TestAccessPolicy_Role(t *testing.T) {
+	if got := (AccessPolicy{}).Role("telegram", "42"); got != RoleAdmin {
+		t.Fatalf("without entries everyone is admin, got %q", got)
+	}
+	p := AccessPolicy{
+		Admins:   []string{"telegram:42", "slack:UADMIN*"},
+		Readonly: []string{"telegram:*", "discord:7"},
+	}
+	for _, tc := range []struct {
+		channel, sender, want string
+	}{
+		{"cli", "", RoleAdmin},
+		{"cli", "local", RoleUser},
+		{"telegram", "42", RoleAdmin},
+		{"telegram", "99|42", RoleAdmin},
+		{"telegram", "99", RoleReadonly},
+		{"slack", "UADMIN01", RoleAdmin},
+		{"slack", "U123", RoleUser},
+		{"discord", "7", RoleReadonly},
+		{"discord", "", RoleUser},
+	} {
+		if got := p.Role(tc.channel, tc.sender); got != tc.want {
+			t.Errorf("Role(%q, %q) = %q, want %q", tc.channel, tc.sender, got, tc.want)
+		}
+	}
+}
+
+func TestAccessPolicy_IsAdmin(t *testing.T) {
+	if (AccessPolicy{}).IsAdmin("telegram", "42") {
+		t.Fatal("without entries nobody is an admin by name")
+	}
+	p := AccessPolicy{Admins: []string{"telegram:42", "cli:local"}}
+	for _, tc := range []struct {
+		channel, sender string
+		want            bool
+	}{
+		{"telegram", "42", true},
+		{"telegram", "7|42", true},
+		{"telegram", "7", false},
+		{"cli", "local", true},
+		{"cli", "", false},
+		{"cli", "other", false},
+	} {
+		if got := p.IsAdmin(tc.channel, tc.sender); got != tc.want {
+			t.Errorf("IsAdmin(%q, %q) = %v, want %v", tc.channel, tc.sender, got, tc.want)
+		}
+	}
+}
+
+func TestAccessPolicy_Denied(t *testing.T) {
+	p := AccessPolicy{
+		AdminTools:    []string{"exec", "install_skill"},
+		ReadonlyTools: []string{"read_file", "exec"},
+	}
+	all := []string{"read_file", "write_file", "exec", "install_skill"}
+	if d := p.Denied(RoleAdmin, all); d != nil {
+		t.Fatalf("admin denied %v", d)
+	}
+	if d := p.Denied(RoleUser, all); len(d) != 2 || !d["exec"] || !d["install_skill"] {
+		t.Fatalf("user denied %v", d)
+	}
+	// Admin tools stay closed to readonly senders even when listed.
+	if d := p.Denied(RoleReadonly, all); len(d) != 3 || d["read_file"] {
+		t.Fatalf("readonly denied %v", d)
+	}
+}
+
+func TestAccessPolicy_Validate(t *testing.T) {
+	if err := (AccessPolicy{Admins: []string{"telegram:*"}}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []string{"42", "telegram:", ":42", "telegram:[1"} {
+		if err := (AccessPolicy{Readonly: []string{entry}}).Validate(); err == nil {
+			t.Errorf("Validate accepted %q", entry)
+		}
+	}
+}
+
+func TestAllowList_Wildcards(t *testing.T) {
+	a := AllowList{AllowFrom: []string{"U0*", "alice"}}
+	for sender, want := range map[string]bool{
+		"U0123":   true,
+		"alice":   true,
+		"9|alice": true,
+		"U1":      false,
+		"bob":     false,
+		"":        false,
+	} {
+		if got := a.Allowed(sender); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", sender, got, want)
+		}
+	}
+	if !(AllowList{AllowFrom: []string{"*"}}).Allowed("anyone") {
+		t.Fatal(`"*" did not allow everyone`)
+	}
+}
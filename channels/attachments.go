@@ -0,0 +1,130 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// DefaultAttachmentRefusal opens the reply to a message whose attachments
+// were not accepted; the reasons follow, one per file.
+const DefaultAttachmentRefusal = "Sorry, I can't accept this:"
+
+// AttachmentPolicy limits the attachments a channel passes on to the agent.
+type AttachmentPolicy struct {
+	// MaxBytes is the largest file accepted; 0 accepts any size. Files of
+	// unknown size are accepted.
+	MaxBytes int64
+	// AllowedTypes are the MIME types accepted, e.g. "application/pdf";
+	// "image/*" accepts a family. Empty accepts every type.
+	AllowedTypes []string
+	// Refusal opens the reply to a refused file; empty uses
+	// DefaultAttachmentRefusal.
+	Refusal string
+}
+
+// Validate rejects malformed types; the gateway checks this at startup.
+func (p AttachmentPolicy) Validate() error {
+	for _, t := range p.AllowedTypes {
+		if _, err := path.Match(t, ""); err != nil || !strings.Contains(t, "/") {
+			return fmt.Errorf("invalid MIME type %q (use e.g. image/png or image/*)", t)
+		}
+	}
+	return nil
+}
+
+// refuse returns why a is not accepted, or "" when it is.
+func (p AttachmentPolicy) refuse(a bus.Attachment) string {
+	if p.MaxBytes > 0 && a.SizeBytes > p.MaxBytes {
+		return "larger than " + formatSize(p.MaxBytes)
+	}
+	if len(p.AllowedTypes) == 0 {
+		return ""
+	}
+	mimeType, _, _ := strings.Cut(strings.ToLower(a.MIMEType), ";")
+	if mimeType = strings.TrimSpace(mimeType); mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	for _, t := range p.AllowedTypes {
+		if ok, _ := path.Match(strings.ToLower(t), mimeType); ok {
+			return ""
+		}
+	}
+	return "files of type " + mimeType + " are not accepted"
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30 && n%(1<<30) == 0:
+		return fmt.Sprintf("%d GB", n>>30)
+	case n >= 1<<20:
+		return fmt.Sprintf("%g MB", float64(n*10/(1<<20))/10)
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// SetAttachmentPolicies limits inbound attachments by channel name. Refused
+// files are taken off the message and the sender is told why; a message
+// left empty is dropped. Call it before StartAll.
+func (m *Manager) SetAttachmentPolicies(policies map[string]AttachmentPolicy) {
+	if len(policies) == 0 {
+		return
+	}
+	m.bus.SetInboundFilter(func(ctx context.Context, msg *bus.InboundMessage) bool {
+		p, ok := policies[msg.Channel]
+		if !ok || len(msg.Attachments) == 0 {
+			return true
+		}
+		return m.filterAttachments(ctx, p, msg)
+	})
+}
+
+func (m *Manager) filterAttachments(ctx context.Context, p AttachmentPolicy, msg *bus.InboundMessage) bool {
+	kept := msg.Attachments[:0:0]
+	var refused, names []string
+	for _, a := range msg.Attachments {
+		reason := p.refuse(a)
+		if reason == "" {
+			kept = append(kept, a)
+			continue
+		}
+		name := a.Name
+		if name == "" {
+			name = "a " + a.Kind + " file"
+			if a.Kind == "" {
+				name = "a file"
+			}
+		}
+		refused = append(refused, fmt.Sprintf("- %s: %s", name, reason))
+		names = append(names, name)
+	}
+	if len(refused) == 0 {
+		return true
+	}
+	text := strings.TrimSpace(p.Refusal)
+	if text == "" {
+		text = DefaultAttachmentRefusal
+	}
+	delivery := msg.Delivery
+	delivery.IsEdit = false
+	if err := m.bus.PublishOutbound(ctx, bus.OutboundMessage{
+		Channel:  msg.Channel,
+		ChatID:   msg.ChatID,
+		Content:  text + "\n" + strings.Join(refused, "\n"),
+		Delivery: delivery,
+	}); err != nil {
+		log.Printf("channels: attachment refusal not sent via %s: %v", msg.Channel, err)
+	}
+	msg.Attachments = kept
+	if len(kept) == 0 && strings.TrimSpace(msg.Content) == "" {
+		return false
+	}
+	msg.Content = strings.TrimSpace(msg.Content + "\n\n[not accepted: " + strings.Join(names, ", ") + "]")
+	return true
+}
@@ -0,0 +1,89 @@
+package channels
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+func TestAttachmentPolicyRefuse(t *testing.T) {
+	p := AttachmentPolicy{MaxBytes: 10 << 20, AllowedTypes: []string{"image/*", "application/pdf"}}
+	cases := []struct {
+		att  bus.Attachment
+		want string
+	}{
+		{bus.Attachment{MIMEType: "image/png", SizeBytes: 1 << 20}, ""},
+		{bus.Attachment{MIMEType: "application/pdf; charset=binary"}, ""},
+		{bus.Attachment{MIMEType: "image/jpeg", SizeBytes: 11 << 20}, "larger than 10 MB"},
+		{bus.Attachment{MIMEType: "video/mp4", SizeBytes: 1 << 20}, "files of type video/mp4 are not accepted"},
+		{bus.Attachment{}, "files of type application/octet-stream are not accepted"},
+	}
+	for _, c := range cases {
+		if got := p.refuse(c.att); got != c.want {
+			t.Errorf("refuse(%+v) = %q, want %q", c.att, got, c.want)
+		}
+	}
+	if got := (AttachmentPolicy{}).refuse(bus.Attachment{MIMEType: "video/mp4", SizeBytes: 2 << 30}); got != "" {
+		t.Errorf("an empty policy refused: %q", got)
+	}
+	if err := (AttachmentPolicy{AllowedTypes: []string{"image"}}).Validate(); err == nil {
+		t.Error("a type without / should be invalid")
+	}
+	if got := formatSize(1536 << 10); got != "1.5 MB" {
+		t.Errorf("formatSize = %q", got)
+	}
+}
+
+func TestManagerRefusesAttachments(t *testing.T) {
+	b := bus.New(16)
+	m := NewManager(b)
+	m.SetAttachmentPolicies(map[string]AttachmentPolicy{
+		"telegram": {MaxBytes: 1 << 20, Refusal: "That's too much for me:"},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	big := bus.Attachment{Name: "movie.mp4", Kind: "video", MIMEType: "video/mp4", SizeBytes: 2 << 30}
+	small := bus.Attachment{Name: "cat.png", Kind: "image", MIMEType: "image/png", SizeBytes: 1000}
+	publish := func(msg bus.InboundMessage) {
+		msg.ChatID = "42"
+		msg.Delivery = bus.Delivery{MessageID: "7"}
+		if err := b.PublishInbound(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectRefusal := func() {
+		t.Helper()
+		out, err := b.ConsumeOutbound(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.ChatID != "42" || out.Delivery.MessageID != "7" || out.Content != "That's too much for me:\n- movie.mp4: larger than 1 MB" {
+			t.Fatalf("refusal = %+v", out)
+		}
+	}
+
+	// Only a refused file: the message is dropped.
+	publish(bus.InboundMessage{Channel: "telegram", Attachments: []bus.Attachment{big}})
+	expectRefusal()
+
+	// With text and an accepted file, the rest goes on with a note.
+	publish(bus.InboundMessage{Channel: "telegram", Content: "look", Attachments: []bus.Attachment{big, small}})
+	expectRefusal()
+	in, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in.Attachments) != 1 || in.Attachments[0].Name != "cat.png" || !strings.HasSuffix(in.Content, "[not accepted: movie.mp4]") {
+		t.Fatalf("inbound = %+v", in)
+	}
+
+	// Other channels are not limited.
+	publish(bus.InboundMessage{Channel: "discord", Attachments: []bus.Attachment{big}})
+	if in, err := b.ConsumeInbound(ctx); err != nil || len(in.Attachments) != 1 {
+		t.Fatalf("discord inbound = %+v, %v", in, err)
+	}
+}
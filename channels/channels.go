@@ -2,6 +2,7 @@ package channels
 
 import (
 	"context"
+	"path"
 	"slices"
 	"strings"
 
@@ -16,6 +17,55 @@ type Channel interface {
 	IsRunning() bool
 }
 
+// AttachmentSender is implemented by channels that upload
+// OutboundMessage.Attachments natively. Other channels get the file names
+// appended to the text instead.
+type AttachmentSender interface {
+	SupportsAttachments() bool
+}
+
+// CardSender is implemented by channels that render OutboundMessage.Cards
+// natively. Other channels get them as text after the message.
+type CardSender interface {
+	SupportsCards() bool
+}
+
+// Previewer is implemented by channels that rewrite message text before
+// sending it. Preview returns text as the channel would send it.
+type Previewer interface {
+	Preview(text string) string
+}
+
+// Streamer is implemented by channels that edit one message in place as a
+// streamed reply grows. Other channels only get the final message.
+type Streamer interface {
+	SupportsStreaming() bool
+}
+
+// Reactor is implemented by channels that can react to a message with an
+// emoji (OutboundMessage of bus.MessageKindReaction). Reactions to other
+// channels are dropped.
+type Reactor interface {
+	SupportsReactions() bool
+}
+
+// Poller is implemented by channels that create native polls
+// (OutboundMessage of bus.MessageKindPoll). Other channels get the poll as
+// a numbered list.
+type Poller interface {
+	SupportsPolls() bool
+}
+
+// Typer is implemented by channels that can show a typing indicator. The
+// indicator lapses after a few seconds, so the manager repeats SendTyping
+// while the agent is working on a reply (OutboundMessage of
+// bus.MessageKindWorking).
+type Typer interface {
+	SendTyping(ctx context.Context, chatID string) error
+}
+
+// AllowList holds the senders a channel answers. Entries may use the * and ?
+// wildcards of path.Match, e.g. "U0*"; "*" allows everyone.
 type AllowList struct {
 	AllowFrom []string
 }
@@ -28,18 +78,27 @@ func (a AllowList) Allowed(senderID string) bool {
 	if senderID == "" {
 		return false
 	}
-	if slices.Contains(a.AllowFrom, senderID) {
-		return true
-	}
-	// Accept compound IDs (e.g. "a|b")
+	return matchSender(a.AllowFrom, "", senderID)
+}
+
+// matchSender reports whether prefix+senderID matches one of patterns. A
+// compound ID (e.g. "a|b") matches on any of its parts.
+func matchSender(patterns []string, prefix, senderID string) bool {
+	ids := []string{senderID}
 	if strings.Contains(senderID, "|") {
-		parts := strings.SplitSeq(senderID, "|")
-		for p := range parts {
-			p = strings.TrimSpace(p)
-			if p == "" {
-				continue
-			}
-			if slices.Contains(a.AllowFrom, p) {
+		ids = append(ids, strings.Split(senderID, "|")...)
+	}
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		id = prefix + id
+		if slices.Contains(patterns, id) {
+			return true
+		}
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, id); ok {
 				return true
 			}
 		}
@@ -0,0 +1,349 @@
+// Package cli is a terminal channel: the REPL behind `clawlet chat`. It talks
+// to the agent through the bus like any chat app, so commands, suggestions
+// and attachments behave as they do elsewhere.
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/term"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+const (
+	prompt   = "> "
+	senderID = "local"
+)
+
+type Options struct {
+	// ChatID names the conversation; its session key is "cli:<ChatID>".
+	ChatID string
+	// In and Out default to os.Stdin and os.Stdout. Line editing and
+	// history are available when In is a terminal.
+	In  io.Reader
+	Out io.Writer
+}
+
+type Channel struct {
+	bus    *bus.Bus
+	chatID string
+	in     io.Reader
+	out    io.Writer
+
+	running  atomic.Bool
+	done     chan struct{}
+	doneOnce sync.Once
+
+	mu          sync.Mutex
+	w           io.Writer // out, or the terminal while it is in raw mode
+	staged      []bus.Attachment
+	suggestions []string
+	// streamID and streamed are the reply being streamed and the text of it
+	// printed so far.
+	streamID string
+	streamed string
+}
+
+func New(b *bus.Bus, opts Options) *Channel {
+	if strings.TrimSpace(opts.ChatID) == "" {
+		opts.ChatID = "default"
+	}
+	if opts.In == nil {
+		opts.In = os.Stdin
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	return &Channel{
+		bus:    b,
+		chatID: strings.TrimSpace(opts.ChatID),
+		in:     opts.In,
+		out:    opts.Out,
+		w:      opts.Out,
+		done:   make(chan struct{}),
+	}
+}
+
+func (c *Channel) Name() string    { return "cli" }
+func (c *Channel) IsRunning() bool { return c.running.Load() }
+
+// SupportsStreaming reports that replies can be streamed: the terminal shows
+// text as the model generates it.
+func (c *Channel) SupportsStreaming() bool { return true }
+
+// Done is closed when the user leaves the REPL.
+func (c *Channel) Done() <-chan struct{} { return c.done }
+
+func (c *Channel) Start(ctx context.Context) error {
+	c.running.Store(true)
+	defer c.running.Store(false)
+	defer c.doneOnce.Do(func() { close(c.done) })
+
+	readLine, restore, err := c.lineReader()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	c.printf("chat: %s (/attach <path> adds a file to your next message, /exit quits)\n", c.chatID)
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			line, err := readLine()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case line := <-lines:
+			if quit := c.handleLine(ctx, line); quit {
+				return nil
+			}
+		}
+	}
+}
+
+func (c *Channel) Stop() error {
+	c.doneOnce.Do(func() { close(c.done) })
+	return nil
+}
+
+// Send prints a reply. Replies for other chats (e.g. from the message tool)
+// are labelled with their chat ID. A streamed reply is printed as it grows,
+// and its final message only adds what is still missing.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if msg.Partial {
+		c.sendPartial(msg)
+		return nil
+	}
+	text := strings.TrimRight(msg.Content, "\n")
+	c.mu.Lock()
+	streamed := ""
+	if msg.StreamID != "" && msg.StreamID == c.streamID {
+		streamed = strings.TrimRight(c.streamed, "\n")
+	}
+	if msg.ChatID == c.chatID {
+		c.streamID, c.streamed = "", ""
+	}
+	c.mu.Unlock()
+	if rest, ok := strings.CutPrefix(text, streamed); ok {
+		text = rest
+	} else {
+		// The final text was rewritten after streaming; print it whole.
+		text = "\n" + text
+	}
+	if chatID := strings.TrimSpace(msg.ChatID); chatID != c.chatID {
+		text = "[" + chatID + "] " + text
+	}
+	var items []string
+	for _, it := range msg.Suggestions {
+		if it = strings.TrimSpace(it); it != "" {
+			items = append(items, it)
+		}
+	}
+	if len(items) > 0 && msg.ChatID == c.chatID {
+		var b strings.Builder
+		b.WriteString(text + "\n")
+		for i, it := range items {
+			fmt.Fprintf(&b, "\n  %d. %s", i+1, it)
+		}
+		text = b.String()
+		c.mu.Lock()
+		c.suggestions = items
+		c.mu.Unlock()
+	}
+	c.printf("%s\n", text)
+	return nil
+}
+
+// sendPartial prints what a partial message of a streamed reply adds to the
+// text printed so far.
+func (c *Channel) sendPartial(msg bus.OutboundMessage) {
+	if msg.ChatID != c.chatID {
+		return
+	}
+	c.mu.Lock()
+	if msg.StreamID != c.streamID {
+		c.streamID, c.streamed = msg.StreamID, ""
+	}
+	added, ok := strings.CutPrefix(msg.Content, c.streamed)
+	if !ok {
+		// A new model round after tool calls starts its text over.
+		added = "\n" + msg.Content
+	}
+	c.streamed = msg.Content
+	c.mu.Unlock()
+	if added != "" {
+		c.printf("%s", added)
+	}
+}
+
+// lineReader reads from a line-editing terminal when In is one, and plain
+// lines otherwise.
+func (c *Channel) lineReader() (read func() (string, error), restore func(), err error) {
+	if f, ok := c.in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		state, err := term.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return nil, nil, err
+		}
+		t := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{f, c.out}, prompt)
+		c.mu.Lock()
+		c.w = t
+		c.mu.Unlock()
+		return t.ReadLine, func() {
+			c.mu.Lock()
+			c.w = c.out
+			c.mu.Unlock()
+			_ = term.Restore(int(f.Fd()), state)
+		}, nil
+	}
+	sc := bufio.NewScanner(c.in)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	return func() (string, error) {
+		if sc.Scan() {
+			return sc.Text(), nil
+		}
+		if err := sc.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}, func() {}, nil
+}
+
+func (c *Channel) handleLine(ctx context.Context, line string) (quit bool) {
+	line = strings.TrimSpace(line)
+	cmd, arg, _ := strings.Cut(line, " ")
+	switch cmd {
+	case "":
+		return false
+	case "/exit", "/quit":
+		return true
+	case "/attach":
+		c.attach(strings.TrimSpace(arg))
+		return false
+	case "/detach":
+		c.mu.Lock()
+		c.staged = nil
+		c.mu.Unlock()
+		c.printf("attachments cleared\n")
+		return false
+	}
+
+	c.mu.Lock()
+	atts := c.staged
+	c.staged = nil
+	items := c.suggestions
+	c.suggestions = nil
+	c.mu.Unlock()
+	if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(items) {
+		line = items[n-1]
+		c.printf("%s%s\n", prompt, line)
+	}
+	err := c.bus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:     "cli",
+		SenderID:    senderID,
+		ChatID:      c.chatID,
+		Content:     line,
+		SessionKey:  "cli:" + c.chatID,
+		Attachments: atts,
+		Delivery:    bus.Delivery{IsDirect: true},
+	})
+	if err != nil {
+		c.printf("error: %v\n", err)
+	}
+	return false
+}
+
+// attach stages a local file for the next message; with no path it lists the
+// staged files.
+func (c *Channel) attach(path string) {
+	if path == "" {
+		c.mu.Lock()
+		staged := append([]bus.Attachment(nil), c.staged...)
+		c.mu.Unlock()
+		if len(staged) == 0 {
+			c.printf("usage: /attach <path>\n")
+		}
+		for _, a := range staged {
+			c.printf("attached: %s (%d bytes)\n", a.LocalPath, a.SizeBytes)
+		}
+		return
+	}
+	att, err := localAttachment(path)
+	if err != nil {
+		c.printf("error: %v\n", err)
+		return
+	}
+	c.mu.Lock()
+	c.staged = append(c.staged, att)
+	c.mu.Unlock()
+	c.printf("attached %s; it is sent with your next message\n", att.Name)
+}
+
+func localAttachment(path string) (bus.Attachment, error) {
+	path = strings.Trim(path, `"'`)
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return bus.Attachment{}, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return bus.Attachment{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return bus.Attachment{}, fmt.Errorf("not a regular file: %s", abs)
+	}
+	mimeType := mime.TypeByExtension(filepath.Ext(abs))
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return bus.Attachment{
+		Name:      filepath.Base(abs),
+		MIMEType:  mimeType,
+		Kind:      bus.InferAttachmentKind(mimeType),
+		SizeBytes: info.Size(),
+		LocalPath: abs,
+	}, nil
+}
+
+func (c *Channel) printf(format string, args ...any) {
+	c.mu.Lock()
+	w := c.w
+	c.mu.Unlock()
+	fmt.Fprintf(w, format, args...)
+}
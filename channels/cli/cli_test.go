@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+func TestChannel_PublishesLinesWithAttachments(t *testing.T) {
+	img := filepath.Join(t.TempDir(), "photo.png")
+	if err := os.WriteFile(img, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	in := strings.NewReader("/attach " + img + "\nwhat is this?\n/attach\n1\n/exit\nignored\n")
+	var out bytes.Buffer
+	b := bus.New(4)
+	c := New(b, Options{ChatID: "t", In: in, Out: &out})
+	// Suggestions from an earlier reply can be picked by number.
+	if err := c.Send(context.Background(), bus.OutboundMessage{Channel: "cli", ChatID: "t", Content: "hi", Suggestions: []string{"Tell me more"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Fatal("Done should be closed after /exit")
+	}
+
+	first, _ := b.ConsumeInbound(ctx)
+	if first.Content != "what is this?" || first.SessionKey != "cli:t" || len(first.Attachments) != 1 {
+		t.Fatalf("first=%+v", first)
+	}
+	if a := first.Attachments[0]; a.LocalPath != img || a.MIMEType != "image/png" || a.Kind != "image" || a.SizeBytes != 3 {
+		t.Fatalf("attachment=%+v", a)
+	}
+	// The first message picked the suggestion list up; it is gone now.
+	second, _ := b.ConsumeInbound(ctx)
+	if second.Content != "1" || len(second.Attachments) != 0 {
+		t.Fatalf("second=%+v", second)
+	}
+	if !strings.Contains(out.String(), "  1. Tell me more") || !strings.Contains(out.String(), "usage: /attach") {
+		t.Fatalf("out=%q", out.String())
+	}
+}
+
+func TestChannel_PicksSuggestion(t *testing.T) {
+	b := bus.New(1)
+	c := New(b, Options{ChatID: "t", In: strings.NewReader("2\n"), Out: io.Discard})
+	_ = c.Send(context.Background(), bus.OutboundMessage{ChatID: "t", Content: "x", Suggestions: []string{"a", "b"}})
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := b.ConsumeInbound(context.Background())
+	if msg.Content != "b" {
+		t.Fatalf("content=%q", msg.Content)
+	}
+}
+
+func TestChannel_StreamsReply(t *testing.T) {
+	var out bytes.Buffer
+	c := New(bus.New(1), Options{ChatID: "t", In: strings.NewReader(""), Out: &out})
+	ctx := context.Background()
+	for _, m := range []bus.OutboundMessage{
+		{ChatID: "t", StreamID: "s", Partial: true, Content: "Let me"},
+		{ChatID: "t", StreamID: "s", Partial: true, Content: "Let me check."},
+		// The round after a tool call starts over.
+		{ChatID: "t", StreamID: "s", Partial: true, Content: "It is"},
+		{ChatID: "t", StreamID: "s", Content: "It is sunny."},
+		{ChatID: "t", StreamID: "s2", Partial: true, Content: "Bye"},
+		{ChatID: "t", StreamID: "s2", Content: "Goodbye."},
+	} {
+		if err := c.Send(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "Let me check.\nIt is sunny.\nBye\nGoodbye.\n"; out.String() != want {
+		t.Fatalf("out=%q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,149 @@
+package channels
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDedupeSize is how many message IDs a Deduper remembers.
+const DefaultDedupeSize = 2000
+
+// dedupeSaveDelay is how long new IDs wait before they are saved, so a
+// burst of messages costs one write rather than one each.
+const dedupeSaveDelay = time.Second
+
+// Redelivering is implemented by channels whose chat app may deliver a
+// message more than once, e.g. a webhook retried after a slow answer. The
+// manager hands them a shared Deduper; until then they use one of their own.
+type Redelivering interface {
+	SetDeduper(d *Deduper)
+}
+
+// Deduper remembers the IDs of recent inbound messages so that a
+// redelivered one can be dropped before it is published. The least recently
+// seen ID is forgotten first. With a path, the IDs are saved there and
+// survive a restart; they are saved in the background shortly after they
+// are seen, and at the latest by Close.
+type Deduper struct {
+	size  int
+	path  string
+	delay time.Duration
+
+	mu    sync.Mutex
+	order *list.List // keys, most recently seen at the front
+	keys  map[string]*list.Element
+	save  *time.Timer // pending save, nil when none is due
+
+	saveMu sync.Mutex // serializes writes of path
+}
+
+// NewDeduper returns a Deduper of size IDs (DefaultDedupeSize when 0) kept
+// in path, or only in memory when path is empty.
+func NewDeduper(size int, path string) *Deduper {
+	if size <= 0 {
+		size = DefaultDedupeSize
+	}
+	d := &Deduper{size: size, path: path, delay: dedupeSaveDelay, order: list.New(), keys: map[string]*list.Element{}}
+	if path == "" {
+		return d
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("channels: dedupe store unreadable, starting empty: %v", err)
+		}
+		return d
+	}
+	var keys []string // oldest first
+	if err := json.Unmarshal(b, &keys); err != nil {
+		log.Printf("channels: dedupe store unreadable, starting empty: %v", err)
+		return d
+	}
+	for _, k := range keys {
+		d.add(k)
+	}
+	return d
+}
+
+// Seen records messageID of channel and reports whether it was already
+// there. Messages without an ID are never duplicates.
+func (d *Deduper) Seen(channel, messageID string) bool {
+	messageID = strings.TrimSpace(messageID)
+	if d == nil || messageID == "" {
+		return false
+	}
+	key := channel + ":" + messageID
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if el, ok := d.keys[key]; ok {
+		d.order.MoveToFront(el)
+		return true
+	}
+	d.add(key)
+	if d.path != "" && d.save == nil {
+		d.save = time.AfterFunc(d.delay, func() {
+			if err := d.flush(); err != nil {
+				log.Printf("channels: dedupe store not saved: %v", err)
+			}
+		})
+	}
+	return false
+}
+
+// Close saves the IDs not saved yet.
+func (d *Deduper) Close() error {
+	if d == nil || d.path == "" {
+		return nil
+	}
+	return d.flush()
+}
+
+func (d *Deduper) add(key string) {
+	if _, ok := d.keys[key]; ok {
+		return
+	}
+	d.keys[key] = d.order.PushFront(key)
+	for d.order.Len() > d.size {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.keys, oldest.Value.(string))
+	}
+}
+
+// flush writes the IDs to path if a save is due. The IDs are copied under
+// mu, so Seen is not held up by the write.
+func (d *Deduper) flush() error {
+	d.saveMu.Lock()
+	defer d.saveMu.Unlock()
+	d.mu.Lock()
+	if d.save == nil {
+		d.mu.Unlock()
+		return nil
+	}
+	d.save.Stop()
+	d.save = nil
+	keys := make([]string, 0, d.order.Len())
+	for el := d.order.Back(); el != nil; el = el.Prev() {
+		keys = append(keys, el.Value.(string))
+	}
+	d.mu.Unlock()
+	b, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0o700); err != nil {
+		return err
+	}
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path)
+}
@@ -0,0 +1,74 @@
+package channels
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeduper(t *testing.T) {
+	d := NewDeduper(2, "")
+	if d.Seen("whatsapp", "a") || !d.Seen("whatsapp", "a") {
+		t.Fatal("second delivery should be seen")
+	}
+	if d.Seen("instagram", "a") {
+		t.Fatal("IDs are per channel")
+	}
+	if d.Seen("whatsapp", "") || d.Seen("whatsapp", "") {
+		t.Fatal("messages without an ID are never duplicates")
+	}
+	// whatsapp:a was seen more recently than instagram:a, which goes first.
+	_ = d.Seen("whatsapp", "a")
+	_ = d.Seen("whatsapp", "b")
+	if d.Seen("instagram", "a") {
+		t.Fatal("least recently seen ID should be forgotten")
+	}
+}
+
+func TestDeduper_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+	d := NewDeduper(10, path)
+	_ = d.Seen("whatsapp", "a")
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !NewDeduper(10, path).Seen("whatsapp", "a") {
+		t.Fatal("seen IDs should survive a restart")
+	}
+}
+
+func TestDeduper_BatchesSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+	d := NewDeduper(10, path)
+	d.delay = 50 * time.Millisecond
+	for _, id := range []string{"a", "b", "c"} {
+		_ = d.Seen("whatsapp", id)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("saved before the delay: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if b, err := os.ReadFile(path); err == nil {
+			if got := string(b); got != `["whatsapp:a","whatsapp:b","whatsapp:c"]` {
+				t.Fatalf("saved %s", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("not saved after the delay")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Nothing new: Close has nothing to write.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("saved again without new IDs: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// interactionTTL is how long Discord accepts edits to a deferred
+// interaction reply.
+const interactionTTL = 15 * time.Minute
+
+type pendingInteraction struct {
+	it *discordgo.Interaction
+	at time.Time
+}
+
+// slashCommands are registered globally when slashCommands is on. /reset
+// and /status are passed to the agent as text commands.
+var slashCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:        "ask",
+		Description: "Ask the assistant",
+		Options: []*discordgo.ApplicationCommandOption{{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "prompt",
+			Description: "Your message",
+			Required:    true,
+		}},
+	},
+	{Name: "reset", Description: "Start a new conversation (memory notes are kept)"},
+	{Name: "status", Description: "Show the model and conversation size"},
+}
+
+func registerSlashCommands(dg *discordgo.Session) {
+	if dg.State == nil || dg.State.User == nil {
+		log.Printf("discord: cannot register slash commands: bot user unknown")
+		return
+	}
+	if _, err := dg.ApplicationCommandBulkOverwrite(dg.State.User.ID, "", slashCommands); err != nil {
+		log.Printf("discord: register slash commands: %v", err)
+	}
+}
+
+// slashCommandText is the inbound message text for a slash command.
+func slashCommandText(data discordgo.ApplicationCommandInteractionData) string {
+	switch data.Name {
+	case "ask":
+		for _, o := range data.Options {
+			if o.Name == "prompt" {
+				return strings.TrimSpace(o.StringValue())
+			}
+		}
+		return ""
+	case "reset", "status":
+		return "/" + data.Name
+	default:
+		return ""
+	}
+}
+
+func interactionUser(it *discordgo.Interaction) *discordgo.User {
+	if it.Member != nil && it.Member.User != nil {
+		return it.Member.User
+	}
+	return it.User
+}
+
+func (c *Channel) onInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i == nil || i.Interaction == nil || i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	user := interactionUser(i.Interaction)
+	chID := strings.TrimSpace(i.ChannelID)
+	text := slashCommandText(i.ApplicationCommandData())
+	if user == nil || chID == "" || text == "" {
+		return
+	}
+	if !c.inScope(s, i.GuildID, chID) {
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: "This bot is not available here.", Flags: discordgo.MessageFlagsEphemeral},
+		})
+		return
+	}
+	if !c.allow.Allowed(user.ID) {
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: "You are not allowed to use this bot.", Flags: discordgo.MessageFlagsEphemeral},
+		})
+		return
+	}
+	// Show "thinking..." until the agent's reply replaces it.
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}); err != nil {
+		log.Printf("discord: acknowledge /%s: %v", i.ApplicationCommandData().Name, err)
+		return
+	}
+
+	ctx := context.Background()
+	c.mu.Lock()
+	if c.ctx != nil {
+		ctx = c.ctx
+	}
+	now := time.Now()
+	if c.pending == nil {
+		c.pending = map[string]pendingInteraction{}
+	}
+	for k, p := range c.pending {
+		if now.Sub(p.at) > interactionTTL {
+			delete(c.pending, k)
+		}
+	}
+	c.pending[chID] = pendingInteraction{it: i.Interaction, at: now}
+	c.mu.Unlock()
+
+	_ = c.bus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:    c.name,
+		SenderID:   user.ID,
+		ChatID:     chID,
+		Content:    text,
+		SessionKey: c.name + ":" + chID,
+		Delivery: bus.Delivery{
+			MessageID: i.ID,
+			IsDirect:  strings.TrimSpace(i.GuildID) == "",
+		},
+	})
+}
+
+// takeInteraction returns the deferred interaction of chID, if it can still
+// be answered, and forgets it.
+func (c *Channel) takeInteraction(chID string) *discordgo.Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[chID]
+	if !ok {
+		return nil
+	}
+	delete(c.pending, chID)
+	if time.Since(p.at) > interactionTTL {
+		return nil
+	}
+	return p.it
+}
+
+// peekInteraction is takeInteraction leaving the interaction pending, for
+// the partial updates of a streamed reply.
+func (c *Channel) peekInteraction(chID string) *discordgo.Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[chID]
+	if !ok || time.Since(p.at) > interactionTTL {
+		return nil
+	}
+	return p.it
+}
+
+// editInteractionReply replaces the deferred "thinking..." reply.
+func editInteractionReply(ctx context.Context, dg *discordgo.Session, it *discordgo.Interaction, content string, files []bus.Attachment, embeds []*discordgo.MessageEmbed) error {
+	edit := &discordgo.WebhookEdit{Content: &content}
+	if len(embeds) > 0 {
+		edit.Embeds = &embeds
+	}
+	for _, f := range files {
+		edit.Files = append(edit.Files, &discordgo.File{
+			Name:        f.Name,
+			ContentType: f.MIMEType,
+			Reader:      bytes.NewReader(f.Data),
+		})
+	}
+	_, err := dg.InteractionResponseEdit(it, edit, discordgo.WithContext(ctx))
+	return err
+}
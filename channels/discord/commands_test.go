@@ -0,0 +1,43 @@
+package discord
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSlashCommandText(t *testing.T) {
+	ask := discordgo.ApplicationCommandInteractionData{
+		Name: "ask",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "prompt", Type: discordgo.ApplicationCommandOptionString, Value: " what's up? "},
+		},
+	}
+	if got := slashCommandText(ask); got != "what's up?" {
+		t.Fatalf("ask=%q", got)
+	}
+	if got := slashCommandText(discordgo.ApplicationCommandInteractionData{Name: "reset"}); got != "/reset" {
+		t.Fatalf("reset=%q", got)
+	}
+	if got := slashCommandText(discordgo.ApplicationCommandInteractionData{Name: "other"}); got != "" {
+		t.Fatalf("other=%q", got)
+	}
+}
+
+func TestTakeInteraction(t *testing.T) {
+	it := &discordgo.Interaction{ID: "i1"}
+	c := &Channel{pending: map[string]pendingInteraction{
+		"c1": {it: it, at: time.Now()},
+		"c2": {it: it, at: time.Now().Add(-interactionTTL - time.Minute)},
+	}}
+	if got := c.takeInteraction("c1"); got != it {
+		t.Fatalf("c1=%v", got)
+	}
+	if got := c.takeInteraction("c1"); got != nil {
+		t.Fatal("interaction answered twice")
+	}
+	if got := c.takeInteraction("c2"); got != nil {
+		t.Fatal("expired interaction returned")
+	}
+}
@@ -1,12 +1,14 @@
 package discord
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -19,32 +21,48 @@ import (
 )
 
 type Channel struct {
+	name  string
 	cfg   config.DiscordConfig
 	bus   *bus.Bus
 	allow channels.AllowList
 
 	running atomic.Bool
-
-	mu  sync.Mutex
-	dg  *discordgo.Session
-	hc  *http.Client
-	ctx context.Context
+	streams channels.Streams
+	replies channels.Streams // inbound message -> answer, for corrections
+
+	mu     sync.Mutex
+	dg     *discordgo.Session
+	hc     *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	// pending holds slash command interactions awaiting their reply, by
+	// channel ID.
+	pending map[string]pendingInteraction
 }
 
 func New(cfg config.DiscordConfig, b *bus.Bus) *Channel {
+	return NewAccount("", cfg, b)
+}
+
+// NewAccount returns the channel of a named account, "discord.<account>";
+// "" is the main account, "discord".
+func NewAccount(account string, cfg config.DiscordConfig, b *bus.Bus) *Channel {
 	return &Channel{
+		name:  config.ChannelName("discord", account),
 		cfg:   cfg,
 		bus:   b,
 		allow: channels.AllowList{AllowFrom: cfg.AllowFrom},
 		hc: &http.Client{
-			Timeout: 20 * time.Second,
+			Timeout: discordRequestTimeout,
 		},
 	}
 }
 
-func (c *Channel) Name() string    { return "discord" }
+func (c *Channel) Name() string    { return c.name }
 func (c *Channel) IsRunning() bool { return c.running.Load() }
 
+func (c *Channel) SupportsAttachments() bool { return true }
+
 func (c *Channel) Start(ctx context.Context) error {
 	if strings.TrimSpace(c.cfg.Token) == "" {
 		return fmt.Errorf("discord token is empty")
@@ -54,17 +72,39 @@ func (c *Channel) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	// Keep operations bounded; discordgo doesn't take context in most calls.
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Keep operations bounded, including those made without a context.
 	dg.Client = c.hc
 
-	if c.cfg.Intents != 0 {
-		dg.Identify.Intents = discordgo.Intent(c.cfg.Intents)
-	}
+	intents := requiredIntents(c.cfg)
+	dg.Identify.Intents = intents
 	dg.AddHandler(c.onMessageCreate)
+	if config.EditsPolicy(c.cfg.Edits) != config.EditsIgnore {
+		dg.AddHandler(c.onMessageUpdate)
+	}
+	if c.cfg.SlashCommands {
+		dg.AddHandler(c.onInteractionCreate)
+	}
+	if c.cfg.ReactionsValue() {
+		dg.AddHandler(c.onReactionAdd)
+		dg.AddHandler(c.onReactionRemove)
+	}
+	dg.AddHandler(c.onPollVoteAdd)
+	dg.AddHandler(c.onPollVoteRemove)
+	dg.AddHandler(func(s *discordgo.Session, g *discordgo.GuildCreate) {
+		if g == nil || c.cfg.DMOnly {
+			return
+		}
+		if issue := diagnoseGuildPermissions(s.State, g.Guild); issue != "" {
+			logDiagnostics([]string{issue})
+		}
+	})
 
 	c.mu.Lock()
 	c.dg = dg
 	c.ctx = ctx
+	c.cancel = cancel
 	c.mu.Unlock()
 
 	c.running.Store(true)
@@ -74,23 +114,35 @@ func (c *Channel) Start(ctx context.Context) error {
 		c.mu.Lock()
 		if c.dg == dg {
 			c.dg = nil
+			c.cancel = nil
 		}
 		c.mu.Unlock()
 	}()
 
 	if err := dg.Open(); err != nil {
-		return err
+		return explainOpenError(err)
+	}
+	appFlags, appFlagsKnown := 0, false
+	if app, err := dg.Application("@me"); err == nil && app != nil {
+		appFlags, appFlagsKnown = app.Flags, true
+	}
+	logDiagnostics(append(diagnoseIntents(intents, appFlags, appFlagsKnown), diagnoseOverride(c.cfg)))
+	if c.cfg.SlashCommands {
+		registerSlashCommands(dg)
 	}
 
-	<-ctx.Done()
+	<-runCtx.Done()
 	return ctx.Err()
 }
 
 func (c *Channel) Stop() error {
 	c.mu.Lock()
-	dg := c.dg
-	c.dg = nil
+	dg, cancel := c.dg, c.cancel
+	c.dg, c.cancel = nil, nil
 	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
 	if dg != nil {
 		return dg.Close()
 	}
@@ -103,7 +155,7 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 		return fmt.Errorf("chat_id is empty")
 	}
 	content := strings.TrimSpace(msg.Content)
-	if content == "" {
+	if content == "" && len(msg.Attachments) == 0 && len(msg.Cards) == 0 && msg.Kind == bus.MessageKindText {
 		return nil
 	}
 
@@ -114,24 +166,81 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 		return fmt.Errorf("discord not connected")
 	}
 
-	// Best-effort cancellation: discordgo doesn't propagate ctx. We at least
-	// fail fast if ctx is already cancelled.
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if msg.Kind == bus.MessageKindReaction {
+		return sendWithRetry(ctx, chID, func(ctx context.Context) error { return sendReaction(ctx, dg, chID, msg.Reaction) })
+	}
+	if msg.Kind == bus.MessageKindPoll {
+		return sendWithRetry(ctx, chID, func(ctx context.Context) error { return sendPoll(ctx, dg, chID, msg.Poll) })
 	}
+	if msg.Partial {
+		return c.sendPartial(ctx, dg, chID, msg)
+	}
+	batches, err := discordFileBatches(msg.Attachments)
+	if err != nil {
+		return err
+	}
+	replyToID := resolveDiscordReplyTarget(c.cfg.Quote, msg)
+	it := c.takeInteraction(chID)
+	streamed, _ := c.streams.Take(msg.StreamID)
+	if corrected, ok := c.correctedReply(msg); ok {
+		streamed = corrected
+	}
+	for i, m := range discordMessages(content, batches, discordEmbeds(msg.Cards), channels.SplitterOrDefault(c.cfg.Split)) {
+		text, files, embeds, reply := m.text, m.files, m.embeds, replyToID
+		if i > 0 {
+			reply = ""
+		}
+		if err := sendWithRetry(ctx, chID, func(ctx context.Context) error {
+			switch {
+			case i == 0 && it != nil:
+				return editInteractionReply(ctx, dg, it, text, files, embeds)
+			case i == 0 && streamed != "":
+				if err := editDiscordMessage(ctx, dg, chID, streamed, text, files, embeds); err != nil {
+					return err
+				}
+				c.rememberReply(msg, streamed)
+				return nil
+			}
+			sent, err := sendDiscordMessage(ctx, dg, chID, text, reply, files, embeds)
+			if err == nil && i == 0 {
+				c.rememberReply(msg, sent.ID)
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	replyToID := resolveDiscordReplyTarget(msg)
+// discordRequestTimeout bounds one REST call, an upload included.
+const discordRequestTimeout = 20 * time.Second
+
+// sendWithRetry runs send with a context that ends with ctx or after
+// discordRequestTimeout, so a canceled send aborts the REST call in flight.
+func sendWithRetry(ctx context.Context, chID string, send func(ctx context.Context) error) error {
 	const maxAttempts = 3
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		err := sendDiscordMessage(dg, chID, content, replyToID)
+		attemptCtx, cancel := context.WithTimeout(ctx, discordRequestTimeout)
+		err := send(attemptCtx)
+		cancel()
 		if err == nil {
 			return nil
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		retry, wait := shouldRetryDiscordSend(err, attempt)
+		if errors.Is(err, context.DeadlineExceeded) {
+			// Only this attempt ran out of time.
+			retry, wait = true, discordSendBackoff(attempt)
+		}
 		if !retry || attempt == maxAttempts {
-			return err
+			return explainSendError(err, chID)
 		}
 		log.Printf("discord: send failed (%d/%d), retry in %s: %v", attempt, maxAttempts, wait, err)
 		t := time.NewTimer(wait)
@@ -146,13 +255,17 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 }
 
 func (c *Channel) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
-	if m == nil || m.Author == nil {
+	c.onMessage(s, m, false)
+}
+
+func (c *Channel) onMessage(s *discordgo.Session, m *discordgo.MessageCreate, edited bool) {
+	if m == nil || m.Message == nil || m.Author == nil {
 		return
 	}
 	if m.Author.Bot {
 		return
 	}
-	if !c.allow.Allowed(m.Author.ID) {
+	if !c.inScope(s, m.GuildID, m.ChannelID) || !c.allow.Allowed(m.Author.ID) {
 		return
 	}
 	chID := strings.TrimSpace(m.ChannelID)
@@ -162,6 +275,40 @@ func (c *Channel) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 		return
 	}
 
+	delivery := buildDiscordDelivery(m)
+	delivery.IsEdit = edited
+	if !delivery.IsDirect {
+		ch := lookupDiscordChannel(s, chID)
+		self := discordSelfID(s)
+		if !c.groupPolicy().Allows(addressedToBot(m, ch, self), discordGroupIDs(chID, m.GuildID, ch)...) {
+			return
+		}
+		if content = stripBotMention(content, self); content == "" && len(attachments) == 0 {
+			return
+		}
+		switch {
+		case ch != nil && ch.IsThread():
+			delivery.ThreadID = chID
+		case edited && startsThread(ch, c.cfg.ThreadReplies):
+			// The first version started a thread, which shares its ID.
+			chID = m.ID
+			delivery.ThreadID, delivery.ReplyToID = m.ID, ""
+		case startsThread(ch, c.cfg.ThreadReplies):
+			// Answer in a thread of its own; messages there continue the
+			// thread's conversation.
+			th, err := s.MessageThreadStartComplex(chID, m.ID, &discordgo.ThreadStart{
+				Name:                discordThreadName(content),
+				AutoArchiveDuration: discordThreadArchiveMinutes,
+			})
+			if err != nil {
+				log.Printf("discord: start thread in %s (grant Create Public Threads and Send Messages in Threads), replying in the channel: %v", chID, err)
+				break
+			}
+			chID = th.ID
+			delivery.ThreadID, delivery.ReplyToID = th.ID, ""
+		}
+	}
+
 	ctx := context.Background()
 	c.mu.Lock()
 	if c.ctx != nil {
@@ -170,13 +317,13 @@ func (c *Channel) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 	c.mu.Unlock()
 
 	_ = c.bus.PublishInbound(ctx, bus.InboundMessage{
-		Channel:     "discord",
+		Channel:     c.name,
 		SenderID:    m.Author.ID,
 		ChatID:      chID,
 		Content:     content,
 		Attachments: attachments,
-		SessionKey:  "discord:" + chID,
-		Delivery:    buildDiscordDelivery(m),
+		SessionKey:  c.name + ":" + chID,
+		Delivery:    delivery,
 	})
 }
 
@@ -209,11 +356,14 @@ func discordInboundAttachments(m *discordgo.MessageCreate) []bus.Attachment {
 	return out
 }
 
-func resolveDiscordReplyTarget(msg bus.OutboundMessage) string {
-	if replyTo := strings.TrimSpace(msg.Delivery.ReplyToID); replyTo != "" {
-		return replyTo
+func resolveDiscordReplyTarget(quote string, msg bus.OutboundMessage) string {
+	target := channels.ReplyTarget(quote, msg)
+	// A thread started from a message shares its ID; the message itself is
+	// in the parent channel and cannot be quoted from the thread.
+	if target == msg.Delivery.ThreadID {
+		return ""
 	}
-	return strings.TrimSpace(msg.ReplyTo)
+	return target
 }
 
 func buildDiscordDelivery(m *discordgo.MessageCreate) bus.Delivery {
@@ -223,6 +373,7 @@ func buildDiscordDelivery(m *discordgo.MessageCreate) bus.Delivery {
 	d := bus.Delivery{
 		MessageID: strings.TrimSpace(m.ID),
 		IsDirect:  strings.TrimSpace(m.GuildID) == "",
+		SentAt:    m.Timestamp,
 	}
 	if m.MessageReference != nil {
 		d.ReplyToID = strings.TrimSpace(m.MessageReference.MessageID)
@@ -233,22 +384,83 @@ func buildDiscordDelivery(m *discordgo.MessageCreate) bus.Delivery {
 	return d
 }
 
-func sendDiscordMessage(dg *discordgo.Session, chID, content, replyToID string) error {
-	if replyToID == "" {
-		_, err := dg.ChannelMessageSend(chID, content)
-		return err
+func sendDiscordMessage(ctx context.Context, dg *discordgo.Session, chID, content, replyToID string, files []bus.Attachment, embeds []*discordgo.MessageEmbed) (*discordgo.Message, error) {
+	if replyToID == "" && len(files) == 0 && len(embeds) == 0 {
+		return dg.ChannelMessageSend(chID, content, discordgo.WithContext(ctx))
 	}
-	_, err := dg.ChannelMessageSendComplex(chID, &discordgo.MessageSend{
-		Content: content,
-		Reference: &discordgo.MessageReference{
+	send := &discordgo.MessageSend{Content: content, Embeds: embeds}
+	if replyToID != "" {
+		send.Reference = &discordgo.MessageReference{
 			MessageID: replyToID,
 			ChannelID: chID,
-		},
-		AllowedMentions: &discordgo.MessageAllowedMentions{
+		}
+		send.AllowedMentions = &discordgo.MessageAllowedMentions{
 			RepliedUser: false,
-		},
-	})
-	return err
+		}
+	}
+	// Readers are consumed by an attempt, so build them per call.
+	for _, f := range files {
+		send.Files = append(send.Files, &discordgo.File{
+			Name:        f.Name,
+			ContentType: f.MIMEType,
+			Reader:      bytes.NewReader(f.Data),
+		})
+	}
+	return dg.ChannelMessageSendComplex(chID, send, discordgo.WithContext(ctx))
+}
+
+type discordMessage struct {
+	text   string
+	files  []bus.Attachment
+	embeds []*discordgo.MessageEmbed
+}
+
+// discordMessages lays out content, cut to Discord's message length with
+// split, and the file batches: the last text part goes with the first batch.
+func discordMessages(content string, batches [][]bus.Attachment, embeds []*discordgo.MessageEmbed, split channels.Splitter) []discordMessage {
+	parts := split(content, channels.MaxDiscordText)
+	if len(parts) == 0 {
+		parts = []string{""}
+	}
+	var out []discordMessage
+	for _, p := range parts[:len(parts)-1] {
+		out = append(out, discordMessage{text: p})
+	}
+	out = append(out, discordMessage{text: parts[len(parts)-1], files: batches[0], embeds: embeds})
+	for _, files := range batches[1:] {
+		out = append(out, discordMessage{files: files})
+	}
+	return out
+}
+
+// maxDiscordFiles is the attachment limit of a single Discord message.
+const maxDiscordFiles = 10
+
+// discordFileBatches reads the attachments and splits them into messages of
+// at most maxDiscordFiles. There is always at least one (maybe empty) batch
+// so the text is sent.
+func discordFileBatches(atts []bus.Attachment) ([][]bus.Attachment, error) {
+	batches := [][]bus.Attachment{nil}
+	for _, a := range atts {
+		data, err := a.ReadData()
+		if err != nil {
+			return nil, fmt.Errorf("discord attachment %s: %w", a.Name, err)
+		}
+		if strings.TrimSpace(a.Name) == "" {
+			a.Name = "file"
+			if a.LocalPath != "" {
+				a.Name = filepath.Base(a.LocalPath)
+			}
+		}
+		a.Data, a.LocalPath = data, ""
+		last := len(batches) - 1
+		if len(batches[last]) == maxDiscordFiles {
+			batches = append(batches, nil)
+			last++
+		}
+		batches[last] = append(batches[last], a)
+	}
+	return batches, nil
 }
 
 func shouldRetryDiscordSend(err error, attempt int) (bool, time.Duration) {
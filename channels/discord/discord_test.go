@@ -2,17 +2,22 @@ package discord
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
 )
 
 func TestResolveDiscordReplyTarget(t *testing.T) {
 	t.Run("prefer delivery reply id", func(t *testing.T) {
-		got := resolveDiscordReplyTarget(bus.OutboundMessage{
+		got := resolveDiscordReplyTarget("", bus.OutboundMessage{
 			ReplyTo: "legacy",
 			Delivery: bus.Delivery{
 				ReplyToID: "typed",
@@ -24,13 +29,22 @@ func TestResolveDiscordReplyTarget(t *testing.T) {
 	})
 
 	t.Run("fallback legacy reply_to", func(t *testing.T) {
-		got := resolveDiscordReplyTarget(bus.OutboundMessage{
+		got := resolveDiscordReplyTarget("", bus.OutboundMessage{
 			ReplyTo: "legacy",
 		})
 		if got != "legacy" {
 			t.Fatalf("expected legacy reply_to, got %q", got)
 		}
 	})
+
+	t.Run("always skips the message a thread started from", func(t *testing.T) {
+		got := resolveDiscordReplyTarget(channels.QuoteAlways, bus.OutboundMessage{
+			Delivery: bus.Delivery{MessageID: "m1", ThreadID: "m1"},
+		})
+		if got != "" {
+			t.Fatalf("expected no reply in the new thread, got %q", got)
+		}
+	})
 }
 
 func TestBuildDiscordDelivery(t *testing.T) {
@@ -111,6 +125,20 @@ func TestShouldRetryDiscordSend(t *testing.T) {
 	})
 }
 
+func TestSendWithRetry_CancelAbortsInFlightCall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	calls := 0
+	err := sendWithRetry(ctx, "c1", func(ctx context.Context) error {
+		calls++
+		<-ctx.Done() // a hung REST call
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Fatalf("err=%v calls=%d", err, calls)
+	}
+}
+
 func TestDiscordInboundAttachments(t *testing.T) {
 	msg := &discordgo.MessageCreate{
 		Message: &discordgo.Message{
@@ -140,3 +168,73 @@ func TestDiscordInboundAttachments(t *testing.T) {
 		t.Fatalf("unexpected kinds: %+v", got)
 	}
 }
+
+func TestDiscordFileBatches(t *testing.T) {
+	batches, err := discordFileBatches(nil)
+	if err != nil || len(batches) != 1 || len(batches[0]) != 0 {
+		t.Fatalf("no files: %v %v", batches, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	atts := []bus.Attachment{{LocalPath: path}}
+	for i := 0; i < maxDiscordFiles; i++ {
+		atts = append(atts, bus.Attachment{Name: "x.png", Data: []byte("x")})
+	}
+	batches, err = discordFileBatches(atts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || len(batches[0]) != maxDiscordFiles || len(batches[1]) != 1 {
+		t.Fatalf("batch sizes: %d", len(batches))
+	}
+	if first := batches[0][0]; string(first.Data) != "hi" || first.Name != "notes.txt" {
+		t.Fatalf("first file: %+v", first)
+	}
+
+	if _, err := discordFileBatches([]bus.Attachment{{Name: "gone", LocalPath: path + ".missing"}}); err == nil {
+		t.Fatal("expected read error")
+	}
+}
+
+func TestDiscordMessages(t *testing.T) {
+	if got := discordMessages("hi", [][]bus.Attachment{nil}, nil, channels.SplitMessage); len(got) != 1 || got[0].text != "hi" {
+		t.Fatalf("short: %+v", got)
+	}
+	long := strings.Repeat("word ", 1000)
+	files := [][]bus.Attachment{{{Name: "a"}}, {{Name: "b"}}}
+	got := discordMessages(long, files, nil, channels.SplitMessage)
+	if len(got) != 4 {
+		t.Fatalf("messages=%d", len(got))
+	}
+	for i, m := range got[:3] {
+		if len([]rune(m.text)) > 2000 || m.text == "" {
+			t.Fatalf("message %d: %d characters", i, len([]rune(m.text)))
+		}
+	}
+	if got[0].files != nil || got[2].files[0].Name != "a" || got[3].text != "" || got[3].files[0].Name != "b" {
+		t.Fatalf("file layout: %+v", got)
+	}
+}
+
+func TestDiscordEmbeds(t *testing.T) {
+	cards := []bus.Card{
+		{Title: strings.Repeat("t", 300), Fields: []bus.CardField{{Name: "a", Value: "1", Inline: true}, {Name: "", Value: "dropped"}}, Footer: "via web_search", Color: 0x5865F2},
+		{},
+	}
+	embeds := discordEmbeds(cards)
+	if len(embeds) != 1 {
+		t.Fatalf("embeds=%d", len(embeds))
+	}
+	e := embeds[0]
+	if n := len([]rune(e.Title)); n != maxDiscordEmbedTitle || len(e.Fields) != 1 || e.Footer.Text != "via web_search" || e.Color != 0x5865F2 {
+		t.Fatalf("embed %+v", e)
+	}
+
+	got := discordMessages("hi", [][]bus.Attachment{nil}, embeds, channels.SplitMessage)
+	if len(got) != 1 || len(got[0].embeds) != 1 {
+		t.Fatalf("messages %+v", got)
+	}
+}
@@ -0,0 +1,39 @@
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// onMessageUpdate handles an edited message like a new one, marked as an
+// edit. Updates without an edit time, such as link embeds being filled in,
+// are not edits.
+func (c *Channel) onMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	if m == nil || m.Message == nil || m.EditedTimestamp == nil {
+		return
+	}
+	c.onMessage(s, &discordgo.MessageCreate{Message: m.Message}, true)
+}
+
+// rememberReply records the message answering msg's inbound message, so a
+// corrected turn can edit it. Only the "correct" edits policy needs it.
+func (c *Channel) rememberReply(msg bus.OutboundMessage, replyID string) {
+	if config.EditsPolicy(c.cfg.Edits) != config.EditsCorrect || msg.Delivery.MessageID == "" || replyID == "" {
+		return
+	}
+	c.replies.Set(replyKey(msg.ChatID, msg.Delivery.MessageID), replyID, "")
+}
+
+// correctedReply returns the earlier answer a corrected reply replaces.
+func (c *Channel) correctedReply(msg bus.OutboundMessage) (string, bool) {
+	if !msg.Delivery.IsEdit || msg.Delivery.MessageID == "" {
+		return "", false
+	}
+	id, _, ok := c.replies.Get(replyKey(msg.ChatID, msg.Delivery.MessageID))
+	return id, ok
+}
+
+func replyKey(chatID, messageID string) string {
+	return chatID + ":" + messageID
+}
@@ -0,0 +1,33 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestCorrectedReply(t *testing.T) {
+	answer := bus.OutboundMessage{ChatID: "c1", Delivery: bus.Delivery{MessageID: "m1"}}
+	correction := bus.OutboundMessage{ChatID: "c1", Delivery: bus.Delivery{MessageID: "m1", IsEdit: true}}
+
+	c := New(config.DiscordConfig{Edits: config.EditsCorrect}, bus.New(1))
+	c.rememberReply(answer, "r1")
+	if id, ok := c.correctedReply(answer); ok {
+		t.Fatalf("plain reply corrected %q", id)
+	}
+	if id, ok := c.correctedReply(correction); !ok || id != "r1" {
+		t.Fatalf("correctedReply = %q, %v", id, ok)
+	}
+	other := correction
+	other.ChatID = "c2"
+	if _, ok := c.correctedReply(other); ok {
+		t.Fatal("reply found in another channel")
+	}
+
+	c = New(config.DiscordConfig{}, bus.New(1))
+	c.rememberReply(answer, "r1")
+	if _, ok := c.correctedReply(correction); ok {
+		t.Fatal("annotate policy recorded a reply")
+	}
+}
@@ -0,0 +1,87 @@
+package discord
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// Discord's embed limits.
+const (
+	maxDiscordEmbeds           = 10
+	maxDiscordEmbedFields      = 25
+	maxDiscordEmbedTitle       = 256
+	maxDiscordEmbedDescription = 4096
+	maxDiscordEmbedFieldName   = 256
+	maxDiscordEmbedFieldValue  = 1024
+	maxDiscordEmbedFooter      = 2048
+	// maxDiscordEmbedsText bounds the text of all embeds of a message.
+	maxDiscordEmbedsText = 6000
+)
+
+func (c *Channel) SupportsCards() bool { return true }
+
+// discordEmbeds renders cards as embeds, cut to Discord's limits. Cards
+// past the limits are left out.
+func discordEmbeds(cards []bus.Card) []*discordgo.MessageEmbed {
+	var out []*discordgo.MessageEmbed
+	total := 0
+	for _, c := range cards {
+		if len(out) == maxDiscordEmbeds {
+			break
+		}
+		e := &discordgo.MessageEmbed{
+			Title:       truncateRunes(strings.TrimSpace(c.Title), maxDiscordEmbedTitle),
+			URL:         strings.TrimSpace(c.URL),
+			Description: truncateRunes(strings.TrimSpace(c.Description), maxDiscordEmbedDescription),
+			Color:       c.Color,
+		}
+		for _, f := range c.Fields {
+			name, value := strings.TrimSpace(f.Name), strings.TrimSpace(f.Value)
+			if name == "" || value == "" {
+				// Discord rejects empty field names and values.
+				continue
+			}
+			if len(e.Fields) == maxDiscordEmbedFields {
+				break
+			}
+			e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+				Name:   truncateRunes(name, maxDiscordEmbedFieldName),
+				Value:  truncateRunes(value, maxDiscordEmbedFieldValue),
+				Inline: f.Inline,
+			})
+		}
+		if footer := strings.TrimSpace(c.Footer); footer != "" {
+			e.Footer = &discordgo.MessageEmbedFooter{Text: truncateRunes(footer, maxDiscordEmbedFooter)}
+		}
+		if e.Title == "" && e.Description == "" && len(e.Fields) == 0 && e.Footer == nil {
+			continue
+		}
+		if total += embedText(e); total > maxDiscordEmbedsText {
+			break
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func embedText(e *discordgo.MessageEmbed) int {
+	n := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	for _, f := range e.Fields {
+		n += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	if e.Footer != nil {
+		n += utf8.RuneCountInString(e.Footer.Text)
+	}
+	return n
+}
+
+func truncateRunes(s string, limit int) string {
+	r := []rune(s)
+	if len(r) <= limit {
+		return s
+	}
+	return string(r[:limit-1]) + "…"
+}
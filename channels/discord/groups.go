@@ -0,0 +1,84 @@
+package discord
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mosaxiv/clawlet/channels"
+)
+
+func (c *Channel) groupPolicy() channels.GroupPolicy {
+	return channels.GroupPolicy{Policy: c.cfg.GroupPolicy, AllowFrom: c.cfg.GroupAllowFrom, Groups: c.cfg.Groups}
+}
+
+// inScope reports whether the bot works where an event happened: always in
+// direct messages, never in servers with dmOnly, and with guilds only in
+// the servers and channels listed. A thread counts as its parent channel.
+func (c *Channel) inScope(s *discordgo.Session, guildID, chID string) bool {
+	guildID, chID = strings.TrimSpace(guildID), strings.TrimSpace(chID)
+	switch {
+	case guildID == "":
+		return true
+	case c.cfg.DMOnly:
+		return false
+	case len(c.cfg.Guilds) == 0:
+		return true
+	case slices.Contains(c.cfg.Guilds, guildID), slices.Contains(c.cfg.Guilds, chID):
+		return true
+	}
+	ch := lookupDiscordChannel(s, chID)
+	return ch != nil && ch.IsThread() && slices.Contains(c.cfg.Guilds, ch.ParentID)
+}
+
+// discordSelfID is the bot's user ID once the gateway is ready.
+func discordSelfID(s *discordgo.Session) string {
+	if s == nil || s.State == nil || s.State.User == nil {
+		return ""
+	}
+	return s.State.User.ID
+}
+
+// addressedToBot reports whether a server message is for the bot: it
+// mentions the bot, replies to one of its messages, or is in a thread the
+// bot started.
+func addressedToBot(m *discordgo.MessageCreate, ch *discordgo.Channel, self string) bool {
+	if self == "" || m == nil || m.Message == nil {
+		return false
+	}
+	for _, u := range m.Mentions {
+		if u != nil && u.ID == self {
+			return true
+		}
+	}
+	if ref := m.ReferencedMessage; ref != nil && ref.Author != nil && ref.Author.ID == self {
+		return true
+	}
+	return ch != nil && ch.IsThread() && ch.OwnerID == self
+}
+
+// discordGroupIDs lists the IDs a group override may name, most specific
+// first: the channel, a thread's parent channel, and the server.
+func discordGroupIDs(chID, guildID string, ch *discordgo.Channel) []string {
+	ids := []string{strings.TrimSpace(chID)}
+	if ch != nil && ch.IsThread() && ch.ParentID != "" {
+		ids = append(ids, ch.ParentID)
+	}
+	if guild := strings.TrimSpace(guildID); guild != "" {
+		ids = append(ids, guild)
+	}
+	return ids
+}
+
+// stripBotMention removes the bot's mention from the start of a message.
+func stripBotMention(content, self string) string {
+	if self == "" {
+		return content
+	}
+	for _, pfx := range []string{"<@" + self + ">", "<@!" + self + ">"} {
+		if after, ok := strings.CutPrefix(content, pfx); ok {
+			return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(after), ",:"))
+		}
+	}
+	return content
+}
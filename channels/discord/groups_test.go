@@ -0,0 +1,89 @@
+package discord
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestAddressedToBot(t *testing.T) {
+	msg := func(m discordgo.Message) *discordgo.MessageCreate { return &discordgo.MessageCreate{Message: &m} }
+	text := &discordgo.Channel{ID: "c1", Type: discordgo.ChannelTypeGuildText}
+	tests := []struct {
+		name string
+		m    *discordgo.MessageCreate
+		ch   *discordgo.Channel
+		want bool
+	}{
+		{"mention", msg(discordgo.Message{Mentions: []*discordgo.User{{ID: "u1"}, {ID: "bot"}}}), text, true},
+		{"reply", msg(discordgo.Message{ReferencedMessage: &discordgo.Message{Author: &discordgo.User{ID: "bot"}}}), text, true},
+		{"own thread", msg(discordgo.Message{}), &discordgo.Channel{Type: discordgo.ChannelTypeGuildPublicThread, OwnerID: "bot"}, true},
+		{"other thread", msg(discordgo.Message{}), &discordgo.Channel{Type: discordgo.ChannelTypeGuildPublicThread, OwnerID: "u1"}, false},
+		{"chatter", msg(discordgo.Message{Mentions: []*discordgo.User{{ID: "u1"}}}), text, false},
+	}
+	for _, tt := range tests {
+		if got := addressedToBot(tt.m, tt.ch, "bot"); got != tt.want {
+			t.Fatalf("%s: got %v", tt.name, got)
+		}
+	}
+}
+
+func TestDiscordGroupIDs(t *testing.T) {
+	th := &discordgo.Channel{ID: "t1", ParentID: "c1", Type: discordgo.ChannelTypeGuildPublicThread}
+	if got := discordGroupIDs("t1", "g1", th); !slices.Equal(got, []string{"t1", "c1", "g1"}) {
+		t.Fatalf("thread ids: %v", got)
+	}
+	if got := discordGroupIDs("t1", "g1", nil); !slices.Equal(got, []string{"t1", "g1"}) {
+		t.Fatalf("channel ids: %v", got)
+	}
+}
+
+func TestStripBotMention(t *testing.T) {
+	for in, want := range map[string]string{
+		"<@bot> hi":   "hi",
+		"<@!bot>: hi": "hi",
+		"hi <@bot>":   "hi <@bot>",
+		"<@other> hi": "<@other> hi",
+	} {
+		if got := stripBotMention(in, "bot"); got != want {
+			t.Fatalf("stripBotMention(%q)=%q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestInScope(t *testing.T) {
+	s := discordgo.NewState()
+	_ = s.GuildAdd(&discordgo.Guild{ID: "g2"})
+	for _, ch := range []*discordgo.Channel{
+		{ID: "c2", GuildID: "g2", Type: discordgo.ChannelTypeGuildText},
+		{ID: "c3", GuildID: "g2", Type: discordgo.ChannelTypeGuildText},
+		{ID: "t1", GuildID: "g2", ParentID: "c2", Type: discordgo.ChannelTypeGuildPublicThread},
+	} {
+		_ = s.ChannelAdd(ch)
+	}
+	session := &discordgo.Session{State: s}
+
+	tests := []struct {
+		name          string
+		cfg           config.DiscordConfig
+		guild, chanID string
+		want          bool
+	}{
+		{"dm", config.DiscordConfig{DMOnly: true}, "", "d1", true},
+		{"server with dmOnly", config.DiscordConfig{DMOnly: true}, "g1", "c1", false},
+		{"no limits", config.DiscordConfig{}, "g1", "c1", true},
+		{"listed server", config.DiscordConfig{Guilds: []string{"g1"}}, "g1", "c1", true},
+		{"listed channel", config.DiscordConfig{Guilds: []string{"c2"}}, "g2", "c2", true},
+		{"thread of listed channel", config.DiscordConfig{Guilds: []string{"c2"}}, "g2", "t1", true},
+		{"other channel", config.DiscordConfig{Guilds: []string{"c2"}}, "g2", "c3", false},
+		{"other server", config.DiscordConfig{Guilds: []string{"g1"}}, "g2", "c2", false},
+	}
+	for _, tt := range tests {
+		c := New(tt.cfg, nil)
+		if got := c.inScope(session, tt.guild, tt.chanID); got != tt.want {
+			t.Errorf("%s: got %v", tt.name, got)
+		}
+	}
+}
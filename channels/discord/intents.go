@@ -0,0 +1,152 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// Application flags reporting whether the Message Content privileged intent
+// is enabled in the Developer Portal.
+const (
+	appFlagGatewayMessageContent        = 1 << 18
+	appFlagGatewayMessageContentLimited = 1 << 19
+)
+
+// legacyIntents is the fixed mask older versions wrote into new configs:
+// GUILDS + GUILD_MESSAGES + DIRECT_MESSAGES + MESSAGE_CONTENT.
+const legacyIntents = 37377
+
+// requiredIntents derives gateway intents from enabled features unless an
+// explicit override is configured.
+func requiredIntents(cfg config.DiscordConfig) discordgo.Intent {
+	if cfg.Intents != nil {
+		return discordgo.Intent(*cfg.Intents)
+	}
+	intents := discordgo.IntentsGuilds
+	if cfg.GuildMessagesValue() {
+		intents |= discordgo.IntentsGuildMessages | discordgo.IntentGuildMessagePolls
+		if cfg.ReactionsValue() {
+			intents |= discordgo.IntentsGuildMessageReactions
+		}
+	}
+	if cfg.DirectMessagesValue() {
+		intents |= discordgo.IntentsDirectMessages | discordgo.IntentDirectMessagePolls
+		if cfg.ReactionsValue() {
+			intents |= discordgo.IntentsDirectMessageReactions
+		}
+	}
+	if cfg.MessageContentValue() {
+		intents |= discordgo.IntentsMessageContent
+	}
+	return intents
+}
+
+// explainOpenError turns gateway close codes into actionable errors.
+func explainOpenError(err error) error {
+	var ce *websocket.CloseError
+	if !errors.As(err, &ce) {
+		return err
+	}
+	switch ce.Code {
+	case 4004:
+		return fmt.Errorf("discord authentication failed (4004): check channels.discord.token: %w", err)
+	case 4013:
+		return fmt.Errorf("discord rejected invalid intents (4013): check channels.discord.intents: %w", err)
+	case 4014:
+		return fmt.Errorf("discord rejected privileged intents (4014): enable \"Message Content Intent\" under Developer Portal -> Bot -> Privileged Gateway Intents, or set channels.discord.messageContent=false: %w", err)
+	}
+	return err
+}
+
+// diagnoseIntents reports feature/intent combinations that make the bot
+// silently miss messages.
+func diagnoseIntents(intents discordgo.Intent, appFlags int, appFlagsKnown bool) []string {
+	var out []string
+	if intents&discordgo.IntentsGuildMessages == 0 && intents&discordgo.IntentsDirectMessages == 0 {
+		out = append(out, "no message intents enabled; the bot will not receive any messages (enable guildMessages or directMessages)")
+	}
+	if intents&discordgo.IntentsMessageContent == 0 {
+		if intents&discordgo.IntentsGuildMessages != 0 {
+			out = append(out, "message content intent is disabled; guild messages arrive without text unless the bot is mentioned (DMs are unaffected)")
+		}
+	} else if appFlagsKnown && appFlags&(appFlagGatewayMessageContent|appFlagGatewayMessageContentLimited) == 0 {
+		out = append(out, "\"Message Content Intent\" is not enabled for this application in the Developer Portal (Bot -> Privileged Gateway Intents)")
+	}
+	return out
+}
+
+// diagnoseOverride reports an intents override that leaves out intents the
+// feature flags need, such as the one older versions wrote into configs.
+func diagnoseOverride(cfg config.DiscordConfig) string {
+	if cfg.Intents == nil {
+		return ""
+	}
+	derived := cfg
+	derived.Intents = nil
+	missing := requiredIntents(derived) &^ discordgo.Intent(*cfg.Intents)
+	if missing == 0 {
+		return ""
+	}
+	origin := ""
+	if *cfg.Intents == legacyIntents {
+		origin = ", the default older versions wrote into the config"
+	}
+	return fmt.Sprintf("channels.discord.intents is %d%s and lacks intents %d that the feature flags enable; remove it to derive intents from the flags", *cfg.Intents, origin, missing)
+}
+
+// diagnoseGuildPermissions reports when the bot cannot post in any text
+// channel of a guild, which otherwise only shows up as failed sends.
+func diagnoseGuildPermissions(st *discordgo.State, g *discordgo.Guild) string {
+	if st == nil || g == nil || st.User == nil {
+		return ""
+	}
+	const need = discordgo.PermissionViewChannel | discordgo.PermissionSendMessages
+	text := 0
+	for _, ch := range g.Channels {
+		if ch == nil || ch.Type != discordgo.ChannelTypeGuildText {
+			continue
+		}
+		text++
+		perms, err := st.UserChannelPermissions(st.User.ID, ch.ID)
+		if err != nil {
+			return ""
+		}
+		if perms&need == need {
+			return ""
+		}
+	}
+	if text == 0 {
+		return ""
+	}
+	return fmt.Sprintf("guild %q: bot cannot view+send in any text channel; grant View Channel and Send Messages (and Read Message History for replies) to the bot role", g.Name)
+}
+
+// explainSendError annotates permission failures with the fix.
+func explainSendError(err error, chID string) error {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) || restErr.Message == nil {
+		return err
+	}
+	switch restErr.Message.Code {
+	case discordgo.ErrCodeMissingAccess:
+		return fmt.Errorf("discord: missing access to channel %s (bot needs View Channel, and the thread/channel must be visible to it): %w", chID, err)
+	case discordgo.ErrCodeMissingPermissions:
+		return fmt.Errorf("discord: missing permissions in channel %s (grant Send Messages and Read Message History): %w", chID, err)
+	}
+	return err
+}
+
+func logDiagnostics(issues []string) {
+	for _, issue := range issues {
+		if strings.TrimSpace(issue) == "" {
+			continue
+		}
+		log.Printf("discord: diagnostic: %s", issue)
+	}
+}
@@ -0,0 +1,110 @@
+package discord
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestRequiredIntents(t *testing.T) {
+	off := false
+	on := true
+	tests := []struct {
+		name string
+		cfg  config.DiscordConfig
+		want discordgo.Intent
+	}{
+		{
+			name: "defaults",
+			cfg:  config.DiscordConfig{},
+			want: 37377 | discordgo.IntentGuildMessagePolls | discordgo.IntentDirectMessagePolls,
+		},
+		{
+			name: "dm only without content",
+			cfg:  config.DiscordConfig{GuildMessages: &off, MessageContent: &off},
+			want: discordgo.IntentsGuilds | discordgo.IntentsDirectMessages | discordgo.IntentDirectMessagePolls,
+		},
+		{
+			name: "reactions",
+			cfg:  config.DiscordConfig{Reactions: &on},
+			want: 37377 | discordgo.IntentGuildMessagePolls | discordgo.IntentDirectMessagePolls | discordgo.IntentsGuildMessageReactions | discordgo.IntentsDirectMessageReactions,
+		},
+		{
+			name: "explicit override",
+			cfg:  config.DiscordConfig{Intents: intPtr(512), Reactions: &on},
+			want: 512,
+		},
+		{
+			name: "explicit old default",
+			cfg:  config.DiscordConfig{Intents: intPtr(legacyIntents), Reactions: &on},
+			want: legacyIntents,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requiredIntents(tt.cfg); got != tt.want {
+				t.Fatalf("requiredIntents()=%d want=%d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainOpenError_DisallowedIntents(t *testing.T) {
+	err := explainOpenError(&websocket.CloseError{Code: 4014, Text: "Disallowed intent(s)."})
+	if !strings.Contains(err.Error(), "Message Content Intent") {
+		t.Fatalf("expected actionable message, got %v", err)
+	}
+	var ce *websocket.CloseError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected wrapped close error")
+	}
+	plain := errors.New("boom")
+	if explainOpenError(plain) != plain {
+		t.Fatalf("expected non-close errors to pass through")
+	}
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestDiagnoseOverride(t *testing.T) {
+	on := true
+	if got := diagnoseOverride(config.DiscordConfig{Reactions: &on}); got != "" {
+		t.Fatalf("expected no issue without an override, got %q", got)
+	}
+	if got := diagnoseOverride(config.DiscordConfig{Intents: intPtr(1<<26 - 1)}); got != "" {
+		t.Fatalf("expected no issue for a superset, got %q", got)
+	}
+	if got := diagnoseOverride(config.DiscordConfig{Intents: intPtr(legacyIntents), Reactions: &on}); !strings.Contains(got, "older versions") {
+		t.Fatalf("expected old default issue, got %q", got)
+	}
+}
+
+func TestDiagnoseIntents(t *testing.T) {
+	if got := diagnoseIntents(37377, appFlagGatewayMessageContentLimited, true); len(got) != 0 {
+		t.Fatalf("expected no issues, got %v", got)
+	}
+	if got := diagnoseIntents(37377, 0, true); len(got) != 1 || !strings.Contains(got[0], "Developer Portal") {
+		t.Fatalf("expected portal issue, got %v", got)
+	}
+	if got := diagnoseIntents(37377, 0, false); len(got) != 0 {
+		t.Fatalf("expected no issues when flags are unknown, got %v", got)
+	}
+	noContent := discordgo.IntentsGuilds | discordgo.IntentsGuildMessages
+	if got := diagnoseIntents(noContent, 0, true); len(got) != 1 || !strings.Contains(got[0], "without text") {
+		t.Fatalf("expected missing content issue, got %v", got)
+	}
+	if got := diagnoseIntents(discordgo.IntentsGuilds, 0, true); len(got) != 1 || !strings.Contains(got[0], "no message intents") {
+		t.Fatalf("expected no-message-intents issue, got %v", got)
+	}
+}
+
+func TestExplainSendError_MissingPermissions(t *testing.T) {
+	err := explainSendError(&discordgo.RESTError{Message: &discordgo.APIErrorMessage{Code: discordgo.ErrCodeMissingPermissions}}, "c1")
+	if !strings.Contains(err.Error(), "Send Messages") {
+		t.Fatalf("expected actionable message, got %v", err)
+	}
+}
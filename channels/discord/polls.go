@@ -0,0 +1,104 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+func (c *Channel) SupportsPolls() bool { return true }
+
+// sendPoll posts a poll. Discord keeps voting open for DurationHours, 24
+// hours when unset.
+func sendPoll(ctx context.Context, dg *discordgo.Session, chID string, p bus.Poll) error {
+	if len(p.Options) < 2 {
+		return fmt.Errorf("discord: a poll needs at least 2 options")
+	}
+	poll := &discordgo.Poll{
+		Question:         discordgo.PollMedia{Text: p.Question},
+		AllowMultiselect: p.MultiSelect,
+		Duration:         p.DurationHours,
+	}
+	for _, o := range p.Options {
+		poll.Answers = append(poll.Answers, discordgo.PollAnswer{Media: &discordgo.PollMedia{Text: o}})
+	}
+	_, err := dg.ChannelMessageSendComplex(chID, &discordgo.MessageSend{Poll: poll}, discordgo.WithContext(ctx))
+	return err
+}
+
+func (c *Channel) onPollVoteAdd(s *discordgo.Session, v *discordgo.MessagePollVoteAdd) {
+	if v == nil {
+		return
+	}
+	c.publishPoll(s, v.ChannelID, v.MessageID, v.GuildID, v.UserID)
+}
+
+func (c *Channel) onPollVoteRemove(s *discordgo.Session, v *discordgo.MessagePollVoteRemove) {
+	if v == nil {
+		return
+	}
+	c.publishPoll(s, v.ChannelID, v.MessageID, v.GuildID, v.UserID)
+}
+
+// publishPoll passes the current votes of one of the bot's polls on to the
+// agent. Vote events carry only the voter and answer, so the poll message
+// is fetched for the totals.
+func (c *Channel) publishPoll(s *discordgo.Session, chID, messageID, guildID, userID string) {
+	self := discordSelfID(s)
+	chID = strings.TrimSpace(chID)
+	if self == "" || userID == self || chID == "" || messageID == "" || !c.inScope(s, guildID, chID) {
+		return
+	}
+	m, err := s.ChannelMessage(chID, messageID)
+	if err != nil || m == nil || m.Poll == nil || m.Author == nil || m.Author.ID != self {
+		return
+	}
+	delivery := bus.Delivery{IsDirect: strings.TrimSpace(guildID) == ""}
+	if ch := lookupDiscordChannel(s, chID); !delivery.IsDirect && ch != nil && ch.IsThread() {
+		delivery.ThreadID = chID
+	}
+	poll := pollResults(m.Poll)
+	poll.ID = m.ID
+
+	ctx := context.Background()
+	c.mu.Lock()
+	if c.ctx != nil {
+		ctx = c.ctx
+	}
+	c.mu.Unlock()
+	_ = c.bus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:    c.name,
+		SenderID:   userID,
+		ChatID:     chID,
+		SessionKey: c.name + ":" + chID,
+		Delivery:   delivery,
+		Kind:       bus.MessageKindPoll,
+		Poll:       poll,
+	})
+}
+
+// pollResults lists a fetched poll's answers with their vote counts.
+func pollResults(p *discordgo.Poll) bus.Poll {
+	out := bus.Poll{Question: p.Question.Text, MultiSelect: p.AllowMultiselect}
+	counts := map[int]int{}
+	if p.Results != nil {
+		out.Closed = p.Results.Finalized
+		for _, ac := range p.Results.AnswerCounts {
+			if ac != nil {
+				counts[ac.ID] = ac.Count
+			}
+		}
+	}
+	for _, a := range p.Answers {
+		text := ""
+		if a.Media != nil {
+			text = a.Media.Text
+		}
+		out.Options = append(out.Options, text)
+		out.Votes = append(out.Votes, counts[a.AnswerID])
+	}
+	return out
+}
@@ -0,0 +1,29 @@
+package discord
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPollResults(t *testing.T) {
+	got := pollResults(&discordgo.Poll{
+		Question: discordgo.PollMedia{Text: "Lunch?"},
+		Answers: []discordgo.PollAnswer{
+			{AnswerID: 1, Media: &discordgo.PollMedia{Text: "Pizza"}},
+			{AnswerID: 2, Media: &discordgo.PollMedia{Text: "Sushi"}},
+			{AnswerID: 3, Media: &discordgo.PollMedia{Text: "Tacos"}},
+		},
+		Results: &discordgo.PollResults{Finalized: true, AnswerCounts: []*discordgo.PollAnswerCount{
+			{ID: 2, Count: 1},
+			{ID: 1, Count: 3},
+		}},
+	})
+	if got.Question != "Lunch?" || !got.Closed {
+		t.Fatalf("poll: %+v", got)
+	}
+	if !slices.Equal(got.Options, []string{"Pizza", "Sushi", "Tacos"}) || !slices.Equal(got.Votes, []int{3, 1, 0}) {
+		t.Fatalf("options=%v votes=%v", got.Options, got.Votes)
+	}
+}
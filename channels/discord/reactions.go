@@ -0,0 +1,98 @@
+package discord
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+func (c *Channel) SupportsReactions() bool { return true }
+
+// sendReaction adds the bot's reaction to a message. emoji is a Unicode
+// emoji or a custom one as "name:id".
+func sendReaction(ctx context.Context, dg *discordgo.Session, chID string, r bus.Reaction) error {
+	emoji := strings.Trim(strings.TrimSpace(r.Emoji), "<>")
+	emoji = strings.TrimPrefix(emoji, ":")
+	return dg.MessageReactionAdd(chID, strings.TrimSpace(r.MessageID), emoji, discordgo.WithContext(ctx))
+}
+
+func (c *Channel) onReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if r == nil || r.MessageReaction == nil || (r.Member != nil && r.Member.User != nil && r.Member.User.Bot) {
+		return
+	}
+	c.publishReaction(s, r.MessageReaction, false)
+}
+
+func (c *Channel) onReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	if r == nil || r.MessageReaction == nil {
+		return
+	}
+	c.publishReaction(s, r.MessageReaction, true)
+}
+
+// publishReaction passes a reaction on to the agent. In servers the group
+// policy applies; a reaction counts as addressed to the bot when it is on
+// one of the bot's messages.
+func (c *Channel) publishReaction(s *discordgo.Session, r *discordgo.MessageReaction, removed bool) {
+	self := discordSelfID(s)
+	if r.UserID == "" || r.UserID == self || !c.inScope(s, r.GuildID, r.ChannelID) || !c.allow.Allowed(r.UserID) {
+		return
+	}
+	chID := strings.TrimSpace(r.ChannelID)
+	emoji := reactionEmoji(r.Emoji)
+	if chID == "" || emoji == "" {
+		return
+	}
+	delivery := bus.Delivery{IsDirect: strings.TrimSpace(r.GuildID) == ""}
+	if !delivery.IsDirect {
+		ch := lookupDiscordChannel(s, chID)
+		if !c.groupPolicy().Allows(reactedToBot(s, chID, r.MessageID, self), discordGroupIDs(chID, r.GuildID, ch)...) {
+			return
+		}
+		if ch != nil && ch.IsThread() {
+			delivery.ThreadID = chID
+		}
+	}
+
+	ctx := context.Background()
+	c.mu.Lock()
+	if c.ctx != nil {
+		ctx = c.ctx
+	}
+	c.mu.Unlock()
+	_ = c.bus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:    c.name,
+		SenderID:   r.UserID,
+		ChatID:     chID,
+		SessionKey: c.name + ":" + chID,
+		Delivery:   delivery,
+		Kind:       bus.MessageKindReaction,
+		Reaction:   bus.Reaction{Emoji: emoji, MessageID: r.MessageID, Removed: removed},
+	})
+}
+
+// reactionEmoji is the Unicode emoji, or ":name:" for a custom one.
+func reactionEmoji(e discordgo.Emoji) string {
+	name := strings.TrimSpace(e.Name)
+	if name != "" && e.ID != "" {
+		return ":" + name + ":"
+	}
+	return name
+}
+
+// reactedToBot reports whether the message was sent by the bot, from the
+// gateway state or else the REST API.
+func reactedToBot(s *discordgo.Session, chID, msgID, self string) bool {
+	if self == "" || s == nil {
+		return false
+	}
+	if s.State != nil {
+		if m, err := s.State.Message(chID, msgID); err == nil && m.Author != nil {
+			return m.Author.ID == self
+		}
+	}
+	m, err := s.ChannelMessage(chID, msgID)
+	return err == nil && m.Author != nil && m.Author.ID == self
+}
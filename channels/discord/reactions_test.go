@@ -0,0 +1,16 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestReactionEmoji(t *testing.T) {
+	if got := reactionEmoji(discordgo.Emoji{Name: "👍"}); got != "👍" {
+		t.Fatalf("unicode: %q", got)
+	}
+	if got := reactionEmoji(discordgo.Emoji{Name: "party_parrot", ID: "123"}); got != ":party_parrot:" {
+		t.Fatalf("custom: %q", got)
+	}
+}
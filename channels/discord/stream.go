@@ -0,0 +1,65 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+)
+
+func (c *Channel) SupportsStreaming() bool { return true }
+
+// sendPartial shows the text so far of a streamed reply: in the deferred
+// reply of a slash command, else in a message sent for the first update and
+// edited by later ones. Send finishes the stream with the final message.
+func (c *Channel) sendPartial(ctx context.Context, dg *discordgo.Session, chID string, msg bus.OutboundMessage) error {
+	text := channels.PartialText(strings.TrimSpace(msg.Content), channels.MaxDiscordText)
+	if text == "" {
+		return nil
+	}
+	if it := c.peekInteraction(chID); it != nil {
+		return sendWithRetry(ctx, chID, func(ctx context.Context) error {
+			return editInteractionReply(ctx, dg, it, text, nil, nil)
+		})
+	}
+	id, shown, ok := c.streams.Get(msg.StreamID)
+	if ok && shown == text {
+		return nil
+	}
+	return sendWithRetry(ctx, chID, func(ctx context.Context) error {
+		if ok {
+			_, err := dg.ChannelMessageEdit(chID, id, text, discordgo.WithContext(ctx))
+			if err == nil {
+				c.streams.Set(msg.StreamID, id, text)
+			}
+			return err
+		}
+		sent, err := sendDiscordMessage(ctx, dg, chID, text, resolveDiscordReplyTarget(c.cfg.Quote, msg), nil, nil)
+		if err != nil {
+			return err
+		}
+		c.streams.Set(msg.StreamID, sent.ID, text)
+		return nil
+	})
+}
+
+// editDiscordMessage replaces the text of message id and appends files and
+// embeds.
+func editDiscordMessage(ctx context.Context, dg *discordgo.Session, chID, id, content string, files []bus.Attachment, embeds []*discordgo.MessageEmbed) error {
+	edit := discordgo.NewMessageEdit(chID, id).SetContent(content)
+	if len(embeds) > 0 {
+		edit.SetEmbeds(embeds)
+	}
+	for _, f := range files {
+		edit.Files = append(edit.Files, &discordgo.File{
+			Name:        f.Name,
+			ContentType: f.MIMEType,
+			Reader:      bytes.NewReader(f.Data),
+		})
+	}
+	_, err := dg.ChannelMessageEditComplex(edit, discordgo.WithContext(ctx))
+	return err
+}
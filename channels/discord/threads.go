@@ -0,0 +1,59 @@
+package discord
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxDiscordThreadName is Discord's thread title limit.
+	maxDiscordThreadName = 100
+	// discordThreadArchiveMinutes archives threads after a day without
+	// messages.
+	discordThreadArchiveMinutes = 1440
+)
+
+// lookupDiscordChannel returns the channel from the gateway state, falling
+// back to the REST API; nil when it cannot be found.
+func lookupDiscordChannel(s *discordgo.Session, chID string) *discordgo.Channel {
+	if s == nil {
+		return nil
+	}
+	if s.State != nil {
+		if ch, err := s.State.Channel(chID); err == nil {
+			return ch
+		}
+	}
+	ch, err := s.Channel(chID)
+	if err != nil {
+		return nil
+	}
+	return ch
+}
+
+// startsThread reports whether a message in ch should get its reply in a
+// new thread: threadReplies is on and ch is a text or announcement channel
+// (not a thread, DM or forum).
+func startsThread(ch *discordgo.Channel, threadReplies bool) bool {
+	if !threadReplies || ch == nil {
+		return false
+	}
+	return ch.Type == discordgo.ChannelTypeGuildText || ch.Type == discordgo.ChannelTypeGuildNews
+}
+
+// discordThreadName is a thread title taken from the first line of the
+// message that starts it.
+func discordThreadName(content string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	line = strings.Join(strings.Fields(line), " ")
+	if line == "" {
+		return "Conversation"
+	}
+	if utf8.RuneCountInString(line) > maxDiscordThreadName {
+		r := []rune(line)
+		line = strings.TrimSpace(string(r[:maxDiscordThreadName-1])) + "…"
+	}
+	return line
+}
@@ -0,0 +1,34 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestStartsThread(t *testing.T) {
+	text := &discordgo.Channel{Type: discordgo.ChannelTypeGuildText}
+	thread := &discordgo.Channel{Type: discordgo.ChannelTypeGuildPublicThread}
+	forum := &discordgo.Channel{Type: discordgo.ChannelTypeGuildForum}
+	if !startsThread(text, true) || startsThread(text, false) {
+		t.Fatal("text channel")
+	}
+	if startsThread(thread, true) || startsThread(forum, true) || startsThread(nil, true) {
+		t.Fatal("threads, forums and unknown channels must not start threads")
+	}
+}
+
+func TestDiscordThreadName(t *testing.T) {
+	if got := discordThreadName("  plan   the trip\nmore details"); got != "plan the trip" {
+		t.Fatalf("got %q", got)
+	}
+	if got := discordThreadName(""); got != "Conversation" {
+		t.Fatalf("empty: %q", got)
+	}
+	long := discordThreadName(strings.Repeat("é", 150))
+	if utf8.RuneCountInString(long) != maxDiscordThreadName || !strings.HasSuffix(long, "…") {
+		t.Fatalf("long: %d runes", utf8.RuneCountInString(long))
+	}
+}
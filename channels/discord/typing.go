@@ -0,0 +1,20 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SendTyping shows "typing…" in the channel for about 10 seconds.
+func (c *Channel) SendTyping(ctx context.Context, chatID string) error {
+	c.mu.Lock()
+	dg := c.dg
+	c.mu.Unlock()
+	if dg == nil {
+		return fmt.Errorf("discord not connected")
+	}
+	return dg.ChannelTyping(strings.TrimSpace(chatID), discordgo.WithContext(ctx))
+}
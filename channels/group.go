@@ -0,0 +1,85 @@
+package channels
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Group policies, as named in each channel's "groupPolicy" and "groups"
+// settings. They decide which messages in group chats reach the agent;
+// direct messages are not affected.
+const (
+	GroupPolicyMention   = "mention"
+	GroupPolicyOpen      = "open"
+	GroupPolicyAllowlist = "allowlist"
+	GroupPolicyDisabled  = "disabled"
+)
+
+// GroupPolicy decides whether the bot answers a group message. Under
+// "mention" it only answers messages addressed to it: ones that mention
+// the bot or reply to one of its messages.
+type GroupPolicy struct {
+	Policy    string            // "" is GroupPolicyMention
+	AllowFrom []string          // group IDs answered under GroupPolicyAllowlist
+	Groups    map[string]string // per-group policy, overriding Policy
+}
+
+// Allows reports whether a message in a group is answered. ids are the
+// group's IDs from the most specific one, e.g. a Discord channel and then
+// its server; the first one with an override in Groups decides. An unknown
+// policy answers nothing.
+func (p GroupPolicy) Allows(addressed bool, ids ...string) bool {
+	for _, id := range ids {
+		if policy, ok := p.Groups[id]; ok && id != "" {
+			return groupPolicyAllows(policy, addressed)
+		}
+	}
+	switch normalizeGroupPolicy(p.Policy) {
+	case GroupPolicyAllowlist:
+		for _, v := range p.AllowFrom {
+			if v = strings.TrimSpace(v); v != "" && slices.Contains(ids, v) {
+				return true
+			}
+		}
+		return false
+	default:
+		return groupPolicyAllows(p.Policy, addressed)
+	}
+}
+
+// Validate rejects unknown policy names; the gateway checks this at startup.
+func (p GroupPolicy) Validate() error {
+	switch normalizeGroupPolicy(p.Policy) {
+	case GroupPolicyMention, GroupPolicyOpen, GroupPolicyAllowlist, GroupPolicyDisabled:
+	default:
+		return fmt.Errorf("unknown group policy %q (use %q, %q, %q or %q)", p.Policy, GroupPolicyMention, GroupPolicyOpen, GroupPolicyAllowlist, GroupPolicyDisabled)
+	}
+	for id, policy := range p.Groups {
+		switch normalizeGroupPolicy(policy) {
+		case GroupPolicyMention, GroupPolicyOpen, GroupPolicyDisabled:
+		default:
+			return fmt.Errorf("group %s: unknown policy %q (use %q, %q or %q)", id, policy, GroupPolicyMention, GroupPolicyOpen, GroupPolicyDisabled)
+		}
+	}
+	return nil
+}
+
+func groupPolicyAllows(policy string, addressed bool) bool {
+	switch normalizeGroupPolicy(policy) {
+	case GroupPolicyOpen:
+		return true
+	case GroupPolicyMention:
+		return addressed
+	default:
+		return false
+	}
+}
+
+func normalizeGroupPolicy(policy string) string {
+	policy = strings.ToLower(strings.TrimSpace(policy))
+	if policy == "" {
+		return GroupPolicyMention
+	}
+	return policy
+}
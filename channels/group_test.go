@@ -0,0 +1,47 @@
+package channels
+
+import "testing"
+
+func TestGroupPolicy_Allows(t *testing.T) {
+	mention := GroupPolicy{}
+	if mention.Allows(false, "c1") || !mention.Allows(true, "c1") {
+		t.Fatal("default policy should answer only addressed messages")
+	}
+	if !(GroupPolicy{Policy: " Open "}).Allows(false, "c1") {
+		t.Fatal("open should answer everything")
+	}
+	if (GroupPolicy{Policy: "bogus"}).Allows(true, "c1") {
+		t.Fatal("unknown policy should answer nothing")
+	}
+
+	allow := GroupPolicy{Policy: "allowlist", AllowFrom: []string{"guild"}}
+	if !allow.Allows(false, "c1", "guild") || allow.Allows(true, "c2", "other") {
+		t.Fatal("allowlist should match any of the group's IDs")
+	}
+
+	p := GroupPolicy{Groups: map[string]string{"c1": "open", "guild": "disabled"}}
+	if !p.Allows(false, "c1", "guild") {
+		t.Fatal("channel override should win over the server's")
+	}
+	if p.Allows(true, "c2", "guild") {
+		t.Fatal("server override should apply to its other channels")
+	}
+	if !p.Allows(true, "c3", "other") || p.Allows(false, "c3", "other") {
+		t.Fatal("groups without an override should follow the policy")
+	}
+}
+
+func TestGroupPolicy_Validate(t *testing.T) {
+	if err := (GroupPolicy{}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (GroupPolicy{Policy: "allowlist", Groups: map[string]string{"1": "Mention"}}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (GroupPolicy{Policy: "mentions"}).Validate(); err == nil {
+		t.Fatal("expected unknown policy error")
+	}
+	if err := (GroupPolicy{Groups: map[string]string{"1": "allowlist"}}).Validate(); err == nil {
+		t.Fatal("allowlist is not a per-group policy")
+	}
+}
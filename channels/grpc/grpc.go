@@ -0,0 +1,347 @@
+// Package grpc is a channel for programs: other services hold clawlet
+// conversations over the bidirectional Chat RPC defined in pb/clawlet.proto.
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/channels/grpc/pb"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/handover"
+)
+
+const (
+	// maxInFlight is how many messages a stream may have waiting for a
+	// reply. Further requests are not read until a reply is sent, so a busy
+	// agent pushes back on the client through gRPC flow control.
+	maxInFlight = 8
+	// sendTimeout bounds how long Send waits for a client that stopped
+	// reading, so one stream cannot hold up other channels' replies.
+	sendTimeout = 10 * time.Second
+)
+
+// Channel serves the Chat service. Replies for a chat go to the stream that
+// last sent a message in it.
+type Channel struct {
+	cfg   config.GRPCConfig
+	bus   *bus.Bus
+	allow channels.AllowList
+
+	running atomic.Bool
+	seq     atomic.Uint64
+
+	mu     sync.Mutex
+	srv    *grpclib.Server
+	routes map[string]*stream // chat ID -> stream
+}
+
+func New(cfg config.GRPCConfig, b *bus.Bus) *Channel {
+	return &Channel{
+		cfg:    cfg,
+		bus:    b,
+		allow:  channels.AllowList{AllowFrom: cfg.AllowFrom},
+		routes: map[string]*stream{},
+	}
+}
+
+func (c *Channel) Name() string    { return "grpc" }
+func (c *Channel) IsRunning() bool { return c.running.Load() }
+
+func (c *Channel) Start(ctx context.Context) error {
+	if strings.TrimSpace(c.cfg.Token) == "" {
+		return errors.New("grpc token is empty")
+	}
+	ln, err := handover.Listen(c.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	srv := grpclib.NewServer()
+	pb.RegisterChatServer(srv, &server{c: c})
+	c.mu.Lock()
+	c.srv = srv
+	c.mu.Unlock()
+
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	select {
+	case <-ctx.Done():
+		// Chat streams are long-lived; a graceful stop would wait for
+		// every client to hang up.
+		srv.Stop()
+		return ctx.Err()
+	case err := <-errCh:
+		if errors.Is(err, grpclib.ErrServerStopped) {
+			return nil
+		}
+		return err
+	}
+}
+
+func (c *Channel) Stop() error {
+	c.mu.Lock()
+	srv := c.srv
+	c.srv = nil
+	c.mu.Unlock()
+	if srv != nil {
+		srv.Stop()
+	}
+	return nil
+}
+
+// Send delivers a reply to the stream serving msg.ChatID.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	chatID := strings.TrimSpace(msg.ChatID)
+	c.mu.Lock()
+	st := c.routes[chatID]
+	c.mu.Unlock()
+	if st == nil {
+		return fmt.Errorf("grpc: no open stream for chat %s", chatID)
+	}
+	return st.send(ctx, &pb.ChatResponse{
+		ChatId:      chatID,
+		Text:        msg.Content,
+		ReplyToId:   msg.Delivery.MessageID,
+		Suggestions: msg.Suggestions,
+	})
+}
+
+// server adapts Channel to the generated service interface.
+type server struct {
+	pb.UnimplementedChatServer
+	c *Channel
+}
+
+func (s *server) Chat(srv pb.Chat_ChatServer) error {
+	c := s.c
+	if err := c.authorize(srv.Context()); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(srv.Context())
+	defer cancel()
+	st := newStream(ctx)
+	defer c.unroute(st)
+
+	pumpErr := make(chan error, 1)
+	go func() { pumpErr <- st.pump(srv) }()
+
+	err := c.receive(ctx, srv, st)
+	if err == nil {
+		// The client closed its side; finish once the pending replies
+		// are out.
+		err = st.drain(ctx)
+	}
+	c.unroute(st)
+	st.close()
+	if err != nil {
+		cancel()
+		<-pumpErr
+		return err
+	}
+	return <-pumpErr
+}
+
+func (c *Channel) receive(ctx context.Context, srv pb.Chat_ChatServer, st *stream) error {
+	for {
+		req, err := srv.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		chatID := strings.TrimSpace(req.GetChatId())
+		text := strings.TrimSpace(req.GetText())
+		if chatID == "" || text == "" {
+			return status.Error(codes.InvalidArgument, "chat_id and text are required")
+		}
+		senderID := strings.TrimSpace(req.GetSenderId())
+		if !c.allow.Allowed(senderID) {
+			return status.Errorf(codes.PermissionDenied, "sender not allowed: %s", senderID)
+		}
+		id := strings.TrimSpace(req.GetMessageId())
+		if id == "" {
+			id = "grpc-" + strconv.FormatUint(c.seq.Add(1), 10)
+		}
+		if err := st.acquire(ctx, id); err != nil {
+			return err
+		}
+		c.route(chatID, st)
+		err = c.bus.PublishInbound(ctx, bus.InboundMessage{
+			Channel:    "grpc",
+			SenderID:   senderID,
+			ChatID:     chatID,
+			Content:    text,
+			SessionKey: "grpc:" + chatID,
+			Delivery:   bus.Delivery{MessageID: id, IsDirect: true},
+		})
+		if err != nil {
+			st.release(id)
+			return err
+		}
+	}
+}
+
+// authorize checks the "authorization: Bearer <token>" metadata.
+func (c *Channel) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(v, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(c.cfg.Token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid token")
+}
+
+func (c *Channel) route(chatID string, st *stream) {
+	c.mu.Lock()
+	c.routes[chatID] = st
+	c.mu.Unlock()
+}
+
+func (c *Channel) unroute(st *stream) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for chatID, s := range c.routes {
+		if s == st {
+			delete(c.routes, chatID)
+		}
+	}
+}
+
+// stream is one open Chat call.
+type stream struct {
+	ctx   context.Context
+	slots chan struct{}
+
+	mu      sync.RWMutex
+	closed  bool
+	out     chan *pb.ChatResponse
+	pending map[string]bool // message IDs waiting for a reply
+}
+
+func newStream(ctx context.Context) *stream {
+	return &stream{
+		ctx:     ctx,
+		slots:   make(chan struct{}, maxInFlight),
+		out:     make(chan *pb.ChatResponse, maxInFlight),
+		pending: map[string]bool{},
+	}
+}
+
+// acquire waits for a free slot and marks id as waiting for a reply.
+func (s *stream) acquire(ctx context.Context, id string) error {
+	s.mu.Lock()
+	dup := s.pending[id]
+	s.mu.Unlock()
+	if dup {
+		return status.Errorf(codes.InvalidArgument, "duplicate message_id: %s", id)
+	}
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.mu.Lock()
+	s.pending[id] = true
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *stream) release(id string) {
+	s.mu.Lock()
+	ok := s.pending[id]
+	delete(s.pending, id)
+	s.mu.Unlock()
+	if ok {
+		<-s.slots
+	}
+}
+
+// drain waits until every message has been answered.
+func (s *stream) drain(ctx context.Context) error {
+	for range maxInFlight {
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// send queues resp; a reply to a message frees its slot.
+func (s *stream) send(ctx context.Context, resp *pb.ChatResponse) error {
+	if err := s.enqueue(ctx, resp); err != nil {
+		return err
+	}
+	if resp.ReplyToId != "" {
+		s.release(resp.ReplyToId)
+	}
+	return nil
+}
+
+func (s *stream) enqueue(ctx context.Context, resp *pb.ChatResponse) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return fmt.Errorf("grpc: stream for chat %s is closed", resp.ChatId)
+	}
+	timer := time.NewTimer(sendTimeout)
+	defer timer.Stop()
+	select {
+	case s.out <- resp:
+		return nil
+	case <-s.ctx.Done():
+		return fmt.Errorf("grpc: stream for chat %s is closed", resp.ChatId)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("grpc: client is not reading replies for chat %s", resp.ChatId)
+	}
+}
+
+// pump writes queued replies to the client until the stream is closed.
+func (s *stream) pump(srv pb.Chat_ChatServer) error {
+	for {
+		select {
+		case resp, ok := <-s.out:
+			if !ok {
+				return nil
+			}
+			if err := srv.Send(resp); err != nil {
+				return err
+			}
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		}
+	}
+}
+
+func (s *stream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.out)
+	}
+}
@@ -0,0 +1,111 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels/grpc/pb"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func newTestClient(t *testing.T, c *Channel) pb.ChatClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	srv := grpclib.NewServer()
+	pb.RegisterChatServer(srv, &server{c: c})
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpclib.NewClient("passthrough:///bufnet",
+		grpclib.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpclib.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewChatClient(conn)
+}
+
+func TestChat_RejectsBadToken(t *testing.T) {
+	client := newTestClient(t, New(config.GRPCConfig{Token: "secret"}, bus.New(1)))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	stream, err := client.Chat(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestChat_RoundTrip(t *testing.T) {
+	b := bus.New(4)
+	c := New(config.GRPCConfig{Token: "secret", AllowFrom: []string{"svc"}}, b)
+	client := newTestClient(t, c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	stream, err := client.Chat(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&pb.ChatRequest{ChatId: "c1", SenderId: "svc", Text: "hi", MessageId: "m1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	in, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Channel != "grpc" || in.ChatID != "c1" || in.SessionKey != "grpc:c1" || in.Delivery.MessageID != "m1" {
+		t.Fatalf("inbound=%+v", in)
+	}
+	if err := c.Send(ctx, bus.OutboundMessage{Channel: "grpc", ChatID: "c1", Content: "hello", Delivery: in.Delivery}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetText() != "hello" || resp.GetReplyToId() != "m1" {
+		t.Fatalf("resp=%+v", resp)
+	}
+	// The client closed its side and every message was answered.
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("expected end of stream, got %v", err)
+	}
+	if err := c.Send(ctx, bus.OutboundMessage{Channel: "grpc", ChatID: "c1", Content: "late"}); err == nil {
+		t.Fatal("send after stream end should fail")
+	}
+}
+
+func TestChat_RejectsDisallowedSender(t *testing.T) {
+	client := newTestClient(t, New(config.GRPCConfig{Token: "secret", AllowFrom: []string{"svc"}}, bus.New(1)))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	stream, err := client.Chat(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&pb.ChatRequest{ChatId: "c1", SenderId: "other", Text: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("err=%v", err)
+	}
+}
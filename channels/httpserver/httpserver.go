@@ -0,0 +1,211 @@
+// Package httpserver serves the webhooks of several channels on one
+// listener, so that only one port has to be exposed through a reverse
+// proxy or tunnel. Channels attach their handler while they run; requests
+// for a channel that isn't running get 503.
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mosaxiv/clawlet/handover"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Server is the shared webhook server.
+type Server struct {
+	listen   string
+	certFile string
+	keyFile  string
+	acme     *autocert.Manager
+
+	mu     sync.RWMutex
+	routes map[string]*route // channel name -> route
+	owners map[string]string // path -> channel name
+
+	done chan struct{}
+	err  error
+}
+
+type route struct {
+	handler http.Handler
+	paths   []string
+	detach  chan struct{}
+}
+
+// New returns a server for listen. With certFile and keyFile, or after
+// UseACME, it serves HTTPS.
+func New(listen, certFile, keyFile string) *Server {
+	return &Server{
+		listen:   strings.TrimSpace(listen),
+		certFile: strings.TrimSpace(certFile),
+		keyFile:  strings.TrimSpace(keyFile),
+		routes:   map[string]*route{},
+		owners:   map[string]string{},
+		done:     make(chan struct{}),
+	}
+}
+
+// UseACME serves HTTPS with certificates for domains from Let's Encrypt,
+// kept in cacheDir. It replaces certFile and keyFile.
+func (s *Server) UseACME(domains []string, email, cacheDir string) {
+	s.acme = &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Email:      strings.TrimSpace(email),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+}
+
+// claim reserves paths for a channel. They stay reserved after it detaches,
+// so that requests for it get 503 rather than 404 while it restarts.
+func (s *Server) claim(name string, paths ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range paths {
+		if owner, ok := s.owners[p]; ok && owner != name {
+			return fmt.Errorf("webhooks: path %s is used by both %s and %s", p, owner, name)
+		}
+	}
+	for _, p := range paths {
+		s.owners[p] = name
+	}
+	return nil
+}
+
+// Start listens, then serves in the background until ctx is done.
+func (s *Server) Start(ctx context.Context) error {
+	if (s.certFile == "") != (s.keyFile == "") {
+		return errors.New("webhooks: set both tlsCert and tlsKey, or neither")
+	}
+	if s.acme != nil && s.certFile != "" {
+		return errors.New("webhooks: set either acme or tlsCert and tlsKey, not both")
+	}
+	ln, err := handover.Listen(s.listen)
+	if err != nil {
+		return fmt.Errorf("webhooks: %w", err)
+	}
+	go func() {
+		s.err = s.serve(ctx, ln)
+		if s.err != nil {
+			log.Printf("webhooks: %v", s.err)
+		}
+		close(s.done)
+	}()
+	return nil
+}
+
+func (s *Server) serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		// No WriteTimeout: a voice call's webhook waits for the agent's
+		// reply, which has a timeout of its own.
+		IdleTimeout: 60 * time.Second,
+	}
+	if s.acme != nil {
+		srv.TLSConfig = s.acme.TLSConfig()
+	}
+	scheme := "http"
+	if s.certFile != "" || s.acme != nil {
+		scheme = "https"
+	}
+	log.Printf("webhooks: serving on %s://%s", scheme, s.listen)
+
+	errCh := make(chan error, 1)
+	go func() {
+		switch {
+		case s.acme != nil:
+			errCh <- srv.ServeTLS(ln, "", "")
+		case s.certFile != "":
+			errCh <- srv.ServeTLS(ln, s.certFile, s.keyFile)
+		default:
+			errCh <- srv.Serve(ln)
+		}
+	}()
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+		return nil
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+// Attach routes paths to h until ctx is done, Detach is called or the
+// server stops. A channel's Start calls it in place of serving its own
+// listener.
+func (s *Server) Attach(ctx context.Context, name string, h http.Handler, paths ...string) error {
+	if err := s.claim(name, paths...); err != nil {
+		return err
+	}
+	r := &route{handler: h, paths: paths, detach: make(chan struct{})}
+	s.mu.Lock()
+	if _, ok := s.routes[name]; ok {
+		s.mu.Unlock()
+		return fmt.Errorf("webhooks: %s is already attached", name)
+	}
+	s.routes[name] = r
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if s.routes[name] == r {
+			delete(s.routes, name)
+		}
+		s.mu.Unlock()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.detach:
+		return nil
+	case <-s.done:
+		if s.err != nil {
+			return fmt.Errorf("webhooks: %w", s.err)
+		}
+		return errors.New("webhooks: server stopped")
+	}
+}
+
+// Detach ends the Attach of a channel, as its Stop does.
+func (s *Server) Detach(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.routes[name]; ok {
+		delete(s.routes, name)
+		close(r.detach)
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	owner, ok := s.owners[r.URL.Path]
+	var h http.Handler
+	if rt := s.routes[owner]; ok && rt != nil && slices.Contains(rt.paths, r.URL.Path) {
+		h = rt.handler
+	}
+	s.mu.RUnlock()
+	switch {
+	case !ok:
+		http.NotFound(w, r)
+	case h == nil:
+		http.Error(w, owner+" is not running", http.StatusServiceUnavailable)
+	default:
+		h.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,128 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitAttached(t *testing.T, s *Server, name string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.RLock()
+		_, ok := s.routes[name]
+		s.mu.RUnlock()
+		if ok {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("%s did not attach", name)
+}
+
+func get(s *Server, path string) (int, string) {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+	body, _ := io.ReadAll(rec.Body)
+	return rec.Code, string(body)
+}
+
+func TestServerRoutesAttachedChannels(t *testing.T) {
+	s := New("127.0.0.1:0", "", "")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	voice := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, "voice "+r.URL.Path) })
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Attach(ctx, "voice", voice, "/voice", "/voice/gather") }()
+	waitAttached(t, s, "voice")
+
+	if code, body := get(s, "/voice/gather"); code != http.StatusOK || body != "voice /voice/gather" {
+		t.Fatalf("got %d %q", code, body)
+	}
+	if code, _ := get(s, "/slack/events"); code != http.StatusNotFound {
+		t.Fatalf("unknown path: got %d, want 404", code)
+	}
+
+	s.Detach("voice")
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Attach after Detach: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Attach did not return after Detach")
+	}
+	code, body := get(s, "/voice")
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, "voice is not running") {
+		t.Fatalf("detached channel: got %d %q", code, body)
+	}
+
+	// The channel can attach again after a restart.
+	go func() { errCh <- s.Attach(ctx, "voice", voice, "/voice", "/voice/gather") }()
+	waitAttached(t, s, "voice")
+	if code, _ := get(s, "/voice"); code != http.StatusOK {
+		t.Fatalf("reattached: got %d", code)
+	}
+	cancel()
+	if err := <-errCh; err == nil {
+		t.Fatal("Attach should return the context's error")
+	}
+}
+
+func TestServerRejectsSharedPaths(t *testing.T) {
+	s := New("127.0.0.1:0", "", "")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = s.Attach(ctx, "a", http.NotFoundHandler(), "/hook") }()
+	waitAttached(t, s, "a")
+	err := s.Attach(ctx, "b", http.NotFoundHandler(), "/other", "/hook")
+	if err == nil || !strings.Contains(err.Error(), "/hook is used by both a and b") {
+		t.Fatalf("got %v", err)
+	}
+	if code, _ := get(s, "/other"); code != http.StatusNotFound {
+		t.Fatalf("a rejected attach must not claim paths: got %d", code)
+	}
+	if err := s.Attach(ctx, "a", http.NotFoundHandler(), "/hook"); err == nil {
+		t.Fatal("attaching a running channel twice should fail")
+	}
+}
+
+func TestServerServesAndStops(t *testing.T) {
+	s := New("127.0.0.1:0", "", "")
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := s.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Attach(context.Background(), "x", http.NotFoundHandler(), "/x") }()
+	cancel()
+	select {
+	case err := <-errCh:
+		if err == nil || !strings.Contains(err.Error(), "server stopped") {
+			t.Fatalf("got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Attach did not return when the server stopped")
+	}
+}
+
+func TestServerNeedsBothTLSFiles(t *testing.T) {
+	s := New("127.0.0.1:0", "cert.pem", "")
+	if err := s.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "tlsKey") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestServerACMEExcludesCertFiles(t *testing.T) {
+	s := New("127.0.0.1:0", "cert.pem", "key.pem")
+	s.UseACME([]string{"bot.example.com"}, "", t.TempDir())
+	if err := s.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "acme") {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,407 @@
+package instagram
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/channels/httpserver"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/handover"
+)
+
+const (
+	webhookPath = "/instagram/webhook"
+
+	maxBodyBytes = 1 << 20
+	// maxTextRunes is the Send API limit for one text message.
+	maxTextRunes = 1000
+	// Quick replies: at most 13, titles up to 20 characters.
+	maxQuickReplies    = 13
+	maxQuickReplyRunes = 20
+)
+
+// Channel answers Instagram Direct messages for a professional account.
+// Inbound messages arrive as Meta webhooks signed with the app secret;
+// replies go out through the Graph API Send API.
+type Channel struct {
+	cfg   config.InstagramConfig
+	bus   *bus.Bus
+	allow channels.AllowList
+	hc    *http.Client
+
+	running atomic.Bool
+	srv     atomic.Pointer[http.Server]
+	// server, when set, is the shared webhook server used in place of
+	// Listen.
+	server *httpserver.Server
+	// seen drops webhook events Meta delivers again.
+	seen atomic.Pointer[channels.Deduper]
+}
+
+func New(cfg config.InstagramConfig, b *bus.Bus) *Channel {
+	c := &Channel{
+		cfg:   cfg,
+		bus:   b,
+		allow: channels.AllowList{AllowFrom: cfg.AllowFrom},
+		hc:    &http.Client{Timeout: 30 * time.Second},
+	}
+	c.seen.Store(channels.NewDeduper(0, ""))
+	return c
+}
+
+func (c *Channel) SetDeduper(d *channels.Deduper) { c.seen.Store(d) }
+
+func (c *Channel) Name() string    { return "instagram" }
+func (c *Channel) IsRunning() bool { return c.running.Load() }
+
+// SetServer serves the webhook on s instead of on Listen.
+func (c *Channel) SetServer(s *httpserver.Server) { c.server = s }
+
+func (c *Channel) Start(ctx context.Context) error {
+	if strings.TrimSpace(c.cfg.AccessToken) == "" {
+		return errors.New("instagram accessToken is empty")
+	}
+	if strings.TrimSpace(c.cfg.AppSecret) == "" {
+		return errors.New("instagram appSecret is empty")
+	}
+	if c.server != nil {
+		c.running.Store(true)
+		defer c.running.Store(false)
+		return c.server.Attach(ctx, c.Name(), c.Handler(ctx), webhookPath)
+	}
+	ln, err := handover.Listen(c.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           c.Handler(ctx),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+	c.srv.Store(srv)
+
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+		return ctx.Err()
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+func (c *Channel) Stop() error {
+	if c.server != nil {
+		c.server.Detach(c.Name())
+		return nil
+	}
+	srv := c.srv.Swap(nil)
+	if srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// Send replies to the Instagram-scoped user ID in ChatID. Long replies are
+// split into several messages; suggestions become quick replies on the last.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	recipient := strings.TrimSpace(msg.ChatID)
+	if recipient == "" {
+		return fmt.Errorf("chat_id is empty")
+	}
+	parts := channels.SplitterOrDefault(c.cfg.Split)(msg.Content, maxTextRunes)
+	for i, text := range parts {
+		m := sendMessage{Text: text}
+		if i == len(parts)-1 {
+			m.QuickReplies = quickReplies(msg.Suggestions)
+		}
+		body := sendRequest{Recipient: sendRecipient{ID: recipient}, Message: m}
+		if err := c.post(ctx, "/"+c.accountID()+"/messages", body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the webhook: the GET subscription handshake and signed POST
+// deliveries. Events are handled with ctx, not the request context.
+func (c *Channel) Handler(ctx context.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+webhookPath, c.handleVerify)
+	mux.HandleFunc("POST "+webhookPath, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if !ValidSignature(c.cfg.AppSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		var p webhookPayload
+		if err := json.Unmarshal(body, &p); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// Meta retries deliveries that are not acknowledged quickly.
+		w.WriteHeader(http.StatusOK)
+		go c.handlePayload(ctx, p)
+	})
+	return mux
+}
+
+// handleVerify answers the hub.challenge handshake Meta sends when the
+// webhook is subscribed.
+func (c *Channel) handleVerify(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	token := strings.TrimSpace(c.cfg.VerifyToken)
+	if q.Get("hub.mode") != "subscribe" || token == "" ||
+		!hmac.Equal([]byte(q.Get("hub.verify_token")), []byte(token)) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = io.WriteString(w, q.Get("hub.challenge"))
+}
+
+// ValidSignature checks X-Hub-Signature-256: "sha256=" + hex(HMAC-SHA256(app
+// secret, raw body)).
+func ValidSignature(appSecret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(strings.TrimSpace(header), "sha256=")
+	if appSecret == "" || !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+type webhookPayload struct {
+	Object string `json:"object"`
+	Entry  []struct {
+		ID        string           `json:"id"`
+		Messaging []messagingEvent `json:"messaging"`
+	} `json:"entry"`
+}
+
+type messagingEvent struct {
+	Sender struct {
+		ID string `json:"id"`
+	} `json:"sender"`
+	Message *struct {
+		MID         string `json:"mid"`
+		Text        string `json:"text"`
+		IsEcho      bool   `json:"is_echo"`
+		IsDeleted   bool   `json:"is_deleted"`
+		Attachments []struct {
+			Type    string `json:"type"`
+			Payload struct {
+				URL string `json:"url"`
+			} `json:"payload"`
+		} `json:"attachments"`
+		QuickReply *struct {
+			Payload string `json:"payload"`
+		} `json:"quick_reply"`
+	} `json:"message"`
+	Postback *struct {
+		MID     string `json:"mid"`
+		Title   string `json:"title"`
+		Payload string `json:"payload"`
+	} `json:"postback"`
+}
+
+func (c *Channel) handlePayload(ctx context.Context, p webhookPayload) {
+	if p.Object != "instagram" {
+		return
+	}
+	for _, e := range p.Entry {
+		for _, ev := range e.Messaging {
+			c.handleEvent(ctx, ev)
+		}
+	}
+}
+
+func (c *Channel) handleEvent(ctx context.Context, ev messagingEvent) {
+	senderID := strings.TrimSpace(ev.Sender.ID)
+	if senderID == "" || senderID == strings.TrimSpace(c.cfg.AccountID) {
+		return
+	}
+	var content, mid string
+	var attachments []bus.Attachment
+	switch {
+	case ev.Message != nil:
+		m := ev.Message
+		if m.IsEcho || m.IsDeleted {
+			return
+		}
+		mid = m.MID
+		content = strings.TrimSpace(m.Text)
+		if m.QuickReply != nil && strings.TrimSpace(m.QuickReply.Payload) != "" {
+			content = strings.TrimSpace(m.QuickReply.Payload)
+		}
+		for i, a := range m.Attachments {
+			if strings.TrimSpace(a.Payload.URL) == "" {
+				continue
+			}
+			attachments = append(attachments, bus.Attachment{
+				ID:   fmt.Sprintf("%s-%d", m.MID, i),
+				Kind: attachmentKind(a.Type),
+				URL:  a.Payload.URL,
+			})
+		}
+	case ev.Postback != nil:
+		mid = ev.Postback.MID
+		content = strings.TrimSpace(ev.Postback.Payload)
+		if content == "" {
+			content = strings.TrimSpace(ev.Postback.Title)
+		}
+	default:
+		return
+	}
+	if content == "" && len(attachments) == 0 {
+		return
+	}
+	if !c.allow.Allowed(senderID) {
+		log.Printf("instagram: ignored message from %s (not in allowFrom)", senderID)
+		return
+	}
+	if c.seen.Load().Seen("instagram", mid) {
+		return
+	}
+	pctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_ = c.bus.PublishInbound(pctx, bus.InboundMessage{
+		Channel:     "instagram",
+		SenderID:    senderID,
+		ChatID:      senderID,
+		Content:     content,
+		Attachments: attachments,
+		SessionKey:  "instagram:" + senderID,
+		Delivery:    bus.Delivery{MessageID: mid, IsDirect: true},
+	})
+}
+
+// attachmentKind maps Instagram attachment types onto bus kinds.
+func attachmentKind(t string) string {
+	switch t {
+	case "image", "audio", "video":
+		return t
+	default:
+		return "file"
+	}
+}
+
+type sendRequest struct {
+	Recipient sendRecipient `json:"recipient"`
+	Message   sendMessage   `json:"message"`
+}
+
+type sendRecipient struct {
+	ID string `json:"id"`
+}
+
+type sendMessage struct {
+	Text         string       `json:"text"`
+	QuickReplies []quickReply `json:"quick_replies,omitempty"`
+}
+
+type quickReply struct {
+	ContentType string `json:"content_type"`
+	Title       string `json:"title"`
+	Payload     string `json:"payload"`
+}
+
+// quickReplies turns suggestions into quick replies. Titles are shortened to
+// the platform limit; the payload keeps the full text and is what comes back.
+func quickReplies(items []string) []quickReply {
+	var out []quickReply
+	for _, it := range items {
+		it = strings.TrimSpace(it)
+		if it == "" {
+			continue
+		}
+		title := it
+		if r := []rune(title); len(r) > maxQuickReplyRunes {
+			title = string(r[:maxQuickReplyRunes-1]) + "…"
+		}
+		out = append(out, quickReply{ContentType: "text", Title: title, Payload: it})
+		if len(out) == maxQuickReplies {
+			break
+		}
+	}
+	return out
+}
+
+func (c *Channel) accountID() string {
+	if id := strings.TrimSpace(c.cfg.AccountID); id != "" {
+		return id
+	}
+	return "me"
+}
+
+func (c *Channel) post(ctx context.Context, p string, body any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	base := strings.TrimRight(strings.TrimSpace(c.cfg.APIBaseURL), "/")
+	if base == "" {
+		base = config.DefaultInstagramAPIBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+p, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(c.cfg.AccessToken))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var gerr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(raw, &gerr)
+		if gerr.Error.Message != "" {
+			return fmt.Errorf("instagram http %d: %s", resp.StatusCode, gerr.Error.Message)
+		}
+		return fmt.Errorf("instagram http %d", resp.StatusCode)
+	}
+	return nil
+}
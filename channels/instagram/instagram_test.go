@@ -0,0 +1,124 @@
+package instagram
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhook_VerifyAndDeliver(t *testing.T) {
+	b := bus.New(4)
+	c := New(config.InstagramConfig{AppSecret: "s3cret", VerifyToken: "vt", AccountID: "100", AllowFrom: []string{"200"}}, b)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv := httptest.NewServer(c.Handler(ctx))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + webhookPath + "?hub.mode=subscribe&hub.verify_token=vt&hub.challenge=42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(got) != "42" {
+		t.Fatalf("verify: %d %q", resp.StatusCode, got)
+	}
+
+	body := `{"object":"instagram","entry":[{"id":"100","messaging":[
+		{"sender":{"id":"100"},"message":{"mid":"m0","text":"echo","is_echo":true}},
+		{"sender":{"id":"300"},"message":{"mid":"m1","text":"not allowed"}},
+		{"sender":{"id":"200"},"message":{"mid":"m2","text":"hi","attachments":[{"type":"image","payload":{"url":"https://cdn.example/x.jpg"}}]}}
+	]}]}`
+	post := func(sig string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+webhookPath, strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", sig)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := post(sign("wrong", body)); code != http.StatusForbidden {
+		t.Fatalf("bad signature: %d", code)
+	}
+	if code := post(sign("s3cret", body)); code != http.StatusOK {
+		t.Fatalf("delivery: %d", code)
+	}
+	msg, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.SenderID != "200" || msg.SessionKey != "instagram:200" || msg.Content != "hi" || msg.Delivery.MessageID != "m2" {
+		t.Fatalf("inbound=%+v", msg)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Kind != "image" {
+		t.Fatalf("attachments=%+v", msg.Attachments)
+	}
+}
+
+func TestHandleEvent_DropsRedelivery(t *testing.T) {
+	b := bus.New(4)
+	c := New(config.InstagramConfig{AccountID: "100"}, b)
+	ctx := t.Context()
+	ev := func(mid string) messagingEvent {
+		var e messagingEvent
+		_ = json.Unmarshal([]byte(`{"sender":{"id":"200"},"message":{"mid":"`+mid+`","text":"hi"}}`), &e)
+		return e
+	}
+	c.handleEvent(ctx, ev("m1"))
+	c.handleEvent(ctx, ev("m1")) // Meta retried
+	c.handleEvent(ctx, ev("m2"))
+	for _, want := range []string{"m1", "m2"} {
+		msg, err := b.ConsumeInbound(ctx)
+		if err != nil || msg.Delivery.MessageID != want {
+			t.Fatalf("got %+v, %v; want %s", msg.Delivery, err, want)
+		}
+	}
+}
+
+func TestSend_SplitsAndAddsQuickReplies(t *testing.T) {
+	var reqs []sendRequest
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/100/messages" || r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, `{"error":{"message":"bad"}}`, http.StatusBadRequest)
+			return
+		}
+		var req sendRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		reqs = append(reqs, req)
+		_, _ = w.Write([]byte(`{"recipient_id":"200","message_id":"x"}`))
+	}))
+	defer api.Close()
+
+	c := New(config.InstagramConfig{AccessToken: "tok", AccountID: "100", APIBaseURL: api.URL}, bus.New(1))
+	long := strings.Repeat("word ", 300)
+	err := c.Send(context.Background(), bus.OutboundMessage{ChatID: "200", Content: long, Suggestions: []string{"Tell me a much longer story please"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 2 || reqs[0].Recipient.ID != "200" || len(reqs[0].Message.QuickReplies) != 0 {
+		t.Fatalf("reqs=%+v", reqs)
+	}
+	qr := reqs[1].Message.QuickReplies
+	if len(qr) != 1 || len([]rune(qr[0].Title)) != maxQuickReplyRunes || qr[0].Payload != "Tell me a much longer story please" {
+		t.Fatalf("quick replies=%+v", qr)
+	}
+}
@@ -5,19 +5,63 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/chaos"
 )
 
+// restartWait bounds how long Restart waits for a stopped channel's Start
+// to return.
+const restartWait = 10 * time.Second
+
+// outboundWorkers bounds the outbound sends in progress at once.
+const outboundWorkers = 8
+
+// maxQueuedOutbound bounds the outbound messages taken off the bus and not
+// yet sent; beyond it, dispatchOutbound waits before consuming more.
+const maxQueuedOutbound = 256
+
 type Manager struct {
 	bus      *bus.Bus
 	channels map[string]Channel
 
 	mu                 sync.RWMutex
+	ctx                context.Context
 	running            bool
 	stopOnce           sync.Once
 	lastErrorByChannel map[string]string
+	limiter            *RateLimiter
+	deduper            *Deduper
+	chaos              *chaos.Injector
+
+	// Supervision of each channel; see supervise.go. runs tells a channel's
+	// current supervisor from one replaced by Restart; starts is closed when
+	// the channel's latest Start returns.
+	runs                   map[string]int
+	starts                 map[string]chan struct{}
+	restarts               map[string]*restartState
+	backoffMin, backoffMax time.Duration
+	restartWait            time.Duration
+	restartMu              sync.Mutex // one Restart at a time
+
+	// Outbound messages waiting per chat; a chat has a key while a worker
+	// delivers its messages. See dispatchOutbound.
+	queueMu   sync.Mutex
+	queues    map[string][]bus.OutboundMessage
+	sendSlots chan struct{}
+	queued    chan struct{} // one per message in queues or being sent
+
+	// The outbound sends in progress; see SendInFlight.
+	sendMu   sync.Mutex
+	inFlight map[*sendRun]struct{}
+
+	// Typing indicators kept up per channel and chat; see typing.go.
+	typingMu sync.Mutex
+	typing   map[string]*typingRun
 }
 
 func NewManager(b *bus.Bus) *Manager {
@@ -25,9 +69,36 @@ func NewManager(b *bus.Bus) *Manager {
 		bus:                b,
 		channels:           map[string]Channel{},
 		lastErrorByChannel: map[string]string{},
+		typing:             map[string]*typingRun{},
+		queues:             map[string][]bus.OutboundMessage{},
+		sendSlots:          make(chan struct{}, outboundWorkers),
+		queued:             make(chan struct{}, maxQueuedOutbound),
+		inFlight:           map[*sendRun]struct{}{},
+		runs:               map[string]int{},
+		starts:             map[string]chan struct{}{},
+		restarts:           map[string]*restartState{},
+		backoffMin:         restartBackoffMin,
+		backoffMax:         restartBackoffMax,
+		restartWait:        restartWait,
 	}
 }
 
+// SetRateLimits spaces outbound messages per channel name; call it before
+// StartAll.
+func (m *Manager) SetRateLimits(limits map[string]RateLimit) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limiter = NewRateLimiter(limits)
+}
+
+// SetChaos fails some outbound sends on purpose; see package chaos. Call it
+// before StartAll.
+func (m *Manager) SetChaos(inj *chaos.Injector) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chaos = inj
+}
+
 func (m *Manager) Add(ch Channel) {
 	if ch == nil {
 		return
@@ -35,6 +106,22 @@ func (m *Manager) Add(ch Channel) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.channels[ch.Name()] = ch
+	if r, ok := ch.(Redelivering); ok && m.deduper != nil {
+		r.SetDeduper(m.deduper)
+	}
+}
+
+// SetDeduper hands d to the channels that may receive a message twice, now
+// and as they are added.
+func (m *Manager) SetDeduper(d *Deduper) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deduper = d
+	for _, ch := range m.channels {
+		if r, ok := ch.(Redelivering); ok {
+			r.SetDeduper(d)
+		}
+	}
 }
 
 func (m *Manager) StartAll(ctx context.Context) error {
@@ -44,6 +131,7 @@ func (m *Manager) StartAll(ctx context.Context) error {
 		return nil
 	}
 	m.running = true
+	m.ctx = ctx
 
 	chs := make([]Channel, 0, len(m.channels))
 	for _, ch := range m.channels {
@@ -56,20 +144,91 @@ func (m *Manager) StartAll(ctx context.Context) error {
 
 	// Start channels
 	for _, ch := range chs {
-		m.setChannelError(ch.Name(), "")
-		go func() {
-			err := ch.Start(ctx)
-			// Context cancellation on shutdown is expected.
-			if err == nil || errors.Is(err, context.Canceled) {
-				return
-			}
-			m.setChannelError(ch.Name(), err.Error())
-			log.Printf("channels: %s stopped with error: %v", ch.Name(), err)
-		}()
+		m.start(ctx, ch)
+	}
+	return nil
+}
+
+// Restart stops a channel, waits for its Start to return and starts it
+// again. It is used to recover a wedged channel; a channel that does not
+// stop within restartWait is left as it is and an error returned.
+func (m *Manager) Restart(name string) error {
+	m.restartMu.Lock()
+	defer m.restartMu.Unlock()
+	m.mu.RLock()
+	ch := m.channels[name]
+	ctx := m.ctx
+	running := m.running
+	started := m.starts[name]
+	m.mu.RUnlock()
+	if ch == nil {
+		return fmt.Errorf("channel not found: %s", name)
+	}
+	if !running || ctx == nil {
+		return fmt.Errorf("channels are not running")
+	}
+	m.CancelSend(name)
+	if err := ch.Stop(); err != nil {
+		log.Printf("channels: failed to stop %s for restart: %v", name, err)
 	}
+	if started != nil {
+		t := time.NewTimer(m.restartWait)
+		defer t.Stop()
+		select {
+		case <-started:
+		case <-t.C:
+			// Starting it again would leave two runs of the channel.
+			return fmt.Errorf("channel %s did not stop within %s", name, m.restartWait)
+		}
+	}
+	m.start(ctx, ch)
 	return nil
 }
 
+// Names lists the registered channels.
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]string, 0, len(m.channels))
+	for name := range m.channels {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// sendRun is an outbound Send in progress.
+type sendRun struct {
+	channel string
+	since   time.Time
+	cancel  context.CancelFunc
+}
+
+// SendInFlight reports the channel of the longest-running Send, and since
+// when. Its chat's replies queue behind it, and it holds one of the
+// outbound workers.
+func (m *Manager) SendInFlight() (channel string, since time.Time, ok bool) {
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+	for run := range m.inFlight {
+		if !ok || run.since.Before(since) {
+			channel, since, ok = run.channel, run.since, true
+		}
+	}
+	return channel, since, ok
+}
+
+// CancelSend cancels the Sends in flight that go to channel.
+func (m *Manager) CancelSend(channel string) {
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+	for run := range m.inFlight {
+		if run.channel == channel {
+			run.cancel()
+		}
+	}
+}
+
 func (m *Manager) StopAll() error {
 	m.stopOnce.Do(func() {
 		m.mu.Lock()
@@ -90,40 +249,207 @@ func (m *Manager) StopAll() error {
 	return nil
 }
 
+// Status describes each channel by name, with the outbound messages queued
+// for it.
 func (m *Manager) Status() map[string]map[string]any {
 	out := map[string]map[string]any{}
+	queued := map[string]int{}
+	m.queueMu.Lock()
+	for _, q := range m.queues {
+		for _, msg := range q {
+			queued[msg.Channel]++
+		}
+	}
+	m.queueMu.Unlock()
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	for name, ch := range m.channels {
 		row := map[string]any{
 			"running": ch.IsRunning(),
+			"queued":  queued[name],
 		}
 		if last, ok := m.lastErrorByChannel[name]; ok && last != "" {
 			row["lastError"] = last
 		}
+		row["restarts"] = 0
+		if st := m.restarts[name]; st != nil {
+			row["restarts"] = st.count
+			if !st.last.IsZero() {
+				row["lastRestart"] = st.last
+			}
+			if !st.next.IsZero() {
+				row["restartAt"] = st.next
+			}
+		}
 		out[name] = row
 	}
 	return out
 }
 
+// BusStats reports the queues of the bus the channels run on; ok is false
+// without one.
+func (m *Manager) BusStats() (st bus.Stats, ok bool) {
+	if m.bus == nil {
+		return bus.Stats{}, false
+	}
+	return m.bus.Stats(), true
+}
+
+// dispatchOutbound queues outbound messages per chat. Each chat with
+// messages waiting gets a worker that delivers them in order, so a slow
+// chat or channel holds up only its own replies; at most outboundWorkers
+// Sends run at once. A message is acknowledged on the bus only once it has
+// been sent, and at most maxQueuedOutbound are taken off the bus unsent.
 func (m *Manager) dispatchOutbound(ctx context.Context) {
 	for {
+		select {
+		case m.queued <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
 		msg, err := m.bus.ConsumeOutbound(ctx)
 		if err != nil {
+			<-m.queued
 			return
 		}
-		m.mu.RLock()
-		ch := m.channels[msg.Channel]
-		m.mu.RUnlock()
-		if ch == nil {
-			// Unknown channel; drop.
-			continue
+		key := typingKey(msg.Channel, msg.ChatID)
+		m.queueMu.Lock()
+		q, busy := m.queues[key]
+		m.queues[key] = append(q, msg)
+		m.queueMu.Unlock()
+		if !busy {
+			go m.drain(ctx, key)
 		}
-		if err := ch.Send(ctx, msg); err != nil && !errors.Is(err, context.Canceled) {
-			m.setChannelError(msg.Channel, err.Error())
-			log.Printf("channels: outbound send failed via %s: %v", msg.Channel, err)
+	}
+}
+
+// drain delivers the messages queued for one chat until there are none.
+// Messages left when ctx ends stay unacknowledged, for the broker to
+// redeliver.
+func (m *Manager) drain(ctx context.Context, key string) {
+	for {
+		m.queueMu.Lock()
+		q := m.queues[key]
+		if len(q) == 0 || ctx.Err() != nil {
+			delete(m.queues, key)
+			m.queueMu.Unlock()
+			for range q {
+				<-m.queued
+			}
+			return
 		}
+		msg := q[0]
+		q[0] = bus.OutboundMessage{}
+		m.queues[key] = q[1:]
+		m.queueMu.Unlock()
+		m.deliver(ctx, msg)
+		if ctx.Err() == nil {
+			if err := m.bus.AckOutbound(ctx, msg); err != nil {
+				log.Printf("channels: outbound ack via %s failed: %v", msg.Channel, err)
+			}
+		}
+		<-m.queued
+	}
+}
+
+func (m *Manager) deliver(ctx context.Context, msg bus.OutboundMessage) {
+	m.mu.RLock()
+	ch := m.channels[msg.Channel]
+	limiter := m.limiter
+	inj := m.chaos
+	m.mu.RUnlock()
+	if ch == nil {
+		// Unknown channel; drop.
+		return
+	}
+	if msg.Kind == bus.MessageKindWorking {
+		m.working(ctx, ch, msg)
+		return
+	}
+	if !msg.Partial {
+		// The reply is here; a late indicator would outlive it.
+		m.stopTyping(msg.Channel, msg.ChatID)
+	}
+	if msg.Partial {
+		// A later update carries the same text; skip rather than wait.
+		if !supportsStreaming(ch) || !limiter.Allow(msg.Channel, msg.ChatID) {
+			return
+		}
+	} else if err := limiter.Wait(ctx, msg.Channel, msg.ChatID); err != nil {
+		return
+	}
+	// A chat waiting on its rate limit does not hold a worker.
+	select {
+	case m.sendSlots <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	defer func() { <-m.sendSlots }()
+	if err := inj.Send(ctx, func() error { return m.send(ctx, ch, msg) }); err != nil && !errors.Is(err, context.Canceled) {
+		m.setChannelError(msg.Channel, err.Error())
+		log.Printf("channels: outbound send failed via %s: %v", msg.Channel, err)
+	}
+}
+
+func (m *Manager) send(ctx context.Context, ch Channel, msg bus.OutboundMessage) error {
+	sctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	run := &sendRun{channel: msg.Channel, since: time.Now(), cancel: cancel}
+	m.sendMu.Lock()
+	m.inFlight[run] = struct{}{}
+	m.sendMu.Unlock()
+	defer func() {
+		m.sendMu.Lock()
+		delete(m.inFlight, run)
+		m.sendMu.Unlock()
+	}()
+	if msg.Partial && !supportsStreaming(ch) {
+		return nil
+	}
+	if msg.Kind == bus.MessageKindReaction && !supportsReactions(ch) {
+		return nil
+	}
+	if msg.Kind == bus.MessageKindPoll && !supportsPolls(ch) {
+		msg.Kind, msg.Content = bus.MessageKindText, pollText(msg.Poll)
+	}
+	if len(msg.Cards) > 0 {
+		if cs, ok := ch.(CardSender); !ok || !cs.SupportsCards() {
+			msg.Content = strings.TrimSpace(msg.Content + "\n\n" + bus.CardText(msg.Cards))
+			msg.Cards = nil
+		}
+	}
+	if len(msg.Attachments) > 0 {
+		if as, ok := ch.(AttachmentSender); !ok || !as.SupportsAttachments() {
+			msg.Content = strings.TrimSpace(msg.Content + "\n\n" + bus.AttachmentNote(msg.Attachments))
+			msg.Attachments = nil
+		}
+	}
+	return ch.Send(sctx, msg)
+}
+
+func supportsStreaming(ch Channel) bool {
+	st, ok := ch.(Streamer)
+	return ok && st.SupportsStreaming()
+}
+
+func supportsReactions(ch Channel) bool {
+	r, ok := ch.(Reactor)
+	return ok && r.SupportsReactions()
+}
+
+func supportsPolls(ch Channel) bool {
+	p, ok := ch.(Poller)
+	return ok && p.SupportsPolls()
+}
+
+// pollText renders a poll for channels without native polls.
+func pollText(p bus.Poll) string {
+	var b strings.Builder
+	b.WriteString("📊 " + strings.TrimSpace(p.Question))
+	for i, opt := range p.Options {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, strings.TrimSpace(opt))
 	}
+	return b.String()
 }
 
 func (m *Manager) Require(name string) (Channel, error) {
@@ -3,6 +3,9 @@ package channels
 import (
 	"context"
 	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -13,7 +16,7 @@ type stubChannel struct {
 	name     string
 	startErr error
 	sendErr  error
-	running  bool
+	running  atomic.Bool
 }
 
 func (s *stubChannel) Name() string { return s.name }
@@ -22,14 +25,14 @@ func (s *stubChannel) Start(ctx context.Context) error {
 	if s.startErr != nil {
 		return s.startErr
 	}
-	s.running = true
+	s.running.Store(true)
 	<-ctx.Done()
-	s.running = false
+	s.running.Store(false)
 	return ctx.Err()
 }
 
 func (s *stubChannel) Stop() error {
-	s.running = false
+	s.running.Store(false)
 	return nil
 }
 
@@ -37,7 +40,7 @@ func (s *stubChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	return s.sendErr
 }
 
-func (s *stubChannel) IsRunning() bool { return s.running }
+func (s *stubChannel) IsRunning() bool { return s.running.Load() }
 
 func TestManagerStartAll_RecordsStartError(t *testing.T) {
 	b := bus.New(16)
@@ -83,6 +86,143 @@ func TestManagerDispatchOutbound_RecordsSendError(t *testing.T) {
 	})
 }
 
+// gatedChannel holds Sends to the chat "slow" until release is closed, and
+// records the order of delivered contents per chat.
+type gatedChannel struct {
+	stubChannel
+	release chan struct{}
+	mu      sync.Mutex
+	got     map[string][]string
+}
+
+func (g *gatedChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if msg.ChatID == "slow" {
+		select {
+		case <-g.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.got[msg.ChatID] = append(g.got[msg.ChatID], msg.Content)
+	return nil
+}
+
+func (g *gatedChannel) delivered(chatID string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.got[chatID])
+}
+
+func TestManagerDispatchOutbound_OrdersPerChat(t *testing.T) {
+	b := bus.New(64)
+	m := NewManager(b)
+	g := &gatedChannel{stubChannel: stubChannel{name: "gated"}, release: make(chan struct{}), got: map[string][]string{}}
+	m.Add(g)
+	ctx := t.Context()
+	if err := m.StartAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	publish := func(chatID, content string) {
+		if err := b.PublishOutbound(ctx, bus.OutboundMessage{Channel: "gated", ChatID: chatID, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []string{"1", "2", "3"} {
+		publish("slow", c)
+	}
+	publish("fast", "a")
+	publish("fast", "b")
+
+	// The blocked chat holds up only its own replies.
+	waitFor(t, time.Second, func() bool { return slices.Equal(g.delivered("fast"), []string{"a", "b"}) })
+	if got := g.delivered("slow"); len(got) != 0 {
+		t.Fatalf("slow chat delivered early: %q", got)
+	}
+	if ch, _, ok := m.SendInFlight(); !ok || ch != "gated" {
+		t.Fatalf("SendInFlight = %q, %v", ch, ok)
+	}
+	// "1" is being sent; "2" and "3" wait.
+	if st := m.Status(); st["gated"]["queued"] != 2 || st["bus"] != nil {
+		t.Fatalf("status = %+v", st)
+	}
+	if st, ok := m.BusStats(); !ok || st.Outbound.Published != 5 {
+		t.Fatalf("bus stats = %+v, %v", st, ok)
+	}
+
+	close(g.release)
+	waitFor(t, time.Second, func() bool { return slices.Equal(g.delivered("slow"), []string{"1", "2", "3"}) })
+	waitFor(t, time.Second, func() bool {
+		_, _, ok := m.SendInFlight()
+		return !ok
+	})
+}
+
+// ackBroker hands out the outbound messages published to it and records
+// which were acknowledged.
+type ackBroker struct {
+	out   chan bus.OutboundMessage
+	mu    sync.Mutex
+	acked []string
+}
+
+func (a *ackBroker) PublishInbound(context.Context, bus.InboundMessage) error { return nil }
+func (a *ackBroker) ConsumeInbound(ctx context.Context) (bus.InboundMessage, error) {
+	<-ctx.Done()
+	return bus.InboundMessage{}, ctx.Err()
+}
+func (a *ackBroker) PublishOutbound(_ context.Context, msg bus.OutboundMessage) error {
+	a.out <- msg
+	return nil
+}
+func (a *ackBroker) ConsumeOutbound(ctx context.Context) (bus.OutboundMessage, error) {
+	select {
+	case msg := <-a.out:
+		return msg, nil
+	case <-ctx.Done():
+		return bus.OutboundMessage{}, ctx.Err()
+	}
+}
+func (a *ackBroker) AckOutbound(_ context.Context, msg bus.OutboundMessage) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked = append(a.acked, msg.Content)
+	return nil
+}
+func (a *ackBroker) Close() error { return nil }
+
+func (a *ackBroker) ackedContents() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.acked)
+}
+
+func TestManagerDispatchOutbound_AcksAfterSend(t *testing.T) {
+	br := &ackBroker{out: make(chan bus.OutboundMessage, 8)}
+	b := bus.NewWithBroker(br)
+	m := NewManager(b)
+	g := &gatedChannel{stubChannel: stubChannel{name: "gated"}, release: make(chan struct{}), got: map[string][]string{}}
+	m.Add(g)
+	ctx := t.Context()
+	if err := m.StartAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []bus.OutboundMessage{
+		{Channel: "gated", ChatID: "slow", Content: "held"},
+		{Channel: "gated", ChatID: "fast", Content: "sent"},
+	} {
+		if err := b.PublishOutbound(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A reply still being sent is not acknowledged, though later ones are.
+	waitFor(t, time.Second, func() bool { return slices.Equal(br.ackedContents(), []string{"sent"}) })
+	close(g.release)
+	waitFor(t, time.Second, func() bool { return slices.Equal(br.ackedContents(), []string{"sent", "held"}) })
+}
+
 func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
 	t.Helper()
 	deadline := time.Now().Add(timeout)
@@ -94,3 +234,310 @@ func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
 	}
 	t.Fatal("condition not met in time")
 }
+
+// wedgedChannel blocks in Send until the send is canceled, and counts starts.
+type wedgedChannel struct {
+	starts  atomic.Int32
+	running atomic.Bool
+	stop    chan struct{}
+}
+
+func (w *wedgedChannel) Name() string { return "wedged" }
+
+func (w *wedgedChannel) Start(ctx context.Context) error {
+	w.starts.Add(1)
+	w.running.Store(true)
+	defer w.running.Store(false)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.stop:
+		return nil
+	}
+}
+
+func (w *wedgedChannel) Stop() error {
+	w.stop <- struct{}{}
+	return nil
+}
+
+func (w *wedgedChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (w *wedgedChannel) IsRunning() bool { return w.running.Load() }
+
+func TestManagerRestart_CancelsSendInFlight(t *testing.T) {
+	b := bus.New(16)
+	m := NewManager(b)
+	w := &wedgedChannel{stop: make(chan struct{})}
+	m.Add(w)
+
+	ctx := t.Context()
+	if err := m.StartAll(ctx); err != nil {
+		t.Fatalf("StartAll returned error: %v", err)
+	}
+	waitFor(t, time.Second, w.IsRunning)
+	if err := b.PublishOutbound(ctx, bus.OutboundMessage{Channel: "wedged", ChatID: "c1", Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, time.Second, func() bool {
+		ch, _, ok := m.SendInFlight()
+		return ok && ch == "wedged"
+	})
+
+	if err := m.Restart("wedged"); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	waitFor(t, time.Second, func() bool {
+		_, _, ok := m.SendInFlight()
+		return !ok && w.starts.Load() == 2 && w.IsRunning()
+	})
+	if err := m.Restart("missing"); err == nil {
+		t.Fatal("restarting an unknown channel should fail")
+	}
+}
+
+type recordingChannel struct {
+	stubChannel
+	native bool
+	got    []bus.OutboundMessage
+}
+
+func (r *recordingChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	r.got = append(r.got, msg)
+	return nil
+}
+
+func (r *recordingChannel) SupportsAttachments() bool { return r.native }
+
+func TestManagerSend_AttachmentFallback(t *testing.T) {
+	m := NewManager(bus.New(1))
+	msg := bus.OutboundMessage{
+		Content:     "report ready",
+		Attachments: []bus.Attachment{{Name: "report.pdf"}, {Name: "chart.png"}},
+	}
+
+	text := &recordingChannel{}
+	if err := m.send(context.Background(), text, msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := text.got[0]; got.Content != "report ready\n\n[attached: report.pdf, chart.png]" || got.Attachments != nil {
+		t.Fatalf("text-only channel got %+v", got)
+	}
+
+	native := &recordingChannel{native: true}
+	if err := m.send(context.Background(), native, msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := native.got[0]; got.Content != "report ready" || len(got.Attachments) != 2 {
+		t.Fatalf("native channel got %+v", got)
+	}
+}
+
+type streamingChannel struct {
+	recordingChannel
+}
+
+func (s *streamingChannel) SupportsStreaming() bool { return true }
+
+func TestManagerSend_DropsPartialsForNonStreamers(t *testing.T) {
+	m := NewManager(bus.New(1))
+	partial := bus.OutboundMessage{Content: "Hel", StreamID: "s1", Partial: true}
+	final := bus.OutboundMessage{Content: "Hello", StreamID: "s1"}
+
+	plain := &recordingChannel{}
+	streaming := &streamingChannel{}
+	for _, ch := range []Channel{plain, streaming} {
+		for _, msg := range []bus.OutboundMessage{partial, final} {
+			if err := m.send(context.Background(), ch, msg); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if len(plain.got) != 1 || plain.got[0].Content != "Hello" {
+		t.Fatalf("plain channel got %+v", plain.got)
+	}
+	if len(streaming.got) != 2 {
+		t.Fatalf("streaming channel got %+v", streaming.got)
+	}
+}
+
+type reactingChannel struct {
+	recordingChannel
+}
+
+func (r *reactingChannel) SupportsReactions() bool { return true }
+
+func TestManagerSend_DropsReactionsForNonReactors(t *testing.T) {
+	m := NewManager(bus.New(1))
+	react := bus.OutboundMessage{Kind: bus.MessageKindReaction, Reaction: bus.Reaction{Emoji: "👍", MessageID: "42"}}
+
+	plain := &recordingChannel{}
+	reacting := &reactingChannel{}
+	for _, ch := range []Channel{plain, reacting} {
+		if err := m.send(context.Background(), ch, react); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(plain.got) != 0 {
+		t.Fatalf("plain channel got %+v", plain.got)
+	}
+	if len(reacting.got) != 1 || reacting.got[0].Reaction.Emoji != "👍" {
+		t.Fatalf("reacting channel got %+v", reacting.got)
+	}
+}
+
+type pollingChannel struct {
+	recordingChannel
+}
+
+func (p *pollingChannel) SupportsPolls() bool { return true }
+
+func TestManagerSend_PollsAsTextForNonPollers(t *testing.T) {
+	m := NewManager(bus.New(1))
+	poll := bus.OutboundMessage{Kind: bus.MessageKindPoll, Poll: bus.Poll{Question: "Lunch?", Options: []string{"Pizza", "Sushi"}}}
+
+	plain := &recordingChannel{}
+	polling := &pollingChannel{}
+	for _, ch := range []Channel{plain, polling} {
+		if err := m.send(context.Background(), ch, poll); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(plain.got) != 1 || plain.got[0].Kind != bus.MessageKindText || plain.got[0].Content != "📊 Lunch?\n1. Pizza\n2. Sushi" {
+		t.Fatalf("plain channel got %+v", plain.got)
+	}
+	if len(polling.got) != 1 || polling.got[0].Kind != bus.MessageKindPoll {
+		t.Fatalf("polling channel got %+v", polling.got)
+	}
+}
+
+type cardChannel struct {
+	recordingChannel
+}
+
+func (c *cardChannel) SupportsCards() bool { return true }
+
+func TestManagerSend_CardsAsTextForOtherChannels(t *testing.T) {
+	m := NewManager(bus.New(1))
+	msg := bus.OutboundMessage{Content: "Found one:", Cards: []bus.Card{{
+		Title:  "Go 1.26",
+		URL:    "https://go.dev/doc/go1.26",
+		Fields: []bus.CardField{{Name: "Released", Value: "February"}},
+	}}}
+
+	plain := &recordingChannel{}
+	cards := &cardChannel{}
+	for _, ch := range []Channel{plain, cards} {
+		if err := m.send(context.Background(), ch, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := plain.got[0]; got.Content != "Found one:\n\n**Go 1.26**\nhttps://go.dev/doc/go1.26\nReleased: February" || got.Cards != nil {
+		t.Fatalf("plain channel got %+v", got)
+	}
+	if got := cards.got[0]; got.Content != "Found one:" || len(got.Cards) != 1 {
+		t.Fatalf("card channel got %+v", got)
+	}
+}
+
+// stopOnlyChannel returns from Start only when Stop is called, like a
+// channel with its own connection; ignoreStop makes it wedge instead.
+type stopOnlyChannel struct {
+	ignoreStop bool
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	starts  int
+	active  int
+	maxRuns int
+}
+
+func (s *stopOnlyChannel) Name() string { return "stoponly" }
+
+func (s *stopOnlyChannel) Start(ctx context.Context) error {
+	s.mu.Lock()
+	stop := make(chan struct{})
+	s.stop = stop
+	s.starts++
+	s.active++
+	s.maxRuns = max(s.maxRuns, s.active)
+	s.mu.Unlock()
+	<-stop
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *stopOnlyChannel) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil && !s.ignoreStop {
+		close(s.stop)
+		s.stop = nil
+	}
+	return nil
+}
+
+func (s *stopOnlyChannel) Send(ctx context.Context, msg bus.OutboundMessage) error { return nil }
+
+func (s *stopOnlyChannel) IsRunning() bool { return false }
+
+func (s *stopOnlyChannel) runs() (starts, active, maxRuns int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.starts, s.active, s.maxRuns
+}
+
+func TestManagerRestart_WaitsForStartToReturn(t *testing.T) {
+	m := NewManager(bus.New(1))
+	s := &stopOnlyChannel{}
+	m.Add(s)
+	if err := m.StartAll(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		waitFor(t, time.Second, func() bool {
+			starts, active, _ := s.runs()
+			return starts == i+1 && active == 1
+		})
+		if err := m.Restart("stoponly"); err != nil {
+			t.Fatalf("Restart: %v", err)
+		}
+	}
+	waitFor(t, time.Second, func() bool {
+		starts, active, _ := s.runs()
+		return starts == 3 && active == 1
+	})
+	if _, _, maxRuns := s.runs(); maxRuns != 1 {
+		t.Fatalf("%d runs at once", maxRuns)
+	}
+}
+
+func TestManagerRestart_FailsWhenStartDoesNotReturn(t *testing.T) {
+	m := NewManager(bus.New(1))
+	m.restartWait = 50 * time.Millisecond
+	s := &stopOnlyChannel{ignoreStop: true}
+	m.Add(s)
+	if err := m.StartAll(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, time.Second, func() bool {
+		starts, _, _ := s.runs()
+		return starts == 1
+	})
+	if err := m.Restart("stoponly"); err == nil {
+		t.Fatal("Restart should fail while the channel is still running")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if starts, _, _ := s.runs(); starts != 1 {
+		t.Fatalf("started %d times", starts)
+	}
+	s.mu.Lock()
+	s.ignoreStop = false
+	s.mu.Unlock()
+	_ = s.Stop()
+}
@@ -0,0 +1,519 @@
+package mastodon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/config"
+	"golang.org/x/net/html"
+)
+
+// streamIdleTimeout reconnects a stream that stopped delivering data; the
+// streaming server sends a heartbeat comment every few seconds.
+const streamIdleTimeout = 2 * time.Minute
+
+// Channel answers Mastodon mentions. Notifications arrive over the streaming
+// API; mentions missed while disconnected are fetched from the REST API.
+// Conversations are keyed by the mentioning account.
+type Channel struct {
+	cfg    config.MastodonConfig
+	bus    *bus.Bus
+	allow  channels.AllowList
+	hc     *http.Client
+	stream *http.Client
+	// host is the instance domain, added to local accounts ("alice" ->
+	// "alice@host") so sender IDs are the same everywhere.
+	host string
+
+	running atomic.Bool
+
+	mu     sync.Mutex
+	lastID string // newest mention handled
+	cancel context.CancelFunc
+}
+
+func New(cfg config.MastodonConfig, b *bus.Bus) *Channel {
+	allow := make([]string, 0, len(cfg.AllowFrom))
+	for _, v := range cfg.AllowFrom {
+		allow = append(allow, strings.TrimPrefix(strings.TrimSpace(v), "@"))
+	}
+	host := ""
+	if u, err := url.Parse(strings.TrimSpace(cfg.Server)); err == nil {
+		host = u.Hostname()
+	}
+	return &Channel{
+		cfg:    cfg,
+		bus:    b,
+		allow:  channels.AllowList{AllowFrom: allow},
+		hc:     &http.Client{Timeout: 30 * time.Second},
+		stream: &http.Client{},
+		host:   host,
+	}
+}
+
+func (c *Channel) Name() string    { return "mastodon" }
+func (c *Channel) IsRunning() bool { return c.running.Load() }
+
+func (c *Channel) Start(ctx context.Context) error {
+	if strings.TrimSpace(c.cfg.Server) == "" {
+		return fmt.Errorf("mastodon server is empty")
+	}
+	if strings.TrimSpace(c.cfg.AccessToken) == "" {
+		return fmt.Errorf("mastodon accessToken is empty")
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Only establish a position; older mentions are not replayed.
+	var latest []notification
+	if err := c.do(runCtx, http.MethodGet, "/api/v1/notifications?types[]=mention&limit=1", nil, &latest); err != nil {
+		return fmt.Errorf("mastodon notifications: %w", err)
+	}
+
+	c.mu.Lock()
+	if len(latest) > 0 {
+		c.lastID = latest[0].ID
+	}
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	failures := 0
+	for {
+		connected, err := c.streamOnce(runCtx)
+		if runCtx.Err() != nil {
+			return runCtx.Err()
+		}
+		if connected {
+			failures = 0
+		}
+		failures++
+		wait := streamBackoff(failures)
+		log.Printf("mastodon: stream ended, reconnect in %s: %v", wait, err)
+		t := time.NewTimer(wait)
+		select {
+		case <-runCtx.Done():
+			t.Stop()
+			return runCtx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (c *Channel) Stop() error {
+	c.running.Store(false)
+	c.mu.Lock()
+	cancel := c.cancel
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// Send replies to the status in msg.Delivery, mentioning the account in
+// ChatID. Text over maxChars is posted as a chain of replies.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	acct := strings.TrimPrefix(strings.TrimSpace(msg.ChatID), "@")
+	if acct == "" {
+		return fmt.Errorf("chat_id is empty")
+	}
+	text := strings.TrimSpace(msg.Content)
+	if text == "" {
+		return nil
+	}
+	replyTo := resolveMastodonReplyTarget(c.cfg.Quote, msg)
+	visibility := c.replyVisibility(ctx, replyTo)
+	prefix := "@" + acct + " "
+	for _, part := range channels.SplitterOrDefault(c.cfg.Split)(text, max(c.maxChars()-len([]rune(prefix)), 100)) {
+		var posted status
+		body := map[string]any{
+			"status":     prefix + part,
+			"visibility": visibility,
+		}
+		if replyTo != "" {
+			body["in_reply_to_id"] = replyTo
+		}
+		if err := c.do(ctx, http.MethodPost, "/api/v1/statuses", body, &posted); err != nil {
+			return err
+		}
+		replyTo = posted.ID
+	}
+	return nil
+}
+
+// streamOnce reads the user notification stream until it ends. Mentions
+// posted while the stream was down are fetched once it is connected.
+func (c *Channel) streamOnce(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("/api/v1/streaming/user/notification"), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(c.cfg.AccessToken))
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.stream.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("mastodon streaming http %d", resp.StatusCode)
+	}
+
+	if err := c.catchUp(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("mastodon: catch-up failed: %v", err)
+	}
+
+	idle := time.AfterFunc(streamIdleTimeout, cancel)
+	defer idle.Stop()
+	err = readEvents(idleReader{r: resp.Body, t: idle}, func(event, data string) {
+		if event != "notification" {
+			return
+		}
+		var n notification
+		if err := json.Unmarshal([]byte(data), &n); err != nil {
+			return
+		}
+		c.handleNotification(ctx, n)
+	})
+	return true, err
+}
+
+// catchUp handles mentions newer than the last one seen, oldest first.
+func (c *Channel) catchUp(ctx context.Context) error {
+	c.mu.Lock()
+	since := c.lastID
+	c.mu.Unlock()
+	p := "/api/v1/notifications?types[]=mention&limit=40"
+	if since != "" {
+		p += "&min_id=" + url.QueryEscape(since)
+	}
+	var missed []notification
+	if err := c.do(ctx, http.MethodGet, p, nil, &missed); err != nil {
+		return err
+	}
+	for i := len(missed) - 1; i >= 0; i-- {
+		c.handleNotification(ctx, missed[i])
+	}
+	return nil
+}
+
+func (c *Channel) handleNotification(ctx context.Context, n notification) {
+	if n.Type != "mention" || n.Status == nil || !c.advance(n.ID) {
+		return
+	}
+	sender := c.fullAcct(n.Account.Acct)
+	if sender == "" || !c.allow.Allowed(sender) {
+		return
+	}
+	text := stripLeadingMentions(statusText(n.Status.Content))
+	attachments := mediaAttachments(n.Status.MediaAttachments)
+	if text == "" && len(attachments) == 0 {
+		return
+	}
+	_ = c.bus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:     "mastodon",
+		SenderID:    sender,
+		ChatID:      sender,
+		Content:     text,
+		Attachments: attachments,
+		SessionKey:  "mastodon:" + sender,
+		Delivery: bus.Delivery{
+			MessageID: n.Status.ID,
+			ReplyToID: n.Status.ID,
+			IsDirect:  n.Status.Visibility == "direct",
+		},
+	})
+}
+
+// advance records id as handled, reporting false for mentions already seen
+// (the stream and catch-up can overlap).
+func (c *Channel) advance(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id == "" || (c.lastID != "" && !newerID(id, c.lastID)) {
+		return false
+	}
+	c.lastID = id
+	return true
+}
+
+func (c *Channel) fullAcct(acct string) string {
+	acct = strings.TrimPrefix(strings.TrimSpace(acct), "@")
+	if acct == "" || strings.Contains(acct, "@") || c.host == "" {
+		return acct
+	}
+	return acct + "@" + c.host
+}
+
+// replyVisibility is the configured visibility narrowed to that of the
+// status being answered. Without either, replies are direct.
+func (c *Channel) replyVisibility(ctx context.Context, statusID string) string {
+	v := c.cfg.Visibility
+	if statusID != "" {
+		var st status
+		if err := c.do(ctx, http.MethodGet, "/api/v1/statuses/"+url.PathEscape(statusID), nil, &st); err == nil {
+			v = narrowerVisibility(v, st.Visibility)
+		}
+	}
+	if visibilityRank(v) < 0 {
+		return "direct"
+	}
+	return v
+}
+
+func (c *Channel) maxChars() int {
+	if c.cfg.MaxChars <= 0 {
+		return config.DefaultMastodonMaxChars
+	}
+	return c.cfg.MaxChars
+}
+
+func (c *Channel) url(p string) string {
+	return strings.TrimRight(strings.TrimSpace(c.cfg.Server), "/") + p
+}
+
+func (c *Channel) do(ctx context.Context, method, p string, body any, out any) error {
+	var rdr io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rdr = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url(p), rdr)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(c.cfg.AccessToken))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var merr struct {
+			Error string `json:"error"`
+		}
+		_ = json.Unmarshal(raw, &merr)
+		if merr.Error != "" {
+			return fmt.Errorf("mastodon http %d: %s", resp.StatusCode, merr.Error)
+		}
+		return fmt.Errorf("mastodon http %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// resolveMastodonReplyTarget is the status a reply answers. By default a
+// reply answers the mention, to stay in its conversation.
+func resolveMastodonReplyTarget(quote string, msg bus.OutboundMessage) string {
+	if target := channels.ReplyTarget(quote, msg); target != "" {
+		return target
+	}
+	switch strings.ToLower(strings.TrimSpace(quote)) {
+	case "", channels.QuoteAuto:
+		return strings.TrimSpace(msg.Delivery.MessageID)
+	}
+	return ""
+}
+
+// readEvents parses a server-sent event stream, calling fn for each event.
+func readEvents(r io.Reader, fn func(event, data string)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	var event string
+	var data []string
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				fn(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// heartbeat comment
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// idleReader pushes back t on every read.
+type idleReader struct {
+	r io.Reader
+	t *time.Timer
+}
+
+func (r idleReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.t.Reset(streamIdleTimeout)
+	return n, err
+}
+
+// statusText converts status HTML to plain text.
+func statusText(content string) string {
+	z := html.NewTokenizer(strings.NewReader(content))
+	var b strings.Builder
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.TrimSpace(b.String())
+		case html.TextToken:
+			b.Write(z.Text())
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			switch {
+			case string(name) == "br":
+				b.WriteByte('\n')
+			case string(name) == "p" && tt == html.EndTagToken:
+				b.WriteString("\n\n")
+			}
+		}
+	}
+}
+
+// stripLeadingMentions drops the @account prefix clients add to replies.
+func stripLeadingMentions(text string) string {
+	for {
+		text = strings.TrimSpace(text)
+		if !strings.HasPrefix(text, "@") {
+			return text
+		}
+		i := strings.IndexFunc(text, unicode.IsSpace)
+		if i < 0 {
+			return ""
+		}
+		text = text[i:]
+	}
+}
+
+func mediaAttachments(media []mediaAttachment) []bus.Attachment {
+	var out []bus.Attachment
+	for _, m := range media {
+		u := strings.TrimSpace(m.URL)
+		if u == "" {
+			continue
+		}
+		kind := "file"
+		switch m.Type {
+		case "image":
+			kind = "image"
+		case "video", "gifv":
+			kind = "video"
+		case "audio":
+			kind = "audio"
+		}
+		name := ""
+		if pu, err := url.Parse(u); err == nil {
+			name = path.Base(pu.Path)
+		}
+		out = append(out, bus.Attachment{
+			ID:   m.ID,
+			Name: name,
+			Kind: kind,
+			URL:  u,
+		})
+	}
+	return out
+}
+
+// visibilityRank orders visibilities from most to least public; unknown
+// values rank -1.
+func visibilityRank(v string) int {
+	switch v {
+	case "public":
+		return 0
+	case "unlisted":
+		return 1
+	case "private":
+		return 2
+	case "direct":
+		return 3
+	default:
+		return -1
+	}
+}
+
+func narrowerVisibility(a, b string) string {
+	if visibilityRank(b) > visibilityRank(a) {
+		return b
+	}
+	return a
+}
+
+// newerID compares Mastodon IDs, which sort by length and then lexically.
+func newerID(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+func streamBackoff(failures int) time.Duration {
+	shift := min(max(failures-1, 0), 6)
+	return time.Second * time.Duration(1<<shift)
+}
+
+type notification struct {
+	ID      string  `json:"id"`
+	Type    string  `json:"type"`
+	Account account `json:"account"`
+	Status  *status `json:"status,omitempty"`
+}
+
+type account struct {
+	ID   string `json:"id"`
+	Acct string `json:"acct"`
+}
+
+type status struct {
+	ID               string            `json:"id"`
+	Content          string            `json:"content"`
+	Visibility       string            `json:"visibility"`
+	MediaAttachments []mediaAttachment `json:"media_attachments"`
+}
+
+type mediaAttachment struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
@@ -0,0 +1,133 @@
+package mastodon
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestHandleNotification_PublishesAllowedMentions(t *testing.T) {
+	b := bus.New(4)
+	c := New(config.MastodonConfig{Server: "https://social.example", AccessToken: "tok", AllowFrom: []string{"@alice@social.example"}}, b)
+	c.lastID = "100"
+
+	raw := `[
+	  {"id": "99", "type": "mention", "account": {"acct": "alice"}, "status": {"id": "s0", "content": "<p>old</p>"}},
+	  {"id": "101", "type": "favourite", "account": {"acct": "alice"}, "status": {"id": "s1", "content": "<p>fav</p>"}},
+	  {"id": "102", "type": "mention", "account": {"acct": "mallory@evil.example"}, "status": {"id": "s2", "content": "<p>hi</p>"}},
+	  {"id": "103", "type": "mention", "account": {"acct": "alice"}, "status": {"id": "s3", "visibility": "direct",
+	    "content": "<p><span class=\"h-card\"><a href=\"https://social.example/@bot\" class=\"u-url mention\">@<span>bot</span></a></span> hello &amp; welcome<br>second line</p><p>next</p>"}},
+	  {"id": "103", "type": "mention", "account": {"acct": "alice"}, "status": {"id": "s3", "content": "<p>duplicate</p>"}}
+	]`
+	var ns []notification
+	if err := json.Unmarshal([]byte(raw), &ns); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	for _, n := range ns {
+		c.handleNotification(context.Background(), n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if msg.SenderID != "alice@social.example" || msg.ChatID != "alice@social.example" || msg.SessionKey != "mastodon:alice@social.example" {
+		t.Fatalf("unexpected sender: %+v", msg)
+	}
+	if msg.Content != "hello & welcome\nsecond line\n\nnext" {
+		t.Fatalf("unexpected content: %q", msg.Content)
+	}
+	if msg.Delivery.ReplyToID != "s3" || !msg.Delivery.IsDirect {
+		t.Fatalf("unexpected delivery: %+v", msg.Delivery)
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	if msg, err := b.ConsumeInbound(short); err == nil {
+		t.Fatalf("expected only one message, got %+v", msg)
+	}
+}
+
+func TestSend_RepliesInThreadWithNarrowedVisibility(t *testing.T) {
+	var posts []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/statuses/s3":
+			_, _ = w.Write([]byte(`{"id":"s3","visibility":"private"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/statuses":
+			var body map[string]any
+			raw, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(raw, &body)
+			posts = append(posts, body)
+			_, _ = w.Write([]byte(`{"id":"p` + strconv.Itoa(len(posts)) + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(config.MastodonConfig{Server: srv.URL, AccessToken: "tok", Visibility: "unlisted", MaxChars: 150}, bus.New(1))
+	text := strings.Repeat("word ", 40)
+	err := c.Send(context.Background(), bus.OutboundMessage{
+		ChatID:   "alice@social.example",
+		Content:  text,
+		Delivery: bus.Delivery{ReplyToID: "s3"},
+	})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("expected reply chain of 2 posts, got %d", len(posts))
+	}
+	if posts[0]["in_reply_to_id"] != "s3" || posts[1]["in_reply_to_id"] != "p1" {
+		t.Fatalf("unexpected threading: %v / %v", posts[0]["in_reply_to_id"], posts[1]["in_reply_to_id"])
+	}
+	for _, p := range posts {
+		s, _ := p["status"].(string)
+		if !strings.HasPrefix(s, "@alice@social.example ") || len([]rune(s)) > 150 {
+			t.Fatalf("unexpected status: %q", s)
+		}
+		if p["visibility"] != "private" {
+			t.Fatalf("expected visibility narrowed to private, got %v", p["visibility"])
+		}
+	}
+}
+
+func TestReplyVisibility_DefaultsToDirect(t *testing.T) {
+	c := New(config.MastodonConfig{Server: "http://127.0.0.1:1", AccessToken: "tok"}, bus.New(1))
+	if got := c.replyVisibility(context.Background(), ""); got != "direct" {
+		t.Fatalf("expected direct, got %q", got)
+	}
+	if got := narrowerVisibility("direct", "public"); got != "direct" {
+		t.Fatalf("configured direct must not widen, got %q", got)
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := ":thump\n\nevent: update\ndata: {}\n\nevent: notification\ndata: {\"id\":\"1\",\ndata: \"type\":\"mention\"}\n\n"
+	var got []string
+	err := readEvents(strings.NewReader(stream), func(event, data string) {
+		got = append(got, event+"="+data)
+	})
+	if err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+	if len(got) != 2 || got[1] != "notification={\"id\":\"1\",\n\"type\":\"mention\"}" {
+		t.Fatalf("unexpected events: %q", got)
+	}
+}
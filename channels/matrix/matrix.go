@@ -0,0 +1,521 @@
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// Channel talks to a Matrix homeserver via the client-server API (/sync long
+// polling + room send). End-to-end encrypted rooms are not supported.
+type Channel struct {
+	cfg   config.MatrixConfig
+	bus   *bus.Bus
+	allow channels.AllowList
+	hc    *http.Client
+
+	running atomic.Bool
+
+	mu     sync.Mutex
+	userID string
+	cancel context.CancelFunc
+	// joined member counts from sync summaries and the rooms listed in
+	// m.direct account data; used to detect DMs.
+	members map[string]int
+	direct  map[string]bool
+}
+
+func New(cfg config.MatrixConfig, b *bus.Bus) *Channel {
+	return &Channel{
+		cfg:     cfg,
+		bus:     b,
+		allow:   channels.AllowList{AllowFrom: cfg.AllowFrom},
+		hc:      &http.Client{Timeout: time.Duration(syncTimeoutSec(cfg)+20) * time.Second},
+		members: map[string]int{},
+		direct:  map[string]bool{},
+	}
+}
+
+func (c *Channel) Name() string    { return "matrix" }
+func (c *Channel) IsRunning() bool { return c.running.Load() }
+
+func (c *Channel) Start(ctx context.Context) error {
+	if strings.TrimSpace(c.cfg.Homeserver) == "" {
+		return fmt.Errorf("matrix homeserver is empty")
+	}
+	if strings.TrimSpace(c.cfg.AccessToken) == "" {
+		return fmt.Errorf("matrix accessToken is empty")
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	userID := strings.TrimSpace(c.cfg.UserID)
+	if userID == "" {
+		var who struct {
+			UserID string `json:"user_id"`
+		}
+		if err := c.do(runCtx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &who); err != nil {
+			return fmt.Errorf("matrix whoami: %w", err)
+		}
+		userID = who.UserID
+	}
+
+	c.mu.Lock()
+	c.userID = userID
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	// Initial sync only establishes a position; backlog is not replayed.
+	var initial syncResponse
+	if err := c.do(runCtx, http.MethodGet, "/_matrix/client/v3/sync?timeout=0&filter="+url.QueryEscape(initialSyncFilter), nil, &initial); err != nil {
+		return fmt.Errorf("matrix initial sync: %w", err)
+	}
+	since := initial.NextBatch
+	c.recordRooms(initial)
+
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	failures := 0
+	for {
+		if runCtx.Err() != nil {
+			return runCtx.Err()
+		}
+		var res syncResponse
+		path := fmt.Sprintf("/_matrix/client/v3/sync?timeout=%d&since=%s&filter=%s", syncTimeoutSec(c.cfg)*1000, url.QueryEscape(since), url.QueryEscape(syncFilter))
+		if err := c.do(runCtx, http.MethodGet, path, nil, &res); err != nil {
+			if runCtx.Err() != nil {
+				return runCtx.Err()
+			}
+			failures++
+			wait := syncBackoff(failures)
+			log.Printf("matrix: sync failed, retry in %s: %v", wait, err)
+			t := time.NewTimer(wait)
+			select {
+			case <-runCtx.Done():
+				t.Stop()
+				return runCtx.Err()
+			case <-t.C:
+			}
+			continue
+		}
+		failures = 0
+		since = res.NextBatch
+		c.handleSync(runCtx, res)
+	}
+}
+
+func (c *Channel) Stop() error {
+	c.running.Store(false)
+	c.mu.Lock()
+	cancel := c.cancel
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	roomID := strings.TrimSpace(msg.ChatID)
+	if roomID == "" {
+		return fmt.Errorf("chat_id is empty")
+	}
+	text := strings.TrimSpace(msg.Content)
+	if text == "" {
+		return nil
+	}
+	content := buildMatrixContent(text, c.cfg.Quote, msg)
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%s", url.PathEscape(roomID), newTxnID())
+	return c.do(ctx, http.MethodPut, path, content, nil)
+}
+
+// SendTyping shows the bot as typing in the room until its next message or
+// for 10 seconds.
+func (c *Channel) SendTyping(ctx context.Context, chatID string) error {
+	c.mu.Lock()
+	self := c.userID
+	c.mu.Unlock()
+	if self == "" {
+		return fmt.Errorf("matrix not connected")
+	}
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/typing/%s", url.PathEscape(strings.TrimSpace(chatID)), url.PathEscape(self))
+	return c.do(ctx, http.MethodPut, path, map[string]any{"typing": true, "timeout": 10000}, nil)
+}
+
+func (c *Channel) handleSync(ctx context.Context, res syncResponse) {
+	c.mu.Lock()
+	self := c.userID
+	c.mu.Unlock()
+
+	for roomID, inv := range res.Rooms.Invite {
+		inviter := ""
+		for _, ev := range inv.InviteState.Events {
+			if ev.Type == "m.room.member" && ev.StateKey == self {
+				inviter = ev.Sender
+			}
+		}
+		if !c.cfg.AutoJoin || !c.allow.Allowed(inviter) {
+			continue
+		}
+		if err := c.do(ctx, http.MethodPost, "/_matrix/client/v3/join/"+url.PathEscape(roomID), map[string]any{}, nil); err != nil {
+			log.Printf("matrix: join %s failed: %v", roomID, err)
+		}
+	}
+
+	c.recordRooms(res)
+	for roomID, room := range res.Rooms.Join {
+		for _, ev := range room.Timeline.Events {
+			c.handleRoomEvent(ctx, roomID, self, ev)
+		}
+	}
+}
+
+func (c *Channel) handleRoomEvent(ctx context.Context, roomID, self string, ev event) {
+	if ev.Type != "m.room.message" || ev.Sender == "" || ev.Sender == self {
+		return
+	}
+	var content messageContent
+	if err := json.Unmarshal(ev.Content, &content); err != nil {
+		return
+	}
+	// Skip edits; the original message was already handled.
+	if content.RelatesTo != nil && content.RelatesTo.RelType == "m.replace" {
+		return
+	}
+	if !c.allow.Allowed(ev.Sender) {
+		return
+	}
+	direct := c.isDirect(roomID)
+	text := strings.TrimSpace(stripReplyFallback(content.Body))
+	var attachments []bus.Attachment
+	switch content.MsgType {
+	case "m.text", "m.emote":
+	case "m.image", "m.audio", "m.video", "m.file":
+		if a, ok := c.mediaAttachment(ev.EventID, content); ok {
+			attachments = append(attachments, a)
+			text = ""
+		}
+	default:
+		return
+	}
+	if !direct && !c.allowedInRoom(roomID, self, text, content) {
+		return
+	}
+	text = stripMention(text, self)
+	if text == "" && len(attachments) == 0 {
+		return
+	}
+	_ = c.bus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:     "matrix",
+		SenderID:    ev.Sender,
+		ChatID:      roomID,
+		Content:     text,
+		Attachments: attachments,
+		SessionKey:  "matrix:" + roomID,
+		Delivery:    buildMatrixDelivery(ev.EventID, content, direct),
+	})
+}
+
+// recordRooms keeps what res tells about which rooms are DMs: the m.direct
+// account data, replaced as a whole when it changes, and the joined member
+// counts, which the summary only carries when they change.
+func (c *Channel) recordRooms(res syncResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ev := range res.AccountData.Events {
+		if ev.Type != "m.direct" {
+			continue
+		}
+		var rooms map[string][]string
+		if err := json.Unmarshal(ev.Content, &rooms); err != nil {
+			continue
+		}
+		c.direct = map[string]bool{}
+		for _, ids := range rooms {
+			for _, id := range ids {
+				c.direct[id] = true
+			}
+		}
+	}
+	for roomID, room := range res.Rooms.Join {
+		if room.Summary.JoinedMemberCount != nil {
+			c.members[roomID] = *room.Summary.JoinedMemberCount
+		}
+	}
+}
+
+// isDirect reports whether roomID is marked as a DM in m.direct or has at
+// most two joined members.
+func (c *Channel) isDirect(roomID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.direct[roomID] {
+		return true
+	}
+	n, ok := c.members[roomID]
+	return ok && n <= 2
+}
+
+// allowedInRoom applies groupPolicy to multi-user rooms.
+func (c *Channel) allowedInRoom(roomID, self, text string, content messageContent) bool {
+	groups := channels.GroupPolicy{Policy: c.cfg.GroupPolicy, AllowFrom: c.cfg.GroupAllowFrom, Groups: c.cfg.Groups}
+	return groups.Allows(mentionsSelf(self, text, content), roomID)
+}
+
+func mentionsSelf(self, text string, content messageContent) bool {
+	if content.Mentions != nil && slices.Contains(content.Mentions.UserIDs, self) {
+		return true
+	}
+	return self != "" && strings.Contains(text, self)
+}
+
+func (c *Channel) mediaAttachment(eventID string, content messageContent) (bus.Attachment, bool) {
+	server, mediaID, ok := parseMXC(content.URL)
+	if !ok {
+		return bus.Attachment{}, false
+	}
+	mimeType := ""
+	size := int64(0)
+	if content.Info != nil {
+		mimeType = strings.TrimSpace(content.Info.MIMEType)
+		size = content.Info.Size
+	}
+	kind := bus.InferAttachmentKind(mimeType)
+	if content.MsgType == "m.audio" {
+		kind = "audio"
+	}
+	return bus.Attachment{
+		ID:        eventID,
+		Name:      strings.TrimSpace(content.Body),
+		MIMEType:  mimeType,
+		Kind:      kind,
+		SizeBytes: size,
+		URL: strings.TrimRight(c.cfg.Homeserver, "/") +
+			"/_matrix/client/v1/media/download/" + url.PathEscape(server) + "/" + url.PathEscape(mediaID),
+		Headers: map[string]string{"Authorization": "Bearer " + strings.TrimSpace(c.cfg.AccessToken)},
+	}, true
+}
+
+func (c *Channel) do(ctx context.Context, method, path string, body any, out any) error {
+	var rdr io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rdr = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.cfg.Homeserver, "/")+path, rdr)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(c.cfg.AccessToken))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var merr struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		_ = json.Unmarshal(raw, &merr)
+		if merr.ErrCode != "" {
+			return fmt.Errorf("matrix http %d: %s: %s", resp.StatusCode, merr.ErrCode, merr.Error)
+		}
+		return fmt.Errorf("matrix http %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// buildMatrixContent creates an m.text event. Replies in a thread stay in the
+// thread; otherwise the message chosen by quote is referenced as a rich
+// reply.
+func buildMatrixContent(text, quote string, msg bus.OutboundMessage) map[string]any {
+	content := map[string]any{
+		"msgtype": "m.text",
+		"body":    text,
+	}
+	threadID := strings.TrimSpace(msg.Delivery.ThreadID)
+	replyTo := channels.ReplyTarget(quote, msg)
+	switch {
+	case threadID != "":
+		rel := map[string]any{
+			"rel_type":        "m.thread",
+			"event_id":        threadID,
+			"is_falling_back": true,
+		}
+		if replyTo != "" {
+			rel["m.in_reply_to"] = map[string]any{"event_id": replyTo}
+		}
+		content["m.relates_to"] = rel
+	case replyTo != "":
+		content["m.relates_to"] = map[string]any{
+			"m.in_reply_to": map[string]any{"event_id": replyTo},
+		}
+	}
+	return content
+}
+
+func buildMatrixDelivery(eventID string, content messageContent, direct bool) bus.Delivery {
+	d := bus.Delivery{
+		MessageID: strings.TrimSpace(eventID),
+		IsDirect:  direct,
+	}
+	if content.RelatesTo != nil && content.RelatesTo.RelType == "m.thread" {
+		d.ThreadID = strings.TrimSpace(content.RelatesTo.EventID)
+		d.ReplyToID = d.MessageID
+		return d
+	}
+	if !direct {
+		d.ReplyToID = d.MessageID
+	}
+	return d
+}
+
+// stripReplyFallback removes the "> <@user> quoted" fallback lines clients
+// prepend to replies.
+func stripReplyFallback(body string) string {
+	if !strings.HasPrefix(body, "> ") {
+		return body
+	}
+	lines := strings.Split(body, "\n")
+	i := 0
+	for i < len(lines) && strings.HasPrefix(lines[i], "> ") {
+		i++
+	}
+	return strings.Join(lines[i:], "\n")
+}
+
+func stripMention(text, self string) string {
+	text = strings.TrimSpace(text)
+	if self == "" {
+		return text
+	}
+	if after, ok := strings.CutPrefix(text, self); ok {
+		text = strings.TrimSpace(after)
+		text = strings.TrimSpace(strings.TrimPrefix(text, ":"))
+		text = strings.TrimSpace(strings.TrimPrefix(text, ","))
+	}
+	return text
+}
+
+func parseMXC(uri string) (server, mediaID string, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(uri), "mxc://")
+	if !found {
+		return "", "", false
+	}
+	server, mediaID, ok = strings.Cut(rest, "/")
+	if !ok || server == "" || mediaID == "" || strings.Contains(mediaID, "/") {
+		return "", "", false
+	}
+	return server, mediaID, true
+}
+
+func syncTimeoutSec(cfg config.MatrixConfig) int {
+	if cfg.SyncTimeoutSec <= 0 {
+		return config.DefaultMatrixSyncTimeoutSec
+	}
+	return cfg.SyncTimeoutSec
+}
+
+func syncBackoff(failures int) time.Duration {
+	shift := min(max(failures-1, 0), 5)
+	return time.Second * time.Duration(1<<shift)
+}
+
+func newTxnID() string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return "clawlet" + hex.EncodeToString(b[:])
+}
+
+// Homeservers only send the joined member count in room summaries when
+// members are lazy loaded, so both filters ask for it.
+const (
+	initialSyncFilter = `{"room":{"state":{"lazy_load_members":true},"timeline":{"limit":1}}}`
+	syncFilter        = `{"room":{"state":{"lazy_load_members":true}}}`
+)
+
+type syncResponse struct {
+	NextBatch   string `json:"next_batch"`
+	AccountData struct {
+		Events []event `json:"events"`
+	} `json:"account_data"`
+	Rooms struct {
+		Join   map[string]joinedRoom  `json:"join"`
+		Invite map[string]invitedRoom `json:"invite"`
+	} `json:"rooms"`
+}
+
+type joinedRoom struct {
+	Summary struct {
+		JoinedMemberCount *int `json:"m.joined_member_count,omitempty"`
+	} `json:"summary"`
+	Timeline struct {
+		Events []event `json:"events"`
+	} `json:"timeline"`
+}
+
+type invitedRoom struct {
+	InviteState struct {
+		Events []event `json:"events"`
+	} `json:"invite_state"`
+}
+
+type event struct {
+	Type     string          `json:"type"`
+	EventID  string          `json:"event_id"`
+	Sender   string          `json:"sender"`
+	StateKey string          `json:"state_key,omitempty"`
+	Content  json.RawMessage `json:"content"`
+}
+
+type messageContent struct {
+	MsgType string `json:"msgtype"`
+	Body    string `json:"body"`
+	URL     string `json:"url,omitempty"`
+	Info    *struct {
+		MIMEType string `json:"mimetype,omitempty"`
+		Size     int64  `json:"size,omitempty"`
+	} `json:"info,omitempty"`
+	RelatesTo *struct {
+		RelType string `json:"rel_type,omitempty"`
+		EventID string `json:"event_id,omitempty"`
+	} `json:"m.relates_to,omitempty"`
+	Mentions *struct {
+		UserIDs []string `json:"user_ids,omitempty"`
+	} `json:"m.mentions,omitempty"`
+}
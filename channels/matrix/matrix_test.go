@@ -0,0 +1,201 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestBuildMatrixContent(t *testing.T) {
+	t.Run("thread reply", func(t *testing.T) {
+		c := buildMatrixContent("hi", "", bus.OutboundMessage{Delivery: bus.Delivery{ThreadID: "$root", ReplyToID: "$ev"}})
+		rel, _ := c["m.relates_to"].(map[string]any)
+		if rel["rel_type"] != "m.thread" || rel["event_id"] != "$root" {
+			t.Fatalf("unexpected relation: %#v", rel)
+		}
+		if irt, _ := rel["m.in_reply_to"].(map[string]any); irt["event_id"] != "$ev" {
+			t.Fatalf("expected in_reply_to fallback: %#v", rel)
+		}
+	})
+	t.Run("plain reply", func(t *testing.T) {
+		c := buildMatrixContent("hi", "", bus.OutboundMessage{ReplyTo: "$legacy"})
+		rel, _ := c["m.relates_to"].(map[string]any)
+		if irt, _ := rel["m.in_reply_to"].(map[string]any); irt["event_id"] != "$legacy" {
+			t.Fatalf("unexpected relation: %#v", rel)
+		}
+	})
+	t.Run("no relation", func(t *testing.T) {
+		if _, ok := buildMatrixContent("hi", "", bus.OutboundMessage{})["m.relates_to"]; ok {
+			t.Fatalf("expected no relation")
+		}
+	})
+}
+
+func TestHandleSync_PublishesAllowedMessages(t *testing.T) {
+	b := bus.New(4)
+	c := New(config.MatrixConfig{Homeserver: "https://hs.example", AccessToken: "tok", AllowFrom: []string{"@alice:example"}}, b)
+	c.userID = "@bot:example"
+
+	raw := `{
+	  "next_batch": "s2",
+	  "rooms": {"join": {
+	    "!dm:example": {
+	      "summary": {"m.joined_member_count": 2},
+	      "timeline": {"events": [
+	        {"type": "m.room.message", "event_id": "$1", "sender": "@mallory:example", "content": {"msgtype": "m.text", "body": "ignored"}},
+	        {"type": "m.room.message", "event_id": "$2", "sender": "@bot:example", "content": {"msgtype": "m.text", "body": "self"}},
+	        {"type": "m.room.message", "event_id": "$3", "sender": "@alice:example", "content": {"msgtype": "m.text", "body": "> <@bot:example> earlier\n\nhello", "m.relates_to": {"rel_type": "m.thread", "event_id": "$root"}}},
+	        {"type": "m.room.message", "event_id": "$4", "sender": "@alice:example", "content": {"msgtype": "m.image", "body": "cat.png", "url": "mxc://example/abc", "info": {"mimetype": "image/png", "size": 10}}}
+	      ]}
+	    },
+	    "!group:example": {
+	      "summary": {"m.joined_member_count": 5},
+	      "timeline": {"events": [
+	        {"type": "m.room.message", "event_id": "$5", "sender": "@alice:example", "content": {"msgtype": "m.text", "body": "not for the bot"}}
+	      ]}
+	    }
+	  }}
+	}`
+	var res syncResponse
+	if err := json.Unmarshal([]byte(raw), &res); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	c.handleSync(context.Background(), res)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	first, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if first.Content != "hello" || first.Delivery.ThreadID != "$root" || !first.Delivery.IsDirect {
+		t.Fatalf("unexpected first message: %+v", first)
+	}
+	second, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if len(second.Attachments) != 1 || !strings.HasSuffix(second.Attachments[0].URL, "/_matrix/client/v1/media/download/example/abc") {
+		t.Fatalf("unexpected attachment message: %+v", second)
+	}
+	if second.Attachments[0].Headers["Authorization"] != "Bearer tok" {
+		t.Fatalf("expected auth header on media download")
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	if msg, err := b.ConsumeInbound(short); err == nil {
+		t.Fatalf("expected group message without mention to be dropped, got %+v", msg)
+	}
+}
+
+func TestHandleSync_DirectFromAccountData(t *testing.T) {
+	b := bus.New(4)
+	c := New(config.MatrixConfig{Homeserver: "https://hs.example", AccessToken: "tok", AllowFrom: []string{"@alice:example"}}, b)
+	c.userID = "@bot:example"
+
+	// No room summaries, as a homeserver sends without lazy-loaded members.
+	raw := `{
+	  "next_batch": "s2",
+	  "account_data": {"events": [
+	    {"type": "m.direct", "content": {"@alice:example": ["!dm:example"]}}
+	  ]},
+	  "rooms": {"join": {
+	    "!dm:example": {"timeline": {"events": [
+	      {"type": "m.room.message", "event_id": "$1", "sender": "@alice:example", "content": {"msgtype": "m.text", "body": "hello"}}
+	    ]}},
+	    "!group:example": {"timeline": {"events": [
+	      {"type": "m.room.message", "event_id": "$2", "sender": "@alice:example", "content": {"msgtype": "m.text", "body": "not for the bot"}}
+	    ]}}
+	  }}
+	}`
+	var res syncResponse
+	if err := json.Unmarshal([]byte(raw), &res); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	c.handleSync(context.Background(), res)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if msg.ChatID != "!dm:example" || !msg.Delivery.IsDirect {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	if msg, err := b.ConsumeInbound(short); err == nil {
+		t.Fatalf("expected message in room without summary or m.direct to need a mention, got %+v", msg)
+	}
+}
+
+func TestSend_PutsRoomMessage(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &gotBody)
+		_, _ = w.Write([]byte(`{"event_id":"$new"}`))
+	}))
+	defer srv.Close()
+
+	c := New(config.MatrixConfig{Homeserver: srv.URL, AccessToken: "tok"}, bus.New(1))
+	if err := c.Send(context.Background(), bus.OutboundMessage{ChatID: "!room:example", Content: "hi"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if !strings.HasPrefix(gotPath, "/_matrix/client/v3/rooms/%21room:example/send/m.room.message/") {
+		t.Fatalf("unexpected path: %s", gotPath)
+	}
+	if gotAuth != "Bearer tok" || gotBody["body"] != "hi" || gotBody["msgtype"] != "m.text" {
+		t.Fatalf("unexpected request: auth=%q body=%v", gotAuth, gotBody)
+	}
+}
+
+func TestSendTyping_PutsTypingState(t *testing.T) {
+	var gotMethod, gotPath string
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.EscapedPath()
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &gotBody)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	c := New(config.MatrixConfig{Homeserver: srv.URL, AccessToken: "tok"}, bus.New(1))
+	if err := c.SendTyping(context.Background(), "!room:example"); err == nil {
+		t.Fatal("expected error before whoami")
+	}
+	c.userID = "@bot:example"
+	if err := c.SendTyping(context.Background(), "!room:example"); err != nil {
+		t.Fatalf("typing: %v", err)
+	}
+	if gotMethod != http.MethodPut || gotPath != "/_matrix/client/v3/rooms/%21room:example/typing/@bot:example" || gotBody["typing"] != true {
+		t.Fatalf("unexpected request: %s %s %v", gotMethod, gotPath, gotBody)
+	}
+}
+
+func TestSend_ReportsMatrixError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errcode":"M_FORBIDDEN","error":"not in room"}`))
+	}))
+	defer srv.Close()
+	c := New(config.MatrixConfig{Homeserver: srv.URL, AccessToken: "tok"}, bus.New(1))
+	err := c.Send(context.Background(), bus.OutboundMessage{ChatID: "!room:example", Content: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "M_FORBIDDEN") {
+		t.Fatalf("expected matrix error, got %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package mqtt
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+const (
+	dialTimeout     = 10 * time.Second
+	ackTimeout      = 10 * time.Second
+	maxReconnectGap = 64 * time.Second
+	chatIDMarker    = "{chat_id}"
+)
+
+var errNotConnected = errors.New("mqtt: not connected")
+
+// Channel subscribes to an inbound topic and publishes replies to an
+// outbound topic. Inbound payloads are plain text or a JSON object with
+// "text" and optional "sender", "chat_id" and "id"; replies are JSON objects
+// with "chat_id", "text" and "reply_to".
+type Channel struct {
+	cfg   config.MQTTConfig
+	bus   *bus.Bus
+	allow channels.AllowList
+
+	running atomic.Bool
+
+	mu     sync.Mutex
+	client paho.Client
+	cancel context.CancelFunc
+}
+
+func New(cfg config.MQTTConfig, b *bus.Bus) *Channel {
+	return &Channel{
+		cfg:   cfg,
+		bus:   b,
+		allow: channels.AllowList{AllowFrom: cfg.AllowFrom},
+	}
+}
+
+func (c *Channel) Name() string    { return "mqtt" }
+func (c *Channel) IsRunning() bool { return c.running.Load() }
+
+// Start connects and keeps the connection up until ctx is done or Stop is
+// called. The client reconnects on its own and subscribes again each time.
+func (c *Channel) Start(ctx context.Context) error {
+	if strings.TrimSpace(c.cfg.Broker) == "" {
+		return fmt.Errorf("mqtt broker is empty")
+	}
+	if c.cfg.QoS < 0 || c.cfg.QoS > 1 {
+		return fmt.Errorf("mqtt qos must be 0 or 1")
+	}
+	broker, err := brokerURL(c.cfg.Broker)
+	if err != nil {
+		return err
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keepAliveSec := c.cfg.KeepAliveSec
+	if keepAliveSec <= 0 {
+		keepAliveSec = config.DefaultMQTTKeepAliveSec
+	}
+	opts := paho.NewClientOptions().
+		AddBroker(broker.String()).
+		SetClientID(cmp.Or(strings.TrimSpace(c.cfg.ClientID), config.DefaultMQTTClientID)).
+		SetUsername(c.cfg.Username).
+		SetPassword(c.cfg.Password).
+		SetKeepAlive(time.Duration(keepAliveSec) * time.Second).
+		SetConnectTimeout(dialTimeout).
+		SetTLSConfig(&tls.Config{ServerName: broker.Hostname()}).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(maxReconnectGap).
+		SetOnConnectHandler(func(cl paho.Client) { c.subscribe(runCtx, cl) }).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Printf("mqtt: connection lost, reconnecting: %v", err)
+		})
+	client := paho.NewClient(opts)
+	c.mu.Lock()
+	c.client, c.cancel = client, cancel
+	c.mu.Unlock()
+
+	defer func() {
+		client.Disconnect(250)
+		c.mu.Lock()
+		if c.client == client {
+			c.client, c.cancel = nil, nil
+		}
+		c.mu.Unlock()
+	}()
+
+	c.running.Store(true)
+	defer c.running.Store(false)
+	// A failed first connection ends the run, so the manager retries it
+	// with backoff and shows the error; later drops are reconnected by the
+	// client.
+	t := client.Connect()
+	select {
+	case <-t.Done():
+		if err := t.Error(); err != nil {
+			return fmt.Errorf("mqtt: %w", err)
+		}
+	case <-runCtx.Done():
+	}
+	<-runCtx.Done()
+	return ctx.Err()
+}
+
+func (c *Channel) Stop() error {
+	c.running.Store(false)
+	c.mu.Lock()
+	cancel := c.cancel
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// subscribe runs on every (re)connect, since the broker forgets the
+// subscriptions of a clean session.
+func (c *Channel) subscribe(ctx context.Context, cl paho.Client) {
+	t := cl.Subscribe(c.cfg.InboundTopic, byte(c.cfg.QoS), func(_ paho.Client, m paho.Message) {
+		c.handlePublish(ctx, m.Topic(), m.Payload())
+	})
+	if !t.WaitTimeout(ackTimeout) {
+		log.Printf("mqtt: no SUBACK for %q", c.cfg.InboundTopic)
+		return
+	}
+	if err := t.Error(); err != nil {
+		log.Printf("mqtt: subscribe to %q failed: %v", c.cfg.InboundTopic, err)
+		return
+	}
+	if st, ok := t.(*paho.SubscribeToken); ok && st.Result()[c.cfg.InboundTopic] == 0x80 {
+		log.Printf("mqtt: subscription to %q refused", c.cfg.InboundTopic)
+	}
+}
+
+type outbound struct {
+	ChatID  string `json:"chat_id"`
+	Text    string `json:"text"`
+	ReplyTo string `json:"reply_to,omitempty"`
+}
+
+// Send publishes the reply to outboundTopic, with "{chat_id}" replaced by
+// msg.ChatID. At QoS 1 it waits for the broker's acknowledgement.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	chatID := strings.TrimSpace(msg.ChatID)
+	if chatID == "" {
+		return fmt.Errorf("chat_id is empty")
+	}
+	text := strings.TrimSpace(msg.Content)
+	if text == "" {
+		return nil
+	}
+	payload, err := json.Marshal(outbound{
+		ChatID:  chatID,
+		Text:    text,
+		ReplyTo: cmp.Or(msg.Delivery.MessageID, msg.ReplyTo),
+	})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil || !client.IsConnectionOpen() {
+		return errNotConnected
+	}
+	topic := strings.ReplaceAll(c.cfg.OutboundTopic, chatIDMarker, chatID)
+	t := client.Publish(topic, byte(c.cfg.QoS), false, payload)
+	timer := time.NewTimer(ackTimeout)
+	defer timer.Stop()
+	select {
+	case <-t.Done():
+		return t.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("mqtt: no PUBACK for %s", topic)
+	}
+}
+
+type inbound struct {
+	Text   string `json:"text"`
+	Sender string `json:"sender"`
+	ChatID string `json:"chat_id"`
+	ID     string `json:"id"`
+}
+
+// handlePublish turns an inbound payload into a message. Without "sender" the
+// topic is the sender, and without "chat_id" the sender is the chat.
+func (c *Channel) handlePublish(ctx context.Context, topic string, payload []byte) {
+	in := parseInbound(payload)
+	if strings.TrimSpace(in.Text) == "" {
+		return
+	}
+	sender := cmp.Or(strings.TrimSpace(in.Sender), topic)
+	if !c.allow.Allowed(sender) {
+		return
+	}
+	chatID := cmp.Or(strings.TrimSpace(in.ChatID), sender)
+	_ = c.bus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:    "mqtt",
+		SenderID:   sender,
+		ChatID:     chatID,
+		Content:    strings.TrimSpace(in.Text),
+		SessionKey: "mqtt:" + chatID,
+		Delivery:   bus.Delivery{MessageID: in.ID, IsDirect: true},
+	})
+}
+
+func parseInbound(payload []byte) inbound {
+	s := strings.TrimSpace(string(payload))
+	if strings.HasPrefix(s, "{") {
+		var in inbound
+		if err := json.Unmarshal([]byte(s), &in); err == nil {
+			return in
+		}
+	}
+	return inbound{Text: s}
+}
+
+// brokerURL checks the scheme and fills in the default port, which the
+// client does not.
+func brokerURL(broker string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(broker))
+	if err != nil {
+		return nil, fmt.Errorf("mqtt: invalid broker url: %w", err)
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "mqtt", "tcp":
+	case "mqtts", "ssl", "tls":
+		port = "8883"
+	default:
+		return nil, fmt.Errorf("mqtt: unsupported scheme %q (use mqtt:// or mqtts://)", u.Scheme)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u, nil
+}
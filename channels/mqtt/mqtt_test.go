@@ -0,0 +1,207 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// fakeBroker accepts clients, acknowledges CONNECT and SUBSCRIBE, and
+// hands every later packet to the test.
+type fakeBroker struct {
+	ln       net.Listener
+	connack  byte
+	filter   chan string
+	received chan packets.ControlPacket
+	conn     chan net.Conn
+}
+
+func newFakeBroker(t *testing.T, connack byte) *fakeBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	fb := &fakeBroker{ln: ln, connack: connack, filter: make(chan string, 4), received: make(chan packets.ControlPacket, 8), conn: make(chan net.Conn, 4)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go fb.serve(conn)
+		}
+	}()
+	return fb
+}
+
+func (fb *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	if p, err := packets.ReadPacket(conn); err != nil {
+		return
+	} else if _, ok := p.(*packets.ConnectPacket); !ok {
+		return
+	}
+	ack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+	ack.ReturnCode = fb.connack
+	if ack.Write(conn) != nil || fb.connack != packets.Accepted {
+		return
+	}
+	for {
+		p, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch p := p.(type) {
+		case *packets.SubscribePacket:
+			fb.filter <- p.Topics[0]
+			suback := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			suback.MessageID = p.MessageID
+			suback.ReturnCodes = []byte{p.Qoss[0]}
+			_ = suback.Write(conn)
+			fb.conn <- conn
+		case *packets.PingreqPacket:
+			_ = packets.NewControlPacket(packets.Pingresp).Write(conn)
+		case *packets.PublishPacket:
+			if p.Qos > 0 {
+				puback := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				puback.MessageID = p.MessageID
+				_ = puback.Write(conn)
+			}
+			fb.received <- p
+		default:
+			fb.received <- p
+		}
+	}
+}
+
+func (fb *fakeBroker) url() string { return "mqtt://" + fb.ln.Addr().String() }
+
+func publishTo(conn net.Conn, topic string, qos byte, id uint16, payload string) error {
+	p := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	p.TopicName, p.Qos, p.MessageID, p.Payload = topic, qos, id, []byte(payload)
+	return p.Write(conn)
+}
+
+func TestChannel_InboundAndReply(t *testing.T) {
+	fb := newFakeBroker(t, packets.Accepted)
+	b := bus.New(4)
+	c := New(config.MQTTConfig{
+		Broker:        fb.url(),
+		InboundTopic:  "home/in/#",
+		OutboundTopic: "home/out/{chat_id}",
+		QoS:           1,
+		KeepAliveSec:  30,
+		AllowFrom:     []string{"home/in/kitchen", "node-red"},
+	}, b)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() { _ = c.Start(ctx) }()
+	defer c.Stop()
+
+	if got := <-fb.filter; got != "home/in/#" {
+		t.Fatalf("filter=%q", got)
+	}
+	conn := <-fb.conn
+	_ = publishTo(conn, "home/in/garage", 0, 0, "not allowed")
+	_ = publishTo(conn, "home/in/kitchen", 0, 0, "lights off")
+	_ = publishTo(conn, "home/in/x", 1, 7, `{"text":"status?","sender":"node-red","chat_id":"flow1","id":"m1"}`)
+
+	msg, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.SenderID != "home/in/kitchen" || msg.ChatID != "home/in/kitchen" || msg.Content != "lights off" {
+		t.Fatalf("plain inbound=%+v", msg)
+	}
+	msg, err = b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.SenderID != "node-red" || msg.SessionKey != "mqtt:flow1" || msg.Content != "status?" || msg.Delivery.MessageID != "m1" {
+		t.Fatalf("json inbound=%+v", msg)
+	}
+	if p, ok := (<-fb.received).(*packets.PubackPacket); !ok || p.MessageID != 7 {
+		t.Fatalf("expected PUBACK for 7, got %v", p)
+	}
+
+	if err := c.Send(ctx, bus.OutboundMessage{Channel: "mqtt", ChatID: "flow1", Content: "all good", Delivery: bus.Delivery{MessageID: "m1"}}); err != nil {
+		t.Fatal(err)
+	}
+	pub, ok := (<-fb.received).(*packets.PublishPacket)
+	if !ok || pub.TopicName != "home/out/flow1" || pub.Qos != 1 {
+		t.Fatalf("publish=%v", pub)
+	}
+	var out outbound
+	if err := json.Unmarshal(pub.Payload, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != (outbound{ChatID: "flow1", Text: "all good", ReplyTo: "m1"}) {
+		t.Fatalf("payload=%+v", out)
+	}
+}
+
+func TestChannel_ResubscribesAfterReconnect(t *testing.T) {
+	fb := newFakeBroker(t, packets.Accepted)
+	b := bus.New(4)
+	c := New(config.MQTTConfig{Broker: fb.url(), InboundTopic: "in", OutboundTopic: "out"}, b)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go func() { _ = c.Start(ctx) }()
+	defer c.Stop()
+
+	<-fb.filter
+	_ = (<-fb.conn).Close()
+	if got := <-fb.filter; got != "in" {
+		t.Fatalf("filter after reconnect=%q", got)
+	}
+	_ = publishTo(<-fb.conn, "in", 0, 0, "back")
+	if msg, err := b.ConsumeInbound(ctx); err != nil || msg.Content != "back" {
+		t.Fatalf("msg=%+v err=%v", msg, err)
+	}
+}
+
+func TestChannel_StartFailsWhenRefused(t *testing.T) {
+	fb := newFakeBroker(t, packets.ErrRefusedNotAuthorised)
+	c := New(config.MQTTConfig{Broker: fb.url(), InboundTopic: "in", OutboundTopic: "out"}, bus.New(1))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := c.Start(ctx)
+	if err == nil || !strings.Contains(err.Error(), "not Authorized") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestSend_NotConnected(t *testing.T) {
+	c := New(config.MQTTConfig{Broker: "mqtt://127.0.0.1:1", OutboundTopic: "out"}, bus.New(1))
+	if err := c.Send(context.Background(), bus.OutboundMessage{ChatID: "x", Content: "hi"}); err != errNotConnected {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestBrokerURL_DefaultPorts(t *testing.T) {
+	for in, want := range map[string]string{
+		"mqtt://broker":      "mqtt://broker:1883",
+		"mqtts://broker":     "mqtts://broker:8883",
+		"mqtt://broker:1884": "mqtt://broker:1884",
+		"mqtt://[::1]":       "mqtt://[::1]:1883",
+		"tcp://10.0.0.1":     "tcp://10.0.0.1:1883",
+	} {
+		u, err := brokerURL(in)
+		if err != nil || u.String() != want {
+			t.Errorf("brokerURL(%q) = %v, %v; want %s", in, u, err, want)
+		}
+	}
+	if _, err := brokerURL("http://broker"); err == nil {
+		t.Error("expected error for http scheme")
+	}
+}
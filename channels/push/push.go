@@ -0,0 +1,193 @@
+// Package push delivers outbound messages as phone notifications through
+// ntfy and Pushover. It is one-way: nothing is received, so it suits cron job
+// output and the message tool when no chat app is configured.
+package push
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+const (
+	// Service limits; longer messages are cut.
+	ntfyMaxBytes        = 4096
+	pushoverMaxRunes    = 1024
+	pushoverTitleRunes  = 250
+	ntfyService         = "ntfy"
+	pushoverService     = "pushover"
+	chatIDAllServices   = "default"
+	truncationIndicator = "…"
+)
+
+// Channel sends to ntfy topics and Pushover users. Chat IDs are "ntfy" or
+// "pushover" for the configured target, "ntfy:<topic>" or
+// "pushover:<user key>" for another one, and "default" for every configured
+// service.
+type Channel struct {
+	cfg config.PushConfig
+	hc  *http.Client
+
+	running atomic.Bool
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func New(cfg config.PushConfig) *Channel {
+	return &Channel{
+		cfg: cfg,
+		hc:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *Channel) Name() string    { return "push" }
+func (c *Channel) IsRunning() bool { return c.running.Load() }
+
+// Start only marks the channel running until it is stopped; there is nothing
+// to receive.
+func (c *Channel) Start(ctx context.Context) error {
+	if !c.cfg.NtfyEnabled() && !c.cfg.PushoverEnabled() {
+		return fmt.Errorf("push: neither ntfy.topic nor pushover appToken/userKey is set")
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	c.running.Store(true)
+	defer c.running.Store(false)
+	<-runCtx.Done()
+	return runCtx.Err()
+}
+
+func (c *Channel) Stop() error {
+	c.running.Store(false)
+	c.mu.Lock()
+	cancel := c.cancel
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	text := strings.TrimSpace(msg.Content)
+	if text == "" {
+		return nil
+	}
+	service, target, _ := strings.Cut(strings.TrimSpace(msg.ChatID), ":")
+	target = strings.TrimSpace(target)
+	switch strings.ToLower(service) {
+	case ntfyService:
+		return c.sendNtfy(ctx, target, text)
+	case pushoverService:
+		return c.sendPushover(ctx, target, text)
+	case chatIDAllServices, "":
+		var errs []error
+		if c.cfg.NtfyEnabled() {
+			errs = append(errs, c.sendNtfy(ctx, "", text))
+		}
+		if c.cfg.PushoverEnabled() {
+			errs = append(errs, c.sendPushover(ctx, "", text))
+		}
+		return errors.Join(errs...)
+	default:
+		return fmt.Errorf("push: chat_id must be ntfy[:topic], pushover[:user key] or default, got %q", msg.ChatID)
+	}
+}
+
+func (c *Channel) sendNtfy(ctx context.Context, topic, text string) error {
+	if topic == "" {
+		topic = strings.TrimSpace(c.cfg.Ntfy.Topic)
+	}
+	if topic == "" {
+		return fmt.Errorf("push: ntfy topic is empty")
+	}
+	endpoint := strings.TrimRight(c.cfg.Ntfy.Server, "/") + "/" + url.PathEscape(topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(truncateBytes(text, ntfyMaxBytes)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", c.cfg.TitleValue())
+	req.Header.Set("Markdown", "yes")
+	if p := strings.TrimSpace(c.cfg.Ntfy.Priority); p != "" {
+		req.Header.Set("Priority", p)
+	}
+	if tok := strings.TrimSpace(c.cfg.Ntfy.Token); tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	return c.do(req, ntfyService)
+}
+
+func (c *Channel) sendPushover(ctx context.Context, user, text string) error {
+	if user == "" {
+		user = strings.TrimSpace(c.cfg.Pushover.UserKey)
+	}
+	if user == "" || strings.TrimSpace(c.cfg.Pushover.AppToken) == "" {
+		return fmt.Errorf("push: pushover appToken or user key is empty")
+	}
+	form := url.Values{
+		"token":   {c.cfg.Pushover.AppToken},
+		"user":    {user},
+		"message": {truncateRunes(text, pushoverMaxRunes)},
+		"title":   {truncateRunes(c.cfg.TitleValue(), pushoverTitleRunes)},
+	}
+	if d := strings.TrimSpace(c.cfg.Pushover.Device); d != "" {
+		form.Set("device", d)
+	}
+	endpoint := strings.TrimRight(c.cfg.Pushover.APIBaseURL, "/") + "/messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req, pushoverService)
+}
+
+func (c *Channel) do(req *http.Request, service string) error {
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("push: %s: %w", service, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("push: %s http %d: %s", service, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func truncateBytes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max - len(truncationIndicator)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + truncationIndicator
+}
+
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + truncationIndicator
+}
@@ -0,0 +1,129 @@
+package push
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+type request struct {
+	path   string
+	header http.Header
+	body   string
+}
+
+func recorder(t *testing.T, status int) (*httptest.Server, func() []request) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, request{path: r.URL.Path, header: r.Header.Clone(), body: string(b)})
+		mu.Unlock()
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"status":1}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]request(nil), got...)
+	}
+}
+
+func TestSend_Ntfy(t *testing.T) {
+	srv, got := recorder(t, http.StatusOK)
+	c := New(config.PushConfig{
+		Title: "Daily",
+		Ntfy:  config.NtfyConfig{Server: srv.URL, Topic: "alerts", Token: "tk", Priority: "high"},
+	})
+	if err := c.Send(context.Background(), bus.OutboundMessage{Channel: "push", ChatID: "ntfy", Content: "**done**"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send(context.Background(), bus.OutboundMessage{Channel: "push", ChatID: "ntfy:other", Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	reqs := got()
+	if len(reqs) != 2 {
+		t.Fatalf("requests: %d", len(reqs))
+	}
+	r := reqs[0]
+	if r.path != "/alerts" || r.body != "**done**" {
+		t.Fatalf("first request: %+v", r)
+	}
+	if r.header.Get("Title") != "Daily" || r.header.Get("Authorization") != "Bearer tk" || r.header.Get("Priority") != "high" || r.header.Get("Markdown") != "yes" {
+		t.Fatalf("headers: %v", r.header)
+	}
+	if reqs[1].path != "/other" {
+		t.Fatalf("topic override: %q", reqs[1].path)
+	}
+}
+
+func TestSend_Pushover(t *testing.T) {
+	srv, got := recorder(t, http.StatusOK)
+	c := New(config.PushConfig{
+		Pushover: config.PushoverConfig{AppToken: "app", UserKey: "user", Device: "phone", APIBaseURL: srv.URL},
+	})
+	long := strings.Repeat("あ", pushoverMaxRunes+10)
+	if err := c.Send(context.Background(), bus.OutboundMessage{Channel: "push", ChatID: "pushover", Content: long}); err != nil {
+		t.Fatal(err)
+	}
+	reqs := got()
+	if len(reqs) != 1 || reqs[0].path != "/messages.json" {
+		t.Fatalf("requests: %+v", reqs)
+	}
+	form, err := url.ParseQuery(reqs[0].body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form.Get("token") != "app" || form.Get("user") != "user" || form.Get("device") != "phone" || form.Get("title") != config.DefaultPushTitle {
+		t.Fatalf("form: %v", form)
+	}
+	if n := utf8.RuneCountInString(form.Get("message")); n != pushoverMaxRunes {
+		t.Fatalf("message runes: %d", n)
+	}
+}
+
+func TestSend_DefaultFansOut(t *testing.T) {
+	srv, got := recorder(t, http.StatusOK)
+	c := New(config.PushConfig{
+		Ntfy:     config.NtfyConfig{Server: srv.URL, Topic: "alerts"},
+		Pushover: config.PushoverConfig{AppToken: "app", UserKey: "user", APIBaseURL: srv.URL},
+	})
+	if err := c.Send(context.Background(), bus.OutboundMessage{Channel: "push", ChatID: "default", Content: "report"}); err != nil {
+		t.Fatal(err)
+	}
+	if reqs := got(); len(reqs) != 2 {
+		t.Fatalf("requests: %+v", reqs)
+	}
+	if err := c.Send(context.Background(), bus.OutboundMessage{Channel: "push", ChatID: "email", Content: "x"}); err == nil {
+		t.Fatal("expected error for unknown chat_id")
+	}
+}
+
+func TestSend_HTTPError(t *testing.T) {
+	srv, _ := recorder(t, http.StatusBadRequest)
+	c := New(config.PushConfig{Ntfy: config.NtfyConfig{Server: srv.URL, Topic: "alerts"}})
+	err := c.Send(context.Background(), bus.OutboundMessage{Channel: "push", ChatID: "ntfy", Content: "x"})
+	if err == nil || !strings.Contains(err.Error(), "http 400") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestTruncateBytes(t *testing.T) {
+	s := strings.Repeat("é", 10) // 2 bytes each
+	got := truncateBytes(s, 8)
+	if !utf8.ValidString(got) || len(got) > 8 || !strings.HasSuffix(got, truncationIndicator) {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,53 @@
+package channels
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// Quote policies, as named in each channel's "quote" setting. They decide
+// which message a reply quotes (answers as a reply in the chat app).
+const (
+	// QuoteAuto keeps the channel's usual behavior: the reply quotes what
+	// the channel put in the inbound message's Delivery.ReplyToID.
+	QuoteAuto = "auto"
+	// QuoteAlways quotes the message being answered.
+	QuoteAlways = "always"
+	// QuoteGroups quotes the message being answered in groups only.
+	QuoteGroups = "groups"
+	// QuoteNever sends plain messages.
+	QuoteNever = "never"
+)
+
+// ValidateQuote rejects an unknown "quote" setting.
+func ValidateQuote(quote string) error {
+	switch strings.ToLower(strings.TrimSpace(quote)) {
+	case "", QuoteAuto, QuoteAlways, QuoteGroups, QuoteNever:
+		return nil
+	}
+	return fmt.Errorf("unknown quote policy %q (use %q, %q, %q or %q)", quote, QuoteAuto, QuoteAlways, QuoteGroups, QuoteNever)
+}
+
+// ReplyTarget is the ID of the message a reply should quote under quote, or
+// "". An explicit msg.ReplyTo is kept whatever the policy.
+func ReplyTarget(quote string, msg bus.OutboundMessage) string {
+	d := msg.Delivery
+	var target string
+	switch strings.ToLower(strings.TrimSpace(quote)) {
+	case QuoteAlways:
+		target = d.MessageID
+	case QuoteGroups:
+		if !d.IsDirect {
+			target = d.MessageID
+		}
+	case QuoteNever:
+	default:
+		target = d.ReplyToID
+	}
+	if target = strings.TrimSpace(target); target != "" {
+		return target
+	}
+	return strings.TrimSpace(msg.ReplyTo)
+}
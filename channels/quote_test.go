@@ -0,0 +1,34 @@
+package channels
+
+import (
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+func TestReplyTarget(t *testing.T) {
+	group := bus.OutboundMessage{Delivery: bus.Delivery{MessageID: "7", ReplyToID: "3"}}
+	direct := bus.OutboundMessage{Delivery: bus.Delivery{MessageID: "7", ReplyToID: "3", IsDirect: true}}
+	tests := []struct {
+		quote string
+		msg   bus.OutboundMessage
+		want  string
+	}{
+		{"", group, "3"},
+		{QuoteAuto, direct, "3"},
+		{QuoteAlways, direct, "7"},
+		{QuoteGroups, group, "7"},
+		{QuoteGroups, direct, ""},
+		{QuoteNever, group, ""},
+		{QuoteNever, bus.OutboundMessage{ReplyTo: "9"}, "9"},
+		{"Always", bus.OutboundMessage{}, ""},
+	}
+	for _, tt := range tests {
+		if got := ReplyTarget(tt.quote, tt.msg); got != tt.want {
+			t.Errorf("ReplyTarget(%q, %+v) = %q, want %q", tt.quote, tt.msg.Delivery, got, tt.want)
+		}
+	}
+	if err := ValidateQuote("sometimes"); err == nil {
+		t.Error("ValidateQuote accepted an unknown policy")
+	}
+}
@@ -0,0 +1,127 @@
+package channels
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit caps the outbound messages of one channel, overall and per
+// chat. A zero rate is unlimited; a zero burst allows one second's worth.
+type RateLimit struct {
+	PerSecond        float64
+	Burst            int
+	PerChatPerSecond float64
+	PerChatBurst     int
+}
+
+// maxIdleBuckets is how many chat buckets are kept before full (idle) ones
+// are dropped.
+const maxIdleBuckets = 1024
+
+// RateLimiter spaces outbound messages with token buckets, one per channel
+// and one per chat.
+type RateLimiter struct {
+	mu      sync.Mutex
+	limits  map[string]RateLimit
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{limits: limits, buckets: map[string]*bucket{}, now: time.Now}
+}
+
+// Wait blocks until a message to chatID on channel may be sent.
+func (r *RateLimiter) Wait(ctx context.Context, channel, chatID string) error {
+	d, _ := r.reserve(channel, chatID, false)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Allow reports whether a message may be sent now, taking a token only if
+// so.
+func (r *RateLimiter) Allow(channel, chatID string) bool {
+	_, ok := r.reserve(channel, chatID, true)
+	return ok
+}
+
+// reserve takes a token from the channel and chat buckets and returns how
+// long to wait before sending. With onlyNow it takes nothing and reports
+// false when there would be a wait.
+func (r *RateLimiter) reserve(channel, chatID string, onlyNow bool) (time.Duration, bool) {
+	if r == nil {
+		return 0, true
+	}
+	lim, ok := r.limits[channel]
+	if !ok {
+		return 0, true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	var bs []*bucket
+	if lim.PerSecond > 0 {
+		bs = append(bs, r.bucket(channel, lim.PerSecond, lim.Burst, now))
+	}
+	if lim.PerChatPerSecond > 0 {
+		bs = append(bs, r.bucket(channel+"\x00"+chatID, lim.PerChatPerSecond, lim.PerChatBurst, now))
+	}
+	var wait time.Duration
+	for _, b := range bs {
+		b.refill(now)
+		if b.tokens < 1 {
+			wait = max(wait, time.Duration((1-b.tokens)/b.rate*float64(time.Second)))
+		}
+	}
+	if onlyNow && wait > 0 {
+		return wait, false
+	}
+	for _, b := range bs {
+		b.tokens--
+	}
+	return wait, true
+}
+
+func (r *RateLimiter) bucket(key string, rate float64, burst int, now time.Time) *bucket {
+	if b, ok := r.buckets[key]; ok {
+		return b
+	}
+	if len(r.buckets) >= maxIdleBuckets {
+		for k, b := range r.buckets {
+			if b.refill(now); b.tokens >= b.burst {
+				delete(r.buckets, k)
+			}
+		}
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	b := &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+	r.buckets[key] = b
+	return b
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
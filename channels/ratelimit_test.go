@@ -0,0 +1,49 @@
+package channels
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	r := NewRateLimiter(map[string]RateLimit{
+		"telegram": {PerSecond: 10, PerChatPerSecond: 1, PerChatBurst: 2},
+	})
+	r.now = func() time.Time { return now }
+
+	if d, _ := r.reserve("matrix", "a", false); d != 0 {
+		t.Fatalf("unlimited channel waited %s", d)
+	}
+	for i := range 2 {
+		if !r.Allow("telegram", "a") {
+			t.Fatalf("burst message %d refused", i)
+		}
+	}
+	if r.Allow("telegram", "a") {
+		t.Fatal("third message within a second allowed")
+	}
+	if !r.Allow("telegram", "b") {
+		t.Fatal("other chat should have its own bucket")
+	}
+	if d, _ := r.reserve("telegram", "a", false); d != time.Second {
+		t.Fatalf("wait=%s, want 1s", d)
+	}
+	// The reservation above is paid back after a second, the next after two.
+	now = now.Add(time.Second)
+	if r.Allow("telegram", "a") {
+		t.Fatal("allowed while a reservation is pending")
+	}
+	now = now.Add(time.Second)
+	if !r.Allow("telegram", "a") {
+		t.Fatal("refused after the bucket refilled")
+	}
+
+	// The channel bucket caps all chats together.
+	for i := range 20 {
+		r.Allow("telegram", string(rune('c'+i)))
+	}
+	if d, _ := r.reserve("telegram", "z", false); d <= 0 {
+		t.Fatal("channel-wide limit not applied")
+	}
+}
@@ -0,0 +1,172 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// maxBlocks is Slack's limit of blocks in one message.
+	maxBlocks = 50
+	// maxHeaderText is Slack's limit for the text of a header block.
+	maxHeaderText = 150
+)
+
+var (
+	reBlockFence   = regexp.MustCompile("^\\s*```")
+	reBlockHeading = regexp.MustCompile(`^#{1,6}\s+(.+)$`)
+	reBlockRule    = regexp.MustCompile(`^\s*(?:-{3,}|\*{3,}|_{3,})\s*$`)
+
+	reMrkdwnInlineCode = regexp.MustCompile("`[^`\n]+`")
+	reMrkdwnLink       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	reMrkdwnBullet     = regexp.MustCompile(`(?m)^(\s*)[-*+]\s+`)
+	reMrkdwnBold       = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	reMrkdwnItalic     = regexp.MustCompile(`\*([^*\s][^*\n]*?)\*`)
+	reMrkdwnStrike     = regexp.MustCompile(`~~(.+?)~~`)
+	reMrkdwnQuote      = regexp.MustCompile(`(?m)^&gt;\s?`)
+)
+
+// slackMessage is one message of a reply. text is the fallback Slack shows
+// in notifications, and the whole message when blocks are rejected.
+type slackMessage struct {
+	text   string
+	blocks []slack.Block
+}
+
+func textMessage(text string) slackMessage {
+	return slackMessage{text: text, blocks: markdownBlocks(text)}
+}
+
+func (m slackMessage) options(withBlocks bool) []slack.MsgOption {
+	opts := []slack.MsgOption{slack.MsgOptionText(m.text, false)}
+	if withBlocks && len(m.blocks) > 0 {
+		opts = append(opts, slack.MsgOptionBlocks(m.blocks...))
+	}
+	return opts
+}
+
+// sendWithFallback sends m with its blocks, and as plain text if Slack
+// rejects them.
+func sendWithFallback(ctx context.Context, m slackMessage, send func(ctx context.Context, opts []slack.MsgOption) error) error {
+	err := send(ctx, m.options(true))
+	var serr slack.SlackErrorResponse
+	if len(m.blocks) > 0 && errors.As(err, &serr) && serr.Err == "invalid_blocks" {
+		return send(ctx, m.options(false))
+	}
+	return err
+}
+
+// markdownBlocks renders the agent's markdown as Block Kit: headings become
+// header blocks, fenced code a section of its own, "---" a divider and the
+// rest mrkdwn sections. It returns nil when the text needs more blocks than
+// a message holds; the text is then sent as it is.
+func markdownBlocks(text string) []slack.Block {
+	var blocks []slack.Block
+	var para, code []string
+	inCode := false
+	flushPara := func() {
+		if s := strings.TrimSpace(strings.Join(para, "\n")); s != "" {
+			for _, chunk := range chunkLines(toMrkdwn(s), maxSectionText) {
+				blocks = append(blocks, mrkdwnSection(chunk))
+			}
+		}
+		para = nil
+	}
+	flushCode := func() {
+		// Each chunk is fenced on its own; the fences count toward the limit.
+		for _, chunk := range chunkLines(escapeMrkdwn(strings.Join(code, "\n")), maxSectionText-8) {
+			blocks = append(blocks, mrkdwnSection("```\n"+chunk+"\n```"))
+		}
+		code = nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case reBlockFence.MatchString(line):
+			if inCode {
+				flushCode()
+			} else {
+				flushPara()
+			}
+			inCode = !inCode
+		case inCode:
+			code = append(code, line)
+		case reBlockHeading.MatchString(line):
+			flushPara()
+			heading := strings.TrimSpace(reBlockHeading.FindStringSubmatch(line)[1])
+			heading = strings.NewReplacer("**", "", "__", "", "`", "").Replace(heading)
+			if r := []rune(heading); len(r) > maxHeaderText {
+				heading = string(r[:maxHeaderText-1]) + "…"
+			}
+			blocks = append(blocks, slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, heading, false, false)))
+		case reBlockRule.MatchString(line):
+			flushPara()
+			blocks = append(blocks, slack.NewDividerBlock())
+		default:
+			para = append(para, line)
+		}
+	}
+	if inCode {
+		flushCode()
+	}
+	flushPara()
+	if len(blocks) > maxBlocks {
+		return nil
+	}
+	return blocks
+}
+
+func mrkdwnSection(text string) *slack.SectionBlock {
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)
+}
+
+// toMrkdwn converts markdown emphasis, links and lists to Slack's mrkdwn
+// and escapes &, < and > outside of them.
+func toMrkdwn(text string) string {
+	// Inline code is kept as it is, apart from escaping.
+	var codes []string
+	text = reMrkdwnInlineCode.ReplaceAllStringFunc(text, func(s string) string {
+		codes = append(codes, escapeMrkdwn(s))
+		return "\x00" + strconv.Itoa(len(codes)-1) + "\x00"
+	})
+	text = escapeMrkdwn(text)
+	text = reMrkdwnQuote.ReplaceAllString(text, "> ")
+	text = reMrkdwnLink.ReplaceAllString(text, "<$2|$1>")
+	text = reMrkdwnBullet.ReplaceAllString(text, "$1• ")
+	// Bold is marked with \x01 until single-star italics are converted.
+	text = reMrkdwnBold.ReplaceAllString(text, "\x01$1$2\x01")
+	text = reMrkdwnItalic.ReplaceAllString(text, "_${1}_")
+	text = strings.ReplaceAll(text, "\x01", "*")
+	text = reMrkdwnStrike.ReplaceAllString(text, "~$1~")
+	for i, c := range codes {
+		text = strings.Replace(text, "\x00"+strconv.Itoa(i)+"\x00", c, 1)
+	}
+	return text
+}
+
+func escapeMrkdwn(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// chunkLines cuts text into pieces of at most limit runes, at line breaks
+// where it can.
+func chunkLines(text string, limit int) []string {
+	var chunks []string
+	for len([]rune(text)) > limit {
+		r := []rune(text)
+		cut := strings.LastIndex(string(r[:limit]), "\n")
+		if cut <= 0 {
+			cut = len(string(r[:limit]))
+		}
+		chunks = append(chunks, strings.TrimRight(text[:cut], "\n"))
+		text = strings.TrimLeft(text[cut:], "\n")
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
@@ -0,0 +1,62 @@
+package slack
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestToMrkdwn(t *testing.T) {
+	for in, want := range map[string]string{
+		"**bold** and *italic*":        "*bold* and _italic_",
+		"~~gone~~ [docs](https://x.y)": "~gone~ <https://x.y|docs>",
+		"- one\n* two":                 "• one\n• two",
+		"a < b & `x<y **z**`":          "a &lt; b &amp; `x&lt;y **z**`",
+		"> quoted":                     "> quoted",
+		"2 * 3 * 4":                    "2 * 3 * 4",
+	} {
+		if got := toMrkdwn(in); got != want {
+			t.Fatalf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMarkdownBlocks(t *testing.T) {
+	blocks := markdownBlocks("# Result\nIt **worked**.\n\n---\n```go\nfmt.Println(\"<hi>\")\n```\nDone")
+	var types []string
+	for _, b := range blocks {
+		types = append(types, string(b.BlockType()))
+	}
+	if got := strings.Join(types, ","); got != "header,section,divider,section,section" {
+		t.Fatalf("blocks %s", got)
+	}
+	if got := blocks[1].(*slack.SectionBlock).Text.Text; got != "It *worked*." {
+		t.Fatalf("section %q", got)
+	}
+	if got := blocks[3].(*slack.SectionBlock).Text.Text; got != "```\nfmt.Println(\"&lt;hi&gt;\")\n```" {
+		t.Fatalf("code %q", got)
+	}
+
+	long := markdownBlocks(strings.Repeat("word ", maxSectionText))
+	for _, b := range long {
+		if n := len([]rune(b.(*slack.SectionBlock).Text.Text)); n > maxSectionText {
+			t.Fatalf("section of %d runes", n)
+		}
+	}
+}
+
+func TestSendWithFallback_PlainTextWhenBlocksRejected(t *testing.T) {
+	var calls int
+	err := sendWithFallback(context.Background(), textMessage("**hi**"), func(ctx context.Context, opts []slack.MsgOption) error {
+		calls++
+		if len(opts) == 2 {
+			return slack.SlackErrorResponse{Err: "invalid_blocks"}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("err=%v calls=%d", err, calls)
+	}
+}
@@ -0,0 +1,159 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/handover"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+const maxEventBodyBytes = 1 << 20
+
+func (c *Channel) eventsMode() bool {
+	return strings.EqualFold(strings.TrimSpace(c.cfg.Mode), config.SlackModeEvents)
+}
+
+// startEvents serves the Events API webhook. Requests are authenticated with
+// the signing secret and acknowledged before processing.
+func (c *Channel) startEvents(ctx context.Context) error {
+	if strings.TrimSpace(c.cfg.SigningSecret) == "" {
+		return fmt.Errorf("slack signingSecret is empty (required for mode=events)")
+	}
+	listen := strings.TrimSpace(c.cfg.Listen)
+	if listen == "" {
+		listen = config.DefaultSlackEventsListen
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	api := slack.New(strings.TrimSpace(c.cfg.BotToken), slack.OptionHTTPClient(c.hc))
+	c.mu.Lock()
+	c.api = api
+	c.cancel = cancel
+	c.mu.Unlock()
+	c.resolveBotUserID(runCtx, api)
+
+	if c.server != nil {
+		c.running.Store(true)
+		defer c.running.Store(false)
+		return c.server.Attach(runCtx, c.Name(), c.EventsHandler(runCtx), config.SlackEventsPath, config.SlackInteractivityPath)
+	}
+	ln, err := handover.Listen(listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           c.EventsHandler(runCtx),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	select {
+	case <-runCtx.Done():
+		shutdownCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+		defer stop()
+		_ = srv.Shutdown(shutdownCtx)
+		return runCtx.Err()
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+// EventsHandler returns the HTTP handler for the Events API request URL.
+func (c *Channel) EventsHandler(ctx context.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+config.SlackEventsPath, func(w http.ResponseWriter, r *http.Request) {
+		body, ok := c.verifiedBody(w, r)
+		if !ok {
+			return
+		}
+		ev, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if ev.Type == slackevents.URLVerification {
+			var ch slackevents.ChallengeResponse
+			if err := json.Unmarshal(body, &ch); err != nil {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(ch.Challenge))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		// Slack retries an event whose ack was slow or failed, e.g. with a
+		// 503 while the channel restarts; only the first one to arrive here
+		// is handled.
+		if cb, ok := ev.Data.(*slackevents.EventsAPICallbackEvent); ok && c.seen.Load().Seen(c.Name(), cb.EventID) {
+			return
+		}
+		go c.handleEvent(ctx, ev)
+	})
+	// Interactivity request URL: clicks on suggestion buttons.
+	mux.HandleFunc("POST "+config.SlackInteractivityPath, func(w http.ResponseWriter, r *http.Request) {
+		body, ok := c.verifiedBody(w, r)
+		if !ok {
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var cb slack.InteractionCallback
+		if err := json.Unmarshal([]byte(form.Get("payload")), &cb); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		go c.handleInteraction(ctx, cb)
+	})
+	return mux
+}
+
+// verifiedBody reads the request body and checks the Slack signature,
+// writing the error response itself when it returns false.
+func (c *Channel) verifiedBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEventBodyBytes))
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return nil, false
+	}
+	sv, err := slack.NewSecretsVerifier(r.Header, c.cfg.SigningSecret)
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return nil, false
+	}
+	if _, err := sv.Write(body); err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return nil, false
+	}
+	if err := sv.Ensure(); err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return nil, false
+	}
+	return body, true
+}
@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels/httpserver"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func signedSlackRequest(secret, body string, ts time.Time) *http.Request {
+	stamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + stamp + ":" + body))
+	req := httptest.NewRequest(http.MethodPost, config.SlackEventsPath, strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", stamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestEventsHandler_URLVerification(t *testing.T) {
+	c := New(config.SlackConfig{Mode: config.SlackModeEvents, SigningSecret: "s3cret"}, bus.New(1))
+	body := `{"type":"url_verification","token":"x","challenge":"abc123"}`
+	rec := httptest.NewRecorder()
+	c.EventsHandler(context.Background()).ServeHTTP(rec, signedSlackRequest("s3cret", body, time.Now()))
+	if rec.Code != http.StatusOK || rec.Body.String() != "abc123" {
+		t.Fatalf("unexpected response: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEventsHandler_RejectsBadSignature(t *testing.T) {
+	c := New(config.SlackConfig{Mode: config.SlackModeEvents, SigningSecret: "s3cret"}, bus.New(1))
+	body := `{"type":"url_verification","challenge":"abc123"}`
+	for name, req := range map[string]*http.Request{
+		"wrong secret": signedSlackRequest("other", body, time.Now()),
+		"stale":        signedSlackRequest("s3cret", body, time.Now().Add(-10*time.Minute)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c.EventsHandler(context.Background()).ServeHTTP(rec, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestEventsHandler_PublishesMessage(t *testing.T) {
+	b := bus.New(1)
+	c := New(config.SlackConfig{Mode: config.SlackModeEvents, SigningSecret: "s3cret", DM: &config.SlackDMConfig{Enabled: true}}, b)
+	body := `{"type":"event_callback","event":{"type":"message","user":"U1","channel":"D1","channel_type":"im","text":"hello","ts":"1.0"}}`
+	rec := httptest.NewRecorder()
+	c.EventsHandler(context.Background()).ServeHTTP(rec, signedSlackRequest("s3cret", body, time.Now()))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if msg.Content != "hello" || msg.ChatID != "D1" || !msg.Delivery.IsDirect {
+		t.Fatalf("unexpected inbound: %+v", msg)
+	}
+}
+
+func TestEventsHandler_SuggestionClick(t *testing.T) {
+	b := bus.New(1)
+	c := New(config.SlackConfig{Mode: config.SlackModeEvents, SigningSecret: "s3cret"}, b)
+	payload := `{"type":"block_actions","user":{"id":"U1"},"container":{"type":"message","channel_id":"C1","message_ts":"2.0","thread_ts":"1.0"},` +
+		`"actions":[{"action_id":"clawlet_suggestion_0","block_id":"clawlet_suggestions","type":"button","value":"Tell me more"}]}`
+	body := "payload=" + url.QueryEscape(payload)
+	req := signedSlackRequest("s3cret", body, time.Now())
+	req.URL.Path = config.SlackInteractivityPath
+	rec := httptest.NewRecorder()
+	c.EventsHandler(context.Background()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	// The default "mention" group policy accepts clicks on the bot's buttons.
+	if msg.Content != "Tell me more" || msg.ChatID != "C1" || msg.SenderID != "U1" || msg.Delivery.ThreadID != "1.0" {
+		t.Fatalf("unexpected inbound: %+v", msg)
+	}
+}
+
+func TestSuggestionMessages_SplitsLongText(t *testing.T) {
+	if got := suggestionMessages("hi", nil); len(got) != 1 || len(got[0].blocks) != 1 {
+		t.Fatalf("no suggestions: %+v", got)
+	}
+	if got := suggestionMessages("hi", []string{"More"}); len(got) != 1 || len(got[0].blocks) != 2 {
+		t.Fatalf("short text: %+v", got)
+	}
+	if got := suggestionMessages(strings.Repeat("x", maxSectionText+1), []string{"More"}); len(got) != 1 || len(got[0].blocks) != 3 {
+		t.Fatalf("long text: %+v", got)
+	}
+	if got := suggestionMessages(strings.Repeat("# h\n", maxBlocks+1), []string{"More"}); len(got) != 2 || got[0].blocks != nil {
+		t.Fatalf("too many blocks: %+v", got)
+	}
+}
+
+func TestEventsHandler_RetryAfter503PublishesOnce(t *testing.T) {
+	b := bus.New(4)
+	c := New(config.SlackConfig{Mode: config.SlackModeEvents, SigningSecret: "s3cret", DM: &config.SlackDMConfig{Enabled: true}}, b)
+	s := httpserver.New("127.0.0.1:0", "", "")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	attach := func() {
+		go func() { _ = s.Attach(ctx, c.Name(), c.EventsHandler(ctx), config.SlackEventsPath) }()
+		for range 100 {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, config.SlackEventsPath, nil))
+			if rec.Code != http.StatusServiceUnavailable && rec.Code != http.StatusNotFound {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("handler not attached")
+	}
+	deliver := func(retry string) int {
+		body := `{"type":"event_callback","event_id":"Ev1","event":{"type":"message","user":"U1","channel":"D1","channel_type":"im","text":"hello","ts":"1.0"}}`
+		req := signedSlackRequest("s3cret", body, time.Now())
+		if retry != "" {
+			req.Header.Set("X-Slack-Retry-Num", retry)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// The channel is restarting when the event first arrives.
+	attach()
+	s.Detach(c.Name())
+	if code := deliver(""); code != http.StatusServiceUnavailable {
+		t.Fatalf("first delivery: got %d, want 503", code)
+	}
+	attach()
+	for _, retry := range []string{"1", "2"} {
+		if code := deliver(retry); code != http.StatusOK {
+			t.Fatalf("retry %s: got %d", retry, code)
+		}
+	}
+
+	consumeCtx, stop := context.WithTimeout(context.Background(), time.Second)
+	defer stop()
+	if msg, err := b.ConsumeInbound(consumeCtx); err != nil || msg.Content != "hello" {
+		t.Fatalf("consume: %+v %v", msg, err)
+	}
+	consumeCtx, stop = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer stop()
+	if msg, err := b.ConsumeInbound(consumeCtx); err == nil {
+		t.Fatalf("published twice: %+v", msg)
+	}
+}
@@ -0,0 +1,131 @@
+package slack
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// Manifest is a Slack app manifest (https://api.slack.com/reference/manifests).
+type Manifest struct {
+	DisplayInformation ManifestDisplay  `json:"display_information"`
+	Features           ManifestFeatures `json:"features"`
+	OAuthConfig        ManifestOAuth    `json:"oauth_config"`
+	Settings           ManifestSettings `json:"settings"`
+}
+
+type ManifestDisplay struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type ManifestFeatures struct {
+	AppHome *ManifestAppHome `json:"app_home,omitempty"`
+	BotUser ManifestBotUser  `json:"bot_user"`
+}
+
+type ManifestAppHome struct {
+	HomeTabEnabled             bool `json:"home_tab_enabled"`
+	MessagesTabEnabled         bool `json:"messages_tab_enabled"`
+	MessagesTabReadOnlyEnabled bool `json:"messages_tab_read_only_enabled"`
+}
+
+type ManifestBotUser struct {
+	DisplayName  string `json:"display_name"`
+	AlwaysOnline bool   `json:"always_online"`
+}
+
+type ManifestOAuth struct {
+	Scopes ManifestScopes `json:"scopes"`
+}
+
+type ManifestScopes struct {
+	Bot []string `json:"bot"`
+}
+
+type ManifestSettings struct {
+	EventSubscriptions   ManifestEvents        `json:"event_subscriptions"`
+	Interactivity        ManifestInteractivity `json:"interactivity"`
+	OrgDeployEnabled     bool                  `json:"org_deploy_enabled"`
+	SocketModeEnabled    bool                  `json:"socket_mode_enabled"`
+	TokenRotationEnabled bool                  `json:"token_rotation_enabled"`
+}
+
+// ManifestInteractivity enables button clicks (follow-up suggestions).
+type ManifestInteractivity struct {
+	IsEnabled  bool   `json:"is_enabled"`
+	RequestURL string `json:"request_url,omitempty"`
+}
+
+type ManifestEvents struct {
+	RequestURL string   `json:"request_url,omitempty"`
+	BotEvents  []string `json:"bot_events"`
+}
+
+// BuildManifest returns the manifest matching what the channel consumes for
+// the given config: mentions, DMs, and (for open/allowlist policies or an
+// "open" group override) channel messages, plus the scopes needed to reply, react, and download and upload
+// files. The transport follows cfg.Mode (Socket Mode or an Events API
+// request URL).
+func BuildManifest(cfg config.SlackConfig, appName string) Manifest {
+	appName = strings.TrimSpace(appName)
+	if appName == "" {
+		appName = "clawlet"
+	}
+	scopes := []string{"app_mentions:read", "chat:write", "reactions:write", "files:read", "files:write"}
+	events := []string{"app_mention"}
+
+	dmEnabled := cfg.DM == nil || cfg.DM.Enabled
+	if dmEnabled {
+		scopes = append(scopes, "im:history", "mpim:history")
+		events = append(events, "message.im", "message.mpim")
+	}
+	if readsChannelMessages(cfg) {
+		scopes = append(scopes, "channels:history", "groups:history")
+		events = append(events, "message.channels", "message.groups")
+	}
+	slices.Sort(scopes)
+	slices.Sort(events)
+
+	m := Manifest{
+		DisplayInformation: ManifestDisplay{
+			Name:        appName,
+			Description: "Personal AI assistant powered by clawlet",
+		},
+		Features: ManifestFeatures{
+			BotUser: ManifestBotUser{DisplayName: appName, AlwaysOnline: true},
+		},
+		OAuthConfig: ManifestOAuth{Scopes: ManifestScopes{Bot: scopes}},
+		Settings: ManifestSettings{
+			EventSubscriptions: ManifestEvents{BotEvents: events},
+			Interactivity:      ManifestInteractivity{IsEnabled: true},
+			SocketModeEnabled:  true,
+		},
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg.Mode), config.SlackModeEvents) {
+		m.Settings.SocketModeEnabled = false
+		if base := strings.TrimRight(strings.TrimSpace(cfg.PublicURL), "/"); base != "" {
+			m.Settings.EventSubscriptions.RequestURL = base + config.SlackEventsPath
+			m.Settings.Interactivity.RequestURL = base + config.SlackInteractivityPath
+		}
+	}
+	if dmEnabled {
+		m.Features.AppHome = &ManifestAppHome{MessagesTabEnabled: true}
+	}
+	return m
+}
+
+func readsChannelMessages(cfg config.SlackConfig) bool {
+	switch strings.ToLower(strings.TrimSpace(cfg.GroupPolicy)) {
+	case channels.GroupPolicyOpen, channels.GroupPolicyAllowlist:
+		return true
+	}
+	for _, policy := range cfg.Groups {
+		if strings.EqualFold(strings.TrimSpace(policy), channels.GroupPolicyOpen) {
+			return true
+		}
+	}
+	return false
+}
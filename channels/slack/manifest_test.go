@@ -0,0 +1,51 @@
+package slack
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestBuildManifest_MatchesConfig(t *testing.T) {
+	t.Run("mention policy with dm", func(t *testing.T) {
+		m := BuildManifest(config.SlackConfig{GroupPolicy: "mention", DM: &config.SlackDMConfig{Enabled: true}}, "")
+		if m.DisplayInformation.Name != "clawlet" || !m.Settings.SocketModeEnabled {
+			t.Fatalf("unexpected manifest basics: %+v", m)
+		}
+		if !slices.Contains(m.Settings.EventSubscriptions.BotEvents, "message.im") {
+			t.Fatalf("expected DM events: %v", m.Settings.EventSubscriptions.BotEvents)
+		}
+		if slices.Contains(m.Settings.EventSubscriptions.BotEvents, "message.channels") {
+			t.Fatalf("mention policy should not subscribe to all channel messages")
+		}
+		if m.Features.AppHome == nil || !m.Features.AppHome.MessagesTabEnabled {
+			t.Fatalf("expected messages tab for DMs")
+		}
+	})
+	t.Run("open policy without dm", func(t *testing.T) {
+		m := BuildManifest(config.SlackConfig{GroupPolicy: "open", DM: &config.SlackDMConfig{Enabled: false}}, "bot")
+		if !slices.Contains(m.OAuthConfig.Scopes.Bot, "channels:history") {
+			t.Fatalf("expected channels:history scope: %v", m.OAuthConfig.Scopes.Bot)
+		}
+		if slices.Contains(m.OAuthConfig.Scopes.Bot, "im:history") {
+			t.Fatalf("dm scopes should be omitted when DMs are disabled")
+		}
+		if m.Features.AppHome != nil {
+			t.Fatalf("expected no app home when DMs are disabled")
+		}
+	})
+}
+
+func TestBuildManifest_EventsMode(t *testing.T) {
+	m := BuildManifest(config.SlackConfig{Mode: config.SlackModeEvents, PublicURL: "https://bot.example.com/"}, "")
+	if m.Settings.SocketModeEnabled {
+		t.Fatalf("expected socket mode disabled")
+	}
+	if m.Settings.EventSubscriptions.RequestURL != "https://bot.example.com/slack/events" {
+		t.Fatalf("unexpected request url: %q", m.Settings.EventSubscriptions.RequestURL)
+	}
+	if !m.Settings.Interactivity.IsEnabled || m.Settings.Interactivity.RequestURL != "https://bot.example.com/slack/interactivity" {
+		t.Fatalf("unexpected interactivity: %+v", m.Settings.Interactivity)
+	}
+}
@@ -0,0 +1,40 @@
+package slack
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/slack-go/slack"
+)
+
+// slackEmojiNames maps common Unicode emoji to Slack's emoji names; others
+// must be given by name, e.g. "white_check_mark" or ":tada:".
+var slackEmojiNames = map[string]string{
+	"👍":  "+1",
+	"👎":  "-1",
+	"❤️": "heart",
+	"😂":  "joy",
+	"😄":  "smile",
+	"🎉":  "tada",
+	"👀":  "eyes",
+	"✅":  "white_check_mark",
+	"🙏":  "pray",
+	"🔥":  "fire",
+	"🚀":  "rocket",
+	"🤔":  "thinking_face",
+}
+
+func (c *Channel) SupportsReactions() bool { return true }
+
+func sendReaction(ctx context.Context, api *slack.Client, ch string, r bus.Reaction) error {
+	return api.AddReactionContext(ctx, slackEmojiName(r.Emoji), slack.ItemRef{Channel: ch, Timestamp: strings.TrimSpace(r.MessageID)})
+}
+
+func slackEmojiName(emoji string) string {
+	emoji = strings.TrimSpace(emoji)
+	if name, ok := slackEmojiNames[emoji]; ok {
+		return name
+	}
+	return strings.Trim(emoji, ":")
+}
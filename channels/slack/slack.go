@@ -1,9 +1,12 @@
 package slack
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -11,6 +14,7 @@ import (
 
 	"github.com/mosaxiv/clawlet/bus"
 	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/channels/httpserver"
 	"github.com/mosaxiv/clawlet/config"
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
@@ -23,6 +27,7 @@ type Channel struct {
 	allow channels.AllowList
 
 	running atomic.Bool
+	streams channels.Streams
 
 	mu  sync.Mutex
 	api *slack.Client
@@ -31,25 +36,42 @@ type Channel struct {
 
 	botUserID string
 	cancel    context.CancelFunc
+	// server, when set, is the shared webhook server used in place of
+	// Listen in events mode.
+	server *httpserver.Server
+	// seen drops Events API events Slack delivers again.
+	seen atomic.Pointer[channels.Deduper]
 }
 
 func New(cfg config.SlackConfig, b *bus.Bus) *Channel {
 	hc := &http.Client{Timeout: 20 * time.Second}
-	return &Channel{
+	c := &Channel{
 		cfg:   cfg,
 		bus:   b,
 		allow: channels.AllowList{AllowFrom: cfg.AllowFrom},
 		hc:    hc,
 	}
+	c.seen.Store(channels.NewDeduper(0, ""))
+	return c
 }
 
+func (c *Channel) SetDeduper(d *channels.Deduper) { c.seen.Store(d) }
+
 func (c *Channel) Name() string    { return "slack" }
 func (c *Channel) IsRunning() bool { return c.running.Load() }
 
+// SetServer serves the Events API webhooks on s instead of on Listen.
+func (c *Channel) SetServer(s *httpserver.Server) { c.server = s }
+
+func (c *Channel) SupportsAttachments() bool { return true }
+
 func (c *Channel) Start(ctx context.Context) error {
 	if strings.TrimSpace(c.cfg.BotToken) == "" {
 		return fmt.Errorf("slack botToken is empty")
 	}
+	if c.eventsMode() {
+		return c.startEvents(ctx)
+	}
 	if strings.TrimSpace(c.cfg.AppToken) == "" {
 		return fmt.Errorf("slack appToken is empty")
 	}
@@ -71,12 +93,7 @@ func (c *Channel) Start(ctx context.Context) error {
 	c.cancel = cancel
 	c.mu.Unlock()
 
-	// Resolve bot user ID for mention stripping/dedup (best-effort).
-	if auth, err := api.AuthTestContext(runCtx); err == nil {
-		c.mu.Lock()
-		c.botUserID = strings.TrimSpace(auth.UserID)
-		c.mu.Unlock()
-	}
+	c.resolveBotUserID(runCtx, api)
 
 	c.running.Store(true)
 	defer c.running.Store(false)
@@ -85,6 +102,15 @@ func (c *Channel) Start(ctx context.Context) error {
 	return sm.RunContext(runCtx)
 }
 
+// resolveBotUserID looks up the bot user ID for mention stripping/dedup (best-effort).
+func (c *Channel) resolveBotUserID(ctx context.Context, api *slack.Client) {
+	if auth, err := api.AuthTestContext(ctx); err == nil {
+		c.mu.Lock()
+		c.botUserID = strings.TrimSpace(auth.UserID)
+		c.mu.Unlock()
+	}
+}
+
 func (c *Channel) Stop() error {
 	c.running.Store(false)
 	c.mu.Lock()
@@ -143,15 +169,12 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	if strings.TrimSpace(c.cfg.BotToken) == "" {
 		return fmt.Errorf("slack botToken is empty")
 	}
-	if strings.TrimSpace(c.cfg.AppToken) == "" {
-		return fmt.Errorf("slack appToken is empty")
-	}
 	ch := strings.TrimSpace(msg.ChatID)
 	if ch == "" {
 		return fmt.Errorf("chat_id is empty")
 	}
 	text := strings.TrimSpace(msg.Content)
-	if text == "" {
+	if text == "" && len(msg.Attachments) == 0 && msg.Kind != bus.MessageKindReaction {
 		return nil
 	}
 	c.mu.Lock()
@@ -175,16 +198,78 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 		c.mu.Unlock()
 	}
 
-	threadTS, direct := slackThreadMeta(msg)
-	opts := []slack.MsgOption{
-		slack.MsgOptionText(text, false),
+	if msg.Kind == bus.MessageKindReaction {
+		return sendReaction(ctx, api, ch, msg.Reaction)
 	}
+	threadTS, direct := slackThreadMeta(msg)
 	// Keep channel conversations in thread; DMs/MPIMs do not use thread_ts.
-	if threadTS != "" && !direct {
-		opts = append(opts, slack.MsgOptionTS(threadTS))
+	if direct {
+		threadTS = ""
+	}
+	if msg.Partial {
+		return c.sendPartial(ctx, api, ch, threadTS, msg.StreamID, text)
+	}
+	streamCh, streamTS := splitStreamRef(c.streams.Take(msg.StreamID))
+	if text != "" {
+		parts := channels.SplitterOrDefault(c.cfg.Split)(text, channels.MaxSlackText)
+		var msgs []slackMessage
+		for _, p := range parts[:len(parts)-1] {
+			msgs = append(msgs, textMessage(p))
+		}
+		msgs = append(msgs, suggestionMessages(parts[len(parts)-1], msg.Suggestions)...)
+		for i, m := range msgs {
+			send := func(ctx context.Context, opts []slack.MsgOption) error {
+				if threadTS != "" {
+					opts = append(opts, slack.MsgOptionTS(threadTS))
+				}
+				_, _, err := api.PostMessageContext(ctx, ch, opts...)
+				return err
+			}
+			if i == 0 && streamTS != "" {
+				send = func(ctx context.Context, opts []slack.MsgOption) error {
+					_, _, _, err := api.UpdateMessageContext(ctx, streamCh, streamTS, opts...)
+					return err
+				}
+			}
+			if err := sendWithFallback(ctx, m, send); err != nil {
+				return err
+			}
+		}
+	}
+	return uploadSlackFiles(ctx, api, ch, threadTS, msg.Attachments)
+}
+
+// uploadSlackFiles shares each attachment in the conversation with the
+// files.getUploadURLExternal / files.completeUploadExternal flow.
+func uploadSlackFiles(ctx context.Context, api *slack.Client, ch, threadTS string, atts []bus.Attachment) error {
+	for _, a := range atts {
+		data, err := a.ReadData()
+		if err != nil {
+			return fmt.Errorf("slack attachment %s: %w", a.Name, err)
+		}
+		name := strings.TrimSpace(a.Name)
+		if name == "" {
+			name = "file"
+			if a.LocalPath != "" {
+				name = filepath.Base(a.LocalPath)
+			}
+		}
+		if len(data) == 0 {
+			return fmt.Errorf("slack attachment %s: empty file", name)
+		}
+		_, err = api.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+			Reader:          bytes.NewReader(data),
+			FileSize:        len(data),
+			Filename:        name,
+			Title:           name,
+			Channel:         ch,
+			ThreadTimestamp: threadTS,
+		})
+		if err != nil {
+			return fmt.Errorf("slack upload %s: %w", name, err)
+		}
 	}
-	_, _, err := api.PostMessageContext(ctx, ch, opts...)
-	return err
+	return nil
 }
 
 func (c *Channel) runSocketEventLoop(ctx context.Context, sm *socketmode.Client) {
@@ -196,6 +281,15 @@ func (c *Channel) runSocketEventLoop(ctx context.Context, sm *socketmode.Client)
 			if !ok {
 				return
 			}
+			if evt.Type == socketmode.EventTypeInteractive {
+				if evt.Request != nil {
+					sm.Ack(*evt.Request)
+				}
+				if cb, ok := evt.Data.(slack.InteractionCallback); ok {
+					go c.handleInteraction(ctx, cb)
+				}
+				continue
+			}
 			if evt.Type != socketmode.EventTypeEventsAPI {
 				continue
 			}
@@ -300,11 +394,6 @@ func (c *Channel) allowedByPolicy(eventType, chatID, channelType, text string) b
 		return true
 	}
 
-	policy := strings.ToLower(strings.TrimSpace(c.cfg.GroupPolicy))
-	if policy == "" {
-		policy = "mention"
-	}
-
 	// Avoid double-processing: for mentions in channels Slack often sends both `message` and `app_mention`.
 	c.mu.Lock()
 	botID := strings.TrimSpace(c.botUserID)
@@ -313,23 +402,11 @@ func (c *Channel) allowedByPolicy(eventType, chatID, channelType, text string) b
 		return false
 	}
 
-	switch policy {
-	case "open":
-		return true
-	case "allowlist":
-		for _, v := range c.cfg.GroupAllowFrom {
-			if strings.TrimSpace(v) == chatID {
-				return true
-			}
-		}
-		return false
-	case "mention":
-		// Respond only to explicit app mentions.
-		return eventType == "app_mention"
-	default:
-		// Fail closed on unknown policy.
-		return false
-	}
+	// Under "mention", respond only to explicit app mentions (clicking one
+	// of the bot's suggestion buttons is addressed to the bot too).
+	addressed := eventType == "app_mention" || eventType == suggestionEventType
+	groups := channels.GroupPolicy{Policy: c.cfg.GroupPolicy, AllowFrom: c.cfg.GroupAllowFrom, Groups: c.cfg.Groups}
+	return groups.Allows(addressed, chatID)
 }
 
 func (c *Channel) stripBotMention(text string) string {
@@ -368,9 +445,14 @@ func buildSlackDelivery(ts, threadTS, channelType string) bus.Delivery {
 	if threadTS == "" {
 		threadTS = ts
 	}
-	return bus.Delivery{
+	d := bus.Delivery{
 		MessageID: ts,
 		ThreadID:  threadTS,
 		IsDirect:  channelType == "im" || channelType == "mpim",
 	}
+	// ts is the Unix time the message was sent, e.g. "1712345678.123456".
+	if sec, err := strconv.ParseFloat(ts, 64); err == nil && sec > 0 {
+		d.SentAt = time.Unix(0, int64(sec*float64(time.Second)))
+	}
+	return d
 }
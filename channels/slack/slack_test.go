@@ -1,6 +1,11 @@
 package slack
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/mosaxiv/clawlet/bus"
@@ -148,3 +153,69 @@ func TestSlackInboundAttachments(t *testing.T) {
 		t.Fatalf("missing url")
 	}
 }
+
+func TestUploadSlackFiles(t *testing.T) {
+	var uploaded, completed []string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/files.getUploadURLExternal":
+			_ = r.ParseForm()
+			_, _ = fmt.Fprintf(w, `{"ok":true,"upload_url":%q,"file_id":"F1"}`, srv.URL+"/upload/"+r.Form.Get("filename"))
+		case "/upload/report.pdf":
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("upload: %v", err)
+				return
+			}
+			b, _ := io.ReadAll(f)
+			uploaded = append(uploaded, string(b))
+		case "/files.completeUploadExternal":
+			_ = r.ParseForm()
+			completed = append(completed, r.Form.Get("channel_id")+"|"+r.Form.Get("thread_ts"))
+			_, _ = io.WriteString(w, `{"ok":true,"files":[{"id":"F1","title":"report.pdf"}]}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	api := slack.New("xoxb-test", slack.OptionAPIURL(srv.URL+"/"))
+	err := uploadSlackFiles(context.Background(), api, "C1", "123.45", []bus.Attachment{
+		{Name: "report.pdf", Data: []byte("pdf")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uploaded) != 1 || uploaded[0] != "pdf" {
+		t.Fatalf("uploaded=%v", uploaded)
+	}
+	if len(completed) != 1 || completed[0] != "C1|123.45" {
+		t.Fatalf("completed=%v", completed)
+	}
+}
+
+func TestAllowedByPolicy_GroupOverride(t *testing.T) {
+	c := &Channel{}
+	c.cfg.GroupPolicy = "mention"
+	c.cfg.Groups = map[string]string{"C123": "open", "C456": "disabled"}
+
+	if !c.allowedByPolicy("message", "C123", "channel", "hi") {
+		t.Fatal("expected open override to allow message")
+	}
+	if c.allowedByPolicy("app_mention", "C456", "channel", "<@U1> hi") {
+		t.Fatal("expected disabled override to deny mention")
+	}
+	if !readsChannelMessages(c.cfg) {
+		t.Fatal("expected open override to subscribe to channel messages")
+	}
+}
+
+func TestSlackEmojiName(t *testing.T) {
+	for in, want := range map[string]string{"👍": "+1", ":tada:": "tada", "white_check_mark": "white_check_mark"} {
+		if got := slackEmojiName(in); got != want {
+			t.Fatalf("slackEmojiName(%q)=%q, want %q", in, got, want)
+		}
+	}
+}
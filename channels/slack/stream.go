@@ -0,0 +1,52 @@
+package slack
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/slack-go/slack"
+)
+
+func (c *Channel) SupportsStreaming() bool { return true }
+
+// sendPartial posts the text so far of a streamed reply and updates that
+// message as the reply grows. Send finishes it with the final message.
+func (c *Channel) sendPartial(ctx context.Context, api *slack.Client, ch, threadTS, streamID, text string) error {
+	if text == "" {
+		return nil
+	}
+	text = channels.PartialText(text, channels.MaxSlackText)
+	ref, shown, ok := c.streams.Get(streamID)
+	if ok && shown == text {
+		return nil
+	}
+	if ok {
+		postedCh, ts := splitStreamRef(ref, true)
+		if _, _, _, err := api.UpdateMessageContext(ctx, postedCh, ts, slack.MsgOptionText(text, false)); err != nil {
+			return err
+		}
+		c.streams.Set(streamID, ref, text)
+		return nil
+	}
+	opts := []slack.MsgOption{slack.MsgOptionText(text, false)}
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS))
+	}
+	postedCh, ts, err := api.PostMessageContext(ctx, ch, opts...)
+	if err != nil {
+		return err
+	}
+	// Updates need the conversation ID, which differs from a user ID chat.
+	c.streams.Set(streamID, postedCh+":"+ts, text)
+	return nil
+}
+
+// splitStreamRef splits the "channel:ts" a streamed message is kept as.
+func splitStreamRef(ref string, ok bool) (ch, ts string) {
+	if !ok {
+		return "", ""
+	}
+	ch, ts, _ = strings.Cut(ref, ":")
+	return ch, ts
+}
@@ -0,0 +1,105 @@
+package slack
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// suggestionBlockID identifies the actions block holding follow-up
+	// buttons; each button's value is the text to send when clicked.
+	suggestionBlockID      = "clawlet_suggestions"
+	suggestionActionPrefix = "clawlet_suggestion_"
+	// suggestionEventType is the publishInbound event type for clicks.
+	suggestionEventType = "suggestion"
+	// maxSectionText is Slack's limit for the text of a section block.
+	maxSectionText = 3000
+)
+
+func suggestionActions(items []string) *slack.ActionBlock {
+	var buttons []slack.BlockElement
+	for i, it := range items {
+		if it = strings.TrimSpace(it); it == "" {
+			continue
+		}
+		buttons = append(buttons, slack.NewButtonBlockElement(
+			suggestionActionPrefix+strconv.Itoa(i),
+			it,
+			slack.NewTextBlockObject(slack.PlainTextType, it, false, false),
+		))
+	}
+	if len(buttons) == 0 {
+		return nil
+	}
+	return slack.NewActionBlock(suggestionBlockID, buttons...)
+}
+
+// suggestionMessages returns text followed by suggestion buttons: one
+// message when the buttons fit after the text's blocks, otherwise the text
+// and a separate message with the buttons.
+func suggestionMessages(text string, items []string) []slackMessage {
+	m := textMessage(text)
+	actions := suggestionActions(items)
+	if actions == nil {
+		return []slackMessage{m}
+	}
+	if len(m.blocks) > 0 && len(m.blocks) < maxBlocks {
+		m.blocks = append(m.blocks, actions)
+		return []slackMessage{m}
+	}
+	return []slackMessage{m, {text: "Suggested follow-ups", blocks: []slack.Block{actions}}}
+}
+
+// handleInteraction sends a clicked suggestion as the user's next message
+// and removes the buttons so it is not sent twice.
+func (c *Channel) handleInteraction(ctx context.Context, cb slack.InteractionCallback) {
+	if cb.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+	for _, a := range cb.ActionCallback.BlockActions {
+		if a == nil || !strings.HasPrefix(a.ActionID, suggestionActionPrefix) {
+			continue
+		}
+		ch := cb.Container.ChannelID
+		if ch == "" {
+			ch = cb.Channel.ID
+		}
+		channelType := ""
+		if strings.HasPrefix(ch, "D") {
+			channelType = "im"
+		}
+		threadTS := cb.Container.ThreadTs
+		if threadTS == "" {
+			threadTS = cb.Message.ThreadTimestamp
+		}
+		c.removeSuggestions(ctx, ch, cb.Message)
+		c.publishInbound(ctx, suggestionEventType, cb.User.ID, ch, channelType, "", threadTS, a.Value, nil)
+		return
+	}
+}
+
+func (c *Channel) removeSuggestions(ctx context.Context, ch string, msg slack.Message) {
+	c.mu.Lock()
+	api := c.api
+	c.mu.Unlock()
+	if api == nil || msg.Timestamp == "" {
+		return
+	}
+	var kept []slack.Block
+	for _, b := range msg.Blocks.BlockSet {
+		if a, ok := b.(*slack.ActionBlock); ok && a.BlockID == suggestionBlockID {
+			continue
+		}
+		kept = append(kept, b)
+	}
+	opts := []slack.MsgOption{slack.MsgOptionText(msg.Text, false)}
+	if len(kept) > 0 {
+		opts = append(opts, slack.MsgOptionBlocks(kept...))
+	} else {
+		opts = append(opts, slack.MsgOptionBlocks())
+	}
+	_, _, _, _ = api.UpdateMessageContext(ctx, ch, msg.Timestamp, opts...)
+}
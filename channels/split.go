@@ -0,0 +1,195 @@
+package channels
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message length limits, in characters, of the chat apps.
+const (
+	MaxTelegramText = 4096
+	MaxDiscordText  = 2000
+	MaxWhatsAppText = 4096
+	MaxSlackText    = 40000
+)
+
+// Splitter cuts a reply into parts of at most limit runes. Channels call
+// one when a reply is longer than the chat app allows.
+type Splitter func(text string, limit int) []string
+
+// Split strategies, as named in each channel's "split" setting.
+const (
+	SplitStrategyParagraphs = "paragraphs"
+	SplitStrategySentences  = "sentences"
+	SplitStrategyNumbered   = "numbered"
+)
+
+// SplitterFor returns the Splitter of a strategy; "" is SplitStrategyParagraphs.
+func SplitterFor(name string) (Splitter, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", SplitStrategyParagraphs:
+		return SplitMessage, nil
+	case SplitStrategySentences:
+		return SplitSentences, nil
+	case SplitStrategyNumbered:
+		return SplitNumbered, nil
+	default:
+		return nil, fmt.Errorf("unknown split strategy %q (use %q, %q or %q)", name, SplitStrategyParagraphs, SplitStrategySentences, SplitStrategyNumbered)
+	}
+}
+
+// SplitterOrDefault is SplitterFor falling back to SplitMessage for an
+// unknown name; the gateway rejects those at startup.
+func SplitterOrDefault(name string) Splitter {
+	s, err := SplitterFor(name)
+	if err != nil {
+		return SplitMessage
+	}
+	return s
+}
+
+// SplitMessage cuts text into parts of at most limit runes. It cuts at a
+// paragraph break, else a line break, else a space, in the second half of
+// the part. A ``` code block that spans a cut is closed at the end of the
+// part and reopened at the start of the next, so each part renders on its
+// own.
+func SplitMessage(text string, limit int) []string {
+	return split(text, limit, splitPoint)
+}
+
+// SplitSentences is SplitMessage cutting after the last full sentence that
+// fits, however short the part gets, so no sentence is broken in two.
+func SplitSentences(text string, limit int) []string {
+	return split(text, limit, sentencePoint)
+}
+
+// SplitNumbered is SplitMessage with each part prefixed "1/3 ", "2/3 " and
+// so on. A reply that fits in one part is not numbered.
+func SplitNumbered(text string, limit int) []string {
+	reserve := len("1/1 ")
+	for {
+		parts := SplitMessage(text, max(limit-reserve, 1))
+		if len(parts) == 1 {
+			return SplitMessage(text, limit)
+		}
+		n := len(parts)
+		if need := len(fmt.Sprintf("%d/%d ", n, n)); need > reserve {
+			reserve = need
+			continue
+		}
+		for i, p := range parts {
+			sep := " "
+			if strings.HasPrefix(p, "```") {
+				// A fence has to start its line.
+				sep = "\n"
+			}
+			parts[i] = fmt.Sprintf("%d/%d", i+1, n) + sep + p
+		}
+		return parts
+	}
+}
+
+func split(text string, limit int, point func([]rune) (int, int)) []string {
+	r := []rune(strings.TrimSpace(text))
+	if len(r) == 0 {
+		return nil
+	}
+	var out []string
+	fence := "" // opening line of the code block the rest starts in
+	for {
+		head := ""
+		if fence != "" {
+			head = fence + "\n"
+		}
+		if len([]rune(head))+len(r) <= limit {
+			out = append(out, head+string(r))
+			return out
+		}
+		budget := max(limit-len([]rune(head)), 1)
+		cut, skip, part, next := cutPart(r, budget, fence, point)
+		if next != "" && len([]rune(head+part))+len(fenceClose) > limit && budget > len(fenceClose) {
+			// Leave room to close the code block.
+			cut, skip, part, next = cutPart(r, budget-len(fenceClose), fence, point)
+		}
+		if next != "" {
+			part += fenceClose
+		}
+		if strings.TrimSpace(part) != "" {
+			out = append(out, head+part)
+		}
+		r = r[cut+skip:]
+		if next == "" {
+			r = []rune(strings.TrimLeft(string(r), " \n"))
+		} else {
+			r = []rune(strings.TrimLeft(string(r), "\n"))
+		}
+		fence = next
+		if len(r) == 0 {
+			return out
+		}
+	}
+}
+
+const fenceClose = "\n```"
+
+// cutPart cuts the first part of at most budget runes off r and returns the
+// cut, the separator runes to drop, the part and the code block open at its
+// end.
+func cutPart(r []rune, budget int, fence string, point func([]rune) (int, int)) (cut, skip int, part, next string) {
+	cut, skip = point(r[:budget])
+	part = strings.TrimRight(string(r[:cut]), " \t\n")
+	return cut, skip, part, openFence(part, fence)
+}
+
+// splitPoint returns where to cut w and how many separator runes to drop.
+func splitPoint(w []rune) (cut, skip int) {
+	half := len(w) / 2
+	for i := len(w) - 2; i > half; i-- {
+		if w[i] == '\n' && w[i+1] == '\n' {
+			return i, 2
+		}
+	}
+	for _, sep := range []rune{'\n', ' '} {
+		for i := len(w) - 1; i > half; i-- {
+			if w[i] == sep {
+				return i, 1
+			}
+		}
+	}
+	return len(w), 0
+}
+
+// sentencePoint cuts w after its last sentence end or paragraph break,
+// falling back to splitPoint when there is none.
+func sentencePoint(w []rune) (cut, skip int) {
+	for i := len(w) - 1; i > 0; i-- {
+		switch w[i] {
+		case '。', '！', '？':
+			return i + 1, 0
+		case '.', '!', '?':
+			if i+1 == len(w) || w[i+1] == ' ' || w[i+1] == '\n' {
+				return i + 1, 0
+			}
+		case '\n':
+			if w[i-1] == '\n' {
+				return i - 1, 2
+			}
+		}
+	}
+	return splitPoint(w)
+}
+
+// openFence returns the opening line of the code block still open at the
+// end of part, given the block open at its start ("" for none).
+func openFence(part, fence string) string {
+	for line := range strings.SplitSeq(part, "\n") {
+		if l := strings.TrimSpace(line); strings.HasPrefix(l, "```") {
+			if fence == "" {
+				fence = l
+			} else {
+				fence = ""
+			}
+		}
+	}
+	return fence
+}
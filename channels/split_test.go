@@ -0,0 +1,109 @@
+package channels
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+	if got := SplitMessage("  short  ", 100); len(got) != 1 || got[0] != "short" {
+		t.Fatalf("short: %q", got)
+	}
+	if got := SplitMessage(" ", 100); got != nil {
+		t.Fatalf("empty: %q", got)
+	}
+
+	para := strings.Repeat("a", 30) + "\n\n" + strings.Repeat("b", 30) + "\n" + strings.Repeat("c", 10)
+	got := SplitMessage(para, 60)
+	if len(got) != 2 || got[0] != strings.Repeat("a", 30) || !strings.HasPrefix(got[1], "bbb") {
+		t.Fatalf("paragraph: %q", got)
+	}
+
+	words := strings.Repeat("word ", 100)
+	for _, p := range SplitMessage(words, 47) {
+		if utf8.RuneCountInString(p) > 47 || strings.HasPrefix(p, " ") || strings.HasSuffix(p, " ") {
+			t.Fatalf("word part %q", p)
+		}
+	}
+
+	if got := SplitMessage(strings.Repeat("x", 25), 10); len(got) != 3 || got[2] != "xxxxx" {
+		t.Fatalf("hard cut: %q", got)
+	}
+}
+
+func TestSplitMessage_CodeFence(t *testing.T) {
+	var lines []string
+	for range 20 {
+		lines = append(lines, "fmt.Println(1)")
+	}
+	text := "Here:\n\n```go\n" + strings.Join(lines, "\n") + "\n```\n\nDone."
+	parts := SplitMessage(text, 120)
+	if len(parts) < 3 {
+		t.Fatalf("parts=%q", parts)
+	}
+	for i, p := range parts {
+		if utf8.RuneCountInString(p) > 120 {
+			t.Fatalf("part %d too long: %d", i, utf8.RuneCountInString(p))
+		}
+		if strings.Count(p, "```")%2 != 0 {
+			t.Fatalf("part %d has an unbalanced fence:\n%s", i, p)
+		}
+	}
+	if !strings.HasPrefix(parts[1], "```go\n") {
+		t.Fatalf("fence not reopened:\n%s", parts[1])
+	}
+	if joined := strings.Join(parts, "\n"); strings.Count(joined, "fmt.Println(1)") != 20 || !strings.HasSuffix(joined, "Done.") {
+		t.Fatalf("content lost:\n%s", joined)
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	text := "One short sentence. Then a second, longer one! Is this the third? Last."
+	for _, p := range SplitSentences(text, 40) {
+		if utf8.RuneCountInString(p) > 40 {
+			t.Fatalf("part too long: %q", p)
+		}
+		if !strings.ContainsAny(p[len(p)-1:], ".!?") {
+			t.Fatalf("sentence broken: %q", p)
+		}
+	}
+	if got := SplitSentences("これは文です。次の文です。", 8); len(got) != 2 || got[0] != "これは文です。" {
+		t.Fatalf("cjk: %q", got)
+	}
+}
+
+func TestSplitNumbered(t *testing.T) {
+	if got := SplitNumbered("fits", 100); len(got) != 1 || got[0] != "fits" {
+		t.Fatalf("single part numbered: %q", got)
+	}
+	parts := SplitNumbered(strings.Repeat("word ", 300), 50)
+	n := len(parts)
+	if n < 10 {
+		t.Fatalf("parts=%d", n)
+	}
+	for i, p := range parts {
+		if utf8.RuneCountInString(p) > 50 {
+			t.Fatalf("part %d too long: %q", i, p)
+		}
+		if want := fmt.Sprintf("%d/%d ", i+1, n); !strings.HasPrefix(p, want) {
+			t.Fatalf("part %d: %q, want prefix %q", i, p, want)
+		}
+	}
+	code := SplitNumbered("```\n"+strings.Repeat("line\n", 30)+"```", 40)
+	if !strings.HasPrefix(code[0], "1/") || !strings.Contains(code[0], "\n```") {
+		t.Fatalf("fence after prefix: %q", code[0])
+	}
+}
+
+func TestSplitterFor(t *testing.T) {
+	for _, name := range []string{"", "paragraphs", "Sentences", "numbered"} {
+		if _, err := SplitterFor(name); err != nil {
+			t.Fatalf("%q: %v", name, err)
+		}
+	}
+	if _, err := SplitterFor("words"); err == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+}
@@ -0,0 +1,89 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// DefaultStaleQuiet is how long StaleNotices waits after the last expired
+// message of a chat before reporting them, so a backlog is reported once.
+const DefaultStaleQuiet = 5 * time.Second
+
+// StaleNotices collects the messages the bus skipped for being too old
+// (see bus.Bus.SetMaxAge) and reports them per chat: a log line, and with
+// notify a message telling the chat how many went unanswered.
+type StaleNotices struct {
+	quiet   time.Duration
+	publish func(context.Context, bus.OutboundMessage) error
+
+	mu    sync.Mutex
+	chats map[string]*staleChat
+}
+
+type staleChat struct {
+	channel string
+	chatID  string
+	count   int
+	oldest  time.Time
+	timer   *time.Timer
+}
+
+// NewStaleNotices returns StaleNotices that report a chat's skipped
+// messages once none have arrived for quiet (DefaultStaleQuiet when 0). A
+// nil publish only logs them.
+func NewStaleNotices(quiet time.Duration, publish func(context.Context, bus.OutboundMessage) error) *StaleNotices {
+	if quiet <= 0 {
+		quiet = DefaultStaleQuiet
+	}
+	return &StaleNotices{quiet: quiet, publish: publish, chats: map[string]*staleChat{}}
+}
+
+// Expired records msg as skipped; it fits bus.Bus.SetMaxAge.
+func (s *StaleNotices) Expired(msg bus.InboundMessage) {
+	key := msg.Channel + ":" + msg.ChatID
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.chats[key]
+	if c == nil {
+		c = &staleChat{channel: msg.Channel, chatID: msg.ChatID}
+		c.timer = time.AfterFunc(s.quiet, func() { s.flush(key) })
+		s.chats[key] = c
+	} else {
+		c.timer.Reset(s.quiet)
+	}
+	c.count++
+	if at := msg.Delivery.SentAt; c.oldest.IsZero() || at.Before(c.oldest) {
+		c.oldest = at
+	}
+}
+
+func (s *StaleNotices) flush(key string) {
+	s.mu.Lock()
+	c := s.chats[key]
+	delete(s.chats, key)
+	s.mu.Unlock()
+	if c == nil {
+		return
+	}
+	log.Printf("channels: skipped %d stale message(s) from %s (oldest sent %s ago)", c.count, key, time.Since(c.oldest).Round(time.Second))
+	if s.publish == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.publish(ctx, bus.OutboundMessage{Channel: c.channel, ChatID: c.chatID, Content: staleNotice(c.count)}); err != nil {
+		log.Printf("channels: stale message notice to %s failed: %v", key, err)
+	}
+}
+
+func staleNotice(n int) string {
+	if n == 1 {
+		return "A message arrived while I was offline and I skipped it. Please send it again if you still need an answer."
+	}
+	return fmt.Sprintf("%d messages arrived while I was offline and I skipped them. Please send again anything you still need an answer to.", n)
+}
@@ -0,0 +1,52 @@
+package channels
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+func TestStaleNotices_OnePerChat(t *testing.T) {
+	var mu sync.Mutex
+	var sent []bus.OutboundMessage
+	s := NewStaleNotices(20*time.Millisecond, func(_ context.Context, m bus.OutboundMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, m)
+		return nil
+	})
+	at := time.Now().Add(-time.Hour)
+	for range 3 {
+		s.Expired(bus.InboundMessage{Channel: "telegram", ChatID: "1", Delivery: bus.Delivery{SentAt: at}})
+	}
+	s.Expired(bus.InboundMessage{Channel: "telegram", ChatID: "2", Delivery: bus.Delivery{SentAt: at}})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(sent)
+		mu.Unlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 2 {
+		t.Fatalf("sent %d notices, want 2", len(sent))
+	}
+	for _, m := range sent {
+		want := "A message arrived"
+		if m.ChatID == "1" {
+			want = "3 messages arrived"
+		}
+		if !strings.HasPrefix(m.Content, want) {
+			t.Fatalf("notice to %s: %q", m.ChatID, m.Content)
+		}
+	}
+}
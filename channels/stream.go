@@ -0,0 +1,65 @@
+package channels
+
+import (
+	"sync"
+	"time"
+)
+
+// streamTTL drops a streamed reply whose final message never came.
+const streamTTL = 15 * time.Minute
+
+// Streams remembers, per OutboundMessage.StreamID, the message a streamed
+// reply is being edited into.
+type Streams struct {
+	mu   sync.Mutex
+	msgs map[string]streamMessage
+}
+
+type streamMessage struct {
+	id   string
+	text string
+	at   time.Time
+}
+
+// Get returns the ID of the message of streamID and the text it shows.
+func (s *Streams) Get(streamID string) (id, text string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.msgs[streamID]
+	return m.id, m.text, ok
+}
+
+// Set records that streamID shows text in message id.
+func (s *Streams) Set(streamID, id, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.msgs == nil {
+		s.msgs = map[string]streamMessage{}
+	}
+	for k, m := range s.msgs {
+		if now.Sub(m.at) > streamTTL {
+			delete(s.msgs, k)
+		}
+	}
+	s.msgs[streamID] = streamMessage{id: id, text: text, at: now}
+}
+
+// Take is Get, forgetting streamID; channels call it for the final message.
+func (s *Streams) Take(streamID string) (id string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.msgs[streamID]
+	delete(s.msgs, streamID)
+	return m.id, ok
+}
+
+// PartialText cuts the text of a partial message to limit runes, marking
+// the cut with an ellipsis; the final message is split as usual.
+func PartialText(text string, limit int) string {
+	r := []rune(text)
+	if len(r) <= limit {
+		return text
+	}
+	return string(r[:limit-1]) + "…"
+}
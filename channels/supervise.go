@@ -0,0 +1,109 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	// restartBackoffMin is the wait before restarting a channel that stopped
+	// with an error; it doubles with each failure in a row.
+	restartBackoffMin = 2 * time.Second
+	// restartBackoffMax caps the wait. A channel that ran this long before
+	// failing starts over from restartBackoffMin.
+	restartBackoffMax = 5 * time.Minute
+)
+
+// restartState counts a channel's automatic restarts.
+type restartState struct {
+	count int
+	last  time.Time
+	next  time.Time // when the pending restart is due; zero when none is
+}
+
+// start runs ch under a supervisor that restarts it with backoff when it
+// stops with an error. Starting a channel again replaces its supervisor.
+func (m *Manager) start(ctx context.Context, ch Channel) {
+	name := ch.Name()
+	m.setChannelError(name, "")
+	m.mu.Lock()
+	m.runs[name]++
+	run := m.runs[name]
+	if st := m.restarts[name]; st != nil {
+		st.next = time.Time{}
+	}
+	// done is closed when the Start below returns; Restart waits for it.
+	done := make(chan struct{})
+	m.starts[name] = done
+	m.mu.Unlock()
+	go m.supervise(ctx, ch, run, done)
+}
+
+func (m *Manager) supervise(ctx context.Context, ch Channel, run int, done chan struct{}) {
+	name := ch.Name()
+	wait := m.backoffMin
+	for {
+		started := time.Now()
+		err := ch.Start(ctx)
+		close(done)
+		// Context cancellation on shutdown is expected.
+		if err == nil || errors.Is(err, context.Canceled) || ctx.Err() != nil {
+			return
+		}
+		m.setChannelError(name, err.Error())
+		if time.Since(started) >= m.backoffMax {
+			wait = m.backoffMin
+		}
+		if !m.scheduleRestart(name, run, time.Now().Add(wait)) {
+			return
+		}
+		log.Printf("channels: %s stopped with error: %v; restarting in %s", name, err, wait)
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		if done = m.beginRestart(name, run); done == nil {
+			return
+		}
+		wait = min(wait*2, m.backoffMax)
+	}
+}
+
+// scheduleRestart records a pending restart, unless the manager stopped or
+// the channel was started again since run began.
+func (m *Manager) scheduleRestart(name string, run int, at time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.running || m.runs[name] != run {
+		return false
+	}
+	st := m.restarts[name]
+	if st == nil {
+		st = &restartState{}
+		m.restarts[name] = st
+	}
+	st.next = at
+	return true
+}
+
+// beginRestart counts the restart that is due, unless it was overtaken,
+// and returns the channel to close when its Start returns.
+func (m *Manager) beginRestart(name string, run int) chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.running || m.runs[name] != run {
+		return nil
+	}
+	st := m.restarts[name]
+	st.count++
+	st.last, st.next = time.Now(), time.Time{}
+	delete(m.lastErrorByChannel, name)
+	done := make(chan struct{})
+	m.starts[name] = done
+	return done
+}
@@ -0,0 +1,92 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// flakyChannel fails its first starts, then runs until stopped.
+type flakyChannel struct {
+	failures int32
+	starts   atomic.Int32
+	running  atomic.Bool
+}
+
+func (f *flakyChannel) Name() string { return "flaky" }
+
+func (f *flakyChannel) Start(ctx context.Context) error {
+	if f.starts.Add(1) <= f.failures {
+		return errors.New("gateway closed")
+	}
+	f.running.Store(true)
+	defer f.running.Store(false)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (f *flakyChannel) Stop() error { return nil }
+
+func (f *flakyChannel) Send(ctx context.Context, msg bus.OutboundMessage) error { return nil }
+
+func (f *flakyChannel) IsRunning() bool { return f.running.Load() }
+
+func TestManagerSupervise_RestartsWithBackoff(t *testing.T) {
+	m := NewManager(bus.New(1))
+	m.backoffMin, m.backoffMax = 10*time.Millisecond, 40*time.Millisecond
+	f := &flakyChannel{failures: 3}
+	m.Add(f)
+	if err := m.StartAll(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, time.Second, f.IsRunning)
+	row := m.Status()["flaky"]
+	if row["restarts"] != 3 || row["lastError"] != nil || row["restartAt"] != nil {
+		t.Fatalf("status %+v", row)
+	}
+	if _, ok := row["lastRestart"].(time.Time); !ok {
+		t.Fatalf("status %+v", row)
+	}
+}
+
+func TestManagerSupervise_StopsWithManager(t *testing.T) {
+	m := NewManager(bus.New(1))
+	m.backoffMin, m.backoffMax = 50*time.Millisecond, 50*time.Millisecond
+	f := &flakyChannel{failures: 100}
+	m.Add(f)
+	if err := m.StartAll(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, time.Second, func() bool { return m.Status()["flaky"]["restartAt"] != nil })
+	_ = m.StopAll()
+	time.Sleep(150 * time.Millisecond)
+	if n := f.starts.Load(); n != 1 {
+		t.Fatalf("started %d times after StopAll", n)
+	}
+}
+
+func TestManagerSupervise_RestartReplacesSupervisor(t *testing.T) {
+	m := NewManager(bus.New(1))
+	m.backoffMin, m.backoffMax = 50*time.Millisecond, 50*time.Millisecond
+	f := &flakyChannel{failures: 1}
+	m.Add(f)
+	if err := m.StartAll(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, time.Second, func() bool { return m.Status()["flaky"]["restartAt"] != nil })
+	if err := m.Restart("flaky"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, time.Second, f.IsRunning)
+	time.Sleep(100 * time.Millisecond)
+	if n := f.starts.Load(); n != 2 {
+		t.Fatalf("started %d times", n)
+	}
+	if row := m.Status()["flaky"]; row["restarts"] != 0 || row["restartAt"] != nil {
+		t.Fatalf("status %+v", row)
+	}
+}
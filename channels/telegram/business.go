@@ -0,0 +1,148 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/mosaxiv/clawlet/channels"
+)
+
+// Business chats are addressed as "business:<connection_id>:<chat_id>" so
+// they never collide with the same user's direct chat with the bot.
+const businessChatPrefix = "business:"
+
+type telegramTarget struct {
+	BusinessConnectionID string
+	ChatID               any
+	ThreadID             int // forum topic; 0 for none
+}
+
+func (c *Channel) businessEnabled() bool {
+	return c.cfg.Business != nil && c.cfg.Business.Enabled
+}
+
+func (c *Channel) allowedUpdates() tgbot.AllowedUpdates {
+	updates := tgbot.AllowedUpdates{
+		models.AllowedUpdateMessage,
+		models.AllowedUpdateEditedMessage,
+		models.AllowedUpdateCallbackQuery,
+		models.AllowedUpdateMessageReaction,
+		models.AllowedUpdatePoll,
+	}
+	if c.businessEnabled() {
+		updates = append(updates,
+			models.AllowedUpdateBusinessConnection,
+			models.AllowedUpdateBusinessMessage,
+		)
+	}
+	return updates
+}
+
+func (c *Channel) rememberBusinessConnection(conn *models.BusinessConnection) {
+	if conn == nil || strings.TrimSpace(conn.ID) == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.business == nil {
+		c.business = map[string]*models.BusinessConnection{}
+	}
+	c.business[conn.ID] = conn
+}
+
+func (c *Channel) businessConnection(ctx context.Context, b *tgbot.Bot, id string) (*models.BusinessConnection, error) {
+	c.mu.Lock()
+	conn := c.business[id]
+	c.mu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	conn, err := b.GetBusinessConnection(reqCtx, &tgbot.GetBusinessConnectionParams{BusinessConnectionID: id})
+	if err != nil {
+		return nil, redactTelegramError(err, c.cfg.Token)
+	}
+	c.rememberBusinessConnection(conn)
+	return conn, nil
+}
+
+// onBusinessMessage handles messages customers send to a connected Telegram
+// Business account. The owning account must be allowed by allowFrom and the
+// connection must grant reply rights; customers are filtered by business.allowFrom.
+func (c *Channel) onBusinessMessage(ctx context.Context, b *tgbot.Bot, msg *models.Message) {
+	if !c.businessEnabled() || msg == nil || msg.From == nil || msg.From.IsBot {
+		return
+	}
+	connID := strings.TrimSpace(msg.BusinessConnectionID)
+	if connID == "" {
+		return
+	}
+	conn, err := c.businessConnection(ctx, b, connID)
+	if err != nil || conn == nil || !conn.IsEnabled {
+		return
+	}
+	if !businessConnectionUsable(conn, c.allow) {
+		return
+	}
+	// Messages the owner sends from their own account are echoed as business
+	// messages too; only answer the other party.
+	if msg.From.ID == conn.User.ID {
+		return
+	}
+	senderID := telegramSenderID(msg.From)
+	if !(channels.AllowList{AllowFrom: c.cfg.Business.AllowFrom}).Allowed(senderID) {
+		return
+	}
+	content := telegramMessageContent(msg)
+	attachments := c.telegramInboundAttachments(ctx, b, msg)
+	if content == "" && len(attachments) == 0 {
+		return
+	}
+	c.publishInbound(senderID, businessChatID(connID, msg.Chat.ID), content, attachments, msg, false)
+}
+
+func businessConnectionUsable(conn *models.BusinessConnection, owners channels.AllowList) bool {
+	if conn == nil || conn.Rights == nil || !conn.Rights.CanReply {
+		return false
+	}
+	return owners.Allowed(telegramSenderID(&conn.User))
+}
+
+func businessChatID(connID string, chatID int64) string {
+	return businessChatPrefix + connID + ":" + strconv.FormatInt(chatID, 10)
+}
+
+func parseTelegramTarget(v string) (telegramTarget, error) {
+	v, thread, err := cutTopic(strings.TrimSpace(v))
+	if err != nil {
+		return telegramTarget{}, err
+	}
+	t, err := parseTelegramChatTarget(v)
+	t.ThreadID = thread
+	return t, err
+}
+
+func parseTelegramChatTarget(v string) (telegramTarget, error) {
+	if rest, ok := strings.CutPrefix(v, businessChatPrefix); ok {
+		connID, chat, ok := strings.Cut(rest, ":")
+		if !ok || strings.TrimSpace(connID) == "" {
+			return telegramTarget{}, fmt.Errorf("invalid business chat_id: %q", v)
+		}
+		id, err := parseTelegramChatID(chat)
+		if err != nil {
+			return telegramTarget{}, err
+		}
+		return telegramTarget{BusinessConnectionID: connID, ChatID: id}, nil
+	}
+	id, err := parseTelegramChatID(v)
+	if err != nil {
+		return telegramTarget{}, err
+	}
+	return telegramTarget{ChatID: id}, nil
+}
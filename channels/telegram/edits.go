@@ -0,0 +1,38 @@
+package telegram
+
+import (
+	"strconv"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// rememberReply records the message answering msg's inbound message, so a
+// corrected turn can edit it. Only the "correct" edits policy needs it.
+func (c *Channel) rememberReply(msg bus.OutboundMessage, replyID string) {
+	if config.EditsPolicy(c.cfg.Edits) != config.EditsCorrect || msg.Delivery.MessageID == "" || msg.Partial {
+		return
+	}
+	c.replies.Set(replyKey(msg.ChatID, msg.Delivery.MessageID), replyID, "")
+}
+
+// correctedReply returns the earlier answer a corrected reply replaces.
+func (c *Channel) correctedReply(msg bus.OutboundMessage) (string, bool) {
+	if !msg.Delivery.IsEdit || msg.Partial || msg.Delivery.MessageID == "" {
+		return "", false
+	}
+	id, _, ok := c.replies.Get(replyKey(msg.ChatID, msg.Delivery.MessageID))
+	return id, ok
+}
+
+func replyKey(chatID, messageID string) string {
+	return chatID + ":" + messageID
+}
+
+func sentID(m *models.Message) string {
+	if m == nil {
+		return ""
+	}
+	return strconv.Itoa(m.ID)
+}
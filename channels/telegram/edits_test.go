@@ -0,0 +1,62 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestSend_CorrectedReplyEditsAnswer(t *testing.T) {
+	type call struct{ method, text, messageID string }
+	var (
+		mu    sync.Mutex
+		calls []call
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := call{method: path.Base(r.URL.Path)}
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			c.text = r.FormValue("text")
+			c.messageID = r.FormValue("message_id")
+		}
+		mu.Lock()
+		calls = append(calls, c)
+		mu.Unlock()
+		_, _ = io.WriteString(w, `{"ok":true,"result":{"message_id":9,"date":0,"chat":{"id":1,"type":"private"}}}`)
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		edits string
+		want  []call
+	}{
+		{config.EditsCorrect, []call{{method: "sendMessage", text: "Paris"}, {method: "editMessageText", text: "Rome", messageID: "9"}}},
+		// Nothing was recorded, so the corrected answer is a new message.
+		{config.EditsAnnotate, []call{{method: "sendMessage", text: "Paris"}, {method: "sendMessage", text: "Rome"}}},
+	} {
+		calls = nil
+		ch := New(config.TelegramConfig{Token: "123:abc", BaseURL: srv.URL, Edits: tc.edits}, bus.New(1))
+		for _, msg := range []bus.OutboundMessage{
+			{ChatID: "42", Content: "Paris", Delivery: bus.Delivery{MessageID: "5"}},
+			{ChatID: "42", Content: "Rome", Delivery: bus.Delivery{MessageID: "5", IsEdit: true}},
+		} {
+			if err := ch.Send(context.Background(), msg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(calls) != len(tc.want) {
+			t.Fatalf("%s: calls=%+v", tc.edits, calls)
+		}
+		for i := range tc.want {
+			if calls[i] != tc.want[i] {
+				t.Fatalf("%s: call %d = %+v, want %+v", tc.edits, i, calls[i], tc.want[i])
+			}
+		}
+	}
+}
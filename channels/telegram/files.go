@@ -0,0 +1,134 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// localServerMaxDownloadBytes caps in-channel downloads from a local Bot API
+// server; media limits still apply afterwards.
+const localServerMaxDownloadBytes = config.DefaultMediaMaxFileBytes
+
+type telegramFileSource struct {
+	URL       string
+	LocalPath string
+	Data      []byte
+}
+
+// resolveTelegramFile returns where an inbound file can be read from.
+//
+// Against api.telegram.org the file URL is returned (it embeds the token, so
+// it must never be logged). With a local Bot API server in --local mode the
+// absolute file_path is read from disk; otherwise the channel downloads the
+// file itself, since the server is usually on a private address that media
+// fetching refuses.
+func (c *Channel) resolveTelegramFile(ctx context.Context, b *tgbot.Bot, fileID string, size int64) (telegramFileSource, error) {
+	fileID = strings.TrimSpace(fileID)
+	if fileID == "" {
+		return telegramFileSource{}, fmt.Errorf("telegram file id is empty")
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	res, err := b.GetFile(reqCtx, &tgbot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return telegramFileSource{}, err
+	}
+	if res == nil || strings.TrimSpace(res.FilePath) == "" {
+		return telegramFileSource{}, fmt.Errorf("telegram file path is empty")
+	}
+	filePath := strings.TrimSpace(res.FilePath)
+
+	if !c.cfg.LocalServer {
+		u, err := telegramFileURL(c.cfg.BaseURL, c.cfg.Token, filePath)
+		return telegramFileSource{URL: u}, err
+	}
+	if filepath.IsAbs(filePath) {
+		if _, err := os.Stat(filePath); err != nil {
+			return telegramFileSource{}, fmt.Errorf("local bot api file not readable (share the server's working directory with clawlet): %w", err)
+		}
+		return telegramFileSource{LocalPath: filePath}, nil
+	}
+	if size > localServerMaxDownloadBytes {
+		return telegramFileSource{}, fmt.Errorf("file too large: %d > %d", size, localServerMaxDownloadBytes)
+	}
+	u, err := telegramFileURL(c.cfg.BaseURL, c.cfg.Token, filePath)
+	if err != nil {
+		return telegramFileSource{}, err
+	}
+	data, err := downloadTelegramFile(ctx, u, localServerMaxDownloadBytes)
+	if err != nil {
+		return telegramFileSource{}, err
+	}
+	return telegramFileSource{Data: data}, nil
+}
+
+func downloadTelegramFile(ctx context.Context, fileURL string, maxBytes int64) ([]byte, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("telegram file http %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("file too large: > %d", maxBytes)
+	}
+	return data, nil
+}
+
+func telegramFileURL(baseURL, token, filePath string) (string, error) {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		baseURL = "https://api.telegram.org"
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("telegram token is empty")
+	}
+	filePath = strings.TrimLeft(strings.TrimSpace(filePath), "/")
+	if filePath == "" {
+		return "", fmt.Errorf("telegram file path is empty")
+	}
+	return baseURL + "/file/bot" + token + "/" + filePath, nil
+}
+
+// redactTelegramError hides the bot token, which HTTP client errors include
+// via the request URL (".../bot<token>/method").
+func redactTelegramError(err error, token string) error {
+	if err == nil {
+		return nil
+	}
+	token = strings.TrimSpace(token)
+	if token == "" || !strings.Contains(err.Error(), token) {
+		return err
+	}
+	return &redactedError{msg: strings.ReplaceAll(err.Error(), token, "<redacted>"), err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
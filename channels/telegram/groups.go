@@ -0,0 +1,65 @@
+package telegram
+
+import (
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/mosaxiv/clawlet/channels"
+)
+
+func (c *Channel) groupPolicy() channels.GroupPolicy {
+	return channels.GroupPolicy{Policy: c.cfg.GroupPolicy, AllowFrom: c.cfg.GroupAllowFrom, Groups: c.cfg.Groups}
+}
+
+func (c *Channel) botUser() *models.User {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.self
+}
+
+// addressedToBot reports whether a group message is for the bot: it
+// mentions the bot's @username (commands like /new@bot included) or
+// replies to one of its messages.
+func addressedToBot(msg *models.Message, content string, self *models.User) bool {
+	if msg == nil || self == nil {
+		return false
+	}
+	if r := repliedTo(msg); r != nil && r.From != nil && r.From.ID == self.ID {
+		return true
+	}
+	return mentionIndex(content, self.Username) >= 0
+}
+
+// mentionIndex finds "@username" in text as a whole handle, ignoring case;
+// -1 when absent.
+func mentionIndex(text, username string) int {
+	if username == "" {
+		return -1
+	}
+	lower, handle := strings.ToLower(text), "@"+strings.ToLower(username)
+	for from := 0; ; {
+		i := strings.Index(lower[from:], handle)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		end := i + len(handle)
+		if end == len(lower) || !isHandleByte(lower[end]) {
+			return i
+		}
+		from = end
+	}
+}
+
+func isHandleByte(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9')
+}
+
+// stripBotMention removes a leading "@username" from a group message.
+func stripBotMention(content string, self *models.User) string {
+	if self == nil || mentionIndex(content, self.Username) != 0 {
+		return content
+	}
+	after := content[len(self.Username)+1:]
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(after), ",:"))
+}
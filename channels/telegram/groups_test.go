@@ -0,0 +1,47 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestAddressedToBot(t *testing.T) {
+	self := &models.User{ID: 42, Username: "clawlet_bot"}
+	tests := []struct {
+		name string
+		msg  *models.Message
+		text string
+		want bool
+	}{
+		{"mention", &models.Message{}, "hey @Clawlet_Bot, what's up", true},
+		{"command", &models.Message{}, "/new@clawlet_bot", true},
+		{"other bot", &models.Message{}, "@clawlet_bot2 hi", false},
+		{"reply", &models.Message{ReplyToMessage: &models.Message{From: &models.User{ID: 42}}}, "yes", true},
+		{"topic opened by the bot", &models.Message{IsTopicMessage: true, MessageThreadID: 5, ReplyToMessage: &models.Message{ID: 5, From: &models.User{ID: 42}}}, "yes", false},
+		{"reply to someone else", &models.Message{ReplyToMessage: &models.Message{From: &models.User{ID: 7}}}, "yes", false},
+		{"chatter", &models.Message{}, "lunch?", false},
+	}
+	for _, tt := range tests {
+		if got := addressedToBot(tt.msg, tt.text, self); got != tt.want {
+			t.Fatalf("%s: got %v", tt.name, got)
+		}
+	}
+	if addressedToBot(&models.Message{}, "@clawlet_bot", nil) {
+		t.Fatal("unknown bot user should not match")
+	}
+}
+
+func TestStripBotMention(t *testing.T) {
+	self := &models.User{ID: 42, Username: "clawlet_bot"}
+	for in, want := range map[string]string{
+		"@clawlet_bot: summarize this": "summarize this",
+		"@CLAWLET_BOT hi":              "hi",
+		"thanks @clawlet_bot":          "thanks @clawlet_bot",
+		"@clawlet_bot2 hi":             "@clawlet_bot2 hi",
+	} {
+		if got := stripBotMention(in, self); got != want {
+			t.Fatalf("stripBotMention(%q)=%q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// maxTrackedPolls bounds the polls whose votes are still reported; the
+// oldest is forgotten first.
+const maxTrackedPolls = 200
+
+// trackedPoll is the chat a poll the bot sent lives in. Poll updates carry
+// only the poll.
+type trackedPoll struct {
+	chatID string
+	direct bool
+}
+
+func (c *Channel) SupportsPolls() bool { return true }
+
+// sendPoll posts a regular poll. Telegram closes polls only by hand or
+// within ten minutes, so DurationHours is ignored.
+func (c *Channel) sendPoll(ctx context.Context, b *tgbot.Bot, target telegramTarget, msg bus.OutboundMessage) error {
+	p := msg.Poll
+	if len(p.Options) < 2 {
+		return fmt.Errorf("telegram: a poll needs at least 2 options")
+	}
+	opts := make([]models.InputPollOption, len(p.Options))
+	for i, o := range p.Options {
+		opts[i] = models.InputPollOption{Text: o}
+	}
+	var sent *models.Message
+	err := withTelegramRetry(ctx, func() error {
+		var err error
+		sent, err = b.SendPoll(ctx, &tgbot.SendPollParams{
+			BusinessConnectionID:  target.BusinessConnectionID,
+			ChatID:                target.ChatID,
+			MessageThreadID:       target.ThreadID,
+			Question:              p.Question,
+			Options:               opts,
+			AllowsMultipleAnswers: p.MultiSelect,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if sent != nil && sent.Poll != nil {
+		c.trackPoll(sent.Poll.ID, trackedPoll{chatID: msg.ChatID, direct: sent.Chat.Type == models.ChatTypePrivate})
+	}
+	return nil
+}
+
+func (c *Channel) trackPoll(id string, p trackedPoll) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.polls == nil {
+		c.polls = map[string]trackedPoll{}
+	}
+	if _, ok := c.polls[id]; !ok {
+		c.pollOrder = append(c.pollOrder, id)
+	}
+	c.polls[id] = p
+	for len(c.pollOrder) > maxTrackedPolls {
+		delete(c.polls, c.pollOrder[0])
+		c.pollOrder = c.pollOrder[1:]
+	}
+}
+
+func (c *Channel) trackedPoll(id string) (trackedPoll, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.polls[id]
+	return p, ok
+}
+
+// onPoll publishes the new state of a poll the bot sent. Telegram sends
+// these for the bot's own polls only, without saying who voted.
+func (c *Channel) onPoll(p *models.Poll) {
+	tracked, ok := c.trackedPoll(p.ID)
+	if !ok {
+		return
+	}
+	poll := bus.Poll{ID: p.ID, Question: p.Question, MultiSelect: p.AllowsMultipleAnswers, Closed: p.IsClosed}
+	for _, o := range p.Options {
+		poll.Options = append(poll.Options, o.Text)
+		poll.Votes = append(poll.Votes, o.VoterCount)
+	}
+	publishCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = c.bus.PublishInbound(publishCtx, bus.InboundMessage{
+		Channel:    c.name,
+		ChatID:     tracked.chatID,
+		SessionKey: c.name + ":" + tracked.chatID,
+		Delivery:   bus.Delivery{IsDirect: tracked.direct},
+		Kind:       bus.MessageKindPoll,
+		Poll:       poll,
+	})
+}
@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestSendPoll_ReportsVotes(t *testing.T) {
+	var method, options string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = path.Base(r.URL.Path)
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			options = r.FormValue("options")
+		}
+		_, _ = io.WriteString(w, `{"ok":true,"result":{"message_id":9,"date":0,"chat":{"id":-100,"type":"group"},"poll":{"id":"p1","question":"Lunch?","options":[]}}}`)
+	}))
+	defer srv.Close()
+
+	b := bus.New(1)
+	ch := New(config.TelegramConfig{Token: "123:abc", BaseURL: srv.URL}, b)
+	err := ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID: "-100",
+		Kind:   bus.MessageKindPoll,
+		Poll:   bus.Poll{Question: "Lunch?", Options: []string{"Pizza", "Sushi"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != "sendPoll" || options != `[{"text":"Pizza"},{"text":"Sushi"}]` {
+		t.Fatalf("method=%q options=%s", method, options)
+	}
+
+	ch.onPoll(&models.Poll{ID: "other"})
+	ch.onPoll(&models.Poll{ID: "p1", Question: "Lunch?", IsClosed: true, Options: []models.PollOption{
+		{Text: "Pizza", VoterCount: 2},
+		{Text: "Sushi", VoterCount: 1},
+	}})
+	in, err := b.ConsumeInbound(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Kind != bus.MessageKindPoll || in.SessionKey != "telegram:-100" || in.Delivery.IsDirect {
+		t.Fatalf("inbound: %+v", in)
+	}
+	if in.Poll.ID != "p1" || !in.Poll.Closed || !slices.Equal(in.Poll.Votes, []int{2, 1}) || !slices.Equal(in.Poll.Options, []string{"Pizza", "Sushi"}) {
+		t.Fatalf("poll: %+v", in.Poll)
+	}
+}
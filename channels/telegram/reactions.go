@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+func (c *Channel) SupportsReactions() bool { return true }
+
+// sendReaction sets the bot's reaction on a message. Telegram allows only
+// its standard reaction emoji and rejects others.
+func (c *Channel) sendReaction(ctx context.Context, b *tgbot.Bot, target telegramTarget, r bus.Reaction) error {
+	if target.BusinessConnectionID != "" {
+		return fmt.Errorf("telegram: reactions are not supported in business chats")
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(r.MessageID))
+	if err != nil {
+		return fmt.Errorf("telegram: invalid message id %q", r.MessageID)
+	}
+	_, err = b.SetMessageReaction(ctx, &tgbot.SetMessageReactionParams{
+		ChatID:    target.ChatID,
+		MessageID: id,
+		Reaction: []models.ReactionType{{
+			Type:              models.ReactionTypeTypeEmoji,
+			ReactionTypeEmoji: &models.ReactionTypeEmoji{Emoji: strings.TrimSpace(r.Emoji)},
+		}},
+	})
+	return err
+}
+
+// onReaction publishes the emoji a user added to or removed from a message.
+// Telegram sends these in groups only when the bot is an administrator.
+func (c *Channel) onReaction(up *models.MessageReactionUpdated) {
+	if up.User == nil || up.User.IsBot {
+		return
+	}
+	senderID := telegramSenderID(up.User)
+	if !c.allow.Allowed(senderID) {
+		return
+	}
+	chatID := strconv.FormatInt(up.Chat.ID, 10)
+	direct := up.Chat.Type == models.ChatTypePrivate
+	// Updates do not say whose message it was, so in groups reactions are
+	// never addressed to the bot.
+	if !direct && !c.groupPolicy().Allows(false, chatID) {
+		return
+	}
+	added, removed := reactionChanges(up.OldReaction, up.NewReaction)
+	for _, r := range append(added, removed...) {
+		r.MessageID = strconv.Itoa(up.MessageID)
+		publishCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		_ = c.bus.PublishInbound(publishCtx, bus.InboundMessage{
+			Channel:    c.name,
+			SenderID:   senderID,
+			ChatID:     chatID,
+			SessionKey: c.name + ":" + chatID,
+			Delivery:   bus.Delivery{IsDirect: direct},
+			Kind:       bus.MessageKindReaction,
+			Reaction:   r,
+		})
+		cancel()
+	}
+}
+
+// reactionChanges compares the emoji reactions before and after an update.
+// Custom and paid reactions are skipped.
+func reactionChanges(old, cur []models.ReactionType) (added, removed []bus.Reaction) {
+	before, after := reactionEmoji(old), reactionEmoji(cur)
+	for _, e := range after {
+		if !slices.Contains(before, e) {
+			added = append(added, bus.Reaction{Emoji: e})
+		}
+	}
+	for _, e := range before {
+		if !slices.Contains(after, e) {
+			removed = append(removed, bus.Reaction{Emoji: e, Removed: true})
+		}
+	}
+	return added, removed
+}
+
+func reactionEmoji(rs []models.ReactionType) []string {
+	var out []string
+	for _, r := range rs {
+		if r.Type == models.ReactionTypeTypeEmoji && r.ReactionTypeEmoji != nil {
+			out = append(out, r.ReactionTypeEmoji.Emoji)
+		}
+	}
+	return out
+}
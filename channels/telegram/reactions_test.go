@@ -0,0 +1,22 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestReactionChanges(t *testing.T) {
+	emoji := func(e string) models.ReactionType {
+		return models.ReactionType{Type: models.ReactionTypeTypeEmoji, ReactionTypeEmoji: &models.ReactionTypeEmoji{Emoji: e}}
+	}
+	custom := models.ReactionType{Type: models.ReactionTypeTypeCustomEmoji, ReactionTypeCustomEmoji: &models.ReactionTypeCustomEmoji{CustomEmojiID: "1"}}
+
+	added, removed := reactionChanges([]models.ReactionType{emoji("👍"), emoji("🔥")}, []models.ReactionType{emoji("🔥"), emoji("🎉"), custom})
+	if len(added) != 1 || added[0].Emoji != "🎉" || added[0].Removed {
+		t.Fatalf("added: %+v", added)
+	}
+	if len(removed) != 1 || removed[0].Emoji != "👍" || !removed[0].Removed {
+		t.Fatalf("removed: %+v", removed)
+	}
+}
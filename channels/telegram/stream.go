@@ -0,0 +1,125 @@
+package telegram
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+)
+
+func (c *Channel) SupportsStreaming() bool { return true }
+
+// sendStream sends the messages of a streamed reply: the first partial
+// message is sent, later ones edit it, and the final message edits it into
+// the first part of the reply. It reports false for a final message with
+// nothing to edit, which is then sent as usual.
+func (c *Channel) sendStream(ctx context.Context, b *tgbot.Bot, target telegramTarget, msg bus.OutboundMessage, text string) (bool, error) {
+	if !msg.Partial {
+		id, ok := c.streams.Take(msg.StreamID)
+		if !ok {
+			return false, nil
+		}
+		return true, c.finishStream(ctx, b, target, msg, text, id)
+	}
+	if text == "" {
+		return true, nil
+	}
+	// Partial text goes out plain: half a reply is often half a code block.
+	text = channels.PartialText(text, channels.MaxTelegramText)
+	id, shown, ok := c.streams.Get(msg.StreamID)
+	switch {
+	case ok && shown == text:
+		return true, nil
+	case ok:
+		if err := c.editText(ctx, b, target, id, text, nil, false); err != nil {
+			return true, err
+		}
+	default:
+		params := &tgbot.SendMessageParams{
+			BusinessConnectionID: target.BusinessConnectionID,
+			ChatID:               target.ChatID,
+			MessageThreadID:      target.ThreadID,
+			Text:                 text,
+		}
+		if replyTo := resolveTelegramReplyTarget(c.cfg.Quote, msg); replyTo > 0 {
+			params.ReplyParameters = &models.ReplyParameters{MessageID: int(replyTo), AllowSendingWithoutReply: true}
+		}
+		sent, err := b.SendMessage(ctx, params)
+		if err != nil {
+			return true, redactTelegramError(err, c.cfg.Token)
+		}
+		id = strconv.Itoa(sent.ID)
+	}
+	c.streams.Set(msg.StreamID, id, text)
+	return true, nil
+}
+
+// finishStream edits message id into the first part of the final reply and
+// sends the other parts and the attachments after it.
+func (c *Channel) finishStream(ctx context.Context, b *tgbot.Bot, target telegramTarget, msg bus.OutboundMessage, text, id string) error {
+	parts := channels.SplitterOrDefault(c.cfg.Split)(text, channels.MaxTelegramText)
+	last := len(msg.Attachments) == 0
+	for i, part := range parts {
+		withKeyboard := last && i == len(parts)-1
+		if i == 0 {
+			var kb *models.InlineKeyboardMarkup
+			if withKeyboard && target.BusinessConnectionID == "" {
+				kb = suggestionKeyboard(msg.Suggestions)
+			}
+			if err := c.editText(ctx, b, target, id, part, kb, true); err != nil {
+				return err
+			}
+			c.rememberReply(msg, id)
+			continue
+		}
+		if err := c.sendTextPart(ctx, b, target, msg, part, withKeyboard, false); err != nil {
+			return err
+		}
+	}
+	if len(msg.Attachments) == 0 {
+		return nil
+	}
+	return c.sendAttachments(ctx, b, target, msg, "", false)
+}
+
+// editText replaces the text of message id, as HTML when html is set and
+// Telegram accepts the markup.
+func (c *Channel) editText(ctx context.Context, b *tgbot.Bot, target telegramTarget, id, text string, kb *models.InlineKeyboardMarkup, html bool) error {
+	msgID, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	params := &tgbot.EditMessageTextParams{
+		BusinessConnectionID: target.BusinessConnectionID,
+		ChatID:               target.ChatID,
+		MessageID:            msgID,
+		Text:                 text,
+	}
+	if kb != nil {
+		params.ReplyMarkup = kb
+	}
+	if html {
+		params.Text = markdownToTelegramHTML(text)
+		params.ParseMode = models.ParseModeHTML
+	}
+	edit := func() error {
+		return withTelegramRetry(ctx, func() error {
+			_, err := b.EditMessageText(ctx, params)
+			if err != nil && strings.Contains(err.Error(), "message is not modified") {
+				return nil
+			}
+			return err
+		})
+	}
+	err = edit()
+	if err != nil && html && isTelegramParseError(err) {
+		params.Text = text
+		params.ParseMode = ""
+		err = edit()
+	}
+	return redactTelegramError(err, c.cfg.Token)
+}
@@ -0,0 +1,62 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestSend_Stream(t *testing.T) {
+	type call struct{ method, text, messageID string }
+	var (
+		mu    sync.Mutex
+		calls []call
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := call{method: path.Base(r.URL.Path)}
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			c.text = r.FormValue("text")
+			c.messageID = r.FormValue("message_id")
+		}
+		mu.Lock()
+		calls = append(calls, c)
+		mu.Unlock()
+		_, _ = io.WriteString(w, `{"ok":true,"result":{"message_id":7,"date":0,"chat":{"id":1,"type":"private"}}}`)
+	}))
+	defer srv.Close()
+
+	ch := New(config.TelegramConfig{Token: "123:abc", BaseURL: srv.URL}, bus.New(1))
+	for _, msg := range []bus.OutboundMessage{
+		{ChatID: "42", Content: "Hel", StreamID: "s1", Partial: true},
+		{ChatID: "42", Content: "Hello wor", StreamID: "s1", Partial: true},
+		{ChatID: "42", Content: "Hello wor", StreamID: "s1", Partial: true},
+		{ChatID: "42", Content: "Hello **world**", StreamID: "s1"},
+		{ChatID: "42", Content: "Next", StreamID: "s1"},
+	} {
+		if err := ch.Send(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []call{
+		{method: "sendMessage", text: "Hel"},
+		{method: "editMessageText", text: "Hello wor", messageID: "7"},
+		{method: "editMessageText", text: "Hello <b>world</b>", messageID: "7"},
+		// The stream is finished; a repeat is sent as a new message.
+		{method: "sendMessage", text: "Next"},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls=%+v", calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("call %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package telegram
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// suggestionCallbackPrefix marks inline buttons carrying follow-up
+// suggestions. The suggestion text is the button label, so taps need no
+// server-side state and work on any gateway instance.
+const suggestionCallbackPrefix = "sg:"
+
+func suggestionKeyboard(items []string) *models.InlineKeyboardMarkup {
+	var rows [][]models.InlineKeyboardButton
+	for i, it := range items {
+		if it = strings.TrimSpace(it); it == "" {
+			continue
+		}
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         it,
+			CallbackData: suggestionCallbackPrefix + strconv.Itoa(i),
+		}})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// suggestionText returns the label of the button q was sent from.
+func suggestionText(q *models.CallbackQuery) string {
+	if q == nil || q.Message.Message == nil || q.Message.Message.ReplyMarkup == nil {
+		return ""
+	}
+	for _, row := range q.Message.Message.ReplyMarkup.InlineKeyboard {
+		for _, btn := range row {
+			if btn.CallbackData == q.Data {
+				return strings.TrimSpace(btn.Text)
+			}
+		}
+	}
+	return ""
+}
+
+// onCallbackQuery sends a tapped suggestion as the user's next message and
+// removes the buttons so it is not sent twice.
+func (c *Channel) onCallbackQuery(ctx context.Context, b *tgbot.Bot, q *models.CallbackQuery) {
+	if !strings.HasPrefix(q.Data, suggestionCallbackPrefix) {
+		return
+	}
+	_, _ = b.AnswerCallbackQuery(ctx, &tgbot.AnswerCallbackQueryParams{CallbackQueryID: q.ID})
+	senderID := telegramSenderID(&q.From)
+	if q.From.IsBot || !c.allow.Allowed(senderID) {
+		return
+	}
+	text := suggestionText(q)
+	if text == "" {
+		return
+	}
+	msg := q.Message.Message
+	_, _ = b.EditMessageReplyMarkup(ctx, &tgbot.EditMessageReplyMarkupParams{
+		ChatID:      msg.Chat.ID,
+		MessageID:   msg.ID,
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{}},
+	})
+	chatID := messageChatID(msg)
+	c.sendTypingHint(chatID)
+	delivery := buildTelegramDelivery(msg)
+	delivery.MessageID, delivery.ReplyToID = "", ""
+	c.publish(senderID, chatID, text, nil, delivery)
+}
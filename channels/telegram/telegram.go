@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net"
 	"net/http"
 	"strconv"
@@ -11,6 +12,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	tgbot "github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -20,6 +22,7 @@ import (
 )
 
 type Channel struct {
+	name  string
 	cfg   config.TelegramConfig
 	bus   *bus.Bus
 	allow channels.AllowList
@@ -28,14 +31,29 @@ type Channel struct {
 	workers        int
 
 	running atomic.Bool
-
-	mu     sync.Mutex
-	bot    *tgbot.Bot
-	cancel context.CancelFunc
+	streams channels.Streams
+	replies channels.Streams // inbound message -> answer, for corrections
+
+	mu       sync.Mutex
+	bot      *tgbot.Bot
+	sender   *tgbot.Bot
+	cancel   context.CancelFunc
+	business map[string]*models.BusinessConnection
+	self     *models.User // the bot, for group mentions
+
+	polls     map[string]trackedPoll // poll ID -> chat, for vote updates
+	pollOrder []string
 }
 
 func New(cfg config.TelegramConfig, b *bus.Bus) *Channel {
+	return NewAccount("", cfg, b)
+}
+
+// NewAccount returns the channel of a named account, "telegram.<account>";
+// "" is the main account, "telegram".
+func NewAccount(account string, cfg config.TelegramConfig, b *bus.Bus) *Channel {
 	return &Channel{
+		name:           config.ChannelName("telegram", account),
 		cfg:            cfg,
 		bus:            b,
 		allow:          channels.AllowList{AllowFrom: cfg.AllowFrom},
@@ -44,9 +62,14 @@ func New(cfg config.TelegramConfig, b *bus.Bus) *Channel {
 	}
 }
 
-func (c *Channel) Name() string    { return "telegram" }
+func (c *Channel) Name() string    { return c.name }
 func (c *Channel) IsRunning() bool { return c.running.Load() }
 
+func (c *Channel) SupportsAttachments() bool { return true }
+
+// Preview returns text as the HTML sent to Telegram.
+func (c *Channel) Preview(text string) string { return markdownToTelegramHTML(text) }
+
 func (c *Channel) Start(ctx context.Context) error {
 	token := strings.TrimSpace(c.cfg.Token)
 	if token == "" {
@@ -60,10 +83,7 @@ func (c *Channel) Start(ctx context.Context) error {
 	opts := []tgbot.Option{
 		tgbot.WithHTTPClient(time.Duration(c.pollTimeoutSec)*time.Second, hc),
 		tgbot.WithWorkers(c.workers),
-		tgbot.WithAllowedUpdates(tgbot.AllowedUpdates{
-			models.AllowedUpdateMessage,
-			models.AllowedUpdateEditedMessage,
-		}),
+		tgbot.WithAllowedUpdates(c.allowedUpdates()),
 		tgbot.WithDefaultHandler(c.onUpdate),
 	}
 	if baseURL := strings.TrimSpace(c.cfg.BaseURL); baseURL != "" {
@@ -75,9 +95,14 @@ func (c *Channel) Start(ctx context.Context) error {
 		return err
 	}
 	_, _ = b.DeleteWebhook(runCtx, &tgbot.DeleteWebhookParams{DropPendingUpdates: true})
+	self, err := b.GetMe(runCtx)
+	if err != nil {
+		log.Printf("telegram: getMe failed, mentions in groups will not be recognized: %v", err)
+	}
 
 	c.mu.Lock()
 	c.bot = b
+	c.self = self
 	c.cancel = cancel
 	c.mu.Unlock()
 	defer func() {
@@ -110,55 +135,161 @@ func (c *Channel) Stop() error {
 
 func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	text := strings.TrimSpace(msg.Content)
-	if text == "" {
+	if text == "" && len(msg.Attachments) == 0 && msg.Kind == bus.MessageKindText {
 		return nil
 	}
 
-	chatIDAny, err := parseTelegramChatID(msg.ChatID)
+	target, err := parseTelegramTarget(msg.ChatID)
 	if err != nil {
 		return err
 	}
 
-	c.mu.Lock()
-	b := c.bot
-	c.mu.Unlock()
-	if b == nil {
-		return fmt.Errorf("telegram not connected")
+	b, err := c.sendBot()
+	if err != nil {
+		return err
 	}
+	if msg.Kind == bus.MessageKindReaction {
+		return c.sendReaction(ctx, b, target, msg.Reaction)
+	}
+	if msg.Kind == bus.MessageKindPoll {
+		return c.sendPoll(ctx, b, target, msg)
+	}
+
+	if id, ok := c.correctedReply(msg); ok {
+		return c.finishStream(ctx, b, target, msg, text, id)
+	}
+	if msg.StreamID != "" {
+		if handled, err := c.sendStream(ctx, b, target, msg, text); handled {
+			return err
+		}
+	}
+	if len(msg.Attachments) == 0 {
+		return c.sendText(ctx, b, target, msg, text, true)
+	}
+	caption := ""
+	if utf8.RuneCountInString(text) <= maxTelegramCaption {
+		caption, text = text, ""
+	}
+	if text != "" {
+		if err := c.sendText(ctx, b, target, msg, text, false); err != nil {
+			return err
+		}
+	}
+	return c.sendAttachments(ctx, b, target, msg, caption, text == "")
+}
 
+// sendText sends text in messages of at most 4096 characters. The first
+// one answers msg, the last one carries the suggestion buttons.
+func (c *Channel) sendText(ctx context.Context, b *tgbot.Bot, target telegramTarget, msg bus.OutboundMessage, text string, withKeyboard bool) error {
+	parts := channels.SplitterOrDefault(c.cfg.Split)(text, channels.MaxTelegramText)
+	for i, part := range parts {
+		if err := c.sendTextPart(ctx, b, target, msg, part, withKeyboard && i == len(parts)-1, i == 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendTextPart sends text as HTML, falling back to plain text when Telegram
+// rejects the markup.
+func (c *Channel) sendTextPart(ctx context.Context, b *tgbot.Bot, target telegramTarget, msg bus.OutboundMessage, text string, withKeyboard, withReply bool) error {
 	params := &tgbot.SendMessageParams{
-		ChatID:    chatIDAny,
-		Text:      markdownToTelegramHTML(text),
-		ParseMode: models.ParseModeHTML,
+		BusinessConnectionID: target.BusinessConnectionID,
+		ChatID:               target.ChatID,
+		MessageThreadID:      target.ThreadID,
+		Text:                 markdownToTelegramHTML(text),
+		ParseMode:            models.ParseModeHTML,
+	}
+	// Inline buttons are not available in business chats.
+	if kb := suggestionKeyboard(msg.Suggestions); kb != nil && withKeyboard && target.BusinessConnectionID == "" {
+		params.ReplyMarkup = kb
 	}
-	if replyTo := resolveTelegramReplyTarget(msg); replyTo > 0 {
+	if replyTo := resolveTelegramReplyTarget(c.cfg.Quote, msg); replyTo > 0 && withReply {
 		params.ReplyParameters = &models.ReplyParameters{
 			MessageID:                int(replyTo),
 			AllowSendingWithoutReply: true,
 		}
 	}
-	if err := c.sendMessageWithRetry(ctx, b, params); err == nil {
-		return nil
-	} else if !isTelegramParseError(err) {
-		return err
+	sent, err := c.sendMessageWithRetry(ctx, b, params)
+	if err != nil && isTelegramParseError(err) {
+		params.Text = text
+		params.ParseMode = ""
+		sent, err = c.sendMessageWithRetry(ctx, b, params)
+	}
+	if err != nil {
+		return redactTelegramError(err, c.cfg.Token)
+	}
+	if withReply {
+		c.rememberReply(msg, sentID(sent))
 	}
+	return nil
+}
 
-	params.Text = text
-	params.ParseMode = ""
-	return c.sendMessageWithRetry(ctx, b, params)
+// sendBot returns the polling bot, or a send-only client when this instance
+// is not polling (for example while another gateway instance holds the
+// Telegram lease).
+func (c *Channel) sendBot() (*tgbot.Bot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bot != nil {
+		return c.bot, nil
+	}
+	if c.sender != nil {
+		return c.sender, nil
+	}
+	token := strings.TrimSpace(c.cfg.Token)
+	if token == "" {
+		return nil, fmt.Errorf("telegram not connected")
+	}
+	opts := []tgbot.Option{
+		tgbot.WithSkipGetMe(),
+		tgbot.WithHTTPClient(30*time.Second, &http.Client{Timeout: 30 * time.Second}),
+	}
+	if baseURL := strings.TrimSpace(c.cfg.BaseURL); baseURL != "" {
+		opts = append(opts, tgbot.WithServerURL(baseURL))
+	}
+	b, err := tgbot.New(token, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.sender = b
+	return b, nil
 }
 
 func (c *Channel) onUpdate(ctx context.Context, b *tgbot.Bot, up *models.Update) {
 	if up == nil {
 		return
 	}
-	msg := up.Message
+	if up.BusinessConnection != nil {
+		c.rememberBusinessConnection(up.BusinessConnection)
+		return
+	}
+	if up.BusinessMessage != nil {
+		c.onBusinessMessage(ctx, b, up.BusinessMessage)
+		return
+	}
+	if up.CallbackQuery != nil {
+		c.onCallbackQuery(ctx, b, up.CallbackQuery)
+		return
+	}
+	if up.MessageReaction != nil {
+		c.onReaction(up.MessageReaction)
+		return
+	}
+	if up.Poll != nil {
+		c.onPoll(up.Poll)
+		return
+	}
+	msg, edited := up.Message, false
 	if msg == nil {
-		msg = up.EditedMessage
+		msg, edited = up.EditedMessage, true
 	}
 	if msg == nil || msg.From == nil || msg.From.IsBot {
 		return
 	}
+	if edited && config.EditsPolicy(c.cfg.Edits) == config.EditsIgnore {
+		return
+	}
 
 	senderID := telegramSenderID(msg.From)
 	if !c.allow.Allowed(senderID) {
@@ -166,31 +297,57 @@ func (c *Channel) onUpdate(ctx context.Context, b *tgbot.Bot, up *models.Update)
 	}
 
 	content := telegramMessageContent(msg)
+	if msg.Chat.Type != models.ChatTypePrivate {
+		self := c.botUser()
+		if !c.groupPolicy().Allows(addressedToBot(msg, content, self), strconv.FormatInt(msg.Chat.ID, 10)) {
+			return
+		}
+		content = stripBotMention(content, self)
+	}
 	attachments := c.telegramInboundAttachments(ctx, b, msg)
 	if content == "" && len(attachments) == 0 {
 		return
 	}
 
-	chatID := strconv.FormatInt(msg.Chat.ID, 10)
+	c.publishInbound(senderID, messageChatID(msg), content, attachments, msg, edited)
+}
+
+func (c *Channel) publishInbound(senderID, chatID, content string, attachments []bus.Attachment, msg *models.Message, edited bool) {
+	delivery := buildTelegramDelivery(msg)
+	delivery.IsEdit = edited
 	c.sendTypingHint(chatID)
+	c.publish(senderID, chatID, content, attachments, delivery)
+}
+
+func (c *Channel) publish(senderID, chatID, content string, attachments []bus.Attachment, delivery bus.Delivery) {
 	// Avoid blocking telegram worker goroutines indefinitely when bus is saturated.
 	publishCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	_ = c.bus.PublishInbound(publishCtx, bus.InboundMessage{
-		Channel:     "telegram",
+		Channel:     c.name,
 		SenderID:    senderID,
 		ChatID:      chatID,
 		Content:     content,
 		Attachments: attachments,
-		SessionKey:  "telegram:" + chatID,
-		Delivery:    buildTelegramDelivery(msg),
+		SessionKey:  c.name + ":" + chatID,
+		Delivery:    delivery,
 	})
 	cancel()
 }
 
-func (c *Channel) sendMessageWithRetry(ctx context.Context, b *tgbot.Bot, params *tgbot.SendMessageParams) error {
+func (c *Channel) sendMessageWithRetry(ctx context.Context, b *tgbot.Bot, params *tgbot.SendMessageParams) (*models.Message, error) {
+	var sent *models.Message
+	err := withTelegramRetry(ctx, func() error {
+		var err error
+		sent, err = b.SendMessage(ctx, params)
+		return err
+	})
+	return sent, err
+}
+
+func withTelegramRetry(ctx context.Context, send func() error) error {
 	const maxAttempts = 3
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		_, err := b.SendMessage(ctx, params)
+		err := send()
 		if err == nil {
 			return nil
 		}
@@ -209,31 +366,38 @@ func (c *Channel) sendMessageWithRetry(ctx context.Context, b *tgbot.Bot, params
 	return nil
 }
 
+// sendTypingHint shows "typing…" right away, before the agent's working
+// signal keeps it up.
 func (c *Channel) sendTypingHint(chatID string) {
-	chatID = strings.TrimSpace(chatID)
-	if chatID == "" {
+	if strings.TrimSpace(chatID) == "" {
 		return
 	}
-	chatIDAny, err := parseTelegramChatID(chatID)
+	go func() {
+		typingCtx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+		defer cancel()
+		_ = c.SendTyping(typingCtx, chatID)
+	}()
+}
+
+// SendTyping shows "typing…" in the chat for about 5 seconds.
+func (c *Channel) SendTyping(ctx context.Context, chatID string) error {
+	target, err := parseTelegramTarget(strings.TrimSpace(chatID))
 	if err != nil {
-		return
+		return err
 	}
-
 	c.mu.Lock()
 	b := c.bot
 	c.mu.Unlock()
 	if b == nil {
-		return
+		return fmt.Errorf("telegram not connected")
 	}
-
-	go func(bot *tgbot.Bot, id any) {
-		typingCtx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
-		defer cancel()
-		_, _ = bot.SendChatAction(typingCtx, &tgbot.SendChatActionParams{
-			ChatID: id,
-			Action: models.ChatActionTyping,
-		})
-	}(b, chatIDAny)
+	_, err = b.SendChatAction(ctx, &tgbot.SendChatActionParams{
+		BusinessConnectionID: target.BusinessConnectionID,
+		ChatID:               target.ChatID,
+		MessageThreadID:      target.ThreadID,
+		Action:               models.ChatActionTyping,
+	})
+	return err
 }
 
 func parseTelegramChatID(v string) (any, error) {
@@ -391,8 +555,9 @@ func (c *Channel) telegramInboundAttachments(ctx context.Context, b *tgbot.Bot,
 		if cand.ID == "" {
 			continue
 		}
-		fileURL, err := c.resolveTelegramFileURL(ctx, b, cand.ID)
-		if err != nil || strings.TrimSpace(fileURL) == "" {
+		src, err := c.resolveTelegramFile(ctx, b, cand.ID, cand.Size)
+		if err != nil {
+			log.Printf("telegram: file %s unavailable: %v", cand.ID, redactTelegramError(err, c.cfg.Token))
 			continue
 		}
 		mimeType := strings.TrimSpace(cand.MIMEType)
@@ -409,7 +574,9 @@ func (c *Channel) telegramInboundAttachments(ctx context.Context, b *tgbot.Bot,
 			MIMEType:  mimeType,
 			Kind:      kind,
 			SizeBytes: cand.Size,
-			URL:       fileURL,
+			URL:       src.URL,
+			LocalPath: src.LocalPath,
+			Data:      src.Data,
 		})
 	}
 	if len(out) == 0 {
@@ -442,39 +609,6 @@ func fallbackTelegramMime(v, fallback string) string {
 	return fallback
 }
 
-func (c *Channel) resolveTelegramFileURL(ctx context.Context, b *tgbot.Bot, fileID string) (string, error) {
-	fileID = strings.TrimSpace(fileID)
-	if fileID == "" {
-		return "", fmt.Errorf("telegram file id is empty")
-	}
-	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-	res, err := b.GetFile(reqCtx, &tgbot.GetFileParams{FileID: fileID})
-	if err != nil {
-		return "", err
-	}
-	if res == nil || strings.TrimSpace(res.FilePath) == "" {
-		return "", fmt.Errorf("telegram file path is empty")
-	}
-	return telegramFileURL(c.cfg.BaseURL, c.cfg.Token, res.FilePath)
-}
-
-func telegramFileURL(baseURL, token, filePath string) (string, error) {
-	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
-	if baseURL == "" {
-		baseURL = "https://api.telegram.org"
-	}
-	token = strings.TrimSpace(token)
-	if token == "" {
-		return "", fmt.Errorf("telegram token is empty")
-	}
-	filePath = strings.TrimLeft(strings.TrimSpace(filePath), "/")
-	if filePath == "" {
-		return "", fmt.Errorf("telegram file path is empty")
-	}
-	return baseURL + "/file/bot" + token + "/" + filePath, nil
-}
-
 func buildTelegramDelivery(msg *models.Message) bus.Delivery {
 	if msg == nil {
 		return bus.Delivery{}
@@ -483,8 +617,11 @@ func buildTelegramDelivery(msg *models.Message) bus.Delivery {
 		MessageID: strconv.Itoa(msg.ID),
 		IsDirect:  msg.Chat.Type == models.ChatTypePrivate,
 	}
-	if msg.ReplyToMessage != nil && msg.ReplyToMessage.ID > 0 {
-		d.ReplyToID = strconv.Itoa(msg.ReplyToMessage.ID)
+	if at := max(msg.Date, msg.EditDate); at > 0 {
+		d.SentAt = time.Unix(int64(at), 0)
+	}
+	if r := repliedTo(msg); r != nil && r.ID > 0 {
+		d.ReplyToID = strconv.Itoa(r.ID)
 	}
 	if msg.MessageThreadID > 0 {
 		d.ThreadID = strconv.Itoa(msg.MessageThreadID)
@@ -492,21 +629,12 @@ func buildTelegramDelivery(msg *models.Message) bus.Delivery {
 	return d
 }
 
-func resolveTelegramReplyTarget(msg bus.OutboundMessage) int64 {
-	candidates := []string{
-		strings.TrimSpace(msg.Delivery.ReplyToID),
-		strings.TrimSpace(msg.ReplyTo),
-	}
-	for _, c := range candidates {
-		if c == "" {
-			continue
-		}
-		n, err := strconv.ParseInt(c, 10, 64)
-		if err == nil && n > 0 {
-			return n
-		}
+func resolveTelegramReplyTarget(quote string, msg bus.OutboundMessage) int64 {
+	n, err := strconv.ParseInt(channels.ReplyTarget(quote, msg), 10, 64)
+	if err != nil || n <= 0 {
+		return 0
 	}
-	return 0
+	return n
 }
 
 func clampTelegramPollTimeout(v int) int {
@@ -3,18 +3,25 @@ package telegram
 import (
 	"context"
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	tgbot "github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/config"
 )
 
 func TestResolveTelegramReplyTarget(t *testing.T) {
 	t.Run("prefer typed delivery reply id", func(t *testing.T) {
-		got := resolveTelegramReplyTarget(bus.OutboundMessage{
+		got := resolveTelegramReplyTarget("", bus.OutboundMessage{
 			ReplyTo: "12",
 			Delivery: bus.Delivery{
 				ReplyToID: "34",
@@ -26,7 +33,7 @@ func TestResolveTelegramReplyTarget(t *testing.T) {
 	})
 
 	t.Run("fallback legacy reply_to", func(t *testing.T) {
-		got := resolveTelegramReplyTarget(bus.OutboundMessage{
+		got := resolveTelegramReplyTarget("", bus.OutboundMessage{
 			ReplyTo: "56",
 		})
 		if got != 56 {
@@ -35,7 +42,7 @@ func TestResolveTelegramReplyTarget(t *testing.T) {
 	})
 
 	t.Run("invalid values", func(t *testing.T) {
-		got := resolveTelegramReplyTarget(bus.OutboundMessage{
+		got := resolveTelegramReplyTarget("", bus.OutboundMessage{
 			ReplyTo: "abc",
 			Delivery: bus.Delivery{
 				ReplyToID: "def",
@@ -189,3 +196,228 @@ func TestTelegramFileURL(t *testing.T) {
 		}
 	})
 }
+
+func TestRedactTelegramError(t *testing.T) {
+	base := errors.New(`Post "https://api.telegram.org/bot123:secret/sendMessage": dial tcp: timeout`)
+	err := redactTelegramError(base, "123:secret")
+	if strings.Contains(err.Error(), "secret") {
+		t.Fatalf("token leaked: %v", err)
+	}
+	if !errors.Is(err, base) {
+		t.Fatalf("expected wrapped error")
+	}
+	if redactTelegramError(nil, "123:secret") != nil {
+		t.Fatalf("expected nil")
+	}
+}
+
+func TestParseTelegramTarget(t *testing.T) {
+	got, err := parseTelegramTarget("business:conn-1:42")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got.BusinessConnectionID != "conn-1" || got.ChatID != int64(42) {
+		t.Fatalf("unexpected target: %+v", got)
+	}
+	got, err = parseTelegramTarget("42")
+	if err != nil || got.BusinessConnectionID != "" || got.ChatID != int64(42) {
+		t.Fatalf("unexpected plain target: %+v err=%v", got, err)
+	}
+	if _, err := parseTelegramTarget("business::42"); err == nil {
+		t.Fatalf("expected error for missing connection id")
+	}
+	got, err = parseTelegramTarget("-1001234:topic:7")
+	if err != nil || got.ChatID != int64(-1001234) || got.ThreadID != 7 {
+		t.Fatalf("unexpected topic target: %+v err=%v", got, err)
+	}
+	if _, err := parseTelegramTarget("-1001234:topic:x"); err == nil {
+		t.Fatalf("expected error for invalid topic")
+	}
+}
+
+func TestMessageChatID_Topics(t *testing.T) {
+	msg := &models.Message{Chat: models.Chat{ID: -1001234, Type: models.ChatTypeSupergroup}, MessageThreadID: 7}
+	// A reply thread outside a forum is not a topic.
+	if got := messageChatID(msg); got != "-1001234" {
+		t.Fatalf("got %q", got)
+	}
+	msg.IsTopicMessage = true
+	if got := messageChatID(msg); got != "-1001234:topic:7" {
+		t.Fatalf("got %q", got)
+	}
+	// Messages in a topic point at the message that opened it.
+	msg.ReplyToMessage = &models.Message{ID: 7}
+	if d := buildTelegramDelivery(msg); d.ReplyToID != "" {
+		t.Fatalf("topic opener taken as reply: %+v", d)
+	}
+}
+
+func TestSend_TopicChat(t *testing.T) {
+	var threads []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			threads = append(threads, r.FormValue("chat_id")+"/"+r.FormValue("message_thread_id"))
+		}
+		_, _ = io.WriteString(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":-1001234,"type":"supergroup"}}}`)
+	}))
+	defer srv.Close()
+
+	ch := New(config.TelegramConfig{Token: "123:abc", BaseURL: srv.URL}, bus.New(1))
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "-1001234:topic:7", Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(threads) != 1 || threads[0] != "-1001234/7" {
+		t.Fatalf("sent %v", threads)
+	}
+}
+
+func TestOnBusinessMessage_Filters(t *testing.T) {
+	newChannel := func(customers []string) (*Channel, *bus.Bus) {
+		b := bus.New(4)
+		c := New(config.TelegramConfig{
+			AllowFrom: []string{"100"},
+			Business:  &config.TelegramBusinessConfig{Enabled: true, AllowFrom: customers},
+		}, b)
+		c.rememberBusinessConnection(&models.BusinessConnection{
+			ID:        "conn",
+			User:      models.User{ID: 100},
+			IsEnabled: true,
+			Rights:    &models.BusinessBotRights{CanReply: true},
+		})
+		c.rememberBusinessConnection(&models.BusinessConnection{
+			ID:        "stranger",
+			User:      models.User{ID: 999},
+			IsEnabled: true,
+			Rights:    &models.BusinessBotRights{CanReply: true},
+		})
+		return c, b
+	}
+	msg := func(conn string, from int64) *models.Message {
+		return &models.Message{
+			ID:                   1,
+			BusinessConnectionID: conn,
+			From:                 &models.User{ID: from},
+			Chat:                 models.Chat{ID: from, Type: models.ChatTypePrivate},
+			Text:                 "hi",
+		}
+	}
+
+	c, b := newChannel(nil)
+	c.onBusinessMessage(context.Background(), nil, msg("conn", 200))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	in, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("expected inbound: %v", err)
+	}
+	if in.ChatID != "business:conn:200" || in.SessionKey != "telegram:business:conn:200" {
+		t.Fatalf("unexpected inbound: %+v", in)
+	}
+
+	for name, tc := range map[string]struct {
+		customers []string
+		msg       *models.Message
+	}{
+		"owner echo":        {msg: msg("conn", 100)},
+		"owner not allowed": {msg: msg("stranger", 200)},
+		"customer filtered": {customers: []string{"300"}, msg: msg("conn", 200)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, b := newChannel(tc.customers)
+			c.onBusinessMessage(context.Background(), nil, tc.msg)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if in, err := b.ConsumeInbound(ctx); err == nil {
+				t.Fatalf("expected no inbound, got %+v", in)
+			}
+		})
+	}
+}
+
+func TestNewAccount_NamesChannelAndSessions(t *testing.T) {
+	b := bus.New(4)
+	c := NewAccount("work", config.TelegramConfig{
+		AllowFrom: []string{"100"},
+		Business:  &config.TelegramBusinessConfig{Enabled: true},
+	}, b)
+	if c.Name() != "telegram.work" {
+		t.Fatalf("Name() = %q", c.Name())
+	}
+	c.rememberBusinessConnection(&models.BusinessConnection{
+		ID:        "conn",
+		User:      models.User{ID: 100},
+		IsEnabled: true,
+		Rights:    &models.BusinessBotRights{CanReply: true},
+	})
+	c.onBusinessMessage(context.Background(), nil, &models.Message{
+		ID:                   1,
+		BusinessConnectionID: "conn",
+		From:                 &models.User{ID: 200},
+		Chat:                 models.Chat{ID: 200, Type: models.ChatTypePrivate},
+		Text:                 "hi",
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	in, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Channel != "telegram.work" || in.SessionKey != "telegram.work:business:conn:200" {
+		t.Fatalf("unexpected inbound: %+v", in)
+	}
+}
+
+func TestSuggestionKeyboard_RoundTrip(t *testing.T) {
+	kb := suggestionKeyboard([]string{"Tell me more", " ", "Show an example"})
+	if kb == nil || len(kb.InlineKeyboard) != 2 {
+		t.Fatalf("keyboard=%+v", kb)
+	}
+	q := &models.CallbackQuery{
+		Data:    kb.InlineKeyboard[1][0].CallbackData,
+		Message: models.MaybeInaccessibleMessage{Message: &models.Message{ReplyMarkup: kb}},
+	}
+	if got := suggestionText(q); got != "Show an example" {
+		t.Fatalf("suggestionText=%q", got)
+	}
+	if suggestionKeyboard(nil) != nil {
+		t.Fatal("no suggestions should mean no keyboard")
+	}
+	if suggestionText(&models.CallbackQuery{Data: "sg:0"}) != "" {
+		t.Fatal("inaccessible message should yield no text")
+	}
+}
+
+func TestSend_SplitsLongText(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		texts []string
+		marks []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			mu.Lock()
+			texts = append(texts, r.FormValue("text"))
+			marks = append(marks, r.FormValue("reply_markup"))
+			mu.Unlock()
+		}
+		_, _ = io.WriteString(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`)
+	}))
+	defer srv.Close()
+
+	ch := New(config.TelegramConfig{Token: "123:abc", BaseURL: srv.URL}, bus.New(1))
+	long := strings.Repeat(strings.Repeat("word ", 100)+"\n\n", 20)
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "42", Content: long, Suggestions: []string{"more"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) != 3 {
+		t.Fatalf("sent %d messages", len(texts))
+	}
+	for i, s := range texts {
+		if n := utf8.RuneCountInString(s); n > channels.MaxTelegramText {
+			t.Fatalf("message %d has %d characters", i, n)
+		}
+		if hasKeyboard := marks[i] != ""; hasKeyboard != (i == len(texts)-1) {
+			t.Fatalf("message %d keyboard=%q", i, marks[i])
+		}
+	}
+}
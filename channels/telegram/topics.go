@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// Forum topics are addressed as "<chat_id>:topic:<thread_id>", so each topic
+// of a group has a session of its own and replies land in the topic they
+// answer. The General topic is the group itself.
+const topicChatSep = ":topic:"
+
+func topicChatID(chatID string, threadID int) string {
+	return chatID + topicChatSep + strconv.Itoa(threadID)
+}
+
+// messageChatID returns the chat msg was sent in, with its topic when it was
+// sent in a forum topic.
+func messageChatID(msg *models.Message) string {
+	id := strconv.FormatInt(msg.Chat.ID, 10)
+	if msg.IsTopicMessage && msg.MessageThreadID > 0 {
+		return topicChatID(id, msg.MessageThreadID)
+	}
+	return id
+}
+
+// cutTopic splits a chat ID into the chat and its topic; 0 for none.
+func cutTopic(v string) (string, int, error) {
+	chat, thread, ok := strings.Cut(v, topicChatSep)
+	if !ok {
+		return v, 0, nil
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(thread))
+	if err != nil || id <= 0 {
+		return "", 0, fmt.Errorf("invalid topic chat_id: %q", v)
+	}
+	return chat, id, nil
+}
+
+// repliedTo returns the message msg answers, if any. Messages in a forum
+// topic that are not replies still point at the message that opened the
+// topic.
+func repliedTo(msg *models.Message) *models.Message {
+	r := msg.ReplyToMessage
+	if r == nil || (msg.IsTopicMessage && r.ID == msg.MessageThreadID) {
+		return nil
+	}
+	return r
+}
@@ -0,0 +1,129 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// maxTelegramCaption is the caption limit of sendPhoto/sendDocument; longer
+// text is sent as a separate message first.
+const maxTelegramCaption = 1024
+
+// sendAttachments uploads images with sendPhoto, voice notes with
+// sendVoice and everything else with sendDocument. The first file carries the caption and the reply, the last
+// one the suggestion buttons.
+func (c *Channel) sendAttachments(ctx context.Context, b *tgbot.Bot, target telegramTarget, msg bus.OutboundMessage, caption string, reply bool) error {
+	for i, att := range msg.Attachments {
+		data, err := att.ReadData()
+		if err != nil {
+			return fmt.Errorf("telegram attachment %s: %w", att.Name, err)
+		}
+		up := telegramUpload{name: telegramFileName(att), data: data, photo: isTelegramPhoto(att.MIMEType, len(data)), voice: att.IsVoiceNote()}
+		if i == 0 {
+			up.caption = caption
+			if reply {
+				up.replyTo = resolveTelegramReplyTarget(c.cfg.Quote, msg)
+			}
+		}
+		if kb := suggestionKeyboard(msg.Suggestions); kb != nil && i == len(msg.Attachments)-1 && target.BusinessConnectionID == "" {
+			up.markup = kb
+		}
+		err = c.upload(ctx, b, target, up, true)
+		if err != nil && up.caption != "" && isTelegramParseError(err) {
+			err = c.upload(ctx, b, target, up, false)
+		}
+		if err != nil {
+			return redactTelegramError(err, c.cfg.Token)
+		}
+	}
+	return nil
+}
+
+type telegramUpload struct {
+	name    string
+	data    []byte
+	photo   bool
+	voice   bool
+	caption string
+	replyTo int64
+	markup  models.ReplyMarkup
+}
+
+func (c *Channel) upload(ctx context.Context, b *tgbot.Bot, target telegramTarget, up telegramUpload, html bool) error {
+	caption, parseMode := up.caption, models.ParseMode("")
+	if html && caption != "" {
+		caption, parseMode = markdownToTelegramHTML(caption), models.ParseModeHTML
+	}
+	var replyParams *models.ReplyParameters
+	if up.replyTo > 0 {
+		replyParams = &models.ReplyParameters{MessageID: int(up.replyTo), AllowSendingWithoutReply: true}
+	}
+	return withTelegramRetry(ctx, func() error {
+		// Each attempt needs a fresh reader.
+		file := &models.InputFileUpload{Filename: up.name, Data: bytes.NewReader(up.data)}
+		var err error
+		switch {
+		case up.voice:
+			_, err = b.SendVoice(ctx, &tgbot.SendVoiceParams{
+				BusinessConnectionID: target.BusinessConnectionID,
+				ChatID:               target.ChatID,
+				MessageThreadID:      target.ThreadID,
+				Voice:                file,
+				Caption:              caption,
+				ParseMode:            parseMode,
+				ReplyParameters:      replyParams,
+				ReplyMarkup:          up.markup,
+			})
+		case up.photo:
+			_, err = b.SendPhoto(ctx, &tgbot.SendPhotoParams{
+				BusinessConnectionID: target.BusinessConnectionID,
+				ChatID:               target.ChatID,
+				MessageThreadID:      target.ThreadID,
+				Photo:                file,
+				Caption:              caption,
+				ParseMode:            parseMode,
+				ReplyParameters:      replyParams,
+				ReplyMarkup:          up.markup,
+			})
+		default:
+			_, err = b.SendDocument(ctx, &tgbot.SendDocumentParams{
+				BusinessConnectionID: target.BusinessConnectionID,
+				ChatID:               target.ChatID,
+				MessageThreadID:      target.ThreadID,
+				Document:             file,
+				Caption:              caption,
+				ParseMode:            parseMode,
+				ReplyParameters:      replyParams,
+				ReplyMarkup:          up.markup,
+			})
+		}
+		return err
+	})
+}
+
+// isTelegramPhoto reports whether sendPhoto accepts the file: photos are
+// limited to 10MB and other image types (SVG, GIF) must go as documents.
+func isTelegramPhoto(mimeType string, size int) bool {
+	switch strings.ToLower(strings.TrimSpace(mimeType)) {
+	case "image/jpeg", "image/png", "image/webp":
+		return size <= 10<<20
+	}
+	return false
+}
+
+func telegramFileName(att bus.Attachment) string {
+	if name := strings.TrimSpace(att.Name); name != "" {
+		return name
+	}
+	if att.LocalPath != "" {
+		return filepath.Base(att.LocalPath)
+	}
+	return "file"
+}
@@ -0,0 +1,81 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestSend_UploadsAttachments(t *testing.T) {
+	type call struct{ method, caption, file string }
+	var (
+		mu    sync.Mutex
+		calls []call
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := call{method: path.Base(r.URL.Path)}
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			c.caption = r.FormValue("caption")
+			for field, files := range r.MultipartForm.File {
+				f, _ := files[0].Open()
+				b, _ := io.ReadAll(f)
+				f.Close()
+				c.file = field + ":" + files[0].Filename + ":" + string(b)
+			}
+		}
+		mu.Lock()
+		calls = append(calls, c)
+		mu.Unlock()
+		_, _ = io.WriteString(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`)
+	}))
+	defer srv.Close()
+
+	ch := New(config.TelegramConfig{Token: "123:abc", BaseURL: srv.URL}, bus.New(1))
+	err := ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:  "42",
+		Content: "here you go",
+		Attachments: []bus.Attachment{
+			{Name: "chart.png", MIMEType: "image/png", Data: []byte("png")},
+			{Name: "report.pdf", MIMEType: "application/pdf", Data: []byte("pdf")},
+			{Name: "voice.ogg", MIMEType: "audio/ogg", Kind: "audio", Data: []byte("ogg")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []call{
+		{method: "sendPhoto", caption: "here you go", file: "photo:chart.png:png"},
+		{method: "sendDocument", file: "document:report.pdf:pdf"},
+		{method: "sendVoice", file: "voice:voice.ogg:ogg"},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls=%+v", calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("call %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+
+	// Text over the caption limit goes first as a message.
+	calls = nil
+	err = ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:      "42",
+		Content:     strings.Repeat("x", maxTelegramCaption+1),
+		Attachments: []bus.Attachment{{Name: "a.svg", MIMEType: "image/svg+xml", Data: []byte("<svg/>")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[0].method != "sendMessage" || calls[1].method != "sendDocument" || calls[1].caption != "" {
+		t.Fatalf("calls=%+v", calls)
+	}
+}
@@ -0,0 +1,78 @@
+package channels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+const (
+	// typingInterval repeats the indicator before it lapses: Telegram's
+	// lasts 5 seconds, Discord's 10.
+	typingInterval = 4 * time.Second
+	// typingMax ends the indicator of a turn whose done signal got lost.
+	typingMax = 5 * time.Minute
+)
+
+type typingRun struct {
+	cancel context.CancelFunc
+}
+
+// working starts or stops the typing indicator in msg's chat, one per chat
+// however many working signals arrive.
+func (m *Manager) working(ctx context.Context, ch Channel, msg bus.OutboundMessage) {
+	m.stopTyping(msg.Channel, msg.ChatID)
+	t, ok := ch.(Typer)
+	if !ok || !msg.Working || msg.ChatID == "" {
+		return
+	}
+	key := typingKey(msg.Channel, msg.ChatID)
+	tctx, cancel := context.WithTimeout(ctx, typingMax)
+	run := &typingRun{cancel: cancel}
+	m.typingMu.Lock()
+	m.typing[key] = run
+	m.typingMu.Unlock()
+	go func() {
+		defer func() {
+			cancel()
+			m.typingMu.Lock()
+			if m.typing[key] == run {
+				delete(m.typing, key)
+			}
+			m.typingMu.Unlock()
+		}()
+		keepTyping(tctx, t, msg.ChatID)
+	}()
+}
+
+func (m *Manager) stopTyping(channel, chatID string) {
+	m.typingMu.Lock()
+	run := m.typing[typingKey(channel, chatID)]
+	m.typingMu.Unlock()
+	if run != nil {
+		run.cancel()
+	}
+}
+
+// keepTyping sends the indicator every typingInterval until ctx is done. A
+// chat that refuses it (gone, or no permission) is not retried.
+func keepTyping(ctx context.Context, t Typer, chatID string) {
+	tick := time.NewTicker(typingInterval)
+	defer tick.Stop()
+	for {
+		sctx, cancel := context.WithTimeout(ctx, typingInterval)
+		err := t.SendTyping(sctx, chatID)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+func typingKey(channel, chatID string) string { return channel + "\x00" + chatID }
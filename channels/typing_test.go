@@ -0,0 +1,50 @@
+package channels
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+type typingChannel struct {
+	stubChannel
+	typed atomic.Int32
+}
+
+func (c *typingChannel) SendTyping(ctx context.Context, chatID string) error {
+	c.typed.Add(1)
+	return nil
+}
+
+func TestManager_KeepsTypingWhileWorking(t *testing.T) {
+	b := bus.New(16)
+	m := NewManager(b)
+	ch := &typingChannel{stubChannel: stubChannel{name: "stub"}}
+	m.Add(ch)
+	ctx := t.Context()
+	if err := m.StartAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 { // a repeated signal keeps one indicator
+		_ = b.PublishOutbound(ctx, bus.OutboundMessage{Channel: "stub", ChatID: "c1", Kind: bus.MessageKindWorking, Working: true})
+	}
+	waitFor(t, 600*time.Millisecond, func() bool { return ch.typed.Load() >= 1 })
+	m.typingMu.Lock()
+	n := len(m.typing)
+	m.typingMu.Unlock()
+	if n != 1 {
+		t.Fatalf("%d indicators", n)
+	}
+
+	// The reply ends it.
+	_ = b.PublishOutbound(ctx, bus.OutboundMessage{Channel: "stub", ChatID: "c1", Content: "done"})
+	waitFor(t, 600*time.Millisecond, func() bool {
+		m.typingMu.Lock()
+		defer m.typingMu.Unlock()
+		return len(m.typing) == 0
+	})
+}
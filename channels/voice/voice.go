@@ -0,0 +1,411 @@
+package voice
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/channels/httpserver"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/handover"
+)
+
+const (
+	pathIncoming = "/voice"
+	pathGather   = "/voice/gather"
+	pathWait     = "/voice/wait"
+
+	maxFormBytes = 64 << 10
+	maxSayChars  = 3000
+
+	// callIdle is how long a call is kept without webhooks or replies. A
+	// live call reaches a webhook every few seconds.
+	callIdle = 10 * time.Minute
+)
+
+// Channel answers Twilio Programmable Voice calls. Twilio does speech-to-text
+// via <Gather input="speech"> and text-to-speech via <Say>; each recognized
+// utterance becomes one agent turn and the reply is spoken back on the call.
+// Gather is used rather than Media Streams: it needs no WebSocket audio
+// stream or streaming speech-to-text provider.
+type Channel struct {
+	cfg   config.VoiceConfig
+	bus   *bus.Bus
+	allow channels.AllowList
+
+	running atomic.Bool
+	// server, when set, is the shared webhook server used in place of
+	// Listen.
+	server *httpserver.Server
+
+	mu    sync.Mutex
+	srv   *http.Server
+	calls map[string]*call // CallSid -> replies
+}
+
+// call collects the agent's replies to one call. A turn may produce more
+// than one message; those not yet spoken when the reply goes out are spoken
+// before the next one.
+type call struct {
+	texts []string
+	ready chan struct{} // holds a token once the current turn has a reply
+	last  time.Time
+}
+
+func New(cfg config.VoiceConfig, b *bus.Bus) *Channel {
+	return &Channel{
+		cfg:   cfg,
+		bus:   b,
+		allow: channels.AllowList{AllowFrom: cfg.AllowFrom},
+		calls: map[string]*call{},
+	}
+}
+
+func (c *Channel) Name() string    { return "voice" }
+func (c *Channel) IsRunning() bool { return c.running.Load() }
+
+// SetServer serves the webhooks on s instead of on Listen.
+func (c *Channel) SetServer(s *httpserver.Server) { c.server = s }
+
+func (c *Channel) Start(ctx context.Context) error {
+	if strings.TrimSpace(c.cfg.AuthToken) == "" {
+		return errors.New("voice authToken is empty")
+	}
+	if strings.TrimSpace(c.cfg.PublicURL) == "" {
+		return errors.New("voice publicURL is empty")
+	}
+	if c.server != nil {
+		c.running.Store(true)
+		defer c.running.Store(false)
+		return c.server.Attach(ctx, c.Name(), c.Handler(), pathIncoming, pathGather, pathWait)
+	}
+	ln, err := handover.Listen(c.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           c.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      c.replyTimeout() + 10*time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+	c.mu.Lock()
+	c.srv = srv
+	c.mu.Unlock()
+
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+		return ctx.Err()
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+func (c *Channel) Stop() error {
+	if c.server != nil {
+		c.server.Detach(c.Name())
+		return nil
+	}
+	c.mu.Lock()
+	srv := c.srv
+	c.srv = nil
+	c.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// Send queues an agent reply for the call identified by ChatID (CallSid).
+// It is spoken with the other replies queued by then, in order.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	callSid := strings.TrimSpace(msg.ChatID)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cl := c.calls[callSid]
+	if cl == nil {
+		return fmt.Errorf("voice: no active call: %s", callSid)
+	}
+	cl.texts = append(cl.texts, msg.Content)
+	cl.last = time.Now()
+	select {
+	case cl.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (c *Channel) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+pathIncoming, c.handleIncoming)
+	mux.HandleFunc("POST "+pathGather, c.handleGather)
+	mux.HandleFunc("POST "+pathWait, c.handleWait)
+	return mux
+}
+
+func (c *Channel) handleIncoming(w http.ResponseWriter, r *http.Request) {
+	if !c.authorize(w, r) {
+		return
+	}
+	greeting := strings.TrimSpace(c.cfg.Greeting)
+	if greeting == "" {
+		greeting = "Hi, how can I help?"
+	}
+	c.writeTwiML(w, c.gather(greeting))
+}
+
+func (c *Channel) handleGather(w http.ResponseWriter, r *http.Request) {
+	if !c.authorize(w, r) {
+		return
+	}
+	callSid := strings.TrimSpace(r.PostForm.Get("CallSid"))
+	from := strings.TrimSpace(r.PostForm.Get("From"))
+	speech := strings.TrimSpace(r.PostForm.Get("SpeechResult"))
+	if callSid == "" {
+		http.Error(w, "missing CallSid", http.StatusBadRequest)
+		return
+	}
+	if speech == "" {
+		c.writeTwiML(w, c.gather("Sorry, I didn't catch that."))
+		return
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	for sid, cl := range c.calls {
+		if now.Sub(cl.last) > callIdle {
+			delete(c.calls, sid)
+		}
+	}
+	cl := c.calls[callSid]
+	if cl == nil {
+		cl = &call{ready: make(chan struct{}, 1)}
+		c.calls[callSid] = cl
+	}
+	// Replies left over from the last turn wait for this turn's reply.
+	select {
+	case <-cl.ready:
+	default:
+	}
+	cl.last = now
+	c.mu.Unlock()
+
+	err := c.bus.PublishInbound(r.Context(), bus.InboundMessage{
+		Channel:    "voice",
+		SenderID:   from,
+		ChatID:     callSid,
+		Content:    speech,
+		SessionKey: "voice:" + from,
+		Delivery:   bus.Delivery{IsDirect: true},
+	})
+	if err != nil {
+		c.endCall(callSid, cl)
+		c.writeTwiML(w, response{Say: c.say("Sorry, something went wrong."), Hangup: &struct{}{}})
+		return
+	}
+	c.awaitReply(w, r, callSid, cl)
+}
+
+func (c *Channel) handleWait(w http.ResponseWriter, r *http.Request) {
+	if !c.authorize(w, r) {
+		return
+	}
+	callSid := strings.TrimSpace(r.PostForm.Get("CallSid"))
+	c.mu.Lock()
+	cl := c.calls[callSid]
+	c.mu.Unlock()
+	if cl == nil {
+		c.writeTwiML(w, c.gather("Is there anything else?"))
+		return
+	}
+	c.awaitReply(w, r, callSid, cl)
+}
+
+// awaitReply holds the webhook open until the agent replies. Twilio gives up
+// after ~15s, so on timeout the call is redirected to wait again.
+func (c *Channel) awaitReply(w http.ResponseWriter, r *http.Request, callSid string, cl *call) {
+	timer := time.NewTimer(c.replyTimeout())
+	defer timer.Stop()
+	select {
+	case <-cl.ready:
+		c.mu.Lock()
+		texts := cl.texts
+		cl.texts = nil
+		cl.last = time.Now()
+		c.mu.Unlock()
+		c.writeTwiML(w, c.gather(strings.Join(texts, "\n\n")))
+	case <-timer.C:
+		c.writeTwiML(w, response{Say: c.say("One moment."), Redirect: &redirect{Method: http.MethodPost, URL: c.callbackURL(pathWait)}})
+	case <-r.Context().Done():
+		c.endCall(callSid, cl)
+	}
+}
+
+func (c *Channel) endCall(callSid string, cl *call) {
+	c.mu.Lock()
+	if c.calls[callSid] == cl {
+		delete(c.calls, callSid)
+	}
+	c.mu.Unlock()
+}
+
+// authorize parses the form, verifies the Twilio signature, and applies the
+// caller allowlist. It writes the response itself when rejecting.
+func (c *Channel) authorize(w http.ResponseWriter, r *http.Request) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormBytes)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return false
+	}
+	fullURL := strings.TrimRight(strings.TrimSpace(c.cfg.PublicURL), "/") + r.URL.RequestURI()
+	if !ValidSignature(c.cfg.AuthToken, fullURL, r.PostForm, r.Header.Get("X-Twilio-Signature")) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return false
+	}
+	from := strings.TrimSpace(r.PostForm.Get("From"))
+	if !c.allow.Allowed(from) {
+		log.Printf("voice: rejected caller %s", from)
+		c.writeTwiML(w, response{Reject: &reject{Reason: "rejected"}})
+		return false
+	}
+	return true
+}
+
+// ValidSignature checks X-Twilio-Signature: base64(HMAC-SHA1(authToken, url +
+// sorted key/value pairs of the POST form)).
+func ValidSignature(authToken, fullURL string, form map[string][]string, signature string) bool {
+	signature = strings.TrimSpace(signature)
+	if authToken == "" || signature == "" {
+		return false
+	}
+	keys := make([]string, 0, len(form))
+	for k := range form {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(fullURL)
+	for _, k := range keys {
+		for _, v := range form[k] {
+			b.WriteString(k)
+			b.WriteString(v)
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(b.String()))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func (c *Channel) replyTimeout() time.Duration {
+	if c.cfg.ReplyTimeoutSec <= 0 {
+		return time.Duration(config.DefaultVoiceReplyTimeoutSec) * time.Second
+	}
+	return time.Duration(c.cfg.ReplyTimeoutSec) * time.Second
+}
+
+type response struct {
+	XMLName  xml.Name  `xml:"Response"`
+	Say      *say      `xml:"Say,omitempty"`
+	Gather   *gather   `xml:"Gather,omitempty"`
+	Redirect *redirect `xml:"Redirect,omitempty"`
+	Reject   *reject   `xml:"Reject,omitempty"`
+	Hangup   *struct{} `xml:"Hangup,omitempty"`
+}
+
+type say struct {
+	Voice    string `xml:"voice,attr,omitempty"`
+	Language string `xml:"language,attr,omitempty"`
+	Text     string `xml:",chardata"`
+}
+
+type gather struct {
+	Input         string `xml:"input,attr"`
+	Action        string `xml:"action,attr"`
+	Method        string `xml:"method,attr"`
+	Language      string `xml:"language,attr,omitempty"`
+	SpeechTimeout string `xml:"speechTimeout,attr"`
+	Say           *say   `xml:"Say,omitempty"`
+}
+
+type redirect struct {
+	Method string `xml:"method,attr"`
+	URL    string `xml:",chardata"`
+}
+
+type reject struct {
+	Reason string `xml:"reason,attr,omitempty"`
+}
+
+func (c *Channel) say(text string) *say {
+	text = strings.TrimSpace(text)
+	if r := []rune(text); len(r) > maxSayChars {
+		text = string(r[:maxSayChars])
+	}
+	return &say{Voice: c.cfg.TTSVoice, Language: c.cfg.Language, Text: text}
+}
+
+// gather speaks text and listens for the caller's next utterance. If the
+// caller says nothing the call falls through to the redirect and re-prompts.
+func (c *Channel) gather(text string) response {
+	return response{
+		Gather: &gather{
+			Input:         "speech",
+			Action:        c.callbackURL(pathGather),
+			Method:        http.MethodPost,
+			Language:      c.cfg.Language,
+			SpeechTimeout: "auto",
+			Say:           c.say(text),
+		},
+		Redirect: &redirect{Method: http.MethodPost, URL: c.callbackURL(pathIncoming)},
+	}
+}
+
+// callbackURL is the URL Twilio calls for path, under publicURL, so a proxy
+// that serves the channel below a path prefix gets the prefix too.
+func (c *Channel) callbackURL(path string) string {
+	u, err := url.JoinPath(strings.TrimSpace(c.cfg.PublicURL), path)
+	if err != nil {
+		return path
+	}
+	return u
+}
+
+func (c *Channel) writeTwiML(w http.ResponseWriter, resp response) {
+	b, err := xml.Marshal(resp)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(b)
+}
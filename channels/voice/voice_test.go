@@ -0,0 +1,172 @@
+package voice
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+const testPublicURL = "https://voice.example.com"
+
+func sign(token, fullURL string, form url.Values) string {
+	// Independent implementation of Twilio's documented algorithm.
+	s := fullURL
+	for _, k := range []string{"CallSid", "From", "SpeechResult"} {
+		if v, ok := form[k]; ok {
+			s += k + v[0]
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(token))
+	mac.Write([]byte(s))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newSignedRequest(path string, form url.Values, token string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Twilio-Signature", sign(token, testPublicURL+path, form))
+	return req
+}
+
+func TestValidSignature(t *testing.T) {
+	form := url.Values{"CallSid": {"CA1"}, "From": {"+15550001"}}
+	sig := sign("secret", testPublicURL+"/voice", form)
+	if !ValidSignature("secret", testPublicURL+"/voice", form, sig) {
+		t.Fatalf("expected valid signature")
+	}
+	if ValidSignature("other", testPublicURL+"/voice", form, sig) {
+		t.Fatalf("expected invalid signature for wrong token")
+	}
+	if ValidSignature("secret", testPublicURL+"/voice", form, "") {
+		t.Fatalf("expected invalid signature when missing")
+	}
+}
+
+func TestHandler_RejectsUnsignedAndDisallowed(t *testing.T) {
+	c := New(config.VoiceConfig{AuthToken: "secret", PublicURL: testPublicURL, AllowFrom: []string{"+15550001"}}, bus.New(1))
+	h := c.Handler()
+
+	form := url.Values{"CallSid": {"CA1"}, "From": {"+15550001"}}
+	req := newSignedRequest("/voice", form, "wrong")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+
+	form = url.Values{"CallSid": {"CA1"}, "From": {"+15559999"}}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newSignedRequest("/voice", form, "secret"))
+	if !strings.Contains(rec.Body.String(), "<Reject") {
+		t.Fatalf("expected reject TwiML, got %s", rec.Body.String())
+	}
+}
+
+func TestHandler_GatherRoundTrip(t *testing.T) {
+	b := bus.New(1)
+	c := New(config.VoiceConfig{AuthToken: "secret", PublicURL: testPublicURL, ReplyTimeoutSec: 5, TTSVoice: "Polly.Joanna"}, b)
+	h := c.Handler()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		in, err := b.ConsumeInbound(ctx)
+		if err != nil {
+			return
+		}
+		if in.SessionKey != "voice:+15550001" || in.Content != "what time is it" {
+			t.Errorf("unexpected inbound: %+v", in)
+		}
+		_ = c.Send(ctx, bus.OutboundMessage{Channel: "voice", ChatID: in.ChatID, Content: "It is noon & sunny."})
+	}()
+
+	form := url.Values{"CallSid": {"CA1"}, "From": {"+15550001"}, "SpeechResult": {"what time is it"}}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newSignedRequest("/voice/gather", form, "secret"))
+	body := rec.Body.String()
+	if !strings.Contains(body, "It is noon &amp; sunny.") || !strings.Contains(body, `<Gather input="speech"`) {
+		t.Fatalf("unexpected TwiML: %s", body)
+	}
+	if !strings.Contains(body, `voice="Polly.Joanna"`) {
+		t.Fatalf("expected tts voice attribute: %s", body)
+	}
+	if err := c.Send(context.Background(), bus.OutboundMessage{ChatID: "CA9", Content: "late"}); err == nil {
+		t.Fatalf("expected error for unknown call")
+	}
+}
+
+func TestHandler_SpeaksEveryReplyOfATurn(t *testing.T) {
+	b := bus.New(1)
+	c := New(config.VoiceConfig{AuthToken: "secret", PublicURL: testPublicURL, ReplyTimeoutSec: 5}, b)
+	h := c.Handler()
+	turn := func(speech string, replies ...string) string {
+		t.Helper()
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			in, err := b.ConsumeInbound(ctx)
+			if err != nil {
+				return
+			}
+			for _, r := range replies {
+				if err := c.Send(ctx, bus.OutboundMessage{ChatID: in.ChatID, Content: r}); err != nil {
+					t.Errorf("send %q: %v", r, err)
+				}
+			}
+		}()
+		form := url.Values{"CallSid": {"CA1"}, "From": {"+15550001"}, "SpeechResult": {speech}}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newSignedRequest("/voice/gather", form, "secret"))
+		return rec.Body.String()
+	}
+
+	body := turn("check my calendar", "Let me look.", "You have one meeting.")
+	// A reply sent after the webhook answered is spoken on the next one.
+	if err := c.Send(context.Background(), bus.OutboundMessage{ChatID: "CA1", Content: "It is at noon."}); err != nil {
+		t.Fatal(err)
+	}
+	body += turn("thanks", "You're welcome.")
+
+	last := -1
+	for _, want := range []string{"Let me look.", "You have one meeting.", "It is at noon.", "You&#39;re welcome."} {
+		i := strings.Index(body, want)
+		if i <= last {
+			t.Fatalf("%q missing or out of order in %s", want, body)
+		}
+		last = i
+	}
+}
+
+func TestHandler_ReplyTimeoutRedirects(t *testing.T) {
+	c := New(config.VoiceConfig{AuthToken: "secret", PublicURL: testPublicURL, ReplyTimeoutSec: 1}, bus.New(1))
+	form := url.Values{"CallSid": {"CA2"}, "From": {"+15550001"}, "SpeechResult": {"hello"}}
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, newSignedRequest("/voice/gather", form, "secret"))
+	if !strings.Contains(rec.Body.String(), ">"+testPublicURL+"/voice/wait</Redirect>") {
+		t.Fatalf("expected wait redirect, got %s", rec.Body.String())
+	}
+	if err := c.Send(context.Background(), bus.OutboundMessage{ChatID: "CA2", Content: "done"}); err != nil {
+		t.Fatalf("expected reply to be queued for the waiting call: %v", err)
+	}
+}
+
+func TestGather_URLsUnderPublicURLPrefix(t *testing.T) {
+	c := New(config.VoiceConfig{PublicURL: "https://example.com/twilio/"}, bus.New(1))
+	resp := c.gather("hi")
+	if resp.Gather.Action != "https://example.com/twilio/voice/gather" {
+		t.Fatalf("action = %q", resp.Gather.Action)
+	}
+	if resp.Redirect.URL != "https://example.com/twilio/voice" {
+		t.Fatalf("redirect = %q", resp.Redirect.URL)
+	}
+}
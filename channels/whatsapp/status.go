@@ -0,0 +1,151 @@
+package whatsapp
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// sentLogSize is how many sent messages are followed for receipts.
+const sentLogSize = 500
+
+var statusRank = map[string]int{bus.StatusSent: 0, bus.StatusDelivered: 1, bus.StatusRead: 2}
+
+type sentMessage struct {
+	chatID  string
+	to      types.JID
+	payload *waE2E.Message
+	state   string
+	resent  bool // the copy of a message that failed; not sent again
+}
+
+// sentLog remembers recently sent messages by ID, so receipts can be
+// reported as statuses and a failed message sent again. The oldest message
+// is forgotten first.
+type sentLog struct {
+	mu    sync.Mutex
+	order []string
+	byID  map[string]*sentMessage
+}
+
+func (l *sentLog) add(id string, m sentMessage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.byID == nil {
+		l.byID = map[string]*sentMessage{}
+	}
+	if _, ok := l.byID[id]; ok {
+		return
+	}
+	m.state = bus.StatusSent
+	l.byID[id] = &m
+	l.order = append(l.order, id)
+	if len(l.order) > sentLogSize {
+		delete(l.byID, l.order[0])
+		l.order = slices.Delete(l.order, 0, 1)
+	}
+}
+
+// advance moves message id to state and reports whether that is news:
+// delivered and read only move forward, and a failure is reported once.
+func (l *sentLog) advance(id, state string) (sentMessage, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m, ok := l.byID[id]
+	if !ok || m.state == state || m.state == bus.StatusFailed {
+		return sentMessage{}, false
+	}
+	if state != bus.StatusFailed && statusRank[state] <= statusRank[m.state] {
+		return sentMessage{}, false
+	}
+	m.state = state
+	return *m, true
+}
+
+// receiptState maps a receipt from the recipient to a delivery state; ""
+// for receipts that say nothing about delivery, such as those from the
+// owner's other devices.
+func receiptState(t types.ReceiptType) string {
+	switch t {
+	case types.ReceiptTypeDelivered:
+		return bus.StatusDelivered
+	case types.ReceiptTypeRead, types.ReceiptTypePlayed:
+		return bus.StatusRead
+	case types.ReceiptTypeServerError:
+		return bus.StatusFailed
+	default:
+		return ""
+	}
+}
+
+// trackSent starts following a message the agent sent and reports it as
+// sent.
+func (c *Channel) trackSent(id, chatID string, to types.JID, payload *waE2E.Message, resent bool) {
+	if id == "" {
+		return
+	}
+	c.sent.add(id, sentMessage{chatID: chatID, to: to, payload: payload, resent: resent})
+	c.publishStatus(chatID, bus.Status{MessageID: id, State: bus.StatusSent})
+}
+
+func (c *Channel) handleReceipt(evt *events.Receipt) {
+	state := receiptState(evt.Type)
+	if state == "" {
+		return
+	}
+	for _, id := range evt.MessageIDs {
+		m, ok := c.sent.advance(id, state)
+		if !ok {
+			continue
+		}
+		st := bus.Status{MessageID: id, State: state}
+		if state == bus.StatusFailed && !m.resent {
+			// Receipts are handled on whatsmeow's event loop; sending waits
+			// for the server.
+			go c.resend(st, m)
+			continue
+		}
+		c.publishStatus(m.chatID, st)
+	}
+}
+
+// resend sends a failed message once more and reports the failure, with the
+// new copy's ID when it went out.
+func (c *Channel) resend(st bus.Status, m sentMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	c.mu.Lock()
+	wa := c.wa
+	c.mu.Unlock()
+	if wa != nil {
+		id, err := sendWithRetry(ctx, wa, m.to, m.payload)
+		if err != nil {
+			log.Printf("whatsapp: resend of %s failed: %v", st.MessageID, err)
+		} else if id != "" {
+			st.Resent, st.ResentID = true, id
+			defer c.trackSent(id, m.chatID, m.to, m.payload, true)
+		}
+	}
+	c.publishStatus(m.chatID, st)
+}
+
+// publishStatus waits briefly for room on the bus: statuses are published
+// while the channel sends and must not hold up the reply.
+func (c *Channel) publishStatus(chatID string, st bus.Status) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = c.bus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:    "whatsapp",
+		ChatID:     chatID,
+		SessionKey: "whatsapp:" + chatID,
+		Kind:       bus.MessageKindStatus,
+		Status:     st,
+	})
+}
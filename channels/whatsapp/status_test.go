@@ -0,0 +1,70 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestSentLog_Advance(t *testing.T) {
+	var l sentLog
+	l.add("a", sentMessage{chatID: "1@s.whatsapp.net"})
+	for _, tc := range []struct {
+		state string
+		news  bool
+	}{
+		{bus.StatusDelivered, true},
+		{bus.StatusDelivered, false}, // another group member
+		{bus.StatusRead, true},
+		{bus.StatusDelivered, false}, // late
+		{bus.StatusFailed, true},
+		{bus.StatusFailed, false},
+	} {
+		if _, news := l.advance("a", tc.state); news != tc.news {
+			t.Fatalf("%s: news=%v, want %v", tc.state, news, tc.news)
+		}
+	}
+	if _, news := l.advance("unknown", bus.StatusRead); news {
+		t.Fatal("untracked message reported")
+	}
+
+	for i := range sentLogSize {
+		l.add(string(rune('b'+i)), sentMessage{})
+	}
+	if _, ok := l.byID["a"]; ok || len(l.order) != sentLogSize {
+		t.Fatalf("log not bounded: %d", len(l.order))
+	}
+}
+
+func TestHandleReceipt_PublishesStatus(t *testing.T) {
+	b := bus.New(10)
+	c := New(config.WhatsAppConfig{}, b)
+	c.sent.add("m1", sentMessage{chatID: "1@s.whatsapp.net"})
+
+	c.handleEvent(&events.Receipt{MessageIDs: []string{"m1", "other"}, Type: types.ReceiptTypeReadSelf})
+	c.handleEvent(&events.Receipt{MessageIDs: []string{"m1", "other"}, Type: types.ReceiptTypeRead})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != bus.MessageKindStatus || got.ChatID != "1@s.whatsapp.net" || got.Status.MessageID != "m1" || got.Status.State != bus.StatusRead {
+		t.Fatalf("status %+v", got)
+	}
+
+	// Not connected, so the failed message cannot go out again.
+	c.handleEvent(&events.Receipt{MessageIDs: []string{"m1"}, Type: types.ReceiptTypeServerError})
+	got, err = b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.State != bus.StatusFailed || got.Status.Resent {
+		t.Fatalf("status %+v", got.Status)
+	}
+}
@@ -0,0 +1,85 @@
+package whatsapp
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A linked device cannot send WhatsApp's interactive buttons (they are
+// reserved for Business API senders), so follow-up suggestions are shown as
+// a numbered list and a reply consisting of just the number picks one.
+
+var suggestionKeycaps = []string{"1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣"}
+
+// maxSuggestionChats bounds how many chats keep pending suggestions.
+const maxSuggestionChats = 1000
+
+func formatSuggestions(text string, items []string) string {
+	var lines []string
+	for _, it := range items {
+		if it = strings.TrimSpace(it); it == "" || len(lines) == len(suggestionKeycaps) {
+			continue
+		}
+		lines = append(lines, suggestionKeycaps[len(lines)]+" "+it)
+	}
+	if len(lines) == 0 {
+		return text
+	}
+	return text + "\n\n" + strings.Join(lines, "\n")
+}
+
+// suggestionMemo keeps the suggestions last sent to each chat until the
+// chat's next inbound message.
+type suggestionMemo struct {
+	mu     sync.Mutex
+	byChat map[string][]string
+}
+
+func (m *suggestionMemo) remember(chatID string, items []string) {
+	var kept []string
+	for _, it := range items {
+		if it = strings.TrimSpace(it); it != "" && len(kept) < len(suggestionKeycaps) {
+			kept = append(kept, it)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(kept) == 0 {
+		delete(m.byChat, chatID)
+		return
+	}
+	if m.byChat == nil {
+		m.byChat = map[string][]string{}
+	}
+	if _, ok := m.byChat[chatID]; !ok && len(m.byChat) >= maxSuggestionChats {
+		for k := range m.byChat {
+			delete(m.byChat, k)
+			break
+		}
+	}
+	m.byChat[chatID] = kept
+}
+
+// resolve returns the suggestion content picks ("2" or "2️⃣"), or content
+// unchanged. Pending suggestions are dropped either way.
+func (m *suggestionMemo) resolve(chatID, content string) string {
+	m.mu.Lock()
+	items := m.byChat[chatID]
+	delete(m.byChat, chatID)
+	m.mu.Unlock()
+	if len(items) == 0 {
+		return content
+	}
+	pick := strings.TrimSpace(content)
+	for i, k := range suggestionKeycaps {
+		if pick == k {
+			pick = strconv.Itoa(i + 1)
+		}
+	}
+	n, err := strconv.Atoi(pick)
+	if err != nil || n < 1 || n > len(items) {
+		return content
+	}
+	return items[n-1]
+}
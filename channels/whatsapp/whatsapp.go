@@ -13,6 +13,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/mdp/qrterminal/v3"
 	"github.com/mosaxiv/clawlet/bus"
@@ -43,6 +44,12 @@ type Channel struct {
 	cancel context.CancelFunc
 	wa     *whatsmeow.Client
 	db     *sqlstore.Container
+
+	suggestions suggestionMemo
+	// seen drops messages whatsmeow delivers again, e.g. after a reconnect.
+	seen *channels.Deduper
+	// sent follows the agent's messages for delivery receipts.
+	sent sentLog
 }
 
 func New(cfg config.WhatsAppConfig, b *bus.Bus) *Channel {
@@ -60,12 +67,21 @@ func newChannel(cfg config.WhatsAppConfig, b *bus.Bus, allowQRLogin bool) *Chann
 		allow:            channels.AllowList{AllowFrom: cfg.AllowFrom},
 		sessionStorePath: resolveWhatsAppSessionStorePath(cfg.SessionStorePath),
 		allowQRLogin:     allowQRLogin,
+		seen:             channels.NewDeduper(0, ""),
 	}
 }
 
+func (c *Channel) SetDeduper(d *channels.Deduper) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen = d
+}
+
 func (c *Channel) Name() string    { return "whatsapp" }
 func (c *Channel) IsRunning() bool { return c.running.Load() }
 
+func (c *Channel) SupportsAttachments() bool { return true }
+
 func (c *Channel) Start(ctx context.Context) error {
 	runCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -147,7 +163,7 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 		return err
 	}
 	text := strings.TrimSpace(msg.Content)
-	if text == "" {
+	if text == "" && len(msg.Attachments) == 0 {
 		return nil
 	}
 
@@ -158,33 +174,83 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 		return fmt.Errorf("whatsapp not connected")
 	}
 
-	payload := buildOutboundMessage(text, resolveWhatsAppReplyTarget(msg))
+	replyTo := resolveWhatsAppReplyTarget(c.cfg.Quote, msg)
+	if text != "" {
+		text = formatSuggestions(text, msg.Suggestions)
+	}
+	// Voice notes carry no caption.
+	if len(msg.Attachments) == 0 || utf8.RuneCountInString(text) > maxWhatsAppCaption || msg.Attachments[0].IsVoiceNote() {
+		for _, part := range channels.SplitterOrDefault(c.cfg.Split)(text, channels.MaxWhatsAppText) {
+			payload := buildOutboundMessage(part, replyTo)
+			id, err := sendWithRetry(ctx, wa, to, payload)
+			if err != nil {
+				return err
+			}
+			c.trackSent(id, msg.ChatID, to, payload, false)
+			replyTo = ""
+		}
+		text, replyTo = "", ""
+	}
+	for i, att := range msg.Attachments {
+		data, err := att.ReadData()
+		if err != nil {
+			return fmt.Errorf("whatsapp attachment %s: %w", att.Name, err)
+		}
+		mediaType := whatsmeow.MediaDocument
+		switch {
+		case isWhatsAppImage(att.MIMEType):
+			mediaType = whatsmeow.MediaImage
+		case att.IsVoiceNote():
+			mediaType = whatsmeow.MediaAudio
+		}
+		up, err := wa.Upload(ctx, data, mediaType)
+		if err != nil {
+			return fmt.Errorf("whatsapp upload %s: %w", att.Name, err)
+		}
+		caption, reply := text, replyTo
+		if i > 0 {
+			caption, reply = "", ""
+		}
+		payload := buildMediaMessage(att, up, caption, reply)
+		id, err := sendWithRetry(ctx, wa, to, payload)
+		if err != nil {
+			return err
+		}
+		c.trackSent(id, msg.ChatID, to, payload, false)
+	}
+	c.suggestions.remember(msg.ChatID, msg.Suggestions)
+	return nil
+}
 
+// sendWithRetry sends payload and returns the ID WhatsApp gave it.
+func sendWithRetry(ctx context.Context, wa *whatsmeow.Client, to types.JID, payload *waE2E.Message) (string, error) {
 	const maxAttempts = 3
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		_, err = wa.SendMessage(ctx, to, payload)
+		resp, err := wa.SendMessage(ctx, to, payload)
 		if err == nil {
-			return nil
+			return resp.ID, nil
 		}
 		retry, wait := shouldRetryWhatsAppSend(err, attempt)
 		if !retry || attempt == maxAttempts {
-			return err
+			return "", err
 		}
 		t := time.NewTimer(wait)
 		select {
 		case <-ctx.Done():
 			t.Stop()
-			return ctx.Err()
+			return "", ctx.Err()
 		case <-t.C:
 		}
 	}
-	return nil
+	return "", nil
 }
 
 func (c *Channel) handleEvent(raw any) {
 	switch evt := raw.(type) {
 	case *events.Message:
 		c.handleIncomingMessage(evt)
+	case *events.Receipt:
+		c.handleReceipt(evt)
 	case *events.LoggedOut:
 		log.Printf("whatsapp: logged out")
 	case *events.Connected:
@@ -207,26 +273,37 @@ func (c *Channel) handleIncomingMessage(evt *events.Message) {
 		return
 	}
 
-	content := whatsappMessageContent(evt.Message)
 	c.mu.Lock()
-	wa := c.wa
+	wa, seen := c.wa, c.seen
 	c.mu.Unlock()
+	if seen.Seen("whatsapp", evt.Info.ID) {
+		return
+	}
+
+	content := whatsappMessageContent(evt.Message)
 	attachments := whatsappInboundAttachments(context.Background(), wa, evt.Message, config.DefaultMediaMaxFileBytes)
+	if hasVoiceData(evt.Message, attachments) {
+		// The voice note is transcribed from the attachment; the placeholder
+		// would only repeat it.
+		content = ""
+	}
 	if content == "" && len(attachments) == 0 {
 		return
 	}
 
 	chatID := evt.Info.Chat.String()
+	if len(attachments) == 0 {
+		content = c.suggestions.resolve(chatID, content)
+	}
 	delivery := bus.Delivery{
 		MessageID: strings.TrimSpace(evt.Info.ID),
 		IsDirect:  !evt.Info.IsGroup,
+		SentAt:    evt.Info.Timestamp,
 	}
 	if replyToID := whatsappReplyToID(evt.Message); replyToID != "" {
 		delivery.ReplyToID = replyToID
 	}
-
-	publishCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	_ = c.bus.PublishInbound(publishCtx, bus.InboundMessage{
+	in := bus.InboundMessage{
 		Channel:     "whatsapp",
 		SenderID:    senderID,
 		ChatID:      chatID,
@@ -234,7 +311,13 @@ func (c *Channel) handleIncomingMessage(evt *events.Message) {
 		Attachments: attachments,
 		SessionKey:  "whatsapp:" + chatID,
 		Delivery:    delivery,
-	})
+	}
+	if react := evt.Message.GetReactionMessage(); react != nil {
+		in.Kind, in.Reaction = bus.MessageKindReaction, whatsappReaction(react)
+	}
+
+	publishCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	_ = c.bus.PublishInbound(publishCtx, in)
 	cancel()
 }
 
@@ -381,17 +464,83 @@ func buildOutboundMessage(text, replyToID string) *waE2E.Message {
 	return &waE2E.Message{Conversation: new(text)}
 }
 
-func resolveWhatsAppReplyTarget(msg bus.OutboundMessage) string {
-	candidates := []string{
-		strings.TrimSpace(msg.Delivery.ReplyToID),
-		strings.TrimSpace(msg.ReplyTo),
+// maxWhatsAppCaption is the longest caption sent with a file; longer text
+// goes out as its own message first.
+const maxWhatsAppCaption = 1024
+
+// isWhatsAppImage reports whether the file can be sent as an image message;
+// other types (including GIF and WebP) are sent as documents.
+func isWhatsAppImage(mimeType string) bool {
+	switch strings.ToLower(strings.TrimSpace(mimeType)) {
+	case "image/jpeg", "image/png":
+		return true
 	}
-	for _, v := range candidates {
-		if v != "" {
-			return v
+	return false
+}
+
+func buildMediaMessage(att bus.Attachment, up whatsmeow.UploadResponse, caption, replyToID string) *waE2E.Message {
+	var ctxInfo *waE2E.ContextInfo
+	if id := strings.TrimSpace(replyToID); id != "" {
+		ctxInfo = &waE2E.ContextInfo{StanzaID: new(id)}
+	}
+	var captionPtr *string
+	if caption != "" {
+		captionPtr = new(caption)
+	}
+	mimeType := strings.TrimSpace(att.MIMEType)
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	if isWhatsAppImage(mimeType) {
+		return &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+			Caption:       captionPtr,
+			Mimetype:      new(mimeType),
+			URL:           new(up.URL),
+			DirectPath:    new(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    new(up.FileLength),
+			ContextInfo:   ctxInfo,
+		}}
+	}
+	if att.IsVoiceNote() {
+		return &waE2E.Message{AudioMessage: &waE2E.AudioMessage{
+			Mimetype:      new("audio/ogg; codecs=opus"),
+			PTT:           new(true),
+			URL:           new(up.URL),
+			DirectPath:    new(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    new(up.FileLength),
+			ContextInfo:   ctxInfo,
+		}}
+	}
+	name := strings.TrimSpace(att.Name)
+	if name == "" {
+		name = "file"
+		if att.LocalPath != "" {
+			name = filepath.Base(att.LocalPath)
 		}
 	}
-	return ""
+	return &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
+		Caption:       captionPtr,
+		FileName:      new(name),
+		Title:         new(name),
+		Mimetype:      new(mimeType),
+		URL:           new(up.URL),
+		DirectPath:    new(up.DirectPath),
+		MediaKey:      up.MediaKey,
+		FileEncSHA256: up.FileEncSHA256,
+		FileSHA256:    up.FileSHA256,
+		FileLength:    new(up.FileLength),
+		ContextInfo:   ctxInfo,
+	}}
+}
+
+func resolveWhatsAppReplyTarget(quote string, msg bus.OutboundMessage) string {
+	return channels.ReplyTarget(quote, msg)
 }
 
 func shouldRetryWhatsAppSend(err error, attempt int) (bool, time.Duration) {
@@ -472,6 +621,12 @@ func whatsappMessageContent(msg *waE2E.Message) string {
 	if msg.GetAudioMessage() != nil {
 		return "[Voice Message]"
 	}
+	if sticker := msg.GetStickerMessage(); sticker != nil {
+		if sticker.GetIsAnimated() {
+			return "[Animated Sticker]"
+		}
+		return "[Sticker]"
+	}
 	if react := msg.GetReactionMessage(); react != nil {
 		if emoji := strings.TrimSpace(react.GetText()); emoji != "" {
 			return "[Reaction] " + emoji
@@ -481,6 +636,17 @@ func whatsappMessageContent(msg *waE2E.Message) string {
 	return ""
 }
 
+// whatsappReaction reads a reaction; an empty emoji takes the sender's
+// reaction back.
+func whatsappReaction(react *waE2E.ReactionMessage) bus.Reaction {
+	emoji := strings.TrimSpace(react.GetText())
+	return bus.Reaction{
+		Emoji:     emoji,
+		MessageID: strings.TrimSpace(react.GetKey().GetID()),
+		Removed:   emoji == "",
+	}
+}
+
 func whatsappReplyToID(msg *waE2E.Message) string {
 	if msg == nil {
 		return ""
@@ -493,6 +659,19 @@ func whatsappReplyToID(msg *waE2E.Message) string {
 	return ""
 }
 
+// hasVoiceData reports whether msg is a voice note that was downloaded.
+func hasVoiceData(msg *waE2E.Message, attachments []bus.Attachment) bool {
+	if msg == nil || msg.GetAudioMessage() == nil {
+		return false
+	}
+	for _, a := range attachments {
+		if a.Kind == "audio" && len(a.Data) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func whatsappInboundAttachments(ctx context.Context, wa *whatsmeow.Client, msg *waE2E.Message, maxBytes int64) []bus.Attachment {
 	if msg == nil {
 		return nil
@@ -503,7 +682,7 @@ func whatsappInboundAttachments(ctx context.Context, wa *whatsmeow.Client, msg *
 	out := make([]bus.Attachment, 0, 4)
 	if image := msg.GetImageMessage(); image != nil {
 		mimeType := strings.TrimSpace(image.GetMimetype())
-		data := whatsappDownloadAttachment(ctx, wa, image, maxBytes)
+		data := whatsappDownloadAttachment(ctx, wa, image, image.GetFileLength(), maxBytes)
 		out = append(out, bus.Attachment{
 			Name:      "image",
 			MIMEType:  mimeType,
@@ -514,7 +693,7 @@ func whatsappInboundAttachments(ctx context.Context, wa *whatsmeow.Client, msg *
 	}
 	if video := msg.GetVideoMessage(); video != nil {
 		mimeType := strings.TrimSpace(video.GetMimetype())
-		data := whatsappDownloadAttachment(ctx, wa, video, maxBytes)
+		data := whatsappDownloadAttachment(ctx, wa, video, video.GetFileLength(), maxBytes)
 		out = append(out, bus.Attachment{
 			Name:      "video",
 			MIMEType:  mimeType,
@@ -525,7 +704,7 @@ func whatsappInboundAttachments(ctx context.Context, wa *whatsmeow.Client, msg *
 	}
 	if doc := msg.GetDocumentMessage(); doc != nil {
 		mimeType := strings.TrimSpace(doc.GetMimetype())
-		data := whatsappDownloadAttachment(ctx, wa, doc, maxBytes)
+		data := whatsappDownloadAttachment(ctx, wa, doc, doc.GetFileLength(), maxBytes)
 		out = append(out, bus.Attachment{
 			Name:      strings.TrimSpace(doc.GetFileName()),
 			MIMEType:  mimeType,
@@ -536,7 +715,7 @@ func whatsappInboundAttachments(ctx context.Context, wa *whatsmeow.Client, msg *
 	}
 	if audio := msg.GetAudioMessage(); audio != nil {
 		mimeType := strings.TrimSpace(audio.GetMimetype())
-		data := whatsappDownloadAttachment(ctx, wa, audio, maxBytes)
+		data := whatsappDownloadAttachment(ctx, wa, audio, audio.GetFileLength(), maxBytes)
 		out = append(out, bus.Attachment{
 			Name:      "voice",
 			MIMEType:  mimeType,
@@ -545,6 +724,20 @@ func whatsappInboundAttachments(ctx context.Context, wa *whatsmeow.Client, msg *
 			Data:      data,
 		})
 	}
+	if sticker := msg.GetStickerMessage(); sticker != nil {
+		mimeType := strings.TrimSpace(sticker.GetMimetype())
+		if mimeType == "" {
+			mimeType = "image/webp"
+		}
+		data := whatsappDownloadAttachment(ctx, wa, sticker, sticker.GetFileLength(), maxBytes)
+		out = append(out, bus.Attachment{
+			Name:      "sticker",
+			MIMEType:  mimeType,
+			Kind:      bus.InferAttachmentKind(mimeType),
+			SizeBytes: int64(sticker.GetFileLength()),
+			Data:      data,
+		})
+	}
 	if len(out) == 0 {
 		return nil
 	}
@@ -556,15 +749,26 @@ func whatsappInboundAttachments(ctx context.Context, wa *whatsmeow.Client, msg *
 	return out
 }
 
-func whatsappDownloadAttachment(ctx context.Context, wa *whatsmeow.Client, media whatsmeow.DownloadableMessage, maxBytes int64) []byte {
+// whatsappDownloadAttachment fetches and decrypts the media. Files whose
+// declared size is over maxBytes are not downloaded; the attachment is then
+// passed on without data, like a failed download.
+func whatsappDownloadAttachment(ctx context.Context, wa *whatsmeow.Client, media whatsmeow.DownloadableMessage, size uint64, maxBytes int64) []byte {
 	if wa == nil || media == nil {
 		return nil
 	}
-	dlCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	if maxBytes > 0 && size > uint64(maxBytes) {
+		log.Printf("whatsapp: media too large to download (%d > %d bytes)", size, maxBytes)
+		return nil
+	}
+	dlCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
 	data, err := wa.Download(dlCtx, media)
-	if err != nil || len(data) == 0 {
+	if err != nil {
+		log.Printf("whatsapp: media download failed: %v", err)
+		return nil
+	}
+	if len(data) == 0 {
 		return nil
 	}
 	if maxBytes > 0 && int64(len(data)) > maxBytes {
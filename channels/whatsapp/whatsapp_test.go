@@ -8,13 +8,14 @@ import (
 
 	"github.com/mosaxiv/clawlet/bus"
 	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waCommon"
 	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 )
 
 func TestResolveWhatsAppReplyTarget(t *testing.T) {
 	t.Run("prefer delivery reply id", func(t *testing.T) {
-		got := resolveWhatsAppReplyTarget(bus.OutboundMessage{
+		got := resolveWhatsAppReplyTarget("", bus.OutboundMessage{
 			ReplyTo: "legacy",
 			Delivery: bus.Delivery{
 				ReplyToID: "typed",
@@ -26,7 +27,7 @@ func TestResolveWhatsAppReplyTarget(t *testing.T) {
 	})
 
 	t.Run("fallback legacy reply_to", func(t *testing.T) {
-		got := resolveWhatsAppReplyTarget(bus.OutboundMessage{
+		got := resolveWhatsAppReplyTarget("", bus.OutboundMessage{
 			ReplyTo: "legacy",
 		})
 		if got != "legacy" {
@@ -97,6 +98,13 @@ func TestWhatsAppMessageContent(t *testing.T) {
 			t.Fatalf("got %q", got)
 		}
 	})
+
+	t.Run("sticker", func(t *testing.T) {
+		msg := &waE2E.Message{StickerMessage: &waE2E.StickerMessage{IsAnimated: new(true)}}
+		if got := whatsappMessageContent(msg); got != "[Animated Sticker]" {
+			t.Fatalf("got %q", got)
+		}
+	})
 }
 
 func TestWhatsAppReplyToID(t *testing.T) {
@@ -150,6 +158,11 @@ func TestWhatsAppInboundAttachments(t *testing.T) {
 	if !foundDoc {
 		t.Fatalf("memo.pdf not found: %+v", got)
 	}
+
+	sticker := whatsappInboundAttachments(context.Background(), nil, &waE2E.Message{StickerMessage: &waE2E.StickerMessage{}}, 1024)
+	if len(sticker) != 1 || sticker[0].Name != "sticker" || sticker[0].MIMEType != "image/webp" || sticker[0].Kind != "image" {
+		t.Fatalf("unexpected sticker attachment: %+v", sticker)
+	}
 }
 
 func TestShouldRetryWhatsAppSend(t *testing.T) {
@@ -213,3 +226,67 @@ func TestSQLiteFileDSN(t *testing.T) {
 		t.Fatalf("expected %q, got %q", want, got)
 	}
 }
+
+func TestSuggestions_NumberPicksSuggestion(t *testing.T) {
+	got := formatSuggestions("Done.", []string{"Tell me more", "Show an example"})
+	if got != "Done.\n\n1️⃣ Tell me more\n2️⃣ Show an example" {
+		t.Fatalf("format: %q", got)
+	}
+	var m suggestionMemo
+	m.remember("chat", []string{"Tell me more", "Show an example"})
+	if got := m.resolve("chat", " 2 "); got != "Show an example" {
+		t.Fatalf("resolve: %q", got)
+	}
+	// Suggestions only apply to the next message.
+	if got := m.resolve("chat", "1"); got != "1" {
+		t.Fatalf("stale resolve: %q", got)
+	}
+	m.remember("chat", []string{"Tell me more"})
+	if got := m.resolve("chat", "1️⃣"); got != "Tell me more" {
+		t.Fatalf("keycap resolve: %q", got)
+	}
+	m.remember("chat", []string{"Tell me more"})
+	if got := m.resolve("chat", "5"); got != "5" {
+		t.Fatalf("out of range: %q", got)
+	}
+}
+
+func TestBuildMediaMessage(t *testing.T) {
+	up := whatsmeow.UploadResponse{URL: "https://mmg.example/x", DirectPath: "/x", FileLength: 3}
+
+	img := buildMediaMessage(bus.Attachment{Name: "chart.png", MIMEType: "image/png"}, up, "look", "wamid.1")
+	if img.GetImageMessage() == nil || img.GetImageMessage().GetCaption() != "look" {
+		t.Fatalf("expected captioned image, got %+v", img)
+	}
+	if img.ImageMessage.ContextInfo.GetStanzaID() != "wamid.1" || img.GetImageMessage().GetFileLength() != 3 {
+		t.Fatalf("unexpected image message: %+v", img.ImageMessage)
+	}
+
+	doc := buildMediaMessage(bus.Attachment{LocalPath: "/tmp/report.pdf", MIMEType: "application/pdf"}, up, "", "")
+	if doc.GetDocumentMessage() == nil || doc.GetDocumentMessage().GetFileName() != "report.pdf" {
+		t.Fatalf("expected document, got %+v", doc)
+	}
+	if doc.DocumentMessage.Caption != nil || doc.DocumentMessage.ContextInfo != nil {
+		t.Fatalf("unexpected caption/reply: %+v", doc.DocumentMessage)
+	}
+
+	if gif := buildMediaMessage(bus.Attachment{Name: "a.gif", MIMEType: "image/gif"}, up, "", ""); gif.GetDocumentMessage() == nil {
+		t.Fatal("gif should be sent as a document")
+	}
+
+	voice := buildMediaMessage(bus.Attachment{Name: "voice.ogg", MIMEType: "audio/ogg", Kind: "audio"}, up, "", "wamid.2")
+	if a := voice.GetAudioMessage(); a == nil || a.PTT == nil || !*a.PTT || a.ContextInfo.GetStanzaID() != "wamid.2" {
+		t.Fatalf("expected voice note, got %+v", voice)
+	}
+}
+
+func TestWhatsAppReaction(t *testing.T) {
+	react := &waE2E.ReactionMessage{Key: &waCommon.MessageKey{ID: new("MSG1")}, Text: new("👍")}
+	if got := whatsappReaction(react); got != (bus.Reaction{Emoji: "👍", MessageID: "MSG1"}) {
+		t.Fatalf("got %+v", got)
+	}
+	react.Text = new("")
+	if got := whatsappReaction(react); !got.Removed || got.MessageID != "MSG1" {
+		t.Fatalf("removal: %+v", got)
+	}
+}
@@ -0,0 +1,278 @@
+// Package chaos injects failures into LLM calls and channel sends, so the
+// retry and error handling around them can be tried out before production
+// depends on it.
+//
+// It is off unless CLAWLET_CHAOS is set, e.g.
+//
+//	CLAWLET_CHAOS="llm.429=0.1,llm.timeout=0.05,send.malformed=0.1,seed=1"
+//
+// gives each LLM call a 10% chance of a 429 and a 5% chance of a timeout,
+// and each send a 10% chance of a malformed response.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvVar turns chaos mode on.
+const EnvVar = "CLAWLET_CHAOS"
+
+// Targets that failures are injected into.
+const (
+	TargetLLM  = "llm"
+	TargetSend = "send"
+)
+
+// Fault is an injected failure.
+type Fault string
+
+const (
+	FaultNone Fault = ""
+	// FaultRateLimit answers 429 Too Many Requests.
+	FaultRateLimit Fault = "429"
+	// FaultTimeout hangs for the delay and then fails as a timed out
+	// request does.
+	FaultTimeout Fault = "timeout"
+	// FaultMalformed answers with a body that doesn't parse. A send goes
+	// out but reports failure, as when the chat app's reply is garbled.
+	FaultMalformed Fault = "malformed"
+)
+
+var faults = []Fault{FaultRateLimit, FaultTimeout, FaultMalformed}
+
+// defaultDelay is how long an injected timeout hangs.
+const defaultDelay = 2 * time.Second
+
+// Rates are the chances of each fault, between 0 and 1.
+type Rates map[Fault]float64
+
+// Injector picks faults at the configured rates. A nil *Injector injects
+// nothing.
+type Injector struct {
+	rates map[string]Rates
+	delay time.Duration
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// FromEnv reads CLAWLET_CHAOS; it returns nil when the variable is unset.
+func FromEnv() (*Injector, error) {
+	spec := strings.TrimSpace(os.Getenv(EnvVar))
+	if spec == "" {
+		return nil, nil
+	}
+	inj, err := Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", EnvVar, err)
+	}
+	return inj, nil
+}
+
+// Parse reads comma-separated settings: target.fault=rate, where target is
+// llm or send and fault is 429, timeout or malformed; seed=N to repeat a
+// run; delay=5s for how long timeouts hang.
+func Parse(spec string) (*Injector, error) {
+	inj := &Injector{rates: map[string]Rates{}, delay: defaultDelay}
+	seed := time.Now().UnixNano()
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: want key=value", part)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "seed":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid seed %q", value)
+			}
+			seed = n
+			continue
+		case "delay":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid delay %q", value)
+			}
+			inj.delay = d
+			continue
+		}
+		target, fault, _ := strings.Cut(key, ".")
+		if target != TargetLLM && target != TargetSend {
+			return nil, fmt.Errorf("%q: unknown target %q (use llm or send)", part, target)
+		}
+		if !validFault(Fault(fault)) {
+			return nil, fmt.Errorf("%q: unknown fault %q (use 429, timeout or malformed)", part, fault)
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("%q: rate must be between 0 and 1", part)
+		}
+		if inj.rates[target] == nil {
+			inj.rates[target] = Rates{}
+		}
+		inj.rates[target][Fault(fault)] = rate
+	}
+	for target, rates := range inj.rates {
+		total := 0.0
+		for _, r := range rates {
+			total += r
+		}
+		if total > 1 {
+			return nil, fmt.Errorf("%s rates add up to more than 1", target)
+		}
+	}
+	inj.rnd = rand.New(rand.NewSource(seed))
+	return inj, nil
+}
+
+func validFault(f Fault) bool {
+	for _, v := range faults {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the rates, for the log line that warns chaos mode is on.
+func (i *Injector) String() string {
+	var parts []string
+	for _, target := range []string{TargetLLM, TargetSend} {
+		for _, f := range faults {
+			if r := i.rates[target][f]; r > 0 {
+				parts = append(parts, fmt.Sprintf("%s.%s=%g", target, f, r))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return "no faults"
+	}
+	return strings.Join(parts, ",")
+}
+
+// Pick draws the fault for one call to target, FaultNone for most.
+func (i *Injector) Pick(target string) Fault {
+	if i == nil {
+		return FaultNone
+	}
+	i.mu.Lock()
+	x := i.rnd.Float64()
+	i.mu.Unlock()
+	for _, f := range faults {
+		r := i.rates[target][f]
+		if x < r {
+			log.Printf("chaos: injecting %s into %s", f, target)
+			return f
+		}
+		x -= r
+	}
+	return FaultNone
+}
+
+// hang waits out the delay of an injected timeout, or ctx.
+func (i *Injector) hang(ctx context.Context) error {
+	t := time.NewTimer(i.delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return fmt.Errorf("chaos: injected timeout: %w", os.ErrDeadlineExceeded)
+	}
+}
+
+// HTTPDoer is the interface of llm.Client.HTTP.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// HTTP wraps next so that requests fail at the llm rates.
+func (i *Injector) HTTP(next HTTPDoer) HTTPDoer {
+	if i == nil {
+		return next
+	}
+	return &doer{inj: i, next: next}
+}
+
+type doer struct {
+	inj  *Injector
+	next HTTPDoer
+}
+
+// malformedBody is cut off in the middle of a JSON object, whether it is
+// read as JSON or as a stream of server-sent events.
+const malformedBody = `data: {"id":"chaos","choices":[{"delta":{"content":"`
+
+func (d *doer) Do(req *http.Request) (*http.Response, error) {
+	switch d.inj.Pick(TargetLLM) {
+	case FaultRateLimit:
+		return response(req, http.StatusTooManyRequests, "application/json",
+			`{"error":{"type":"rate_limit_error","message":"chaos: injected rate limit"}}`), nil
+	case FaultTimeout:
+		if err := d.inj.hang(req.Context()); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, err)
+		}
+	case FaultMalformed:
+		return response(req, http.StatusOK, req.Header.Get("Accept"), malformedBody), nil
+	}
+	return d.next.Do(req)
+}
+
+func response(req *http.Request, code int, contentType, body string) *http.Response {
+	h := http.Header{}
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	if code == http.StatusTooManyRequests {
+		h.Set("Retry-After", "1")
+	}
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// ErrMalformed is the error of a send with FaultMalformed.
+var ErrMalformed = errors.New("chaos: injected malformed response")
+
+// Send runs send, a channel send, failing at the send rates.
+func (i *Injector) Send(ctx context.Context, send func() error) error {
+	switch i.Pick(TargetSend) {
+	case FaultRateLimit:
+		return errors.New("chaos: injected 429 Too Many Requests")
+	case FaultTimeout:
+		return i.hang(ctx)
+	case FaultMalformed:
+		if err := send(); err != nil {
+			return err
+		}
+		return ErrMalformed
+	}
+	return send()
+}
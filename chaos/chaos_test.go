@@ -0,0 +1,149 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+func TestParse(t *testing.T) {
+	inj, err := Parse(" llm.429=0.1, llm.timeout=0.05 ,send.malformed=1,seed=7,delay=10ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := inj.String(); got != "llm.429=0.1,llm.timeout=0.05,send.malformed=1" {
+		t.Fatalf("String() = %q", got)
+	}
+	if inj.delay != 10*time.Millisecond {
+		t.Fatalf("delay %v", inj.delay)
+	}
+	for _, spec := range []string{
+		"llm",
+		"db.429=0.1",
+		"llm.500=0.1",
+		"llm.429=2",
+		"llm.429=x",
+		"send.429=0.6,send.timeout=0.6",
+		"seed=x",
+		"delay=-1s",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestFromEnv_Unset(t *testing.T) {
+	t.Setenv(EnvVar, "")
+	if inj, err := FromEnv(); inj != nil || err != nil {
+		t.Fatalf("FromEnv() = %v, %v", inj, err)
+	}
+}
+
+func TestPick_RatesAndSeed(t *testing.T) {
+	draw := func() []Fault {
+		inj, err := Parse("llm.429=0.2,llm.timeout=0.1,llm.malformed=0.1,seed=42")
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]Fault, 2000)
+		for i := range out {
+			out[i] = inj.Pick(TargetLLM)
+		}
+		return out
+	}
+	a, b := draw(), draw()
+	counts := map[Fault]int{}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("draw %d differs with the same seed: %q, %q", i, a[i], b[i])
+		}
+		counts[a[i]]++
+	}
+	within := func(f Fault, want float64) {
+		if got := float64(counts[f]) / float64(len(a)); got < want-0.05 || got > want+0.05 {
+			t.Errorf("%q drawn %.3f of the time, want about %.2f", f, got, want)
+		}
+	}
+	within(FaultRateLimit, 0.2)
+	within(FaultTimeout, 0.1)
+	within(FaultMalformed, 0.1)
+	within(FaultNone, 0.6)
+
+	var off *Injector
+	if f := off.Pick(TargetSend); f != FaultNone {
+		t.Fatalf("nil injector picked %q", f)
+	}
+}
+
+type okDoer struct{ calls int }
+
+func (d *okDoer) Do(req *http.Request) (*http.Response, error) {
+	d.calls++
+	return response(req, http.StatusOK, "application/json",
+		`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`), nil
+}
+
+func TestHTTP_FailsLLMCalls(t *testing.T) {
+	chat := func(spec string) (*llm.ChatResult, *okDoer, error) {
+		t.Helper()
+		inj, err := Parse(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := &okDoer{}
+		c := &llm.Client{BaseURL: "http://llm.test/v1", APIKey: "k", Model: "m", HTTP: inj.HTTP(next)}
+		res, err := c.Chat(context.Background(), []llm.Message{{Role: "user", Content: "hello"}}, nil)
+		return res, next, err
+	}
+
+	if res, next, err := chat("llm.429=0"); err != nil || res.Content != "hi" || next.calls != 1 {
+		t.Fatalf("no faults: %v, %+v, %d calls", err, res, next.calls)
+	}
+	if _, next, err := chat("llm.429=1"); err == nil || !strings.Contains(err.Error(), "429") || next.calls != 0 {
+		t.Fatalf("429: %v, %d calls", err, next.calls)
+	}
+	if _, _, err := chat("llm.malformed=1"); err == nil {
+		t.Fatal("malformed response parsed")
+	}
+	inj, _ := Parse("llm.malformed=1")
+	c := &llm.Client{BaseURL: "http://llm.test/v1", APIKey: "k", Model: "m", HTTP: inj.HTTP(&okDoer{})}
+	// A stream skips chunks it cannot read, so the reply comes back empty.
+	if res, err := c.ChatStream(context.Background(), []llm.Message{{Role: "user", Content: "hello"}}, nil, func(string) {}); err == nil && res.Content != "" {
+		t.Fatalf("malformed stream read as %q", res.Content)
+	}
+	if _, next, err := chat("llm.timeout=1,delay=1ms"); !errors.Is(err, os.ErrDeadlineExceeded) || next.calls != 0 {
+		t.Fatalf("timeout: %v, %d calls", err, next.calls)
+	}
+}
+
+func TestSend(t *testing.T) {
+	sends := 0
+	send := func() error { sends++; return nil }
+	var off *Injector
+	if err := off.Send(context.Background(), send); err != nil || sends != 1 {
+		t.Fatalf("nil injector: %v, %d sends", err, sends)
+	}
+
+	inj, _ := Parse("send.429=1")
+	if err := inj.Send(context.Background(), send); err == nil || sends != 1 {
+		t.Fatalf("429: %v, %d sends", err, sends)
+	}
+	// A malformed response comes after the message went out.
+	inj, _ = Parse("send.malformed=1")
+	if err := inj.Send(context.Background(), send); !errors.Is(err, ErrMalformed) || sends != 2 {
+		t.Fatalf("malformed: %v, %d sends", err, sends)
+	}
+	inj, _ = Parse("send.timeout=1,delay=1h")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := inj.Send(ctx, send); !errors.Is(err, context.Canceled) || sends != 2 {
+		t.Fatalf("timeout: %v, %d sends", err, sends)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/handover"
+	"github.com/mosaxiv/clawlet/metrics"
+)
+
+var digestWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func sloTargets(c config.SLOConfig) metrics.SLOTargets {
+	return metrics.SLOTargets{
+		TurnSuccess:     c.TurnSuccessValue(),
+		ProviderSuccess: c.ProviderSuccessValue(),
+		ReplyLatencyP95: time.Duration(c.ReplyLatencyP95SecValue()) * time.Second,
+	}
+}
+
+// adminSchemaVersion versions the admin API's JSON payloads. Fields may be
+// added within a version; removing or changing one bumps it, along with
+// info.version in openapi.json.
+const adminSchemaVersion = 1
+
+// openAPISpec describes the admin API for integrators generating clients.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// pauser pauses and resumes the agent in a chat; the agent loop.
+type pauser interface {
+	SetPaused(sessionKey string, paused bool) error
+}
+
+// adminHandler serves GET /metrics in the Prometheus text format, GET /slo
+// as JSON (?window=24h limits it to one window), GET /inbound, a stream of
+// inbound messages for outside observers, and POST /sessions/pause and
+// /sessions/resume (?session=telegram:123) for a human taking a chat over,
+// which are only served with a token. GET /openapi.json describes them all.
+func adminHandler(c config.AdminConfig, slo config.SLOConfig, b *bus.Bus, p pauser) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPISpec)
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_ = metrics.Default.WriteText(w)
+		_ = metrics.WriteSLOText(w, metrics.DefaultSLO.Reports(time.Now(), sloTargets(slo)))
+		_ = b.Stats().WriteText(w)
+	})
+	mux.HandleFunc("GET /slo", func(w http.ResponseWriter, r *http.Request) {
+		reports := metrics.DefaultSLO.Reports(time.Now(), sloTargets(slo))
+		if v := strings.TrimSpace(r.URL.Query().Get("window")); v != "" {
+			d, err := parseWindow(v)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			reports = []metrics.SLOReport{metrics.DefaultSLO.Report(d, time.Now(), sloTargets(slo))}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"version": adminSchemaVersion, "reports": reports})
+	})
+	mux.HandleFunc("GET /inbound", func(w http.ResponseWriter, r *http.Request) {
+		streamInbound(w, r, b)
+	})
+	token := strings.TrimSpace(c.Token)
+	mux.HandleFunc("POST /sessions/{action}", func(w http.ResponseWriter, r *http.Request) {
+		// Pausing silences the agent in a chat, so it is not left open to
+		// whoever reaches the port.
+		if token == "" {
+			http.Error(w, "set gateway.admin.token to pause or resume sessions", http.StatusForbidden)
+			return
+		}
+		action := r.PathValue("action")
+		if action != "pause" && action != "resume" {
+			http.NotFound(w, r)
+			return
+		}
+		key := strings.TrimSpace(r.URL.Query().Get("session"))
+		if key == "" {
+			http.Error(w, "session is required", http.StatusBadRequest)
+			return
+		}
+		if err := p.SetPaused(key, action == "pause"); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"version": adminSchemaVersion, "session": key, "paused": action == "pause"})
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		w.Header().Set("Clawlet-Schema-Version", strconv.Itoa(adminSchemaVersion))
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// inboundEvent is the data of an /inbound event: the message with the
+// schema version.
+type inboundEvent struct {
+	Version int `json:"version"`
+	bus.InboundMessage
+}
+
+// inboundBuffer is how far an /inbound client may fall behind before it
+// misses messages.
+const inboundBuffer = 256
+
+// streamInbound sends each inbound message as a server-sent event until the
+// client goes away. ?channel= limits the stream to one channel. Attachment
+// contents and download headers are left out.
+func streamInbound(w http.ResponseWriter, r *http.Request, b *bus.Bus) {
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout.
+	_ = rc.SetWriteDeadline(time.Time{})
+	only := strings.TrimSpace(r.URL.Query().Get("channel"))
+	msgs, cancel := b.Subscribe(inboundBuffer)
+	defer cancel()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case msg := <-msgs:
+			if only != "" && msg.Channel != only {
+				continue
+			}
+			msg.Attachments = slices.Clone(msg.Attachments) // shared with the agent
+			for i := range msg.Attachments {
+				msg.Attachments[i].Data, msg.Attachments[i].Headers, msg.Attachments[i].LocalPath = nil, nil, ""
+			}
+			data, err := json.Marshal(inboundEvent{Version: adminSchemaVersion, InboundMessage: msg})
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: inbound\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// parseWindow reads a window like "6h" or "7d"; Go durations don't have days.
+func parseWindow(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		var n int
+		if _, err := fmt.Sscanf(days, "%d", &n); err == nil && n > 0 && n <= 7 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(v); err == nil && d > 0 && d <= 7*24*time.Hour {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid window %q (use e.g. 1h, 24h or 7d, at most 7d)", v)
+}
+
+// validateAdminToken refuses an admin API without a token that other
+// machines may reach: one not bound to loopback, or with
+// gateway.allowPublicBind, which means a proxy forwards to it.
+func validateAdminToken(cfg config.GatewayConfig) error {
+	if !cfg.Admin.Enabled || strings.TrimSpace(cfg.Admin.Token) != "" {
+		return nil
+	}
+	if isLocalGatewayHost(gatewayListenHost(strings.TrimSpace(cfg.Listen))) && !cfg.AllowPublicBind {
+		return nil
+	}
+	return errors.New("refusing to serve the admin API without gateway.admin.token on a non-loopback or public bind; set a token")
+}
+
+// serveAdmin runs the admin API on the gateway listen address until ctx is
+// done.
+func serveAdmin(ctx context.Context, cfg *config.Config, b *bus.Bus, p pauser) error {
+	ln, err := handover.Listen(cfg.Gateway.Listen)
+	if err != nil {
+		return fmt.Errorf("admin: %w", err)
+	}
+	srv := &http.Server{
+		Handler:           adminHandler(cfg.Gateway.Admin, cfg.SLO, b, p),
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		// Ends /inbound streams on shutdown.
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// nextDigest is the first digest time after now: day at hour, local time.
+func nextDigest(now time.Time, day time.Weekday, hour int) time.Time {
+	at := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	at = at.AddDate(0, 0, (int(day)-int(now.Weekday())+7)%7)
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 7)
+	}
+	return at
+}
+
+// runSLODigest sends the owner the weekly service level report until ctx is
+// done.
+func runSLODigest(ctx context.Context, c config.SLOConfig, b *bus.Bus) {
+	day := digestWeekdays[c.DigestDayValue()]
+	for {
+		t := time.NewTimer(time.Until(nextDigest(time.Now(), day, c.DigestHourValue())))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		text := metrics.Digest(metrics.DefaultSLO.Reports(time.Now(), sloTargets(c)))
+		_ = b.PublishOutbound(ctx, bus.OutboundMessage{Channel: c.DigestChannel, ChatID: c.DigestChatID, Content: text})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestAdminHandler(t *testing.T) {
+	h := adminHandler(config.AdminConfig{Token: "secret"}, config.SLOConfig{}, bus.New(1), nil)
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := get("/metrics", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("no token: %d", rec.Code)
+	}
+	if rec := get("/metrics", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: %d", rec.Code)
+	}
+	// The token alone, without the Bearer scheme, is refused.
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bare token: %d", rec.Code)
+	}
+	if rec := get("/metrics", "secret"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "clawlet_slo_turn_success_ratio") {
+		t.Fatalf("metrics: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("/slo?window=7d", "secret"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"window":"7d"`) {
+		t.Fatalf("slo: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("/slo?window=30d", "secret"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad window: %d", rec.Code)
+	}
+}
+
+func TestAdminHandler_OpenAPI(t *testing.T) {
+	h := adminHandler(config.AdminConfig{}, config.SLOConfig{}, bus.New(1), nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Clawlet-Schema-Version") != "1" {
+		t.Fatalf("openapi: %d %v", rec.Code, rec.Header())
+	}
+	var spec struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Info.Version != strconv.Itoa(adminSchemaVersion) {
+		t.Fatalf("spec version %q, schema version %d", spec.Info.Version, adminSchemaVersion)
+	}
+	for path, method := range map[string]string{
+		"/openapi.json":      "get",
+		"/metrics":           "get",
+		"/slo":               "get",
+		"/inbound":           "get",
+		"/sessions/{action}": "post",
+	} {
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Errorf("%s %s is not described", method, path)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slo", nil))
+	if !strings.Contains(rec.Body.String(), `"version":1`) {
+		t.Fatalf("slo: %s", rec.Body.String())
+	}
+}
+
+func TestAdminHandler_StreamsInbound(t *testing.T) {
+	b := bus.New(4)
+	srv := httptest.NewServer(adminHandler(config.AdminConfig{}, config.SLOConfig{}, b, nil))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/inbound?channel=telegram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+
+	ctx := t.Context()
+	_ = b.PublishInbound(ctx, bus.InboundMessage{Channel: "slack", Content: "skipped"})
+	_ = b.PublishInbound(ctx, bus.InboundMessage{
+		Channel:     "telegram",
+		Content:     "hello",
+		Attachments: []bus.Attachment{{Name: "a.png", Data: []byte("png"), Headers: map[string]string{"Authorization": "x"}}},
+	})
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var msg inboundEvent
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Version != adminSchemaVersion || msg.Content != "hello" || len(msg.Attachments) != 1 || msg.Attachments[0].Data != nil || msg.Attachments[0].Headers != nil {
+			t.Fatalf("streamed %+v", msg)
+		}
+		break
+	}
+	// The agent's copy keeps its attachment.
+	if msg, _ := b.ConsumeInbound(ctx); msg.Content != "skipped" {
+		t.Fatalf("consumed %+v", msg)
+	}
+	if msg, _ := b.ConsumeInbound(ctx); string(msg.Attachments[0].Data) != "png" {
+		t.Fatalf("attachment data lost: %+v", msg)
+	}
+}
+
+func TestNextDigest(t *testing.T) {
+	fri := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	if got := nextDigest(fri, time.Monday, 9); !got.Equal(time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("next monday: %v", got)
+	}
+	// Later the same day, or a week on once the hour has passed.
+	if got := nextDigest(fri, time.Friday, 11); !got.Equal(time.Date(2026, 10, 16, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("same day: %v", got)
+	}
+	if got := nextDigest(fri, time.Friday, 9); !got.Equal(time.Date(2026, 10, 23, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("next week: %v", got)
+	}
+}
+
+type pausedSessions map[string]bool
+
+func (p pausedSessions) SetPaused(sessionKey string, paused bool) error {
+	p[sessionKey] = paused
+	return nil
+}
+
+func TestAdminHandler_PausesSessions(t *testing.T) {
+	p := pausedSessions{}
+	h := adminHandler(config.AdminConfig{Token: "secret"}, config.SLOConfig{}, bus.New(1), p)
+	post := func(path string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := post("/sessions/pause?session=whatsapp:1"); code != http.StatusOK || !p["whatsapp:1"] {
+		t.Fatalf("pause: %d %v", code, p)
+	}
+	if code := post("/sessions/resume?session=whatsapp:1"); code != http.StatusOK || p["whatsapp:1"] {
+		t.Fatalf("resume: %d %v", code, p)
+	}
+	if code := post("/sessions/resume"); code != http.StatusBadRequest {
+		t.Fatalf("no session: %d", code)
+	}
+	if code := post("/sessions/delete?session=whatsapp:1"); code != http.StatusNotFound {
+		t.Fatalf("unknown action: %d", code)
+	}
+}
+
+func TestAdminHandler_SessionsNeedToken(t *testing.T) {
+	p := pausedSessions{}
+	h := adminHandler(config.AdminConfig{}, config.SLOConfig{}, bus.New(1), p)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sessions/pause?session=whatsapp:1", nil))
+	if rec.Code != http.StatusForbidden || p["whatsapp:1"] {
+		t.Fatalf("pause without a token: %d %v", rec.Code, p)
+	}
+}
@@ -28,22 +28,38 @@ func cmdAgent() *cli.Command {
 			if err != nil {
 				return err
 			}
+			if err := runStartupMigrations(); err != nil {
+				return err
+			}
 
 			wsAbs, err := resolveWorkspace(cmd.String("workspace"))
 			if err != nil {
 				return err
 			}
 
+			st, err := openStorage(cfg)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+
+			inj, err := loadChaos()
+			if err != nil {
+				return err
+			}
 			a, err := agent.New(agent.Options{
 				Config:       cfg,
 				WorkspaceDir: wsAbs,
 				SessionKey:   cmd.String("session"),
+				Sessions:     st,
 				MaxIters:     cmd.Int("max-iters"),
+				Chaos:        inj,
 				Verbose:      cmd.Bool("verbose"),
 			})
 			if err != nil {
 				return err
 			}
+			defer a.Close()
 
 			msg := cmd.String("message")
 			if msg != "" {
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/contacts"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdAllowlist() *cli.Command {
+	return &cli.Command{
+		Name:  "allowlist",
+		Usage: "allowlist utilities",
+		Commands: []*cli.Command{
+			{
+				Name:      "import",
+				Usage:     "add users from a CSV file (channel,identifier,label,role) to channel allowlists and contacts",
+				ArgsUsage: "<csv>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "workspace", Usage: "workspace directory (default: ~/.clawlet/workspace or CLAWLET_WORKSPACE)"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "report what would change without writing"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Args().Len() != 1 {
+						return errors.New("usage: clawlet allowlist import <csv>")
+					}
+					f, err := os.Open(cmd.Args().First())
+					if err != nil {
+						return err
+					}
+					defer f.Close()
+					rows, err := readAllowlistCSV(f)
+					if err != nil {
+						return err
+					}
+					_, cfgPath, err := loadConfig()
+					if err != nil {
+						return err
+					}
+					ws, err := resolveWorkspace(cmd.String("workspace"))
+					if err != nil {
+						return err
+					}
+					return importAllowlist(cfgPath, contacts.NewStore(contacts.Path(ws)), rows, cmd.Bool("dry-run"), os.Stdout)
+				},
+			},
+		},
+	}
+}
+
+// Allowlist CSV roles.
+const (
+	allowlistRoleUser    = "user"    // allowFrom, plus a contact when labelled
+	allowlistRoleGroup   = "group"   // groupAllowFrom (group chats)
+	allowlistRoleContact = "contact" // contact only, not allowed to chat
+)
+
+type allowlistRow struct {
+	line    int
+	channel string
+	id      string
+	label   string
+	role    string
+}
+
+// readAllowlistCSV reads rows under a header naming the channel and
+// identifier columns, and optionally label and role, in any order.
+func readAllowlistCSV(r io.Reader) ([]allowlistRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	for _, req := range []string{"channel", "identifier"} {
+		if _, ok := col[req]; !ok {
+			return nil, fmt.Errorf("header needs a %q column (columns: channel, identifier, label, role)", req)
+		}
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var rows []allowlistRow
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := allowlistRow{
+			line:    line,
+			channel: strings.ToLower(field(rec, "channel")),
+			id:      field(rec, "identifier"),
+			label:   field(rec, "label"),
+			role:    strings.ToLower(field(rec, "role")),
+		}
+		if row.channel == "" && row.id == "" {
+			continue
+		}
+		if row.role == "" {
+			row.role = allowlistRoleUser
+		}
+		switch row.role {
+		case allowlistRoleUser, allowlistRoleGroup:
+			e := config.AllowlistEntry{Channel: row.channel, ID: row.id, Group: row.role == allowlistRoleGroup}
+			if err := e.Validate(); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		case allowlistRoleContact:
+			if row.label == "" || row.id == "" || row.channel == "" {
+				return nil, fmt.Errorf("line %d: a contact needs channel, identifier and label", line)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: role must be %s, %s or %s, got %q", line, allowlistRoleUser, allowlistRoleGroup, allowlistRoleContact, row.role)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func importAllowlist(cfgPath string, book *contacts.Store, rows []allowlistRow, dryRun bool, out io.Writer) error {
+	var entries []config.AllowlistEntry
+	labelled := 0
+	for _, r := range rows {
+		if r.role != allowlistRoleContact {
+			entries = append(entries, config.AllowlistEntry{Channel: r.channel, ID: r.id, Group: r.role == allowlistRoleGroup})
+		}
+		if r.role != allowlistRoleGroup && r.label != "" {
+			labelled++
+		}
+	}
+	added, err := config.MergeAllowlists(cfgPath, entries, dryRun)
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		for _, r := range rows {
+			if r.role == allowlistRoleGroup || r.label == "" {
+				continue
+			}
+			if _, err := book.Upsert(contacts.Contact{Name: r.label, Addresses: map[string]string{r.channel: r.id}}); err != nil {
+				return fmt.Errorf("line %d: contact %s: %w", r.line, r.label, err)
+			}
+		}
+	}
+	if dryRun {
+		fmt.Fprintf(out, "would add %d allowlist entries (%d already present) and save %d contact addresses\n", added, len(entries)-added, labelled)
+		return nil
+	}
+	fmt.Fprintf(out, "added %d allowlist entries (%d already present), saved %d contact addresses\n", added, len(entries)-added, labelled)
+	if added > 0 {
+		fmt.Fprintln(out, "restart the gateway to apply the allowlists")
+	}
+	return nil
+}
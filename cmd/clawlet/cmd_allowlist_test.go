@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/contacts"
+)
+
+func TestImportAllowlist(t *testing.T) {
+	csvText := "\ufeffLabel,Channel,Identifier,Role\n" +
+		"Alice,telegram,111,\n" +
+		"Alice,slack,U1,user\n" +
+		",slack,C9,group\n" +
+		"Bob,whatsapp,+15550001,contact\n" +
+		",,,\n"
+	rows, err := readAllowlistCSV(strings.NewReader(csvText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("rows=%+v", rows)
+	}
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(cfgPath, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	book := contacts.NewStore(filepath.Join(dir, contacts.FileName))
+	var out bytes.Buffer
+	if err := importAllowlist(cfgPath, book, rows, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "added 3 allowlist entries (0 already present), saved 3 contact addresses") {
+		t.Fatalf("summary: %q", out.String())
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.Channels.Telegram.AllowFrom, []string{"111"}) || !slices.Equal(cfg.Channels.Slack.GroupAllowFrom, []string{"C9"}) || len(cfg.Channels.WhatsApp.AllowFrom) != 0 {
+		t.Fatalf("allowlists: %+v", cfg.Channels)
+	}
+	alice, err := book.Resolve("alice")
+	if err != nil || alice.Addresses["telegram"] != "111" || alice.Addresses["slack"] != "U1" {
+		t.Fatalf("alice: %+v %v", alice, err)
+	}
+	if _, err := book.Resolve("bob"); err != nil {
+		t.Fatalf("bob: %v", err)
+	}
+}
+
+func TestReadAllowlistCSV_Errors(t *testing.T) {
+	for _, in := range []string{
+		"label,identifier\nA,1\n",
+		"channel,identifier,role\ntelegram,1,admin\n",
+		"channel,identifier,role\nwhatsapp,1,group\n",
+		"channel,identifier,role\nwhatsapp,1,contact\n",
+	} {
+		if _, err := readAllowlistCSV(strings.NewReader(in)); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+	}
+}
@@ -31,6 +31,19 @@ func cmdChannels() *cli.Command {
 					fmt.Printf("slack.enabled=%v\n", cfg.Channels.Slack.Enabled)
 					fmt.Printf("telegram.enabled=%v\n", cfg.Channels.Telegram.Enabled)
 					fmt.Printf("whatsapp.enabled=%v\n", cfg.Channels.WhatsApp.Enabled)
+					fmt.Printf("matrix.enabled=%v\n", cfg.Channels.Matrix.Enabled)
+					fmt.Printf("mastodon.enabled=%v\n", cfg.Channels.Mastodon.Enabled)
+					fmt.Printf("voice.enabled=%v\n", cfg.Channels.Voice.Enabled)
+					fmt.Printf("grpc.enabled=%v\n", cfg.Channels.GRPC.Enabled)
+					fmt.Printf("instagram.enabled=%v\n", cfg.Channels.Instagram.Enabled)
+					fmt.Printf("push.enabled=%v\n", cfg.Channels.Push.Enabled)
+					fmt.Printf("mqtt.enabled=%v\n", cfg.Channels.MQTT.Enabled)
+					for _, name := range accountNames(cfg.Channels.Telegram.Accounts) {
+						fmt.Printf("telegram.%s.enabled=%v\n", name, cfg.Channels.Telegram.Accounts[name].Enabled)
+					}
+					for _, name := range accountNames(cfg.Channels.Discord.Accounts) {
+						fmt.Printf("discord.%s.enabled=%v\n", name, cfg.Channels.Discord.Accounts[name].Enabled)
+					}
 					return nil
 				},
 			},
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"os"
+	"os/signal"
+
+	"github.com/mosaxiv/clawlet/agent"
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+	clichannel "github.com/mosaxiv/clawlet/channels/cli"
+	"github.com/mosaxiv/clawlet/session"
+	"github.com/urfave/cli/v3"
+)
+
+// chatStreamIntervalMs is how often `clawlet chat` prints a streamed reply
+// unless agents.defaults.streaming.intervalMs says otherwise. The terminal
+// has no edit rate limits, so it is short enough to show tokens as they
+// arrive.
+const chatStreamIntervalMs = 30
+
+func cmdChat() *cli.Command {
+	return &cli.Command{
+		Name:  "chat",
+		Usage: "chat with the agent in the terminal through the gateway loop",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "session", Aliases: []string{"s"}, Value: "default", Usage: "chat ID (session key cli:<id>)"},
+			&cli.StringFlag{Name: "workspace", Usage: "workspace directory (default: ~/.clawlet/workspace or CLAWLET_WORKSPACE)"},
+			&cli.IntFlag{Name: "max-iters", Value: 20, Usage: "max tool-call iterations"},
+			&cli.BoolFlag{Name: "verbose", Aliases: []string{"v"}, Usage: "verbose"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			cfg, _, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if err := runStartupMigrations(); err != nil {
+				return err
+			}
+			wsAbs, err := resolveWorkspace(cmd.String("workspace"))
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+			defer stop()
+
+			st, err := openStorage(cfg)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+
+			// Replies stream to the terminal unless
+			// agents.defaults.streaming.channels.cli turns it off.
+			streaming := &cfg.Agents.Defaults.Streaming
+			if _, ok := streaming.Channels["cli"]; !ok {
+				streaming.Channels = maps.Clone(streaming.Channels)
+				if streaming.Channels == nil {
+					streaming.Channels = map[string]bool{}
+				}
+				streaming.Channels["cli"] = true
+			}
+			if streaming.IntervalMs <= 0 {
+				streaming.IntervalMs = chatStreamIntervalMs
+			}
+
+			inj, err := loadChaos()
+			if err != nil {
+				return err
+			}
+			b := bus.New(64)
+			defer b.Close()
+			loop, err := agent.NewLoop(agent.LoopOptions{
+				Config:       cfg,
+				WorkspaceDir: wsAbs,
+				Model:        cfg.LLM.Model,
+				MaxIters:     cmd.Int("max-iters"),
+				Bus:          b,
+				Sessions:     session.NewManagerWithStore(st),
+				Chaos:        inj,
+				Verbose:      cmd.Bool("verbose"),
+			})
+			if err != nil {
+				return err
+			}
+			defer loop.Close()
+			sa := agent.NewSubagentManager(loop)
+			loop.SetSpawn(sa.Spawn)
+
+			repl := clichannel.New(b, clichannel.Options{ChatID: cmd.String("session")})
+			cm := channels.NewManager(b)
+			cm.Add(repl)
+			cm.SetChaos(inj)
+			if err := cm.StartAll(ctx); err != nil {
+				return err
+			}
+			go func() { _ = loop.Run(ctx) }()
+
+			select {
+			case <-ctx.Done():
+			case <-repl.Done():
+			}
+			_ = cm.StopAll()
+			return nil
+		},
+	}
+}
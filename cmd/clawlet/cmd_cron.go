@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"github.com/mosaxiv/clawlet/cron"
-	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/llm"
 	"github.com/urfave/cli/v3"
 )
 
@@ -30,18 +30,26 @@ func cronListCmd() *cli.Command {
 		Name:  "list",
 		Usage: "list jobs",
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, _, err := loadConfig()
+			cfg, _, err := loadConfig()
 			if err != nil {
 				return err
 			}
-			svc := cron.NewService(paths.CronStorePath(), nil)
+			svc, closeStore, err := openCronService(cfg)
+			if err != nil {
+				return err
+			}
+			defer closeStore()
 			jobs := svc.List(true)
 			if len(jobs) == 0 {
 				fmt.Println("No jobs.")
 				return nil
 			}
 			for _, j := range jobs {
-				fmt.Printf("- %s id=%s enabled=%v kind=%s next=%d\n", j.Name, j.ID, j.Enabled, j.Schedule.Kind, j.State.NextRunAtMS)
+				fmt.Printf("- %s id=%s enabled=%v kind=%s next=%d", j.Name, j.ID, j.Enabled, j.Schedule.Kind, j.State.NextRunAtMS)
+				if j.State.BatchID != "" {
+					fmt.Printf(" batch=%s", j.State.BatchID)
+				}
+				fmt.Println()
 			}
 			return nil
 		},
@@ -61,9 +69,10 @@ func cronAddCmd() *cli.Command {
 			&cli.BoolFlag{Name: "deliver", Value: true, Usage: "deliver response to a channel"},
 			&cli.StringFlag{Name: "channel", Usage: "delivery channel (e.g. discord, slack)"},
 			&cli.StringFlag{Name: "to", Usage: "delivery chat/user id"},
+			&cli.BoolFlag{Name: "batch", Usage: "run on the provider's batch API (half price, result within 24h)"},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, _, err := loadConfig()
+			cfg, _, err := loadConfig()
 			if err != nil {
 				return err
 			}
@@ -121,9 +130,17 @@ func cronAddCmd() *cli.Command {
 				Deliver: cmd.Bool("deliver"),
 				Channel: channel,
 				To:      to,
+				Batch:   cmd.Bool("batch"),
+			}
+			if payload.Batch && !llm.SupportsBatch(cfg.LLM.Provider) {
+				return cli.Exit("--batch needs the openai or anthropic provider", 2)
 			}
 
-			svc := cron.NewService(paths.CronStorePath(), nil)
+			svc, closeStore, err := openCronService(cfg)
+			if err != nil {
+				return err
+			}
+			defer closeStore()
 			j, err := svc.Add(jname, sched, payload)
 			if err != nil {
 				return err
@@ -140,7 +157,7 @@ func cronRemoveCmd() *cli.Command {
 		Usage:     "remove a job",
 		ArgsUsage: "<job_id>",
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, _, err := loadConfig()
+			cfg, _, err := loadConfig()
 			if err != nil {
 				return err
 			}
@@ -148,7 +165,11 @@ func cronRemoveCmd() *cli.Command {
 				return cli.Exit("usage: clawlet cron remove <job_id>", 2)
 			}
 			id := cmd.Args().Get(0)
-			svc := cron.NewService(paths.CronStorePath(), nil)
+			svc, closeStore, err := openCronService(cfg)
+			if err != nil {
+				return err
+			}
+			defer closeStore()
 			if svc.Remove(id) {
 				fmt.Println("Removed:", id)
 			} else {
@@ -168,7 +189,7 @@ func cronToggleCmd() *cli.Command {
 			&cli.BoolFlag{Name: "disable", Usage: "disable instead of enable"},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, _, err := loadConfig()
+			cfg, _, err := loadConfig()
 			if err != nil {
 				return err
 			}
@@ -176,7 +197,11 @@ func cronToggleCmd() *cli.Command {
 				return cli.Exit("usage: clawlet cron toggle [--disable] <job_id>", 2)
 			}
 			id := cmd.Args().Get(0)
-			svc := cron.NewService(paths.CronStorePath(), nil)
+			svc, closeStore, err := openCronService(cfg)
+			if err != nil {
+				return err
+			}
+			defer closeStore()
 			if svc.Toggle(id, cmd.Bool("disable")) {
 				if cmd.Bool("disable") {
 					fmt.Println("Disabled:", id)
@@ -200,7 +225,7 @@ func cronRunCmd() *cli.Command {
 			&cli.BoolFlag{Name: "force", Usage: "run even if disabled"},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, _, err := loadConfig()
+			cfg, _, err := loadConfig()
 			if err != nil {
 				return err
 			}
@@ -208,7 +233,11 @@ func cronRunCmd() *cli.Command {
 				return cli.Exit("usage: clawlet cron run [--force] <job_id>", 2)
 			}
 			id := cmd.Args().Get(0)
-			svc := cron.NewService(paths.CronStorePath(), nil)
+			svc, closeStore, err := openCronService(cfg)
+			if err != nil {
+				return err
+			}
+			defer closeStore()
 			_, err = svc.RunNow(ctx, id, cmd.Bool("force"))
 			if err != nil {
 				return err
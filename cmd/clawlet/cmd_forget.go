@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/forget"
+	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/presence"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdForget() *cli.Command {
+	return &cli.Command{
+		Name:  "forget",
+		Usage: "delete everything stored about a chat sender (sessions, notes, contacts, cron jobs, held messages)",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "sender", Required: true, Usage: "sender ID as seen by the channel (e.g. telegram user ID)"},
+			&cli.StringFlag{Name: "channel", Usage: "only match this channel (e.g. telegram, slack)"},
+			&cli.StringFlag{Name: "workspace", Usage: "workspace directory (default: ~/.clawlet/workspace or CLAWLET_WORKSPACE)"},
+			&cli.BoolFlag{Name: "dry-run", Usage: "list what would be deleted without deleting"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			cfg, _, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := openStorage(cfg)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+			ws, err := resolveWorkspace(cmd.String("workspace"))
+			if err != nil {
+				return err
+			}
+			dryRun := cmd.Bool("dry-run")
+			if _, err := readGatewayPID(); err == nil && !dryRun {
+				fmt.Fprintln(os.Stderr, "warning: the gateway appears to be running and may write cached sessions back; stop it first or use /forget-me in chat")
+			}
+			pres, err := presence.New(cfg.Presence, paths.PresencePath(), paths.PresenceQueuePath())
+			if err != nil {
+				return err
+			}
+			rep, err := forget.Run(forget.Env{
+				Sessions:  st,
+				Turns:     st,
+				Workspace: ws,
+				Cron:      cron.NewServiceWithStore(st, nil),
+				Presence:  pres,
+			}, forget.Target{
+				Sender:  strings.TrimSpace(cmd.String("sender")),
+				Channel: strings.TrimSpace(cmd.String("channel")),
+			}, dryRun)
+			if err != nil {
+				return err
+			}
+			fmt.Println(rep.Summary())
+			return nil
+		},
+	}
+}
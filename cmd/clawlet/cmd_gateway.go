@@ -2,24 +2,45 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net"
 	"os"
 	"os/signal"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/mosaxiv/clawlet/agent"
+	"github.com/mosaxiv/clawlet/broadcast"
 	"github.com/mosaxiv/clawlet/bus"
 	"github.com/mosaxiv/clawlet/channels"
 	"github.com/mosaxiv/clawlet/channels/discord"
+	grpcchannel "github.com/mosaxiv/clawlet/channels/grpc"
+	"github.com/mosaxiv/clawlet/channels/httpserver"
+	"github.com/mosaxiv/clawlet/channels/instagram"
+	"github.com/mosaxiv/clawlet/channels/mastodon"
+	"github.com/mosaxiv/clawlet/channels/matrix"
+	"github.com/mosaxiv/clawlet/channels/mqtt"
+	"github.com/mosaxiv/clawlet/channels/push"
 	"github.com/mosaxiv/clawlet/channels/slack"
 	"github.com/mosaxiv/clawlet/channels/telegram"
+	"github.com/mosaxiv/clawlet/channels/voice"
 	"github.com/mosaxiv/clawlet/channels/whatsapp"
 	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/contacts"
 	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/handover"
 	"github.com/mosaxiv/clawlet/heartbeat"
+	"github.com/mosaxiv/clawlet/memory"
 	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/presence"
+	"github.com/mosaxiv/clawlet/reminders"
 	"github.com/mosaxiv/clawlet/session"
+	"github.com/mosaxiv/clawlet/tools"
+	"github.com/mosaxiv/clawlet/watchdog"
 	"github.com/urfave/cli/v3"
 )
 
@@ -40,6 +61,12 @@ func cmdGateway() *cli.Command {
 			if err := validateGatewayBindPolicy(cfg.Gateway); err != nil {
 				return err
 			}
+			if err := validateAdminToken(cfg.Gateway); err != nil {
+				return err
+			}
+			if err := runStartupMigrations(); err != nil {
+				return err
+			}
 
 			wsAbs, err := resolveWorkspace(cmd.String("workspace"))
 			if err != nil {
@@ -49,20 +76,46 @@ func cmdGateway() *cli.Command {
 			ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
 			defer stop()
 
-			b := bus.New(256)
-			smgr := session.NewManager(paths.SessionsDir())
+			st, err := openStorage(cfg)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+
+			b, err := newGatewayBus(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+			if err := setBusMaxAge(b, cfg.Bus); err != nil {
+				return err
+			}
+			duties, err := newSingletons(ctx, cfg, st)
+			if err != nil {
+				return err
+			}
+			defer duties.stop()
+			smgr := session.NewManagerWithStore(st)
 
 			var cronSvc *cron.Service
+			var loop *agent.Loop
 			if cfg.Cron.EnabledValue() {
-				cronSvc = cron.NewService(paths.CronStorePath(), func(ctx context.Context, job cron.Job) (string, error) {
-					if job.Payload.Kind != "" && job.Payload.Kind != "agent_turn" {
-						return "", nil
+				cronSvc = cron.NewServiceWithStore(st, func(ctx context.Context, job cron.Job) (string, error) {
+					if job.Payload.Group != "" {
+						return "", cronBroadcast(ctx, cfg, wsAbs, b, loop, job)
 					}
 					ch := job.Payload.Channel
 					to := job.Payload.To
 					if !job.Payload.Deliver || strings.TrimSpace(ch) == "" || strings.TrimSpace(to) == "" {
 						return "", nil
 					}
+					switch job.Payload.Kind {
+					case "", "agent_turn":
+					case reminders.PayloadKind:
+						return "", b.PublishOutbound(ctx, bus.OutboundMessage{Channel: ch, ChatID: to, Content: reminders.Message(job.Payload.Message)})
+					default:
+						return "", nil
+					}
 					_ = b.PublishInbound(ctx, bus.InboundMessage{
 						Channel:    ch,
 						SenderID:   "cron:" + job.ID,
@@ -74,7 +127,15 @@ func cmdGateway() *cli.Command {
 				})
 			}
 
-			loop, err := agent.NewLoop(agent.LoopOptions{
+			pres, err := presence.New(cfg.Presence, paths.PresencePath(), paths.PresenceQueuePath())
+			if err != nil {
+				return err
+			}
+			inj, err := loadChaos()
+			if err != nil {
+				return err
+			}
+			loop, err = agent.NewLoop(agent.LoopOptions{
 				Config:       cfg,
 				WorkspaceDir: wsAbs,
 				Model:        cfg.LLM.Model,
@@ -83,50 +144,161 @@ func cmdGateway() *cli.Command {
 				Sessions:     smgr,
 				Cron:         cronSvc,
 				Spawn:        nil,
+				Presence:     pres,
+				Chaos:        inj,
 				Verbose:      cmd.Bool("verbose"),
 			})
 			if err != nil {
 				return err
 			}
+			defer loop.Close()
 
 			sa := agent.NewSubagentManager(loop)
 			loop.SetSpawn(sa.Spawn)
 
 			if cronSvc != nil {
-				if err := cronSvc.Start(ctx); err != nil {
+				if duties.elected() {
+					cronSvc.SetRefresh(cronRefreshInterval)
+				}
+				if loop.SupportsBatch() {
+					cronSvc.SetBatcher(cronBatcher{cfg: cfg, wsAbs: wsAbs, b: b, loop: loop}, cronBatchPollInterval)
+				}
+				duties.run("cron", func(ctx context.Context) {
+					if err := cronSvc.Start(ctx); err != nil {
+						log.Printf("cron: start failed: %v", err)
+						return
+					}
+					<-ctx.Done()
+					cronSvc.Stop()
+				})
+			}
+
+			if cfg.Heartbeat.EnabledValue() {
+				duties.run("heartbeat", func(ctx context.Context) {
+					hb := heartbeat.New(wsAbs, heartbeat.Options{
+						Enabled:     true,
+						IntervalSec: cfg.Heartbeat.IntervalSec,
+						OnHeartbeat: func(ctx context.Context, prompt string) (string, error) {
+							return loop.ProcessDirect(ctx, prompt, "heartbeat", "cli", "heartbeat")
+						},
+					})
+					hb.Start(ctx)
+					<-ctx.Done()
+					hb.Stop()
+				})
+			}
+
+			if cfg.Maintenance.Enabled {
+				idx, err := memory.NewIndexManager(cfg, wsAbs)
+				if err != nil {
 					return err
 				}
+				defer idx.Close()
+				duties.run("maintenance", func(ctx context.Context) {
+					runMaintenance(ctx, cfg.Maintenance, st, idx)
+				})
 			}
 
-			hb := heartbeat.New(wsAbs, heartbeat.Options{
-				Enabled:     cfg.Heartbeat.EnabledValue(),
-				IntervalSec: cfg.Heartbeat.IntervalSec,
-				OnHeartbeat: func(ctx context.Context, prompt string) (string, error) {
-					return loop.ProcessDirect(ctx, prompt, "heartbeat", "cli", "heartbeat")
-				},
-			})
-			hb.Start(ctx)
+			if cfg.SLO.DigestEnabled() {
+				if _, ok := digestWeekdays[cfg.SLO.DigestDayValue()]; !ok {
+					return fmt.Errorf("slo.digestDay must be mon to sun, got %q", cfg.SLO.DigestDay)
+				}
+				duties.run("slo-digest", func(ctx context.Context) {
+					runSLODigest(ctx, cfg.SLO, b)
+				})
+			}
 
+			if cfg.WeeklyReport.Enabled() {
+				if _, ok := digestWeekdays[cfg.WeeklyReport.DayValue()]; !ok {
+					return fmt.Errorf("weeklyReport.day must be mon to sun, got %q", cfg.WeeklyReport.Day)
+				}
+				duties.run("weekly-report", func(ctx context.Context) {
+					runWeeklyReport(ctx, cfg.WeeklyReport, st, wsAbs, b)
+				})
+			}
+
+			if err := validateAccounts(cfg.Channels); err != nil {
+				return err
+			}
+			if err := validateSplitStrategies(cfg.Channels); err != nil {
+				return err
+			}
+			if err := validateGroupPolicies(cfg.Channels); err != nil {
+				return err
+			}
+			if err := validateAccess(cfg); err != nil {
+				return err
+			}
+			if err := validateEditPolicies(cfg.Channels); err != nil {
+				return err
+			}
+			if err := validateQuotePolicies(cfg.Channels); err != nil {
+				return err
+			}
+			if err := broadcast.Validate(cfg.Channels.Recipients); err != nil {
+				return fmt.Errorf("channels.recipients: %w", err)
+			}
+			webhooks, err := newWebhookServer(cfg)
+			if err != nil {
+				return err
+			}
 			cm := channels.NewManager(b)
 			if cfg.Channels.Discord.Enabled {
 				cm.Add(discord.New(cfg.Channels.Discord, b))
 			}
+			for _, name := range accountNames(cfg.Channels.Discord.Accounts) {
+				if a := cfg.Channels.Discord.Accounts[name]; a.Enabled {
+					cm.Add(discord.NewAccount(name, a, b))
+				}
+			}
 			var sl *slack.Channel
 			if cfg.Channels.Slack.Enabled {
 				if strings.TrimSpace(cfg.Channels.Slack.BotToken) == "" {
 					return fmt.Errorf("slack enabled but botToken is empty")
 				}
-				if strings.TrimSpace(cfg.Channels.Slack.AppToken) == "" {
-					return fmt.Errorf("slack enabled but appToken is empty")
+				switch cfg.Channels.Slack.Mode {
+				case config.SlackModeEvents:
+					if strings.TrimSpace(cfg.Channels.Slack.SigningSecret) == "" {
+						return fmt.Errorf("slack mode=events but signingSecret is empty")
+					}
+					if err := validateGatewayBindPolicy(config.GatewayConfig{
+						Listen:          cfg.Channels.Slack.Listen,
+						AllowPublicBind: cfg.Gateway.AllowPublicBind,
+					}); err != nil {
+						return fmt.Errorf("slack: %w", err)
+					}
+				case config.SlackModeSocket:
+					if strings.TrimSpace(cfg.Channels.Slack.AppToken) == "" {
+						return fmt.Errorf("slack enabled but appToken is empty")
+					}
+				default:
+					return fmt.Errorf("slack mode must be %q or %q, got %q", config.SlackModeSocket, config.SlackModeEvents, cfg.Channels.Slack.Mode)
 				}
 				sl = slack.New(cfg.Channels.Slack, b)
+				if webhooks != nil {
+					sl.SetServer(webhooks)
+				}
 				cm.Add(sl)
 			}
+			var telegramAccounts []string
 			if cfg.Channels.Telegram.Enabled {
-				if strings.TrimSpace(cfg.Channels.Telegram.Token) == "" {
-					return fmt.Errorf("telegram enabled but token is empty")
+				telegramAccounts = append(telegramAccounts, "")
+			}
+			for _, name := range accountNames(cfg.Channels.Telegram.Accounts) {
+				if cfg.Channels.Telegram.Accounts[name].Enabled {
+					telegramAccounts = append(telegramAccounts, name)
 				}
-				cm.Add(telegram.New(cfg.Channels.Telegram, b))
+			}
+			for _, name := range telegramAccounts {
+				a, _ := cfg.Channels.TelegramAccount(config.ChannelName("telegram", name))
+				if strings.TrimSpace(a.Token) == "" {
+					return fmt.Errorf("%s enabled but token is empty", config.ChannelName("telegram", name))
+				}
+				var tg channels.Channel = telegram.NewAccount(name, a, b)
+				if duties.elected() {
+					tg = &electedChannel{Channel: tg, duties: duties}
+				}
+				cm.Add(tg)
 			}
 			if cfg.Channels.WhatsApp.Enabled {
 				linked, err := whatsapp.IsLinked(ctx, cfg.Channels.WhatsApp)
@@ -138,27 +310,464 @@ func cmdGateway() *cli.Command {
 				}
 				cm.Add(whatsapp.New(cfg.Channels.WhatsApp, b))
 			}
+			if cfg.Channels.Matrix.Enabled {
+				if strings.TrimSpace(cfg.Channels.Matrix.Homeserver) == "" {
+					return fmt.Errorf("matrix enabled but homeserver is empty")
+				}
+				if strings.TrimSpace(cfg.Channels.Matrix.AccessToken) == "" {
+					return fmt.Errorf("matrix enabled but accessToken is empty")
+				}
+				cm.Add(matrix.New(cfg.Channels.Matrix, b))
+			}
+			if cfg.Channels.Mastodon.Enabled {
+				if strings.TrimSpace(cfg.Channels.Mastodon.Server) == "" {
+					return fmt.Errorf("mastodon enabled but server is empty")
+				}
+				if strings.TrimSpace(cfg.Channels.Mastodon.AccessToken) == "" {
+					return fmt.Errorf("mastodon enabled but accessToken is empty")
+				}
+				switch cfg.Channels.Mastodon.Visibility {
+				case "", "public", "unlisted", "private", "direct":
+				default:
+					return fmt.Errorf("mastodon visibility must be public, unlisted, private or direct, got %q", cfg.Channels.Mastodon.Visibility)
+				}
+				var md channels.Channel = mastodon.New(cfg.Channels.Mastodon, b)
+				if duties.elected() {
+					md = &electedChannel{Channel: md, duties: duties}
+				}
+				cm.Add(md)
+			}
+			if cfg.Channels.Voice.Enabled {
+				if strings.TrimSpace(cfg.Channels.Voice.AuthToken) == "" {
+					return fmt.Errorf("voice enabled but authToken is empty")
+				}
+				if strings.TrimSpace(cfg.Channels.Voice.PublicURL) == "" {
+					return fmt.Errorf("voice enabled but publicURL is empty")
+				}
+				if err := validateGatewayBindPolicy(config.GatewayConfig{
+					Listen:          cfg.Channels.Voice.Listen,
+					AllowPublicBind: cfg.Gateway.AllowPublicBind,
+				}); err != nil {
+					return fmt.Errorf("voice: %w", err)
+				}
+				vc := voice.New(cfg.Channels.Voice, b)
+				if webhooks != nil {
+					vc.SetServer(webhooks)
+				}
+				cm.Add(vc)
+			}
+			if cfg.Channels.GRPC.Enabled {
+				if strings.TrimSpace(cfg.Channels.GRPC.Token) == "" {
+					return fmt.Errorf("grpc enabled but token is empty")
+				}
+				if err := validateGatewayBindPolicy(config.GatewayConfig{
+					Listen:          cfg.Channels.GRPC.Listen,
+					AllowPublicBind: cfg.Gateway.AllowPublicBind,
+				}); err != nil {
+					return fmt.Errorf("grpc: %w", err)
+				}
+				cm.Add(grpcchannel.New(cfg.Channels.GRPC, b))
+			}
+			if cfg.Channels.Instagram.Enabled {
+				if strings.TrimSpace(cfg.Channels.Instagram.AccessToken) == "" {
+					return fmt.Errorf("instagram enabled but accessToken is empty")
+				}
+				if strings.TrimSpace(cfg.Channels.Instagram.AppSecret) == "" {
+					return fmt.Errorf("instagram enabled but appSecret is empty")
+				}
+				if strings.TrimSpace(cfg.Channels.Instagram.VerifyToken) == "" {
+					return fmt.Errorf("instagram enabled but verifyToken is empty")
+				}
+				if err := validateGatewayBindPolicy(config.GatewayConfig{
+					Listen:          cfg.Channels.Instagram.Listen,
+					AllowPublicBind: cfg.Gateway.AllowPublicBind,
+				}); err != nil {
+					return fmt.Errorf("instagram: %w", err)
+				}
+				ig := instagram.New(cfg.Channels.Instagram, b)
+				if webhooks != nil {
+					ig.SetServer(webhooks)
+				}
+				cm.Add(ig)
+			}
+			if cfg.Channels.Push.Enabled {
+				p := cfg.Channels.Push
+				hasPushover := strings.TrimSpace(p.Pushover.AppToken) != "" || strings.TrimSpace(p.Pushover.UserKey) != ""
+				if hasPushover && !p.PushoverEnabled() {
+					return fmt.Errorf("push: pushover needs both appToken and userKey")
+				}
+				if !p.NtfyEnabled() && !p.PushoverEnabled() {
+					return fmt.Errorf("push enabled but neither ntfy.topic nor pushover is set")
+				}
+				cm.Add(push.New(p))
+			}
+			if cfg.Channels.MQTT.Enabled {
+				if strings.TrimSpace(cfg.Channels.MQTT.Broker) == "" {
+					return fmt.Errorf("mqtt enabled but broker is empty")
+				}
+				if q := cfg.Channels.MQTT.QoS; q != 0 && q != 1 {
+					return fmt.Errorf("mqtt qos must be 0 or 1, got %d", q)
+				}
+				cm.Add(mqtt.New(cfg.Channels.MQTT, b))
+			}
 
+			cm.SetRateLimits(rateLimits(cfg.Channels, cm.Names()))
+			policies, err := attachmentPolicies(cfg.Channels, cm.Names())
+			if err != nil {
+				return err
+			}
+			cm.SetAttachmentPolicies(policies)
+			cm.SetChaos(inj)
+			seen := channels.NewDeduper(0, paths.InboundSeenPath())
+			defer func() {
+				if err := seen.Close(); err != nil {
+					log.Printf("channels: dedupe store not saved: %v", err)
+				}
+			}()
+			cm.SetDeduper(seen)
+			if webhooks != nil {
+				if err := webhooks.Start(ctx); err != nil {
+					return err
+				}
+			}
 			if err := cm.StartAll(ctx); err != nil {
 				return err
 			}
 
 			go func() { _ = loop.Run(ctx) }()
+			if cfg.Gateway.Admin.Enabled {
+				if err := serveAdmin(ctx, cfg, b, loop); err != nil {
+					return err
+				}
+			}
+			if cfg.Watchdog.Enabled {
+				go newWatchdog(cfg.Watchdog, cm, b).Run(ctx)
+			}
+
+			if err := handover.Ready(); err != nil {
+				fmt.Fprintf(os.Stderr, "handover: %v\n", err)
+			}
+			writeGatewayPID()
+			defer removeGatewayPID()
 
 			fmt.Printf("gateway running\n- workspace: %s\n- sessions: %s\n", wsAbs, paths.SessionsDir())
+			if cfg.Gateway.Admin.Enabled {
+				fmt.Printf("- admin: http://%s/metrics, /slo\n", cfg.Gateway.Listen)
+			}
 			fmt.Println("stop: Ctrl+C")
-			<-ctx.Done()
+			waitGateway(ctx)
 
 			_ = cm.StopAll()
-			if cronSvc != nil {
-				cronSvc.Stop()
-			}
-			hb.Stop()
 			return nil
 		},
 	}
 }
 
+func newWatchdog(c config.WatchdogConfig, cm *channels.Manager, b *bus.Bus) *watchdog.Watchdog {
+	opts := watchdog.Options{
+		Interval:      time.Duration(c.IntervalSec) * time.Second,
+		DispatchStall: time.Duration(c.DispatchStallSec) * time.Second,
+		Idle:          time.Duration(c.IdleHoursValue()) * time.Hour,
+		Restart:       c.RestartValue(),
+	}
+	if c.AlertChannel != "" && c.AlertChatID != "" {
+		opts.Alert = func(ctx context.Context, text string) {
+			_ = b.PublishOutbound(ctx, bus.OutboundMessage{Channel: c.AlertChannel, ChatID: c.AlertChatID, Content: text})
+		}
+	}
+	return watchdog.New(cm, b, opts)
+}
+
+// waitGateway blocks until ctx is done or a handover to a newly installed
+// binary succeeds. After a handover the caller drains channels and exits while
+// the new process keeps serving on the inherited listeners.
+func waitGateway(ctx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	if len(handover.Signals) > 0 {
+		signal.Notify(sigCh, handover.Signals...)
+		defer signal.Stop(sigCh)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			exe, err := os.Executable()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "handover: %v\n", err)
+				continue
+			}
+			fmt.Printf("handover: starting %s\n", exe)
+			pid, err := handover.Start(ctx, exe, os.Args[1:], handover.DefaultReadyTimeout)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "handover failed, still serving: %v\n", err)
+				continue
+			}
+			fmt.Printf("handover: pid %d is ready; draining\n", pid)
+			return
+		}
+	}
+}
+
+func rateLimits(c config.ChannelsConfig, names []string) map[string]channels.RateLimit {
+	out := map[string]channels.RateLimit{}
+	for _, name := range names {
+		if rl, ok := c.RateLimitFor(name); ok {
+			out[name] = channels.RateLimit(rl)
+		}
+	}
+	return out
+}
+
+// attachmentPolicies returns the inbound attachment limits of the named
+// channels.
+func attachmentPolicies(c config.ChannelsConfig, names []string) (map[string]channels.AttachmentPolicy, error) {
+	for key, a := range c.Attachments {
+		if err := channels.AttachmentPolicy(a).Validate(); err != nil {
+			return nil, fmt.Errorf("channels.attachments.%s: %w", key, err)
+		}
+	}
+	out := map[string]channels.AttachmentPolicy{}
+	for _, name := range names {
+		if a, ok := c.AttachmentsFor(name); ok {
+			out[name] = channels.AttachmentPolicy(a)
+		}
+	}
+	return out, nil
+}
+
+// accountNames returns the names of accounts in order.
+func accountNames[T any](accounts map[string]T) []string {
+	names := make([]string, 0, len(accounts))
+	for name := range accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateAccounts rejects an account name that can't name a channel.
+func validateAccounts(c config.ChannelsConfig) error {
+	for typ, names := range map[string][]string{
+		"telegram": accountNames(c.Telegram.Accounts),
+		"discord":  accountNames(c.Discord.Accounts),
+	} {
+		for _, name := range names {
+			if err := config.ValidateAccountName(name); err != nil {
+				return fmt.Errorf("channels.%s.accounts: %w", typ, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateSplitStrategies rejects an unknown "split" setting up front;
+// channels fall back to splitting by paragraphs.
+func validateSplitStrategies(c config.ChannelsConfig) error {
+	splits := map[string]string{
+		"discord":   c.Discord.Split,
+		"slack":     c.Slack.Split,
+		"telegram":  c.Telegram.Split,
+		"whatsapp":  c.WhatsApp.Split,
+		"mastodon":  c.Mastodon.Split,
+		"instagram": c.Instagram.Split,
+	}
+	for name, a := range c.Telegram.Accounts {
+		splits["telegram.accounts."+name] = a.Split
+	}
+	for name, a := range c.Discord.Accounts {
+		splits["discord.accounts."+name] = a.Split
+	}
+	for name, split := range splits {
+		if _, err := channels.SplitterFor(split); err != nil {
+			return fmt.Errorf("channels.%s.split: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateEditPolicies rejects an unknown "edits" setting up front.
+func validateEditPolicies(c config.ChannelsConfig) error {
+	policies := map[string]string{
+		"discord":  c.Discord.Edits,
+		"telegram": c.Telegram.Edits,
+	}
+	for name, a := range c.Telegram.Accounts {
+		policies["telegram.accounts."+name] = a.Edits
+	}
+	for name, a := range c.Discord.Accounts {
+		policies["discord.accounts."+name] = a.Edits
+	}
+	for name, edits := range policies {
+		if err := config.ValidateEdits(edits); err != nil {
+			return fmt.Errorf("channels.%s.edits: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateQuotePolicies rejects an unknown "quote" setting up front;
+// channels fall back to their usual behavior.
+func validateQuotePolicies(c config.ChannelsConfig) error {
+	quotes := map[string]string{
+		"discord":  c.Discord.Quote,
+		"telegram": c.Telegram.Quote,
+		"whatsapp": c.WhatsApp.Quote,
+		"matrix":   c.Matrix.Quote,
+		"mastodon": c.Mastodon.Quote,
+	}
+	for name, a := range c.Telegram.Accounts {
+		quotes["telegram.accounts."+name] = a.Quote
+	}
+	for name, a := range c.Discord.Accounts {
+		quotes["discord.accounts."+name] = a.Quote
+	}
+	for name, quote := range quotes {
+		if err := channels.ValidateQuote(quote); err != nil {
+			return fmt.Errorf("channels.%s.quote: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// cronBroadcast runs a cron job that delivers to a recipient group. An
+// agent turn runs once, in the job's own session, and each member gets a
+// personalized copy of its answer.
+func cronBroadcast(ctx context.Context, cfg *config.Config, wsAbs string, b *bus.Bus, loop *agent.Loop, job cron.Job) error {
+	var content string
+	var err error
+	switch job.Payload.Kind {
+	case "", "agent_turn":
+		if loop == nil {
+			return errors.New("agent not ready")
+		}
+		if content, err = loop.ProcessDirect(ctx, job.Payload.Message, "cron:"+job.ID, "cron", job.ID); err != nil {
+			return err
+		}
+	case reminders.PayloadKind:
+		content = reminders.Message(job.Payload.Message)
+	default:
+		return nil
+	}
+	return sendToGroup(ctx, cfg, wsAbs, b, job.Payload.Group, content)
+}
+
+func sendToGroup(ctx context.Context, cfg *config.Config, wsAbs string, b *bus.Bus, group, content string) error {
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	recipients, err := broadcast.Resolve(cfg.Channels.Recipients, group, contacts.NewStore(contacts.Path(wsAbs)))
+	if err != nil {
+		return err
+	}
+	_, err = broadcast.Send(ctx, b.PublishOutbound, bus.OutboundMessage{Content: content}, recipients)
+	return err
+}
+
+// cronBatcher runs the cron jobs marked batch on the provider's batch API
+// and delivers each result to the job's chat or group.
+type cronBatcher struct {
+	cfg   *config.Config
+	wsAbs string
+	b     *bus.Bus
+	loop  *agent.Loop
+}
+
+func (c cronBatcher) Submit(ctx context.Context, job cron.Job) (string, error) {
+	if k := job.Payload.Kind; k != "" && k != "agent_turn" {
+		return "", fmt.Errorf("only agent turns run as a batch, not %s", k)
+	}
+	return c.loop.SubmitBatch(ctx, job.Payload.Message, job.Payload.Channel, job.Payload.To)
+}
+
+func (c cronBatcher) Collect(ctx context.Context, job cron.Job) (bool, error) {
+	res, done, err := c.loop.BatchResult(ctx, job.State.BatchID, job.Payload.Channel)
+	if !done || err != nil {
+		return done, err
+	}
+	if job.Payload.Group != "" {
+		return true, sendToGroup(ctx, c.cfg, c.wsAbs, c.b, job.Payload.Group, res)
+	}
+	ch, to := strings.TrimSpace(job.Payload.Channel), strings.TrimSpace(job.Payload.To)
+	if !job.Payload.Deliver || ch == "" || to == "" || strings.TrimSpace(res) == "" {
+		return true, nil
+	}
+	return true, c.b.PublishOutbound(ctx, bus.OutboundMessage{Channel: ch, ChatID: to, Content: res})
+}
+
+// validateGroupPolicies rejects an unknown "groupPolicy" or "groups" entry
+// up front; the channels would otherwise ignore every group message.
+func validateGroupPolicies(c config.ChannelsConfig) error {
+	policies := map[string]channels.GroupPolicy{
+		"discord":  {Policy: c.Discord.GroupPolicy, Groups: c.Discord.Groups},
+		"slack":    {Policy: c.Slack.GroupPolicy, Groups: c.Slack.Groups},
+		"telegram": {Policy: c.Telegram.GroupPolicy, Groups: c.Telegram.Groups},
+		"matrix":   {Policy: c.Matrix.GroupPolicy, Groups: c.Matrix.Groups},
+	}
+	for name, a := range c.Telegram.Accounts {
+		policies["telegram.accounts."+name] = channels.GroupPolicy{Policy: a.GroupPolicy, Groups: a.Groups}
+	}
+	for name, a := range c.Discord.Accounts {
+		policies["discord.accounts."+name] = channels.GroupPolicy{Policy: a.GroupPolicy, Groups: a.Groups}
+	}
+	for name, p := range policies {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("channels.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// newWebhookServer returns the shared webhook server, or nil when
+// channels.webhooks.listen is not set.
+func newWebhookServer(cfg *config.Config) (*httpserver.Server, error) {
+	w := cfg.Channels.Webhooks
+	if strings.TrimSpace(w.Listen) == "" {
+		return nil, nil
+	}
+	if err := validateGatewayBindPolicy(config.GatewayConfig{
+		Listen:          w.Listen,
+		AllowPublicBind: cfg.Gateway.AllowPublicBind,
+	}); err != nil {
+		return nil, fmt.Errorf("channels.webhooks: %w", err)
+	}
+	s := httpserver.New(w.Listen, w.TLSCert, w.TLSKey)
+	var domains []string
+	for _, d := range w.ACME.Domains {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	if len(domains) > 0 {
+		cacheDir := strings.TrimSpace(w.ACME.CacheDir)
+		if cacheDir == "" {
+			cacheDir = paths.ACMECacheDir()
+		}
+		s.UseACME(domains, w.ACME.Email, cacheDir)
+	}
+	return s, nil
+}
+
+// validateAccess rejects malformed sender entries and unknown tool names,
+// which would otherwise leave a tool open to senders it was meant for
+// admins only. The deprecated owner lists are checked as admins.
+func validateAccess(cfg *config.Config) error {
+	c := cfg.Channels.Access
+	p := channels.AccessPolicy{Admins: cfg.AdminSenders(), Readonly: c.Readonly}
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("channels.access: %w", err)
+	}
+	if len(cfg.Agents.Defaults.Trace.Owners) > 0 || len(cfg.Agents.Defaults.Takeover.Owners) > 0 {
+		log.Printf("config: agents.defaults.trace.owners and takeover.owners are deprecated; list those senders in channels.access.admins")
+	}
+	for _, name := range append(slices.Clone(c.AdminTools), c.ReadonlyTools...) {
+		if !slices.Contains(tools.ToolNames, name) {
+			return fmt.Errorf("channels.access: unknown tool %q", name)
+		}
+	}
+	return nil
+}
+
 func validateGatewayBindPolicy(cfg config.GatewayConfig) error {
 	listen := strings.TrimSpace(cfg.Listen)
 	if listen == "" {
@@ -197,3 +806,99 @@ func isLocalGatewayHost(host string) bool {
 	}
 	return false
 }
+
+// newGatewayBus returns the in-process bus, or one shared with other
+// instances through Redis or NATS when bus.backend is "redis" or "nats".
+func newGatewayBus(ctx context.Context, cfg *config.Config) (*bus.Bus, error) {
+	switch cfg.Bus.Backend {
+	case "", config.BusBackendMemory:
+		return bus.New(256), nil
+	case config.BusBackendRedis:
+		id, err := gatewayInstanceID(cfg)
+		if err != nil {
+			return nil, err
+		}
+		br, err := bus.NewRedisBroker(ctx, bus.RedisOptions{
+			URL:        cfg.Bus.RedisURL,
+			Prefix:     cfg.Bus.Prefix,
+			InstanceID: id,
+			Partitions: cfg.Bus.Partitions,
+			Channels:   enabledChannels(cfg),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return bus.NewWithBroker(br), nil
+	case config.BusBackendNATS:
+		id, err := gatewayInstanceID(cfg)
+		if err != nil {
+			return nil, err
+		}
+		br, err := bus.NewNATSBroker(ctx, bus.NATSOptions{
+			URL:        cfg.Bus.NATSURL,
+			Prefix:     cfg.Bus.Prefix,
+			InstanceID: id,
+			Partitions: cfg.Bus.Partitions,
+			Channels:   enabledChannels(cfg),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return bus.NewWithBroker(br), nil
+	default:
+		return nil, fmt.Errorf("bus.backend must be %q, %q or %q, got %q", config.BusBackendMemory, config.BusBackendRedis, config.BusBackendNATS, cfg.Bus.Backend)
+	}
+}
+
+// setBusMaxAge makes b skip messages older than bus.maxAgeSec, reporting
+// them as bus.stalePolicy says.
+func setBusMaxAge(b *bus.Bus, c config.BusConfig) error {
+	if c.MaxAgeSec < 0 {
+		return fmt.Errorf("bus.maxAgeSec must be >= 0, got %d", c.MaxAgeSec)
+	}
+	var publish func(context.Context, bus.OutboundMessage) error
+	switch c.StalePolicy {
+	case "", config.BusStalePolicyDrop:
+	case config.BusStalePolicyNotify:
+		publish = b.PublishOutbound
+	default:
+		return fmt.Errorf("bus.stalePolicy must be %q or %q, got %q", config.BusStalePolicyDrop, config.BusStalePolicyNotify, c.StalePolicy)
+	}
+	if c.MaxAgeSec > 0 {
+		b.SetMaxAge(time.Duration(c.MaxAgeSec)*time.Second, channels.NewStaleNotices(0, publish).Expired)
+	}
+	return nil
+}
+
+func enabledChannels(cfg *config.Config) []string {
+	var out []string
+	for name, on := range map[string]bool{
+		"discord":   cfg.Channels.Discord.Enabled,
+		"slack":     cfg.Channels.Slack.Enabled,
+		"telegram":  cfg.Channels.Telegram.Enabled,
+		"whatsapp":  cfg.Channels.WhatsApp.Enabled,
+		"matrix":    cfg.Channels.Matrix.Enabled,
+		"mastodon":  cfg.Channels.Mastodon.Enabled,
+		"voice":     cfg.Channels.Voice.Enabled,
+		"grpc":      cfg.Channels.GRPC.Enabled,
+		"instagram": cfg.Channels.Instagram.Enabled,
+		"push":      cfg.Channels.Push.Enabled,
+		"mqtt":      cfg.Channels.MQTT.Enabled,
+	} {
+		if on {
+			out = append(out, name)
+		}
+	}
+	for name, a := range cfg.Channels.Telegram.Accounts {
+		if a.Enabled {
+			out = append(out, config.ChannelName("telegram", name))
+		}
+	}
+	for name, a := range cfg.Channels.Discord.Accounts {
+		if a.Enabled {
+			out = append(out, config.ChannelName("discord", name))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -29,3 +29,21 @@ func TestValidateGatewayBindPolicy_PublicBindAllowedWhenExplicitlyEnabled(t *tes
 		t.Fatalf("expected explicit public bind allow, got: %v", err)
 	}
 }
+
+func TestValidateAdminToken(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  config.GatewayConfig
+		ok   bool
+	}{
+		{"loopback without token", config.GatewayConfig{Listen: "127.0.0.1:18790", Admin: config.AdminConfig{Enabled: true}}, true},
+		{"public without token", config.GatewayConfig{Listen: "0.0.0.0:18790", AllowPublicBind: true, Admin: config.AdminConfig{Enabled: true}}, false},
+		{"loopback behind a proxy without token", config.GatewayConfig{Listen: "127.0.0.1:18790", AllowPublicBind: true, Admin: config.AdminConfig{Enabled: true}}, false},
+		{"public with token", config.GatewayConfig{Listen: "0.0.0.0:18790", AllowPublicBind: true, Admin: config.AdminConfig{Enabled: true, Token: "secret"}}, true},
+		{"disabled", config.GatewayConfig{Listen: "0.0.0.0:18790", AllowPublicBind: true}, true},
+	} {
+		if err := validateAdminToken(tc.cfg); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+}
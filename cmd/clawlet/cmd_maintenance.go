@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/maintenance"
+	"github.com/mosaxiv/clawlet/memory"
+	"github.com/mosaxiv/clawlet/storage"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdMaintenance() *cli.Command {
+	return &cli.Command{
+		Name:  "maintenance",
+		Usage: "remove stale temp files and idle sessions, and compact the SQLite store",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "dry-run", Usage: "list what would be removed without removing it"},
+			&cli.StringFlag{Name: "workspace", Usage: "workspace directory (default: ~/.clawlet/workspace or CLAWLET_WORKSPACE)"},
+			&cli.IntFlag{Name: "session-days", Usage: "delete sessions idle this many days (default: maintenance.sessionRetentionDays)"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			cfg, _, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := openStorage(cfg)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+			ws, err := resolveWorkspace(cmd.String("workspace"))
+			if err != nil {
+				return err
+			}
+			idx, err := memory.NewIndexManager(cfg, ws)
+			if err != nil {
+				return err
+			}
+			defer idx.Close()
+
+			opts := maintenanceOptions(cfg.Maintenance, st, idx)
+			opts.DryRun = cmd.Bool("dry-run")
+			if days := cmd.Int("session-days"); days > 0 {
+				opts.SessionMaxAge = time.Duration(days) * 24 * time.Hour
+			}
+			rep, err := maintenance.Run(opts)
+			for _, f := range rep.TempFiles {
+				fmt.Println("temp:", f)
+			}
+			for _, k := range rep.Sessions {
+				fmt.Println("session:", k)
+			}
+			fmt.Println(rep.Summary())
+			return err
+		},
+	}
+}
+
+// maintenanceOptions builds the options of a run; idx is nil when memory
+// search is off.
+func maintenanceOptions(c config.MaintenanceConfig, st storage.Store, idx *memory.IndexManager) maintenance.Options {
+	opts := maintenance.Options{
+		Sessions:      st,
+		SessionMaxAge: time.Duration(c.SessionRetentionDays) * 24 * time.Hour,
+		TempDir:       os.TempDir(),
+		TempMaxAge:    time.Duration(c.TempRetentionHoursValue()) * time.Hour,
+	}
+	if idx != nil {
+		opts.Index = idx
+	}
+	return opts
+}
+
+// runMaintenance runs a pass now and then every IntervalHours until ctx is
+// done.
+func runMaintenance(ctx context.Context, c config.MaintenanceConfig, st storage.Store, idx *memory.IndexManager) {
+	t := time.NewTicker(time.Duration(c.IntervalHoursValue()) * time.Hour)
+	defer t.Stop()
+	for {
+		rep, err := maintenance.Run(maintenanceOptions(c, st, idx))
+		if err != nil {
+			log.Printf("maintenance: %v", err)
+		}
+		if len(rep.TempFiles)+len(rep.Sessions) > 0 {
+			log.Print(rep.Summary())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
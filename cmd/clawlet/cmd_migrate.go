@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mosaxiv/clawlet/migrate"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdMigrate() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "upgrade on-disk state (sessions, cron store) to the current format",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "dry-run", Usage: "list pending migrations and affected files without writing"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			// Loads the encryption key for session files.
+			if _, _, err := loadConfig(); err != nil {
+				return err
+			}
+			env, err := migrate.DefaultEnv()
+			if err != nil {
+				return err
+			}
+			rep, err := migrate.Run(env, cmd.Bool("dry-run"))
+			printMigrationReport(rep)
+			if err != nil {
+				return err
+			}
+			if len(rep.Steps) == 0 {
+				fmt.Println("state is up to date")
+			}
+			return nil
+		},
+	}
+}
+
+// runStartupMigrations applies pending migrations before long-running
+// commands touch state.
+func runStartupMigrations() error {
+	env, err := migrate.DefaultEnv()
+	if err != nil {
+		return err
+	}
+	rep, err := migrate.Run(env, false)
+	if err != nil {
+		return fmt.Errorf("state migration failed: %w\nhint: run `clawlet migrate --dry-run` for details", err)
+	}
+	for _, s := range rep.Steps {
+		if len(s.Changes) > 0 {
+			fmt.Fprintf(os.Stderr, "migrated %s v%d -> v%d (%d files)\n", s.Store, s.From, s.To, len(s.Changes))
+		}
+	}
+	if rep.BackupDir != "" {
+		fmt.Fprintf(os.Stderr, "backups: %s\n", rep.BackupDir)
+	}
+	return nil
+}
+
+func printMigrationReport(rep migrate.Report) {
+	verb := "applied"
+	if rep.DryRun {
+		verb = "pending"
+	}
+	for _, s := range rep.Steps {
+		fmt.Printf("%s: %s v%d -> v%d: %s\n", verb, s.Store, s.From, s.To, s.Description)
+		for _, p := range s.Changes {
+			fmt.Printf("  - %s\n", p)
+		}
+	}
+	if rep.BackupDir != "" {
+		fmt.Printf("backups: %s\n", rep.BackupDir)
+	}
+}
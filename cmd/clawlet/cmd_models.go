@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdModels() *cli.Command {
+	baseURL := &cli.StringFlag{Name: "base-url", Usage: "Ollama URL (default: llm.baseURL when the provider is ollama, else " + config.DefaultOllamaBaseURL + ")"}
+	return &cli.Command{
+		Name:  "models",
+		Usage: "manage local Ollama models",
+		Commands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "list installed models",
+				Flags: []cli.Flag{baseURL},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					o, _, err := ollamaClient(cmd)
+					if err != nil {
+						return err
+					}
+					models, err := o.List(ctx)
+					if err != nil {
+						return err
+					}
+					if len(models) == 0 {
+						fmt.Println("No models installed.")
+						return nil
+					}
+					for _, m := range models {
+						fmt.Printf("- %s %s %s\n", m.Name, formatBytes(m.Size), m.ModifiedAt.Format("2006-01-02"))
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "pull",
+				Usage:     "download a model (default: the configured model)",
+				ArgsUsage: "[model]",
+				Flags:     []cli.Flag{baseURL},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					o, model, err := ollamaClient(cmd)
+					if err != nil {
+						return err
+					}
+					if cmd.Args().Len() > 0 {
+						model = strings.TrimSpace(cmd.Args().Get(0))
+					}
+					if model == "" {
+						return cli.Exit("usage: clawlet models pull <model>", 2)
+					}
+					status := ""
+					err = o.Pull(ctx, model, func(p llm.PullProgress) {
+						if p.Total > 0 {
+							fmt.Printf("\r%s %3d%% of %s", p.Status, p.Completed*100/p.Total, formatBytes(p.Total))
+							status = p.Status
+							return
+						}
+						if status != "" {
+							fmt.Println()
+						}
+						status = ""
+						fmt.Println(p.Status)
+					})
+					if status != "" {
+						fmt.Println()
+					}
+					return err
+				},
+			},
+			{
+				Name:      "rm",
+				Usage:     "delete a model",
+				ArgsUsage: "<model>",
+				Flags:     []cli.Flag{baseURL},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Args().Len() < 1 {
+						return cli.Exit("usage: clawlet models rm <model>", 2)
+					}
+					o, _, err := ollamaClient(cmd)
+					if err != nil {
+						return err
+					}
+					model := strings.TrimSpace(cmd.Args().Get(0))
+					if err := o.Delete(ctx, model); err != nil {
+						return fmt.Errorf("%s: %w", model, err)
+					}
+					fmt.Printf("deleted %s\n", model)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// ollamaClient returns a client for --base-url or the configured Ollama, and
+// the configured model if the provider is ollama.
+func ollamaClient(cmd *cli.Command) (*llm.Ollama, string, error) {
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	o := &llm.Ollama{BaseURL: config.DefaultOllamaBaseURL}
+	model := ""
+	if cfg.LLM.Provider == "ollama" {
+		o.BaseURL = cfg.LLM.BaseURL
+		o.Headers = cfg.LLM.Headers
+		model = cfg.LLM.Model
+	}
+	if v := strings.TrimSpace(cmd.String("base-url")); v != "" {
+		o.BaseURL = v
+	}
+	return o, model, nil
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	default:
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/presence"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdPresence() *cli.Command {
+	return &cli.Command{
+		Name:  "presence",
+		Usage: "mark the agent away or back by hand",
+		Commands: []*cli.Command{
+			presenceAwayCmd(),
+			presenceBackCmd(),
+			presenceAutoCmd(),
+			presenceStatusCmd(),
+		},
+	}
+}
+
+func presenceAwayCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "away",
+		Usage: "answer chats with the away notice and hold their messages",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{Name: "for", Usage: "stay away this long, e.g. 2h (default: until `presence back`)"},
+			&cli.StringFlag{Name: "note", Usage: "notice to send instead of presence.notice"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			m := &presence.Manual{Away: true, Note: strings.TrimSpace(cmd.String("note"))}
+			if d := cmd.Duration("for"); d > 0 {
+				m.Until = time.Now().Add(d)
+			}
+			if err := presence.SaveManual(paths.PresencePath(), m); err != nil {
+				return err
+			}
+			fmt.Println(describeManual(m))
+			return nil
+		},
+	}
+}
+
+func presenceBackCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "back",
+		Usage: "answer chats again, ignoring the schedule, and handle held messages",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{Name: "for", Usage: "ignore the schedule this long (default: until `presence auto`)"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			m := &presence.Manual{}
+			if d := cmd.Duration("for"); d > 0 {
+				m.Until = time.Now().Add(d)
+			}
+			if err := presence.SaveManual(paths.PresencePath(), m); err != nil {
+				return err
+			}
+			fmt.Println(describeManual(m))
+			return nil
+		},
+	}
+}
+
+func presenceAutoCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "auto",
+		Usage: "follow the presence.away schedule again",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := presence.SaveManual(paths.PresencePath(), nil); err != nil {
+				return err
+			}
+			fmt.Println("Following the schedule.")
+			return nil
+		},
+	}
+}
+
+func presenceStatusCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "show whether the agent is away",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			cfg, _, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			tr, err := presence.New(cfg.Presence, paths.PresencePath(), paths.PresenceQueuePath())
+			if err != nil {
+				return err
+			}
+			state := "present"
+			if away, _ := tr.Away(time.Now()); away {
+				state = "away"
+			}
+			m, err := presence.LoadManual(paths.PresencePath())
+			if err != nil {
+				return err
+			}
+			if m != nil && m.Active(time.Now()) {
+				fmt.Println(describeManual(m))
+			} else {
+				fmt.Printf("%s (schedule: %d away windows)\n", state, len(cfg.Presence.Away))
+			}
+			fmt.Printf("held messages: %d\n", tr.Queued())
+			return nil
+		},
+	}
+}
+
+func describeManual(m *presence.Manual) string {
+	state := "Back"
+	if m.Away {
+		state = "Away"
+	}
+	if m.Until.IsZero() {
+		return state + " until changed."
+	}
+	return state + " until " + m.Until.Format("Mon 15:04") + "."
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/agent"
+	"github.com/mosaxiv/clawlet/storage"
+	"github.com/mosaxiv/clawlet/turns"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdReplay() *cli.Command {
+	return &cli.Command{
+		Name:      "replay",
+		Usage:     "re-run a recorded turn against its recorded model and tool responses",
+		ArgsUsage: "<turn_id>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "list", Usage: "list recorded turns instead"},
+			&cli.StringFlag{Name: "session", Usage: "with --list: only turns of this session key"},
+			&cli.IntFlag{Name: "limit", Value: 20, Usage: "with --list: newest turns to show"},
+			&cli.BoolFlag{Name: "live", Usage: "ask the model again instead of using the recorded responses"},
+			&cli.BoolFlag{Name: "live-tools", Usage: "run tool calls that are not in the recording (may have side effects)"},
+			&cli.BoolFlag{Name: "json", Usage: "print the replayed turn as JSON"},
+			&cli.StringFlag{Name: "workspace", Usage: "workspace directory (default: ~/.clawlet/workspace or CLAWLET_WORKSPACE)"},
+			&cli.IntFlag{Name: "max-iters", Value: 20, Usage: "max tool-call iterations"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			cfg, _, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := openStorage(cfg)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+
+			if cmd.Bool("list") {
+				return listTurns(st, cmd.String("session"), cmd.Int("limit"))
+			}
+			if cmd.Args().Len() < 1 {
+				return cli.Exit("usage: clawlet replay <turn_id> (see clawlet replay --list)", 2)
+			}
+			id := strings.TrimSpace(cmd.Args().Get(0))
+			rec, err := turns.Load(st, id)
+			if errors.Is(err, storage.ErrNotFound) {
+				return cli.Exit("turn not found: "+id, 1)
+			}
+			if err != nil {
+				return err
+			}
+
+			wsAbs, err := resolveWorkspace(cmd.String("workspace"))
+			if err != nil {
+				return err
+			}
+			a, err := agent.New(agent.Options{
+				Config:       cfg,
+				WorkspaceDir: wsAbs,
+				SessionKey:   rec.SessionKey,
+				Sessions:     st,
+				MaxIters:     cmd.Int("max-iters"),
+			})
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			got, err := a.Replay(ctx, rec, agent.ReplayOptions{
+				Live:      cmd.Bool("live"),
+				LiveTools: cmd.Bool("live-tools"),
+			})
+			if cmd.Bool("json") {
+				b, jerr := json.MarshalIndent(got, "", "  ")
+				if jerr != nil {
+					return jerr
+				}
+				fmt.Println(string(b))
+				return err
+			}
+			printReplay(rec, got)
+			return err
+		},
+	}
+}
+
+func listTurns(st storage.Store, sessionKey string, limit int) error {
+	ids, err := turns.List(st)
+	if err != nil {
+		return err
+	}
+	shown := 0
+	for i := len(ids) - 1; i >= 0 && (limit <= 0 || shown < limit); i-- {
+		t, err := turns.Load(st, ids[i])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			continue
+		}
+		if sessionKey != "" && t.SessionKey != sessionKey {
+			continue
+		}
+		fmt.Printf("- %s session=%s steps=%d user=%q\n", t.ID, t.SessionKey, len(t.Steps), preview(t.UserText(), 60))
+		shown++
+	}
+	if shown == 0 {
+		fmt.Println("No recorded turns. Enable agents.defaults.record.enabled to record them.")
+	}
+	return nil
+}
+
+func printReplay(rec, got *turns.Turn) {
+	fmt.Printf("turn %s session=%s model=%s recorded=%s\n", rec.ID, rec.SessionKey, got.Model, rec.StartedAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("user: %s\n", preview(rec.UserText(), 200))
+	for i, s := range got.Steps {
+		if s.Error != "" {
+			fmt.Printf("[%d] model error: %s\n", i+1, s.Error)
+			continue
+		}
+		if len(s.ToolCalls) == 0 {
+			fmt.Printf("[%d] reply: %s\n", i+1, s.Content)
+			continue
+		}
+		if strings.TrimSpace(s.Content) != "" {
+			fmt.Printf("[%d] %s\n", i+1, preview(s.Content, 200))
+		}
+		for _, tc := range s.ToolCalls {
+			fmt.Printf("[%d] tool %s %s\n", i+1, tc.Name, preview(string(tc.Arguments), 200))
+			fmt.Printf("    -> %s\n", preview(tc.Output, 200))
+		}
+	}
+	if got.Error != "" {
+		return
+	}
+	if got.FinalContent() == rec.FinalContent() {
+		fmt.Println("reply matches the recording")
+	} else {
+		fmt.Printf("reply differs from the recording:\n%s\n", rec.FinalContent())
+	}
+}
+
+func preview(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > max {
+		return string(r[:max]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/metrics"
+	"github.com/mosaxiv/clawlet/storage"
+	"github.com/mosaxiv/clawlet/weekly"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdReport() *cli.Command {
+	return &cli.Command{
+		Name:  "report",
+		Usage: "print the weekly report of what the agent did in the past seven days",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "workspace", Usage: "workspace directory (default: ~/.clawlet/workspace or CLAWLET_WORKSPACE)"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			cfg, _, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := openStorage(cfg)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+			ws, err := resolveWorkspace(cmd.String("workspace"))
+			if err != nil {
+				return err
+			}
+			// Model calls are counted by a running gateway only.
+			rep, err := weekly.Compile(weeklyOptions(st, ws, nil))
+			fmt.Println(rep.Text())
+			return err
+		},
+	}
+}
+
+func weeklyOptions(st storage.Store, workspace string, calls map[string]int) weekly.Options {
+	return weekly.Options{
+		Sessions:  st,
+		Workspace: workspace,
+		Jobs:      cron.NewServiceWithStore(st, nil).List(true),
+		LLMCalls:  calls,
+	}
+}
+
+// runWeeklyReport sends the owner the weekly report until ctx is done.
+func runWeeklyReport(ctx context.Context, c config.WeeklyReportConfig, st storage.Store, workspace string, b *bus.Bus) {
+	day := digestWeekdays[c.DayValue()]
+	for {
+		t := time.NewTimer(time.Until(nextDigest(time.Now(), day, c.HourValue())))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		calls := map[string]int{}
+		for _, p := range metrics.DefaultSLO.Report(weekly.Period, time.Now(), metrics.SLOTargets{}).Providers {
+			calls[p.Provider] = p.Calls
+		}
+		rep, err := weekly.Compile(weeklyOptions(st, workspace, calls))
+		if err != nil {
+			log.Printf("weekly report: %v", err)
+		}
+		_ = b.PublishOutbound(ctx, bus.OutboundMessage{Channel: c.Channel, ChatID: c.ChatID, Content: rep.Text()})
+	}
+}
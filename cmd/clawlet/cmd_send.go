@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/channels/discord"
+	"github.com/mosaxiv/clawlet/channels/instagram"
+	"github.com/mosaxiv/clawlet/channels/mastodon"
+	"github.com/mosaxiv/clawlet/channels/matrix"
+	"github.com/mosaxiv/clawlet/channels/push"
+	"github.com/mosaxiv/clawlet/channels/slack"
+	"github.com/mosaxiv/clawlet/channels/telegram"
+	"github.com/mosaxiv/clawlet/channels/whatsapp"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/urfave/cli/v3"
+)
+
+// sendConnectTimeout bounds the wait for channels that must connect before
+// they can send.
+const sendConnectTimeout = 30 * time.Second
+
+func cmdSend() *cli.Command {
+	return &cli.Command{
+		Name:  "send",
+		Usage: "send a test message to a chat, or preview how a channel formats it",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "channel", Aliases: []string{"c"}, Required: true, Usage: "telegram, slack, discord, whatsapp, matrix, mastodon, instagram or push; telegram.<account> or discord.<account> for an account"},
+			&cli.StringFlag{Name: "chat", Usage: "chat ID as used in session keys (required unless --preview)"},
+			&cli.StringFlag{Name: "file", Aliases: []string{"f"}, Usage: "read the Markdown message from a file (- for stdin)"},
+			&cli.StringFlag{Name: "text", Aliases: []string{"t"}, Usage: "message text"},
+			&cli.BoolFlag{Name: "preview", Usage: "print the text as the channel would send it instead of sending"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			text, err := readSendText(cmd.String("file"), cmd.String("text"))
+			if err != nil {
+				return err
+			}
+			name := strings.ToLower(strings.TrimSpace(cmd.String("channel")))
+			preview := cmd.Bool("preview")
+			var cfg *config.Config
+			if preview {
+				cfg, err = loadConfigOrDefault()
+			} else {
+				cfg, _, err = loadConfig()
+			}
+			if err != nil {
+				return err
+			}
+			ch, err := newSendChannel(cfg, name, bus.New(8))
+			if err != nil {
+				return err
+			}
+			if preview {
+				fmt.Print(previewText(ch, text))
+				return nil
+			}
+			chatID := strings.TrimSpace(cmd.String("chat"))
+			if chatID == "" {
+				return errors.New("--chat is required")
+			}
+			return sendOnce(ctx, ch, bus.OutboundMessage{Channel: name, ChatID: chatID, Content: text})
+		},
+	}
+}
+
+func readSendText(file, text string) (string, error) {
+	switch {
+	case file != "" && text != "":
+		return "", errors.New("use either --file or --text")
+	case file == "-":
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		text = string(b)
+	case file != "":
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		text = string(b)
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return "", errors.New("message is empty (use --file or --text)")
+	}
+	return text, nil
+}
+
+func newSendChannel(cfg *config.Config, name string, b *bus.Bus) (channels.Channel, error) {
+	if account, ok := strings.CutPrefix(name, "telegram."); ok {
+		if a, ok := cfg.Channels.TelegramAccount(name); ok {
+			return telegram.NewAccount(account, a, b), nil
+		}
+		return nil, fmt.Errorf("unknown telegram account %q", account)
+	}
+	if account, ok := strings.CutPrefix(name, "discord."); ok {
+		if a, ok := cfg.Channels.DiscordAccount(name); ok {
+			return discord.NewAccount(account, a, b), nil
+		}
+		return nil, fmt.Errorf("unknown discord account %q", account)
+	}
+	switch name {
+	case "telegram":
+		return telegram.New(cfg.Channels.Telegram, b), nil
+	case "slack":
+		return slack.New(cfg.Channels.Slack, b), nil
+	case "discord":
+		return discord.New(cfg.Channels.Discord, b), nil
+	case "whatsapp":
+		return whatsapp.New(cfg.Channels.WhatsApp, b), nil
+	case "matrix":
+		return matrix.New(cfg.Channels.Matrix, b), nil
+	case "mastodon":
+		return mastodon.New(cfg.Channels.Mastodon, b), nil
+	case "instagram":
+		return instagram.New(cfg.Channels.Instagram, b), nil
+	case "push":
+		return push.New(cfg.Channels.Push), nil
+	default:
+		return nil, fmt.Errorf("unsupported channel for send: %s", name)
+	}
+}
+
+// previewText shows text as ch would send it, under a header naming the
+// format.
+func previewText(ch channels.Channel, text string) string {
+	if p, ok := ch.(channels.Previewer); ok {
+		return fmt.Sprintf("--- %s (formatted) ---\n%s\n", ch.Name(), p.Preview(text))
+	}
+	return fmt.Sprintf("--- %s (sent as-is) ---\n%s\n", ch.Name(), text)
+}
+
+// sendOnce delivers msg through ch. Discord and WhatsApp can only send over
+// their own connection, so they are started first and stopped afterwards.
+func sendOnce(ctx context.Context, ch channels.Channel, msg bus.OutboundMessage) error {
+	switch config.ChannelType(ch.Name()) {
+	case "discord", "whatsapp":
+	default:
+		return ch.Send(ctx, msg)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	started := make(chan error, 1)
+	go func() { started <- ch.Start(runCtx) }()
+	defer func() { _ = ch.Stop() }()
+
+	deadline := time.NewTimer(sendConnectTimeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for !ch.IsRunning() {
+		select {
+		case err := <-started:
+			if err == nil {
+				err = errors.New("channel stopped")
+			}
+			return fmt.Errorf("%s: %w", ch.Name(), err)
+		case <-deadline.C:
+			return fmt.Errorf("%s: not connected after %s", ch.Name(), sendConnectTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+	return ch.Send(ctx, msg)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestSendPreview(t *testing.T) {
+	cfg := config.Default()
+	tg, err := newSendChannel(cfg, "telegram", bus.New(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := previewText(tg, "**hi** <you>"); !strings.Contains(got, "<b>hi</b> &lt;you&gt;") || !strings.Contains(got, "telegram (formatted)") {
+		t.Fatalf("telegram preview: %q", got)
+	}
+	sl, err := newSendChannel(cfg, "slack", bus.New(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := previewText(sl, "**hi**"); got != "--- slack (sent as-is) ---\n**hi**\n" {
+		t.Fatalf("slack preview: %q", got)
+	}
+	if _, err := newSendChannel(cfg, "voice", bus.New(1)); err == nil {
+		t.Fatal("expected unsupported channel error")
+	}
+}
+
+func TestReadSendText(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "reply.md")
+	if err := os.WriteFile(p, []byte("\n# Title\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readSendText(p, ""); err != nil || got != "# Title" {
+		t.Fatalf("file: %q %v", got, err)
+	}
+	if _, err := readSendText(p, "x"); err == nil {
+		t.Fatal("expected error for --file with --text")
+	}
+	if _, err := readSendText("", "  "); err == nil {
+		t.Fatal("expected empty message error")
+	}
+}
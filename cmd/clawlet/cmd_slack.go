@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/mosaxiv/clawlet/channels/slack"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/paths"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdSlack() *cli.Command {
+	return &cli.Command{
+		Name:  "slack",
+		Usage: "slack utilities",
+		Commands: []*cli.Command{
+			{
+				Name:  "manifest",
+				Usage: "print a Slack app manifest (JSON) matching channels.slack config",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "name", Value: "clawlet", Usage: "app and bot display name"},
+					&cli.StringFlag{Name: "out", Aliases: []string{"o"}, Usage: "write to file instead of stdout"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					cfg, err := loadConfigOrDefault()
+					if err != nil {
+						return err
+					}
+					b, err := json.MarshalIndent(slack.BuildManifest(cfg.Channels.Slack, cmd.String("name")), "", "  ")
+					if err != nil {
+						return err
+					}
+					b = append(b, '\n')
+					if out := cmd.String("out"); out != "" {
+						if err := os.WriteFile(out, b, 0o644); err != nil {
+							return err
+						}
+						fmt.Printf("wrote %s\nimport it at https://api.slack.com/apps -> Create New App -> From an app manifest\n", out)
+						return nil
+					}
+					_, err = os.Stdout.Write(b)
+					return err
+				},
+			},
+		},
+	}
+}
+
+// loadConfigOrDefault is for setup helpers that should work before onboarding.
+func loadConfigOrDefault() (*config.Config, error) {
+	cfgPath, err := paths.ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load(cfgPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config.Default(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %s\n%w", cfgPath, err)
+	}
+	return cfg, nil
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"strings"
 
+	"github.com/mosaxiv/clawlet/config"
 	"github.com/mosaxiv/clawlet/paths"
 	"github.com/urfave/cli/v3"
 )
@@ -23,6 +25,14 @@ func cmdStatus() *cli.Command {
 			fmt.Printf("llm.provider: %s\n", cfg.LLM.Provider)
 			fmt.Printf("llm.baseURL: %s\n", cfg.LLM.BaseURL)
 			fmt.Printf("llm.model: %s\n", cfg.LLM.Model)
+			if cfg.LLM.LlamaServer.Enabled {
+				fmt.Printf("llm.llamaServer.listen: %s\n", cfg.LLM.LlamaServer.ListenValue())
+				fmt.Printf("llm.llamaServer.model: %s\n", cfg.LLM.LlamaServer.Model)
+			}
+			if cfg.LLM.Provider == "ollama" {
+				fmt.Printf("llm.ollama.keepAlive: %s\n", cfg.LLM.Ollama.KeepAlive)
+				fmt.Printf("llm.ollama.autoPull: %v\n", cfg.LLM.Ollama.AutoPull)
+			}
 			if strings.TrimSpace(cfg.Agents.Defaults.Model) != "" {
 				fmt.Printf("agents.defaults.model: %s\n", cfg.Agents.Defaults.Model)
 			}
@@ -30,6 +40,7 @@ func cmdStatus() *cli.Command {
 			fmt.Printf("agents.defaults.temperature: %.2f\n", cfg.Agents.Defaults.TemperatureValue())
 			fmt.Printf("tools.restrictToWorkspace: %v\n", cfg.Tools.RestrictToWorkspaceValue())
 			fmt.Printf("tools.exec.timeoutSec: %d\n", cfg.Tools.Exec.TimeoutSec)
+			fmt.Printf("tools.web.search: %s\n", cmp.Or(cfg.Tools.Web.Search, config.WebSearchBrave))
 			fmt.Printf("tools.web.braveApiKey: %v\n", cfg.Tools.Web.BraveAPIKey != "")
 			fmt.Printf("tools.web.allowedDomains: %v\n", cfg.Tools.Web.AllowedDomains)
 			fmt.Printf("tools.web.blockedDomains: %v\n", cfg.Tools.Web.BlockedDomains)
@@ -47,6 +58,13 @@ func cmdStatus() *cli.Command {
 			fmt.Printf("channels.slack.enabled: %v\n", cfg.Channels.Slack.Enabled)
 			fmt.Printf("channels.telegram.enabled: %v\n", cfg.Channels.Telegram.Enabled)
 			fmt.Printf("channels.whatsapp.enabled: %v\n", cfg.Channels.WhatsApp.Enabled)
+			fmt.Printf("channels.matrix.enabled: %v\n", cfg.Channels.Matrix.Enabled)
+			fmt.Printf("channels.mastodon.enabled: %v\n", cfg.Channels.Mastodon.Enabled)
+			fmt.Printf("channels.voice.enabled: %v\n", cfg.Channels.Voice.Enabled)
+			fmt.Printf("channels.grpc.enabled: %v\n", cfg.Channels.GRPC.Enabled)
+			fmt.Printf("channels.instagram.enabled: %v\n", cfg.Channels.Instagram.Enabled)
+			fmt.Printf("channels.push.enabled: %v\n", cfg.Channels.Push.Enabled)
+			fmt.Printf("channels.mqtt.enabled: %v\n", cfg.Channels.MQTT.Enabled)
 			return nil
 		},
 	}
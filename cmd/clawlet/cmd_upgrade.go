@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/handover"
+	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/upgrade"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdUpgrade() *cli.Command {
+	return &cli.Command{
+		Name:  "upgrade",
+		Usage: "download a signed release and replace this binary",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "tag", Usage: "release tag to install (default: latest)"},
+			&cli.BoolFlag{Name: "check", Usage: "only report whether an upgrade is available"},
+			&cli.BoolFlag{Name: "force", Usage: "reinstall even if already on that version"},
+			&cli.BoolFlag{Name: "restart", Usage: "ask the running gateway to hand over to the new binary"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			client := upgrade.NewClient()
+			rel, err := client.Release(ctx, cmd.String("tag"))
+			if err != nil {
+				return fmt.Errorf("fetch release: %w", err)
+			}
+			current := resolveVersion()
+			fmt.Printf("current: %s\nrelease: %s\n", current, rel.Tag)
+			if sameVersion(current, rel.Tag) && !cmd.Bool("force") {
+				fmt.Println("already up to date")
+				return nil
+			}
+			if cmd.Bool("check") {
+				return nil
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+				exe = resolved
+			}
+			bin, err := client.Download(ctx, rel)
+			if err != nil {
+				return err
+			}
+			if err := upgrade.Install(exe, bin); err != nil {
+				return fmt.Errorf("install %s: %w", exe, err)
+			}
+			fmt.Printf("installed %s to %s (signature verified)\n", rel.Tag, exe)
+
+			if !cmd.Bool("restart") {
+				fmt.Println("restart the gateway, or run `clawlet upgrade --restart` to hand over without downtime")
+				return nil
+			}
+			pid, err := readGatewayPID()
+			if err != nil {
+				return fmt.Errorf("no running gateway found: %w", err)
+			}
+			if err := handover.Notify(pid); err != nil {
+				return fmt.Errorf("signal gateway (pid %d): %w", pid, err)
+			}
+			fmt.Printf("asked gateway (pid %d) to hand over\n", pid)
+			return nil
+		},
+	}
+}
+
+func sameVersion(a, b string) bool {
+	a = strings.TrimPrefix(strings.TrimSpace(a), "v")
+	b = strings.TrimPrefix(strings.TrimSpace(b), "v")
+	return a != "" && a == b
+}
+
+func writeGatewayPID() {
+	p := paths.GatewayPIDPath()
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return
+	}
+	_ = os.WriteFile(p, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o600)
+}
+
+// removeGatewayPID removes the PID file unless a newer gateway has already
+// replaced it during a handover.
+func removeGatewayPID() {
+	if pid, err := readGatewayPID(); err == nil && pid == os.Getpid() {
+		_ = os.Remove(paths.GatewayPIDPath())
+	}
+}
+
+func readGatewayPID() (int, error) {
+	b, err := os.ReadFile(paths.GatewayPIDPath())
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pid file %s", paths.GatewayPIDPath())
+	}
+	return pid, nil
+}
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/mosaxiv/clawlet/atrest"
+	"github.com/mosaxiv/clawlet/chaos"
 	"github.com/mosaxiv/clawlet/config"
 	"github.com/mosaxiv/clawlet/paths"
 )
@@ -23,6 +25,9 @@ func loadConfig() (*config.Config, string, error) {
 
 	applyEnvOverrides(cfg)
 	cfg.ApplyLLMRouting()
+	if err := setupEncryption(cfg); err != nil {
+		return nil, cfgPath, err
+	}
 
 	if strings.TrimSpace(cfg.LLM.APIKey) == "" && providerNeedsAPIKey(cfg.LLM.Provider) {
 		fmt.Fprintln(os.Stderr, "warning: llm.apiKey is empty (set in config.env or env vars)")
@@ -31,6 +36,52 @@ func loadConfig() (*config.Config, string, error) {
 	return cfg, cfgPath, nil
 }
 
+// setupEncryption installs the at-rest cipher used for session files.
+func setupEncryption(cfg *config.Config) error {
+	if !cfg.Encryption.Enabled {
+		atrest.SetDefault(nil)
+		return nil
+	}
+	var (
+		c   *atrest.Cipher
+		err error
+	)
+	if cfg.Encryption.KeyFile != "" {
+		c, err = atrest.NewKeyFile(resolveStatePath(cfg.Encryption.KeyFile))
+	} else {
+		env := cfg.Encryption.PassphraseEnv
+		pass := os.Getenv(env)
+		if pass == "" {
+			pass = cfg.Env[env]
+		}
+		if pass == "" {
+			return fmt.Errorf("encryption is enabled but neither encryption.keyFile nor $%s is set", env)
+		}
+		c, err = atrest.NewPassphrase(pass)
+	}
+	if err != nil {
+		return fmt.Errorf("encryption: %w", err)
+	}
+	atrest.SetDefault(c)
+	return nil
+}
+
+// resolveStatePath expands "~/" and resolves relative paths against the
+// config directory.
+func resolveStatePath(p string) string {
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(p) {
+		if dir, err := paths.ConfigDir(); err == nil {
+			return filepath.Join(dir, p)
+		}
+	}
+	return p
+}
+
 func applyEnvOverrides(cfg *config.Config) {
 	if v := os.Getenv("CLAWLET_API_KEY"); v != "" {
 		cfg.LLM.APIKey = v
@@ -162,3 +213,14 @@ func providerNeedsAPIKey(provider string) bool {
 		return true
 	}
 }
+
+// loadChaos reads CLAWLET_CHAOS and warns when chaos mode is on; nil when
+// it is off.
+func loadChaos() (*chaos.Injector, error) {
+	inj, err := chaos.FromEnv()
+	if err != nil || inj == nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "chaos mode: injecting failures (%s)\n", inj)
+	return inj, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/leader"
+	"github.com/mosaxiv/clawlet/storage"
+)
+
+// cronRefreshInterval is how often the elected cron instance re-reads jobs
+// that other instances may have added.
+const cronRefreshInterval = 30 * time.Second
+
+// cronBatchPollInterval is how often the cron instance checks on the batches
+// of batch jobs; they take minutes to hours.
+const cronBatchPollInterval = 5 * time.Minute
+
+// singletons runs duties that must not fire on more than one gateway. With
+// the in-process bus there is only one instance and duties simply run; with a
+// shared bus each duty is guarded by a lease in the storage backend.
+type singletons struct {
+	leaser storage.Leaser
+	owner  string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newSingletons(ctx context.Context, cfg *config.Config, st storage.Store) (*singletons, error) {
+	s := &singletons{}
+	if cfg.Bus.Shared() {
+		l, ok := st.(storage.Leaser)
+		if !ok {
+			return nil, fmt.Errorf("storage backend %q does not support leader election", cfg.Storage.Backend)
+		}
+		id, err := gatewayInstanceID(cfg)
+		if err != nil {
+			return nil, err
+		}
+		s.leaser, s.owner = l, id
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	return s, nil
+}
+
+func (s *singletons) elected() bool { return s.leaser != nil }
+
+// campaign runs duty, or only while this instance holds the named lease, and
+// blocks until ctx is done.
+func (s *singletons) campaign(ctx context.Context, name string, duty func(ctx context.Context)) {
+	if !s.elected() {
+		duty(ctx)
+		return
+	}
+	leader.New(s.leaser, name, s.owner, leader.DefaultTTL).Run(ctx, duty)
+}
+
+// run is campaign in the background until stop.
+func (s *singletons) run(name string, duty func(ctx context.Context)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.campaign(s.ctx, name, duty)
+	}()
+}
+
+// stop ends all duties and releases their leases.
+func (s *singletons) stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func gatewayInstanceID(cfg *config.Config) (string, error) {
+	if cfg.Bus.InstanceID != "" {
+		return cfg.Bus.InstanceID, nil
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "", fmt.Errorf("bus.instanceID is empty and hostname is unavailable: %v", err)
+	}
+	return host, nil
+}
+
+// electedChannel receives (polls) only while this instance holds the
+// channel's lease. Send is not gated, so replies go out from any instance.
+type electedChannel struct {
+	channels.Channel
+	duties *singletons
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func (c *electedChannel) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+	defer cancel()
+	c.duties.campaign(ctx, c.Name(), func(ctx context.Context) {
+		if err := c.Channel.Start(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("channels: %s stopped with error: %v", c.Name(), err)
+		}
+	})
+	return ctx.Err()
+}
+
+func (c *electedChannel) Stop() error {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return c.Channel.Stop()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/storage"
+)
+
+func TestNewSingletons_MemoryBusRunsDutiesDirectly(t *testing.T) {
+	cfg := &config.Config{Bus: config.BusConfig{Backend: config.BusBackendMemory}}
+	s, err := newSingletons(context.Background(), cfg, storage.NewFiles(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.elected() {
+		t.Fatal("single instance should not elect")
+	}
+	ran := make(chan struct{})
+	s.run("cron", func(ctx context.Context) { close(ran); <-ctx.Done() })
+	<-ran
+	s.stop()
+}
+
+func TestNewSingletons_RedisBusElects(t *testing.T) {
+	st, err := storage.OpenSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	cfg := &config.Config{Bus: config.BusConfig{Backend: config.BusBackendRedis, InstanceID: "gw-1"}}
+	s, err := newSingletons(context.Background(), cfg, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.stop()
+	if !s.elected() || s.owner != "gw-1" {
+		t.Fatalf("elected=%v owner=%q", s.elected(), s.owner)
+	}
+	ran := make(chan struct{})
+	s.run("cron", func(ctx context.Context) { close(ran); <-ctx.Done() })
+	<-ran
+	if ok, _ := st.AcquireLease("cron", "gw-2", time.Minute); ok {
+		t.Fatal("second instance took the cron lease")
+	}
+}
@@ -18,10 +18,23 @@ func main() {
 			cmdOnboard(),
 			cmdStatus(),
 			cmdAgent(),
+			cmdChat(),
+			cmdSend(),
 			cmdGateway(),
 			cmdProvider(),
 			cmdChannels(),
+			cmdAllowlist(),
+			cmdSlack(),
+			cmdUpgrade(),
+			cmdMigrate(),
+			cmdForget(),
+			cmdStorage(),
+			cmdMaintenance(),
+			cmdReport(),
+			cmdPresence(),
 			cmdCron(),
+			cmdReplay(),
+			cmdModels(),
 		},
 	}
 
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/storage"
+	"github.com/mosaxiv/clawlet/turns"
+	"github.com/urfave/cli/v3"
+)
+
+// openStorage opens the configured backend for sessions and cron jobs.
+func openStorage(cfg *config.Config) (storage.Store, error) {
+	switch cfg.Storage.Backend {
+	case "", config.StorageBackendFiles:
+		return fileStorage(), nil
+	case config.StorageBackendSQLite:
+		st, err := storage.OpenSQLite(resolveStatePath(cfg.Storage.SQLitePath))
+		if err != nil {
+			return nil, fmt.Errorf("storage: open sqlite: %w", err)
+		}
+		return st, nil
+	default:
+		return nil, fmt.Errorf("storage.backend %q is not supported (use %q or %q)", cfg.Storage.Backend, config.StorageBackendFiles, config.StorageBackendSQLite)
+	}
+}
+
+// fileStorage is the historical layout under ~/.clawlet.
+func fileStorage() storage.Store {
+	return storage.NewFiles(map[string]string{
+		storage.NamespaceSessions: paths.SessionsDir(),
+		storage.NamespaceCron:     filepath.Dir(paths.CronStorePath()),
+		storage.NamespaceLeases:   paths.LeasesDir(),
+		turns.Namespace:           paths.TurnsDir(),
+	})
+}
+
+// openCronService returns a cron service on the configured storage, for
+// one-shot CLI commands.
+func openCronService(cfg *config.Config) (*cron.Service, func(), error) {
+	st, err := openStorage(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cron.NewServiceWithStore(st, nil), func() { _ = st.Close() }, nil
+}
+
+func cmdStorage() *cli.Command {
+	return &cli.Command{
+		Name:  "storage",
+		Usage: "manage the state storage backend",
+		Commands: []*cli.Command{
+			{
+				Name:  "import",
+				Usage: "copy sessions and cron jobs from ~/.clawlet files into the configured backend",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "force", Usage: "overwrite records that already exist in the backend"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					cfg, _, err := loadConfig()
+					if err != nil {
+						return err
+					}
+					if cfg.Storage.Backend == config.StorageBackendFiles {
+						return cli.Exit("storage.backend is files; nothing to import", 2)
+					}
+					if err := runStartupMigrations(); err != nil {
+						return err
+					}
+					dst, err := openStorage(cfg)
+					if err != nil {
+						return err
+					}
+					defer dst.Close()
+					src := fileStorage()
+					imports := []struct {
+						ns   string
+						keys []string
+					}{
+						{ns: storage.NamespaceSessions},
+						{ns: storage.NamespaceCron, keys: []string{cron.StoreKey}},
+					}
+					if !cmd.Bool("force") {
+						for _, im := range imports {
+							existing, err := dst.List(im.ns)
+							if err != nil {
+								return err
+							}
+							if len(existing) > 0 {
+								return cli.Exit(fmt.Sprintf("backend already has %s records; use --force to overwrite", im.ns), 2)
+							}
+						}
+					}
+					for _, im := range imports {
+						n, err := storage.Copy(dst, src, im.ns, im.keys...)
+						if err != nil {
+							return err
+						}
+						fmt.Printf("%s: imported %d records\n", im.ns, n)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// allowFromChannels are the channels with an allowFrom list.
+var allowFromChannels = []string{"discord", "slack", "telegram", "whatsapp", "matrix", "mastodon", "voice", "grpc", "instagram", "mqtt"}
+
+// groupAllowFromChannels also have a groupAllowFrom list of rooms.
+var groupAllowFromChannels = []string{"discord", "slack", "telegram", "matrix"}
+
+// AllowlistEntry is one identifier to allow on a channel. Group entries go
+// to groupAllowFrom (Discord channel or server, Slack channel, Telegram chat
+// or Matrix room IDs).
+type AllowlistEntry struct {
+	Channel string
+	ID      string
+	Group   bool
+}
+
+// Validate reports an unknown channel or a group entry for a channel
+// without groupAllowFrom.
+func (e AllowlistEntry) Validate() error {
+	if strings.TrimSpace(e.ID) == "" {
+		return fmt.Errorf("identifier is empty")
+	}
+	if !slices.Contains(allowFromChannels, e.Channel) {
+		return fmt.Errorf("channel %q has no allowFrom (use one of %s)", e.Channel, strings.Join(allowFromChannels, ", "))
+	}
+	if e.Group && !slices.Contains(groupAllowFromChannels, e.Channel) {
+		return fmt.Errorf("channel %q has no groupAllowFrom (use one of %s)", e.Channel, strings.Join(groupAllowFromChannels, ", "))
+	}
+	return nil
+}
+
+// MergeAllowlists adds entries to the channel lists of the config file at
+// path and returns how many were new. The file is edited as plain JSON, so
+// settings that Load fills in are not written back. With dryRun nothing is
+// written.
+func MergeAllowlists(path string, entries []AllowlistEntry, dryRun bool) (int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	root := map[string]any{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&root); err != nil {
+		return 0, fmt.Errorf("parse %s: %w", path, err)
+	}
+	channels, err := objectField(root, "channels")
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, e := range entries {
+		if err := e.Validate(); err != nil {
+			return 0, err
+		}
+		ch, err := objectField(channels, e.Channel)
+		if err != nil {
+			return 0, err
+		}
+		key := "allowFrom"
+		if e.Group {
+			key = "groupAllowFrom"
+		}
+		list, _ := ch[key].([]any)
+		id := strings.TrimSpace(e.ID)
+		if slices.Contains(list, any(id)) {
+			continue
+		}
+		ch[key] = append(list, id)
+		added++
+	}
+	if dryRun || added == 0 {
+		return added, nil
+	}
+	out, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	return added, os.WriteFile(path, append(out, '\n'), 0o600)
+}
+
+// objectField returns m[key] as an object, creating it when missing.
+func objectField(m map[string]any, key string) (map[string]any, error) {
+	switch v := m[key].(type) {
+	case nil:
+		obj := map[string]any{}
+		m[key] = obj
+		return obj, nil
+	case map[string]any:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s is not an object", key)
+	}
+}
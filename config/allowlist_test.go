@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMergeAllowlists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	orig := `{"env":{"OPENAI_API_KEY":"k"},"tools":{"exec":{"timeoutSec":90}},"channels":{"telegram":{"enabled":true,"allowFrom":["1"]}}}`
+	if err := os.WriteFile(path, []byte(orig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entries := []AllowlistEntry{
+		{Channel: "telegram", ID: "1"},
+		{Channel: "telegram", ID: "2"},
+		{Channel: "slack", ID: "U1"},
+		{Channel: "slack", ID: "C1", Group: true},
+	}
+	if n, err := MergeAllowlists(path, entries, true); err != nil || n != 3 {
+		t.Fatalf("dry run: n=%d err=%v", n, err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != orig {
+		t.Fatalf("dry run wrote the file: %s", b)
+	}
+	if n, err := MergeAllowlists(path, entries, false); err != nil || n != 3 {
+		t.Fatalf("merge: n=%d err=%v", n, err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.Channels.Telegram.AllowFrom, []string{"1", "2"}) || !cfg.Channels.Telegram.Enabled {
+		t.Fatalf("telegram: %+v", cfg.Channels.Telegram)
+	}
+	if !slices.Equal(cfg.Channels.Slack.AllowFrom, []string{"U1"}) || !slices.Equal(cfg.Channels.Slack.GroupAllowFrom, []string{"C1"}) {
+		t.Fatalf("slack: %+v", cfg.Channels.Slack)
+	}
+	if cfg.Tools.Exec.TimeoutSec != 90 || cfg.Env["OPENAI_API_KEY"] != "k" {
+		t.Fatalf("other settings lost: %+v %+v", cfg.Tools.Exec, cfg.Env)
+	}
+	if b, _ := os.ReadFile(path); strings.Contains(string(b), "allowedDomains") {
+		t.Fatalf("defaults written back: %s", b)
+	}
+
+	for _, e := range []AllowlistEntry{{Channel: "push", ID: "x"}, {Channel: "whatsapp", ID: "x", Group: true}, {Channel: "telegram"}} {
+		if _, err := MergeAllowlists(path, []AllowlistEntry{e}, true); err == nil {
+			t.Fatalf("expected error for %+v", e)
+		}
+	}
+}
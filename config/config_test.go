@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"slices"
+	"testing"
+)
 
 func TestAgentDefaults_MaxTokensTemperature(t *testing.T) {
 	cfg := Default()
@@ -360,3 +363,80 @@ func TestSkillsRegistryDefaults(t *testing.T) {
 		t.Fatalf("loaded skills.registry.timeoutSec=%d", loaded.Tools.Skills.Registry.TimeoutSec)
 	}
 }
+
+func TestChannelsConfig_EditsFor(t *testing.T) {
+	var c ChannelsConfig
+	c.Telegram.Edits = " Correct "
+	if got := c.EditsFor("telegram"); got != EditsCorrect {
+		t.Fatalf("telegram=%q", got)
+	}
+	if got := c.EditsFor("discord"); got != EditsAnnotate {
+		t.Fatalf("discord default=%q", got)
+	}
+	if ValidateEdits("rewrite") == nil || ValidateEdits("") != nil {
+		t.Fatal("unexpected validation result")
+	}
+	c.Telegram.Accounts = map[string]TelegramConfig{"work": {Edits: "ignore"}}
+	if got := c.EditsFor("telegram.work"); got != EditsIgnore {
+		t.Fatalf("telegram.work=%q", got)
+	}
+}
+
+func TestChannelsConfig_Accounts(t *testing.T) {
+	var c ChannelsConfig
+	c.Telegram.Token = "main"
+	c.Telegram.Accounts = map[string]TelegramConfig{"work": {Token: "work"}}
+	c.Discord.Accounts = map[string]DiscordConfig{"home": {Token: "home"}}
+	if a, ok := c.TelegramAccount("telegram"); !ok || a.Token != "main" {
+		t.Fatalf("telegram: %+v %v", a, ok)
+	}
+	if a, ok := c.TelegramAccount("telegram.work"); !ok || a.Token != "work" {
+		t.Fatalf("telegram.work: %+v %v", a, ok)
+	}
+	if _, ok := c.TelegramAccount("telegram.home"); ok {
+		t.Fatal("unknown account found")
+	}
+	if a, ok := c.DiscordAccount("discord.home"); !ok || a.Token != "home" {
+		t.Fatalf("discord.home: %+v %v", a, ok)
+	}
+	if ChannelType("telegram.work") != "telegram" || ChannelType("slack") != "slack" {
+		t.Fatal("ChannelType")
+	}
+	if ChannelName("telegram", "") != "telegram" || ChannelName("telegram", "work") != "telegram.work" {
+		t.Fatal("ChannelName")
+	}
+	for _, name := range []string{"", "Work", "a.b", "a:b", "a b", "my_bot"} {
+		if ValidateAccountName(name) == nil {
+			t.Errorf("account name %q accepted", name)
+		}
+	}
+	if err := ValidateAccountName("work-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Accounts share their type's rate limits unless given their own.
+	if rl, ok := c.RateLimitFor("telegram.work"); !ok || rl.PerSecond != 30 {
+		t.Fatalf("default limit: %+v %v", rl, ok)
+	}
+	c.RateLimits = map[string]RateLimitConfig{"telegram": {PerSecond: 5}, "telegram.work": {PerSecond: 1}}
+	if rl, _ := c.RateLimitFor("telegram.work"); rl.PerSecond != 1 {
+		t.Fatalf("account limit: %+v", rl)
+	}
+	if rl, _ := c.RateLimitFor("telegram.home"); rl.PerSecond != 5 {
+		t.Fatalf("type limit: %+v", rl)
+	}
+}
+
+func TestConfig_AdminSendersIncludesDeprecatedOwners(t *testing.T) {
+	c := &Config{}
+	c.Channels.Access.Admins = []string{"telegram:1"}
+	c.Agents.Defaults.Trace.Owners = []string{"telegram:1", "slack:U2"}
+	c.Agents.Defaults.Takeover.Owners = []string{"discord:3"}
+	got := c.AdminSenders()
+	if want := []string{"telegram:1", "slack:U2", "discord:3"}; !slices.Equal(got, want) {
+		t.Fatalf("AdminSenders() = %v, want %v", got, want)
+	}
+	if len(c.Channels.Access.Admins) != 1 {
+		t.Fatalf("admins modified: %v", c.Channels.Access.Admins)
+	}
+}
@@ -0,0 +1,326 @@
+package contacts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the contacts store file, relative to the workspace.
+const FileName = "contacts.json"
+
+// Address kinds that are not chat channels. They are stored for reference and
+// used as fallbacks (a phone number doubles as a WhatsApp chat_id).
+const (
+	KindPhone = "phone"
+	KindEmail = "email"
+)
+
+type Contact struct {
+	Name        string            `json:"name"`
+	Aliases     []string          `json:"aliases,omitempty"`
+	Addresses   map[string]string `json:"addresses"` // channel|phone|email -> address
+	Notes       string            `json:"notes,omitempty"`
+	CreatedAtMS int64             `json:"createdAtMs"`
+	UpdatedAtMS int64             `json:"updatedAtMs"`
+}
+
+type Book struct {
+	Version  int       `json:"version"`
+	Contacts []Contact `json:"contacts"`
+}
+
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func Path(workspace string) string {
+	return filepath.Join(workspace, FileName)
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Upsert adds a contact or merges aliases/addresses into an existing contact
+// with the same name (case-insensitive).
+func (s *Store) Upsert(c Contact) (Contact, error) {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return Contact{}, errors.New("name is required")
+	}
+	addrs, err := normalizeAddresses(c.Addresses)
+	if err != nil {
+		return Contact{}, err
+	}
+	if len(addrs) == 0 {
+		return Contact{}, errors.New("at least one address is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	book, err := s.loadLocked()
+	if err != nil {
+		return Contact{}, err
+	}
+	now := time.Now().UnixMilli()
+	for i := range book.Contacts {
+		existing := &book.Contacts[i]
+		if !strings.EqualFold(existing.Name, c.Name) {
+			continue
+		}
+		if existing.Addresses == nil {
+			existing.Addresses = map[string]string{}
+		}
+		for k, v := range addrs {
+			existing.Addresses[k] = v
+		}
+		existing.Aliases = mergeAliases(existing.Aliases, c.Aliases)
+		if notes := strings.TrimSpace(c.Notes); notes != "" {
+			existing.Notes = notes
+		}
+		existing.UpdatedAtMS = now
+		out := *existing
+		return out, s.saveLocked(book)
+	}
+	nc := Contact{
+		Name:        c.Name,
+		Aliases:     mergeAliases(nil, c.Aliases),
+		Addresses:   addrs,
+		Notes:       strings.TrimSpace(c.Notes),
+		CreatedAtMS: now,
+		UpdatedAtMS: now,
+	}
+	book.Contacts = append(book.Contacts, nc)
+	return nc, s.saveLocked(book)
+}
+
+// Search returns contacts whose name, aliases, notes or addresses contain the
+// query (case-insensitive). Exact name/alias matches are listed first.
+func (s *Store) Search(query string, limit int) ([]Contact, error) {
+	s.mu.Lock()
+	book, err := s.loadLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	q := strings.ToLower(strings.TrimSpace(query))
+	type scored struct {
+		c     Contact
+		exact bool
+	}
+	var hits []scored
+	for _, c := range book.Contacts {
+		if q == "" {
+			hits = append(hits, scored{c: c})
+			continue
+		}
+		if matchesName(c, q) {
+			hits = append(hits, scored{c: c, exact: true})
+			continue
+		}
+		if strings.Contains(searchText(c), q) {
+			hits = append(hits, scored{c: c})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].exact != hits[j].exact {
+			return hits[i].exact
+		}
+		return strings.ToLower(hits[i].c.Name) < strings.ToLower(hits[j].c.Name)
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	out := make([]Contact, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, h.c)
+	}
+	return out, nil
+}
+
+// Resolve finds the single contact whose name or alias equals name
+// (case-insensitive). Partial matches are never used, so a message cannot be
+// routed to someone the user did not name.
+func (s *Store) Resolve(name string) (Contact, error) {
+	q := strings.ToLower(strings.TrimSpace(name))
+	if q == "" {
+		return Contact{}, errors.New("contact name is empty")
+	}
+	s.mu.Lock()
+	book, err := s.loadLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return Contact{}, err
+	}
+	var found []Contact
+	for _, c := range book.Contacts {
+		if matchesName(c, q) {
+			found = append(found, c)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Contact{}, fmt.Errorf("contact not found: %s", name)
+	case 1:
+		return found[0], nil
+	default:
+		names := make([]string, 0, len(found))
+		for _, c := range found {
+			names = append(names, c.Name)
+		}
+		return Contact{}, fmt.Errorf("contact %q is ambiguous: %s", name, strings.Join(names, ", "))
+	}
+}
+
+// Target picks the channel/chat_id to message a contact on. When channel is
+// empty the contact must have exactly one chat channel address.
+func (c Contact) Target(channel string) (string, string, error) {
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	if channel != "" {
+		if v := strings.TrimSpace(c.Addresses[channel]); v != "" && channel != KindEmail && channel != KindPhone {
+			return channel, v, nil
+		}
+		if channel == "whatsapp" {
+			if v := strings.TrimSpace(c.Addresses[KindPhone]); v != "" {
+				return channel, v, nil
+			}
+		}
+		return "", "", fmt.Errorf("contact %s has no %s address", c.Name, channel)
+	}
+	var chans []string
+	for k, v := range c.Addresses {
+		if k == KindEmail || k == KindPhone || strings.TrimSpace(v) == "" {
+			continue
+		}
+		chans = append(chans, k)
+	}
+	slices.Sort(chans)
+	switch len(chans) {
+	case 1:
+		return chans[0], strings.TrimSpace(c.Addresses[chans[0]]), nil
+	case 0:
+		return "", "", fmt.Errorf("contact %s has no chat channel address; specify channel", c.Name)
+	default:
+		return "", "", fmt.Errorf("contact %s has multiple channels (%s); specify channel", c.Name, strings.Join(chans, ", "))
+	}
+}
+
+// RemoveWhere deletes every contact for which match returns true and returns
+// the removed contacts. With dryRun the book is left unchanged.
+func (s *Store) RemoveWhere(match func(Contact) bool, dryRun bool) ([]Contact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	book, err := s.loadLocked()
+	if err != nil {
+		return nil, err
+	}
+	var removed []Contact
+	kept := book.Contacts[:0:0]
+	for _, c := range book.Contacts {
+		if match(c) {
+			removed = append(removed, c)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	if len(removed) == 0 || dryRun {
+		return removed, nil
+	}
+	book.Contacts = kept
+	return removed, s.saveLocked(book)
+}
+
+func (s *Store) loadLocked() (Book, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Book{Version: 1}, nil
+		}
+		return Book{}, err
+	}
+	var book Book
+	if err := json.Unmarshal(b, &book); err != nil {
+		return Book{}, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	if book.Version == 0 {
+		book.Version = 1
+	}
+	return book, nil
+}
+
+func (s *Store) saveLocked(book Book) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(book, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func normalizeAddresses(in map[string]string) (map[string]string, error) {
+	out := map[string]string{}
+	for k, v := range in {
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimSpace(v)
+		if k == "" || v == "" {
+			continue
+		}
+		if k == KindEmail && !strings.Contains(v, "@") {
+			return nil, fmt.Errorf("invalid email address: %q", v)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+func mergeAliases(existing, add []string) []string {
+	out := append([]string(nil), existing...)
+	for _, a := range add {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		if slices.ContainsFunc(out, func(e string) bool { return strings.EqualFold(e, a) }) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func matchesName(c Contact, lowerQuery string) bool {
+	if strings.ToLower(c.Name) == lowerQuery {
+		return true
+	}
+	for _, a := range c.Aliases {
+		if strings.ToLower(strings.TrimSpace(a)) == lowerQuery {
+			return true
+		}
+	}
+	return false
+}
+
+func searchText(c Contact) string {
+	parts := []string{c.Name, c.Notes}
+	parts = append(parts, c.Aliases...)
+	for _, v := range c.Addresses {
+		parts = append(parts, v)
+	}
+	return strings.ToLower(strings.Join(parts, "\n"))
+}
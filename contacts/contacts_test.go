@@ -0,0 +1,73 @@
+package contacts
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_UpsertMergesByName(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), FileName))
+	if _, err := s.Upsert(Contact{Name: "Bob", Addresses: map[string]string{"telegram": "111"}}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	c, err := s.Upsert(Contact{Name: "bob", Aliases: []string{"Bobby"}, Addresses: map[string]string{"Email": "bob@example.com"}})
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if c.Name != "Bob" || c.Addresses["telegram"] != "111" || c.Addresses["email"] != "bob@example.com" {
+		t.Fatalf("unexpected merge result: %+v", c)
+	}
+	all, err := s.Search("", 0)
+	if err != nil || len(all) != 1 {
+		t.Fatalf("expected 1 contact, got %d (%v)", len(all), err)
+	}
+}
+
+func TestStore_UpsertValidates(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), FileName))
+	if _, err := s.Upsert(Contact{Name: "Bob"}); err == nil {
+		t.Fatalf("expected error without addresses")
+	}
+	if _, err := s.Upsert(Contact{Name: "Bob", Addresses: map[string]string{"email": "nope"}}); err == nil {
+		t.Fatalf("expected invalid email error")
+	}
+}
+
+func TestStore_ResolveExactOnly(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), FileName))
+	_, _ = s.Upsert(Contact{Name: "Bob Smith", Aliases: []string{"Bob"}, Addresses: map[string]string{"telegram": "111"}})
+	_, _ = s.Upsert(Contact{Name: "Bobby Tables", Addresses: map[string]string{"discord": "222"}})
+
+	c, err := s.Resolve("bob")
+	if err != nil || c.Name != "Bob Smith" {
+		t.Fatalf("resolve alias: %+v %v", c, err)
+	}
+	if _, err := s.Resolve("Bo"); err == nil {
+		t.Fatalf("expected partial match to be rejected")
+	}
+
+	_, _ = s.Upsert(Contact{Name: "Robert", Aliases: []string{"bob"}, Addresses: map[string]string{"slack": "U1"}})
+	if _, err := s.Resolve("Bob"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous error, got %v", err)
+	}
+}
+
+func TestContact_Target(t *testing.T) {
+	c := Contact{Name: "Bob", Addresses: map[string]string{"telegram": "111", "phone": "+15550001", "email": "b@example.com"}}
+	ch, id, err := c.Target("")
+	if err != nil || ch != "telegram" || id != "111" {
+		t.Fatalf("default target: %s %s %v", ch, id, err)
+	}
+	ch, id, err = c.Target("whatsapp")
+	if err != nil || ch != "whatsapp" || id != "+15550001" {
+		t.Fatalf("phone fallback: %s %s %v", ch, id, err)
+	}
+	if _, _, err := c.Target("email"); err == nil {
+		t.Fatalf("expected email to be rejected as a chat target")
+	}
+	c.Addresses["discord"] = "222"
+	if _, _, err := c.Target(""); err == nil {
+		t.Fatalf("expected error when multiple channels exist")
+	}
+}
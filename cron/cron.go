@@ -5,12 +5,14 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/mosaxiv/clawlet/storage"
 )
 
 type Schedule struct {
@@ -27,6 +29,12 @@ type Payload struct {
 	Deliver bool   `json:"deliver"`
 	Channel string `json:"channel,omitempty"`
 	To      string `json:"to,omitempty"`
+	// Group, when set, delivers to the members of a recipient group
+	// instead of Channel and To.
+	Group string `json:"group,omitempty"`
+	// Batch runs the job on the provider's batch API, at lower cost but
+	// with the result up to a day later; see Batcher.
+	Batch bool `json:"batch,omitempty"`
 }
 
 type State struct {
@@ -34,6 +42,8 @@ type State struct {
 	LastRunAtMS int64  `json:"lastRunAtMs,omitempty"`
 	LastStatus  string `json:"lastStatus,omitempty"`
 	LastError   string `json:"lastError,omitempty"`
+	// BatchID is the batch a batch job is waiting for.
+	BatchID string `json:"batchId,omitempty"`
 }
 
 type Job struct {
@@ -48,26 +58,59 @@ type Job struct {
 	DeleteAfterRun bool     `json:"deleteAfterRun,omitempty"`
 }
 
+// StoreVersion is the cron store format written by this version.
+const StoreVersion = 1
+
 type Store struct {
 	Version int   `json:"version"`
 	Jobs    []Job `json:"jobs"`
 }
 
+// StoreKey is the record key of the job store in storage.NamespaceCron.
+const StoreKey = "cron.json"
+
 type Service struct {
-	storePath string
-	onJob     func(ctx context.Context, job Job) (string, error)
+	backend  storage.Store
+	storeKey string
+	onJob    func(ctx context.Context, job Job) (string, error)
 
 	mu      sync.Mutex
 	store   Store
 	running bool
 	timer   *time.Timer
+	refresh time.Duration
+
+	batcher    Batcher
+	batchPoll  time.Duration
+	polledAtMS int64
+}
+
+// Batcher runs the jobs with Payload.Batch on a provider's batch API.
+type Batcher interface {
+	// Submit queues the job's work and returns the batch ID.
+	Submit(ctx context.Context, job Job) (string, error)
+	// Collect delivers the result of the job's batch (State.BatchID) once
+	// it has finished. done is false while the batch is still running; an
+	// error with done false is retried at the next poll.
+	Collect(ctx context.Context, job Job) (done bool, err error)
 }
 
 func NewService(storePath string, onJob func(ctx context.Context, job Job) (string, error)) *Service {
+	st := storage.NewFiles(map[string]string{storage.NamespaceCron: filepath.Dir(storePath)})
+	return newService(st, filepath.Base(storePath), onJob)
+}
+
+// NewServiceWithStore keeps the job store in st under StoreKey.
+func NewServiceWithStore(st storage.Store, onJob func(ctx context.Context, job Job) (string, error)) *Service {
+	return newService(st, StoreKey, onJob)
+}
+
+func newService(st storage.Store, key string, onJob func(ctx context.Context, job Job) (string, error)) *Service {
 	return &Service{
-		storePath: storePath,
-		onJob:     onJob,
-		store:     Store{Version: 1, Jobs: nil},
+		backend:  st,
+		storeKey: key,
+		onJob:    onJob,
+		store:    Store{Version: StoreVersion, Jobs: nil},
 	}
 }
 
@@ -89,6 +132,22 @@ func (s *Service) Start(ctx context.Context) error {
 	return nil
 }
 
+// SetRefresh makes a running service re-read its store at least every d, so
+// jobs added by other processes sharing the store are scheduled promptly.
+func (s *Service) SetRefresh(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh = d
+}
+
+// SetBatcher hands batch jobs to b and checks on their batches every poll.
+// Without a batcher, batch jobs run like any other.
+func (s *Service) SetBatcher(b Batcher, poll time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batcher, s.batchPoll = b, poll
+}
+
 func (s *Service) Stop() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -209,10 +268,16 @@ func (s *Service) armLocked(ctx context.Context) {
 		return
 	}
 	next := s.nextWakeMSLocked()
-	if next <= 0 {
+	if next <= 0 && s.refresh <= 0 {
 		return
 	}
-	delay := time.Duration(max64(0, next-nowMS())) * time.Millisecond
+	delay := s.refresh
+	if next > 0 {
+		d := time.Duration(max64(0, next-nowMS())) * time.Millisecond
+		if s.refresh <= 0 || d < s.refresh {
+			delay = d
+		}
+	}
 	if s.timer != nil {
 		s.timer.Stop()
 	}
@@ -231,7 +296,12 @@ func (s *Service) onTimer(ctx context.Context) error {
 		return nil
 	}
 	_ = s.loadLocked()
+	var pending []Job
+	pollBatches := s.batcher != nil && now >= s.polledAtMS+s.batchPoll.Milliseconds()
 	for _, j := range s.store.Jobs {
+		if pollBatches && j.State.BatchID != "" {
+			pending = append(pending, j)
+		}
 		if !j.Enabled || j.State.NextRunAtMS <= 0 {
 			continue
 		}
@@ -239,6 +309,20 @@ func (s *Service) onTimer(ctx context.Context) error {
 			due = append(due, j)
 		}
 	}
+	if pollBatches {
+		s.polledAtMS = now
+	}
+	s.mu.Unlock()
+	for _, j := range pending {
+		s.collect(ctx, j)
+	}
+	s.mu.Lock()
+	if len(due) == 0 {
+		// Refresh wake-up: the reload above picked up any new jobs.
+		s.armLocked(ctx)
+		s.mu.Unlock()
+		return nil
+	}
 	s.mu.Unlock()
 
 	for _, j := range due {
@@ -256,9 +340,19 @@ func (s *Service) onTimer(ctx context.Context) error {
 
 func (s *Service) execute(ctx context.Context, job Job) (string, error) {
 	start := nowMS()
-	var resp string
+	var resp, status, batchID string
 	var err error
-	if s.onJob != nil {
+	s.mu.Lock()
+	batcher := s.batcher
+	s.mu.Unlock()
+	switch {
+	case batcher != nil && job.Payload.Batch && job.State.BatchID != "":
+		// The previous run's batch is still running.
+		status = "skipped"
+	case batcher != nil && job.Payload.Batch:
+		batchID, err = batcher.Submit(ctx, job)
+		status = "submitted"
+	case s.onJob != nil:
 		resp, err = s.onJob(ctx, job)
 	}
 
@@ -277,13 +371,20 @@ func (s *Service) execute(ctx context.Context, job Job) (string, error) {
 			j.State.LastError = err.Error()
 		} else {
 			j.State.LastStatus = "ok"
+			if status != "" {
+				j.State.LastStatus = status
+			}
 			j.State.LastError = ""
 		}
+		if batchID != "" {
+			j.State.BatchID = batchID
+		}
 		j.UpdatedAtMS = updated
 
-		// One-shot at: disable or delete
+		// One-shot at: disable or delete; a job waiting for its batch is
+		// deleted once the batch is collected.
 		if j.Schedule.Kind == "at" {
-			if j.DeleteAfterRun {
+			if j.DeleteAfterRun && j.State.BatchID == "" {
 				s.store.Jobs = append(s.store.Jobs[:i], s.store.Jobs[i+1:]...)
 			} else {
 				j.Enabled = false
@@ -298,40 +399,65 @@ func (s *Service) execute(ctx context.Context, job Job) (string, error) {
 	return resp, err
 }
 
+// collect checks on the batch of job and records its outcome when it has
+// finished.
+func (s *Service) collect(ctx context.Context, job Job) {
+	done, err := s.batcher.Collect(ctx, job)
+	if !done {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.loadLocked()
+	for i := range s.store.Jobs {
+		j := &s.store.Jobs[i]
+		if j.ID != job.ID || j.State.BatchID != job.State.BatchID {
+			continue
+		}
+		j.State.BatchID = ""
+		if err != nil {
+			j.State.LastStatus = "error"
+			j.State.LastError = err.Error()
+		} else {
+			j.State.LastStatus = "ok"
+			j.State.LastError = ""
+		}
+		j.UpdatedAtMS = nowMS()
+		if j.Schedule.Kind == "at" && j.DeleteAfterRun && !j.Enabled {
+			s.store.Jobs = append(s.store.Jobs[:i], s.store.Jobs[i+1:]...)
+		}
+		break
+	}
+	_ = s.saveLocked()
+}
+
 func (s *Service) loadLocked() error {
-	b, err := os.ReadFile(s.storePath)
+	b, err := s.backend.Get(storage.NamespaceCron, s.storeKey)
 	if err != nil {
-		if os.IsNotExist(err) {
-			s.store = Store{Version: 1, Jobs: nil}
+		if errors.Is(err, storage.ErrNotFound) {
+			s.store = Store{Version: StoreVersion, Jobs: nil}
 			return nil
 		}
 		return err
 	}
 	var st Store
 	if err := json.Unmarshal(b, &st); err != nil {
-		return fmt.Errorf("parse %s: %w", s.storePath, err)
+		return fmt.Errorf("parse cron store %s: %w", s.storeKey, err)
 	}
 	if st.Version == 0 {
-		st.Version = 1
+		st.Version = StoreVersion
 	}
 	s.store = st
 	return nil
 }
 
 func (s *Service) saveLocked() error {
-	if err := os.MkdirAll(filepath.Dir(s.storePath), 0o700); err != nil {
-		return err
-	}
 	b, err := json.MarshalIndent(s.store, "", "  ")
 	if err != nil {
 		return err
 	}
 	b = append(b, '\n')
-	tmp := s.storePath + ".tmp"
-	if err := os.WriteFile(tmp, b, 0o600); err != nil {
-		return err
-	}
-	return os.Rename(tmp, s.storePath)
+	return s.backend.Put(storage.NamespaceCron, s.storeKey, b)
 }
 
 func (s *Service) recomputeNextRunsLocked() {
@@ -348,6 +474,11 @@ func (s *Service) recomputeNextRunsLocked() {
 func (s *Service) nextWakeMSLocked() int64 {
 	var best int64
 	for _, j := range s.store.Jobs {
+		if s.batcher != nil && j.State.BatchID != "" {
+			if poll := s.polledAtMS + s.batchPoll.Milliseconds(); best == 0 || poll < best {
+				best = poll
+			}
+		}
 		if !j.Enabled || j.State.NextRunAtMS <= 0 {
 			continue
 		}
@@ -378,13 +509,34 @@ func computeNextRunMS(s Schedule, now int64) int64 {
 		if err != nil {
 			return 0
 		}
-		next := sched.Next(time.UnixMilli(now))
+		t := time.UnixMilli(now)
+		if tz := strings.TrimSpace(s.TZ); tz != "" {
+			loc, err := time.LoadLocation(tz)
+			if err != nil {
+				return 0
+			}
+			t = t.In(loc)
+		}
+		next := sched.Next(t)
+		if next.IsZero() {
+			return 0
+		}
 		return next.UnixMilli()
 	default:
 		return 0
 	}
 }
 
+// Next returns when the schedule runs next after now; zero when it
+// doesn't.
+func (s Schedule) Next(now time.Time) time.Time {
+	ms := computeNextRunMS(s, now.UnixMilli())
+	if ms <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
 func nowMS() int64 { return time.Now().UnixMilli() }
 
 func validateSchedule(s Schedule, now int64) error {
@@ -410,6 +562,11 @@ func validateSchedule(s Schedule, now int64) error {
 		if _, err := parseCron5(expr); err != nil {
 			return fmt.Errorf("invalid cron expression: %w", err)
 		}
+		if tz := strings.TrimSpace(s.TZ); tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				return fmt.Errorf("invalid schedule timezone: %w", err)
+			}
+		}
 		return nil
 	default:
 		return fmt.Errorf("unknown schedule kind: %s", s.Kind)
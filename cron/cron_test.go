@@ -1,9 +1,14 @@
 package cron
 
 import (
+	"context"
+	"fmt"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/mosaxiv/clawlet/storage"
 )
 
 func TestServiceAdd_RejectsInvalidSchedule(t *testing.T) {
@@ -101,3 +106,137 @@ func TestComputeNextRunMS_CronWeekday(t *testing.T) {
 		t.Fatalf("expected %v, got %v", want, got)
 	}
 }
+
+func TestService_WithStore(t *testing.T) {
+	st := storage.NewFiles(map[string]string{storage.NamespaceCron: t.TempDir()})
+	svc := NewServiceWithStore(st, nil)
+	j, err := svc.Add("ping", Schedule{Kind: "every", EveryMS: 60000}, Payload{Kind: "agent_turn", Message: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Get(storage.NamespaceCron, StoreKey); err != nil {
+		t.Fatalf("store not written: %v", err)
+	}
+	jobs := NewServiceWithStore(st, nil).List(true)
+	if len(jobs) != 1 || jobs[0].ID != j.ID {
+		t.Fatalf("jobs=%+v", jobs)
+	}
+}
+
+func TestService_RefreshPicksUpJobsFromOtherInstances(t *testing.T) {
+	st := storage.NewFiles(map[string]string{storage.NamespaceCron: t.TempDir()})
+	fired := make(chan string, 1)
+	leader := NewServiceWithStore(st, func(ctx context.Context, job Job) (string, error) {
+		fired <- job.Name
+		return "", nil
+	})
+	leader.SetRefresh(20 * time.Millisecond)
+	if err := leader.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer leader.Stop()
+
+	other := NewServiceWithStore(st, nil)
+	if _, err := other.Add("remote", Schedule{Kind: "at", AtMS: time.Now().Add(80 * time.Millisecond).UnixMilli()}, Payload{Kind: "agent_turn", Message: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case name := <-fired:
+		if name != "remote" {
+			t.Fatalf("fired %q", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("job added by another instance never ran")
+	}
+}
+
+func TestScheduleNext_Timezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) // 21:00 in Tokyo
+	next := Schedule{Kind: "cron", Expr: "0 9 * * *", TZ: "Asia/Tokyo"}.Next(now)
+	if want := time.Date(2026, 10, 17, 9, 0, 0, 0, tokyo); !next.Equal(want) {
+		t.Fatalf("next = %v, want %v", next, want)
+	}
+	if err := validateSchedule(Schedule{Kind: "cron", Expr: "0 9 * * *", TZ: "Mars/Base"}, now.UnixMilli()); err == nil {
+		t.Fatal("expected error for an unknown timezone")
+	}
+}
+
+type fakeBatcher struct {
+	mu        sync.Mutex
+	submitted int
+	done      bool
+	collected chan string
+}
+
+func (b *fakeBatcher) Submit(ctx context.Context, job Job) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.submitted++
+	return fmt.Sprintf("batch-%d", b.submitted), nil
+}
+
+func (b *fakeBatcher) Collect(ctx context.Context, job Job) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		b.collected <- job.State.BatchID
+	}
+	return b.done, nil
+}
+
+func TestService_BatchJobs(t *testing.T) {
+	st := storage.NewFiles(map[string]string{storage.NamespaceCron: t.TempDir()})
+	svc := NewServiceWithStore(st, func(ctx context.Context, job Job) (string, error) {
+		t.Errorf("batch job %s ran as a turn", job.Name)
+		return "", nil
+	})
+	b := &fakeBatcher{collected: make(chan string, 1)}
+	svc.SetBatcher(b, 20*time.Millisecond)
+	j, err := svc.Add("digest", Schedule{Kind: "every", EveryMS: 3600000}, Payload{Kind: "agent_turn", Message: "summarize", Batch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := svc.RunNow(ctx, j.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	// A run while the batch is pending does not submit another.
+	if _, err := svc.RunNow(ctx, j.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	jobs := svc.List(true)
+	if b.submitted != 1 || jobs[0].State.BatchID != "batch-1" || jobs[0].State.LastStatus != "skipped" {
+		t.Fatalf("submitted=%d state=%+v", b.submitted, jobs[0].State)
+	}
+
+	if err := svc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Stop()
+	b.mu.Lock()
+	b.done = true
+	b.mu.Unlock()
+	select {
+	case id := <-b.collected:
+		if id != "batch-1" {
+			t.Fatalf("collected %q", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("batch never collected")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		st := svc.List(true)[0].State
+		if st.BatchID == "" && st.LastStatus == "ok" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("state after collect: %+v", st)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
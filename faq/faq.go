@@ -0,0 +1,256 @@
+// Package faq answers common questions from a workspace file, so they need
+// no LLM call. Recent does the same for questions repeated in a group chat
+// shortly after the agent answered them.
+//
+// The file is markdown. Each "## " heading is a question and the text under
+// it the answer; consecutive headings are ways of asking the same thing. A
+// heading between slashes, such as "## /opening hours|when.*open/", is a
+// case-insensitive regular expression:
+//
+//	## What are your opening hours?
+//	## /when.*open/
+//	We are open 9:00 to 17:00, Monday to Friday.
+package faq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Entry is one answer and the questions it is given for.
+type Entry struct {
+	Questions []string
+	Patterns  []*regexp.Regexp
+	Answer    string
+}
+
+// Parse reads an FAQ file. Text before the first heading is ignored.
+func Parse(text string) ([]Entry, error) {
+	var entries []Entry
+	var cur *Entry
+	var answer []string
+	flush := func() error {
+		if cur == nil {
+			return nil
+		}
+		cur.Answer = strings.TrimSpace(strings.Join(answer, "\n"))
+		if cur.Answer == "" {
+			return fmt.Errorf("faq: %q has no answer", firstQuestion(*cur))
+		}
+		entries = append(entries, *cur)
+		cur, answer = nil, nil
+		return nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		q, ok := strings.CutPrefix(line, "## ")
+		if !ok {
+			if cur != nil {
+				answer = append(answer, line)
+			}
+			continue
+		}
+		if cur != nil && strings.TrimSpace(strings.Join(answer, "")) != "" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		if cur == nil {
+			cur = &Entry{}
+		}
+		q = strings.TrimSpace(q)
+		if len(q) > 2 && strings.HasPrefix(q, "/") && strings.HasSuffix(q, "/") {
+			re, err := regexp.Compile("(?i)" + q[1:len(q)-1])
+			if err != nil {
+				return nil, fmt.Errorf("faq: pattern %s: %w", q, err)
+			}
+			cur.Patterns = append(cur.Patterns, re)
+		} else if q != "" {
+			cur.Questions = append(cur.Questions, q)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func firstQuestion(e Entry) string {
+	if len(e.Questions) > 0 {
+		return e.Questions[0]
+	}
+	if len(e.Patterns) > 0 {
+		return e.Patterns[0].String()
+	}
+	return ""
+}
+
+// Embedder embeds texts as vectors, e.g. memory.Embedder.
+type Embedder interface {
+	EmbedBatch(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// Matcher finds the FAQ entry for a question. The file is read again when
+// it changes.
+type Matcher struct {
+	path      string
+	threshold float64
+	embedder  Embedder
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	entries []Entry
+	err     error
+	// vectors embeds each of entries' questions; owner is the entry of each.
+	vectors [][]float64
+	owner   []int
+}
+
+// New returns a Matcher for the FAQ at path. With an embedder, questions
+// are also matched by meaning when their similarity reaches threshold.
+func New(path string, threshold float64, e Embedder) *Matcher {
+	return &Matcher{path: path, threshold: threshold, embedder: e}
+}
+
+// Match returns the answer for question and how confident the match is:
+// 1 for an exact or pattern match, the similarity for one by meaning. ok is
+// false when nothing matches well enough, or the file is missing.
+func (m *Matcher) Match(ctx context.Context, question string) (answer string, score float64, ok bool, err error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return "", 0, false, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.loadLocked(); err != nil {
+		return "", 0, false, err
+	}
+	norm := normalize(question)
+	for _, e := range m.entries {
+		for _, q := range e.Questions {
+			if normalize(q) == norm {
+				return e.Answer, 1, true, nil
+			}
+		}
+		for _, re := range e.Patterns {
+			if re.MatchString(question) {
+				return e.Answer, 1, true, nil
+			}
+		}
+	}
+	if m.embedder == nil {
+		return "", 0, false, nil
+	}
+	if err := m.embedLocked(ctx); err != nil {
+		return "", 0, false, err
+	}
+	if len(m.vectors) == 0 {
+		return "", 0, false, nil
+	}
+	vecs, err := m.embedder.EmbedBatch(ctx, []string{question})
+	if err != nil || len(vecs) == 0 {
+		return "", 0, false, err
+	}
+	best, bestScore := -1, 0.0
+	for i, v := range m.vectors {
+		if s := cosine(vecs[0], v); s > bestScore {
+			best, bestScore = m.owner[i], s
+		}
+	}
+	if best < 0 || bestScore < m.threshold {
+		return "", bestScore, false, nil
+	}
+	return m.entries[best].Answer, bestScore, true, nil
+}
+
+// loadLocked reads the file when it changed since the last read.
+func (m *Matcher) loadLocked() error {
+	info, err := os.Stat(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		m.entries, m.vectors, m.owner, m.err = nil, nil, nil, nil
+		m.modTime, m.size = time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(m.modTime) && info.Size() == m.size {
+		return m.err
+	}
+	m.modTime, m.size = info.ModTime(), info.Size()
+	m.entries, m.vectors, m.owner = nil, nil, nil
+	b, err := os.ReadFile(m.path)
+	if err != nil {
+		m.err = err
+		return err
+	}
+	m.entries, m.err = Parse(string(b))
+	return m.err
+}
+
+// embedLocked embeds the questions of the loaded file once.
+func (m *Matcher) embedLocked(ctx context.Context) error {
+	if m.vectors != nil {
+		return nil
+	}
+	var texts []string
+	var owner []int
+	for i, e := range m.entries {
+		for _, q := range e.Questions {
+			texts = append(texts, q)
+			owner = append(owner, i)
+		}
+	}
+	if len(texts) == 0 {
+		m.vectors = [][]float64{}
+		return nil
+	}
+	vecs, err := m.embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return err
+	}
+	if len(vecs) != len(texts) {
+		return fmt.Errorf("faq: %d embeddings for %d questions", len(vecs), len(texts))
+	}
+	m.vectors, m.owner = vecs, owner
+	return nil
+}
+
+// normalize lowercases s and drops punctuation, so "Opening hours?" and
+// "opening hours" are the same question.
+func normalize(s string) string {
+	var b strings.Builder
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(w)
+	}
+	return b.String()
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
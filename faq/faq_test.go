@@ -0,0 +1,81 @@
+package faq
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testFAQ = `# Shop FAQ
+
+Intro text is ignored.
+
+## What are your opening hours?
+## /when.*open/
+We are open 9:00 to 17:00.
+
+## How do I get a refund?
+
+Reply with your order number.
+`
+
+func TestParse(t *testing.T) {
+	entries, err := Parse(testFAQ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || len(entries[0].Questions) != 1 || len(entries[0].Patterns) != 1 || entries[1].Answer != "Reply with your order number." {
+		t.Fatalf("entries %+v", entries)
+	}
+	if _, err := Parse("## Question without answer\n"); err == nil {
+		t.Fatal("expected error for a question without an answer")
+	}
+	if _, err := Parse("## /(unclosed/\nanswer"); err == nil {
+		t.Fatal("expected error for an invalid pattern")
+	}
+}
+
+// fakeEmbedder maps texts to fixed vectors.
+type fakeEmbedder map[string][]float64
+
+func (f fakeEmbedder) EmbedBatch(_ context.Context, texts []string) ([][]float64, error) {
+	out := make([][]float64, len(texts))
+	for i, t := range texts {
+		out[i] = f[t]
+		if out[i] == nil {
+			out[i] = []float64{0, 0, 1}
+		}
+	}
+	return out, nil
+}
+
+func TestMatcher_Match(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "FAQ.md")
+	m := New(path, 0.9, fakeEmbedder{
+		"What are your opening hours?": {1, 0, 0},
+		"How do I get a refund?":       {0, 1, 0},
+		"can I have my money back":     {0.1, 0.99, 0},
+		"what time is it":              {0.7, 0.7, 0},
+	})
+	if _, _, ok, err := m.Match(context.Background(), "opening hours?"); ok || err != nil {
+		t.Fatalf("missing file: ok=%v err=%v", ok, err)
+	}
+	if err := os.WriteFile(path, []byte(testFAQ), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		q      string
+		answer string
+	}{
+		{"what are your OPENING hours", "We are open 9:00 to 17:00."},
+		{"When are you open on Sunday?", "We are open 9:00 to 17:00."},
+		{"can I have my money back", "Reply with your order number."},
+		{"what time is it", ""},
+	} {
+		answer, _, ok, err := m.Match(context.Background(), tc.q)
+		if err != nil || ok != (tc.answer != "") || answer != tc.answer {
+			t.Fatalf("%q: %q ok=%v err=%v", tc.q, answer, ok, err)
+		}
+	}
+}
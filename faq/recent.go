@@ -0,0 +1,101 @@
+package faq
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRecent caps the answers kept per chat.
+const maxRecent = 200
+
+// Recent remembers the questions answered in each chat for a while, so a
+// question that nearly repeats one of them can get the same answer.
+type Recent struct {
+	embedder  Embedder
+	window    time.Duration
+	threshold float64
+	now       func() time.Time
+
+	mu    sync.Mutex
+	chats map[string][]Answered
+}
+
+// Answered is a question answered in a chat.
+type Answered struct {
+	Question string
+	SenderID string
+	Answer   string
+	At       time.Time
+	vector   []float64
+}
+
+// NewRecent returns a Recent that keeps answers for window and matches
+// questions whose similarity reaches threshold.
+func NewRecent(e Embedder, window time.Duration, threshold float64) *Recent {
+	return &Recent{embedder: e, window: window, threshold: threshold, now: time.Now, chats: map[string][]Answered{}}
+}
+
+// IsQuestion reports whether text asks something. Only questions are
+// compared, so chatter such as "thanks" is never answered twice.
+func IsQuestion(text string) bool {
+	return strings.ContainsAny(text, "?？")
+}
+
+// Find embeds question and returns the most similar question answered in
+// chat within the window, if it is similar enough and was asked by someone
+// other than senderID. The embedding is returned for Add.
+func (r *Recent) Find(ctx context.Context, chat, senderID, question string) (Answered, []float64, bool, error) {
+	vecs, err := r.embedder.EmbedBatch(ctx, []string{question})
+	if err != nil || len(vecs) == 0 {
+		return Answered{}, nil, false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	best, bestScore := -1, 0.0
+	recent := r.pruneLocked(chat)
+	for i, a := range recent {
+		if a.SenderID == senderID {
+			continue
+		}
+		if s := cosine(vecs[0], a.vector); s > bestScore {
+			best, bestScore = i, s
+		}
+	}
+	if best < 0 || bestScore < r.threshold {
+		return Answered{}, vecs[0], false, nil
+	}
+	return recent[best], vecs[0], true, nil
+}
+
+// Add remembers that question, embedded as vector, was answered in chat.
+func (r *Recent) Add(chat, senderID, question string, vector []float64, answer string) {
+	if len(vector) == 0 || strings.TrimSpace(answer) == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recent := append(r.pruneLocked(chat), Answered{Question: question, SenderID: senderID, Answer: answer, At: r.now(), vector: vector})
+	if len(recent) > maxRecent {
+		recent = recent[len(recent)-maxRecent:]
+	}
+	r.chats[chat] = recent
+}
+
+// pruneLocked drops the answers of chat older than the window.
+func (r *Recent) pruneLocked(chat string) []Answered {
+	recent := r.chats[chat]
+	cutoff := r.now().Add(-r.window)
+	i := 0
+	for i < len(recent) && recent[i].At.Before(cutoff) {
+		i++
+	}
+	recent = recent[i:]
+	if len(recent) == 0 {
+		delete(r.chats, chat)
+		return nil
+	}
+	r.chats[chat] = recent
+	return recent
+}
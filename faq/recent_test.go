@@ -0,0 +1,56 @@
+package faq
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecent_Find(t *testing.T) {
+	r := NewRecent(fakeEmbedder{
+		"How do I reset my password?":       {1, 0, 0},
+		"how can I reset the password?":     {0.98, 0.1, 0},
+		"Where do I download the app?":      {0, 1, 0},
+		"password reset not working either": {0.6, 0, 0.8},
+	}, time.Hour, 0.9)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, vec, ok, err := r.Find(ctx, "slack:C1", "alice", "How do I reset my password?")
+	if ok || err != nil || vec == nil {
+		t.Fatalf("first question: ok=%v vec=%v err=%v", ok, vec, err)
+	}
+	r.Add("slack:C1", "alice", "How do I reset my password?", vec, "Use the link on the login page.")
+
+	for _, tc := range []struct {
+		chat, sender, q string
+		want            bool
+	}{
+		{"slack:C1", "bob", "how can I reset the password?", true},
+		// The asker repeating themselves likely wants more than the same answer.
+		{"slack:C1", "alice", "how can I reset the password?", false},
+		{"slack:C2", "bob", "how can I reset the password?", false},
+		{"slack:C1", "bob", "password reset not working either", false},
+		{"slack:C1", "bob", "Where do I download the app?", false},
+	} {
+		got, _, ok, err := r.Find(ctx, tc.chat, tc.sender, tc.q)
+		if err != nil || ok != tc.want {
+			t.Fatalf("%s %s %q: ok=%v err=%v", tc.chat, tc.sender, tc.q, ok, err)
+		}
+		if ok && got.Answer != "Use the link on the login page." {
+			t.Fatalf("answer = %q", got.Answer)
+		}
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, _, ok, _ := r.Find(ctx, "slack:C1", "bob", "how can I reset the password?"); ok {
+		t.Fatal("matched an answer older than the window")
+	}
+}
+
+func TestIsQuestion(t *testing.T) {
+	if !IsQuestion("is it open today?") || !IsQuestion("今日は開いていますか？") || IsQuestion("thanks!") {
+		t.Fatal("IsQuestion")
+	}
+}
@@ -0,0 +1,433 @@
+// Package forget removes what clawlet stored about a single chat sender, so
+// deletion requests can be honoured: their sessions and messages, /remember
+// notes taken from those sessions, contacts and cron deliveries addressed to
+// them, recorded turns, and their messages held while the agent was away.
+// Free-text memory (MEMORY.md, HISTORY.md, daily notes) cannot be
+// attributed reliably; lines mentioning the sender are only reported.
+package forget
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/atrest"
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/contacts"
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/memory"
+	"github.com/mosaxiv/clawlet/presence"
+	"github.com/mosaxiv/clawlet/session"
+	"github.com/mosaxiv/clawlet/storage"
+	"github.com/mosaxiv/clawlet/turns"
+)
+
+// Target identifies the sender to forget.
+type Target struct {
+	// Sender is a channel sender ID. Compound IDs ("id|username") match on
+	// any part.
+	Sender string
+	// Channel optionally restricts matching to one channel.
+	Channel string
+}
+
+type Env struct {
+	Sessions  storage.Store // nil skips sessions
+	Turns     storage.Store // nil skips recorded turns
+	Workspace string
+	Cron      *cron.Service     // nil skips cron jobs
+	Presence  *presence.Tracker // nil skips messages held while away
+}
+
+type SessionChange struct {
+	Key     string // storage key, see session.StorageKey
+	Removed int    // messages removed
+	Deleted bool   // whole session file removed
+}
+
+type Report struct {
+	DryRun   bool
+	Sessions []SessionChange
+	Notes    []string // workspace-relative paths
+	Contacts []string
+	CronJobs []string
+	Turns    []string
+	Held     int // messages held while away
+	// Review lists "path:line" locations in free-text memory that mention
+	// the sender and need a manual look.
+	Review []string
+}
+
+func (r Report) Empty() bool {
+	return len(r.Sessions) == 0 && len(r.Notes) == 0 && len(r.Contacts) == 0 &&
+		len(r.CronJobs) == 0 && len(r.Turns) == 0 && r.Held == 0 && len(r.Review) == 0
+}
+
+// Summary renders the report as plain text for the CLI and chat replies.
+func (r Report) Summary() string {
+	if r.Empty() {
+		return "nothing stored for this sender"
+	}
+	verb := "removed"
+	if r.DryRun {
+		verb = "would remove"
+	}
+	var b strings.Builder
+	for _, s := range r.Sessions {
+		if s.Deleted {
+			fmt.Fprintf(&b, "%s session %s\n", verb, s.Key)
+		} else {
+			fmt.Fprintf(&b, "%s %d messages from session %s\n", verb, s.Removed, s.Key)
+		}
+	}
+	for _, n := range r.Notes {
+		fmt.Fprintf(&b, "%s note %s\n", verb, n)
+	}
+	for _, c := range r.Contacts {
+		fmt.Fprintf(&b, "%s contact %s\n", verb, c)
+	}
+	for _, j := range r.CronJobs {
+		fmt.Fprintf(&b, "%s cron job %s\n", verb, j)
+	}
+	for _, t := range r.Turns {
+		fmt.Fprintf(&b, "%s recorded turn %s\n", verb, t)
+	}
+	if r.Held > 0 {
+		fmt.Fprintf(&b, "%s %d messages held while away\n", verb, r.Held)
+	}
+	if len(r.Review) > 0 {
+		b.WriteString("review manually (free-text memory mentioning the sender):\n")
+		for _, loc := range r.Review {
+			fmt.Fprintf(&b, "  %s\n", loc)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Run removes everything stored for t. With dryRun nothing is changed and the
+// report lists what would be removed.
+func Run(env Env, t Target, dryRun bool) (Report, error) {
+	rep := Report{DryRun: dryRun}
+	ids := senderParts(t.Sender)
+	if len(ids) == 0 {
+		return rep, errors.New("sender is required")
+	}
+	channel := strings.TrimSpace(t.Channel)
+
+	affected := map[string]bool{} // session storage keys
+	if env.Sessions != nil {
+		changes, err := forgetSessions(env.Sessions, channel, ids, dryRun)
+		if err != nil {
+			return rep, err
+		}
+		rep.Sessions = changes
+		for _, c := range changes {
+			affected[c.Key] = true
+		}
+	}
+	if env.Turns != nil {
+		removed, err := forgetTurns(env.Turns, channel, ids, affected, dryRun)
+		if err != nil {
+			return rep, err
+		}
+		rep.Turns = removed
+	}
+	if env.Workspace != "" {
+		notes, err := forgetNotes(env.Workspace, affected, dryRun)
+		if err != nil {
+			return rep, err
+		}
+		rep.Notes = notes
+
+		removed, err := contacts.NewStore(contacts.Path(env.Workspace)).RemoveWhere(func(c contacts.Contact) bool {
+			for k, v := range c.Addresses {
+				if (channel == "" || k == channel) && slices.Contains(ids, strings.TrimSpace(v)) {
+					return true
+				}
+			}
+			return false
+		}, dryRun)
+		if err != nil {
+			return rep, err
+		}
+		for _, c := range removed {
+			rep.Contacts = append(rep.Contacts, c.Name)
+		}
+
+		review, err := reviewMemory(env.Workspace, ids)
+		if err != nil {
+			return rep, err
+		}
+		rep.Review = review
+	}
+	if env.Presence != nil {
+		held, err := env.Presence.Drop(func(m bus.InboundMessage) bool {
+			return (channel == "" || m.Channel == channel) && matchSender(m.SenderID, ids)
+		}, dryRun)
+		if err != nil {
+			return rep, err
+		}
+		rep.Held = held
+	}
+	if env.Cron != nil {
+		for _, j := range env.Cron.List(true) {
+			if channel != "" && j.Payload.Channel != channel {
+				continue
+			}
+			if !slices.Contains(ids, strings.TrimSpace(j.Payload.To)) {
+				continue
+			}
+			rep.CronJobs = append(rep.CronJobs, fmt.Sprintf("%s (%s)", j.ID, j.Name))
+			if !dryRun {
+				env.Cron.Remove(j.ID)
+			}
+		}
+	}
+	return rep, nil
+}
+
+// forgetSessions deletes direct-chat sessions with the sender and strips
+// their turns (message plus the replies to it) from shared sessions. Sessions
+// left without user messages are deleted. Messages stored before senders
+// were recorded are only removed together with a direct-chat session.
+func forgetSessions(st storage.Store, channel string, ids []string, dryRun bool) ([]SessionChange, error) {
+	keys, err := st.List(storage.NamespaceSessions)
+	if err != nil {
+		return nil, err
+	}
+	var out []SessionChange
+	for _, key := range keys {
+		base, ok := strings.CutSuffix(key, ".jsonl")
+		if !ok || (channel != "" && !strings.HasPrefix(base, session.FileBase(channel)+"_")) {
+			continue
+		}
+		b, err := st.Get(storage.NamespaceSessions, key)
+		if err != nil {
+			return nil, err
+		}
+		if b, err = atrest.Decode(b); err != nil {
+			return nil, fmt.Errorf("session %s: %w", key, err)
+		}
+		kept, removed, userLeft := filterSession(b, ids)
+		direct := isDirectSession(base, ids)
+		if !direct && removed == 0 {
+			continue
+		}
+		change := SessionChange{Key: key, Removed: removed, Deleted: direct || !userLeft}
+		out = append(out, change)
+		if dryRun {
+			continue
+		}
+		if change.Deleted {
+			if err := st.Delete(storage.NamespaceSessions, key); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if kept, err = atrest.Encode(kept); err != nil {
+			return nil, err
+		}
+		if err := st.Put(storage.NamespaceSessions, key, kept); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// forgetTurns deletes recordings of the sender's turns and of turns in
+// sessions that held their messages, since a recording includes the history.
+func forgetTurns(st storage.Store, channel string, ids []string, affected map[string]bool, dryRun bool) ([]string, error) {
+	keys, err := turns.List(st)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, id := range keys {
+		t, err := turns.Load(st, id)
+		if err != nil {
+			return nil, err
+		}
+		bySender := (channel == "" || t.Channel == channel) && matchSender(t.Sender, ids)
+		if !bySender && !affected[session.StorageKey(t.SessionKey)] {
+			continue
+		}
+		out = append(out, id)
+		if dryRun {
+			continue
+		}
+		if err := st.Delete(turns.Namespace, id); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func filterSession(b []byte, ids []string) (kept []byte, removed int, userLeft bool) {
+	var buf strings.Builder
+	dropping := false
+	sc := bufio.NewScanner(strings.NewReader(string(b)))
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var m struct {
+			Type   string `json:"_type"`
+			Role   string `json:"role"`
+			Sender string `json:"sender"`
+		}
+		if json.Unmarshal([]byte(line), &m) == nil && m.Type == "" {
+			if m.Role == "user" {
+				dropping = matchSender(m.Sender, ids)
+				if !dropping {
+					userLeft = true
+				}
+			}
+			if dropping {
+				removed++
+				continue
+			}
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return []byte(buf.String()), removed, userLeft
+}
+
+// isDirectSession reports whether a session file belongs to a one-to-one chat
+// whose chat ID is the sender ID ("<channel>_<id>").
+func isDirectSession(base string, ids []string) bool {
+	_, chat, ok := strings.Cut(base, "_")
+	if !ok {
+		return false
+	}
+	// Forks of a direct chat ("telegram:1#fork-x") are direct too.
+	chat, _, _ = strings.Cut(chat, "_fork-")
+	for _, id := range ids {
+		if chat == session.FileBase(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func forgetNotes(workspace string, sessions map[string]bool, dryRun bool) ([]string, error) {
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	files, err := filepath.Glob(filepath.Join(memory.New(workspace).Dir, memory.NotesDir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, path := range files {
+		src, err := noteSource(path)
+		if err != nil {
+			return nil, err
+		}
+		if src == "" || !sessions[session.StorageKey(src)] {
+			continue
+		}
+		out = append(out, relPath(workspace, path))
+		if !dryRun {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// noteSource reads the source session key from a note's front matter.
+func noteSource(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for i := 0; sc.Scan(); i++ {
+		line := strings.TrimSpace(sc.Text())
+		if i == 0 && line != "---" {
+			return "", nil
+		}
+		if i > 0 && line == "---" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "source:"); ok {
+			v = strings.TrimSpace(v)
+			if s, err := strconv.Unquote(v); err == nil {
+				return s, nil
+			}
+			return v, nil
+		}
+	}
+	return "", sc.Err()
+}
+
+// reviewMemory finds lines in free-text memory files that mention the sender.
+func reviewMemory(workspace string, ids []string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(memory.New(workspace).Dir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	var needles []string
+	for _, id := range ids {
+		// Very short IDs would match unrelated text.
+		if len(id) >= 3 {
+			needles = append(needles, id)
+		}
+	}
+	if len(needles) == 0 {
+		return nil, nil
+	}
+	var out []string
+	for _, path := range files {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for i, line := range strings.Split(string(b), "\n") {
+			for _, n := range needles {
+				if strings.Contains(line, n) {
+					out = append(out, fmt.Sprintf("%s:%d", relPath(workspace, path), i+1))
+					break
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func senderParts(sender string) []string {
+	var out []string
+	for p := range strings.SplitSeq(sender, "|") {
+		if p = strings.TrimSpace(p); p != "" && !slices.Contains(out, p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func matchSender(stored string, ids []string) bool {
+	for _, p := range senderParts(stored) {
+		if slices.Contains(ids, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func relPath(base, path string) string {
+	if rel, err := filepath.Rel(base, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
@@ -0,0 +1,211 @@
+package forget
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/contacts"
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/memory"
+	"github.com/mosaxiv/clawlet/presence"
+	"github.com/mosaxiv/clawlet/session"
+	"github.com/mosaxiv/clawlet/storage"
+	"github.com/mosaxiv/clawlet/turns"
+)
+
+func setup(t *testing.T) Env {
+	t.Helper()
+	root := t.TempDir()
+	sessionsDir := filepath.Join(root, "sessions")
+	env := Env{
+		Sessions:  session.FileStore(sessionsDir),
+		Workspace: filepath.Join(root, "ws"),
+		Cron:      cron.NewService(filepath.Join(root, "cron.json"), nil),
+	}
+
+	dm := session.New("telegram:111")
+	dm.Add("user", "legacy message without sender")
+	dm.Add("assistant", "hi")
+	group := session.New("telegram:-100")
+	group.AddFrom("111|alice", "my address is 1 Main St")
+	group.Add("assistant", "noted")
+	group.AddFrom("222|bob", "hello")
+	group.Add("assistant", "hi bob")
+	other := session.New("discord:111")
+	other.AddFrom("111", "same id, other channel")
+	for _, s := range []*session.Session{dm, group, other} {
+		if err := session.SaveTo(env.Sessions, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ms := memory.New(env.Workspace)
+	if _, err := ms.SaveNote(memory.Note{Title: "alice", Source: "telegram:-100", Summary: "x"}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ms.SaveNote(memory.Note{Title: "other", Source: "slack:C1", Summary: "y"}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ms.LongTerm, []byte("# Memory\nalice likes tea\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cs := contacts.NewStore(contacts.Path(env.Workspace))
+	if _, err := cs.Upsert(contacts.Contact{Name: "Alice", Addresses: map[string]string{"telegram": "111"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.Upsert(contacts.Contact{Name: "Bob", Addresses: map[string]string{"telegram": "222"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.Cron.Add("reminder", cron.Schedule{Kind: "every", EveryMS: 60000}, cron.Payload{Kind: "agent_turn", Message: "m", Deliver: true, Channel: "telegram", To: "111"}); err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func TestRun_DryRunChangesNothing(t *testing.T) {
+	env := setup(t)
+	rep, err := Run(env, Target{Sender: "111|alice", Channel: "telegram"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Sessions) != 2 || len(rep.Notes) != 1 || len(rep.Contacts) != 1 || len(rep.CronJobs) != 1 || len(rep.Review) != 1 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	if s, _ := session.LoadFrom(env.Sessions, "telegram:111"); s == nil {
+		t.Fatal("dry run deleted session")
+	}
+	if len(env.Cron.List(true)) != 1 {
+		t.Fatal("dry run removed cron job")
+	}
+}
+
+func TestRun_Purges(t *testing.T) {
+	env := setup(t)
+	rep, err := Run(env, Target{Sender: "111|alice", Channel: "telegram"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := session.LoadFrom(env.Sessions, "telegram:111"); s != nil || err != nil {
+		t.Fatalf("direct session still exists: %v", err)
+	}
+	group, err := session.LoadFrom(env.Sessions, "telegram:-100")
+	if err != nil || group == nil {
+		t.Fatalf("group session: %v", err)
+	}
+	if len(group.Messages) != 2 || group.Messages[0].Content != "hello" || group.Messages[1].Content != "hi bob" {
+		t.Fatalf("group messages: %+v", group.Messages)
+	}
+	if s, _ := session.LoadFrom(env.Sessions, "discord:111"); s == nil {
+		t.Fatal("session on another channel was removed")
+	}
+
+	notes, _ := filepath.Glob(filepath.Join(env.Workspace, "memory", memory.NotesDir, "*.md"))
+	if len(notes) != 1 || !strings.Contains(notes[0], "other") {
+		t.Fatalf("notes left: %v", notes)
+	}
+	left, err := contacts.NewStore(contacts.Path(env.Workspace)).Search("", 0)
+	if err != nil || len(left) != 1 || left[0].Name != "Bob" {
+		t.Fatalf("contacts left: %+v %v", left, err)
+	}
+	if len(env.Cron.List(true)) != 0 {
+		t.Fatal("cron job not removed")
+	}
+	if len(rep.Review) != 1 || rep.Review[0] != "memory/MEMORY.md:2" {
+		t.Fatalf("review: %v", rep.Review)
+	}
+	if b, _ := os.ReadFile(filepath.Join(env.Workspace, "memory", "MEMORY.md")); !strings.Contains(string(b), "alice likes tea") {
+		t.Fatal("free-text memory must not be edited")
+	}
+}
+
+func TestRun_AccountChannel(t *testing.T) {
+	env := setup(t)
+	for _, key := range []string{"telegram.my:111", "telegram.my-bot:111"} {
+		s := session.New(key)
+		s.AddFrom("111", "hello")
+		if err := session.SaveTo(env.Sessions, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Run(env, Target{Sender: "111", Channel: "telegram.my"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := session.LoadFrom(env.Sessions, "telegram.my:111"); s != nil {
+		t.Fatal("direct session of the account still exists")
+	}
+	for _, key := range []string{"telegram.my-bot:111", "telegram:111"} {
+		if s, _ := session.LoadFrom(env.Sessions, key); s == nil {
+			t.Fatalf("session %s of another account was removed", key)
+		}
+	}
+}
+
+func TestRun_RequiresSender(t *testing.T) {
+	if _, err := Run(Env{}, Target{Sender: " | "}, true); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRun_DeletesRecordedTurns(t *testing.T) {
+	env := setup(t)
+	env.Turns = storage.NewFiles(map[string]string{turns.Namespace: t.TempDir()})
+	for _, tt := range []turns.Turn{
+		{ID: "1", SessionKey: "telegram:-100", Channel: "telegram", Sender: "111|alice"},
+		{ID: "2", SessionKey: "telegram:-100", Channel: "telegram", Sender: "222|bob"},
+		{ID: "3", SessionKey: "slack:C1", Channel: "slack", Sender: "333"},
+	} {
+		if err := turns.Save(env.Turns, &tt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rep, err := Run(env, Target{Sender: "111", Channel: "telegram"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Bob's turn in the shared session is recorded with Alice's messages in
+	// its history, so it goes too.
+	if strings.Join(rep.Turns, ",") != "1,2" {
+		t.Fatalf("turns=%v", rep.Turns)
+	}
+	left, _ := turns.List(env.Turns)
+	if strings.Join(left, ",") != "3" {
+		t.Fatalf("left=%v", left)
+	}
+}
+
+func TestRun_DropsHeldMessages(t *testing.T) {
+	env := setup(t)
+	dir := t.TempDir()
+	pres, err := presence.New(config.PresenceConfig{}, filepath.Join(dir, "presence.json"), filepath.Join(dir, "queue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []bus.InboundMessage{
+		{Channel: "telegram", SenderID: "111|alice", Content: "are you there?"},
+		{Channel: "telegram", SenderID: "222|bob", Content: "hello"},
+		{Channel: "discord", SenderID: "111", Content: "other channel"},
+	} {
+		if _, err := pres.Hold(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env.Presence = pres
+	rep, err := Run(env, Target{Sender: "111", Channel: "telegram"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Held != 1 || !strings.Contains(rep.Summary(), "removed 1 messages held while away") {
+		t.Fatalf("held=%d summary=%q", rep.Held, rep.Summary())
+	}
+	left, err := pres.Release(time.Now())
+	if err != nil || len(left) != 2 || left[0].SenderID != "222|bob" || left[1].Channel != "discord" {
+		t.Fatalf("left=%+v %v", left, err)
+	}
+}
@@ -0,0 +1,223 @@
+// Package fswatch records changes made to workspace files outside the agent,
+// so a turn can be told which files changed since the previous one.
+package fswatch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	OpCreated  = "created"
+	OpModified = "modified"
+	OpDeleted  = "deleted"
+)
+
+const (
+	// maxTracked bounds the recorded paths; the oldest are dropped first.
+	maxTracked = 1000
+	// busyGrace covers events delivered shortly after the agent's own writes.
+	busyGrace = time.Second
+)
+
+// Change is the last recorded change to a workspace file.
+type Change struct {
+	// Path is relative to the workspace, with forward slashes.
+	Path string
+	Op   string
+	At   time.Time
+}
+
+// Watcher watches a workspace tree. Hidden files and directories, and paths
+// under the excluded directories, are ignored. Changes made while the agent
+// is busy (see Busy) are treated as its own and not recorded.
+type Watcher struct {
+	root    string
+	exclude []string
+	fsw     *fsnotify.Watcher
+
+	mu         sync.Mutex
+	changes    map[string]Change
+	busy       int
+	quietUntil time.Time
+}
+
+// New watches root and every directory below it. exclude lists directories
+// relative to root, e.g. "memory".
+func New(root string, exclude []string) (*Watcher, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{root: root, fsw: fsw, changes: map[string]Change{}}
+	for _, e := range exclude {
+		e = strings.Trim(filepath.ToSlash(filepath.Clean(e)), "/")
+		if e != "" && e != "." {
+			w.exclude = append(w.exclude, e)
+		}
+	}
+	if err := w.addTree(root); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Run records events until ctx is done, then closes the watcher.
+func (w *Watcher) Run(ctx context.Context) {
+	defer w.fsw.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.handle(ev)
+		case _, ok := <-w.fsw.Errors:
+			// Overflows and similar errors only lose events; keep going.
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// Busy marks the agent as working on the workspace until the returned
+// function is called.
+func (w *Watcher) Busy() (done func()) {
+	if w == nil {
+		return func() {}
+	}
+	w.mu.Lock()
+	w.busy++
+	w.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			w.mu.Lock()
+			w.busy--
+			w.quietUntil = time.Now().Add(busyGrace)
+			w.mu.Unlock()
+		})
+	}
+}
+
+// Since returns the files changed after t, sorted by path.
+func (w *Watcher) Since(t time.Time) []Change {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var out []Change
+	for _, c := range w.changes {
+		if c.At.After(t) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+func (w *Watcher) handle(ev fsnotify.Event) {
+	rel, ok := w.rel(ev.Name)
+	if !ok {
+		return
+	}
+	var op string
+	switch {
+	case ev.Has(fsnotify.Create):
+		op = OpCreated
+		// New directories are watched too; files already inside them
+		// were created in the same burst and are not reported.
+		if err := w.addTree(ev.Name); err != nil {
+			return
+		}
+		if isDir(ev.Name) {
+			return
+		}
+	case ev.Has(fsnotify.Write):
+		op = OpModified
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		op = OpDeleted
+	default:
+		return
+	}
+
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.busy > 0 || now.Before(w.quietUntil) {
+		return
+	}
+	if prev, ok := w.changes[rel]; ok && prev.Op == OpCreated && op == OpModified {
+		op = OpCreated
+	}
+	w.changes[rel] = Change{Path: rel, Op: op, At: now}
+	if len(w.changes) > maxTracked {
+		var oldest Change
+		for _, c := range w.changes {
+			if oldest.Path == "" || c.At.Before(oldest.At) {
+				oldest = c
+			}
+		}
+		delete(w.changes, oldest.Path)
+	}
+}
+
+// rel returns the workspace-relative path of name, or false when it is
+// hidden or excluded.
+func (w *Watcher) rel(name string) (string, bool) {
+	rel, err := filepath.Rel(w.root, name)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, part := range strings.Split(rel, "/") {
+		if strings.HasPrefix(part, ".") {
+			return "", false
+		}
+	}
+	for _, e := range w.exclude {
+		if rel == e || strings.HasPrefix(rel, e+"/") {
+			return "", false
+		}
+	}
+	return rel, true
+}
+
+func (w *Watcher) addTree(dir string) error {
+	if !isDir(dir) {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if p != w.root {
+			if _, ok := w.rel(p); !ok {
+				return fs.SkipDir
+			}
+		}
+		return w.fsw.Add(p)
+	})
+}
+
+func isDir(p string) bool {
+	info, err := os.Lstat(p)
+	return err == nil && info.IsDir()
+}
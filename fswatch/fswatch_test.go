@@ -0,0 +1,69 @@
+package fswatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, w *Watcher, since time.Time, n int) []Change {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		got := w.Since(since)
+		if len(got) >= n || time.Now().After(deadline) {
+			return got
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWatcher_RecordsExternalChanges(t *testing.T) {
+	ws := t.TempDir()
+	for _, d := range []string{"notes", "memory", ".git"} {
+		if err := os.MkdirAll(filepath.Join(ws, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(ws, "todo.md"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(ws, []string{"memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	start := time.Now()
+	write := func(rel string) {
+		if err := os.WriteFile(filepath.Join(ws, rel), []byte("b"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("memory/MEMORY.md")
+	write(".git/HEAD")
+	write("todo.md")
+	write("notes/new.md")
+
+	got := waitFor(t, w, start, 2)
+	if len(got) != 2 || got[0].Path != "notes/new.md" || got[0].Op != OpCreated || got[1].Path != "todo.md" || got[1].Op != OpModified {
+		t.Fatalf("changes=%+v", got)
+	}
+
+	done := w.Busy()
+	mark := time.Now()
+	write("mine.md")
+	time.Sleep(200 * time.Millisecond)
+	done()
+	if err := os.Remove(filepath.Join(ws, "todo.md")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if got := w.Since(mark); len(got) != 0 {
+		t.Fatalf("changes while busy should be ignored: %+v", got)
+	}
+}
@@ -0,0 +1,253 @@
+// Package handover passes listening sockets from a running gateway to a
+// freshly started one, so an upgraded binary can take over webhook listeners
+// without a window where connections are refused.
+//
+// The old process starts the new one with its listeners as inherited file
+// descriptors, waits until the new process reports ready, then drains and
+// exits. The new process picks the sockets up through Listen.
+package handover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	envListeners = "CLAWLET_HANDOVER_LISTENERS" // addr=fd,addr=fd
+	envReadyFD   = "CLAWLET_HANDOVER_READY_FD"
+
+	// DefaultReadyTimeout bounds how long the old process waits for the new
+	// one before giving up and keeping its listeners.
+	DefaultReadyTimeout = 60 * time.Second
+)
+
+var (
+	mu            sync.Mutex
+	inheritedOnce sync.Once
+	inherited     map[string]int // addr -> fd
+	active        = map[string]*net.TCPListener{}
+)
+
+// Listen returns a TCP listener for addr, reusing a socket inherited from a
+// previous process when one was handed over for the same address.
+func Listen(addr string) (net.Listener, error) {
+	addr = strings.TrimSpace(addr)
+	var (
+		ln  net.Listener
+		err error
+	)
+	if f := takeInherited(addr); f != nil {
+		ln, err = net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherited listener %s: %w", addr, err)
+		}
+	} else {
+		ln, err = net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tl, ok := ln.(*net.TCPListener)
+	if !ok {
+		return ln, nil
+	}
+	mu.Lock()
+	active[addr] = tl
+	mu.Unlock()
+	return &trackedListener{TCPListener: tl, addr: addr}, nil
+}
+
+type trackedListener struct {
+	*net.TCPListener
+	addr string
+}
+
+func (l *trackedListener) Close() error {
+	mu.Lock()
+	if active[l.addr] == l.TCPListener {
+		delete(active, l.addr)
+	}
+	mu.Unlock()
+	return l.TCPListener.Close()
+}
+
+// Inherited reports whether this process was started by a handover.
+func Inherited() bool {
+	return strings.TrimSpace(os.Getenv(envReadyFD)) != ""
+}
+
+// Ready tells the previous process that this one has started and it may
+// drain. Inherited sockets not claimed by Listen are closed. It is a no-op
+// when the process was not started by a handover.
+func Ready() error {
+	mu.Lock()
+	loadInheritedLocked()
+	for addr, fd := range inherited {
+		_ = os.NewFile(uintptr(fd), addr).Close()
+		delete(inherited, addr)
+	}
+	mu.Unlock()
+
+	v := strings.TrimSpace(os.Getenv(envReadyFD))
+	if v == "" {
+		return nil
+	}
+	_ = os.Unsetenv(envReadyFD)
+	_ = os.Unsetenv(envListeners)
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %q", envReadyFD, v)
+	}
+	f := os.NewFile(uintptr(fd), "handover-ready")
+	if f == nil {
+		return fmt.Errorf("invalid ready fd %d", fd)
+	}
+	defer f.Close()
+	_, err = f.Write([]byte("ready\n"))
+	return err
+}
+
+// Start launches exe with args, hands it every listener opened through
+// Listen and waits until it calls Ready. On success the caller should drain
+// and exit; on error the new process has been killed and the caller keeps
+// serving.
+func Start(ctx context.Context, exe string, args []string, timeout time.Duration) (int, error) {
+	if runtime.GOOS == "windows" {
+		return 0, errors.New("listener handover is not supported on windows")
+	}
+	if timeout <= 0 {
+		timeout = DefaultReadyTimeout
+	}
+
+	mu.Lock()
+	var (
+		files []*os.File
+		specs []string
+	)
+	for addr, ln := range active {
+		f, err := ln.File()
+		if err != nil {
+			mu.Unlock()
+			closeAll(files)
+			return 0, fmt.Errorf("dup listener %s: %w", addr, err)
+		}
+		// ExtraFiles[i] becomes fd 3+i in the child.
+		specs = append(specs, addr+"="+strconv.Itoa(3+len(files)))
+		files = append(files, f)
+	}
+	mu.Unlock()
+	defer closeAll(files)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, w)
+	cmd.Env = append(filterEnv(os.Environ()),
+		envListeners+"="+strings.Join(specs, ","),
+		envReadyFD+"="+strconv.Itoa(3+len(files)),
+	)
+	err = cmd.Start()
+	_ = w.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 16)
+		n, err := r.Read(buf)
+		if n > 0 {
+			err = nil
+		}
+		ready <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-ready:
+		if err == nil {
+			return cmd.Process.Pid, nil
+		}
+		_ = cmd.Process.Kill()
+		return 0, fmt.Errorf("new process exited before ready: %w", err)
+	case err := <-exited:
+		return 0, fmt.Errorf("new process exited before ready: %v", err)
+	case <-timer.C:
+		_ = cmd.Process.Kill()
+		return 0, fmt.Errorf("new process not ready after %s", timeout)
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		return 0, ctx.Err()
+	}
+}
+
+func takeInherited(addr string) *os.File {
+	mu.Lock()
+	defer mu.Unlock()
+	loadInheritedLocked()
+	fd, ok := inherited[addr]
+	if !ok {
+		return nil
+	}
+	delete(inherited, addr)
+	return os.NewFile(uintptr(fd), "handover:"+addr)
+}
+
+func loadInheritedLocked() {
+	inheritedOnce.Do(func() {
+		inherited = parseListeners(os.Getenv(envListeners))
+	})
+}
+
+func parseListeners(v string) map[string]int {
+	out := map[string]int{}
+	for _, part := range strings.Split(v, ",") {
+		addr, fdStr, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || addr == "" {
+			continue
+		}
+		fd, err := strconv.Atoi(fdStr)
+		if err != nil || fd < 3 {
+			continue
+		}
+		out[addr] = fd
+	}
+	return out
+}
+
+func filterEnv(env []string) []string {
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		if strings.HasPrefix(kv, envListeners+"=") || strings.HasPrefix(kv, envReadyFD+"=") {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+func closeAll(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
@@ -0,0 +1,113 @@
+package handover
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestListen_TracksActiveListeners(t *testing.T) {
+	ln, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	mu.Lock()
+	_, ok := active["127.0.0.1:0"]
+	mu.Unlock()
+	if !ok {
+		t.Fatalf("listener not tracked")
+	}
+	_ = ln.Close()
+	mu.Lock()
+	_, ok = active["127.0.0.1:0"]
+	mu.Unlock()
+	if ok {
+		t.Fatalf("listener still tracked after close")
+	}
+}
+
+func TestParseListeners(t *testing.T) {
+	got := parseListeners("127.0.0.1:1=3, bad, :2=x, :3=1,[::1]:4=4")
+	if len(got) != 2 || got["127.0.0.1:1"] != 3 || got["[::1]:4"] != 4 {
+		t.Fatalf("unexpected listeners: %v", got)
+	}
+}
+
+func TestFilterEnv_DropsHandoverVars(t *testing.T) {
+	got := filterEnv([]string{"A=1", envListeners + "=x=3", envReadyFD + "=4", "B=2"})
+	if len(got) != 2 || got[0] != "A=1" || got[1] != "B=2" {
+		t.Fatalf("unexpected env: %v", got)
+	}
+}
+
+func TestReady_NoopWithoutHandover(t *testing.T) {
+	t.Setenv(envReadyFD, "")
+	if err := Ready(); err != nil {
+		t.Fatalf("ready: %v", err)
+	}
+}
+
+const childEnv = "CLAWLET_HANDOVER_TEST_CHILD"
+
+func TestMain(m *testing.M) {
+	if addr := os.Getenv(childEnv); addr != "" {
+		runChild(addr)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runChild plays the upgraded process: it claims the inherited listener,
+// reports ready and answers one connection.
+func runChild(addr string) {
+	ln, err := Listen(addr)
+	if err != nil {
+		os.Exit(2)
+	}
+	if err := Ready(); err != nil {
+		os.Exit(3)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		os.Exit(4)
+	}
+	_, _ = conn.Write([]byte("child"))
+	_ = conn.Close()
+	os.Exit(0)
+}
+
+func TestStart_HandsOverListener(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("handover is not supported on windows")
+	}
+	const addr = "127.0.0.1:0"
+	ln, err := Listen(addr)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := ln.Addr().String()
+
+	t.Setenv(childEnv, addr)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := Start(ctx, os.Args[0], []string{"-test.run=^$"}, 10*time.Second); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	// The old process stops accepting; the socket stays open in the child.
+	_ = ln.Close()
+
+	conn, err := net.DialTimeout("tcp", port, 5*time.Second)
+	if err != nil {
+		t.Fatalf("dial after handover: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b, err := io.ReadAll(conn)
+	if err != nil || string(b) != "child" {
+		t.Fatalf("expected child to answer, got %q err=%v", b, err)
+	}
+}
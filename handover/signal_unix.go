@@ -0,0 +1,16 @@
+//go:build !windows
+
+package handover
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals trigger a handover in a running gateway.
+var Signals = []os.Signal{syscall.SIGUSR2}
+
+// Notify asks the process with pid to hand over to the installed binary.
+func Notify(pid int) error {
+	return syscall.Kill(pid, syscall.SIGUSR2)
+}
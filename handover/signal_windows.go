@@ -0,0 +1,15 @@
+//go:build windows
+
+package handover
+
+import (
+	"errors"
+	"os"
+)
+
+// Signals is empty on Windows, where listener handover is unavailable.
+var Signals []os.Signal
+
+func Notify(pid int) error {
+	return errors.New("listener handover is not supported on windows")
+}
@@ -0,0 +1,134 @@
+// Package leader runs singleton duties (cron, heartbeat, polling channels) on
+// exactly one of several gateway instances that share a storage backend.
+package leader
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mosaxiv/clawlet/storage"
+)
+
+const DefaultTTL = 15 * time.Second
+
+// Elector holds one named lease on behalf of an instance.
+type Elector struct {
+	leaser storage.Leaser
+	name   string
+	owner  string
+	ttl    time.Duration
+
+	mu     sync.Mutex
+	leader bool
+}
+
+func New(l storage.Leaser, name, owner string, ttl time.Duration) *Elector {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Elector{leaser: l, name: name, owner: owner, ttl: ttl}
+}
+
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Run blocks until ctx is done. While the lease is held, duty runs with a
+// context that is cancelled as soon as leadership is lost; Run waits for duty
+// to return before campaigning again, so two instances never overlap as long
+// as duty honours its context.
+func (e *Elector) Run(ctx context.Context, duty func(ctx context.Context)) {
+	interval := e.ttl / 3
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	var (
+		cur       *term
+		done      <-chan struct{}
+		heldUntil time.Time
+	)
+	stepDown := func() {
+		if cur == nil {
+			return
+		}
+		cur.stop()
+		cur, done = nil, nil
+		e.setLeader(false)
+	}
+	defer func() {
+		stepDown()
+		if err := e.leaser.ReleaseLease(e.name, e.owner); err != nil {
+			log.Printf("leader: release %s: %v", e.name, err)
+		}
+	}()
+
+	for {
+		start := time.Now()
+		ok, err := e.leaser.AcquireLease(e.name, e.owner, e.ttl)
+		switch {
+		case err != nil:
+			log.Printf("leader: %s: %v", e.name, err)
+			// Keep the duty only while the last grant is certainly valid.
+			if time.Now().Add(interval).After(heldUntil) {
+				stepDown()
+			}
+		case !ok:
+			stepDown()
+		default:
+			heldUntil = start.Add(e.ttl)
+			if cur == nil {
+				e.setLeader(true)
+				log.Printf("leader: %s acquired by %s", e.name, e.owner)
+				cur = startTerm(ctx, duty)
+				done = cur.done
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			// The duty gave up on its own; let another instance take over.
+			stepDown()
+			if err := e.leaser.ReleaseLease(e.name, e.owner); err != nil {
+				log.Printf("leader: release %s: %v", e.name, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		case <-t.C:
+		}
+	}
+}
+
+// term is one stretch of leadership.
+type term struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startTerm(ctx context.Context, duty func(ctx context.Context)) *term {
+	ctx, cancel := context.WithCancel(ctx)
+	t := &term{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		duty(ctx)
+	}()
+	return t
+}
+
+func (t *term) stop() {
+	t.cancel()
+	<-t.done
+}
+
+func (e *Elector) setLeader(v bool) {
+	e.mu.Lock()
+	e.leader = v
+	e.mu.Unlock()
+}
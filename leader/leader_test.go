@@ -0,0 +1,62 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/storage"
+)
+
+func TestElector_SingleLeaderAndFailover(t *testing.T) {
+	st := storage.NewFiles(map[string]string{storage.NamespaceLeases: t.TempDir()})
+	var active, overlap atomic.Int32
+	var ran sync.Map
+	duty := func(id string) func(ctx context.Context) {
+		return func(ctx context.Context) {
+			if active.Add(1) > 1 {
+				overlap.Add(1)
+			}
+			ran.Store(id, true)
+			<-ctx.Done()
+			active.Add(-1)
+		}
+	}
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	a := New(st, "cron", "a", 90*time.Millisecond)
+	doneA := make(chan struct{})
+	go func() { a.Run(ctxA, duty("a")); close(doneA) }()
+	waitFor(t, a.IsLeader)
+
+	ctxB, stopB := context.WithCancel(context.Background())
+	b := New(st, "cron", "b", 90*time.Millisecond)
+	doneB := make(chan struct{})
+	go func() { b.Run(ctxB, duty("b")); close(doneB) }()
+	defer func() { stopB(); <-doneB }()
+	time.Sleep(200 * time.Millisecond)
+	if b.IsLeader() {
+		t.Fatal("b became leader while a holds the lease")
+	}
+
+	stopA()
+	<-doneA
+	waitFor(t, b.IsLeader)
+	waitFor(t, func() bool { _, ok := ran.Load("b"); return ok })
+	if overlap.Load() != 0 {
+		t.Fatal("duties overlapped")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
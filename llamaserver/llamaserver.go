@@ -0,0 +1,309 @@
+// Package llamaserver runs a local llama.cpp llama-server for the agent. The
+// process is started before the first LLM request, health-checked while it
+// runs, and restarted when it crashes or stops answering.
+package llamaserver
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCommand        = "llama-server"
+	DefaultStartupTimeout = 2 * time.Minute
+	DefaultHealthInterval = 30 * time.Second
+
+	// unhealthyChecks failed health checks in a row get the process killed
+	// (and restarted).
+	unhealthyChecks = 3
+	stopGrace       = 5 * time.Second
+	maxBackoff      = time.Minute
+)
+
+type Options struct {
+	// Command is the llama-server binary. Default: "llama-server"
+	Command string
+	// Model is passed as -m; leave empty when Args selects the model.
+	Model string
+	// Listen is host:port; clawlet passes it as --host/--port.
+	Listen string
+	// Args are extra command-line arguments, e.g. ["-c", "8192"].
+	Args           []string
+	StartupTimeout time.Duration
+	HealthInterval time.Duration
+	// LogPath receives the process's output (appended). Empty discards it.
+	LogPath string
+}
+
+// Server is a managed llama-server process. A server that already answers
+// on Listen when clawlet needs one is used as is and never stopped.
+type Server struct {
+	opts   Options
+	health *http.Client
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	exited  chan struct{} // closed when cmd exits
+	ready   bool
+	closed  bool
+	adopted bool
+	stopMon context.CancelFunc
+}
+
+func New(opts Options) *Server {
+	if opts.Command == "" {
+		opts.Command = DefaultCommand
+	}
+	if opts.StartupTimeout <= 0 {
+		opts.StartupTimeout = DefaultStartupTimeout
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = DefaultHealthInterval
+	}
+	return &Server{opts: opts, health: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// BaseURL is the OpenAI-compatible endpoint of the server.
+func (s *Server) BaseURL() string {
+	return "http://" + s.opts.Listen + "/v1"
+}
+
+// Ensure starts the server if it is not running and waits until it is
+// healthy (llama-server answers /health with 503 while loading the model).
+func (s *Server) Ensure(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("llama-server: closed")
+	}
+	if s.ready {
+		return nil
+	}
+	switch {
+	case s.cmd == nil && s.healthy(ctx):
+		if !s.adopted {
+			log.Printf("llama-server: using the server already running on %s", s.opts.Listen)
+		}
+		s.adopted = true
+	case s.cmd == nil:
+		if err := s.startLocked(); err != nil {
+			return err
+		}
+		fallthrough
+	default:
+		if err := s.waitHealthyLocked(ctx); err != nil {
+			return err
+		}
+	}
+	s.ready = true
+	if s.stopMon == nil {
+		monCtx, cancel := context.WithCancel(context.Background())
+		s.stopMon = cancel
+		go s.monitor(monCtx)
+	}
+	return nil
+}
+
+// Close stops the process if clawlet started it.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	if s.stopMon != nil {
+		s.stopMon()
+	}
+	cmd, exited := s.cmd, s.exited
+	s.mu.Unlock()
+	if cmd == nil {
+		return nil
+	}
+	return stop(cmd, exited)
+}
+
+func (s *Server) startLocked() error {
+	host, port, err := net.SplitHostPort(s.opts.Listen)
+	if err != nil {
+		return fmt.Errorf("llama-server listen: %w", err)
+	}
+	var args []string
+	if s.opts.Model != "" {
+		args = append(args, "-m", s.opts.Model)
+	}
+	args = append(args, "--host", host, "--port", port)
+	args = append(args, s.opts.Args...)
+	var out io.WriteCloser = nopCloser{io.Discard}
+	if s.opts.LogPath != "" {
+		f, err := os.OpenFile(s.opts.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("llama-server log: %w", err)
+		}
+		out = f
+	}
+	cmd := exec.Command(s.opts.Command, args...)
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Start(); err != nil {
+		out.Close()
+		return fmt.Errorf("start llama-server: %w", err)
+	}
+	log.Printf("llama-server: started pid %d on %s", cmd.Process.Pid, s.opts.Listen)
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		out.Close()
+		close(exited)
+		s.mu.Lock()
+		if s.cmd == cmd {
+			s.ready = false
+		}
+		s.mu.Unlock()
+	}()
+	s.cmd, s.exited, s.ready = cmd, exited, false
+	return nil
+}
+
+func (s *Server) waitHealthyLocked(ctx context.Context) error {
+	deadline := time.NewTimer(s.opts.StartupTimeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(500 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		if s.healthy(ctx) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.exited:
+			s.cmd = nil
+			return fmt.Errorf("llama-server exited during startup (output: %s)", cmp.Or(s.opts.LogPath, "discarded"))
+		case <-deadline.C:
+			return fmt.Errorf("llama-server not healthy after %s", s.opts.StartupTimeout)
+		case <-tick.C:
+		}
+	}
+}
+
+// monitor restarts the process when it exits or fails unhealthyChecks
+// health checks in a row. Restarts after quick crashes back off.
+func (s *Server) monitor(ctx context.Context) {
+	failures := 0
+	backoff := time.Second
+	t := time.NewTicker(s.opts.HealthInterval)
+	defer t.Stop()
+	for {
+		s.mu.Lock()
+		exited, adopted := s.exited, s.adopted
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-exited:
+			log.Printf("llama-server: exited, restarting in %s", backoff)
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, maxBackoff)
+			s.restart(ctx, exited)
+			failures = 0
+			continue
+		case <-t.C:
+		}
+		if s.healthy(ctx) {
+			failures = 0
+			backoff = time.Second
+			continue
+		}
+		failures++
+		if failures < unhealthyChecks {
+			continue
+		}
+		failures = 0
+		if adopted {
+			// Not ours to restart; start our own on the next request.
+			log.Printf("llama-server: %s is not healthy", s.opts.Listen)
+			s.mu.Lock()
+			s.adopted, s.ready = false, false
+			s.mu.Unlock()
+			continue
+		}
+		log.Printf("llama-server: %d health checks failed, restarting", unhealthyChecks)
+		s.mu.Lock()
+		cmd := s.cmd
+		s.mu.Unlock()
+		if cmd != nil {
+			_ = stop(cmd, exited)
+		}
+	}
+}
+
+// restart replaces the process that closed exited, unless Ensure already
+// started another one.
+func (s *Server) restart(ctx context.Context, exited chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.exited != exited {
+		return
+	}
+	s.cmd, s.ready = nil, false
+	if err := s.startLocked(); err != nil {
+		log.Printf("llama-server: %v", err)
+		// Try again on the next request.
+		s.exited = nil
+		return
+	}
+	if err := s.waitHealthyLocked(ctx); err != nil {
+		log.Printf("llama-server: %v", err)
+		return
+	}
+	s.ready = true
+}
+
+func (s *Server) healthy(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+s.opts.Listen+"/health", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := s.health.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// stop asks the process to exit and kills it after stopGrace.
+func stop(cmd *exec.Cmd, exited <-chan struct{}) error {
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		return cmd.Process.Kill()
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(stopGrace):
+		return cmd.Process.Kill()
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
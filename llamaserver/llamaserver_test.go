@@ -0,0 +1,126 @@
+package llamaserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestMain doubles as a fake llama-server: /health answers 503 for a moment
+// (model loading) and then 200.
+func TestMain(m *testing.M) {
+	if os.Getenv("CLAWLET_FAKE_LLAMA_SERVER") == "1" {
+		fakeServer(os.Args[1:])
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func fakeServer(args []string) {
+	var host, port string
+	for i := 0; i+1 < len(args); i++ {
+		switch args[i] {
+		case "--host":
+			host = args[i+1]
+		case "--port":
+			port = args[i+1]
+		}
+	}
+	loaded := time.Now().Add(300 * time.Millisecond)
+	_ = http.ListenAndServe(net.JoinHostPort(host, port), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if time.Now().Before(loaded) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestServer_StartsAndRestartsAfterCrash(t *testing.T) {
+	t.Setenv("CLAWLET_FAKE_LLAMA_SERVER", "1")
+	addr := freeAddr(t)
+	s := New(Options{Command: os.Args[0], Listen: addr, StartupTimeout: 10 * time.Second, HealthInterval: time.Hour})
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	if err := s.Ensure(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s.BaseURL() != "http://"+addr+"/v1" {
+		t.Fatalf("base url=%q", s.BaseURL())
+	}
+	s.mu.Lock()
+	first := s.cmd
+	s.mu.Unlock()
+	if first == nil || s.adopted {
+		t.Fatal("expected a started process")
+	}
+
+	_ = first.Process.Kill()
+	for {
+		s.mu.Lock()
+		cmd, ready := s.cmd, s.ready
+		s.mu.Unlock()
+		if cmd != nil && cmd != first && ready {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("server was not restarted")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	if err := s.Ensure(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	exited := s.exited
+	s.mu.Unlock()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(10 * time.Second):
+		t.Fatal("process still running after Close")
+	}
+	if err := s.Ensure(ctx); err == nil {
+		t.Fatal("Ensure after Close should fail")
+	}
+}
+
+func TestServer_AdoptsRunningServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	s := New(Options{Command: "does-not-exist", Listen: srv.Listener.Addr().String(), HealthInterval: time.Hour})
+	defer s.Close()
+	if err := s.Ensure(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !s.adopted || s.cmd != nil {
+		t.Fatalf("adopted=%v cmd=%v", s.adopted, s.cmd)
+	}
+}
+
+func TestServer_StartFailure(t *testing.T) {
+	s := New(Options{Command: "clawlet-no-such-llama-server", Listen: freeAddr(t)})
+	defer s.Close()
+	if err := s.Ensure(context.Background()); err == nil {
+		t.Fatal("expected start error")
+	}
+}
@@ -10,20 +10,25 @@ import (
 	"strings"
 )
 
-const anthropicVersion = "2023-06-01"
+const (
+	anthropicVersion = "2023-06-01"
+	// anthropicWebSearchMaxUses caps server-side searches per request.
+	anthropicWebSearchMaxUses = 5
+)
 
-func (c *Client) chatAnthropic(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
-	endpoint := anthropicMessagesEndpoint(c.BaseURL)
+// anthropicRequest is the body of a Messages API request.
+type anthropicRequest struct {
+	Model       string          `json:"model"`
+	Messages    []anthropicMsg  `json:"messages"`
+	System      string          `json:"system,omitempty"`
+	Tools       []anthropicTool `json:"tools,omitempty"`
+	MaxTokens   int             `json:"max_tokens"`
+	Temperature *float64        `json:"temperature,omitempty"`
+}
 
+func (c *Client) anthropicRequestBody(messages []Message, tools []ToolDefinition) (anthropicRequest, error) {
 	anthropicMessages, systemText := toAnthropicMessages(messages)
-	reqBody := struct {
-		Model       string          `json:"model"`
-		Messages    []anthropicMsg  `json:"messages"`
-		System      string          `json:"system,omitempty"`
-		Tools       []anthropicTool `json:"tools,omitempty"`
-		MaxTokens   int             `json:"max_tokens"`
-		Temperature *float64        `json:"temperature,omitempty"`
-	}{
+	reqBody := anthropicRequest{
 		Model:       c.Model,
 		Messages:    anthropicMessages,
 		System:      systemText,
@@ -33,20 +38,38 @@ func (c *Client) chatAnthropic(ctx context.Context, messages []Message, tools []
 	if len(tools) > 0 {
 		converted, err := toAnthropicTools(tools)
 		if err != nil {
-			return nil, err
+			return anthropicRequest{}, err
 		}
 		reqBody.Tools = converted
 	}
+	if c.WebSearch {
+		reqBody.Tools = append(reqBody.Tools, anthropicTool{
+			Type:    "web_search_20250305",
+			Name:    "web_search",
+			MaxUses: anthropicWebSearchMaxUses,
+		})
+	}
+	return reqBody, nil
+}
 
-	b, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, err
+// anthropicDo sends a request to the Anthropic API and returns the body of
+// a successful response.
+func (c *Client) anthropicDo(ctx context.Context, method, endpoint string, payload any) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(b)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	if strings.TrimSpace(c.APIKey) != "" {
 		req.Header.Set("x-api-key", c.APIKey)
 	}
@@ -67,7 +90,23 @@ func (c *Client) chatAnthropic(ctx context.Context, messages []Message, tools []
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("llm http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
 	}
+	return body, nil
+}
+
+func (c *Client) chatAnthropic(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
+	reqBody, err := c.anthropicRequestBody(messages, tools)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.anthropicDo(ctx, http.MethodPost, anthropicMessagesEndpoint(c.BaseURL), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	return parseAnthropicMessage(body)
+}
 
+// parseAnthropicMessage reads a Messages API response.
+func parseAnthropicMessage(body []byte) (*ChatResult, error) {
 	var parsed struct {
 		Content []struct {
 			Type  string          `json:"type"`
@@ -75,6 +114,11 @@ func (c *Client) chatAnthropic(ctx context.Context, messages []Message, tools []
 			ID    string          `json:"id,omitempty"`
 			Name  string          `json:"name,omitempty"`
 			Input json.RawMessage `json:"input,omitempty"`
+			// Citations are set on text blocks that quote web search results.
+			Citations []struct {
+				URL   string `json:"url,omitempty"`
+				Title string `json:"title,omitempty"`
+			} `json:"citations,omitempty"`
 		} `json:"content"`
 	}
 	if err := json.Unmarshal(body, &parsed); err != nil {
@@ -86,11 +130,22 @@ func (c *Client) chatAnthropic(ctx context.Context, messages []Message, tools []
 
 	out := &ChatResult{}
 	var textParts []string
+	// After a web search the answer arrives as text fragments split at
+	// citations; they are joined back without separators.
+	searched, fragment := false, false
 	for i, part := range parsed.Content {
 		switch part.Type {
+		case "server_tool_use", "web_search_tool_result":
+			searched, fragment = true, false
 		case "text":
-			if strings.TrimSpace(part.Text) != "" {
+			for _, cit := range part.Citations {
+				out.addCitation(cit.Title, cit.URL)
+			}
+			if fragment && len(textParts) > 0 {
+				textParts[len(textParts)-1] += part.Text
+			} else if strings.TrimSpace(part.Text) != "" {
 				textParts = append(textParts, part.Text)
+				fragment = searched
 			}
 		case "tool_use":
 			toolID := strings.TrimSpace(part.ID)
@@ -135,7 +190,10 @@ type anthropicSource struct {
 }
 
 type anthropicTool struct {
+	// Type is set for server tools such as web search.
+	Type        string          `json:"type,omitempty"`
 	Name        string          `json:"name"`
+	MaxUses     int             `json:"max_uses,omitempty"`
 	Description string          `json:"description,omitempty"`
 	InputSchema json.RawMessage `json:"input_schema,omitempty"`
 }
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SynthesizeSpeech reads text aloud with an OpenAI-compatible
+// /audio/speech endpoint and returns Ogg/Opus audio, the format of voice
+// notes.
+func (c *Client) SynthesizeSpeech(ctx context.Context, model, voice, text string) ([]byte, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("speech text is empty")
+	}
+	if strings.TrimSpace(c.BaseURL) == "" {
+		return nil, fmt.Errorf("baseURL is empty for speech")
+	}
+	endpoint := strings.TrimRight(strings.TrimSpace(c.BaseURL), "/") + "/audio/speech"
+	body, err := json.Marshal(map[string]string{
+		"model":           model,
+		"voice":           voice,
+		"input":           text,
+		"response_format": "opus",
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if strings.TrimSpace(c.APIKey) != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	for k, v := range c.Headers {
+		if strings.TrimSpace(k) == "" {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	hc := c.HTTP
+	if hc == nil {
+		hc = &http.Client{Timeout: 120 * time.Second}
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 25<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("speech http %d: %s", resp.StatusCode, strings.TrimSpace(string(payload)))
+	}
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("speech response is empty")
+	}
+	return payload, nil
+}
@@ -0,0 +1,38 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSynthesizeSpeech(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/speech" {
+			t.Fatalf("path=%q", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Fatalf("authorization=%q", got)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body["model"] != "tts-1" || body["voice"] != "nova" || body["input"] != "Hello" || body["response_format"] != "opus" {
+			t.Fatalf("body=%v", body)
+		}
+		_, _ = w.Write([]byte("OggS"))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, APIKey: "test-key", HTTP: srv.Client()}
+	got, err := c.SynthesizeSpeech(context.Background(), "tts-1", "nova", "Hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "OggS" {
+		t.Fatalf("audio=%q", got)
+	}
+}
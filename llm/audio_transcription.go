@@ -86,9 +86,18 @@ func (c *Client) transcribeAudioOpenAICompatible(ctx context.Context, data []byt
 	if _, err := part.Write(data); err != nil {
 		return "", err
 	}
-	if err := writer.WriteField("model", defaultOpenAIAudioTranscriptionModel); err != nil {
+	model := strings.TrimSpace(c.TranscriptionModel)
+	if model == "" {
+		model = defaultOpenAIAudioTranscriptionModel
+	}
+	if err := writer.WriteField("model", model); err != nil {
 		return "", err
 	}
+	if lang := strings.TrimSpace(c.TranscriptionLanguage); lang != "" {
+		if err := writer.WriteField("language", lang); err != nil {
+			return "", err
+		}
+	}
 	if err := writer.Close(); err != nil {
 		return "", err
 	}
@@ -0,0 +1,285 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// batchCustomID names the single request of the batches clawlet submits.
+const batchCustomID = "clawlet-1"
+
+// SupportsBatch reports whether clawlet can use provider's batch API:
+// OpenAI's Batch API or Anthropic's Message Batches. Batched requests cost
+// half as much but may take up to 24 hours.
+func SupportsBatch(provider string) bool {
+	switch normalizeProvider(provider) {
+	case "", "openai", "anthropic":
+		return true
+	default:
+		return false
+	}
+}
+
+// SubmitBatch queues a chat request, without tools, on the provider's batch
+// API and returns the batch ID for BatchResult.
+func (c *Client) SubmitBatch(ctx context.Context, messages []Message) (string, error) {
+	if c.HTTP == nil {
+		c.HTTP = &http.Client{Timeout: 120 * time.Second}
+	}
+	switch normalizeProvider(c.Provider) {
+	case "", "openai":
+		return c.submitOpenAIBatch(ctx, messages)
+	case "anthropic":
+		return c.submitAnthropicBatch(ctx, messages)
+	default:
+		return "", fmt.Errorf("batch requests are unsupported for provider: %s", strings.TrimSpace(c.Provider))
+	}
+}
+
+// BatchResult returns the reply of a batch queued with SubmitBatch. done is
+// false while the batch is still running; a batch that failed, expired or
+// was canceled is done with an error. An error with done false is
+// transient, and the batch can be checked again.
+func (c *Client) BatchResult(ctx context.Context, id string) (_ *ChatResult, done bool, _ error) {
+	if c.HTTP == nil {
+		c.HTTP = &http.Client{Timeout: 120 * time.Second}
+	}
+	switch normalizeProvider(c.Provider) {
+	case "", "openai":
+		return c.openAIBatchResult(ctx, id)
+	case "anthropic":
+		return c.anthropicBatchResult(ctx, id)
+	default:
+		return nil, true, fmt.Errorf("batch requests are unsupported for provider: %s", strings.TrimSpace(c.Provider))
+	}
+}
+
+func (c *Client) submitAnthropicBatch(ctx context.Context, messages []Message) (string, error) {
+	params, err := c.anthropicRequestBody(messages, nil)
+	if err != nil {
+		return "", err
+	}
+	payload := map[string]any{
+		"requests": []map[string]any{{"custom_id": batchCustomID, "params": params}},
+	}
+	body, err := c.anthropicDo(ctx, http.MethodPost, anthropicMessagesEndpoint(c.BaseURL)+"/batches", payload)
+	if err != nil {
+		return "", err
+	}
+	var parsed struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil || parsed.ID == "" {
+		return "", fmt.Errorf("anthropic batch: unexpected response: %s", strings.TrimSpace(string(body)))
+	}
+	return parsed.ID, nil
+}
+
+func (c *Client) anthropicBatchResult(ctx context.Context, id string) (*ChatResult, bool, error) {
+	body, err := c.anthropicDo(ctx, http.MethodGet, anthropicMessagesEndpoint(c.BaseURL)+"/batches/"+id, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	var batch struct {
+		ProcessingStatus string `json:"processing_status"`
+		ResultsURL       string `json:"results_url"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, false, fmt.Errorf("parse anthropic batch: %w", err)
+	}
+	if batch.ProcessingStatus != "ended" {
+		return nil, false, nil
+	}
+	if batch.ResultsURL == "" {
+		return nil, true, errors.New("anthropic batch ended without results")
+	}
+	body, err = c.anthropicDo(ctx, http.MethodGet, batch.ResultsURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	for line := range strings.SplitSeq(string(body), "\n") {
+		var r struct {
+			CustomID string `json:"custom_id"`
+			Result   struct {
+				Type    string          `json:"type"`
+				Message json.RawMessage `json:"message"`
+				Error   struct {
+					Error struct {
+						Message string `json:"message"`
+					} `json:"error"`
+				} `json:"error"`
+			} `json:"result"`
+		}
+		if json.Unmarshal([]byte(line), &r) != nil || r.CustomID != batchCustomID {
+			continue
+		}
+		switch r.Result.Type {
+		case "succeeded":
+			res, err := parseAnthropicMessage(r.Result.Message)
+			return res, true, err
+		case "errored":
+			return nil, true, fmt.Errorf("anthropic batch request failed: %s", r.Result.Error.Error.Message)
+		default:
+			return nil, true, fmt.Errorf("anthropic batch request %s", r.Result.Type)
+		}
+	}
+	return nil, true, errors.New("anthropic batch results have no reply")
+}
+
+func (c *Client) submitOpenAIBatch(ctx context.Context, messages []Message) (string, error) {
+	base := strings.TrimRight(c.BaseURL, "/")
+	line, err := json.Marshal(map[string]any{
+		"custom_id": batchCustomID,
+		"method":    http.MethodPost,
+		"url":       "/v1/chat/completions",
+		"body":      c.openAIChatBody(messages, nil, false),
+	})
+	if err != nil {
+		return "", err
+	}
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	if err := writer.WriteField("purpose", "batch"); err != nil {
+		return "", err
+	}
+	part, err := writer.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(append(line, '\n')); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	body, err := c.openAIDo(ctx, http.MethodPost, base+"/files", writer.FormDataContentType(), &form)
+	if err != nil {
+		return "", err
+	}
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &file); err != nil || file.ID == "" {
+		return "", fmt.Errorf("openai batch: unexpected file response: %s", strings.TrimSpace(string(body)))
+	}
+
+	req, err := json.Marshal(map[string]string{
+		"input_file_id":     file.ID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	})
+	if err != nil {
+		return "", err
+	}
+	body, err = c.openAIDo(ctx, http.MethodPost, base+"/batches", "application/json", bytes.NewReader(req))
+	if err != nil {
+		return "", err
+	}
+	var batch struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil || batch.ID == "" {
+		return "", fmt.Errorf("openai batch: unexpected response: %s", strings.TrimSpace(string(body)))
+	}
+	return batch.ID, nil
+}
+
+func (c *Client) openAIBatchResult(ctx context.Context, id string) (*ChatResult, bool, error) {
+	base := strings.TrimRight(c.BaseURL, "/")
+	body, err := c.openAIDo(ctx, http.MethodGet, base+"/batches/"+id, "", nil)
+	if err != nil {
+		return nil, false, err
+	}
+	var batch struct {
+		Status       string `json:"status"`
+		OutputFileID string `json:"output_file_id"`
+		ErrorFileID  string `json:"error_file_id"`
+		Errors       struct {
+			Data []struct {
+				Message string `json:"message"`
+			} `json:"data"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, false, fmt.Errorf("parse openai batch: %w", err)
+	}
+	switch batch.Status {
+	case "completed":
+	case "failed", "expired", "cancelled":
+		msg := batch.Status
+		if len(batch.Errors.Data) > 0 {
+			msg += ": " + batch.Errors.Data[0].Message
+		}
+		return nil, true, fmt.Errorf("openai batch %s", msg)
+	default:
+		return nil, false, nil
+	}
+	fileID := batch.OutputFileID
+	if fileID == "" {
+		fileID = batch.ErrorFileID
+	}
+	if fileID == "" {
+		return nil, true, errors.New("openai batch completed without output")
+	}
+	body, err = c.openAIDo(ctx, http.MethodGet, base+"/files/"+fileID+"/content", "", nil)
+	if err != nil {
+		return nil, false, err
+	}
+	for line := range strings.SplitSeq(string(body), "\n") {
+		var r struct {
+			CustomID string `json:"custom_id"`
+			Response *struct {
+				StatusCode int             `json:"status_code"`
+				Body       json.RawMessage `json:"body"`
+			} `json:"response"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal([]byte(line), &r) != nil || r.CustomID != batchCustomID {
+			continue
+		}
+		switch {
+		case r.Error != nil:
+			return nil, true, fmt.Errorf("openai batch request failed: %s", r.Error.Message)
+		case r.Response == nil:
+			return nil, true, errors.New("openai batch request has no response")
+		case r.Response.StatusCode < 200 || r.Response.StatusCode >= 300:
+			return nil, true, fmt.Errorf("llm http %d: %s", r.Response.StatusCode, strings.TrimSpace(string(r.Response.Body)))
+		}
+		res, err := parseOpenAIChat(r.Response.Body)
+		return res, true, err
+	}
+	return nil, true, errors.New("openai batch output has no reply")
+}
+
+// openAIDo sends a request to an OpenAI endpoint and returns the body of a
+// successful response.
+func (c *Client) openAIDo(ctx context.Context, method, endpoint, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.setOpenAIHeaders(req)
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("llm http %d: %s", resp.StatusCode, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicBatch(t *testing.T) {
+	status := "in_progress"
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "k" {
+			t.Errorf("missing api key on %s", r.URL.Path)
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/batches":
+			var req struct {
+				Requests []struct {
+					CustomID string           `json:"custom_id"`
+					Params   anthropicRequest `json:"params"`
+				} `json:"requests"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if len(req.Requests) != 1 || req.Requests[0].Params.System != "sys" || len(req.Requests[0].Params.Tools) != 0 {
+				t.Errorf("batch request = %+v", req)
+			}
+			io.WriteString(w, `{"id":"msgbatch_1","processing_status":"in_progress"}`)
+		case r.URL.Path == "/v1/messages/batches/msgbatch_1":
+			io.WriteString(w, `{"id":"msgbatch_1","processing_status":"`+status+`","results_url":"`+srvURL+`/results"}`)
+		case r.URL.Path == "/results":
+			io.WriteString(w, `{"custom_id":"`+batchCustomID+`","result":{"type":"succeeded","message":{"content":[{"type":"text","text":"digest"}]}}}`+"\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+	c := &Client{Provider: "anthropic", BaseURL: srv.URL, APIKey: "k", Model: "m", HTTP: srv.Client()}
+	ctx := context.Background()
+
+	id, err := c.SubmitBatch(ctx, []Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "summarize"}})
+	if err != nil || id != "msgbatch_1" {
+		t.Fatalf("submit: %q %v", id, err)
+	}
+	if _, done, err := c.BatchResult(ctx, id); done || err != nil {
+		t.Fatalf("running batch: done=%v err=%v", done, err)
+	}
+	status = "ended"
+	res, done, err := c.BatchResult(ctx, id)
+	if !done || err != nil || res.Content != "digest" {
+		t.Fatalf("ended batch: %+v done=%v err=%v", res, done, err)
+	}
+}
+
+func TestOpenAIBatch(t *testing.T) {
+	status := "in_progress"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("missing api key on %s", r.URL.Path)
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/files":
+			if r.FormValue("purpose") != "batch" {
+				t.Errorf("purpose = %q", r.FormValue("purpose"))
+			}
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := io.ReadAll(f)
+			if !strings.Contains(string(b), `"url":"/v1/chat/completions"`) || !strings.Contains(string(b), `"summarize"`) {
+				t.Errorf("batch file = %s", b)
+			}
+			io.WriteString(w, `{"id":"file-in"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/batches":
+			var req map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req["input_file_id"] != "file-in" || req["completion_window"] != "24h" {
+				t.Errorf("batch request = %v", req)
+			}
+			io.WriteString(w, `{"id":"batch_1","status":"validating"}`)
+		case r.URL.Path == "/v1/batches/batch_1":
+			io.WriteString(w, `{"id":"batch_1","status":"`+status+`","output_file_id":"file-out"}`)
+		case r.URL.Path == "/v1/files/file-out/content":
+			io.WriteString(w, `{"custom_id":"`+batchCustomID+`","response":{"status_code":200,"body":{"choices":[{"message":{"content":"digest"}}]}},"error":null}`+"\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := &Client{Provider: "openai", BaseURL: srv.URL + "/v1", APIKey: "k", Model: "m", HTTP: srv.Client()}
+	ctx := context.Background()
+
+	id, err := c.SubmitBatch(ctx, []Message{{Role: "user", Content: "summarize"}})
+	if err != nil || id != "batch_1" {
+		t.Fatalf("submit: %q %v", id, err)
+	}
+	if _, done, err := c.BatchResult(ctx, id); done || err != nil {
+		t.Fatalf("running batch: done=%v err=%v", done, err)
+	}
+	status = "completed"
+	res, done, err := c.BatchResult(ctx, id)
+	if !done || err != nil || res.Content != "digest" {
+		t.Fatalf("completed batch: %+v done=%v err=%v", res, done, err)
+	}
+	status = "expired"
+	if _, done, err := c.BatchResult(ctx, id); !done || err == nil {
+		t.Fatalf("expired batch: done=%v err=%v", done, err)
+	}
+}
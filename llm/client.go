@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,6 +19,28 @@ type Client struct {
 	Temperature *float64
 	Headers     map[string]string
 	HTTP        HTTPDoer
+	// WebSearch turns on the provider's own web search tool, see
+	// SupportsWebSearch.
+	WebSearch bool
+	// TranscriptionModel and TranscriptionLanguage are sent to
+	// OpenAI-compatible /audio/transcriptions endpoints; an empty model is
+	// gpt-4o-mini-transcribe.
+	TranscriptionModel    string
+	TranscriptionLanguage string
+
+	// Ollama only. KeepAlive is how long the model stays loaded after a
+	// request ("30m", "-1" for always, "0" to unload); setting it switches to
+	// the native /api/chat endpoint. AutoPull pulls a missing model before
+	// the first request, reporting to PullProgress.
+	KeepAlive    string
+	AutoPull     bool
+	PullProgress func(PullProgress)
+
+	// Prepare runs before each chat request, e.g. to start a local server.
+	Prepare func(ctx context.Context) error
+
+	pullMu sync.Mutex
+	pulled bool
 }
 
 type HTTPDoer interface {
@@ -33,16 +56,52 @@ type ToolCall struct {
 type ChatResult struct {
 	Content   string
 	ToolCalls []ToolCall
+	// Citations are the pages a provider-native web search drew on.
+	Citations []Citation
+}
+
+// Citation is a web page cited by the provider.
+type Citation struct {
+	Title string
+	URL   string
 }
 
 func (r ChatResult) HasToolCalls() bool { return len(r.ToolCalls) > 0 }
 
+func (r *ChatResult) addCitation(title, url string) {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return
+	}
+	for _, c := range r.Citations {
+		if c.URL == url {
+			return
+		}
+	}
+	r.Citations = append(r.Citations, Citation{Title: strings.TrimSpace(title), URL: url})
+}
+
 func (c *Client) Chat(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
 	if c.HTTP == nil {
 		c.HTTP = &http.Client{Timeout: 120 * time.Second}
 	}
+	if c.Prepare != nil {
+		if err := c.Prepare(ctx); err != nil {
+			return nil, err
+		}
+	}
 	switch normalizeProvider(c.Provider) {
-	case "", "openai", "openrouter", "ollama":
+	case "", "openai", "openrouter":
+		return c.chatOpenAICompatible(ctx, messages, tools)
+	case "ollama":
+		if c.AutoPull {
+			if err := c.ensureOllamaModel(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if strings.TrimSpace(c.KeepAlive) != "" {
+			return c.chatOllama(ctx, messages, tools)
+		}
 		return c.chatOpenAICompatible(ctx, messages, tools)
 	case "anthropic":
 		return c.chatAnthropic(ctx, messages, tools)
@@ -55,6 +114,18 @@ func (c *Client) Chat(ctx context.Context, messages []Message, tools []ToolDefin
 	}
 }
 
+// SupportsWebSearch reports whether clawlet can turn on provider's native
+// web search: OpenAI web_search_options, Anthropic web search, Gemini
+// grounding with Google Search, or the Codex web_search tool.
+func SupportsWebSearch(provider string) bool {
+	switch normalizeProvider(provider) {
+	case "", "openai", "anthropic", "gemini", "openai-codex":
+		return true
+	default:
+		return false
+	}
+}
+
 func normalizeProvider(p string) string {
 	switch strings.ToLower(strings.TrimSpace(p)) {
 	case "local":
@@ -38,6 +38,9 @@ func (c *Client) chatGemini(ctx context.Context, messages []Message, tools []Too
 		}
 		reqBody.Tools = converted
 	}
+	if c.WebSearch {
+		reqBody.Tools = append(reqBody.Tools, geminiTool{GoogleSearch: &struct{}{}})
+	}
 	reqBody.GenerationConfig.MaxOutputTokens = c.maxTokensValue()
 	reqBody.GenerationConfig.Temperature = c.temperatureValue()
 
@@ -81,6 +84,14 @@ func (c *Client) chatGemini(ctx context.Context, messages []Message, tools []Too
 					} `json:"functionCall,omitempty"`
 				} `json:"parts"`
 			} `json:"content"`
+			GroundingMetadata struct {
+				GroundingChunks []struct {
+					Web *struct {
+						URI   string `json:"uri"`
+						Title string `json:"title"`
+					} `json:"web,omitempty"`
+				} `json:"groundingChunks"`
+			} `json:"groundingMetadata"`
 		} `json:"candidates"`
 		PromptFeedback struct {
 			BlockReason string `json:"blockReason,omitempty"`
@@ -117,6 +128,11 @@ func (c *Client) chatGemini(ctx context.Context, messages []Message, tools []Too
 		}
 	}
 	out.Content = strings.Join(textParts, "\n")
+	for _, chunk := range parsed.Candidates[0].GroundingMetadata.GroundingChunks {
+		if chunk.Web != nil {
+			out.addCitation(chunk.Web.Title, chunk.Web.URI)
+		}
+	}
 	return out, nil
 }
 
@@ -148,7 +164,8 @@ type geminiFunctionResponse struct {
 }
 
 type geminiTool struct {
-	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations,omitempty"`
+	GoogleSearch         *struct{}                   `json:"google_search,omitempty"`
 }
 
 type geminiFunctionDeclaration struct {
@@ -0,0 +1,337 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ollama talks to Ollama's native API (/api/...) for model management.
+// BaseURL may be the OpenAI-compatible URL; a trailing /v1 is ignored.
+type Ollama struct {
+	BaseURL string
+	Headers map[string]string
+	// HTTP must not time out requests on its own: pulls take minutes.
+	HTTP HTTPDoer
+}
+
+// OllamaModel is an installed model as reported by /api/tags.
+type OllamaModel struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// PullProgress is one status line of a model pull. Total and Completed are
+// set while a layer downloads.
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+var errOllamaModelMissing = errors.New("ollama: model not found")
+
+func ollamaRoot(baseURL string) string {
+	u := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	u = strings.TrimSuffix(u, "/v1")
+	if u == "" {
+		return "http://localhost:11434"
+	}
+	return u
+}
+
+// Has reports whether model is installed.
+func (o *Ollama) Has(ctx context.Context, model string) (bool, error) {
+	resp, err := o.post(ctx, "/api/show", map[string]any{"model": model})
+	if errors.Is(err, errOllamaModelMissing) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// Pull downloads model, calling progress (if set) for every status line.
+func (o *Ollama) Pull(ctx context.Context, model string, progress func(PullProgress)) error {
+	resp, err := o.post(ctx, "/api/pull", map[string]any{"model": model, "stream": true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	last := ""
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var p PullProgress
+		if err := json.Unmarshal(line, &p); err != nil {
+			return fmt.Errorf("ollama pull: %w", err)
+		}
+		if p.Error != "" {
+			return fmt.Errorf("ollama pull %s: %s", model, p.Error)
+		}
+		last = p.Status
+		if progress != nil {
+			progress(p)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("ollama pull: %w", err)
+	}
+	if last != "success" {
+		return fmt.Errorf("ollama pull %s: ended without success", model)
+	}
+	return nil
+}
+
+// Delete removes model.
+func (o *Ollama) Delete(ctx context.Context, model string) error {
+	resp, err := o.do(ctx, http.MethodDelete, "/api/delete", map[string]any{"model": model})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List returns the installed models.
+func (o *Ollama) List(ctx context.Context) ([]OllamaModel, error) {
+	resp, err := o.do(ctx, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var parsed struct {
+		Models []OllamaModel `json:"models"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("ollama tags: %w", err)
+	}
+	return parsed.Models, nil
+}
+
+func (o *Ollama) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	return o.do(ctx, http.MethodPost, path, body)
+}
+
+// do sends the request and returns the response if it succeeded; the caller
+// closes the body.
+func (o *Ollama) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, ollamaRoot(o.BaseURL)+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range o.Headers {
+		if strings.TrimSpace(k) == "" {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	hc := o.HTTP
+	if hc == nil {
+		hc = &http.Client{}
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errOllamaModelMissing
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return nil, fmt.Errorf("ollama http %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+}
+
+// ensureOllamaModel pulls the model the first time it is needed. A failed
+// check is retried on the next call.
+func (c *Client) ensureOllamaModel(ctx context.Context) error {
+	c.pullMu.Lock()
+	defer c.pullMu.Unlock()
+	if c.pulled {
+		return nil
+	}
+	o := &Ollama{BaseURL: c.BaseURL, Headers: c.Headers}
+	has, err := o.Has(ctx, c.Model)
+	if err != nil {
+		return err
+	}
+	if !has {
+		if err := o.Pull(ctx, c.Model, c.PullProgress); err != nil {
+			return err
+		}
+	}
+	c.pulled = true
+	return nil
+}
+
+// chatOllama uses the native /api/chat endpoint, which (unlike the
+// OpenAI-compatible one) honours keep_alive.
+func (c *Client) chatOllama(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
+	reqBody := struct {
+		Model     string           `json:"model"`
+		Messages  []ollamaMessage  `json:"messages"`
+		Tools     []ToolDefinition `json:"tools,omitempty"`
+		Stream    bool             `json:"stream"`
+		KeepAlive any              `json:"keep_alive,omitempty"`
+		Options   struct {
+			NumPredict  int      `json:"num_predict,omitempty"`
+			Temperature *float64 `json:"temperature,omitempty"`
+		} `json:"options"`
+	}{
+		Model:     c.Model,
+		Messages:  toOllamaMessages(messages),
+		Tools:     tools,
+		KeepAlive: ollamaKeepAlive(c.KeepAlive),
+	}
+	reqBody.Options.NumPredict = c.maxTokensValue()
+	reqBody.Options.Temperature = c.temperatureValue()
+	b, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ollamaRoot(c.BaseURL)+"/api/chat", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Headers {
+		if strings.TrimSpace(k) == "" {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("llm http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var parsed struct {
+		Message struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				ID       string `json:"id"`
+				Function struct {
+					Name      string          `json:"name"`
+					Arguments json.RawMessage `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("parse llm response: %w", err)
+	}
+	out := &ChatResult{Content: parsed.Message.Content}
+	for i, tc := range parsed.Message.ToolCalls {
+		id := tc.ID
+		if id == "" {
+			// Older Ollama versions do not number tool calls.
+			id = "call_" + strconv.Itoa(i+1)
+		}
+		out.ToolCalls = append(out.ToolCalls, ToolCall{
+			ID:        id,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+	return out, nil
+}
+
+// ollamaKeepAlive sends plain numbers as seconds and anything else
+// ("30m", "1h") as a duration string.
+func ollamaKeepAlive(s string) any {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return s
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+func toOllamaMessages(messages []Message) []ollamaMessage {
+	out := make([]ollamaMessage, 0, len(messages))
+	for _, m := range messages {
+		item := ollamaMessage{Role: m.Role, Content: m.Content}
+		if m.Role == "tool" {
+			item.ToolName = m.Name
+		}
+		var texts []string
+		for _, part := range m.Parts {
+			switch part.Type {
+			case ContentPartTypeText:
+				if strings.TrimSpace(part.Text) != "" {
+					texts = append(texts, part.Text)
+				}
+			case ContentPartTypeImage:
+				if data := strings.TrimSpace(part.Data); data != "" {
+					item.Images = append(item.Images, data)
+				}
+			}
+		}
+		if len(texts) > 0 {
+			item.Content = strings.Join(texts, "\n")
+		}
+		for _, tc := range m.ToolCalls {
+			var call ollamaToolCall
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = json.RawMessage(tc.Function.Arguments)
+			if !json.Valid(call.Function.Arguments) {
+				call.Function.Arguments = json.RawMessage("{}")
+			}
+			item.ToolCalls = append(item.ToolCalls, call)
+		}
+		out = append(out, item)
+	}
+	return out
+}
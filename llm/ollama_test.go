@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOllamaRoot(t *testing.T) {
+	for in, want := range map[string]string{
+		"http://localhost:11434/v1": "http://localhost:11434",
+		"http://gpu:11434/v1/":      "http://gpu:11434",
+		"http://gpu:11434":          "http://gpu:11434",
+		"":                          "http://localhost:11434",
+	} {
+		if got := ollamaRoot(in); got != want {
+			t.Fatalf("ollamaRoot(%q)=%q", in, got)
+		}
+	}
+}
+
+func TestChat_OllamaAutoPullAndKeepAlive(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	var chatBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/api/show":
+			http.Error(w, `{"error":"model 'qwen3' not found"}`, http.StatusNotFound)
+		case "/api/pull":
+			_, _ = io.WriteString(w, `{"status":"pulling manifest"}
+{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":50}
+{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":100}
+{"status":"success"}
+`)
+		case "/api/chat":
+			_ = json.NewDecoder(r.Body).Decode(&chatBody)
+			_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"read_file","arguments":{"path":"a.txt"}}}]},"done":true}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var progress []PullProgress
+	c := &Client{
+		Provider:     "ollama",
+		BaseURL:      srv.URL + "/v1",
+		Model:        "qwen3",
+		KeepAlive:    "-1",
+		AutoPull:     true,
+		PullProgress: func(p PullProgress) { progress = append(progress, p) },
+	}
+	msgs := []Message{
+		{Role: "user", Content: "read it"},
+		{Role: "assistant", ToolCalls: []ToolCallPayload{{ID: "call_1", Type: "function", Function: ToolCallPayloadFunc{Name: "read_file", Arguments: `{"path":"b.txt"}`}}}},
+		{Role: "tool", ToolCallID: "call_1", Name: "read_file", Content: "hello"},
+	}
+	for range 2 {
+		res, err := c.Chat(context.Background(), msgs, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.ToolCalls) != 1 || res.ToolCalls[0].ID != "call_1" || string(res.ToolCalls[0].Arguments) != `{"path":"a.txt"}` {
+			t.Fatalf("tool calls=%+v", res.ToolCalls)
+		}
+	}
+	if got := strings.Join(calls, ","); got != "/api/show,/api/pull,/api/chat,/api/chat" {
+		t.Fatalf("calls=%s", got)
+	}
+	if len(progress) != 4 || progress[2].Completed != 100 {
+		t.Fatalf("progress=%+v", progress)
+	}
+	if chatBody["keep_alive"] != float64(-1) || chatBody["stream"] != false {
+		t.Fatalf("body=%v", chatBody)
+	}
+	sent := chatBody["messages"].([]any)
+	if tool := sent[2].(map[string]any); tool["tool_name"] != "read_file" {
+		t.Fatalf("tool message=%v", tool)
+	}
+	call := sent[1].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)["function"].(map[string]any)
+	if args := call["arguments"].(map[string]any); args["path"] != "b.txt" {
+		t.Fatalf("tool call arguments=%v", call)
+	}
+}
+
+func TestOllama_PullError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"status":"pulling manifest"}
+{"error":"pull model manifest: file does not exist"}
+`)
+	}))
+	defer srv.Close()
+	o := &Ollama{BaseURL: srv.URL}
+	if err := o.Pull(context.Background(), "nope", nil); err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Fatalf("err=%v", err)
+	}
+}
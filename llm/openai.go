@@ -12,45 +12,10 @@ import (
 )
 
 func (c *Client) chatOpenAICompatible(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
-	endpoint := strings.TrimRight(c.BaseURL, "/") + "/chat/completions"
-
-	type chatRequest struct {
-		Model       string           `json:"model"`
-		Messages    []openAIMessage  `json:"messages"`
-		MaxTokens   int              `json:"max_tokens,omitempty"`
-		Temperature *float64         `json:"temperature,omitempty"`
-		Tools       []ToolDefinition `json:"tools,omitempty"`
-		ToolChoice  string           `json:"tool_choice,omitempty"`
-	}
-	reqBody := chatRequest{
-		Model:       c.Model,
-		Messages:    toOpenAIMessages(messages),
-		MaxTokens:   c.maxTokensValue(),
-		Temperature: c.temperatureValue(),
-	}
-	if len(tools) > 0 {
-		reqBody.Tools = tools
-		reqBody.ToolChoice = "auto"
-	}
-	b, err := json.Marshal(reqBody)
+	req, err := c.openAIRequest(ctx, messages, tools, false)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if strings.TrimSpace(c.APIKey) != "" {
-		req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	}
-	for k, v := range c.Headers {
-		if strings.TrimSpace(k) == "" {
-			continue
-		}
-		req.Header.Set(k, v)
-	}
-
 	hc := c.HTTP
 	if hc == nil {
 		hc = &http.Client{Timeout: 120 * time.Second}
@@ -64,7 +29,11 @@ func (c *Client) chatOpenAICompatible(ctx context.Context, messages []Message, t
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("llm http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
 	}
+	return parseOpenAIChat(body)
+}
 
+// parseOpenAIChat reads a chat/completions response.
+func parseOpenAIChat(body []byte) (*ChatResult, error) {
 	var parsed struct {
 		Choices []struct {
 			Message struct {
@@ -77,6 +46,7 @@ func (c *Client) chatOpenAICompatible(ctx context.Context, messages []Message, t
 						Arguments json.RawMessage `json:"arguments"`
 					} `json:"function"`
 				} `json:"tool_calls"`
+				Annotations []openAIAnnotation `json:"annotations"`
 			} `json:"message"`
 		} `json:"choices"`
 	}
@@ -88,25 +58,106 @@ func (c *Client) chatOpenAICompatible(ctx context.Context, messages []Message, t
 	}
 	m := parsed.Choices[0].Message
 	out := &ChatResult{Content: m.Content}
+	addOpenAICitations(out, m.Annotations)
 	for _, tc := range m.ToolCalls {
-		args := tc.Function.Arguments
-		// OpenAI-compatible servers typically return arguments as a JSON string.
-		// Convert it to raw JSON bytes so downstream tools can unmarshal into structs.
-		if len(args) > 0 && args[0] == '"' {
-			var s string
-			if err := json.Unmarshal(args, &s); err == nil {
-				args = []byte(s)
-			}
-		}
 		out.ToolCalls = append(out.ToolCalls, ToolCall{
 			ID:        tc.ID,
 			Name:      tc.Function.Name,
-			Arguments: args,
+			Arguments: openAIArguments(tc.Function.Arguments),
 		})
 	}
 	return out, nil
 }
 
+type openAIAnnotation struct {
+	Type        string `json:"type"`
+	URLCitation struct {
+		URL   string `json:"url"`
+		Title string `json:"title"`
+	} `json:"url_citation"`
+}
+
+func addOpenAICitations(out *ChatResult, annotations []openAIAnnotation) {
+	for _, a := range annotations {
+		if a.Type == "url_citation" {
+			out.addCitation(a.URLCitation.Title, a.URLCitation.URL)
+		}
+	}
+}
+
+// openAIArguments returns tool call arguments as raw JSON.
+// OpenAI-compatible servers typically return arguments as a JSON string.
+// Convert it to raw JSON bytes so downstream tools can unmarshal into structs.
+func openAIArguments(args json.RawMessage) json.RawMessage {
+	if len(args) > 0 && args[0] == '"' {
+		var s string
+		if err := json.Unmarshal(args, &s); err == nil {
+			return []byte(s)
+		}
+	}
+	return args
+}
+
+// openAIRequest builds a chat/completions request; stream asks for
+// server-sent events.
+func (c *Client) openAIRequest(ctx context.Context, messages []Message, tools []ToolDefinition, stream bool) (*http.Request, error) {
+	endpoint := strings.TrimRight(c.BaseURL, "/") + "/chat/completions"
+	b, err := json.Marshal(c.openAIChatBody(messages, tools, stream))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setOpenAIHeaders(req)
+	return req, nil
+}
+
+// openAIChatRequest is the body of a chat/completions request.
+type openAIChatRequest struct {
+	Model       string           `json:"model"`
+	Messages    []openAIMessage  `json:"messages"`
+	MaxTokens   int              `json:"max_tokens,omitempty"`
+	Temperature *float64         `json:"temperature,omitempty"`
+	Tools       []ToolDefinition `json:"tools,omitempty"`
+	ToolChoice  string           `json:"tool_choice,omitempty"`
+	// WebSearchOptions needs a search model, e.g. gpt-4o-search-preview.
+	WebSearchOptions *struct{} `json:"web_search_options,omitempty"`
+	Stream           bool      `json:"stream,omitempty"`
+}
+
+func (c *Client) openAIChatBody(messages []Message, tools []ToolDefinition, stream bool) openAIChatRequest {
+	reqBody := openAIChatRequest{
+		Model:       c.Model,
+		Messages:    toOpenAIMessages(messages),
+		MaxTokens:   c.maxTokensValue(),
+		Temperature: c.temperatureValue(),
+		Stream:      stream,
+	}
+	if len(tools) > 0 {
+		reqBody.Tools = tools
+		reqBody.ToolChoice = "auto"
+	}
+	if c.WebSearch && SupportsWebSearch(c.Provider) {
+		reqBody.WebSearchOptions = &struct{}{}
+	}
+	return reqBody
+}
+
+func (c *Client) setOpenAIHeaders(req *http.Request) {
+	if strings.TrimSpace(c.APIKey) != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	for k, v := range c.Headers {
+		if strings.TrimSpace(k) == "" {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+}
+
 type openAIMessage struct {
 	Role       string            `json:"role"`
 	Content    *openAIContent    `json:"content,omitempty"`
@@ -39,9 +39,9 @@ type codexTextConfig struct {
 
 type codexTool struct {
 	Type        string          `json:"type"`
-	Name        string          `json:"name"`
+	Name        string          `json:"name,omitempty"`
 	Description string          `json:"description,omitempty"`
-	Parameters  json.RawMessage `json:"parameters"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
 }
 
 type codexInputItem struct {
@@ -95,6 +95,9 @@ func (c *Client) chatOpenAICodex(ctx context.Context, messages []Message, tools
 		}
 		reqBody.Tools = convertedTools
 	}
+	if c.WebSearch {
+		reqBody.Tools = append(reqBody.Tools, codexTool{Type: "web_search"})
+	}
 
 	b, err := json.Marshal(reqBody)
 	if err != nil {
@@ -145,6 +148,11 @@ type codexSSEEvent struct {
 		Name      string          `json:"name"`
 		Arguments json.RawMessage `json:"arguments"`
 	} `json:"item"`
+	Annotation struct {
+		Type  string `json:"type"`
+		URL   string `json:"url"`
+		Title string `json:"title"`
+	} `json:"annotation"`
 }
 
 type codexToolCallBuffer struct {
@@ -204,6 +212,10 @@ func handleCodexSSEData(data string, out *ChatResult, buffers map[string]*codexT
 	switch evt.Type {
 	case "response.output_text.delta":
 		out.Content += evt.Delta
+	case "response.output_text.annotation.added":
+		if evt.Annotation.Type == "url_citation" {
+			out.addCitation(evt.Annotation.Title, evt.Annotation.URL)
+		}
 	case "response.output_item.added":
 		if evt.Item.Type != "function_call" {
 			return nil
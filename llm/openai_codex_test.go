@@ -109,6 +109,28 @@ func TestConsumeCodexSSE_ToolCall(t *testing.T) {
 	}
 }
 
+func TestConsumeCodexSSE_WebSearchCitations(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"type":"response.output_item.added","item":{"type":"web_search_call","id":"ws_1"}}`,
+		"",
+		`data: {"type":"response.output_text.delta","delta":"Go 1.26 is out."}`,
+		"",
+		`data: {"type":"response.output_text.annotation.added","annotation":{"type":"url_citation","url":"https://go.dev/doc/go1.26","title":"Go 1.26 Release Notes"}}`,
+		"",
+	}, "\n")
+
+	out, err := consumeCodexSSE(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if out.Content != "Go 1.26 is out." || len(out.ToolCalls) != 0 {
+		t.Fatalf("out=%+v", out)
+	}
+	if len(out.Citations) != 1 || out.Citations[0].URL != "https://go.dev/doc/go1.26" {
+		t.Fatalf("citations=%+v", out.Citations)
+	}
+}
+
 func TestParseAuthorizationInput(t *testing.T) {
 	code, state := parseAuthorizationInput("http://localhost:1455/auth/callback?code=abc&state=xyz")
 	if code != "abc" || state != "xyz" {
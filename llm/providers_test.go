@@ -1,6 +1,10 @@
 package llm
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -192,3 +196,79 @@ func TestToGeminiMessages_ImagePart(t *testing.T) {
 		t.Fatalf("inline data=%q", converted[0].Parts[1].InlineData.Data)
 	}
 }
+
+type captureDoer struct {
+	body     []byte
+	response string
+}
+
+func (d *captureDoer) Do(req *http.Request) (*http.Response, error) {
+	d.body, _ = io.ReadAll(req.Body)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(d.response)),
+	}, nil
+}
+
+func TestChat_WebSearch(t *testing.T) {
+	tests := []struct {
+		provider string
+		inBody   string
+		response string
+		content  string
+		cites    []string
+	}{
+		{
+			provider: "openai",
+			inBody:   `"web_search_options":{}`,
+			response: `{"choices":[{"message":{"content":"Sunny in Tokyo.","annotations":[{"type":"url_citation","url_citation":{"url":"https://weather.example/tokyo","title":"Tokyo weather"}}]}}]}`,
+			content:  "Sunny in Tokyo.",
+			cites:    []string{"https://weather.example/tokyo"},
+		},
+		{
+			provider: "anthropic",
+			inBody:   `{"type":"web_search_20250305","name":"web_search","max_uses":5}`,
+			response: `{"content":[
+				{"type":"text","text":"Let me check."},
+				{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{"query":"tokyo weather"}},
+				{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[]},
+				{"type":"text","text":"It is "},
+				{"type":"text","text":"sunny","citations":[{"type":"web_search_result_location","url":"https://weather.example/tokyo","title":"Tokyo weather"}]},
+				{"type":"text","text":" in Tokyo."}]}`,
+			content: "Let me check.\nIt is sunny in Tokyo.",
+			cites:   []string{"https://weather.example/tokyo"},
+		},
+		{
+			provider: "gemini",
+			inBody:   `{"google_search":{}}`,
+			response: `{"candidates":[{"content":{"parts":[{"text":"Sunny in Tokyo."}]},"groundingMetadata":{"groundingChunks":[{"web":{"uri":"https://vertexaisearch.example/r/1","title":"weather.example"}},{"web":{"uri":"https://vertexaisearch.example/r/1","title":"weather.example"}}]}}]}`,
+			content:  "Sunny in Tokyo.",
+			cites:    []string{"https://vertexaisearch.example/r/1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.provider, func(t *testing.T) {
+			d := &captureDoer{response: tc.response}
+			c := &Client{Provider: tc.provider, BaseURL: "https://llm.example", Model: "m", HTTP: d, WebSearch: true}
+			tools := []ToolDefinition{{Type: "function", Function: FunctionDefinition{Name: "read_file", Parameters: JSONSchema{Type: "object"}}}}
+			res, err := c.Chat(context.Background(), []Message{{Role: "user", Content: "weather in tokyo?"}}, tools)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(d.body), tc.inBody) {
+				t.Fatalf("request %s lacks %s", d.body, tc.inBody)
+			}
+			if res.Content != tc.content {
+				t.Fatalf("content=%q", res.Content)
+			}
+			var got []string
+			for _, c := range res.Citations {
+				got = append(got, c.URL)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.cites, ",") {
+				t.Fatalf("citations=%v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ChatStream is Chat calling onText with each piece of reply text as it
+// arrives. OpenAI-compatible providers stream; the others answer in one
+// piece, which is passed to onText once.
+func (c *Client) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, onText func(string)) (*ChatResult, error) {
+	if onText == nil || !c.streams() {
+		res, err := c.Chat(ctx, messages, tools)
+		if err == nil && onText != nil && res.Content != "" {
+			onText(res.Content)
+		}
+		return res, err
+	}
+	if c.HTTP == nil {
+		c.HTTP = &http.Client{Timeout: 120 * time.Second}
+	}
+	if c.Prepare != nil {
+		if err := c.Prepare(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if normalizeProvider(c.Provider) == "ollama" && c.AutoPull {
+		if err := c.ensureOllamaModel(ctx); err != nil {
+			return nil, err
+		}
+	}
+	req, err := c.openAIRequest(ctx, messages, tools, true)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+		return nil, fmt.Errorf("llm http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return consumeOpenAISSE(resp.Body, onText)
+}
+
+// streams reports whether Chat goes through the OpenAI-compatible endpoint,
+// the one ChatStream can stream from.
+func (c *Client) streams() bool {
+	switch normalizeProvider(c.Provider) {
+	case "", "openai", "openrouter":
+		return true
+	case "ollama":
+		return strings.TrimSpace(c.KeepAlive) == ""
+	default:
+		return false
+	}
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+			Annotations []openAIAnnotation `json:"annotations"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// consumeOpenAISSE assembles a chat/completions event stream; tool calls
+// arrive in pieces keyed by index.
+func consumeOpenAISSE(r io.Reader, onText func(string)) (*ChatResult, error) {
+	out := &ChatResult{}
+	var content strings.Builder
+	type callBuffer struct {
+		id, name string
+		args     strings.Builder
+	}
+	var calls []*callBuffer
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 2<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		data = strings.TrimSpace(data)
+		if !ok || data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			// Ignore non-JSON chunks.
+			continue
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("llm stream: %s", chunk.Error.Message)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		d := chunk.Choices[0].Delta
+		if d.Content != "" {
+			content.WriteString(d.Content)
+			onText(d.Content)
+		}
+		addOpenAICitations(out, d.Annotations)
+		for _, tc := range d.ToolCalls {
+			for len(calls) <= tc.Index {
+				calls = append(calls, &callBuffer{})
+			}
+			buf := calls[tc.Index]
+			if tc.ID != "" {
+				buf.id = tc.ID
+			}
+			if tc.Function.Name != "" {
+				buf.name = tc.Function.Name
+			}
+			buf.args.WriteString(tc.Function.Arguments)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	out.Content = content.String()
+	for _, buf := range calls {
+		if buf.name == "" {
+			continue
+		}
+		args := json.RawMessage(buf.args.String())
+		if len(args) == 0 {
+			args = json.RawMessage("{}")
+		}
+		out.ToolCalls = append(out.ToolCalls, ToolCall{ID: buf.id, Name: buf.name, Arguments: args})
+	}
+	return out, nil
+}
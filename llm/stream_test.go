@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestChatStream(t *testing.T) {
+	sse := strings.Join([]string{
+		`data: {"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+		`data: {"choices":[{"delta":{"content":"lo"}}]}`,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"read_file","arguments":"{\"pa"}}]}}]}`,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\":\"a\"}"}}]}}]}`,
+		`data: [DONE]`,
+	}, "\n\n")
+	d := &captureDoer{response: sse}
+	c := &Client{Provider: "openai", BaseURL: "https://llm.example", Model: "m", HTTP: d}
+	var pieces []string
+	res, err := c.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, func(s string) {
+		pieces = append(pieces, s)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(d.body), `"stream":true`) {
+		t.Fatalf("request not streamed: %s", d.body)
+	}
+	if strings.Join(pieces, "|") != "Hel|lo" || res.Content != "Hello" {
+		t.Fatalf("pieces=%q content=%q", pieces, res.Content)
+	}
+	if len(res.ToolCalls) != 1 || res.ToolCalls[0].ID != "call_1" || string(res.ToolCalls[0].Arguments) != `{"path":"a"}` {
+		t.Fatalf("tool calls: %+v", res.ToolCalls)
+	}
+
+	// Providers without streaming pass the whole reply once.
+	d = &captureDoer{response: `{"candidates":[{"content":{"parts":[{"text":"Hi there."}]}}]}`}
+	c = &Client{Provider: "gemini", BaseURL: "https://llm.example", Model: "m", HTTP: d}
+	pieces = nil
+	if _, err := c.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, func(s string) {
+		pieces = append(pieces, s)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(pieces) != 1 || pieces[0] != "Hi there." {
+		t.Fatalf("gemini pieces=%q", pieces)
+	}
+}
@@ -0,0 +1,153 @@
+// Package maintenance does the housekeeping of a long-running gateway:
+// removing temp files left by interrupted downloads, deleting sessions idle
+// past a retention period, and compacting the SQLite store and the memory
+// search index.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/atrest"
+	"github.com/mosaxiv/clawlet/session"
+	"github.com/mosaxiv/clawlet/storage"
+)
+
+// tempPrefix matches the temp files clawlet creates (skill downloads).
+const tempPrefix = "clawlet-"
+
+type Options struct {
+	// Sessions is where sessions are kept; nil skips sessions and vacuum.
+	Sessions storage.Store
+	// SessionMaxAge deletes sessions not updated for this long; 0 keeps
+	// them.
+	SessionMaxAge time.Duration
+	// Index is the memory search index; nil skips it.
+	Index Vacuumer
+	// TempDir is searched for stale clawlet temp files; empty skips them.
+	TempDir    string
+	TempMaxAge time.Duration
+	DryRun     bool
+	Now        time.Time
+}
+
+type Report struct {
+	DryRun    bool
+	TempFiles []string
+	Sessions  []string // storage keys
+	Vacuumed  bool
+	// IndexVacuumed is set when the memory search index was compacted.
+	IndexVacuumed bool
+}
+
+func (r Report) Summary() string {
+	verb := "removed"
+	if r.DryRun {
+		verb = "would remove"
+	}
+	s := fmt.Sprintf("maintenance: %s %d temp files and %d idle sessions", verb, len(r.TempFiles), len(r.Sessions))
+	if r.Vacuumed {
+		s += "; vacuumed the database"
+	}
+	if r.IndexVacuumed {
+		s += "; vacuumed the memory index"
+	}
+	return s
+}
+
+// Vacuumer is implemented by stores that can compact themselves.
+type Vacuumer interface {
+	Vacuum() error
+}
+
+// Run does one maintenance pass. It carries on past a failing step and
+// returns the errors joined.
+func Run(opts Options) (Report, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	rep := Report{DryRun: opts.DryRun}
+	var errs []error
+	if opts.TempDir != "" && opts.TempMaxAge > 0 {
+		files, err := cleanTemp(opts.TempDir, opts.Now.Add(-opts.TempMaxAge), opts.DryRun)
+		rep.TempFiles = files
+		errs = append(errs, err)
+	}
+	if opts.Sessions != nil && opts.SessionMaxAge > 0 {
+		keys, err := pruneSessions(opts.Sessions, opts.Now.Add(-opts.SessionMaxAge), opts.DryRun)
+		rep.Sessions = keys
+		errs = append(errs, err)
+	}
+	if v, ok := opts.Sessions.(Vacuumer); ok && !opts.DryRun {
+		err := v.Vacuum()
+		rep.Vacuumed = err == nil
+		errs = append(errs, err)
+	}
+	if opts.Index != nil && !opts.DryRun {
+		err := opts.Index.Vacuum()
+		rep.IndexVacuumed = err == nil
+		errs = append(errs, err)
+	}
+	return rep, errors.Join(errs...)
+}
+
+func cleanTemp(dir string, before time.Time, dryRun bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), tempPrefix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || !info.ModTime().Before(before) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if !dryRun {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return out, err
+			}
+		}
+		out = append(out, path)
+	}
+	return out, nil
+}
+
+func pruneSessions(st storage.Store, before time.Time, dryRun bool) ([]string, error) {
+	keys, err := st.List(storage.NamespaceSessions)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, key := range keys {
+		b, err := st.Get(storage.NamespaceSessions, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return out, err
+		}
+		if b, err = atrest.Decode(b); err != nil {
+			// Unreadable with the current key; leave it alone.
+			continue
+		}
+		updated, ok := session.StoredUpdatedAt(b)
+		if !ok || !updated.Before(before) {
+			continue
+		}
+		if !dryRun {
+			if err := st.Delete(storage.NamespaceSessions, key); err != nil {
+				return out, err
+			}
+		}
+		out = append(out, key)
+	}
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/storage"
+)
+
+func TestRun(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tmp := t.TempDir()
+	for name, age := range map[string]time.Duration{
+		"clawlet-skill-1.zip": 48 * time.Hour,
+		"clawlet-skill-2.zip": time.Hour,
+		"other.zip":           48 * time.Hour,
+	} {
+		p := filepath.Join(tmp, name)
+		if err := os.WriteFile(p, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st := storage.NewFiles(map[string]string{storage.NamespaceSessions: t.TempDir()})
+	put := func(key string, updated time.Time) {
+		line := `{"_type":"metadata","created_at":"","updated_at":"` + updated.Format(time.RFC3339Nano) + `"}` + "\n"
+		if err := st.Put(storage.NamespaceSessions, key, []byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("old.jsonl", now.AddDate(0, 0, -40))
+	put("recent.jsonl", now.AddDate(0, 0, -2))
+	if err := st.Put(storage.NamespaceSessions, "broken.jsonl", []byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{
+		Sessions:      st,
+		SessionMaxAge: 30 * 24 * time.Hour,
+		TempDir:       tmp,
+		TempMaxAge:    24 * time.Hour,
+		DryRun:        true,
+		Now:           now,
+	}
+	rep, err := Run(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(rep.TempFiles, []string{filepath.Join(tmp, "clawlet-skill-1.zip")}) || !slices.Equal(rep.Sessions, []string{"old.jsonl"}) {
+		t.Fatalf("dry run report: %+v", rep)
+	}
+	if _, err := os.Stat(rep.TempFiles[0]); err != nil {
+		t.Fatalf("dry run removed a file: %v", err)
+	}
+
+	opts.DryRun = false
+	if _, err := Run(opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "clawlet-skill-1.zip")); !os.IsNotExist(err) {
+		t.Fatalf("stale temp file kept: %v", err)
+	}
+	keys, err := st.List(storage.NamespaceSessions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"broken.jsonl", "recent.jsonl"}) {
+		t.Fatalf("sessions left: %v", keys)
+	}
+}
@@ -26,7 +26,9 @@ type PreparedInbound struct {
 	SessionText string
 }
 
-func PrepareInbound(ctx context.Context, client *llm.Client, cfg config.MediaToolsConfig, inbound bus.InboundMessage) (PreparedInbound, error) {
+// PrepareInbound builds the user message from an inbound message and its
+// attachments. Audio is transcribed with tx when it is set.
+func PrepareInbound(ctx context.Context, client *llm.Client, tx Transcriber, cfg config.MediaToolsConfig, inbound bus.InboundMessage) (PreparedInbound, error) {
 	baseText := strings.TrimSpace(inbound.Content)
 	prepared := PreparedInbound{
 		UserMessage: llm.Message{Role: "user", Content: baseText},
@@ -91,10 +93,10 @@ func PrepareInbound(ctx context.Context, client *llm.Client, cfg config.MediaToo
 			}
 		case "audio":
 			handledAudio := false
-			if cfg.AudioEnabledValue() && client.SupportsAudioTranscription() {
+			if cfg.AudioEnabledValue() && tx != nil {
 				data, mimeType, err := readAttachmentBytes(ctx, att, cfg.MaxFileBytes, cfg.DownloadTimeoutSec)
 				if err == nil && len(data) > 0 {
-					transcript, txErr := client.TranscribeAudio(ctx, data, mimeType, name)
+					transcript, txErr := tx.TranscribeAudio(ctx, data, mimeType, name)
 					if txErr == nil && strings.TrimSpace(transcript) != "" {
 						textSections = append(textSections, fmt.Sprintf("[Audio transcript: %s]\n%s", name, strings.TrimSpace(transcript)))
 						handledAudio = true
@@ -34,7 +34,7 @@ func TestPrepareInbound_ImagePart(t *testing.T) {
 	}
 	client := &llm.Client{Provider: "openai", Model: "gpt-4o-mini"}
 
-	got, err := PrepareInbound(context.Background(), client, cfg, inbound)
+	got, err := PrepareInbound(context.Background(), client, nil, cfg, inbound)
 	if err != nil {
 		t.Fatalf("PrepareInbound error: %v", err)
 	}
@@ -82,7 +82,7 @@ func TestPrepareInbound_AudioTranscription(t *testing.T) {
 		HTTP:     srv.Client(),
 	}
 
-	got, err := PrepareInbound(context.Background(), client, cfg, inbound)
+	got, err := PrepareInbound(context.Background(), client, client, cfg, inbound)
 	if err != nil {
 		t.Fatalf("PrepareInbound error: %v", err)
 	}
@@ -113,7 +113,7 @@ func TestPrepareInbound_TextAttachment(t *testing.T) {
 	}
 	client := &llm.Client{Provider: "openai", Model: "gpt-4o-mini"}
 
-	got, err := PrepareInbound(context.Background(), client, cfg, inbound)
+	got, err := PrepareInbound(context.Background(), client, nil, cfg, inbound)
 	if err != nil {
 		t.Fatalf("PrepareInbound error: %v", err)
 	}
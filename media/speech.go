@@ -0,0 +1,143 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+// Speaker reads text aloud as a voice note.
+type Speaker interface {
+	Speak(ctx context.Context, text string) (bus.Attachment, error)
+}
+
+// NewSpeaker returns the speaker cfg selects; nil when no provider is set.
+func NewSpeaker(cfg config.SpeechConfig) (Speaker, error) {
+	timeout := time.Duration(cfg.TimeoutSecValue()) * time.Second
+	switch strings.TrimSpace(cfg.Provider) {
+	case "":
+		return nil, nil
+	case config.SpeechOpenAI:
+		return &openAISpeech{
+			client: &llm.Client{
+				BaseURL: cfg.BaseURLValue(),
+				APIKey:  strings.TrimSpace(cfg.APIKey),
+				HTTP:    &http.Client{Timeout: timeout},
+			},
+			model: cfg.ModelValue(),
+			voice: cfg.VoiceValue(),
+		}, nil
+	case config.SpeechCommand:
+		if strings.TrimSpace(cfg.Command) == "" {
+			return nil, errors.New("tools.media.speech.command is empty")
+		}
+		if !slices.ContainsFunc(cfg.Args, func(a string) bool { return strings.Contains(a, outputPlaceholder) }) {
+			return nil, fmt.Errorf("tools.media.speech.args must name the audio file as %q", outputPlaceholder)
+		}
+		return &commandSpeech{
+			command: strings.TrimSpace(cfg.Command),
+			args:    cfg.Args,
+			ffmpeg:  cfg.FFmpegValue(),
+			timeout: timeout,
+			run:     runCommandInput,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown speech provider %q (use %q or %q)", cfg.Provider, config.SpeechOpenAI, config.SpeechCommand)
+	}
+}
+
+// VoiceNote wraps Ogg/Opus audio as an attachment that channels send as a
+// voice note.
+func VoiceNote(data []byte) bus.Attachment {
+	return bus.Attachment{Name: "voice.ogg", MIMEType: "audio/ogg", Kind: "audio", SizeBytes: int64(len(data)), Data: data}
+}
+
+type openAISpeech struct {
+	client *llm.Client
+	model  string
+	voice  string
+}
+
+func (s *openAISpeech) Speak(ctx context.Context, text string) (bus.Attachment, error) {
+	data, err := s.client.SynthesizeSpeech(ctx, s.model, s.voice, text)
+	if err != nil {
+		return bus.Attachment{}, err
+	}
+	return VoiceNote(data), nil
+}
+
+// outputPlaceholder in a speech command's args is replaced with the path
+// of the audio file to write.
+const outputPlaceholder = "{output}"
+
+// commandSpeech runs a local text-to-speech program, which usually writes
+// WAV, and converts its output to Ogg/Opus with ffmpeg.
+type commandSpeech struct {
+	command string
+	args    []string
+	ffmpeg  string
+	timeout time.Duration
+	run     func(ctx context.Context, stdin, name string, args ...string) ([]byte, error)
+}
+
+func (s *commandSpeech) Speak(ctx context.Context, text string) (bus.Attachment, error) {
+	if strings.TrimSpace(text) == "" {
+		return bus.Attachment{}, errors.New("speech text is empty")
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	dir, err := os.MkdirTemp("", "clawlet-speech-")
+	if err != nil {
+		return bus.Attachment{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	audio := filepath.Join(dir, "speech.wav")
+	args := make([]string, len(s.args))
+	for i, a := range s.args {
+		args[i] = strings.ReplaceAll(a, outputPlaceholder, audio)
+	}
+	if _, err := s.run(ctx, text, s.command, args...); err != nil {
+		return bus.Attachment{}, fmt.Errorf("speech command: %w", err)
+	}
+	voice := filepath.Join(dir, "voice.ogg")
+	if _, err := s.run(ctx, "", s.ffmpeg, "-nostdin", "-loglevel", "error", "-i", audio, "-ac", "1", "-c:a", "libopus", "-b:a", "32k", voice); err != nil {
+		return bus.Attachment{}, fmt.Errorf("convert speech: %w", err)
+	}
+	data, err := os.ReadFile(voice)
+	if err != nil {
+		return bus.Attachment{}, err
+	}
+	return VoiceNote(data), nil
+}
+
+var (
+	speechCodeBlockRe = regexp.MustCompile("(?s)```.*?```")
+	speechLinkRe      = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`)
+	speechMarkupRe    = regexp.MustCompile("[*_`#>]+")
+)
+
+// SpeechText prepares a reply for reading aloud: code blocks are dropped,
+// links keep their text and Markdown marks are removed. It returns "" when
+// nothing is left or the text is longer than maxChars.
+func SpeechText(reply string, maxChars int) string {
+	text := speechCodeBlockRe.ReplaceAllString(reply, " ")
+	text = speechLinkRe.ReplaceAllString(text, "$1")
+	text = speechMarkupRe.ReplaceAllString(text, "")
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" || len([]rune(text)) > maxChars {
+		return ""
+	}
+	return text
+}
@@ -0,0 +1,69 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestNewSpeaker(t *testing.T) {
+	if s, err := NewSpeaker(config.SpeechConfig{}); err != nil || s != nil {
+		t.Fatalf("no provider: %v, %v", s, err)
+	}
+	if _, err := NewSpeaker(config.SpeechConfig{Provider: config.SpeechCommand, Command: "piper"}); err == nil {
+		t.Fatal("command without {output} should fail")
+	}
+	if _, err := NewSpeaker(config.SpeechConfig{Provider: "siri"}); err == nil {
+		t.Fatal("unknown provider should fail")
+	}
+}
+
+func TestCommandSpeech(t *testing.T) {
+	var stdin string
+	var calls [][]string
+	s := &commandSpeech{
+		command: "piper", args: []string{"--model", "en.onnx", "--output_file", "{output}"}, ffmpeg: "ffmpeg",
+		timeout: 5 * time.Second,
+		run: func(ctx context.Context, in, name string, args ...string) ([]byte, error) {
+			calls = append(calls, append([]string{name}, args...))
+			if name == "piper" {
+				stdin = in
+			} else {
+				return nil, os.WriteFile(args[len(args)-1], []byte("OggS"), 0o600)
+			}
+			return nil, nil
+		},
+	}
+	got, err := s.Speak(context.Background(), "Hello there")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got.Data) != "OggS" || got.MIMEType != "audio/ogg" || got.Kind != "audio" {
+		t.Fatalf("attachment: %+v", got)
+	}
+	if stdin != "Hello there" || len(calls) != 2 {
+		t.Fatalf("stdin=%q calls=%v", stdin, calls)
+	}
+	wav := calls[0][len(calls[0])-1]
+	if filepath.Base(wav) != "speech.wav" || !strings.Contains(strings.Join(calls[1], " "), "-i "+wav) {
+		t.Fatalf("calls=%v", calls)
+	}
+}
+
+func TestSpeechText(t *testing.T) {
+	reply := "**Sure!** See [the docs](https://example.com).\n\n```go\nfmt.Println()\n```\n# Done"
+	if got := SpeechText(reply, 100); got != "Sure! See the docs. Done" {
+		t.Fatalf("got %q", got)
+	}
+	if got := SpeechText(reply, 10); got != "" {
+		t.Fatalf("too long: %q", got)
+	}
+	if got := SpeechText("```\ncode\n```", 100); got != "" {
+		t.Fatalf("code only: %q", got)
+	}
+}
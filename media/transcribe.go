@@ -0,0 +1,141 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+// Transcriber turns an audio attachment into text. *llm.Client is one.
+type Transcriber interface {
+	TranscribeAudio(ctx context.Context, data []byte, mimeType, fileName string) (string, error)
+}
+
+// NewTranscriber returns the transcriber cfg selects. With no provider the
+// chat client transcribes when its provider can; nil means audio stays an
+// attachment note.
+func NewTranscriber(cfg config.TranscriptionConfig, client *llm.Client) (Transcriber, error) {
+	timeout := time.Duration(cfg.TimeoutSecValue()) * time.Second
+	switch strings.TrimSpace(cfg.Provider) {
+	case "":
+		if client == nil || !client.SupportsAudioTranscription() {
+			return nil, nil
+		}
+		return client, nil
+	case config.TranscriptionWhisper:
+		return &llm.Client{
+			Provider:              "openai",
+			BaseURL:               cfg.BaseURLValue(),
+			APIKey:                strings.TrimSpace(cfg.APIKey),
+			TranscriptionModel:    cfg.ModelValue(),
+			TranscriptionLanguage: strings.TrimSpace(cfg.Language),
+			HTTP:                  &http.Client{Timeout: timeout},
+		}, nil
+	case config.TranscriptionWhisperCpp:
+		if cfg.ModelValue() == "" {
+			return nil, errors.New("tools.media.transcription.model must point at a whisper.cpp model file")
+		}
+		return &whisperCpp{
+			command:  cfg.CommandValue(),
+			ffmpeg:   cfg.FFmpegValue(),
+			model:    cfg.ModelValue(),
+			language: strings.TrimSpace(cfg.Language),
+			timeout:  timeout,
+			run:      runCommand,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown transcription provider %q (use %q or %q)", cfg.Provider, config.TranscriptionWhisper, config.TranscriptionWhisperCpp)
+	}
+}
+
+// whisperCpp transcribes with a local whisper.cpp build. whisper-cli reads
+// 16 kHz WAV, so other formats (such as Telegram and WhatsApp voice notes)
+// are converted with ffmpeg first.
+type whisperCpp struct {
+	command  string
+	ffmpeg   string
+	model    string
+	language string
+	timeout  time.Duration
+	run      func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+func (w *whisperCpp) TranscribeAudio(ctx context.Context, data []byte, mimeType, fileName string) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("audio data is empty")
+	}
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	dir, err := os.MkdirTemp("", "clawlet-whisper-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input"+filepath.Ext(fileName))
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return "", err
+	}
+	wav := input
+	if !isWAV(mimeType, fileName) {
+		wav = filepath.Join(dir, "audio.wav")
+		if _, err := w.run(ctx, w.ffmpeg, "-nostdin", "-loglevel", "error", "-i", input, "-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le", wav); err != nil {
+			return "", fmt.Errorf("convert audio: %w", err)
+		}
+	}
+	args := []string{"-m", w.model, "-f", wav, "-nt", "-np"}
+	if w.language != "" {
+		args = append(args, "-l", w.language)
+	}
+	out, err := w.run(ctx, w.command, args...)
+	if err != nil {
+		return "", fmt.Errorf("whisper.cpp: %w", err)
+	}
+	text := strings.Join(strings.Fields(string(out)), " ")
+	if text == "" {
+		return "", errors.New("whisper.cpp returned no text")
+	}
+	return text, nil
+}
+
+func isWAV(mimeType, fileName string) bool {
+	switch strings.ToLower(strings.TrimSpace(mimeType)) {
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return true
+	}
+	return strings.EqualFold(filepath.Ext(fileName), ".wav")
+}
+
+// runCommand runs name and returns its stdout; a failure carries stderr.
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return runCommandInput(ctx, "", name, args...)
+}
+
+// runCommandInput is runCommand with stdin.
+func runCommandInput(ctx context.Context, stdin, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			if len(msg) > 500 {
+				msg = msg[:500]
+			}
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,96 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+func TestNewTranscriber(t *testing.T) {
+	chat := &llm.Client{Provider: "anthropic"}
+	if tx, err := NewTranscriber(config.TranscriptionConfig{}, chat); err != nil || tx != nil {
+		t.Fatalf("anthropic chat provider: %v, %v", tx, err)
+	}
+	chat = &llm.Client{Provider: "openai"}
+	if tx, err := NewTranscriber(config.TranscriptionConfig{}, chat); err != nil || tx != Transcriber(chat) {
+		t.Fatalf("openai chat provider: %v, %v", tx, err)
+	}
+	if _, err := NewTranscriber(config.TranscriptionConfig{Provider: config.TranscriptionWhisperCpp}, chat); err == nil {
+		t.Fatal("whisper.cpp without a model should fail")
+	}
+	if _, err := NewTranscriber(config.TranscriptionConfig{Provider: "siri"}, chat); err == nil {
+		t.Fatal("unknown provider should fail")
+	}
+}
+
+func TestWhisperAPITranscriber(t *testing.T) {
+	var model, lang string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer sk-w" {
+			t.Errorf("path=%q auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		model, lang = r.FormValue("model"), r.FormValue("language")
+		_ = json.NewEncoder(w).Encode(map[string]string{"text": "hello there"})
+	}))
+	defer srv.Close()
+
+	tx, err := NewTranscriber(config.TranscriptionConfig{
+		Provider: config.TranscriptionWhisper,
+		BaseURL:  srv.URL + "/v1",
+		APIKey:   "sk-w",
+		Language: "en",
+	}, &llm.Client{Provider: "anthropic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tx.TranscribeAudio(context.Background(), []byte("OggS"), "audio/ogg", "voice.ogg")
+	if err != nil || got != "hello there" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if model != config.DefaultTranscriptionWhisperModel || lang != "en" {
+		t.Fatalf("model=%q language=%q", model, lang)
+	}
+}
+
+func TestWhisperCppTranscriber(t *testing.T) {
+	var calls [][]string
+	w := &whisperCpp{
+		command: "whisper-cli", ffmpeg: "ffmpeg", model: "/models/base.bin", language: "de",
+		timeout: 5 * time.Second,
+		run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			calls = append(calls, append([]string{name}, args...))
+			if name == "whisper-cli" {
+				return []byte("\n Guten Tag,\n wie geht's?\n"), nil
+			}
+			return nil, nil
+		},
+	}
+	got, err := w.TranscribeAudio(context.Background(), []byte("OggS"), "audio/ogg", "voice.ogg")
+	if err != nil || got != "Guten Tag, wie geht's?" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if len(calls) != 2 || calls[0][0] != "ffmpeg" || !strings.HasSuffix(calls[0][len(calls[0])-1], "audio.wav") {
+		t.Fatalf("calls=%v", calls)
+	}
+	whisper := calls[1]
+	if !slices.Contains(whisper, "/models/base.bin") || !slices.Contains(whisper, "de") || !strings.HasSuffix(whisper[slices.Index(whisper, "-f")+1], "audio.wav") {
+		t.Fatalf("whisper args=%v", whisper)
+	}
+
+	// WAV goes to whisper-cli as is.
+	calls = nil
+	if _, err := w.TranscribeAudio(context.Background(), []byte("RIFF"), "audio/wav", "note.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0][0] != "whisper-cli" {
+		t.Fatalf("calls=%v", calls)
+	}
+}
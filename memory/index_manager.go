@@ -165,6 +165,34 @@ type openAIEmbeddingProvider struct {
 	client   *http.Client
 }
 
+// Embedder embeds texts as normalized vectors.
+type Embedder interface {
+	EmbedBatch(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// NewEmbedder returns a client for the embedding model configured under
+// memorySearch, whether or not memory search itself is enabled.
+func NewEmbedder(cfg *config.Config) (Embedder, error) {
+	if cfg == nil {
+		return nil, errors.New("config is nil")
+	}
+	resolved, err := resolveSearchConfig(cfg, "")
+	if err != nil {
+		return nil, err
+	}
+	if resolved.model == "" {
+		return nil, errors.New("agents.defaults.memorySearch.model is required for embeddings")
+	}
+	return &openAIEmbeddingProvider{
+		provider: resolved.provider,
+		baseURL:  strings.TrimRight(resolved.baseURL, "/"),
+		apiKey:   resolved.apiKey,
+		model:    resolved.model,
+		headers:  copyHeaders(resolved.headers),
+		client:   &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
 func NewIndexManager(cfg *config.Config, workspace string) (*IndexManager, error) {
 	if cfg == nil {
 		return nil, errors.New("config is nil")
@@ -231,6 +259,17 @@ func (m *IndexManager) Close() error {
 	return m.db.Close()
 }
 
+// Vacuum compacts the index database after chunks have been replaced.
+func (m *IndexManager) Vacuum() error {
+	if m == nil {
+		return errors.New("memory manager is nil")
+	}
+	m.dbMu.Lock()
+	defer m.dbMu.Unlock()
+	_, err := m.db.Exec(`VACUUM`)
+	return err
+}
+
 func (m *IndexManager) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
 	if m == nil {
 		return nil, errors.New("memory manager is nil")
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Journal keeps the daily note (memory/YYYY-MM-DD.md) in a fixed structure:
+// a new note starts from the template, and entries are appended under its
+// "## " sections.
+type Journal struct {
+	Store    *Store
+	Sections []string
+	// Template is the text of a new daily note; "{{date}}" is replaced with
+	// the date. Empty means a title plus one heading per section.
+	Template string
+}
+
+// TemplateSections returns the "## " headings of a daily-note template.
+func TemplateSections(tmpl string) []string {
+	var out []string
+	for line := range strings.SplitSeq(tmpl, "\n") {
+		if h, ok := strings.CutPrefix(strings.TrimSpace(line), "## "); ok {
+			if h = strings.TrimSpace(h); h != "" && !slices.Contains(out, h) {
+				out = append(out, h)
+			}
+		}
+	}
+	return out
+}
+
+// DailyPath is the note for the day of t.
+func (s *Store) DailyPath(t time.Time) string {
+	return filepath.Join(s.Dir, t.Format("2006-01-02")+".md")
+}
+
+// Append adds entry under section of the note for now, creating the note
+// from the template first. Entries in a "Tasks" section become checklist
+// items; others are prefixed with the time. It returns the note's
+// workspace-relative path.
+func (j *Journal) Append(now time.Time, section, entry string) (string, error) {
+	entry = strings.Join(strings.Fields(entry), " ")
+	if entry == "" {
+		return "", fmt.Errorf("entry is empty")
+	}
+	heading := j.section(section)
+	if heading == "" {
+		return "", fmt.Errorf("unknown section %q (sections: %s)", section, strings.Join(j.Sections, ", "))
+	}
+	if err := j.Store.EnsureInitialized(); err != nil {
+		return "", err
+	}
+	path := j.Store.DailyPath(now)
+	b, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		b = []byte(j.render(now))
+	case err != nil:
+		return "", err
+	}
+	line := "- " + now.Format("15:04") + " " + entry
+	if strings.EqualFold(heading, "Tasks") {
+		line = "- [ ] " + entry
+	}
+	if err := os.WriteFile(path, []byte(insertUnder(string(b), heading, line)), 0o644); err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(j.Store.Workspace, path)
+	if err != nil {
+		return path, nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// section matches name against the configured sections, ignoring case.
+func (j *Journal) section(name string) string {
+	name = strings.TrimSpace(name)
+	for _, s := range j.Sections {
+		if strings.EqualFold(s, name) {
+			return s
+		}
+	}
+	return ""
+}
+
+func (j *Journal) render(now time.Time) string {
+	date := now.Format("2006-01-02")
+	if strings.TrimSpace(j.Template) != "" {
+		return strings.TrimRight(strings.ReplaceAll(j.Template, "{{date}}", date), "\n") + "\n"
+	}
+	var b strings.Builder
+	b.WriteString("# " + date + "\n")
+	for _, s := range j.Sections {
+		b.WriteString("\n## " + s + "\n")
+	}
+	return b.String()
+}
+
+// insertUnder appends line at the end of the "## heading" section, adding
+// the section at the end of the note when it is missing.
+func insertUnder(note, heading, line string) string {
+	lines := strings.Split(strings.TrimRight(note, "\n"), "\n")
+	start := -1
+	for i, l := range lines {
+		if h, ok := strings.CutPrefix(strings.TrimSpace(l), "## "); ok && strings.EqualFold(strings.TrimSpace(h), heading) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return strings.Join(lines, "\n") + "\n\n## " + heading + "\n" + line + "\n"
+	}
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if l := strings.TrimSpace(lines[i]); strings.HasPrefix(l, "# ") || strings.HasPrefix(l, "## ") {
+			end = i
+			break
+		}
+	}
+	// Insert after the section's last non-blank line.
+	at := end
+	for at > start+1 && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	out := make([]string, 0, len(lines)+2)
+	out = append(out, lines[:at]...)
+	out = append(out, line)
+	if at < len(lines) {
+		out = append(out, "")
+		for _, l := range lines[at:] {
+			if len(out) > 0 && out[len(out)-1] == "" && strings.TrimSpace(l) == "" {
+				continue
+			}
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n") + "\n"
+}
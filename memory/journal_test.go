@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestJournalAppend(t *testing.T) {
+	s := New(t.TempDir())
+	j := &Journal{Store: s, Sections: []string{"Tasks", "Conversations", "Decisions"}}
+	now := time.Date(2026, 10, 16, 9, 5, 0, 0, time.Local)
+
+	rel, err := j.Append(now, "decisions", "Use Postgres for the\n  new service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel != "memory/2026-10-16.md" {
+		t.Fatalf("rel=%q", rel)
+	}
+	if _, err := j.Append(now, "Tasks", "Book flights"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Append(now.Add(time.Hour), "Tasks", "Renew passport"); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(s.DailyPath(now))
+	want := `# 2026-10-16
+
+## Tasks
+- [ ] Book flights
+- [ ] Renew passport
+
+## Conversations
+
+## Decisions
+- 09:05 Use Postgres for the new service
+`
+	if string(b) != want {
+		t.Fatalf("note:\n%s\nwant:\n%s", b, want)
+	}
+
+	if _, err := j.Append(now, "Ideas", "x"); err == nil {
+		t.Fatal("expected unknown section error")
+	}
+	if _, err := j.Append(now, "Tasks", "  "); err == nil {
+		t.Fatal("expected empty entry error")
+	}
+}
+
+func TestJournalAppend_TemplateAndFreeformNote(t *testing.T) {
+	s := New(t.TempDir())
+	tmpl := "# Journal {{date}}\n\n## Log\n\n## Follow-ups\n- (none yet)\n"
+	j := &Journal{Store: s, Sections: TemplateSections(tmpl), Template: tmpl}
+	if len(j.Sections) != 2 || j.Sections[1] != "Follow-ups" {
+		t.Fatalf("sections=%v", j.Sections)
+	}
+	now := time.Date(2026, 10, 16, 18, 30, 0, 0, time.Local)
+	if _, err := j.Append(now, "follow-ups", "Call Ana"); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(s.DailyPath(now))
+	want := "# Journal 2026-10-16\n\n## Log\n\n## Follow-ups\n- (none yet)\n- 18:30 Call Ana\n"
+	if string(b) != want {
+		t.Fatalf("note:\n%q", b)
+	}
+
+	// A note written before the journal was enabled gets the section added.
+	next := now.Add(24 * time.Hour)
+	if err := os.WriteFile(s.DailyPath(next), []byte("# 2026-10-17\n\nfree text\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Append(next, "Log", "Shipped v2"); err != nil {
+		t.Fatal(err)
+	}
+	b, _ = os.ReadFile(s.DailyPath(next))
+	if string(b) != "# 2026-10-17\n\nfree text\n\n## Log\n- 18:30 Shipped v2\n" {
+		t.Fatalf("note:\n%q", b)
+	}
+}
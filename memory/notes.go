@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// NotesDir holds explicit conversation snapshots, relative to the memory dir.
+const NotesDir = "notes"
+
+const maxNoteTranscriptChars = 32 << 10
+
+type NoteMessage struct {
+	Role    string
+	Content string
+}
+
+// Note is an explicitly saved memory entry (see /remember).
+type Note struct {
+	Title      string
+	Tags       []string
+	Source     string // session key the snapshot came from
+	Summary    string
+	Transcript []NoteMessage
+}
+
+// SaveNote writes n as a Markdown file under memory/notes/ and returns its
+// workspace-relative path. Notes are picked up by memory search indexing.
+func (s *Store) SaveNote(n Note, now time.Time) (string, error) {
+	n.Title = strings.TrimSpace(n.Title)
+	if n.Title == "" {
+		n.Title = "Conversation " + now.Format("2006-01-02 15:04")
+	}
+	if strings.TrimSpace(n.Summary) == "" && len(n.Transcript) == 0 {
+		return "", fmt.Errorf("note is empty")
+	}
+	dir := filepath.Join(s.Dir, NotesDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := now.Format("2006-01-02-150405") + "-" + noteSlug(n.Title)
+	path := filepath.Join(dir, base+".md")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.md", base, i))
+	}
+	if err := os.WriteFile(path, []byte(renderNote(n, now)), 0o644); err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(s.Workspace, path)
+	if err != nil {
+		return path, nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func renderNote(n Note, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString("title: " + yamlQuote(n.Title) + "\n")
+	tags := normalizeTags(n.Tags)
+	if len(tags) > 0 {
+		b.WriteString("tags: [" + strings.Join(tags, ", ") + "]\n")
+	}
+	if src := strings.TrimSpace(n.Source); src != "" {
+		b.WriteString("source: " + yamlQuote(src) + "\n")
+	}
+	b.WriteString("created: " + now.Format(time.RFC3339) + "\n")
+	b.WriteString("---\n\n")
+	b.WriteString("# " + n.Title + "\n\n")
+	if len(tags) > 0 {
+		b.WriteString("Tags: #" + strings.Join(tags, " #") + "\n\n")
+	}
+	if summary := strings.TrimSpace(n.Summary); summary != "" {
+		b.WriteString("## Summary\n\n" + summary + "\n\n")
+	}
+	if len(n.Transcript) > 0 {
+		var t strings.Builder
+		for _, m := range n.Transcript {
+			content := strings.TrimSpace(m.Content)
+			if content == "" {
+				continue
+			}
+			t.WriteString("**" + strings.TrimSpace(m.Role) + ":** " + content + "\n\n")
+		}
+		b.WriteString("## Conversation\n\n")
+		b.WriteString(truncate(strings.TrimSpace(t.String()), maxNoteTranscriptChars) + "\n")
+	}
+	return b.String()
+}
+
+func normalizeTags(in []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, t := range in {
+		t = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(t), "#")))
+		t = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+				return r
+			}
+			if unicode.IsSpace(r) {
+				return '-'
+			}
+			return -1
+		}, t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+func noteSlug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= 48 {
+			break
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if slug == "" {
+		return "note"
+	}
+	return slug
+}
+
+func yamlQuote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	s = strings.ReplaceAll(s, "\n", " ")
+	return "\"" + s + "\""
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveNote_WritesFrontMatterAndTranscript(t *testing.T) {
+	ws := t.TempDir()
+	now := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	rel, err := New(ws).SaveNote(Note{
+		Title:   "Trip plan: Kyoto",
+		Tags:    []string{"#Travel", "travel", "japan trip"},
+		Source:  "telegram:42",
+		Summary: "Leave on the 3rd.",
+		Transcript: []NoteMessage{
+			{Role: "user", Content: "plan kyoto"},
+			{Role: "assistant", Content: "sure"},
+		},
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel != "memory/notes/2026-03-01-093000-trip-plan-kyoto.md" {
+		t.Fatalf("path=%q", rel)
+	}
+	b, err := os.ReadFile(filepath.Join(ws, rel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{
+		`title: "Trip plan: Kyoto"`,
+		"tags: [travel, japan-trip]",
+		`source: "telegram:42"`,
+		"## Summary\n\nLeave on the 3rd.",
+		"**user:** plan kyoto",
+		"**assistant:** sure",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("missing %q in:\n%s", want, got)
+		}
+	}
+
+	// Same second and title must not overwrite.
+	rel2, err := New(ws).SaveNote(Note{Title: "Trip plan: Kyoto", Summary: "x"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel2 == rel {
+		t.Fatalf("note overwritten: %q", rel2)
+	}
+}
+
+func TestSaveNote_RejectsEmpty(t *testing.T) {
+	if _, err := New(t.TempDir()).SaveNote(Note{Title: "x"}, time.Now()); err == nil {
+		t.Fatal("expected error")
+	}
+}
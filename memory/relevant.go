@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// relevantCandidates is how many search hits are considered before the
+// budget is applied.
+const relevantCandidates = 24
+
+// RelevantContext is GetContext limited to about maxTokens: when the files
+// do not fit, only the chunks of MEMORY.md and today's notes that search
+// ranks highest for query are included, in file order. Tokens are counted
+// as 4 bytes.
+func (s *Store) RelevantContext(ctx context.Context, search SearchManager, query string, maxTokens int) (string, error) {
+	full := s.GetContext()
+	maxBytes := maxTokens * 4
+	if len(full) <= maxBytes || strings.TrimSpace(query) == "" {
+		return full, nil
+	}
+	results, err := search.Search(ctx, query, SearchOptions{MaxResults: relevantCandidates})
+	if err != nil {
+		return "", err
+	}
+
+	files := []struct {
+		title string
+		path  string
+	}{
+		{"## Long-term Memory (relevant excerpts)", s.LongTerm},
+		{"## Today's Notes (relevant excerpts)", s.TodayPath()},
+	}
+	var parts []string
+	used := 0
+	picked := map[string][]lineRange{}
+	lines := map[string][]string{}
+	for _, r := range results {
+		for _, f := range files {
+			if filepath.Join(s.Workspace, filepath.FromSlash(r.Path)) != filepath.Clean(f.path) {
+				continue
+			}
+			if _, ok := lines[f.path]; !ok {
+				b, err := os.ReadFile(f.path)
+				if err != nil {
+					lines[f.path] = nil
+					continue
+				}
+				lines[f.path] = strings.Split(string(b), "\n")
+			}
+			rng := lineRange{r.StartLine, r.EndLine}
+			added := rangeBytes(lines[f.path], subtractRanges(rng, picked[f.path]))
+			if added == 0 || used+added > maxBytes {
+				continue
+			}
+			used += added
+			picked[f.path] = append(picked[f.path], rng)
+		}
+	}
+	for _, f := range files {
+		if text := joinRanges(lines[f.path], picked[f.path]); text != "" {
+			parts = append(parts, f.title+"\n"+text)
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// lineRange is a 1-based inclusive line range.
+type lineRange struct{ from, to int }
+
+// subtractRanges returns the lines of r not yet covered by picked (chunks
+// overlap).
+func subtractRanges(r lineRange, picked []lineRange) []int {
+	var out []int
+	for n := r.from; n <= r.to; n++ {
+		covered := false
+		for _, p := range picked {
+			if n >= p.from && n <= p.to {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func rangeBytes(lines []string, nums []int) int {
+	n := 0
+	for _, i := range nums {
+		if i >= 1 && i <= len(lines) {
+			n += len(lines[i-1]) + 1
+		}
+	}
+	return n
+}
+
+// joinRanges renders the picked lines in file order; "..." marks skipped
+// lines.
+func joinRanges(lines []string, picked []lineRange) string {
+	if len(picked) == 0 {
+		return ""
+	}
+	keep := map[int]bool{}
+	for _, r := range picked {
+		for n := max(r.from, 1); n <= r.to && n <= len(lines); n++ {
+			keep[n] = true
+		}
+	}
+	nums := make([]int, 0, len(keep))
+	for n := range keep {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	var b strings.Builder
+	prev := 0
+	for _, n := range nums {
+		if prev != 0 && n != prev+1 {
+			b.WriteString("...\n")
+		}
+		b.WriteString(lines[n-1])
+		b.WriteString("\n")
+		prev = n
+	}
+	return strings.TrimSpace(b.String())
+}
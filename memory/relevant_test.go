@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+type fakeSearch struct {
+	SearchManager
+	results []SearchResult
+	err     error
+	queries []string
+}
+
+func (f *fakeSearch) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	f.queries = append(f.queries, query)
+	return f.results, f.err
+}
+
+func TestRelevantContext(t *testing.T) {
+	s := New(t.TempDir())
+	if err := s.EnsureInitialized(); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, fmt.Sprintf("line %02d %s", i, strings.Repeat("x", 40)))
+	}
+	if err := os.WriteFile(s.LongTerm, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything fits: no search.
+	search := &fakeSearch{}
+	got, err := s.RelevantContext(context.Background(), search, "q", 10000)
+	if err != nil || got != s.GetContext() || len(search.queries) != 0 {
+		t.Fatalf("small memory: err=%v queries=%v", err, search.queries)
+	}
+
+	search.results = []SearchResult{
+		{Path: "memory/MEMORY.md", StartLine: 30, EndLine: 32},
+		{Path: "memory/other.md", StartLine: 1, EndLine: 3},
+		{Path: "memory/MEMORY.md", StartLine: 2, EndLine: 4},
+		{Path: "memory/MEMORY.md", StartLine: 31, EndLine: 33}, // overlaps the first hit
+		{Path: "memory/MEMORY.md", StartLine: 10, EndLine: 20}, // over budget
+	}
+	got, err = s.RelevantContext(context.Background(), search, "q", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## Long-term Memory (relevant excerpts)\n" + strings.Join(lines[1:4], "\n") + "\n...\n" + strings.Join(lines[29:33], "\n")
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	search.err = errors.New("embeddings down")
+	if _, err := s.RelevantContext(context.Background(), search, "q", 100); err == nil {
+		t.Fatal("expected search error")
+	}
+}
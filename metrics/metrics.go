@@ -0,0 +1,92 @@
+// Package metrics keeps process-wide counters, e.g. tool failures by kind.
+// Counters are labelled and can be written in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds labelled counters.
+type Registry struct {
+	mu       sync.Mutex
+	counters map[string]map[string]int64 // name -> rendered labels -> value
+}
+
+func NewRegistry() *Registry {
+	return &Registry{counters: map[string]map[string]int64{}}
+}
+
+// Default is the registry used by the package-level helpers.
+var Default = NewRegistry()
+
+// Inc adds one to the counter name with the given label pairs
+// ("tool", "web_fetch", "kind", "dns").
+func Inc(name string, labels ...string) { Default.Add(name, 1, labels...) }
+
+func (r *Registry) Add(name string, delta int64, labels ...string) {
+	key := renderLabels(labels)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.counters[name]
+	if m == nil {
+		m = map[string]int64{}
+		r.counters[name] = m
+	}
+	m[key] += delta
+}
+
+// Value returns the counter for name and the exact label pairs.
+func (r *Registry) Value(name string, labels ...string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counters[name][renderLabels(labels)]
+}
+
+// WriteText writes every counter in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.counters))
+	for name := range r.counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "# TYPE %s counter\n", name); err != nil {
+			return err
+		}
+		series := r.counters[name]
+		keys := make([]string, 0, len(series))
+		for k := range series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if _, err := fmt.Fprintf(w, "%s%s %d\n", name, k, series[k]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func renderLabels(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1])
+		fmt.Fprintf(&b, `%s="%s"`, labels[i], v)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	r.Add("tool_failures_total", 1, "tool", "web_fetch", "kind", "dns")
+	r.Add("tool_failures_total", 2, "tool", "web_fetch", "kind", "dns")
+	r.Add("tool_failures_total", 1, "tool", "exec", "kind", `a"b`)
+
+	if got := r.Value("tool_failures_total", "tool", "web_fetch", "kind", "dns"); got != 3 {
+		t.Fatalf("value=%d", got)
+	}
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "# TYPE tool_failures_total counter\n" +
+		`tool_failures_total{tool="exec",kind="a\"b"} 1` + "\n" +
+		`tool_failures_total{tool="web_fetch",kind="dns"} 3` + "\n"
+	if b.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,286 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rolling windows reported by the SLO tracker.
+var SLOWindows = []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+
+const (
+	// sloKeep is how long events are kept: the longest window.
+	sloKeep = 7 * 24 * time.Hour
+	// sloMaxEvents bounds memory on a very busy gateway; the oldest events
+	// go first.
+	sloMaxEvents = 100_000
+)
+
+// SLOTargets are the objectives a report is measured against.
+type SLOTargets struct {
+	// TurnSuccess is the share of turns that should succeed, e.g. 0.99.
+	TurnSuccess float64
+	// ProviderSuccess is the share of LLM calls that should succeed; the rest
+	// is each provider's error budget.
+	ProviderSuccess float64
+	// ReplyLatencyP95 is the p95 reply latency to stay under.
+	ReplyLatencyP95 time.Duration
+}
+
+type turnEvent struct {
+	at      time.Time
+	latency time.Duration
+	ok      bool
+}
+
+type callEvent struct {
+	at       time.Time
+	provider string
+	ok       bool
+}
+
+// SLO keeps the agent turns and LLM calls of the last week in memory, so
+// reply latency, turn success and provider error rates can be reported over
+// rolling windows. Events do not survive a restart.
+type SLO struct {
+	mu    sync.Mutex
+	turns []turnEvent
+	calls []callEvent
+}
+
+func NewSLO() *SLO { return &SLO{} }
+
+// DefaultSLO is the tracker used by the package-level helpers.
+var DefaultSLO = NewSLO()
+
+// RecordTurn counts one agent turn: how long the reply took and whether it
+// succeeded.
+func RecordTurn(latency time.Duration, ok bool) {
+	Inc("clawlet_turns_total", "result", result(ok))
+	DefaultSLO.Turn(time.Now(), latency, ok)
+}
+
+// RecordLLMCall counts one request to an LLM provider; err is its outcome.
+func RecordLLMCall(provider string, err error) {
+	Inc("clawlet_llm_requests_total", "provider", provider, "result", result(err == nil))
+	DefaultSLO.Call(time.Now(), provider, err == nil)
+}
+
+func result(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "error"
+}
+
+func (s *SLO) Turn(at time.Time, latency time.Duration, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns = append(prune(s.turns, at, func(e turnEvent) time.Time { return e.at }), turnEvent{at: at, latency: latency, ok: ok})
+}
+
+func (s *SLO) Call(at time.Time, provider string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(prune(s.calls, at, func(e callEvent) time.Time { return e.at }), callEvent{at: at, provider: provider, ok: ok})
+}
+
+// prune drops events older than sloKeep and, past sloMaxEvents, the oldest.
+func prune[E any](events []E, now time.Time, at func(E) time.Time) []E {
+	i := sort.Search(len(events), func(i int) bool { return now.Sub(at(events[i])) <= sloKeep })
+	i = max(i, len(events)-sloMaxEvents+1)
+	if i <= 0 {
+		return events
+	}
+	return slices.Delete(events, 0, i)
+}
+
+// SLOReport is the service level over one window.
+type SLOReport struct {
+	Window time.Duration `json:"-"`
+	// WindowName is Window for humans, e.g. "24h" or "7d".
+	WindowName string `json:"window"`
+	Turns      int    `json:"turns"`
+	// TurnSuccessRate is the share of turns that succeeded; 1 when there
+	// were none.
+	TurnSuccessRate    float64          `json:"turnSuccessRate"`
+	ReplyLatencyP95Sec float64          `json:"replyLatencyP95Sec"`
+	Providers          []ProviderReport `json:"providers"`
+	Targets            SLOTargetsReport `json:"targets"`
+	Breaches           []string         `json:"breaches,omitempty"`
+}
+
+// ProviderReport is one LLM provider's share of an SLOReport.
+type ProviderReport struct {
+	Provider  string  `json:"provider"`
+	Calls     int     `json:"calls"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	// BudgetRemaining is the unspent share of the error budget; negative
+	// once the provider has failed more than the target allows.
+	BudgetRemaining float64 `json:"budgetRemaining"`
+}
+
+// SLOTargetsReport echoes the targets a report was measured against.
+type SLOTargetsReport struct {
+	TurnSuccess        float64 `json:"turnSuccess"`
+	ProviderSuccess    float64 `json:"providerSuccess"`
+	ReplyLatencyP95Sec float64 `json:"replyLatencyP95Sec"`
+}
+
+// Report measures the window ending at now against targets.
+func (s *SLO) Report(window time.Duration, now time.Time, targets SLOTargets) SLOReport {
+	rep := SLOReport{
+		Window:          window,
+		WindowName:      WindowName(window),
+		TurnSuccessRate: 1,
+		Targets: SLOTargetsReport{
+			TurnSuccess:        targets.TurnSuccess,
+			ProviderSuccess:    targets.ProviderSuccess,
+			ReplyLatencyP95Sec: targets.ReplyLatencyP95.Seconds(),
+		},
+	}
+	since := now.Add(-window)
+
+	s.mu.Lock()
+	var latencies []time.Duration
+	ok := 0
+	for _, e := range s.turns {
+		if e.at.Before(since) || e.at.After(now) {
+			continue
+		}
+		latencies = append(latencies, e.latency)
+		if e.ok {
+			ok++
+		}
+	}
+	byProvider := map[string]*ProviderReport{}
+	for _, e := range s.calls {
+		if e.at.Before(since) || e.at.After(now) {
+			continue
+		}
+		p := byProvider[e.provider]
+		if p == nil {
+			p = &ProviderReport{Provider: e.provider}
+			byProvider[e.provider] = p
+		}
+		p.Calls++
+		if !e.ok {
+			p.Errors++
+		}
+	}
+	s.mu.Unlock()
+
+	if rep.Turns = len(latencies); rep.Turns > 0 {
+		rep.TurnSuccessRate = float64(ok) / float64(rep.Turns)
+		rep.ReplyLatencyP95Sec = percentile(latencies, 0.95).Seconds()
+	}
+	budget := 1 - targets.ProviderSuccess
+	for _, p := range byProvider {
+		p.ErrorRate = float64(p.Errors) / float64(p.Calls)
+		p.BudgetRemaining = 1
+		if budget > 0 {
+			p.BudgetRemaining = 1 - p.ErrorRate/budget
+		}
+		rep.Providers = append(rep.Providers, *p)
+	}
+	sort.Slice(rep.Providers, func(i, j int) bool { return rep.Providers[i].Provider < rep.Providers[j].Provider })
+
+	if rep.Turns > 0 && targets.TurnSuccess > 0 && rep.TurnSuccessRate < targets.TurnSuccess {
+		rep.Breaches = append(rep.Breaches, fmt.Sprintf("turn success %s is below %s", percent(rep.TurnSuccessRate), percent(targets.TurnSuccess)))
+	}
+	if rep.Turns > 0 && targets.ReplyLatencyP95 > 0 && rep.ReplyLatencyP95Sec > targets.ReplyLatencyP95.Seconds() {
+		rep.Breaches = append(rep.Breaches, fmt.Sprintf("p95 reply latency %.1fs is over %.1fs", rep.ReplyLatencyP95Sec, targets.ReplyLatencyP95.Seconds()))
+	}
+	for _, p := range rep.Providers {
+		if p.BudgetRemaining < 0 {
+			rep.Breaches = append(rep.Breaches, fmt.Sprintf("%s error rate %s spent its error budget", p.Provider, percent(p.ErrorRate)))
+		}
+	}
+	return rep
+}
+
+// Reports measures every window in SLOWindows.
+func (s *SLO) Reports(now time.Time, targets SLOTargets) []SLOReport {
+	out := make([]SLOReport, 0, len(SLOWindows))
+	for _, w := range SLOWindows {
+		out = append(out, s.Report(w, now, targets))
+	}
+	return out
+}
+
+// WriteSLOText writes the reports as Prometheus gauges.
+func WriteSLOText(w io.Writer, reports []SLOReport) error {
+	var b strings.Builder
+	b.WriteString("# TYPE clawlet_slo_turns gauge\n")
+	for _, r := range reports {
+		fmt.Fprintf(&b, "clawlet_slo_turns%s %d\n", renderLabels([]string{"window", r.WindowName}), r.Turns)
+	}
+	b.WriteString("# TYPE clawlet_slo_turn_success_ratio gauge\n")
+	for _, r := range reports {
+		fmt.Fprintf(&b, "clawlet_slo_turn_success_ratio%s %g\n", renderLabels([]string{"window", r.WindowName}), r.TurnSuccessRate)
+	}
+	b.WriteString("# TYPE clawlet_slo_reply_latency_p95_seconds gauge\n")
+	for _, r := range reports {
+		fmt.Fprintf(&b, "clawlet_slo_reply_latency_p95_seconds%s %g\n", renderLabels([]string{"window", r.WindowName}), r.ReplyLatencyP95Sec)
+	}
+	b.WriteString("# TYPE clawlet_slo_llm_error_ratio gauge\n")
+	for _, r := range reports {
+		for _, p := range r.Providers {
+			fmt.Fprintf(&b, "clawlet_slo_llm_error_ratio%s %g\n", renderLabels([]string{"provider", p.Provider, "window", r.WindowName}), p.ErrorRate)
+		}
+	}
+	b.WriteString("# TYPE clawlet_slo_llm_error_budget_remaining gauge\n")
+	for _, r := range reports {
+		for _, p := range r.Providers {
+			fmt.Fprintf(&b, "clawlet_slo_llm_error_budget_remaining%s %g\n", renderLabels([]string{"provider", p.Provider, "window", r.WindowName}), p.BudgetRemaining)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Digest is the owner's summary of the reports, one line per window and
+// provider.
+func Digest(reports []SLOReport) string {
+	var b strings.Builder
+	b.WriteString("Service level report")
+	for _, r := range reports {
+		fmt.Fprintf(&b, "\n\n%s: %d turns, %s succeeded, p95 reply %.1fs", r.WindowName, r.Turns, percent(r.TurnSuccessRate), r.ReplyLatencyP95Sec)
+		for _, p := range r.Providers {
+			fmt.Fprintf(&b, "\n- %s: %d calls, %d errors (%s), %s of error budget left", p.Provider, p.Calls, p.Errors, percent(p.ErrorRate), percent(p.BudgetRemaining))
+		}
+		for _, br := range r.Breaches {
+			fmt.Fprintf(&b, "\n! %s", br)
+		}
+	}
+	return b.String()
+}
+
+// WindowName renders a window as "1h", "24h" or "7d".
+func WindowName(d time.Duration) string {
+	if d >= 48*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return d.String()
+}
+
+func percentile(vals []time.Duration, p float64) time.Duration {
+	sorted := slices.Clone(vals)
+	slices.Sort(sorted)
+	i := int(math.Ceil(float64(len(sorted))*p)) - 1 // nearest rank
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+func percent(v float64) string {
+	return fmt.Sprintf("%.1f%%", v*100)
+}
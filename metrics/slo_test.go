@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSLO_Report(t *testing.T) {
+	s := NewSLO()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	for i := range 20 {
+		s.Turn(now.Add(-time.Duration(i)*time.Minute), time.Duration(i+1)*time.Second, i != 0)
+	}
+	s.Turn(now.Add(-2*time.Hour), time.Minute, false) // outside the hour
+	for i := range 100 {
+		s.Call(now.Add(-time.Minute), "openai", i >= 3)
+		s.Call(now.Add(-time.Minute), "anthropic", true)
+	}
+	targets := SLOTargets{TurnSuccess: 0.99, ProviderSuccess: 0.99, ReplyLatencyP95: 10 * time.Second}
+
+	rep := s.Report(time.Hour, now, targets)
+	if rep.WindowName != "1h" || rep.Turns != 20 || rep.TurnSuccessRate != 0.95 {
+		t.Fatalf("turns: %+v", rep)
+	}
+	if rep.ReplyLatencyP95Sec != 19 {
+		t.Fatalf("p95=%v", rep.ReplyLatencyP95Sec)
+	}
+	if len(rep.Providers) != 2 || rep.Providers[0].Provider != "anthropic" || rep.Providers[0].BudgetRemaining != 1 {
+		t.Fatalf("providers: %+v", rep.Providers)
+	}
+	if oa := rep.Providers[1]; oa.Errors != 3 || oa.ErrorRate != 0.03 || oa.BudgetRemaining > -1.99 || oa.BudgetRemaining < -2.01 {
+		t.Fatalf("openai: %+v", oa)
+	}
+	// Turn success, latency and the openai budget are all breached.
+	if len(rep.Breaches) != 3 {
+		t.Fatalf("breaches: %q", rep.Breaches)
+	}
+	if day := s.Report(24*time.Hour, now, targets); day.Turns != 21 || day.WindowName != "24h" {
+		t.Fatalf("24h: %+v", day)
+	}
+
+	var b strings.Builder
+	if err := WriteSLOText(&b, s.Reports(now, targets)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`clawlet_slo_turn_success_ratio{window="1h"} 0.95`,
+		`clawlet_slo_llm_error_ratio{provider="openai",window="7d"} 0.03`,
+		"# TYPE clawlet_slo_llm_error_budget_remaining gauge",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("missing %q in:\n%s", want, b.String())
+		}
+	}
+	if d := Digest(s.Reports(now, targets)); !strings.Contains(d, "openai: 100 calls, 3 errors (3.0%)") {
+		t.Fatalf("digest:\n%s", d)
+	}
+}
+
+func TestSLO_PrunesOldEvents(t *testing.T) {
+	s := NewSLO()
+	now := time.Now()
+	s.Turn(now.Add(-8*24*time.Hour), time.Second, true)
+	s.Turn(now, time.Second, true)
+	if len(s.turns) != 1 {
+		t.Fatalf("kept %d turns", len(s.turns))
+	}
+}
+
+func TestRecordLLMCall_Counts(t *testing.T) {
+	before := Default.Value("clawlet_llm_requests_total", "provider", "test", "result", "error")
+	RecordLLMCall("test", errors.New("boom"))
+	if got := Default.Value("clawlet_llm_requests_total", "provider", "test", "result", "error"); got != before+1 {
+		t.Fatalf("count=%d", got)
+	}
+}
@@ -0,0 +1,218 @@
+// Package migrate upgrades on-disk state (sessions, cron store) written by
+// older clawlet versions. Each store has a schema version recorded in
+// <stateDir>/state.json; migrations step a store from one version to the
+// next and run automatically on startup. Only the files under the state
+// directory are migrated, not records in the SQLite storage backend nor the
+// workspace.
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/paths"
+)
+
+// StateFile records store versions, relative to the state directory.
+const StateFile = "state.json"
+
+// ErrNewerState means the state was written by a newer clawlet; running an
+// older binary against it could corrupt it.
+var ErrNewerState = errors.New("state was written by a newer clawlet version")
+
+type State struct {
+	Stores    map[string]int `json:"stores"`
+	UpdatedAt string         `json:"updatedAt,omitempty"`
+}
+
+// Env locates the state a migration operates on.
+type Env struct {
+	StateDir    string
+	SessionsDir string
+	CronPath    string
+}
+
+func DefaultEnv() (Env, error) {
+	dir, err := paths.ConfigDir()
+	if err != nil {
+		return Env{}, err
+	}
+	return Env{
+		StateDir:    dir,
+		SessionsDir: paths.SessionsDir(),
+		CronPath:    paths.CronStorePath(),
+	}, nil
+}
+
+// Migration moves Store from version From to From+1.
+type Migration struct {
+	Store       string
+	From        int
+	Description string
+	Apply       func(*Context) error
+}
+
+// Context is passed to a migration. File changes go through WriteFile so
+// dry runs only record them and real runs back up the original first.
+type Context struct {
+	Env    Env
+	DryRun bool
+
+	backupDir string
+	changes   []string
+}
+
+// WriteFile atomically replaces path with data, keeping a copy of the
+// previous content under the run's backup directory.
+func (c *Context) WriteFile(path string, data []byte) error {
+	c.changes = append(c.changes, path)
+	if c.DryRun {
+		return nil
+	}
+	if old, err := os.ReadFile(path); err == nil {
+		rel, err := filepath.Rel(c.Env.StateDir, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(path)
+		}
+		dst := filepath.Join(c.backupDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, old, 0o600); err != nil {
+			return fmt.Errorf("backup %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Step is one applied (or, in a dry run, pending) migration.
+type Step struct {
+	Store       string
+	From        int
+	To          int
+	Description string
+	Changes     []string
+}
+
+type Report struct {
+	DryRun    bool
+	Steps     []Step
+	BackupDir string
+}
+
+// Run brings every store up to its current version. With dryRun nothing is
+// written; the report lists what would change.
+func Run(env Env, dryRun bool) (Report, error) {
+	return run(env, dryRun, currentVersions, migrations, time.Now())
+}
+
+func run(env Env, dryRun bool, current map[string]int, all []Migration, now time.Time) (Report, error) {
+	rep := Report{DryRun: dryRun}
+	st, err := loadState(env.StateDir)
+	if err != nil {
+		return rep, err
+	}
+	stores := make([]string, 0, len(current))
+	for name := range current {
+		stores = append(stores, name)
+	}
+	sort.Strings(stores)
+
+	for _, name := range stores {
+		if v := st.Stores[name]; v > current[name] {
+			return rep, fmt.Errorf("%w: %s is at version %d, this binary supports %d", ErrNewerState, name, v, current[name])
+		}
+	}
+
+	backupDir := filepath.Join(env.StateDir, "backups", "migrate-"+now.UTC().Format("20060102T150405Z"))
+	for _, name := range stores {
+		for v := st.Stores[name]; v < current[name]; v++ {
+			m, ok := findMigration(all, name, v)
+			if !ok {
+				return rep, fmt.Errorf("no migration for %s from version %d", name, v)
+			}
+			ctx := &Context{Env: env, DryRun: dryRun, backupDir: backupDir}
+			if err := m.Apply(ctx); err != nil {
+				return rep, fmt.Errorf("migrate %s %d->%d: %w", name, v, v+1, err)
+			}
+			rep.Steps = append(rep.Steps, Step{
+				Store:       name,
+				From:        v,
+				To:          v + 1,
+				Description: m.Description,
+				Changes:     ctx.changes,
+			})
+			if len(ctx.changes) > 0 && !dryRun {
+				rep.BackupDir = backupDir
+			}
+			if !dryRun {
+				// Record progress per step so an interrupted run resumes.
+				st.Stores[name] = v + 1
+				if err := saveState(env.StateDir, st, now); err != nil {
+					return rep, err
+				}
+			}
+		}
+	}
+	return rep, nil
+}
+
+func findMigration(all []Migration, store string, from int) (Migration, bool) {
+	for _, m := range all {
+		if m.Store == store && m.From == from {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
+
+func loadState(dir string) (State, error) {
+	st := State{Stores: map[string]int{}}
+	b, err := os.ReadFile(filepath.Join(dir, StateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return st, err
+	}
+	if err := json.Unmarshal(b, &st); err != nil {
+		return st, fmt.Errorf("parse %s: %w", filepath.Join(dir, StateFile), err)
+	}
+	if st.Stores == nil {
+		st.Stores = map[string]int{}
+	}
+	return st, nil
+}
+
+func saveState(dir string, st State, now time.Time) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	st.UpdatedAt = now.UTC().Format(time.RFC3339)
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	path := filepath.Join(dir, StateFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
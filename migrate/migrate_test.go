@@ -0,0 +1,156 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func testEnv(t *testing.T) Env {
+	t.Helper()
+	dir := t.TempDir()
+	return Env{
+		StateDir:    dir,
+		SessionsDir: filepath.Join(dir, "sessions"),
+		CronPath:    filepath.Join(dir, "cron.json"),
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestMigrations_ChainComplete(t *testing.T) {
+	for store, cur := range currentVersions {
+		for v := 0; v < cur; v++ {
+			if _, ok := findMigration(migrations, store, v); !ok {
+				t.Fatalf("missing migration for %s from %d", store, v)
+			}
+		}
+	}
+}
+
+func TestRun_MigratesLegacyState(t *testing.T) {
+	env := testEnv(t)
+	legacy := filepath.Join(env.SessionsDir, "telegram_1.jsonl")
+	writeFile(t, legacy, `{"role":"user","content":"hi","extra":"kept"}`+"\n")
+	stamped := filepath.Join(env.SessionsDir, "cli_default.jsonl")
+	writeFile(t, stamped, `{"_type":"metadata","version":1,"created_at":"","updated_at":"","metadata":{}}`+"\n")
+	writeFile(t, env.CronPath, `{"jobs":[]}`)
+
+	rep, err := Run(env, false)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(rep.Steps) != 2 || rep.BackupDir == "" {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+
+	got := readFile(t, legacy)
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"version":1`) || lines[1] != `{"role":"user","content":"hi","extra":"kept"}` {
+		t.Fatalf("unexpected session file:\n%s", got)
+	}
+	s, err := session.Load(env.SessionsDir, "telegram:1")
+	if err != nil || s == nil || len(s.Messages) != 1 {
+		t.Fatalf("migrated session not loadable: %+v err=%v", s, err)
+	}
+	if !strings.Contains(readFile(t, env.CronPath), `"version": 1`) {
+		t.Fatalf("cron store not stamped: %s", readFile(t, env.CronPath))
+	}
+	if _, err := os.Stat(filepath.Join(rep.BackupDir, "sessions", "telegram_1.jsonl")); err != nil {
+		t.Fatalf("expected backup: %v", err)
+	}
+	for _, step := range rep.Steps {
+		for _, p := range step.Changes {
+			if p == stamped {
+				t.Fatalf("already stamped file rewritten")
+			}
+		}
+	}
+
+	st, err := loadState(env.StateDir)
+	if err != nil || st.Stores["sessions"] != session.FormatVersion || st.Stores["cron"] != cron.StoreVersion {
+		t.Fatalf("unexpected state: %+v err=%v", st, err)
+	}
+	rep, err = Run(env, false)
+	if err != nil || len(rep.Steps) != 0 {
+		t.Fatalf("expected no-op rerun, got %+v err=%v", rep, err)
+	}
+}
+
+func TestRun_DryRunWritesNothing(t *testing.T) {
+	env := testEnv(t)
+	writeFile(t, env.CronPath, `{"jobs":[]}`)
+
+	rep, err := Run(env, true)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(rep.Steps) != 2 || len(rep.Steps[0].Changes) != 1 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	if got := readFile(t, env.CronPath); got != `{"jobs":[]}` {
+		t.Fatalf("dry run modified cron store: %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(env.StateDir, StateFile)); !os.IsNotExist(err) {
+		t.Fatalf("dry run wrote state file: %v", err)
+	}
+}
+
+func TestRun_RefusesNewerState(t *testing.T) {
+	env := testEnv(t)
+	writeFile(t, filepath.Join(env.StateDir, StateFile), `{"stores":{"cron":99}}`)
+	if _, err := Run(env, false); !errors.Is(err, ErrNewerState) {
+		t.Fatalf("expected ErrNewerState, got %v", err)
+	}
+}
+
+func TestRun_StepsInOrderAndResumes(t *testing.T) {
+	env := testEnv(t)
+	var applied []int
+	step := func(from int, fail bool) Migration {
+		return Migration{Store: "x", From: from, Apply: func(*Context) error {
+			if fail {
+				return errors.New("boom")
+			}
+			applied = append(applied, from)
+			return nil
+		}}
+	}
+	now := time.Unix(0, 0)
+	if _, err := run(env, false, map[string]int{"x": 3}, []Migration{step(0, false), step(1, false), step(2, true)}, now); err == nil {
+		t.Fatalf("expected failure")
+	}
+	st, _ := loadState(env.StateDir)
+	if st.Stores["x"] != 2 {
+		t.Fatalf("expected progress recorded at 2, got %d", st.Stores["x"])
+	}
+	if _, err := run(env, false, map[string]int{"x": 3}, []Migration{step(2, false)}, now); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(applied) != 3 || applied[0] != 0 || applied[1] != 1 || applied[2] != 2 {
+		t.Fatalf("unexpected order: %v", applied)
+	}
+}
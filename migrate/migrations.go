@@ -0,0 +1,150 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/atrest"
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+// currentVersions is the schema version this binary writes for each store.
+// Bumping one requires a Migration from the previous version.
+var currentVersions = map[string]int{
+	"sessions": session.FormatVersion,
+	"cron":     cron.StoreVersion,
+}
+
+var migrations = []Migration{
+	{
+		Store:       "sessions",
+		From:        0,
+		Description: "stamp format version into session metadata",
+		Apply:       migrateSessionsV1,
+	},
+	{
+		Store:       "cron",
+		From:        0,
+		Description: "stamp store version",
+		Apply:       migrateCronV1,
+	},
+}
+
+// migrateSessionsV1 ensures every session file starts with a metadata line
+// carrying the format version. Message lines are kept byte-for-byte.
+func migrateSessionsV1(c *Context) error {
+	files, err := filepath.Glob(filepath.Join(c.Env.SessionsDir, "*.jsonl"))
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		b, err := atrest.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		out, changed, err := stampSessionFile(b, modTime(path))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if !changed {
+			continue
+		}
+		if out, err = atrest.Encode(out); err != nil {
+			return err
+		}
+		if err := c.WriteFile(path, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stampSessionFile(b []byte, mtime time.Time) ([]byte, bool, error) {
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	metaIdx := -1
+	var meta map[string]any
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var raw map[string]any
+		if json.Unmarshal([]byte(line), &raw) == nil && raw["_type"] == "metadata" {
+			metaIdx, meta = i, raw
+		}
+		break
+	}
+	if meta != nil {
+		if v, _ := meta["version"].(float64); int(v) >= 1 {
+			return b, false, nil
+		}
+	} else {
+		ts := mtime.Format(time.RFC3339Nano)
+		meta = map[string]any{
+			"_type":      "metadata",
+			"created_at": ts,
+			"updated_at": ts,
+			"metadata":   map[string]any{},
+		}
+	}
+	meta["version"] = 1
+	mb, err := json.Marshal(meta)
+	if err != nil {
+		return nil, false, err
+	}
+	if metaIdx >= 0 {
+		lines[metaIdx] = string(mb)
+	} else {
+		lines = append([]string{string(mb)}, lines...)
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), true, nil
+}
+
+// migrateCronV1 writes an explicit version into cron stores that predate it.
+func migrateCronV1(c *Context) error {
+	b, err := os.ReadFile(c.Env.CronPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("parse %s: %w", c.Env.CronPath, err)
+	}
+	var v int
+	if rv, ok := raw["version"]; ok {
+		_ = json.Unmarshal(rv, &v)
+	}
+	if v >= 1 {
+		return nil
+	}
+	raw["version"] = json.RawMessage("1")
+	out, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	return c.WriteFile(c.Env.CronPath, append(out, '\n'))
+}
+
+func modTime(path string) time.Time {
+	if st, err := os.Stat(path); err == nil {
+		return st.ModTime()
+	}
+	return time.Now()
+}
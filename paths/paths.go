@@ -39,6 +39,25 @@ func CronStorePath() string {
 	return filepath.Join(dir, "cron.json")
 }
 
+// LeasesDir holds leader-election leases when state is kept in files.
+func LeasesDir() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/leases"
+	}
+	return filepath.Join(dir, "leases")
+}
+
+// TurnsDir holds recorded turns for `clawlet replay` when state is kept in
+// files.
+func TurnsDir() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/turns"
+	}
+	return filepath.Join(dir, "turns")
+}
+
 func WorkspaceDir() string {
 	dir, err := ConfigDir()
 	if err != nil {
@@ -61,3 +80,73 @@ func EnsureStateDirs() error {
 	}
 	return nil
 }
+
+// GatewayPIDPath is where a running gateway records its PID so that
+// `clawlet upgrade --restart` can ask it to hand over to the new binary.
+func GatewayPIDPath() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/gateway.pid"
+	}
+	return filepath.Join(dir, "gateway.pid")
+}
+
+// LlamaServerLogPath receives the output of a llama-server started by
+// clawlet.
+func LlamaServerLogPath() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/llama-server.log"
+	}
+	return filepath.Join(dir, "llama-server.log")
+}
+
+// PresencePath holds the away or back state set with `clawlet presence`.
+func PresencePath() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/presence.json"
+	}
+	return filepath.Join(dir, "presence.json")
+}
+
+// PresenceQueuePath holds the messages that arrived while the agent was
+// away.
+func PresenceQueuePath() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/presence-queue.json"
+	}
+	return filepath.Join(dir, "presence-queue.json")
+}
+
+// InboundSeenPath holds the IDs of recent inbound messages, so a message a
+// chat app delivers again after a restart is still dropped.
+func InboundSeenPath() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/inbound-seen.json"
+	}
+	return filepath.Join(dir, "inbound-seen.json")
+}
+
+// ACMECacheDir holds the certificates the webhook listener obtains from
+// Let's Encrypt.
+func ACMECacheDir() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/acme"
+	}
+	return filepath.Join(dir, "acme")
+}
+
+// SentMessagesPath holds the idempotency keys of recent messages sent with
+// the message tool, so a turn retried after a crash does not send them
+// again.
+func SentMessagesPath() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/sent-messages.json"
+	}
+	return filepath.Join(dir, "sent-messages.json")
+}
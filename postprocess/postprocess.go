@@ -0,0 +1,204 @@
+// Package postprocess rewrites the final LLM reply through an ordered list
+// of configured rules before it is stored and delivered.
+package postprocess
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// DefaultTruncateSuffix ends replies cut by a maxLength rule.
+const DefaultTruncateSuffix = "…"
+
+// Pipeline is a compiled list of rules. A nil Pipeline leaves replies
+// unchanged.
+type Pipeline struct {
+	steps []step
+}
+
+type step struct {
+	channels []string
+	apply    func(string) string
+}
+
+// New validates and compiles rules.
+func New(rules []config.PostProcessRule) (*Pipeline, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	p := &Pipeline{}
+	for i, r := range rules {
+		fn, err := compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("agents.defaults.postProcess[%d]: %w", i, err)
+		}
+		var chs []string
+		for _, c := range r.Channels {
+			if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+				chs = append(chs, c)
+			}
+		}
+		p.steps = append(p.steps, step{channels: chs, apply: fn})
+	}
+	return p, nil
+}
+
+// Apply runs the rules that match channel, in order.
+func (p *Pipeline) Apply(channel, reply string) string {
+	if p == nil {
+		return reply
+	}
+	channel = strings.ToLower(channel)
+	for _, s := range p.steps {
+		if len(s.channels) > 0 && !slices.Contains(s.channels, channel) {
+			continue
+		}
+		reply = s.apply(reply)
+	}
+	return reply
+}
+
+func compile(r config.PostProcessRule) (func(string) string, error) {
+	switch strings.TrimSpace(r.Type) {
+	case config.PostProcessStripThinking:
+		return StripThinking, nil
+	case config.PostProcessMaxLength:
+		suffix := r.Text
+		if suffix == "" {
+			suffix = DefaultTruncateSuffix
+		}
+		if r.MaxChars <= len([]rune(suffix)) {
+			return nil, fmt.Errorf("maxLength needs maxChars greater than the length of text")
+		}
+		return func(s string) string { return Truncate(s, r.MaxChars, suffix) }, nil
+	case config.PostProcessAppend:
+		if strings.TrimSpace(r.Text) == "" {
+			return nil, fmt.Errorf("append needs text")
+		}
+		return func(s string) string { return appendText(s, r.Text) }, nil
+	case config.PostProcessPrepend:
+		if strings.TrimSpace(r.Text) == "" {
+			return nil, fmt.Errorf("prepend needs text")
+		}
+		return func(s string) string { return prependText(s, r.Text) }, nil
+	case config.PostProcessReplace:
+		if r.Pattern == "" {
+			return nil, fmt.Errorf("replace needs pattern")
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("replace: %w", err)
+		}
+		return func(s string) string { return re.ReplaceAllString(s, r.Replacement) }, nil
+	case config.PostProcessBoldHeadings:
+		return BoldHeadings, nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", r.Type)
+	}
+}
+
+var thinkingTags = []string{"think", "thinking", "reasoning"}
+
+// StripThinking removes chain-of-thought blocks such as <think>…</think>
+// that some models emit before the answer. A closing tag without an opening
+// one drops everything before it; an unterminated block is dropped to the end.
+func StripThinking(s string) string {
+	lower := asciiLower(s)
+	for _, tag := range thinkingTags {
+		open, closing := "<"+tag+">", "</"+tag+">"
+		for {
+			i := strings.Index(lower, open)
+			j := strings.Index(lower, closing)
+			switch {
+			case j >= 0 && (i < 0 || j < i):
+				i = 0
+			case i >= 0 && j < 0:
+				j = len(lower) - len(closing)
+			case i < 0:
+				j = -1
+			}
+			if j < 0 {
+				break
+			}
+			end := j + len(closing)
+			s = s[:i] + s[end:]
+			lower = lower[:i] + lower[end:]
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// asciiLower lowercases ASCII letters only, so byte offsets match s.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// Truncate limits s to max runes, cutting at a word boundary when one is
+// close and ending with suffix.
+func Truncate(s string, max int, suffix string) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	keep := max - len([]rune(suffix))
+	cut := keep
+	for i := keep - 1; i > keep/2; i-- {
+		if r[i] == ' ' || r[i] == '\n' {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRight(string(r[:cut]), " \n\t") + suffix
+}
+
+func appendText(s, text string) string {
+	if strings.HasSuffix(strings.TrimSpace(s), strings.TrimSpace(text)) {
+		return s
+	}
+	return strings.TrimRight(s, " \n\t") + "\n\n" + text
+}
+
+func prependText(s, text string) string {
+	if strings.HasPrefix(strings.TrimSpace(s), strings.TrimSpace(text)) {
+		return s
+	}
+	return text + "\n\n" + strings.TrimLeft(s, " \n\t")
+}
+
+var headingRe = regexp.MustCompile(`^ {0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+
+// BoldHeadings turns Markdown headings into bold lines (*Title*) for chat
+// apps without heading support such as WhatsApp. Code blocks are left as is.
+func BoldHeadings(s string) string {
+	lines := strings.Split(s, "\n")
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		m := headingRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		title := strings.Trim(strings.TrimSpace(m[1]), "*_")
+		if title == "" {
+			continue
+		}
+		lines[i] = "*" + title + "*"
+	}
+	return strings.Join(lines, "\n")
+}
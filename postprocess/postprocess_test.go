@@ -0,0 +1,86 @@
+package postprocess
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestStripThinking(t *testing.T) {
+	cases := map[string]string{
+		"<think>plan</think>\nAnswer":               "Answer",
+		"<Thinking>a</Thinking>A <think>b</think>B": "A B",
+		"reasoning leaked</think>Answer":            "Answer",
+		"Answer<think>unfinished":                   "Answer",
+		"No markers":                                "No markers",
+		"İstanbul <think>x</think>ok":               "İstanbul ok",
+	}
+	for in, want := range cases {
+		if got := StripThinking(in); got != want {
+			t.Errorf("StripThinking(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTruncate_WordBoundary(t *testing.T) {
+	got := Truncate("hello brave new world", 14, "…")
+	if got != "hello brave…" {
+		t.Fatalf("got %q", got)
+	}
+	if got := Truncate("short", 14, "…"); got != "short" {
+		t.Fatalf("got %q", got)
+	}
+	if got := Truncate("ああああああああああ", 5, "…"); got != "ああああ…" {
+		t.Fatalf("runes: %q", got)
+	}
+}
+
+func TestBoldHeadings_SkipsCode(t *testing.T) {
+	in := "# Title\ntext\n```\n# comment\n```\n### **Sub** ###"
+	want := "*Title*\ntext\n```\n# comment\n```\n*Sub*"
+	if got := BoldHeadings(in); got != want {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestPipeline_OrderAndChannels(t *testing.T) {
+	p, err := New([]config.PostProcessRule{
+		{Type: config.PostProcessStripThinking},
+		{Type: config.PostProcessBoldHeadings, Channels: []string{"whatsapp"}},
+		{Type: config.PostProcessReplace, Pattern: `(?i)acme`, Replacement: "ACME"},
+		{Type: config.PostProcessAppend, Text: "— sent by bot"},
+		{Type: config.PostProcessMaxLength, MaxChars: 30, Channels: []string{"sms", "WhatsApp"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "<think>x</think># Acme news\nAcme shipped a thing today."
+	if got := p.Apply("slack", in); got != "# ACME news\nACME shipped a thing today.\n\n— sent by bot" {
+		t.Fatalf("slack: %q", got)
+	}
+	got := p.Apply("whatsapp", in)
+	if !strings.HasPrefix(got, "*ACME news*") || len([]rune(got)) > 30 || !strings.HasSuffix(got, "…") {
+		t.Fatalf("whatsapp: %q", got)
+	}
+	// The signature is not appended twice when the model repeats it.
+	if got := p.Apply("slack", "hi\n\n— sent by bot"); got != "hi\n\n— sent by bot" {
+		t.Fatalf("duplicate signature: %q", got)
+	}
+}
+
+func TestNew_RejectsInvalidRules(t *testing.T) {
+	for _, r := range []config.PostProcessRule{
+		{Type: "shout"},
+		{Type: config.PostProcessAppend},
+		{Type: config.PostProcessReplace, Pattern: "("},
+		{Type: config.PostProcessMaxLength, MaxChars: 1},
+	} {
+		if _, err := New([]config.PostProcessRule{r}); err == nil {
+			t.Errorf("rule %+v accepted", r)
+		}
+	}
+	if p, err := New(nil); err != nil || p.Apply("x", "y") != "y" {
+		t.Fatal("empty pipeline should pass replies through")
+	}
+}
@@ -0,0 +1,233 @@
+// Package presence decides whether the agent is away: on a weekly
+// schedule, or by hand with `clawlet presence`. While it is away, chats get
+// a short notice and their messages wait in a queue until it is back, so
+// off-hours messages cost no model calls.
+package presence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mosaxiv/clawlet/atrest"
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// Manual is the away or back state set by hand. It overrides the schedule
+// until Until, or indefinitely when Until is zero.
+type Manual struct {
+	Away  bool      `json:"away"`
+	Until time.Time `json:"until,omitzero"`
+	// Note replaces the configured notice while away.
+	Note string `json:"note,omitempty"`
+}
+
+// Active reports whether the manual state still applies at now.
+func (m Manual) Active(now time.Time) bool {
+	return m.Until.IsZero() || now.Before(m.Until)
+}
+
+// LoadManual reads the manual state; a missing file means none is set.
+func LoadManual(path string) (*Manual, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manual
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// SaveManual sets the manual state; nil clears it, so the schedule applies
+// again.
+func SaveManual(path string, m *Manual) error {
+	if m == nil {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return writeJSON(path, m)
+}
+
+// Tracker holds the presence state of a gateway.
+type Tracker struct {
+	windows    []window
+	loc        *time.Location
+	notice     string
+	urgent     []string
+	maxQueued  int
+	manualPath string
+	queuePath  string
+
+	mu       sync.Mutex
+	queue    []bus.InboundMessage
+	notified map[string]bool // session keys told about the absence
+}
+
+// New returns the tracker for cfg. The manual state is read from
+// manualPath on every check; queued messages are kept in queuePath,
+// encrypted when at-rest encryption is configured.
+func New(cfg config.PresenceConfig, manualPath, queuePath string) (*Tracker, error) {
+	loc := time.Local
+	if tz := strings.TrimSpace(cfg.Timezone); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("presence.timezone: %w", err)
+		}
+	}
+	t := &Tracker{
+		loc:        loc,
+		notice:     cfg.NoticeValue(),
+		maxQueued:  cfg.MaxQueuedValue(),
+		manualPath: manualPath,
+		queuePath:  queuePath,
+		notified:   map[string]bool{},
+	}
+	for i, w := range cfg.Away {
+		pw, err := parseWindow(w)
+		if err != nil {
+			return nil, fmt.Errorf("presence.away[%d]: %w", i, err)
+		}
+		t.windows = append(t.windows, pw)
+	}
+	for _, u := range cfg.UrgentValue() {
+		if u = strings.ToLower(strings.TrimSpace(u)); u != "" {
+			t.urgent = append(t.urgent, u)
+		}
+	}
+	if b, err := atrest.ReadFile(queuePath); err == nil {
+		if err := json.Unmarshal(b, &t.queue); err != nil {
+			return nil, fmt.Errorf("%s: %w", queuePath, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Away reports whether the agent is away at now and the notice to send.
+// The manual state wins over the schedule.
+func (t *Tracker) Away(now time.Time) (bool, string) {
+	if m, err := LoadManual(t.manualPath); err == nil && m != nil && m.Active(now) {
+		if m.Away && strings.TrimSpace(m.Note) != "" {
+			return true, strings.TrimSpace(m.Note)
+		}
+		return m.Away, t.notice
+	}
+	local := now.In(t.loc)
+	for _, w := range t.windows {
+		if w.contains(local) {
+			return true, t.notice
+		}
+	}
+	return false, ""
+}
+
+// Urgent reports whether a message should be answered even while away.
+func (t *Tracker) Urgent(content string) bool {
+	content = strings.ToLower(content)
+	for _, u := range t.urgent {
+		if strings.Contains(content, u) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hold queues msg until the agent is back. It reports whether the chat
+// still needs the away notice, which is sent once per away period.
+func (t *Tracker) Hold(msg bus.InboundMessage) (notify bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queue = append(t.queue, msg)
+	if over := len(t.queue) - t.maxQueued; over > 0 {
+		t.queue = t.queue[over:]
+	}
+	notify = !t.notified[msg.SessionKey]
+	t.notified[msg.SessionKey] = true
+	return notify, t.saveLocked()
+}
+
+// Release returns the queued messages once the agent is back at now, and
+// forgets which chats were notified.
+func (t *Tracker) Release(now time.Time) ([]bus.InboundMessage, error) {
+	if away, _ := t.Away(now); away {
+		return nil, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.notified)
+	if len(t.queue) == 0 {
+		return nil, nil
+	}
+	out := t.queue
+	t.queue = nil
+	return out, t.saveLocked()
+}
+
+// Drop removes the queued messages for which match is true and returns how
+// many there were; with dryRun they are only counted.
+func (t *Tracker) Drop(match func(bus.InboundMessage) bool, dryRun bool) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	kept := t.queue[:0:0]
+	for _, msg := range t.queue {
+		if !match(msg) {
+			kept = append(kept, msg)
+		}
+	}
+	dropped := len(t.queue) - len(kept)
+	if dryRun || dropped == 0 {
+		return dropped, nil
+	}
+	t.queue = kept
+	return dropped, t.saveLocked()
+}
+
+// Queued is the number of messages waiting.
+func (t *Tracker) Queued() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.queue)
+}
+
+func (t *Tracker) saveLocked() error {
+	if len(t.queue) == 0 {
+		if err := os.Remove(t.queuePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	b, err := json.Marshal(t.queue)
+	if err != nil {
+		return err
+	}
+	return atrest.WriteFile(t.queuePath, b, 0o600)
+}
+
+func writeJSON(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
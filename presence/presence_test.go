@@ -0,0 +1,163 @@
+package presence
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/atrest"
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestTracker_Schedule(t *testing.T) {
+	dir := t.TempDir()
+	tr, err := New(config.PresenceConfig{
+		Timezone: "UTC",
+		Away: []config.AwayWindow{
+			{From: "22:00", To: "07:00", Days: []string{"fri"}},
+			{From: "12:00", To: "13:00"},
+		},
+	}, filepath.Join(dir, "presence.json"), filepath.Join(dir, "queue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		v, err := time.Parse("Mon 2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for _, tc := range []struct {
+		when string
+		away bool
+	}{
+		{"Fri 2026-10-16 23:30", true},
+		{"Sat 2026-10-17 06:59", true}, // Friday's window runs past midnight
+		{"Sat 2026-10-17 07:00", false},
+		{"Sat 2026-10-17 23:30", false},
+		{"Sun 2026-10-18 12:15", true},
+	} {
+		if away, _ := tr.Away(at(tc.when)); away != tc.away {
+			t.Fatalf("%s: away=%v, want %v", tc.when, away, tc.away)
+		}
+	}
+
+	// By hand: back overrides the schedule, away brings its note.
+	if err := SaveManual(tr.manualPath, &Manual{Away: false}); err != nil {
+		t.Fatal(err)
+	}
+	if away, _ := tr.Away(at("Fri 2026-10-16 23:30")); away {
+		t.Fatal("manual back should override the schedule")
+	}
+	if err := SaveManual(tr.manualPath, &Manual{Away: true, Note: "On holiday", Until: at("Mon 2026-10-19 09:00")}); err != nil {
+		t.Fatal(err)
+	}
+	if away, notice := tr.Away(at("Sat 2026-10-17 10:00")); !away || notice != "On holiday" {
+		t.Fatalf("manual away: %v %q", away, notice)
+	}
+	if away, _ := tr.Away(at("Mon 2026-10-19 10:00")); away {
+		t.Fatal("manual away should end at until")
+	}
+}
+
+func TestTracker_HoldAndRelease(t *testing.T) {
+	dir := t.TempDir()
+	manual, queue := filepath.Join(dir, "presence.json"), filepath.Join(dir, "queue.json")
+	cfg := config.PresenceConfig{MaxQueued: 2}
+	tr, err := New(cfg, manual, queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tr.Urgent("This is URGENT, call me") || tr.Urgent("see you tomorrow") {
+		t.Fatal("urgent keyword")
+	}
+	if err := SaveManual(manual, &Manual{Away: true}); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{true, false, false} {
+		notify, err := tr.Hold(bus.InboundMessage{SessionKey: "telegram:1", Content: string(rune('a' + i))})
+		if err != nil || notify != want {
+			t.Fatalf("hold %d: notify=%v err=%v", i, notify, err)
+		}
+	}
+	if got, _ := tr.Release(time.Now()); got != nil {
+		t.Fatal("released while away")
+	}
+
+	// The queue survives a restart.
+	tr, err = New(cfg, manual, queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveManual(manual, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := tr.Release(time.Now())
+	if err != nil || len(got) != 2 || got[0].Content != "b" || got[1].Content != "c" {
+		t.Fatalf("released %+v, %v", got, err)
+	}
+	if tr.Queued() != 0 {
+		t.Fatal("queue not emptied")
+	}
+}
+
+func TestNew_InvalidSchedule(t *testing.T) {
+	dir := t.TempDir()
+	for _, w := range []config.AwayWindow{
+		{From: "25:00", To: "07:00"},
+		{From: "22:00", To: "22:00"},
+		{From: "22:00", To: "07:00", Days: []string{"someday"}},
+	} {
+		if _, err := New(config.PresenceConfig{Away: []config.AwayWindow{w}}, filepath.Join(dir, "p"), filepath.Join(dir, "q")); err == nil {
+			t.Fatalf("expected error for %+v", w)
+		}
+	}
+}
+
+func TestTracker_QueueEncryptedAndDropped(t *testing.T) {
+	c, err := atrest.NewPassphrase("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	atrest.SetDefault(c)
+	t.Cleanup(func() { atrest.SetDefault(nil) })
+
+	dir := t.TempDir()
+	manual, queue := filepath.Join(dir, "presence.json"), filepath.Join(dir, "queue.json")
+	tr, err := New(config.PresenceConfig{}, manual, queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []bus.InboundMessage{
+		{Channel: "telegram", SenderID: "111", Content: "my address is 1 Main St"},
+		{Channel: "telegram", SenderID: "222", Content: "hello"},
+	} {
+		if _, err := tr.Hold(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(queue)
+	if err != nil || !atrest.IsEncrypted(b) || strings.Contains(string(b), "Main St") {
+		t.Fatalf("queue not encrypted: %q %v", b, err)
+	}
+
+	fromAlice := func(m bus.InboundMessage) bool { return m.SenderID == "111" }
+	if n, err := tr.Drop(fromAlice, true); n != 1 || err != nil || tr.Queued() != 2 {
+		t.Fatalf("dry run: dropped %d, %v, %d queued", n, err, tr.Queued())
+	}
+	if n, err := tr.Drop(fromAlice, false); n != 1 || err != nil {
+		t.Fatalf("dropped %d, %v", n, err)
+	}
+	tr, err = New(config.PresenceConfig{}, manual, queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tr.Release(time.Now())
+	if err != nil || len(got) != 1 || got[0].SenderID != "222" {
+		t.Fatalf("released %+v, %v", got, err)
+	}
+}
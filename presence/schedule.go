@@ -0,0 +1,74 @@
+package presence
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// window is an away window in minutes since midnight. days are the days it
+// starts on; none means every day.
+type window struct {
+	days     []time.Weekday
+	from, to int
+}
+
+func parseWindow(w config.AwayWindow) (window, error) {
+	var out window
+	for _, d := range w.Days {
+		name := strings.ToLower(strings.TrimSpace(d))
+		if len(name) > 3 {
+			name = name[:3] // "monday"
+		}
+		wd, ok := weekdays[name]
+		if !ok {
+			return window{}, fmt.Errorf("unknown day %q (use mon to sun)", d)
+		}
+		out.days = append(out.days, wd)
+	}
+	var err error
+	if out.from, err = parseClock(w.From); err != nil {
+		return window{}, err
+	}
+	if out.to, err = parseClock(w.To); err != nil {
+		return window{}, err
+	}
+	if out.from == out.to {
+		return window{}, fmt.Errorf("window from %s to %s is empty", w.From, w.To)
+	}
+	return out, nil
+}
+
+// parseClock reads "HH:MM" as minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains reports whether t, in the schedule's time zone, is inside the
+// window. A window past midnight belongs to the day it starts on.
+func (w window) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.from < w.to {
+		return m >= w.from && m < w.to && w.on(t.Weekday())
+	}
+	if m >= w.from {
+		return w.on(t.Weekday())
+	}
+	return m < w.to && w.on((t.Weekday()+6)%7)
+}
+
+func (w window) on(d time.Weekday) bool {
+	return len(w.days) == 0 || slices.Contains(w.days, d)
+}
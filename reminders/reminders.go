@@ -0,0 +1,196 @@
+// Package reminders turns a reminder the model read from a user's message
+// into a cron schedule, and says back when it fires.
+//
+// The model does the language part: it fills in a Request with a calendar
+// date and a clock time. Parse checks the result, so a misread date is an
+// error the model sees rather than a reminder that never fires.
+package reminders
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/cron"
+)
+
+// PayloadKind marks cron jobs that are reminders. They are sent to their
+// chat as they are, without an agent turn.
+const PayloadKind = "reminder"
+
+// How a reminder repeats.
+const (
+	RepeatNone     = "none"
+	RepeatDaily    = "daily"
+	RepeatWeekdays = "weekdays"
+	RepeatWeekly   = "weekly"
+	RepeatMonthly  = "monthly"
+)
+
+// maxText bounds the text of a reminder.
+const maxText = 500
+
+// Request is a reminder as the model reads it from the user's words.
+type Request struct {
+	Text string
+	// Date is YYYY-MM-DD: the day of a one-off reminder. For weekly and
+	// monthly ones it gives the weekday or the day of the month; daily
+	// ones don't need it.
+	Date string
+	// Time is the clock time, HH:MM on a 24-hour clock.
+	Time string
+	// Timezone is an IANA name for a time the user gave in another zone;
+	// empty for the default.
+	Timezone string
+	Repeat   string
+}
+
+// Reminder is a checked request, ready to be scheduled.
+type Reminder struct {
+	Text     string
+	Repeat   string
+	Schedule cron.Schedule
+	// Next is when the reminder first fires, in its time zone.
+	Next time.Time
+	// YourTime is set when the reminder is in the default time zone.
+	YourTime bool
+}
+
+// Parse checks r and schedules it after now. loc is the default time zone.
+func Parse(r Request, now time.Time, loc *time.Location) (Reminder, error) {
+	text := strings.TrimSpace(r.Text)
+	if text == "" {
+		return Reminder{}, errors.New("text is required")
+	}
+	if len([]rune(text)) > maxText {
+		return Reminder{}, fmt.Errorf("text is too long (max %d characters)", maxText)
+	}
+	repeat := strings.ToLower(strings.TrimSpace(r.Repeat))
+	if repeat == "" {
+		repeat = RepeatNone
+	}
+	out := Reminder{Text: text, Repeat: repeat, YourTime: true}
+	if tz := strings.TrimSpace(r.Timezone); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return Reminder{}, fmt.Errorf("unknown timezone %q", tz)
+		}
+		out.YourTime = l.String() == loc.String()
+		loc = l
+	}
+	clock, err := time.Parse("15:04", strings.TrimSpace(r.Time))
+	if err != nil {
+		return Reminder{}, fmt.Errorf("time must be HH:MM on a 24-hour clock, got %q", r.Time)
+	}
+	var day time.Time
+	if d := strings.TrimSpace(r.Date); d != "" {
+		day, err = time.ParseInLocation("2006-01-02", d, loc)
+		if err != nil {
+			return Reminder{}, fmt.Errorf("date must be YYYY-MM-DD, got %q", r.Date)
+		}
+	} else if repeat != RepeatDaily && repeat != RepeatWeekdays {
+		return Reminder{}, errors.New("date is required")
+	}
+
+	if repeat == RepeatNone {
+		at := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+		if !at.After(now) {
+			return Reminder{}, fmt.Errorf("%s is in the past; it is now %s", at.Format("2006-01-02 15:04 MST"), now.In(loc).Format("2006-01-02 15:04 MST"))
+		}
+		out.Schedule = cron.Schedule{Kind: "at", AtMS: at.UnixMilli()}
+		out.Next = at
+		return out, nil
+	}
+
+	var days string
+	switch repeat {
+	case RepeatDaily:
+		days = "* * *"
+	case RepeatWeekdays:
+		days = "* * 1-5"
+	case RepeatWeekly:
+		days = "* * " + strconv.Itoa(int(day.Weekday()))
+	case RepeatMonthly:
+		days = strconv.Itoa(day.Day()) + " * *"
+	default:
+		return Reminder{}, fmt.Errorf("unknown repeat %q (use none, daily, weekdays, weekly or monthly)", r.Repeat)
+	}
+	out.Schedule = cron.Schedule{
+		Kind: "cron",
+		Expr: fmt.Sprintf("%d %d %s", clock.Minute(), clock.Hour(), days),
+	}
+	if loc != time.Local {
+		out.Schedule.TZ = loc.String()
+	}
+	out.Next = out.Schedule.Next(now)
+	if out.Next.IsZero() {
+		return Reminder{}, errors.New("the reminder would never fire")
+	}
+	out.Next = out.Next.In(loc)
+	return out, nil
+}
+
+// Confirmation says when the reminder fires, e.g. "OK — Tuesday 9:00 AM,
+// your time."
+func (r Reminder) Confirmation(now time.Time) string {
+	t := r.Next
+	when := t.Format("3:04 PM")
+	switch r.Repeat {
+	case RepeatDaily:
+		when = "every day at " + when
+	case RepeatWeekdays:
+		when = "every weekday at " + when
+	case RepeatWeekly:
+		when = "every " + t.Format("Monday") + " at " + when
+	case RepeatMonthly:
+		when = "on the " + ordinal(t.Day()) + " of every month at " + when
+	default:
+		when = Day(t, now) + " " + when
+	}
+	if r.YourTime {
+		return "OK — " + when + ", your time."
+	}
+	return "OK — " + when + " (" + t.Location().String() + ")."
+}
+
+// Day names t's day as seen at now: "today", "tomorrow", a weekday within
+// the week, or else the date.
+func Day(t, now time.Time) string {
+	now = now.In(t.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, t.Location())
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch n := int(day.Sub(today).Hours()+12) / 24; {
+	case n == 0:
+		return "today"
+	case n == 1:
+		return "tomorrow"
+	case n > 1 && n < 7:
+		return t.Format("Monday")
+	case t.Year() == now.Year():
+		return t.Format("Monday 2 January")
+	default:
+		return t.Format("Monday 2 January 2006")
+	}
+}
+
+// Message is the text sent when a reminder fires.
+func Message(text string) string {
+	return "⏰ Reminder: " + text
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
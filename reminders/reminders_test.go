@@ -0,0 +1,95 @@
+package reminders
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_OneOff(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	now := time.Date(2026, 10, 16, 18, 0, 0, 0, berlin) // a Friday
+	r, err := Parse(Request{Text: " call mom ", Date: "2026-10-20", Time: "9:00"}, now, berlin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Text != "call mom" || r.Schedule.Kind != "at" || !r.Next.Equal(time.Date(2026, 10, 20, 9, 0, 0, 0, berlin)) {
+		t.Fatalf("got %+v", r)
+	}
+	if got := r.Confirmation(now); got != "OK — Tuesday 9:00 AM, your time." {
+		t.Fatalf("confirmation %q", got)
+	}
+
+	r, err = Parse(Request{Text: "standup", Date: "2026-10-17", Time: "15:30", Timezone: "America/New_York"}, now, berlin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Confirmation(now); got != "OK — tomorrow 3:30 PM (America/New_York)." {
+		t.Fatalf("confirmation %q", got)
+	}
+}
+
+func TestParse_Repeating(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	now := time.Date(2026, 10, 16, 18, 0, 0, 0, berlin)
+	for _, tc := range []struct {
+		req     Request
+		expr    string
+		confirm string
+	}{
+		{Request{Text: "pills", Time: "08:00", Repeat: "daily"}, "0 8 * * *", "OK — every day at 8:00 AM, your time."},
+		{Request{Text: "standup", Time: "09:15", Repeat: "weekdays"}, "15 9 * * 1-5", "OK — every weekday at 9:15 AM, your time."},
+		{Request{Text: "bins", Date: "2026-10-20", Time: "19:00", Repeat: "weekly"}, "0 19 * * 2", "OK — every Tuesday at 7:00 PM, your time."},
+		{Request{Text: "rent", Date: "2026-11-01", Time: "10:00", Repeat: "monthly"}, "0 10 1 * *", "OK — on the 1st of every month at 10:00 AM, your time."},
+	} {
+		r, err := Parse(tc.req, now, berlin)
+		if err != nil {
+			t.Fatalf("%+v: %v", tc.req, err)
+		}
+		if r.Schedule.Kind != "cron" || r.Schedule.Expr != tc.expr || r.Schedule.TZ != "Europe/Berlin" {
+			t.Fatalf("%+v: schedule %+v", tc.req, r.Schedule)
+		}
+		if got := r.Confirmation(now); got != tc.confirm {
+			t.Fatalf("%+v: confirmation %q", tc.req, got)
+		}
+		if !r.Next.After(now) {
+			t.Fatalf("%+v: next %v", tc.req, r.Next)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	now := time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		req  Request
+		want string
+	}{
+		{Request{Date: "2026-10-20", Time: "09:00"}, "text is required"},
+		{Request{Text: "x", Date: "2026-10-20", Time: "9am"}, "HH:MM"},
+		{Request{Text: "x", Date: "20/10/2026", Time: "09:00"}, "YYYY-MM-DD"},
+		{Request{Text: "x", Time: "09:00"}, "date is required"},
+		{Request{Text: "x", Date: "2026-10-16", Time: "09:00"}, "in the past"},
+		{Request{Text: "x", Date: "2026-10-20", Time: "09:00", Timezone: "Mars/Base"}, "unknown timezone"},
+		{Request{Text: "x", Date: "2026-10-20", Time: "09:00", Repeat: "hourly"}, "unknown repeat"},
+	} {
+		_, err := Parse(tc.req, now, time.UTC)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%+v: err = %v, want %q", tc.req, err, tc.want)
+		}
+	}
+}
+
+func TestDay(t *testing.T) {
+	now := time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)
+	for days, want := range map[int]string{0: "today", 1: "tomorrow", 3: "Monday", 10: "Monday 26 October", 80: "Monday 4 January 2027"} {
+		if got := Day(now.AddDate(0, 0, days), now); got != want {
+			t.Fatalf("%d days: got %q, want %q", days, got, want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Kinds of image PNG draws.
+const (
+	KindBar   = "bar"
+	KindLine  = "line"
+	KindTable = "table"
+)
+
+const (
+	chartW     = 480
+	plotH      = 220
+	yTickCount = 5
+	// maxPoints is how many rows a chart draws at most.
+	maxPoints      = 200
+	maxLegendChars = 30
+)
+
+// PNG draws t as a bar chart, a line chart or a table, under title.
+//
+// A chart takes its labels from the first column and draws each column of
+// numbers after it as a series; a table with a single column of numbers is
+// charted by row number.
+func PNG(t Table, kind, title string) ([]byte, error) {
+	title = strings.TrimSpace(title)
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case KindBar:
+		return chart(t, false, title)
+	case KindLine:
+		return chart(t, true, title)
+	case KindTable, "":
+		return table(t, title)
+	default:
+		return nil, fmt.Errorf("unknown kind %q (use bar, line or table)", kind)
+	}
+}
+
+type series struct {
+	name string
+	// values has NaN for empty cells.
+	values []float64
+}
+
+func chartSeries(t Table) ([]string, []series, error) {
+	if len(t.Rows) == 0 {
+		return nil, nil, errors.New("data has no rows")
+	}
+	if len(t.Rows) > maxPoints {
+		return nil, nil, fmt.Errorf("too many rows to chart: %d (at most %d)", len(t.Rows), maxPoints)
+	}
+	labels := make([]string, len(t.Rows))
+	first := 1
+	if len(t.Header) == 1 {
+		first = 0
+		for i := range labels {
+			labels[i] = strconv.Itoa(i + 1)
+		}
+	} else {
+		for i, row := range t.Rows {
+			labels[i] = row[0]
+		}
+	}
+	var ss []series
+	for c := first; c < len(t.Header); c++ {
+		values, ok := numericColumn(t, c)
+		if !ok {
+			continue
+		}
+		if len(ss) == len(palette) {
+			return nil, nil, fmt.Errorf("too many series to chart (at most %d)", len(palette))
+		}
+		ss = append(ss, series{name: t.Header[c], values: values})
+	}
+	if len(ss) == 0 {
+		return nil, nil, errors.New("no column of numbers to chart")
+	}
+	return labels, ss, nil
+}
+
+// numericColumn reads column c as numbers. It fails when a cell is not a
+// number or every cell is empty.
+func numericColumn(t Table, c int) ([]float64, bool) {
+	values := make([]float64, len(t.Rows))
+	found := false
+	for i, row := range t.Rows {
+		if strings.TrimSpace(row[c]) == "" {
+			values[i] = math.NaN()
+			continue
+		}
+		v, ok := Number(row[c])
+		if !ok {
+			return nil, false
+		}
+		values[i], found = v, true
+	}
+	return values, found
+}
+
+func chart(t Table, line bool, title string) ([]byte, error) {
+	labels, ss, err := chartSeries(t)
+	if err != nil {
+		return nil, err
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range ss {
+		for _, v := range s.values {
+			if !math.IsNaN(v) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+	}
+	if !line {
+		// Bars grow from zero.
+		lo, hi = math.Min(lo, 0), math.Max(hi, 0)
+	}
+	ticks, step := niceTicks(lo, hi, yTickCount)
+	lo, hi = ticks[0], ticks[len(ticks)-1]
+	tickText := make([]string, len(ticks))
+	yLabelW := 0
+	for i, v := range ticks {
+		tickText[i] = formatTick(v, step)
+		yLabelW = max(yLabelW, textWidth(tickText[i]))
+	}
+
+	top := 10
+	if title != "" {
+		top += lineH + 8
+	}
+	legend := legendLayout(ss, chartW-20)
+	plot := image.Rect(10+yLabelW+6, top, chartW-14, top+plotH)
+	h := plot.Max.Y + lineH + 12
+	if len(legend) > 0 {
+		h += legend[len(legend)-1].y + lineH + 6
+	}
+	img := newCanvas(chartW, h)
+	if title != "" {
+		drawBold(img, 10, 10, fit(title, (chartW-20)/charW), colorText)
+	}
+
+	py := func(v float64) int {
+		return plot.Max.Y - int(math.Round((v-lo)/(hi-lo)*float64(plot.Dy())))
+	}
+	for i, v := range ticks {
+		y := py(v)
+		fillRect(img, image.Rect(plot.Min.X, y, plot.Max.X, y+1), colorGrid)
+		drawText(img, plot.Min.X-6-textWidth(tickText[i]), y-lineH/2, tickText[i], colorMuted)
+	}
+	base := py(math.Max(lo, math.Min(0, hi)))
+	fillRect(img, image.Rect(plot.Min.X, plot.Min.Y, plot.Min.X+1, plot.Max.Y+1), colorAxis)
+	fillRect(img, image.Rect(plot.Min.X, base, plot.Max.X, base+1), colorAxis)
+
+	n := len(labels)
+	slot := float64(plot.Dx()) / float64(n)
+	cx := func(i int) int {
+		return plot.Min.X + int(slot*(float64(i)+0.5))
+	}
+	// Labels are skipped evenly when they don't fit under every point.
+	every := max(1, int(math.Ceil(float64(charW*4)/slot)))
+	chars := max(1, int(slot*float64(every))/charW-1)
+	for i := 0; i < n; i += every {
+		l := fit(labels[i], chars)
+		drawText(img, cx(i)-textWidth(l)/2, plot.Max.Y+6, l, colorMuted)
+	}
+
+	if line {
+		for j, s := range ss {
+			c := palette[j]
+			px, pyPrev := -1, 0
+			for i, v := range s.values {
+				if math.IsNaN(v) {
+					px = -1
+					continue
+				}
+				x, y := cx(i), py(v)
+				if px >= 0 {
+					drawLine(img, px, pyPrev, x, y, c)
+				}
+				if n <= 40 {
+					fillRect(img, image.Rect(x-2, y-2, x+3, y+3), c)
+				}
+				px, pyPrev = x, y
+			}
+		}
+	} else {
+		group := slot * 0.8
+		barW := group / float64(len(ss))
+		for i := range labels {
+			left := float64(cx(i)) - group/2
+			for j, s := range ss {
+				if math.IsNaN(s.values[i]) {
+					continue
+				}
+				x0 := int(left + barW*float64(j))
+				x1 := max(x0+1, int(left+barW*float64(j+1))-1)
+				y := py(s.values[i])
+				fillRect(img, image.Rect(x0, min(y, base), x1, max(y, base)+1), palette[j])
+			}
+		}
+	}
+
+	legendTop := plot.Max.Y + lineH + 12
+	for _, item := range legend {
+		x, y := 10+item.x, legendTop+item.y
+		fillRect(img, image.Rect(x, y+2, x+10, y+12), palette[item.series])
+		drawText(img, x+14, y, fit(ss[item.series].name, maxLegendChars), colorText)
+	}
+	return encode(img)
+}
+
+type legendItem struct {
+	series int
+	x, y   int
+}
+
+// legendLayout places the names of the series in rows of at most width;
+// a single series needs no legend.
+func legendLayout(ss []series, width int) []legendItem {
+	if len(ss) < 2 {
+		return nil
+	}
+	var items []legendItem
+	x, y := 0, 0
+	for i, s := range ss {
+		w := 14 + textWidth(fit(s.name, maxLegendChars)) + 14
+		if x > 0 && x+w > width {
+			x, y = 0, y+lineH+4
+		}
+		items = append(items, legendItem{series: i, x: x, y: y})
+		x += w
+	}
+	return items
+}
+
+// niceTicks picks about n round values covering lo to hi, and their step.
+func niceTicks(lo, hi float64, n int) ([]float64, float64) {
+	if hi <= lo {
+		lo, hi = lo-1, hi+1
+	}
+	step := niceNum((hi - lo) / float64(n))
+	start := math.Floor(lo/step) * step
+	count := int(math.Round((math.Ceil(hi/step)*step-start)/step)) + 1
+	ticks := make([]float64, count)
+	for i := range ticks {
+		ticks[i] = start + float64(i)*step
+	}
+	return ticks, step
+}
+
+func niceNum(x float64) float64 {
+	exp := math.Floor(math.Log10(x))
+	f := x / math.Pow(10, exp)
+	switch {
+	case f <= 1:
+		f = 1
+	case f <= 2:
+		f = 2
+	case f <= 5:
+		f = 5
+	default:
+		f = 10
+	}
+	return f * math.Pow(10, exp)
+}
+
+func formatTick(v, step float64) string {
+	decimals := max(0, int(-math.Floor(math.Log10(step))))
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if strings.Trim(s, "-0.") == "" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,172 @@
+// Package render draws tabular data as PNG images, charts and tables, for
+// chat apps that show long tables badly as text.
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Table is data to draw: a header and rows of cells.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Parse reads CSV, or JSON that is an array of objects or an array of
+// arrays. The first CSV line and the first JSON array are the header; for
+// objects it is their keys, in the order they first appear. format is
+// "csv", "json", or "" to tell from the data.
+func Parse(data, format string) (Table, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return Table{}, errors.New("data is empty")
+	}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "":
+		if strings.HasPrefix(data, "[") {
+			return parseJSON(data)
+		}
+		return parseCSV(data)
+	case "csv":
+		return parseCSV(data)
+	case "json":
+		return parseJSON(data)
+	default:
+		return Table{}, fmt.Errorf("unknown format %q (use csv or json)", format)
+	}
+}
+
+func parseCSV(data string) (Table, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return Table{}, fmt.Errorf("csv: %w", err)
+	}
+	return newTable(records[0], records[1:])
+}
+
+func parseJSON(data string) (Table, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &items); err != nil {
+		return Table{}, fmt.Errorf("json: %w", err)
+	}
+	if len(items) == 0 {
+		return Table{}, errors.New("json: no rows")
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(items[0]), []byte("[")) {
+		records := make([][]string, 0, len(items))
+		for i, item := range items {
+			var cells []json.RawMessage
+			if err := json.Unmarshal(item, &cells); err != nil {
+				return Table{}, fmt.Errorf("json: row %d: %w", i, err)
+			}
+			row := make([]string, len(cells))
+			for j, c := range cells {
+				row[j] = jsonCell(c)
+			}
+			records = append(records, row)
+		}
+		return newTable(records[0], records[1:])
+	}
+	var header []string
+	col := map[string]int{}
+	objects := make([]map[string]json.RawMessage, 0, len(items))
+	for i, item := range items {
+		keys, obj, err := orderedObject(item)
+		if err != nil {
+			return Table{}, fmt.Errorf("json: row %d: %w", i, err)
+		}
+		for _, k := range keys {
+			if _, ok := col[k]; !ok {
+				col[k] = len(header)
+				header = append(header, k)
+			}
+		}
+		objects = append(objects, obj)
+	}
+	rows := make([][]string, 0, len(objects))
+	for _, obj := range objects {
+		row := make([]string, len(header))
+		for k, v := range obj {
+			row[col[k]] = jsonCell(v)
+		}
+		rows = append(rows, row)
+	}
+	return newTable(header, rows)
+}
+
+// orderedObject decodes a JSON object and the order of its keys, which a
+// map loses.
+func orderedObject(raw json.RawMessage) ([]string, map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, errors.New("want an object or an array")
+	}
+	var keys []string
+	obj := map[string]json.RawMessage{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		k, _ := tok.(string)
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := obj[k]; !ok {
+			keys = append(keys, k)
+		}
+		obj[k] = v
+	}
+	return keys, obj, nil
+}
+
+func jsonCell(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	v := string(bytes.TrimSpace(raw))
+	if v == "null" {
+		return ""
+	}
+	return v
+}
+
+func newTable(header []string, rows [][]string) (Table, error) {
+	if len(header) == 0 {
+		return Table{}, errors.New("data has no columns")
+	}
+	for i, row := range rows {
+		switch {
+		case len(row) < len(header):
+			rows[i] = append(row, make([]string, len(header)-len(row))...)
+		case len(row) > len(header):
+			rows[i] = row[:len(header)]
+		}
+	}
+	return Table{Header: header, Rows: rows}, nil
+}
+
+// Number reads a cell as a number. Thousands separators, a leading
+// currency sign and a trailing percent sign are allowed.
+func Number(cell string) (float64, bool) {
+	s := strings.TrimSpace(cell)
+	s = strings.TrimSuffix(s, "%")
+	s = strings.TrimLeft(s, "$€£¥")
+	s = strings.ReplaceAll(s, ",", "")
+	if s == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
@@ -0,0 +1,139 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// scale enlarges the finished image, so the small built-in font stays
+	// sharp and readable on phones.
+	scale = 2
+	// charW and lineH are the cell size of the font; it has glyphs for
+	// ASCII only and draws other characters as a box.
+	charW = 7
+	lineH = 13
+)
+
+var (
+	colorBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	colorText       = color.RGBA{0x22, 0x22, 0x22, 0xff}
+	colorMuted      = color.RGBA{0x77, 0x77, 0x77, 0xff}
+	colorGrid       = color.RGBA{0xe3, 0xe3, 0xe3, 0xff}
+	colorAxis       = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	colorHeader     = color.RGBA{0xec, 0xef, 0xf4, 0xff}
+	colorStripe     = color.RGBA{0xf7, 0xf8, 0xfa, 0xff}
+
+	// palette colors the series of a chart, in order.
+	palette = []color.RGBA{
+		{0x4e, 0x79, 0xa7, 0xff},
+		{0xf2, 0x8e, 0x2b, 0xff},
+		{0x59, 0xa1, 0x4f, 0xff},
+		{0xe1, 0x57, 0x59, 0xff},
+		{0x76, 0xb7, 0xb2, 0xff},
+		{0xb0, 0x7a, 0xa1, 0xff},
+		{0xed, 0xc9, 0x48, 0xff},
+		{0x9c, 0x75, 0x5f, 0xff},
+	}
+)
+
+func newCanvas(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(colorBackground), image.Point{}, draw.Src)
+	return img
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// drawText draws s with its top left corner at x, y.
+func drawText(img *image.RGBA, x, y int, s string, c color.Color) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y+basicfont.Face7x13.Ascent),
+	}
+	d.DrawString(s)
+}
+
+// drawBold draws s twice, a pixel apart.
+func drawBold(img *image.RGBA, x, y int, s string, c color.Color) {
+	drawText(img, x, y, s, c)
+	drawText(img, x+1, y, s, c)
+}
+
+func textWidth(s string) int {
+	return len([]rune(s)) * charW
+}
+
+// fit shortens s to at most n characters, marking the cut with "..".
+func fit(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 2 {
+		return string(r[:max(n, 0)])
+	}
+	return string(r[:n-2]) + ".."
+}
+
+// drawLine draws a line two pixels thick from (x0, y0) to (x1, y1).
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		fillRect(img, image.Rect(x0, y0, x0+2, y0+2), c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// encode enlarges img by scale and encodes it as PNG.
+func encode(img *image.RGBA) ([]byte, error) {
+	b := img.Bounds()
+	big := image.NewRGBA(image.Rect(0, 0, b.Dx()*scale, b.Dy()*scale))
+	for y := 0; y < big.Rect.Dy(); y++ {
+		for x := 0; x < big.Rect.Dx(); x++ {
+			big.SetRGBA(x, y, img.RGBAAt(b.Min.X+x/scale, b.Min.Y+y/scale))
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, big); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
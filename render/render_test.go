@@ -0,0 +1,102 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	want := Table{Header: []string{"month", "sales"}, Rows: [][]string{{"Jan", "10"}, {"Feb", "12.5"}}}
+	for name, in := range map[string]string{
+		"csv":     "month,sales\nJan,10\nFeb,12.5\n",
+		"objects": `[{"month":"Jan","sales":10},{"month":"Feb","sales":12.5}]`,
+		"arrays":  `[["month","sales"],["Jan",10],["Feb",12.5]]`,
+	} {
+		got, err := Parse(in, "")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestParse_ObjectsKeepKeyOrderAndFillGaps(t *testing.T) {
+	got, err := Parse(`[{"b":1,"a":"x"},{"c":true}]`, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Table{Header: []string{"b", "a", "c"}, Rows: [][]string{{"1", "x", ""}, {"", "", "true"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, tc := range []struct{ data, format string }{
+		{"", ""},
+		{"[]", ""},
+		{"[1,2]", "json"},
+		{"a,b", "xml"},
+	} {
+		if _, err := Parse(tc.data, tc.format); err == nil {
+			t.Fatalf("Parse(%q, %q): want error", tc.data, tc.format)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	for in, want := range map[string]float64{"12": 12, "1,234.5": 1234.5, "$40": 40, "7%": 7, "-3": -3} {
+		if got, ok := Number(in); !ok || got != want {
+			t.Fatalf("Number(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+	if _, ok := Number("n/a"); ok {
+		t.Fatal("Number(n/a) should fail")
+	}
+}
+
+func TestPNG(t *testing.T) {
+	tbl, err := Parse("day,visits,signups\nMon,120,4\nTue,98,\nWed,143,9\n", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []string{KindBar, KindLine, KindTable} {
+		b, err := PNG(tbl, kind, "Last week")
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		img, err := png.Decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if img.Bounds().Dx() < 100 || img.Bounds().Dy() < 50 {
+			t.Fatalf("%s: image too small: %v", kind, img.Bounds())
+		}
+	}
+	if _, err := PNG(tbl, "pie", ""); err == nil {
+		t.Fatal("want error for unknown kind")
+	}
+}
+
+func TestPNG_ChartNeedsNumbers(t *testing.T) {
+	tbl, _ := Parse("name,city\nAnn,Oslo\n", "csv")
+	_, err := PNG(tbl, KindBar, "")
+	if err == nil || !strings.Contains(err.Error(), "no column of numbers") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestNiceTicks(t *testing.T) {
+	ticks, step := niceTicks(0, 143, 5)
+	if step != 50 || ticks[0] != 0 || ticks[len(ticks)-1] != 150 {
+		t.Fatalf("ticks = %v, step %v", ticks, step)
+	}
+	if got := formatTick(2.5, 0.5); got != "2.5" {
+		t.Fatalf("formatTick = %q", got)
+	}
+}
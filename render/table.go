@@ -0,0 +1,110 @@
+package render
+
+import (
+	"fmt"
+	"image"
+)
+
+const (
+	// maxTableRows is how many rows a table image shows; the rest are
+	// counted under it.
+	maxTableRows = 100
+	maxCellChars = 40
+	minCellChars = 6
+	maxTableW    = 1200
+	cellPadX     = 6
+	rowH         = lineH + 8
+)
+
+func table(t Table, title string) ([]byte, error) {
+	title = fit(title, maxTableW/charW)
+	rows := t.Rows
+	more := 0
+	if len(rows) > maxTableRows {
+		rows, more = rows[:maxTableRows], len(rows)-maxTableRows
+	}
+	cols := len(t.Header)
+	widths := make([]int, cols)
+	numeric := make([]bool, cols)
+	for c, h := range t.Header {
+		widths[c] = len([]rune(h))
+		for _, row := range rows {
+			widths[c] = max(widths[c], len([]rune(row[c])))
+		}
+		widths[c] = min(max(widths[c], 1), maxCellChars)
+		_, numeric[c] = numericColumn(t, c)
+	}
+	// Wide tables give up characters from their widest columns first.
+	for tableWidth(widths) > maxTableW {
+		widest := 0
+		for c := range widths {
+			if widths[c] > widths[widest] {
+				widest = c
+			}
+		}
+		if widths[widest] <= minCellChars {
+			break
+		}
+		widths[widest]--
+	}
+
+	w := 20 + tableWidth(widths)
+	top := 10
+	if title != "" {
+		top += lineH + 8
+	}
+	h := top + rowH*(len(rows)+1) + 10
+	if more > 0 {
+		h += lineH + 6
+	}
+	if title != "" {
+		w = max(w, 20+textWidth(title))
+	}
+	img := newCanvas(w, h)
+	if title != "" {
+		drawBold(img, 10, 10, title, colorText)
+	}
+
+	right := 10 + tableWidth(widths)
+	cell := func(y int, cells []string, bold bool) {
+		x := 10
+		for c, s := range cells {
+			s = fit(s, widths[c])
+			tx := x + cellPadX
+			if numeric[c] {
+				tx = x + cellPadX + (widths[c]*charW - textWidth(s))
+			}
+			if bold {
+				drawBold(img, tx, y+4, s, colorText)
+			} else {
+				drawText(img, tx, y+4, s, colorText)
+			}
+			x += widths[c]*charW + 2*cellPadX
+		}
+	}
+	fillRect(img, image.Rect(10, top, right, top+rowH), colorHeader)
+	cell(top, t.Header, true)
+	for i, row := range rows {
+		y := top + rowH*(i+1)
+		if i%2 == 1 {
+			fillRect(img, image.Rect(10, y, right, y+rowH), colorStripe)
+		}
+		fillRect(img, image.Rect(10, y, right, y+1), colorGrid)
+		cell(y, row, false)
+	}
+	bottom := top + rowH*(len(rows)+1)
+	fillRect(img, image.Rect(10, top+rowH-1, right, top+rowH), colorAxis)
+	fillRect(img, image.Rect(10, bottom, right, bottom+1), colorAxis)
+	if more > 0 {
+		drawText(img, 10, bottom+6, fmt.Sprintf("and %d more rows", more), colorMuted)
+	}
+	return encode(img)
+}
+
+func tableWidth(widths []int) int {
+	w := 0
+	for _, c := range widths {
+		w += c*charW + 2*cellPadX
+	}
+	return w
+}
@@ -2,13 +2,19 @@ package session
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
-	"os"
+	"errors"
+	"fmt"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/mosaxiv/clawlet/atrest"
+	"github.com/mosaxiv/clawlet/storage"
 )
 
 type Message struct {
@@ -16,10 +22,34 @@ type Message struct {
 	Content   string   `json:"content"`
 	Timestamp string   `json:"timestamp,omitempty"`
 	ToolsUsed []string `json:"tools_used,omitempty"`
+	// Sender is the channel sender ID of a user message, when known.
+	Sender string `json:"sender,omitempty"`
+	// MessageID is the chat app's ID of a user message, when known.
+	MessageID string `json:"message_id,omitempty"`
+	// Turn is the recorded turn that produced an assistant message, see
+	// `clawlet replay`.
+	Turn string `json:"turn,omitempty"`
+	// Trace lists the tool calls that led to an assistant message.
+	Trace []ToolTrace `json:"trace,omitempty"`
+}
+
+// ToolTrace is one tool call of a turn. Arguments and Result are shortened
+// by the agent before they are stored.
+type ToolTrace struct {
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments,omitempty"`
+	Result     string `json:"result,omitempty"`
+	Failed     bool   `json:"failed,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
 }
 
+// FormatVersion is the session file format written by Save. It is recorded in
+// the metadata line so future format changes can be migrated.
+const FormatVersion = 1
+
 type metadataLine struct {
 	Type      string         `json:"_type"`
+	Version   int            `json:"version,omitempty"`
 	CreatedAt string         `json:"created_at"`
 	UpdatedAt string         `json:"updated_at"`
 	Metadata  map[string]any `json:"metadata"`
@@ -37,13 +67,25 @@ type Session struct {
 }
 
 type Manager struct {
-	Dir   string
+	Dir   string // empty when Store is not file based
+	Store storage.Store
 	cache map[string]*Session
 	mu    sync.Mutex
 }
 
 func NewManager(dir string) *Manager {
-	return &Manager{Dir: dir, cache: map[string]*Session{}}
+	return &Manager{Dir: dir, Store: FileStore(dir), cache: map[string]*Session{}}
+}
+
+// NewManagerWithStore keeps sessions in st (namespace storage.NamespaceSessions).
+func NewManagerWithStore(st storage.Store) *Manager {
+	return &Manager{Store: st, cache: map[string]*Session{}}
+}
+
+// FileStore is the default storage for sessions: one JSONL file per session
+// in dir.
+func FileStore(dir string) storage.Store {
+	return storage.NewFiles(map[string]string{storage.NamespaceSessions: dir})
 }
 
 func (m *Manager) GetOrCreate(key string) (*Session, error) {
@@ -53,7 +95,7 @@ func (m *Manager) GetOrCreate(key string) (*Session, error) {
 		return s, nil
 	}
 	m.mu.Unlock()
-	s, err := Load(m.Dir, key)
+	s, err := LoadFrom(m.Store, key)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +109,7 @@ func (m *Manager) GetOrCreate(key string) (*Session, error) {
 }
 
 func (m *Manager) Save(s *Session) error {
-	if err := Save(m.Dir, s); err != nil {
+	if err := SaveTo(m.Store, s); err != nil {
 		return err
 	}
 	m.mu.Lock()
@@ -76,16 +118,45 @@ func (m *Manager) Save(s *Session) error {
 	return nil
 }
 
+// Invalidate drops all cached sessions so they are reloaded from disk, e.g.
+// after files were rewritten outside the manager.
+func (m *Manager) Invalidate() {
+	m.mu.Lock()
+	m.cache = map[string]*Session{}
+	m.mu.Unlock()
+}
+
+// Path returns the file a session key is stored in.
+func Path(dir, key string) string {
+	return filepath.Join(dir, FileBase(key)+".jsonl")
+}
+
+// FileBase is the file name (without extension) used for a session key.
+func FileBase(key string) string {
+	return safeFilename(strings.ReplaceAll(key, ":", "_"))
+}
+
+// StorageKey is the record key of a session in storage.NamespaceSessions.
+func StorageKey(key string) string {
+	return FileBase(key) + ".jsonl"
+}
+
 func Load(dir, key string) (*Session, error) {
-	path := filepath.Join(dir, safeFilename(strings.ReplaceAll(key, ":", "_"))+".jsonl")
-	f, err := os.Open(path)
+	return LoadFrom(FileStore(dir), key)
+}
+
+// LoadFrom reads a session from st; a missing session returns nil, nil.
+func LoadFrom(st storage.Store, key string) (*Session, error) {
+	b, err := st.Get(storage.NamespaceSessions, StorageKey(key))
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, storage.ErrNotFound) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	defer f.Close()
+	if b, err = atrest.Decode(b); err != nil {
+		return nil, fmt.Errorf("session %s: %w", key, err)
+	}
 
 	s := &Session{
 		Key:      key,
@@ -93,7 +164,8 @@ func Load(dir, key string) (*Session, error) {
 		Metadata: map[string]any{},
 	}
 
-	sc := bufio.NewScanner(f)
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
 	for sc.Scan() {
 		line := strings.TrimSpace(sc.Text())
 		if line == "" {
@@ -150,7 +222,40 @@ func (s *Session) Add(role, content string) {
 	s.AddWithTools(role, content, nil)
 }
 
+// AddFrom adds a user message attributed to a channel sender.
+func (s *Session) AddFrom(sender, content string) {
+	s.AddFromMessage(sender, "", content)
+}
+
+// AddFromMessage is AddFrom keeping the chat app's ID of the message.
+func (s *Session) AddFromMessage(sender, messageID, content string) {
+	s.add(Message{Role: "user", Content: content, Sender: strings.TrimSpace(sender), MessageID: strings.TrimSpace(messageID)})
+}
+
+// ReplaceFromMessage is AddFromMessage dropping the earlier messages with
+// the same messageID first, so e.g. only the latest results of a poll stay.
+func (s *Session) ReplaceFromMessage(sender, messageID, content string) {
+	messageID = strings.TrimSpace(messageID)
+	s.mu.Lock()
+	if messageID != "" {
+		s.Messages = slices.DeleteFunc(s.Messages, func(m Message) bool { return m.MessageID == messageID })
+	}
+	s.mu.Unlock()
+	s.AddFromMessage(sender, messageID, content)
+}
+
 func (s *Session) AddWithTools(role, content string, toolsUsed []string) {
+	s.AddReply(role, content, toolsUsed, "")
+}
+
+// AddReply adds a message together with the ID of the recorded turn that
+// produced it (empty when recording is off).
+func (s *Session) AddReply(role, content string, toolsUsed []string, turn string) {
+	s.AddReplyTrace(role, content, toolsUsed, turn, nil)
+}
+
+// AddReplyTrace is AddReply keeping the tool calls of the turn as well.
+func (s *Session) AddReplyTrace(role, content string, toolsUsed []string, turn string, trace []ToolTrace) {
 	var copied []string
 	if len(toolsUsed) > 0 {
 		copied = make([]string, 0, len(toolsUsed))
@@ -162,14 +267,14 @@ func (s *Session) AddWithTools(role, content string, toolsUsed []string) {
 			copied = append(copied, name)
 		}
 	}
+	s.add(Message{Role: role, Content: content, ToolsUsed: copied, Turn: turn, Trace: slices.Clone(trace)})
+}
+
+func (s *Session) add(m Message) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.Messages = append(s.Messages, Message{
-		Role:      role,
-		Content:   content,
-		Timestamp: time.Now().Format(time.RFC3339Nano),
-		ToolsUsed: copied,
-	})
+	m.Timestamp = time.Now().Format(time.RFC3339Nano)
+	s.Messages = append(s.Messages, m)
 	s.UpdatedAt = time.Now()
 	s.version++
 }
@@ -184,6 +289,72 @@ func (s *Session) History(max int) []Message {
 	return cloneMessages(msgs)
 }
 
+// Clear drops the messages and keeps the metadata.
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Messages = []Message{}
+	s.UpdatedAt = time.Now()
+	s.version++
+}
+
+// DropLastTurn removes the last user message and the replies after it when
+// that message has the chat app ID messageID, so the turn can be run again
+// for an edited message. It reports whether it did.
+func (s *Session) DropLastTurn(messageID string) bool {
+	messageID = strings.TrimSpace(messageID)
+	if messageID == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Role != "user" {
+			continue
+		}
+		if s.Messages[i].MessageID != messageID {
+			return false
+		}
+		s.Messages = s.Messages[:i]
+		s.UpdatedAt = time.Now()
+		s.version++
+		return true
+	}
+	return false
+}
+
+// Fork returns a new session under key holding a copy of s's messages.
+func (s *Session) Fork(key string) *Session {
+	s.mu.Lock()
+	msgs := cloneMessages(s.Messages)
+	s.mu.Unlock()
+	f := New(key)
+	f.Messages = msgs
+	return f
+}
+
+// Meta returns the metadata value stored under name.
+func (s *Session) Meta(name string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.Metadata[name]
+	return v, ok
+}
+
+// SetMeta stores v under name; a nil v removes it.
+func (s *Session) SetMeta(name string, v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v == nil {
+		delete(s.Metadata, name)
+		return
+	}
+	if s.Metadata == nil {
+		s.Metadata = map[string]any{}
+	}
+	s.Metadata[name] = v
+}
+
 func (s *Session) NeedsConsolidation(memoryWindow int) bool {
 	if memoryWindow <= 0 {
 		memoryWindow = 50
@@ -227,49 +398,47 @@ func (s *Session) ApplyConsolidation(version uint64, keep int) bool {
 }
 
 func Save(dir string, s *Session) error {
-	if err := os.MkdirAll(dir, 0o700); err != nil {
-		return err
-	}
-	path := filepath.Join(dir, safeFilename(strings.ReplaceAll(s.Key, ":", "_"))+".jsonl")
+	return SaveTo(FileStore(dir), s)
+}
 
+func SaveTo(st storage.Store, s *Session) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
-	if err != nil {
-		return err
-	}
-	bw := bufio.NewWriter(f)
-
+	var buf bytes.Buffer
 	meta := metadataLine{
 		Type:      "metadata",
+		Version:   FormatVersion,
 		CreatedAt: s.CreatedAt.Format(time.RFC3339Nano),
 		UpdatedAt: s.UpdatedAt.Format(time.RFC3339Nano),
 		Metadata:  s.Metadata,
 	}
 	if b, err := json.Marshal(meta); err == nil {
-		if _, err := bw.Write(append(b, '\n')); err != nil {
-			_ = f.Close()
-			return err
-		}
+		buf.Write(append(b, '\n'))
 	}
-
 	for _, m := range s.Messages {
 		if b, err := json.Marshal(m); err == nil {
-			if _, err := bw.Write(append(b, '\n')); err != nil {
-				_ = f.Close()
-				return err
-			}
+			buf.Write(append(b, '\n'))
 		}
 	}
-	if err := bw.Flush(); err != nil {
-		_ = f.Close()
+	// Encrypted when at-rest encryption is configured (see atrest).
+	b, err := atrest.Encode(buf.Bytes())
+	if err != nil {
 		return err
 	}
-	if err := f.Close(); err != nil {
-		return err
+	return st.Put(storage.NamespaceSessions, StorageKey(s.Key), b)
+}
+
+// StoredUpdatedAt returns the last update time recorded in the metadata line
+// of a stored (decoded) session.
+func StoredUpdatedAt(b []byte) (time.Time, bool) {
+	line, _, _ := bytes.Cut(b, []byte("\n"))
+	var ml metadataLine
+	if err := json.Unmarshal(line, &ml); err != nil || ml.Type != "metadata" {
+		return time.Time{}, false
 	}
-	return nil
+	t, err := time.Parse(time.RFC3339Nano, ml.UpdatedAt)
+	return t, err == nil
 }
 
 func cloneMessages(in []Message) []Message {
@@ -279,10 +448,14 @@ func cloneMessages(in []Message) []Message {
 			Role:      m.Role,
 			Content:   m.Content,
 			Timestamp: m.Timestamp,
+			Sender:    m.Sender,
+			MessageID: m.MessageID,
+			Turn:      m.Turn,
 		}
 		if len(m.ToolsUsed) > 0 {
 			msg.ToolsUsed = append([]string{}, m.ToolsUsed...)
 		}
+		msg.Trace = slices.Clone(m.Trace)
 		out = append(out, msg)
 	}
 	return out
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/mosaxiv/clawlet/atrest"
 )
 
 func TestSaveLoad_RewriteSnapshot(t *testing.T) {
@@ -90,3 +92,110 @@ func TestSave_AfterConsolidationPersistsTrimmedMessages(t *testing.T) {
 		t.Fatalf("messages=%d want=%d", got, keep)
 	}
 }
+
+func TestAddFrom_PersistsSender(t *testing.T) {
+	dir := t.TempDir()
+	s := New("telegram:-100")
+	s.AddFrom("111|alice", "hi")
+	s.Add("assistant", "hello")
+	if err := Save(dir, s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(dir, s.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Messages[0].Sender != "111|alice" || got.Messages[1].Sender != "" {
+		t.Fatalf("messages=%+v", got.Messages)
+	}
+	if h := got.History(0); h[0].Sender != "111|alice" {
+		t.Fatalf("history lost sender: %+v", h)
+	}
+}
+
+func TestSaveLoad_Encrypted(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyPath, []byte(strings.Repeat("ab", 32)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := atrest.NewKeyFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	atrest.SetDefault(c)
+	t.Cleanup(func() { atrest.SetDefault(nil) })
+
+	s := New("telegram:1")
+	s.Add("user", "top secret")
+	if err := Save(dir, s); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(Path(dir, s.Key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "top secret") {
+		t.Fatal("session stored in plaintext")
+	}
+	got, err := Load(dir, s.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Messages) != 1 || got.Messages[0].Content != "top secret" {
+		t.Fatalf("messages=%+v", got.Messages)
+	}
+}
+
+func TestFork_CopiesMessagesOnly(t *testing.T) {
+	s := New("telegram:1")
+	s.AddFrom("42", "hello")
+	s.AddWithTools("assistant", "hi", []string{"exec"})
+	s.SetMeta("fork_active", "telegram:1#fork-x")
+
+	f := s.Fork("telegram:1#fork-y")
+	f.Add("user", "what if")
+	if f.Key != "telegram:1#fork-y" || len(f.Messages) != 3 || f.Messages[0].Sender != "42" {
+		t.Fatalf("fork=%+v", f)
+	}
+	if len(s.Messages) != 2 {
+		t.Fatalf("fork changed the original: %d messages", len(s.Messages))
+	}
+	if _, ok := f.Meta("fork_active"); ok {
+		t.Fatal("metadata should not be copied")
+	}
+	s.SetMeta("fork_active", nil)
+	if _, ok := s.Meta("fork_active"); ok {
+		t.Fatal("nil should remove metadata")
+	}
+}
+
+func TestDropLastTurn(t *testing.T) {
+	s := New("telegram:1")
+	s.AddFromMessage("u", "10", "first")
+	s.Add("assistant", "one")
+	s.AddFromMessage("u", "11", "secnod")
+	s.Add("assistant", "two")
+
+	if s.DropLastTurn("10") || s.DropLastTurn("") {
+		t.Fatal("only the last user message may be dropped")
+	}
+	if !s.DropLastTurn("11") {
+		t.Fatal("expected the last turn to be dropped")
+	}
+	if h := s.History(0); len(h) != 2 || h[0].MessageID != "10" || h[1].Content != "one" {
+		t.Fatalf("history=%+v", h)
+	}
+}
+
+func TestReplaceFromMessage(t *testing.T) {
+	s := New("telegram:1")
+	s.AddFromMessage("", "poll:7", "[Poll: A 1]")
+	s.Add("assistant", "noted")
+	s.ReplaceFromMessage("", "poll:7", "[Poll: A 2]")
+	s.ReplaceFromMessage("", "poll:8", "[Other poll]")
+	h := s.History(0)
+	if len(h) != 3 || h[0].Content != "noted" || h[1].Content != "[Poll: A 2]" || h[2].MessageID != "poll:8" {
+		t.Fatalf("history=%+v", h)
+	}
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Files stores each record as <dir>/<key>, with one directory per namespace.
+type Files struct {
+	Dirs map[string]string
+}
+
+func NewFiles(dirs map[string]string) *Files {
+	return &Files{Dirs: dirs}
+}
+
+func (f *Files) path(ns, key string) (string, error) {
+	dir, ok := f.Dirs[ns]
+	if !ok || dir == "" {
+		return "", fmt.Errorf("storage: unknown namespace %q", ns)
+	}
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, key), nil
+}
+
+func (f *Files) Get(ns, key string) ([]byte, error) {
+	p, err := f.path(ns, key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+func (f *Files) Put(ns, key string, data []byte) error {
+	p, err := f.path(ns, key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (f *Files) Delete(ns, key string) error {
+	p, err := f.path(ns, key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (f *Files) List(ns string) ([]string, error) {
+	dir, ok := f.Dirs[ns]
+	if !ok || dir == "" {
+		return nil, fmt.Errorf("storage: unknown namespace %q", ns)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var keys []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasSuffix(e.Name(), ".tmp") || strings.HasSuffix(e.Name(), ".lock") {
+			continue
+		}
+		keys = append(keys, e.Name())
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (f *Files) Close() error { return nil }
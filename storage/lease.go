@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NamespaceLeases holds leader-election leases.
+const NamespaceLeases = "leases"
+
+// Leaser is implemented by stores that can arbitrate a named lease between
+// processes sharing the store. Expiry uses the local clock, so instances
+// should keep their clocks in sync.
+type Leaser interface {
+	// AcquireLease grants or renews name for owner until ttl from now and
+	// reports whether owner holds it. A lease held by another owner is only
+	// taken over once it has expired.
+	AcquireLease(name, owner string, ttl time.Duration) (bool, error)
+	// ReleaseLease drops name if owner holds it.
+	ReleaseLease(name, owner string) error
+}
+
+func (s *SQLite) AcquireLease(name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now().UnixMilli()
+	res, err := s.db.Exec(`INSERT INTO leases (name, owner, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE leases.owner = excluded.owner OR leases.expires_at <= ?`,
+		name, owner, now+ttl.Milliseconds(), now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *SQLite) ReleaseLease(name, owner string) error {
+	_, err := s.db.Exec(`DELETE FROM leases WHERE name = ? AND owner = ?`, name, owner)
+	return err
+}
+
+type fileLease struct {
+	Owner     string `json:"owner"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// fileLockStale is how old a lock file must be before it is treated as left
+// behind by a crashed process.
+const fileLockStale = 10 * time.Second
+
+// AcquireLease keeps leases as JSON records in NamespaceLeases. Updates are
+// serialised with an exclusive lock file, which works on local disks and on
+// shared volumes that honour O_EXCL.
+func (f *Files) AcquireLease(name, owner string, ttl time.Duration) (bool, error) {
+	held := false
+	err := f.withLeaseLock(name, func(p string) error {
+		now := time.Now().UnixMilli()
+		var cur fileLease
+		if b, err := os.ReadFile(p); err == nil {
+			_ = json.Unmarshal(b, &cur)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		if cur.Owner != "" && cur.Owner != owner && cur.ExpiresAt > now {
+			return nil
+		}
+		b, err := json.Marshal(fileLease{Owner: owner, ExpiresAt: now + ttl.Milliseconds()})
+		if err != nil {
+			return err
+		}
+		if err := f.Put(NamespaceLeases, name, b); err != nil {
+			return err
+		}
+		held = true
+		return nil
+	})
+	return held, err
+}
+
+func (f *Files) ReleaseLease(name, owner string) error {
+	return f.withLeaseLock(name, func(p string) error {
+		b, err := os.ReadFile(p)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var cur fileLease
+		if json.Unmarshal(b, &cur) == nil && cur.Owner != owner {
+			return nil
+		}
+		return f.Delete(NamespaceLeases, name)
+	})
+}
+
+func (f *Files) withLeaseLock(name string, fn func(path string) error) error {
+	p, err := f.path(NamespaceLeases, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	lock := p + ".lock"
+	for attempt := 0; ; attempt++ {
+		lf, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = lf.Close()
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		if fi, err := os.Stat(lock); err == nil && time.Since(fi.ModTime()) > fileLockStale {
+			_ = os.Remove(lock)
+			continue
+		}
+		if attempt >= 50 {
+			return errors.New("storage: lease lock busy")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	defer os.Remove(lock)
+	return fn(p)
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mosaxiv/clawlet/internal/sqlite3"
+)
+
+// SQLite stores all namespaces in one table of a SQLite database.
+type SQLite struct {
+	db *sql.DB
+}
+
+func OpenSQLite(path string) (*SQLite, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	for _, stmt := range []string{
+		`PRAGMA busy_timeout = 5000`,
+		`PRAGMA journal_mode = WAL`,
+		`CREATE TABLE IF NOT EXISTS records (
+			ns TEXT NOT NULL,
+			key TEXT NOT NULL,
+			data BLOB NOT NULL,
+			updated_at INTEGER NOT NULL,
+			PRIMARY KEY (ns, key)
+		)`,
+		`CREATE TABLE IF NOT EXISTS leases (
+			name TEXT PRIMARY KEY,
+			owner TEXT NOT NULL,
+			expires_at INTEGER NOT NULL
+		)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return &SQLite{db: db}, nil
+}
+
+func (s *SQLite) Get(ns, key string) ([]byte, error) {
+	var b []byte
+	err := s.db.QueryRow(`SELECT data FROM records WHERE ns = ? AND key = ?`, ns, key).Scan(&b)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+func (s *SQLite) Put(ns, key string, data []byte) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	_, err := s.db.Exec(`INSERT INTO records (ns, key, data, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (ns, key) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		ns, key, data, time.Now().UnixMilli())
+	return err
+}
+
+func (s *SQLite) Delete(ns, key string) error {
+	_, err := s.db.Exec(`DELETE FROM records WHERE ns = ? AND key = ?`, ns, key)
+	return err
+}
+
+func (s *SQLite) List(ns string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT key FROM records WHERE ns = ? ORDER BY key`, ns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLite) Close() error { return s.db.Close() }
+
+// Vacuum rebuilds the database file to give back the space of deleted
+// records.
+func (s *SQLite) Vacuum() error {
+	_, err := s.db.Exec(`VACUUM`)
+	return err
+}
@@ -0,0 +1,65 @@
+// Package storage abstracts where clawlet persists state. Records are opaque
+// blobs addressed by namespace and key. The default backend keeps one file
+// per record (the historical ~/.clawlet layout); the SQLite backend keeps
+// everything in a single database so server deployments can centralise state.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Namespaces used by clawlet.
+const (
+	NamespaceSessions = "sessions"
+	NamespaceCron     = "cron"
+)
+
+var ErrNotFound = errors.New("storage: not found")
+
+// Store persists blobs. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns ErrNotFound when the record does not exist.
+	Get(ns, key string) ([]byte, error)
+	// Put atomically replaces the record.
+	Put(ns, key string, data []byte) error
+	// Delete removes the record; deleting a missing record is not an error.
+	Delete(ns, key string) error
+	// List returns the keys in ns, sorted.
+	List(ns string) ([]string, error)
+	Close() error
+}
+
+// Copy copies the given keys (all keys of ns when keys is empty) from src to
+// dst and returns how many records were copied. Missing keys are skipped.
+func Copy(dst, src Store, ns string, keys ...string) (int, error) {
+	if len(keys) == 0 {
+		var err error
+		if keys, err = src.List(ns); err != nil {
+			return 0, err
+		}
+	}
+	n := 0
+	for _, k := range keys {
+		b, err := src.Get(ns, k)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := dst.Put(ns, k, b); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func validKey(key string) error {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) || strings.ContainsRune(key, 0) {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	return nil
+}
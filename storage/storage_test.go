@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T, st Store) {
+	t.Helper()
+	if _, err := st.Get(NamespaceSessions, "a.jsonl"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get missing: %v", err)
+	}
+	if err := st.Put(NamespaceSessions, "b.jsonl", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put(NamespaceSessions, "a.jsonl", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put(NamespaceSessions, "a.jsonl", []byte("1b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put(NamespaceCron, "cron.json", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := st.Get(NamespaceSessions, "a.jsonl"); err != nil || string(b) != "1b" {
+		t.Fatalf("get: %q %v", b, err)
+	}
+	keys, err := st.List(NamespaceSessions)
+	if err != nil || !slices.Equal(keys, []string{"a.jsonl", "b.jsonl"}) {
+		t.Fatalf("list: %v %v", keys, err)
+	}
+	if err := st.Delete(NamespaceSessions, "a.jsonl"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Delete(NamespaceSessions, "a.jsonl"); err != nil {
+		t.Fatalf("delete missing: %v", err)
+	}
+	if _, err := st.Get(NamespaceSessions, "a.jsonl"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get deleted: %v", err)
+	}
+	for _, bad := range []string{"", "..", "../x", "a/b"} {
+		if err := st.Put(NamespaceSessions, bad, []byte("x")); err == nil {
+			t.Fatalf("key %q accepted", bad)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	root := t.TempDir()
+	st := NewFiles(map[string]string{
+		NamespaceSessions: filepath.Join(root, "sessions"),
+		NamespaceCron:     root,
+	})
+	testStore(t, st)
+	if _, err := os.Stat(filepath.Join(root, "sessions", "b.jsonl")); err != nil {
+		t.Fatalf("file layout: %v", err)
+	}
+	if _, err := st.List("unknown"); err == nil {
+		t.Fatal("unknown namespace accepted")
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	st, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, st)
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Data survives reopening.
+	st, err = OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	if b, err := st.Get(NamespaceSessions, "b.jsonl"); err != nil || string(b) != "2" {
+		t.Fatalf("reopen: %q %v", b, err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	src := NewFiles(map[string]string{NamespaceSessions: t.TempDir()})
+	_ = src.Put(NamespaceSessions, "a.jsonl", []byte("a"))
+	_ = src.Put(NamespaceSessions, "b.jsonl", []byte("b"))
+	dst, err := OpenSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	n, err := Copy(dst, src, NamespaceSessions)
+	if err != nil || n != 2 {
+		t.Fatalf("copy: %d %v", n, err)
+	}
+	if n, err := Copy(dst, src, NamespaceSessions, "missing.jsonl"); err != nil || n != 0 {
+		t.Fatalf("copy missing: %d %v", n, err)
+	}
+	if b, _ := dst.Get(NamespaceSessions, "b.jsonl"); string(b) != "b" {
+		t.Fatalf("dst b=%q", b)
+	}
+}
+
+func testLeaser(t *testing.T, l Leaser) {
+	t.Helper()
+	if ok, err := l.AcquireLease("cron", "a", time.Minute); err != nil || !ok {
+		t.Fatalf("a acquire: %v %v", ok, err)
+	}
+	if ok, err := l.AcquireLease("cron", "b", time.Minute); err != nil || ok {
+		t.Fatalf("b took a live lease: %v %v", ok, err)
+	}
+	if ok, err := l.AcquireLease("cron", "a", time.Millisecond); err != nil || !ok {
+		t.Fatalf("a renew: %v %v", ok, err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if ok, err := l.AcquireLease("cron", "b", time.Minute); err != nil || !ok {
+		t.Fatalf("b after expiry: %v %v", ok, err)
+	}
+	if err := l.ReleaseLease("cron", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := l.AcquireLease("cron", "a", time.Minute); ok {
+		t.Fatal("release by a non-owner dropped the lease")
+	}
+	if err := l.ReleaseLease("cron", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := l.AcquireLease("cron", "a", time.Minute); err != nil || !ok {
+		t.Fatalf("a after release: %v %v", ok, err)
+	}
+}
+
+func TestFiles_Lease(t *testing.T) {
+	testLeaser(t, NewFiles(map[string]string{NamespaceLeases: t.TempDir()}))
+}
+
+func TestSQLite_Lease(t *testing.T) {
+	st, err := OpenSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	testLeaser(t, st)
+}
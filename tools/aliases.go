@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+// ToolNames lists every built-in tool name accepted by Execute.
+var ToolNames = []string{
+	"read_file", "write_file", "edit_file", "get_value", "set_value", "list_dir",
+	"exec", "eval", "set_buffer", "get_buffer",
+	"read_skill", "find_skills", "install_skill",
+	"web_fetch", "web_search", "message", "react", "create_poll", "render", "send_voice", "spawn", "cron", "remind",
+	"memory_search", "memory_get", "contacts_add", "contacts_search",
+	"remember", "journal",
+}
+
+// toolShim maps a deprecated or foreign tool name onto a current tool,
+// renaming argument keys where they differ.
+type toolShim struct {
+	Target string
+	Args   map[string]string // old key -> new key
+}
+
+// deprecatedTools are accepted by Execute but never advertised, so prompts
+// and skills written for other agents keep working.
+var deprecatedTools = map[string]toolShim{
+	"read":  {Target: "read_file", Args: map[string]string{"file_path": "path"}},
+	"write": {Target: "write_file", Args: map[string]string{"file_path": "path"}},
+	"edit": {Target: "edit_file", Args: map[string]string{
+		"file_path":  "path",
+		"oldText":    "old_text",
+		"newText":    "new_text",
+		"old_string": "old_text",
+		"new_string": "new_text",
+	}},
+	"bash": {Target: "exec"},
+}
+
+var (
+	toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	warnedShims     sync.Map
+)
+
+// ValidateToolNames checks configured aliases (alias -> tool) and renames
+// (tool -> new name): targets must be built-in tools and new names must be
+// valid, unique and not shadow a built-in tool.
+func ValidateToolNames(aliases, renames map[string]string) error {
+	seen := map[string]string{}
+	check := func(kind, name, target string) error {
+		if !toolNamePattern.MatchString(name) {
+			return fmt.Errorf("tools.%s: invalid tool name %q", kind, name)
+		}
+		if !slices.Contains(ToolNames, target) {
+			return fmt.Errorf("tools.%s: %q refers to unknown tool %q", kind, name, target)
+		}
+		if slices.Contains(ToolNames, name) {
+			return fmt.Errorf("tools.%s: %q shadows a built-in tool", kind, name)
+		}
+		if prev, ok := seen[name]; ok {
+			return fmt.Errorf("tools.%s: %q is already used for %q", kind, name, prev)
+		}
+		seen[name] = target
+		return nil
+	}
+	for _, tool := range sortedKeys(renames) {
+		if err := check("rename", strings.TrimSpace(renames[tool]), strings.TrimSpace(tool)); err != nil {
+			return err
+		}
+	}
+	for _, alias := range sortedKeys(aliases) {
+		if err := check("aliases", strings.TrimSpace(alias), strings.TrimSpace(aliases[alias])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exposeNames applies renames and aliases to the advertised definitions.
+func (r *Registry) exposeNames(defs []llm.ToolDefinition) []llm.ToolDefinition {
+	if len(r.Aliases) == 0 && len(r.Renames) == 0 {
+		return defs
+	}
+	out := make([]llm.ToolDefinition, 0, len(defs)+len(r.Aliases))
+	byName := map[string]llm.ToolDefinition{}
+	for _, d := range defs {
+		byName[d.Function.Name] = d
+		if newName := strings.TrimSpace(r.Renames[d.Function.Name]); newName != "" {
+			d.Function.Name = newName
+		}
+		out = append(out, d)
+	}
+	for _, alias := range sortedKeys(r.Aliases) {
+		d, ok := byName[strings.TrimSpace(r.Aliases[alias])]
+		if !ok {
+			continue
+		}
+		d.Function.Name = strings.TrimSpace(alias)
+		out = append(out, d)
+	}
+	return out
+}
+
+// ExposedName is the name the model knows a built-in tool by.
+func (r *Registry) ExposedName(tool string) string {
+	if newName := strings.TrimSpace(r.Renames[tool]); newName != "" {
+		return newName
+	}
+	return tool
+}
+
+// resolveName maps an exposed name back to the built-in tool, rewriting
+// arguments for deprecated shims.
+func (r *Registry) resolveName(name string, args json.RawMessage) (string, json.RawMessage, error) {
+	for tool, newName := range r.Renames {
+		if strings.TrimSpace(newName) == name {
+			return strings.TrimSpace(tool), args, nil
+		}
+	}
+	if target := strings.TrimSpace(r.Aliases[name]); target != "" {
+		return target, args, nil
+	}
+	if slices.Contains(ToolNames, name) {
+		return name, args, nil
+	}
+	shim, ok := deprecatedTools[name]
+	if !ok {
+		return name, args, nil
+	}
+	if _, warned := warnedShims.LoadOrStore(name, true); !warned {
+		log.Printf("tools: %q is deprecated, use %q", name, shim.Target)
+	}
+	if len(shim.Args) == 0 {
+		return shim.Target, args, nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(args, &m); err != nil {
+		return "", nil, err
+	}
+	for from, to := range shim.Args {
+		v, ok := m[from]
+		if !ok {
+			continue
+		}
+		delete(m, from)
+		if _, exists := m[to]; !exists {
+			m[to] = v
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", nil, err
+	}
+	return shim.Target, b, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -6,30 +6,39 @@ import (
 	"strings"
 )
 
-func formatBraveSearchResults(query string, count int, body []byte) string {
-	type item struct {
-		Title       string `json:"title"`
-		URL         string `json:"url"`
-		Description string `json:"description"`
-	}
+type braveResult struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+func parseBraveResults(count int, body []byte) ([]braveResult, error) {
 	var parsed struct {
 		Web struct {
-			Results []item `json:"results"`
+			Results []braveResult `json:"results"`
 		} `json:"web"`
 	}
 	if err := json.Unmarshal(body, &parsed); err != nil {
-		return "Error: failed to parse search results"
+		return nil, err
 	}
 	results := parsed.Web.Results
-	if len(results) == 0 {
-		return fmt.Sprintf("No results for: %s", query)
-	}
 	if count <= 0 || count > 10 {
 		count = 5
 	}
 	if len(results) > count {
 		results = results[:count]
 	}
+	return results, nil
+}
+
+func formatBraveSearchResults(query string, count int, body []byte) string {
+	results, err := parseBraveResults(count, body)
+	if err != nil {
+		return "Error: failed to parse search results"
+	}
+	if len(results) == 0 {
+		return fmt.Sprintf("No results for: %s", query)
+	}
 	lines := []string{fmt.Sprintf("Results for: %s\n", query)}
 	for i, it := range results {
 		title := strings.TrimSpace(it.Title)
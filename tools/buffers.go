@@ -0,0 +1,214 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+const (
+	maxBuffers     = 16
+	maxBufferBytes = 1 << 20
+	// bufferChunk is how much of a buffer get_buffer returns at once.
+	bufferChunk = 16 << 10
+	// bufferPreview is how much of a saved output the model still sees.
+	bufferPreview = 200
+)
+
+// bufferSources are the tools whose output can go to a buffer with
+// save_to_buffer instead of into the model's context.
+var bufferSources = []string{"read_file", "get_value", "exec", "eval", "web_fetch", "web_search", "memory_get"}
+
+var (
+	bufferNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+	bufferRef         = regexp.MustCompile(`\{\{buffer:([^{}]*)\}\}`)
+)
+
+// Buffers are named scratch texts that live for one turn. They carry large
+// intermediate data from one tool to the next (fetch, transform, write)
+// without passing it through the model: a tool's output is saved with
+// save_to_buffer, and {{buffer:name}} in a later tool's arguments is
+// replaced with the text. A nil *Buffers has none.
+type Buffers struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+// Set stores content under name, replacing what was there.
+func (b *Buffers) Set(name, content string) error {
+	if b == nil {
+		return errors.New("scratch buffers are not available here")
+	}
+	if !bufferNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid buffer name %q (use letters, digits, _, - and ., at most 64)", name)
+	}
+	if len(content) > maxBufferBytes {
+		return fmt.Errorf("buffer %s would hold %d bytes (max %d)", name, len(content), maxBufferBytes)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.m[name]; !ok && len(b.m) >= maxBuffers {
+		return fmt.Errorf("too many buffers (max %d); reuse one", maxBuffers)
+	}
+	if b.m == nil {
+		b.m = map[string]string{}
+	}
+	b.m[name] = content
+	return nil
+}
+
+// Get returns the content of a buffer.
+func (b *Buffers) Get(name string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.m[name]
+	return s, ok
+}
+
+// Names lists the buffers, sorted.
+func (b *Buffers) Names() []string {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names := make([]string, 0, len(b.m))
+	for name := range b.m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// prepare takes save_to_buffer out of args and replaces {{buffer:name}}
+// references in its string values.
+func (b *Buffers) prepare(args json.RawMessage) (json.RawMessage, string, error) {
+	if b == nil || (!bytes.Contains(args, []byte(`"save_to_buffer"`)) && !bytes.Contains(args, []byte("{{buffer:"))) {
+		return args, "", nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return args, "", nil
+	}
+	saveTo, _ := m["save_to_buffer"].(string)
+	delete(m, "save_to_buffer")
+	var missing string
+	var expand func(any) any
+	expand = func(v any) any {
+		switch v := v.(type) {
+		case string:
+			return bufferRef.ReplaceAllStringFunc(v, func(ref string) string {
+				name := bufferRef.FindStringSubmatch(ref)[1]
+				s, ok := b.Get(name)
+				if !ok && missing == "" {
+					missing = name
+				}
+				return s
+			})
+		case []any:
+			for i := range v {
+				v[i] = expand(v[i])
+			}
+		case map[string]any:
+			for k := range v {
+				v[k] = expand(v[k])
+			}
+		}
+		return v
+	}
+	expand(m)
+	if missing != "" {
+		return nil, "", fmt.Errorf("buffer %q is not set (set: %s)", missing, b.list())
+	}
+	out, err := json.Marshal(m)
+	if err != nil {
+		return nil, "", err
+	}
+	return out, strings.TrimSpace(saveTo), nil
+}
+
+// save stores a tool's output and returns what the model sees instead.
+func (b *Buffers) save(name, out string) (string, error) {
+	if err := b.Set(name, out); err != nil {
+		return "", err
+	}
+	preview := out
+	if len(preview) > bufferPreview {
+		cut := bufferPreview
+		for cut > 0 && !utf8.RuneStart(preview[cut]) {
+			cut--
+		}
+		preview = preview[:cut] + "..."
+	}
+	return fmt.Sprintf("saved %d bytes to buffer %s; use {{buffer:%s}} in a tool's arguments, or get_buffer to read it\n%s", len(out), name, name, preview), nil
+}
+
+func (b *Buffers) list() string {
+	names := b.Names()
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// getBuffer reads a buffer in chunks, or lists the buffers.
+func getBuffer(b *Buffers, name string, offset int) (string, error) {
+	if b == nil {
+		return "", errors.New("scratch buffers are not available here")
+	}
+	if name == "" {
+		var lines []string
+		for _, n := range b.Names() {
+			s, _ := b.Get(n)
+			lines = append(lines, fmt.Sprintf("%s: %d bytes", n, len(s)))
+		}
+		if len(lines) == 0 {
+			return "(no buffers)", nil
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+	s, ok := b.Get(name)
+	if !ok {
+		return "", fmt.Errorf("buffer %q is not set (set: %s)", name, b.list())
+	}
+	if offset < 0 || offset > len(s) {
+		return "", fmt.Errorf("offset %d is out of range (buffer %s has %d bytes)", offset, name, len(s))
+	}
+	end := min(offset+bufferChunk, len(s))
+	for end < len(s) && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	if end == len(s) {
+		return s[offset:], nil
+	}
+	return fmt.Sprintf("%s\n(%d more bytes; continue with offset=%d)", s[offset:end], len(s)-end, end), nil
+}
+
+// withSaveToBuffer adds the save_to_buffer parameter to the tools in
+// bufferSources.
+func withSaveToBuffer(defs []llm.ToolDefinition) {
+	for i, d := range defs {
+		if !slices.Contains(bufferSources, d.Function.Name) {
+			continue
+		}
+		props := make(map[string]llm.JSONSchema, len(d.Function.Parameters.Properties)+1)
+		for k, v := range d.Function.Parameters.Properties {
+			props[k] = v
+		}
+		props["save_to_buffer"] = llm.JSONSchema{Type: "string", Description: "Save the output to this scratch buffer instead of returning it."}
+		defs[i].Function.Parameters.Properties = props
+	}
+}
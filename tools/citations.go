@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mosaxiv/clawlet/memory"
+)
+
+// Source is a web page or memory file that a tool returned during a turn.
+type Source struct {
+	Title string
+	// Ref is the URL, or the workspace-relative path for memory files.
+	Ref string
+}
+
+// Sources collects the sources seen during one turn, in order and without
+// duplicates. A nil *Sources ignores everything.
+type Sources struct {
+	mu   sync.Mutex
+	list []Source
+}
+
+// Add records a source; refs already seen are ignored.
+func (s *Sources) Add(title, ref string) {
+	ref = strings.TrimSpace(ref)
+	if s == nil || ref == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, x := range s.list {
+		if x.Ref == ref {
+			return
+		}
+	}
+	s.list = append(s.list, Source{Title: strings.TrimSpace(title), Ref: ref})
+}
+
+func (s *Sources) List() []Source {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Source(nil), s.list...)
+}
+
+// record picks sources out of a tool's JSON output. web_search results are
+// recorded by webSearch, which has the structured response.
+func (s *Sources) record(name, out string) {
+	if s == nil {
+		return
+	}
+	switch name {
+	case "web_fetch":
+		var r struct {
+			URL      string `json:"url"`
+			FinalURL string `json:"finalUrl"`
+			Status   int    `json:"status"`
+			Error    string `json:"error"`
+		}
+		if json.Unmarshal([]byte(out), &r) != nil || r.Error != "" || r.Status < 200 || r.Status >= 300 {
+			return
+		}
+		if r.FinalURL != "" {
+			s.Add("", r.FinalURL)
+		} else {
+			s.Add("", r.URL)
+		}
+	case "memory_search":
+		var r struct {
+			Results []memory.SearchResult `json:"results"`
+		}
+		if json.Unmarshal([]byte(out), &r) != nil {
+			return
+		}
+		for _, it := range r.Results {
+			s.Add("", it.Path)
+		}
+	case "memory_get":
+		var r struct {
+			Path  string `json:"path"`
+			Error string `json:"error"`
+		}
+		if json.Unmarshal([]byte(out), &r) == nil && r.Error == "" {
+			s.Add("", r.Path)
+		}
+	}
+}
+
+// FormatSources returns a "Sources:" footer listing up to max sources that
+// reply does not already mention, or "" when there is nothing to add.
+func FormatSources(reply string, srcs []Source, max int) string {
+	var lines []string
+	for _, s := range srcs {
+		if len(lines) >= max {
+			break
+		}
+		if strings.Contains(reply, s.Ref) {
+			continue
+		}
+		line := s.Ref
+		if s.Title != "" {
+			line = s.Title + " — " + s.Ref
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s", len(lines)+1, line))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Sources:\n" + strings.Join(lines, "\n")
+}
@@ -60,6 +60,44 @@ func defEditFile() llm.ToolDefinition {
 	}
 }
 
+func defGetValue() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "get_value",
+			Description: "Read a value from a JSON, YAML or TOML file by key, e.g. server.ports[0]. Returns JSON (TOML values as written).",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"path": {Type: "string"},
+					"key":  {Type: "string", Description: `Dotted key with [N] list indexes and ["quoted.names"]; "." for the whole file.`},
+				},
+				Required: []string{"path", "key"},
+			},
+		},
+	}
+}
+
+func defSetValue() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "set_value",
+			Description: "Set or delete a value in a JSON, YAML or TOML file by key, keeping the rest of the file and its comments. Prefer it to edit_file for these files. Missing maps are created; index N = list length appends.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"path":   {Type: "string"},
+					"key":    {Type: "string", Description: "Dotted key, e.g. server.ports[0]."},
+					"value":  {Type: "string", Description: `New value as JSON, e.g. 8080, true, "text", [1, 2] or {"a": 1}. Text that isn't JSON is set as a string.`},
+					"delete": {Type: "boolean", Description: "Delete the key instead."},
+				},
+				Required: []string{"path", "key"},
+			},
+		},
+	}
+}
+
 func defListDir() llm.ToolDefinition {
 	return llm.ToolDefinition{
 		Type: "function",
@@ -96,6 +134,59 @@ func defExec() llm.ToolDefinition {
 	}
 }
 
+func defEval() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "eval",
+			Description: "Run a short Starlark (Python-like) snippet for calculations, JSON transforms or formatting data, without a shell. Returns what it prints and the value of its last expression. The json (encode, decode, indent) and math modules are available, and scratch buffers are in the buffers dict; there is no file, network or clock access and no imports. Unlike Python there is no sum() and no %.2f: round with math.round(x * 100) / 100.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"code": {Type: "string", Description: "e.g. '[math.round(p * 108) / 100 for p in [12.5, 40, 7.25]]'"},
+				},
+				Required: []string{"code"},
+			},
+		},
+	}
+}
+
+func defSetBuffer() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "set_buffer",
+			Description: "Store text in a named scratch buffer for this turn. {{buffer:name}} in any tool's arguments is replaced with the buffer's text, and eval sees buffers as the buffers dict, so large data can go from tool to tool without being repeated. Tools with save_to_buffer store their output directly.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"name":    {Type: "string", Description: "Letters, digits, _, - and ."},
+					"content": {Type: "string"},
+					"append":  {Type: "boolean", Description: "Add to the end instead of replacing."},
+				},
+				Required: []string{"name", "content"},
+			},
+		},
+	}
+}
+
+func defGetBuffer() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "get_buffer",
+			Description: "Read a scratch buffer in 16 KB chunks; without a name, list the buffers and their sizes.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"name":   {Type: "string"},
+					"offset": {Type: "integer", Description: "Byte offset to continue from."},
+				},
+			},
+		},
+	}
+}
+
 func defReadSkill() llm.ToolDefinition {
 	return llm.ToolDefinition{
 		Type: "function",
@@ -141,7 +232,7 @@ func defInstallSkill() llm.ToolDefinition {
 				Type: "object",
 				Properties: map[string]llm.JSONSchema{
 					"slug":     {Type: "string", Description: "Skill slug to install."},
-					"registry": {Type: "string", Description: "Registry name (currently: clawhub)."},
+					"registry": {Type: "string", Description: "Registry name from find_skills results (clawhub or a configured index)."},
 					"version":  {Type: "string", Description: "Optional version. If omitted, latest is used."},
 					"force":    {Type: "boolean", Description: "Reinstall even when target already exists."},
 				},
@@ -199,15 +290,119 @@ func defMessage() llm.ToolDefinition {
 		Type: "function",
 		Function: llm.FunctionDefinition{
 			Name:        "message",
-			Description: "Send a message to a specific channel/chat_id. Do not use for replying to the current conversation.",
+			Description: "Send a message to a specific channel/chat_id or a saved contact. Do not use for replying to the current conversation, except to send files, options or cards there.",
 			Parameters: llm.JSONSchema{
 				Type: "object",
 				Properties: map[string]llm.JSONSchema{
 					"content": {Type: "string"},
-					"channel": {Type: "string"},
+					"channel": {Type: "string", Description: "Target channel. Optional with contact when the contact has a single channel."},
 					"chat_id": {Type: "string"},
+					"contact": {Type: "string", Description: "Exact contact name or alias from contacts_search (used when chat_id is omitted)."},
+					"group":   {Type: "string", Description: "Recipient group from the config to send to instead of one chat (used when chat_id and contact are omitted). Each member gets a copy; {{name}} and {{first_name}} in content become the member's name, {{name|fallback}} uses fallback when it is unknown."},
+					"files":   {Type: "array", Items: &llm.JSONSchema{Type: "string"}, Description: "Workspace files to attach (images are sent as photos where supported). content becomes the caption."},
+					"options": {Type: "array", Items: &llm.JSONSchema{Type: "string"}, Description: "Up to 5 quick replies shown with the message (buttons, or a numbered list on WhatsApp). Choosing one sends its text as the user's reply."},
+					"cards": {
+						Type:        "array",
+						Description: "Up to 10 structured results, e.g. search hits, shown as embeds on Discord and as text elsewhere.",
+						Items: &llm.JSONSchema{
+							Type: "object",
+							Properties: map[string]llm.JSONSchema{
+								"title":       {Type: "string"},
+								"url":         {Type: "string", Description: "The title links here."},
+								"description": {Type: "string"},
+								"fields": {Type: "array", Items: &llm.JSONSchema{
+									Type: "object",
+									Properties: map[string]llm.JSONSchema{
+										"name":   {Type: "string"},
+										"value":  {Type: "string"},
+										"inline": {Type: "boolean"},
+									},
+									Required: []string{"name", "value"},
+								}},
+								"footer": {Type: "string"},
+								"color":  {Type: "string", Description: "Accent color as #RRGGBB."},
+							},
+						},
+					},
+				},
+				Required: []string{"content"},
+			},
+		},
+	}
+}
+
+func defReact() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "react",
+			Description: "React with an emoji to a message in the current conversation (Telegram, Discord, Slack), e.g. to acknowledge it without a reply. Other channels ignore reactions.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"emoji":      {Type: "string", Description: "A single emoji, e.g. 👍. Telegram accepts only its standard reaction emoji."},
+					"message_id": {Type: "string", Description: "Message to react to. Defaults to the message being answered."},
+				},
+				Required: []string{"emoji"},
+			},
+		},
+	}
+}
+
+func defCreatePoll() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "create_poll",
+			Description: "Post a poll in the current conversation, e.g. to let a group vote. Telegram and Discord show a native poll and report the votes back as notes in the conversation; other channels get a numbered list.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"question":       {Type: "string"},
+					"options":        {Type: "array", Items: &llm.JSONSchema{Type: "string"}, Description: "2 to 10 answers."},
+					"multiple":       {Type: "boolean", Description: "Allow voting for several answers."},
+					"duration_hours": {Type: "integer", Description: "How long voting stays open on Discord (default 24)."},
 				},
-				Required: []string{"content", "channel", "chat_id"},
+				Required: []string{"question", "options"},
+			},
+		},
+	}
+}
+
+func defRender() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "render",
+			Description: "Draw CSV or JSON data as a bar chart, line chart or table image and send it to the current conversation. Use it instead of long Markdown tables. Charts take labels from the first column and plot each numeric column after it.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"kind":    {Type: "string", Enum: []string{"bar", "line", "table"}},
+					"data":    {Type: "string", Description: "CSV with a header line, or a JSON array of objects or of arrays (the first array is the header)."},
+					"path":    {Type: "string", Description: "Workspace file with the data, instead of data."},
+					"format":  {Type: "string", Enum: []string{"csv", "json"}, Description: "Defaults to the file extension, or is told from the data."},
+					"title":   {Type: "string", Description: "Drawn above the image. ASCII only."},
+					"caption": {Type: "string", Description: "Text sent with the image."},
+				},
+				Required: []string{"kind"},
+			},
+		},
+	}
+}
+
+func defSendVoice() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "send_voice",
+			Description: "Send text to the current conversation as a spoken voice note, e.g. when the user asks to hear the answer. Write it as speech: no Markdown, links or code.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"text": {Type: "string", Description: "What to say."},
+				},
+				Required: []string{"text"},
 			},
 		},
 	}
@@ -248,6 +443,8 @@ func defCron() llm.ToolDefinition {
 					"every_seconds": {Type: "integer"},
 					"cron_expr":     {Type: "string"},
 					"job_id":        {Type: "string"},
+					"group":         {Type: "string", Description: "Recipient group from the config to deliver each run to, instead of this chat."},
+					"batch":         {Type: "boolean", Description: "Run on the provider's batch API: half the cost, but the result arrives up to 24 hours later and the run has no tools. For jobs that are not time-sensitive."},
 				},
 				Required: []string{"action"},
 			},
@@ -255,6 +452,28 @@ func defCron() llm.ToolDefinition {
 	}
 }
 
+func defRemind() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "remind",
+			Description: "Set a reminder for the user in the current chat, or list or cancel its reminders. Work out the calendar date and 24-hour time from the user's words and the current time; the reply gives the confirmation to pass on.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"action":   {Type: "string", Enum: []string{"add", "list", "cancel"}, Description: "Default add."},
+					"text":     {Type: "string", Description: "What to remind the user of."},
+					"date":     {Type: "string", Description: "YYYY-MM-DD. For weekly and monthly reminders it gives the weekday or day of the month; not needed for daily ones."},
+					"time":     {Type: "string", Description: "HH:MM, 24-hour clock."},
+					"timezone": {Type: "string", Description: "IANA name, only when the user gives the time in another time zone."},
+					"repeat":   {Type: "string", Enum: []string{"none", "daily", "weekdays", "weekly", "monthly"}, Description: "Default none."},
+					"job_id":   {Type: "string", Description: "The reminder to cancel."},
+				},
+			},
+		},
+	}
+}
+
 func defMemorySearch() llm.ToolDefinition {
 	return llm.ToolDefinition{
 		Type: "function",
@@ -292,3 +511,82 @@ func defMemoryGet() llm.ToolDefinition {
 		},
 	}
 }
+
+func defContactsAdd() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "contacts_add",
+			Description: "Add or update a contact mapping a name to channel addresses (merged by name).",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"name":    {Type: "string"},
+					"aliases": {Type: "array", Items: &llm.JSONSchema{Type: "string"}},
+					"addresses": {
+						Type:        "object",
+						Description: "Map of channel (telegram, discord, slack, whatsapp) or phone/email to chat_id/address.",
+					},
+					"notes": {Type: "string"},
+				},
+				Required: []string{"name", "addresses"},
+			},
+		},
+	}
+}
+
+func defContactsSearch() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "contacts_search",
+			Description: "Search saved contacts by name, alias, notes, or address.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"query": {Type: "string", Description: "Search text (empty lists all)."},
+					"limit": {Type: "integer", Description: "Maximum results (default 10)."},
+				},
+			},
+		},
+	}
+}
+
+func defRemember() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "remember",
+			Description: "Save the current conversation (or its last N messages) as a tagged note under memory/notes/. Use when the user asks to remember or persist something.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"title":              {Type: "string"},
+					"tags":               {Type: "array", Items: &llm.JSONSchema{Type: "string"}},
+					"summary":            {Type: "string", Description: "Concise summary of what should be remembered."},
+					"last_messages":      {Type: "integer", Description: "Only include the last N messages (default: whole recent conversation)."},
+					"include_transcript": {Type: "boolean", Description: "Include the messages verbatim (default true)."},
+				},
+				Required: []string{"title"},
+			},
+		},
+	}
+}
+
+func defJournal(sections []string) llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "journal",
+			Description: "Add an entry to a section of today's daily note (memory/YYYY-MM-DD.md). Use this instead of editing the daily note directly. Entries in Tasks become checklist items.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"section": {Type: "string", Enum: sections},
+					"entry":   {Type: "string", Description: "One line, e.g. a task, a conversation summary or a decision and its reason."},
+				},
+				Required: []string{"section", "entry"},
+			},
+		},
+	}
+}
@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	diffContext = 3
+	// diffMaxCells bounds the LCS table; larger changes are shown as one
+	// replaced block.
+	diffMaxCells = 1 << 20
+)
+
+// Edits collects the diffs of the files changed during one turn. A nil
+// *Edits ignores everything.
+type Edits struct {
+	mu    sync.Mutex
+	diffs []string
+}
+
+func (e *Edits) add(diff string) {
+	if e == nil || diff == "" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.diffs = append(e.diffs, diff)
+}
+
+func (e *Edits) List() []string {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.diffs...)
+}
+
+// withDiff appends the diff of a file write to a tool result.
+func (r *Registry) withDiff(tctx Context, result, path, before, after string) string {
+	if r.DiffMaxLines <= 0 {
+		return result
+	}
+	diff := unifiedDiff(path, before, after, r.DiffMaxLines)
+	if diff == "" {
+		return result + "\n(no changes)"
+	}
+	tctx.Edits.add(diff)
+	return result + "\n" + diff
+}
+
+// unifiedDiff returns a unified diff of before and after with at most
+// maxLines hunk lines, or "" when they are equal.
+func unifiedDiff(path, before, after string, maxLines int) string {
+	if before == after {
+		return ""
+	}
+	a, b := splitLines(before), splitLines(after)
+	ops := diffLines(a, b)
+
+	var out []string
+	for _, h := range hunks(ops) {
+		out = append(out, h...)
+	}
+	header := fmt.Sprintf("--- %s\n+++ %s\n", path, path)
+	if len(out) > maxLines {
+		more := len(out) - maxLines
+		out = append(out[:maxLines], fmt.Sprintf("... (%d more lines)", more))
+	}
+	return header + strings.Join(out, "\n")
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+	a, b int // 1-based line numbers in before/after
+}
+
+// diffLines trims the common prefix and suffix and runs an LCS over the rest.
+func diffLines(a, b []string) []diffOp {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	var ops []diffOp
+	for i := 0; i < pre; i++ {
+		ops = append(ops, diffOp{kind: ' ', text: a[i], a: i + 1, b: i + 1})
+	}
+	ops = append(ops, diffMiddle(a[pre:len(a)-suf], b[pre:len(b)-suf], pre, pre)...)
+	for i := 0; i < suf; i++ {
+		ia, ib := len(a)-suf+i, len(b)-suf+i
+		ops = append(ops, diffOp{kind: ' ', text: a[ia], a: ia + 1, b: ib + 1})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string, offA, offB int) []diffOp {
+	var ops []diffOp
+	del := func(i int) { ops = append(ops, diffOp{kind: '-', text: a[i], a: offA + i + 1, b: offB}) }
+	ins := func(j int) { ops = append(ops, diffOp{kind: '+', text: b[j], a: offA, b: offB + j + 1}) }
+
+	if len(a)*len(b) > diffMaxCells || len(a) == 0 || len(b) == 0 {
+		for i := range a {
+			del(i)
+		}
+		for j := range b {
+			ins(j)
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', text: a[i], a: offA + i + 1, b: offB + j + 1})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			del(i)
+			i++
+		default:
+			ins(j)
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		del(i)
+	}
+	for ; j < len(b); j++ {
+		ins(j)
+	}
+	return ops
+}
+
+// hunks groups ops into "@@" hunks with diffContext lines around changes.
+func hunks(ops []diffOp) [][]string {
+	var out [][]string
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		// Extend while the next change is within 2*diffContext lines.
+		end := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				end = k
+			} else if k-end > 2*diffContext {
+				break
+			}
+		}
+		lo := max(start-diffContext, 0)
+		hi := min(end+diffContext+1, len(ops))
+		out = append(out, formatHunk(ops[lo:hi]))
+		start = hi
+	}
+	return out
+}
+
+func formatHunk(ops []diffOp) []string {
+	var aStart, bStart, aLen, bLen int
+	for _, op := range ops {
+		if op.kind != '+' {
+			if aLen == 0 {
+				aStart = op.a
+			}
+			aLen++
+		}
+		if op.kind != '-' {
+			if bLen == 0 {
+				bStart = op.b
+			}
+			bLen++
+		}
+	}
+	// An empty side is reported at the line before it, as diff(1) does.
+	if aLen == 0 {
+		aStart = ops[0].a
+	}
+	if bLen == 0 {
+		bStart = ops[0].b
+	}
+	lines := []string{fmt.Sprintf("@@ -%d,%d +%d,%d @@", aStart, aLen, bStart, bLen)}
+	for _, op := range ops {
+		lines = append(lines, string(op.kind)+op.text)
+	}
+	return lines
+}
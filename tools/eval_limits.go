@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+const (
+	// evalMaxValue bounds the estimated size in bytes of a value a builtin
+	// builds in one call, such as str(x) or list(range(n)), which the step
+	// limit cannot catch.
+	evalMaxValue = 16 << 20
+	// evalElemSize is the size counted per element of a list, tuple or dict.
+	evalElemSize = 16
+)
+
+// evalBuiltins replace the universe builtins that build a string or a list
+// from another value in one call.
+var evalBuiltins = func() starlark.StringDict {
+	d := starlark.StringDict{}
+	for _, name := range []string{"str", "repr", "print", "fail"} {
+		d[name] = sizedBuiltin(starlark.Universe[name].(*starlark.Builtin), textArgsSize)
+	}
+	for _, name := range []string{"list", "tuple", "sorted", "reversed", "enumerate", "zip"} {
+		d[name] = sizedBuiltin(starlark.Universe[name].(*starlark.Builtin), lenArgsSize)
+	}
+	return d
+}()
+
+// sizedBuiltin wraps fn so it is refused when size says its result would
+// exceed evalMaxValue.
+func sizedBuiltin(fn *starlark.Builtin, size func(args starlark.Tuple) int) *starlark.Builtin {
+	return starlark.NewBuiltin(fn.Name(), func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if n := size(args); n > evalMaxValue {
+			return nil, fmt.Errorf("%s: result too large (about %d MB, max %d MB)", fn.Name(), n>>20, evalMaxValue>>20)
+		}
+		return starlark.Call(thread, fn, args, kwargs)
+	})
+}
+
+// textArgsSize estimates the string made of args, for str and print.
+func textArgsSize(args starlark.Tuple) int {
+	size := 0
+	for _, a := range args {
+		size += textSize(a, evalMaxValue-size)
+	}
+	return size
+}
+
+// lenArgsSize estimates a list of as many elements as the longest of args.
+func lenArgsSize(args starlark.Tuple) int {
+	n := 0
+	for _, a := range args {
+		n = max(n, starlark.Len(a))
+	}
+	if n > evalMaxValue/evalElemSize {
+		return evalMaxValue + 1
+	}
+	return n * evalElemSize
+}
+
+// textSize estimates the length of v as text, up to limit; a container that
+// holds a value many times counts it each time.
+func textSize(v starlark.Value, limit int) int {
+	switch v := v.(type) {
+	case starlark.String:
+		return len(v) + 2
+	case starlark.Bytes:
+		return 4*len(v) + 3
+	case starlark.Int:
+		return v.BigInt().BitLen()/3 + 2
+	case *starlark.List, starlark.Tuple, *starlark.Dict, *starlark.Set:
+		size := 2
+		iter := v.(starlark.Iterable).Iterate()
+		defer iter.Done()
+		var x starlark.Value
+		for iter.Next(&x) && size <= limit {
+			size += textSize(x, limit-size) + 2
+			if m, ok := v.(starlark.Mapping); ok {
+				if y, found, _ := m.Get(x); found {
+					size += textSize(y, limit-size) + 2
+				}
+			}
+		}
+		return size
+	}
+	return 32
+}
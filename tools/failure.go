@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/mosaxiv/clawlet/metrics"
+)
+
+// Failure kinds reported to the model and counted in metrics.
+const (
+	FailureTimeout     = "timeout"
+	FailureCanceled    = "canceled"
+	FailureDNS         = "dns"
+	FailureNetwork     = "network"
+	FailureHTTP        = "http"
+	FailureRateLimited = "rate_limited"
+	FailureNotFound    = "not_found"
+	FailurePermission  = "permission"
+	FailureBlocked     = "blocked"
+	FailureInvalidArgs = "invalid_arguments"
+	FailureDisabled    = "disabled"
+	FailureOther       = "error"
+)
+
+// Failure describes a failed tool call in a shape the model can act on.
+type Failure struct {
+	Tool      string `json:"tool"`
+	Kind      string `json:"kind"`
+	Retryable bool   `json:"retryable"`
+	Hint      string `json:"hint,omitempty"`
+	Error     string `json:"error"`
+}
+
+var httpStatusPattern = regexp.MustCompile(`\bhttp (\d{3})\b`)
+
+// ClassifyFailure maps a tool error to a failure kind, whether a retry may
+// help, and a hint for the next step.
+func ClassifyFailure(tool string, err error) Failure {
+	f := Failure{Tool: tool, Kind: FailureOther, Error: err.Error()}
+	msg := err.Error()
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, context.Canceled):
+		f.Kind = FailureCanceled
+	case errors.As(err, &dnsErr):
+		f.Kind, f.Retryable = FailureDNS, dnsErr.IsTemporary || dnsErr.IsTimeout
+		f.Hint = "the host name could not be resolved; check the URL"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		f.Kind, f.Retryable = FailureTimeout, true
+		f.Hint = "retry once, or ask for less data"
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.As(err, new(*net.OpError)):
+		f.Kind, f.Retryable = FailureNetwork, true
+		f.Hint = "the server could not be reached"
+	case errors.Is(err, os.ErrNotExist):
+		f.Kind = FailureNotFound
+		f.Hint = "check the path with list_dir"
+	case errors.Is(err, os.ErrPermission):
+		f.Kind = FailurePermission
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		f.Kind = FailureInvalidArgs
+		f.Hint = "fix the arguments to match the tool schema"
+	case strings.HasPrefix(msg, "tool disabled:"), strings.HasPrefix(msg, "unknown tool:"):
+		f.Kind = FailureDisabled
+		f.Hint = "use another tool"
+	case strings.Contains(msg, "blocked"), strings.Contains(msg, "outside workspace"), strings.Contains(msg, "not allowed"):
+		f.Kind = FailureBlocked
+		f.Hint = "this is refused by policy; do not retry"
+	default:
+		if m := httpStatusPattern.FindStringSubmatch(msg); m != nil {
+			code, _ := strconv.Atoi(m[1])
+			switch {
+			case code == 429:
+				f.Kind, f.Retryable = FailureRateLimited, true
+				f.Hint = "the service is rate limiting; try later"
+			case code >= 500:
+				f.Kind, f.Retryable = FailureHTTP, true
+			default:
+				f.Kind = FailureHTTP
+			}
+		}
+	}
+	return f
+}
+
+// Describe is a short user-facing reason, e.g. "DNS failure".
+func (f Failure) Describe() string {
+	switch f.Kind {
+	case FailureTimeout:
+		return "timed out"
+	case FailureCanceled:
+		return "canceled"
+	case FailureDNS:
+		return "DNS failure"
+	case FailureNetwork:
+		return "network error"
+	case FailureHTTP:
+		if m := httpStatusPattern.FindStringSubmatch(f.Error); m != nil {
+			return "HTTP " + m[1]
+		}
+		return "HTTP error"
+	case FailureRateLimited:
+		return "rate limited"
+	case FailureNotFound:
+		return "not found"
+	case FailurePermission:
+		return "permission denied"
+	case FailureBlocked:
+		return "blocked by policy"
+	case FailureInvalidArgs:
+		return "invalid arguments"
+	case FailureDisabled:
+		return "tool unavailable"
+	}
+	return "error"
+}
+
+// Failures collects the failed tool calls of one turn.
+type Failures struct {
+	mu   sync.Mutex
+	list []Failure
+}
+
+// Record classifies err, counts it in metrics and returns the tool result the
+// model sees: the error line followed by the structured failure object.
+func (f *Failures) Record(tool string, err error) string {
+	fail := ClassifyFailure(tool, err)
+	metrics.Inc("clawlet_tool_failures_total", "tool", tool, "kind", fail.Kind)
+	if f != nil {
+		f.mu.Lock()
+		f.list = append(f.list, fail)
+		f.mu.Unlock()
+	}
+	b, _ := json.Marshal(fail)
+	return fmt.Sprintf("error: %s\nfailure: %s", fail.Error, b)
+}
+
+// Last returns the most recent failure.
+func (f *Failures) Last() (Failure, bool) {
+	if f == nil {
+		return Failure{}, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.list) == 0 {
+		return Failure{}, false
+	}
+	return f.list[len(f.list)-1], true
+}
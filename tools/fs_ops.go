@@ -166,7 +166,7 @@ func (r *Registry) readFile(path string) (string, error) {
 	return string(b), nil
 }
 
-func (r *Registry) writeFile(path, content string) (string, error) {
+func (r *Registry) writeFile(tctx Context, path, content string) (string, error) {
 	abs, err := r.resolvePath(path)
 	if err != nil {
 		return "", err
@@ -195,13 +195,15 @@ func (r *Registry) writeFile(path, content string) (string, error) {
 	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
 		return "", fmt.Errorf("refusing to write through symlink: %s", target)
 	}
+	// A missing file diffs as empty.
+	before, _ := os.ReadFile(target)
 	if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("wrote %d bytes to %s", len(content), target), nil
+	return r.withDiff(tctx, fmt.Sprintf("wrote %d bytes to %s", len(content), target), target, string(before), content), nil
 }
 
-func (r *Registry) editFile(path string, startLine, endLine int, newText string) (string, error) {
+func (r *Registry) editFile(tctx Context, path string, startLine, endLine int, newText string) (string, error) {
 	abs, err := r.resolvePath(path)
 	if err != nil {
 		return "", err
@@ -247,10 +249,10 @@ func (r *Registry) editFile(path string, startLine, endLine int, newText string)
 	if err := os.WriteFile(abs, []byte(newContent), 0o644); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("edited %s", abs), nil
+	return r.withDiff(tctx, fmt.Sprintf("edited %s", abs), abs, s, newContent), nil
 }
 
-func (r *Registry) editFileReplace(path, oldText, newText string) (string, error) {
+func (r *Registry) editFileReplace(tctx Context, path, oldText, newText string) (string, error) {
 	abs, err := r.resolvePath(path)
 	if err != nil {
 		return "", err
@@ -274,7 +276,7 @@ func (r *Registry) editFileReplace(path, oldText, newText string) (string, error
 	if err := os.WriteFile(abs, []byte(updated), 0o644); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("edited %s", abs), nil
+	return r.withDiff(tctx, fmt.Sprintf("edited %s", abs), abs, content, updated), nil
 }
 
 func (r *Registry) listDir(path string, recursive bool, maxEntries int) (string, error) {
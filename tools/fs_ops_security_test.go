@@ -82,7 +82,7 @@ func TestWriteFile_BlocksSymlinkTarget(t *testing.T) {
 		WorkspaceDir:        ws,
 		RestrictToWorkspace: true,
 	}
-	if _, err := r.writeFile("link.txt", "overwrite"); err == nil {
+	if _, err := r.writeFile(Context{}, "link.txt", "overwrite"); err == nil {
 		t.Fatalf("expected symlink target write to be blocked")
 	}
 	got, err := os.ReadFile(outside)
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// sentKeyTTL is how long a sent message's key is kept: long enough for a
+// turn cut short by a crash to be redelivered and run again.
+const sentKeyTTL = 24 * time.Hour
+
+// SentLog remembers the idempotency keys of messages the message tool
+// sent, so that a turn that runs again does not send them twice.
+type SentLog struct {
+	path string
+	now  func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]time.Time // key -> when it was sent
+}
+
+// NewSentLog returns a log kept in path, or only in memory when path is
+// empty.
+func NewSentLog(path string) *SentLog {
+	l := &SentLog{path: path, now: time.Now, keys: map[string]time.Time{}}
+	if path == "" {
+		return l
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("tools: sent message log unreadable, starting empty: %v", err)
+		}
+		return l
+	}
+	if err := json.Unmarshal(b, &l.keys); err != nil {
+		log.Printf("tools: sent message log unreadable, starting empty: %v", err)
+		l.keys = map[string]time.Time{}
+	}
+	return l
+}
+
+// Sent reports whether a message with key was sent recently.
+func (l *SentLog) Sent(key string) bool {
+	if l == nil || key == "" {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	at, ok := l.keys[key]
+	return ok && l.now().Sub(at) < sentKeyTTL
+}
+
+// Record notes that the message with key was sent, and forgets keys older
+// than sentKeyTTL.
+func (l *SentLog) Record(key string) {
+	if l == nil || key == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for k, at := range l.keys {
+		if now.Sub(at) >= sentKeyTTL {
+			delete(l.keys, k)
+		}
+	}
+	l.keys[key] = now
+	if l.path == "" {
+		return
+	}
+	if err := l.saveLocked(); err != nil {
+		log.Printf("tools: sent message log not saved: %v", err)
+	}
+}
+
+func (l *SentLog) saveLocked() error {
+	b, err := json.Marshal(l.keys)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// TurnSends numbers the messages a turn sends to each chat. The nth
+// message to a chat gets the same key every time the turn runs, so a
+// retried turn skips the messages its earlier run already delivered. A nil
+// *TurnSends gives no keys.
+type TurnSends struct {
+	turn string
+
+	mu sync.Mutex
+	n  map[string]int
+}
+
+// NewTurnSends returns the counter for the turn with ID turn, or nil when
+// turn is empty.
+func NewTurnSends(turn string) *TurnSends {
+	if turn == "" {
+		return nil
+	}
+	return &TurnSends{turn: turn, n: map[string]int{}}
+}
+
+// next returns the key of the turn's next message to channel:chatID.
+func (t *TurnSends) next(channel, chatID string) string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	target := channel + ":" + chatID
+	t.n[target]++
+	return fmt.Sprintf("%s/%s/%d", t.turn, target, t.n[target])
+}
@@ -9,50 +9,110 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mosaxiv/clawlet/broadcast"
 	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/contacts"
 	"github.com/mosaxiv/clawlet/cron"
 	"github.com/mosaxiv/clawlet/llm"
 	"github.com/mosaxiv/clawlet/memory"
+	"github.com/mosaxiv/clawlet/reminders"
+	"github.com/mosaxiv/clawlet/session"
 )
 
 type Context struct {
 	Channel    string
 	ChatID     string
 	SessionKey string
+	// MessageID is the chat app's ID of the message being answered; the
+	// react tool reacts to it by default.
+	MessageID string
+	// Sources, when set, collects the pages and memory files returned by
+	// web and memory tools so the reply can cite them.
+	Sources *Sources
+	// Edits, when set, collects the diffs of files written during the turn.
+	Edits *Edits
+	// Skill, when set, tracks the skill read with read_skill during the
+	// turn; later calls get its data directory.
+	Skill *ActiveSkill
+	// Denied are tools the sender of the turn may not use, by built-in name.
+	Denied map[string]bool
+	// Buffers, when set, holds the turn's scratch buffers.
+	Buffers *Buffers
+	// Sends, when set, keys the turn's message tool calls so that a retry
+	// of the turn does not deliver them twice.
+	Sends *TurnSends
 }
 
 type Registry struct {
 	WorkspaceDir        string
 	RestrictToWorkspace bool
-	ExecTimeout         time.Duration
+	// IsolateSkills confines file tools and exec to the active skill's
+	// directory once read_skill has run in a turn.
+	IsolateSkills bool
+	ExecTimeout   time.Duration
+	// DiffMaxLines caps the unified diff appended to write_file and
+	// edit_file results; 0 leaves results without a diff.
+	DiffMaxLines int
 
 	// If non-empty, only these tools are exposed and executable.
 	// Unknown tool names are ignored.
 	AllowTools []string
 
-	BraveAPIKey             string
-	WebFetchAllowedDomains  []string
-	WebFetchBlockedDomains  []string
-	WebFetchMaxResponse     int64
-	WebFetchTimeout         time.Duration
-	Outbound                func(ctx context.Context, msg bus.OutboundMessage) error
-	Spawn                   func(ctx context.Context, task, label, originChannel, originChatID string) (string, error)
-	Cron                    *cron.Service
+	// Aliases exposes a tool under additional names (alias -> tool);
+	// Renames exposes a tool only under a new name (tool -> new name).
+	// AllowTools always refers to the built-in names.
+	Aliases map[string]string
+	Renames map[string]string
+
+	BraveAPIKey            string
+	WebFetchAllowedDomains []string
+	WebFetchBlockedDomains []string
+	WebFetchMaxResponse    int64
+	WebFetchTimeout        time.Duration
+	Outbound               func(ctx context.Context, msg bus.OutboundMessage) error
+	Spawn                  func(ctx context.Context, task, label, originChannel, originChatID string) (string, error)
+	Cron                   *cron.Service
+	// ReminderLocation is the time zone reminders are set in; nil for the
+	// system's.
+	ReminderLocation        *time.Location
 	ReadSkill               func(name string) (string, bool)
 	SkillRegistry           SkillRegistry
 	SkillSearchDefaultLimit int
 	MemorySearch            memory.SearchManager
+	Contacts                *contacts.Store
+	// Conversation returns the stored messages of a session, for remember.
+	Conversation func(sessionKey string) []session.Message
+	// Journal, when set, adds the journal tool for the daily note.
+	Journal *memory.Journal
+	// Speak, when set with Outbound, adds the send_voice tool.
+	Speak func(ctx context.Context, text string) (bus.Attachment, error)
+	// Sent, when set, records the messages sent in turns with Sends.
+	Sent *SentLog
+	// Recipients, when set, resolves the recipient groups message can
+	// send to.
+	Recipients func(group string) ([]broadcast.Recipient, error)
 
 	skillInstallMu sync.Mutex
 }
 
 func (r *Registry) Definitions() []llm.ToolDefinition {
+	return r.DefinitionsFor(nil)
+}
+
+// DefinitionsFor is Definitions without the denied tools, for a sender
+// whose role rules them out.
+func (r *Registry) DefinitionsFor(denied map[string]bool) []llm.ToolDefinition {
 	defs := []llm.ToolDefinition{
 		defReadFile(),
 		defWriteFile(),
 		defEditFile(),
+		defGetValue(),
+		defSetValue(),
 		defListDir(),
 		defExec(),
+		defEval(),
+		defSetBuffer(),
+		defGetBuffer(),
 		defWebFetch(),
 	}
 	if r.ReadSkill != nil {
@@ -65,35 +125,71 @@ func (r *Registry) Definitions() []llm.ToolDefinition {
 		defs = append(defs, defWebSearch())
 	}
 	if r.Outbound != nil {
-		defs = append(defs, defMessage())
+		defs = append(defs, defMessage(), defReact(), defCreatePoll(), defRender())
+		if r.Speak != nil {
+			defs = append(defs, defSendVoice())
+		}
 	}
 	if r.Spawn != nil {
 		defs = append(defs, defSpawn())
 	}
 	if r.Cron != nil {
-		defs = append(defs, defCron())
+		defs = append(defs, defCron(), defRemind())
 	}
 	if r.MemorySearch != nil {
 		defs = append(defs, defMemorySearch(), defMemoryGet())
 	}
-	if len(r.AllowTools) == 0 {
-		return defs
+	if r.Contacts != nil {
+		defs = append(defs, defContactsAdd(), defContactsSearch())
+	}
+	if r.Conversation != nil {
+		defs = append(defs, defRemember())
+	}
+	if r.Journal != nil {
+		defs = append(defs, defJournal(r.Journal.Sections))
+	}
+	withSaveToBuffer(defs)
+	if len(r.AllowTools) == 0 && len(denied) == 0 {
+		return r.exposeNames(defs)
 	}
 	allow := r.allowSet()
 	out := make([]llm.ToolDefinition, 0, len(defs))
 	for _, d := range defs {
 		name := strings.TrimSpace(d.Function.Name)
-		if name != "" && allow[name] {
-			out = append(out, d)
+		if name == "" || denied[name] || (len(r.AllowTools) > 0 && !allow[name]) {
+			continue
 		}
+		out = append(out, d)
 	}
-	return out
+	return r.exposeNames(out)
 }
 
 func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args json.RawMessage) (string, error) {
+	name, args, err := r.resolveName(name, args)
+	if err != nil {
+		return "", err
+	}
 	if !r.allowed(name) {
 		return "", fmt.Errorf("tool disabled: %s", name)
 	}
+	if tctx.Denied[name] {
+		return "", fmt.Errorf("tool not allowed for this sender: %s", name)
+	}
+	args, saveTo, err := tctx.Buffers.prepare(args)
+	if err != nil {
+		return "", err
+	}
+	if saveTo == "" {
+		return r.execute(ctx, tctx, name, args)
+	}
+	out, err := r.execute(ctx, tctx, name, args)
+	if err != nil {
+		return "", err
+	}
+	return tctx.Buffers.save(saveTo, out)
+}
+
+func (r *Registry) execute(ctx context.Context, tctx Context, name string, args json.RawMessage) (string, error) {
 	switch name {
 	case "read_file":
 		var a struct {
@@ -102,6 +198,9 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
+		if err := r.checkSkillScope(tctx, a.Path, false); err != nil {
+			return "", err
+		}
 		return r.readFile(a.Path)
 	case "write_file":
 		var a struct {
@@ -111,12 +210,22 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
-		return r.writeFile(a.Path, a.Content)
+		if err := r.checkSkillScope(tctx, a.Path, true); err != nil {
+			return "", err
+		}
+		return r.writeFile(tctx, a.Path, a.Content)
 	case "edit_file":
 		var raw map[string]json.RawMessage
 		if err := json.Unmarshal(args, &raw); err != nil {
 			return "", err
 		}
+		var target struct {
+			Path string `json:"path"`
+		}
+		_ = json.Unmarshal(args, &target)
+		if err := r.checkSkillScope(tctx, target.Path, true); err != nil {
+			return "", err
+		}
 		_, hasOld := raw["old_text"]
 		_, hasNew := raw["new_text"]
 		if !hasOld && !hasNew {
@@ -130,7 +239,7 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 			if err := json.Unmarshal(args, &a); err != nil {
 				return "", err
 			}
-			return r.editFile(a.Path, a.StartLine, a.EndLine, a.NewText)
+			return r.editFile(tctx, a.Path, a.StartLine, a.EndLine, a.NewText)
 		}
 		var a struct {
 			Path    string `json:"path"`
@@ -140,7 +249,44 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
-		return r.editFileReplace(a.Path, a.OldText, a.NewText)
+		return r.editFileReplace(tctx, a.Path, a.OldText, a.NewText)
+	case "get_value":
+		var a struct {
+			Path string `json:"path"`
+			Key  string `json:"key"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		if err := r.checkSkillScope(tctx, a.Path, false); err != nil {
+			return "", err
+		}
+		return r.getValue(a.Path, a.Key)
+	case "set_value":
+		var a struct {
+			Path   string          `json:"path"`
+			Key    string          `json:"key"`
+			Value  json.RawMessage `json:"value"`
+			Delete bool            `json:"delete"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		if err := r.checkSkillScope(tctx, a.Path, true); err != nil {
+			return "", err
+		}
+		var value string
+		if !a.Delete {
+			if len(a.Value) == 0 {
+				return "", errors.New("value is required unless delete is set")
+			}
+			v, err := parseValue(a.Value)
+			if err != nil {
+				return "", err
+			}
+			value = v
+		}
+		return r.setValue(tctx, a.Path, a.Key, value, a.Delete)
 	case "list_dir":
 		var a struct {
 			Path       string `json:"path"`
@@ -150,6 +296,9 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
+		if err := r.checkSkillScope(tctx, a.Path, false); err != nil {
+			return "", err
+		}
 		return r.listDir(a.Path, a.Recursive, a.MaxEntries)
 	case "exec":
 		var a struct {
@@ -158,7 +307,41 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
-		return r.exec(ctx, a.Command)
+		return r.exec(ctx, tctx, a.Command)
+	case "eval":
+		var a struct {
+			Code string `json:"code"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		return r.evalCode(ctx, tctx.Buffers, a.Code)
+	case "set_buffer":
+		var a struct {
+			Name    string `json:"name"`
+			Content string `json:"content"`
+			Append  bool   `json:"append"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		if a.Append {
+			prev, _ := tctx.Buffers.Get(a.Name)
+			a.Content = prev + a.Content
+		}
+		if err := tctx.Buffers.Set(a.Name, a.Content); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("buffer %s holds %d bytes", a.Name, len(a.Content)), nil
+	case "get_buffer":
+		var a struct {
+			Name   string `json:"name"`
+			Offset int    `json:"offset"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		return getBuffer(tctx.Buffers, strings.TrimSpace(a.Name), a.Offset)
 	case "read_skill":
 		var a struct {
 			Name string `json:"name"`
@@ -166,7 +349,7 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
-		return r.readSkill(a.Name)
+		return r.readSkill(tctx, a.Name)
 	case "find_skills":
 		var a struct {
 			Query string `json:"query"`
@@ -197,7 +380,11 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
-		return r.webFetch(ctx, a.URL, a.ExtractMode, a.MaxChars, a.Headers)
+		out, err := r.webFetch(ctx, a.URL, a.ExtractMode, a.MaxChars, a.Headers)
+		if err == nil {
+			tctx.Sources.record(name, out)
+		}
+		return out, err
 	case "web_search":
 		var a struct {
 			Query string `json:"query"`
@@ -206,28 +393,93 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
-		return r.webSearch(ctx, a.Query, a.Count)
+		return r.webSearch(ctx, a.Query, a.Count, tctx.Sources)
 	case "message":
 		var a struct {
-			Content string `json:"content"`
-			Channel string `json:"channel"`
-			ChatID  string `json:"chat_id"`
+			Content string        `json:"content"`
+			Channel string        `json:"channel"`
+			ChatID  string        `json:"chat_id"`
+			Contact string        `json:"contact"`
+			Group   string        `json:"group"`
+			Files   []string      `json:"files"`
+			Options []string      `json:"options"`
+			Cards   []messageCard `json:"cards"`
 		}
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
 		ch := strings.TrimSpace(a.Channel)
 		cid := strings.TrimSpace(a.ChatID)
+		if group := strings.TrimSpace(a.Group); group != "" && cid == "" && strings.TrimSpace(a.Contact) == "" {
+			return r.messageGroup(ctx, tctx, group, a.Content, a.Files, a.Options, a.Cards)
+		}
+		if cid == "" && strings.TrimSpace(a.Contact) != "" {
+			var err error
+			ch, cid, err = r.resolveMessageTarget(a.Contact, ch)
+			if err != nil {
+				return "", err
+			}
+		}
 		if ch == "" || cid == "" {
-			return "", errors.New("message requires explicit channel and chat_id")
+			return "", errors.New("message requires explicit channel and chat_id (or a known contact or recipient group)")
 		}
 		// Avoid duplicate sends to the active conversation; reply with normal assistant text instead.
-		if strings.TrimSpace(tctx.Channel) != "" && strings.TrimSpace(tctx.ChatID) != "" {
+		// Files, options and cards are the exception: assistant text cannot carry them.
+		if len(a.Files) == 0 && len(a.Options) == 0 && len(a.Cards) == 0 && strings.TrimSpace(tctx.Channel) != "" && strings.TrimSpace(tctx.ChatID) != "" {
 			if ch == strings.TrimSpace(tctx.Channel) && cid == strings.TrimSpace(tctx.ChatID) {
 				return "", errors.New("message to current session is not allowed; respond with assistant text instead")
 			}
 		}
-		return r.message(ctx, ch, cid, a.Content)
+		key := tctx.Sends.next(ch, cid)
+		if r.Sent.Sent(key) {
+			return fmt.Sprintf("Message already sent to %s:%s by an earlier run of this turn; not sent again", ch, cid), nil
+		}
+		out, err := r.message(ctx, ch, cid, a.Content, a.Files, a.Options, a.Cards)
+		if err == nil {
+			r.Sent.Record(key)
+		}
+		return out, err
+	case "react":
+		var a struct {
+			Emoji     string `json:"emoji"`
+			MessageID string `json:"message_id"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		return r.react(ctx, tctx, a.Emoji, a.MessageID)
+	case "create_poll":
+		var a struct {
+			Question      string   `json:"question"`
+			Options       []string `json:"options"`
+			Multiple      bool     `json:"multiple"`
+			DurationHours int      `json:"duration_hours"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		return r.createPoll(ctx, tctx, a.Question, a.Options, a.Multiple, a.DurationHours)
+	case "render":
+		var a struct {
+			Kind    string `json:"kind"`
+			Data    string `json:"data"`
+			Path    string `json:"path"`
+			Format  string `json:"format"`
+			Title   string `json:"title"`
+			Caption string `json:"caption"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		return r.renderData(ctx, tctx, a.Kind, a.Data, a.Path, a.Format, a.Title, a.Caption)
+	case "send_voice":
+		var a struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		return r.sendVoice(ctx, tctx, a.Text)
 	case "spawn":
 		var a struct {
 			Task  string `json:"task"`
@@ -244,11 +496,27 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 			EverySeconds int    `json:"every_seconds"`
 			CronExpr     string `json:"cron_expr"`
 			JobID        string `json:"job_id"`
+			Group        string `json:"group"`
+			Batch        bool   `json:"batch"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		return r.cronTool(ctx, tctx, a.Action, a.Message, a.EverySeconds, a.CronExpr, a.JobID, a.Group, a.Batch)
+	case "remind":
+		var a struct {
+			Action   string `json:"action"`
+			Text     string `json:"text"`
+			Date     string `json:"date"`
+			Time     string `json:"time"`
+			Timezone string `json:"timezone"`
+			Repeat   string `json:"repeat"`
+			JobID    string `json:"job_id"`
 		}
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
-		return r.cronTool(ctx, tctx, a.Action, a.Message, a.EverySeconds, a.CronExpr, a.JobID)
+		return r.remindTool(tctx, a.Action, a.JobID, reminders.Request{Text: a.Text, Date: a.Date, Time: a.Time, Timezone: a.Timezone, Repeat: a.Repeat})
 	case "memory_search":
 		var a struct {
 			Query      string   `json:"query"`
@@ -258,7 +526,11 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
-		return r.memorySearch(ctx, a.Query, a.MaxResults, a.MinScore)
+		out, err := r.memorySearch(ctx, a.Query, a.MaxResults, a.MinScore)
+		if err == nil {
+			tctx.Sources.record(name, out)
+		}
+		return out, err
 	case "memory_get":
 		var a struct {
 			Path  string `json:"path"`
@@ -268,7 +540,53 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 		if err := json.Unmarshal(args, &a); err != nil {
 			return "", err
 		}
-		return r.memoryGet(a.Path, a.From, a.Lines)
+		out, err := r.memoryGet(a.Path, a.From, a.Lines)
+		if err == nil {
+			tctx.Sources.record(name, out)
+		}
+		return out, err
+	case "remember":
+		var a struct {
+			Title             string   `json:"title"`
+			Tags              []string `json:"tags"`
+			Summary           string   `json:"summary"`
+			LastMessages      int      `json:"last_messages"`
+			IncludeTranscript *bool    `json:"include_transcript"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		include := a.IncludeTranscript == nil || *a.IncludeTranscript
+		return r.remember(tctx, a.Title, a.Tags, a.Summary, a.LastMessages, include)
+	case "journal":
+		var a struct {
+			Section string `json:"section"`
+			Entry   string `json:"entry"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		return r.journal(a.Section, a.Entry)
+	case "contacts_add":
+		var a struct {
+			Name      string            `json:"name"`
+			Aliases   []string          `json:"aliases"`
+			Addresses map[string]string `json:"addresses"`
+			Notes     string            `json:"notes"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		return r.contactsAdd(a.Name, a.Aliases, a.Addresses, a.Notes)
+	case "contacts_search":
+		var a struct {
+			Query string `json:"query"`
+			Limit int    `json:"limit"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		return r.contactsSearch(a.Query, a.Limit)
 	default:
 		return "", fmt.Errorf("unknown tool: %s", name)
 	}
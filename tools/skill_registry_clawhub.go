@@ -194,43 +194,12 @@ func (c *ClawHubRegistry) Install(ctx context.Context, req SkillInstallRequest)
 	if registryName != "clawhub" {
 		return SkillInstallResult{}, fmt.Errorf("unsupported registry: %s", registryName)
 	}
-	workspace := strings.TrimSpace(req.WorkspaceDir)
-	if workspace == "" {
-		return SkillInstallResult{}, fmt.Errorf("workspace is empty")
-	}
-	workspaceAbs, err := filepath.Abs(workspace)
-	if err != nil {
-		return SkillInstallResult{}, err
-	}
 	version := strings.TrimSpace(req.Version)
 
-	skillsDir := filepath.Join(workspaceAbs, "skills")
-	targetDir := filepath.Join(skillsDir, slug)
-
-	if _, err := os.Stat(targetDir); err == nil {
-		if !req.Force {
-			return SkillInstallResult{}, fmt.Errorf("skill %q already installed (use force=true to reinstall)", slug)
-		}
-		if err := os.RemoveAll(targetDir); err != nil {
-			return SkillInstallResult{}, fmt.Errorf("failed to remove existing skill: %w", err)
-		}
-	}
-	if err := os.MkdirAll(targetDir, 0o755); err != nil {
-		return SkillInstallResult{}, fmt.Errorf("failed to create skill directory: %w", err)
-	}
-
-	cleanup := true
-	defer func() {
-		if cleanup {
-			_ = os.RemoveAll(targetDir)
-		}
-	}()
-
 	meta, _ := c.fetchSkillMeta(ctx, slug)
 	result := SkillInstallResult{
 		RegistryName: "clawhub",
 		Slug:         slug,
-		InstallPath:  targetDir,
 	}
 	if meta != nil {
 		result.Summary = strings.TrimSpace(meta.Summary)
@@ -252,27 +221,9 @@ func (c *ClawHubRegistry) Install(ctx context.Context, req SkillInstallRequest)
 	}
 	result.Version = version
 
-	zipPath, err := c.downloadSkillArchive(ctx, slug, version)
-	if err != nil {
-		return SkillInstallResult{}, err
-	}
-	defer os.Remove(zipPath)
-
-	if err := extractZipSecure(zipPath, targetDir); err != nil {
-		return SkillInstallResult{}, err
-	}
-	if err := normalizeSkillLayout(targetDir); err != nil {
-		return SkillInstallResult{}, err
-	}
-	if _, err := os.Stat(filepath.Join(targetDir, "SKILL.md")); err != nil {
-		return SkillInstallResult{}, fmt.Errorf("installed archive does not contain SKILL.md")
-	}
-	if err := writeSkillOrigin(targetDir, result.RegistryName, result.Slug, result.Version); err != nil {
-		return SkillInstallResult{}, fmt.Errorf("failed to write skill metadata: %w", err)
-	}
-
-	cleanup = false
-	return result, nil
+	return installSkillArchive(req.WorkspaceDir, req.Force, result, func() (string, error) {
+		return c.downloadSkillArchive(ctx, slug, version)
+	})
 }
 
 func (c *ClawHubRegistry) fetchSkillMeta(ctx context.Context, slug string) (*clawHubSkillResponse, error) {
@@ -377,6 +328,71 @@ func (c *ClawHubRegistry) buildURL(path string) (*url.URL, error) {
 	return base, nil
 }
 
+// installSkillArchive installs the zip archive that download saves to a
+// temporary file as workspace/skills/<slug>. An installed skill is only
+// replaced when force is set.
+func installSkillArchive(workspace string, force bool, result SkillInstallResult, download func() (string, error)) (SkillInstallResult, error) {
+	workspace = strings.TrimSpace(workspace)
+	if workspace == "" {
+		return SkillInstallResult{}, fmt.Errorf("workspace is empty")
+	}
+	workspaceAbs, err := filepath.Abs(workspace)
+	if err != nil {
+		return SkillInstallResult{}, err
+	}
+	targetDir := filepath.Join(workspaceAbs, "skills", result.Slug)
+
+	// The skill's data directory survives a reinstall.
+	var stash string
+	if _, err := os.Stat(targetDir); err == nil {
+		if _, err := os.Stat(filepath.Join(targetDir, "SKILL.md")); err == nil && !force {
+			return SkillInstallResult{}, fmt.Errorf("skill %q already installed (use force=true to reinstall)", result.Slug)
+		}
+		if stash, err = stashSkillData(targetDir); err != nil {
+			return SkillInstallResult{}, fmt.Errorf("failed to keep skill data: %w", err)
+		}
+		if err := os.RemoveAll(targetDir); err != nil {
+			restoreSkillData(stash, targetDir)
+			return SkillInstallResult{}, fmt.Errorf("failed to remove existing skill: %w", err)
+		}
+	}
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		restoreSkillData(stash, targetDir)
+		return SkillInstallResult{}, fmt.Errorf("failed to create skill directory: %w", err)
+	}
+
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = os.RemoveAll(targetDir)
+		}
+		restoreSkillData(stash, targetDir)
+	}()
+
+	zipPath, err := download()
+	if err != nil {
+		return SkillInstallResult{}, err
+	}
+	defer os.Remove(zipPath)
+
+	if err := extractZipSecure(zipPath, targetDir); err != nil {
+		return SkillInstallResult{}, err
+	}
+	if err := normalizeSkillLayout(targetDir); err != nil {
+		return SkillInstallResult{}, err
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "SKILL.md")); err != nil {
+		return SkillInstallResult{}, fmt.Errorf("installed archive does not contain SKILL.md")
+	}
+	if err := writeSkillOrigin(targetDir, result.RegistryName, result.Slug, result.Version); err != nil {
+		return SkillInstallResult{}, fmt.Errorf("failed to write skill metadata: %w", err)
+	}
+
+	cleanup = false
+	result.InstallPath = targetDir
+	return result, nil
+}
+
 func extractZipSecure(zipPath, targetDir string) error {
 	zr, err := zip.OpenReader(zipPath)
 	if err != nil {
@@ -0,0 +1,396 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexRegistryConfig describes a static skill registry: an index.json plus
+// zip files on any HTTP server or in a local directory.
+type IndexRegistryConfig struct {
+	Name string
+	// URL locates index.json: http(s)://, file:// or a local path.
+	URL              string
+	AuthToken        string
+	TimeoutSec       int
+	MaxZipBytes      int64
+	MaxResponseBytes int64
+}
+
+// IndexRegistry serves skills listed in a static index file:
+//
+//	{"skills": [{"slug": "weather", "version": "1.2.0", "summary": "...",
+//	             "url": "weather-1.2.0.zip", "sha256": "..."}]}
+//
+// Archive URLs are resolved relative to the index. A slug may be listed once
+// per version, newest first.
+type IndexRegistry struct {
+	name             string
+	location         string
+	authToken        string
+	maxZipBytes      int64
+	maxResponseBytes int64
+	client           *http.Client
+}
+
+func NewIndexRegistry(cfg IndexRegistryConfig) *IndexRegistry {
+	timeoutSec := cfg.TimeoutSec
+	if timeoutSec <= 0 {
+		timeoutSec = defaultSkillRegistryTimeoutSec
+	}
+	maxZipBytes := cfg.MaxZipBytes
+	if maxZipBytes <= 0 {
+		maxZipBytes = defaultSkillRegistryMaxZipBytes
+	}
+	maxResponseBytes := cfg.MaxResponseBytes
+	if maxResponseBytes <= 0 {
+		maxResponseBytes = defaultSkillRegistryMaxResponseBytes
+	}
+	return &IndexRegistry{
+		name:             strings.TrimSpace(cfg.Name),
+		location:         strings.TrimSpace(cfg.URL),
+		authToken:        strings.TrimSpace(cfg.AuthToken),
+		maxZipBytes:      maxZipBytes,
+		maxResponseBytes: maxResponseBytes,
+		client: &http.Client{
+			Timeout: time.Duration(timeoutSec) * time.Second,
+		},
+	}
+}
+
+type skillIndex struct {
+	Skills []skillIndexEntry `json:"skills"`
+}
+
+type skillIndexEntry struct {
+	Slug        string   `json:"slug"`
+	DisplayName string   `json:"displayName"`
+	Summary     string   `json:"summary"`
+	Version     string   `json:"version"`
+	URL         string   `json:"url"`
+	SHA256      string   `json:"sha256"`
+	Tags        []string `json:"tags"`
+}
+
+func (r *IndexRegistry) Search(ctx context.Context, query string, limit int) ([]SkillSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("query is empty")
+	}
+	if limit <= 0 {
+		limit = 5
+	}
+	if limit > 20 {
+		limit = 20
+	}
+	index, err := r.fetchIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(strings.ToLower(query))
+	seen := map[string]bool{}
+	var out []SkillSearchResult
+	for _, e := range index.Skills {
+		slug := strings.TrimSpace(e.Slug)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		score := scoreIndexEntry(e, terms)
+		if score <= 0 {
+			continue
+		}
+		displayName := strings.TrimSpace(e.DisplayName)
+		if displayName == "" {
+			displayName = slug
+		}
+		out = append(out, SkillSearchResult{
+			Score:        score,
+			Slug:         slug,
+			DisplayName:  displayName,
+			Summary:      strings.TrimSpace(e.Summary),
+			Version:      strings.TrimSpace(e.Version),
+			RegistryName: r.name,
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// scoreIndexEntry returns the share of query terms found in the entry, with a
+// bonus when a term is the slug itself.
+func scoreIndexEntry(e skillIndexEntry, terms []string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	slug := strings.ToLower(strings.TrimSpace(e.Slug))
+	text := strings.ToLower(strings.Join(append([]string{e.Slug, e.DisplayName, e.Summary}, e.Tags...), " "))
+	var hits, bonus float64
+	for _, t := range terms {
+		if strings.Contains(text, t) {
+			hits++
+		}
+		if t == slug {
+			bonus = 0.5
+		}
+	}
+	if hits == 0 {
+		return 0
+	}
+	return hits/float64(len(terms)) + bonus
+}
+
+func (r *IndexRegistry) Install(ctx context.Context, req SkillInstallRequest) (SkillInstallResult, error) {
+	slug, err := validateSkillIdentifier(req.Slug)
+	if err != nil {
+		return SkillInstallResult{}, fmt.Errorf("invalid slug: %w", err)
+	}
+	registryName, err := validateSkillIdentifier(req.RegistryName)
+	if err != nil {
+		return SkillInstallResult{}, fmt.Errorf("invalid registry: %w", err)
+	}
+	if registryName != r.name {
+		return SkillInstallResult{}, fmt.Errorf("unsupported registry: %s", registryName)
+	}
+	index, err := r.fetchIndex(ctx)
+	if err != nil {
+		return SkillInstallResult{}, err
+	}
+	version := strings.TrimSpace(req.Version)
+	var entry *skillIndexEntry
+	for i := range index.Skills {
+		e := &index.Skills[i]
+		if strings.TrimSpace(e.Slug) != slug {
+			continue
+		}
+		if version == "" || version == "latest" || strings.TrimSpace(e.Version) == version {
+			entry = e
+			break
+		}
+	}
+	if entry == nil {
+		if version != "" && version != "latest" {
+			return SkillInstallResult{}, fmt.Errorf("skill %q version %s not found in %s", slug, version, r.name)
+		}
+		return SkillInstallResult{}, fmt.Errorf("skill %q not found in %s", slug, r.name)
+	}
+	if strings.TrimSpace(entry.URL) == "" {
+		return SkillInstallResult{}, fmt.Errorf("skill %q has no archive url in %s", slug, r.name)
+	}
+
+	result := SkillInstallResult{
+		RegistryName: r.name,
+		Slug:         slug,
+		Version:      strings.TrimSpace(entry.Version),
+		Summary:      strings.TrimSpace(entry.Summary),
+	}
+	if result.Version == "" {
+		result.Version = "latest"
+	}
+	return installSkillArchive(req.WorkspaceDir, req.Force, result, func() (string, error) {
+		return r.downloadArchive(ctx, entry.URL, entry.SHA256)
+	})
+}
+
+func (r *IndexRegistry) fetchIndex(ctx context.Context) (*skillIndex, error) {
+	if r.location == "" {
+		return nil, fmt.Errorf("registry %s has no index url", r.name)
+	}
+	rc, err := r.open(ctx, r.location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index: %w", err)
+	}
+	defer rc.Close()
+	body, err := io.ReadAll(io.LimitReader(rc, r.maxResponseBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index: %w", err)
+	}
+	if int64(len(body)) > r.maxResponseBytes {
+		return nil, fmt.Errorf("index too large")
+	}
+	var index skillIndex
+	if err := json.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse index: %w", err)
+	}
+	return &index, nil
+}
+
+func (r *IndexRegistry) downloadArchive(ctx context.Context, ref, wantSHA256 string) (string, error) {
+	loc, err := r.resolve(ref)
+	if err != nil {
+		return "", err
+	}
+	rc, err := r.open(ctx, loc)
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "clawlet-skill-*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	h := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(rc, r.maxZipBytes+1))
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to save downloaded archive: %w", err)
+	}
+	if written > r.maxZipBytes {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("downloaded archive exceeds size limit")
+	}
+	if want := strings.TrimSpace(wantSHA256); want != "" {
+		if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, want) {
+			_ = os.Remove(tmp.Name())
+			return "", fmt.Errorf("archive checksum mismatch: got sha256 %s", got)
+		}
+	}
+	return tmp.Name(), nil
+}
+
+// resolve turns an archive reference from the index into a location that open
+// understands, relative to the index itself.
+func (r *IndexRegistry) resolve(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if isHTTPURL(ref) {
+		return ref, nil
+	}
+	if isHTTPURL(r.location) {
+		base, err := url.Parse(r.location)
+		if err != nil {
+			return "", fmt.Errorf("invalid index url: %w", err)
+		}
+		u, err := base.Parse(ref)
+		if err != nil {
+			return "", fmt.Errorf("invalid archive url %q: %w", ref, err)
+		}
+		return u.String(), nil
+	}
+	if p, ok := strings.CutPrefix(ref, "file://"); ok {
+		return p, nil
+	}
+	if filepath.IsAbs(ref) {
+		return ref, nil
+	}
+	return filepath.Join(filepath.Dir(localIndexPath(r.location)), filepath.FromSlash(ref)), nil
+}
+
+// open reads an http(s) URL, a file:// URL or a local path.
+func (r *IndexRegistry) open(ctx context.Context, loc string) (io.ReadCloser, error) {
+	if !isHTTPURL(loc) {
+		return os.Open(localIndexPath(loc))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, loc, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.authToken)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, string(body))
+	}
+	return resp.Body, nil
+}
+
+func isHTTPURL(s string) bool {
+	lower := strings.ToLower(s)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+func localIndexPath(loc string) string {
+	if p, ok := strings.CutPrefix(loc, "file://"); ok {
+		return p
+	}
+	return loc
+}
+
+// MultiSkillRegistry searches several registries at once and installs from the
+// one named in the request.
+type MultiSkillRegistry struct {
+	names      []string
+	registries map[string]SkillRegistry
+}
+
+func NewMultiSkillRegistry() *MultiSkillRegistry {
+	return &MultiSkillRegistry{registries: map[string]SkillRegistry{}}
+}
+
+// Add registers reg under name; it fails when the name is already taken.
+func (m *MultiSkillRegistry) Add(name string, reg SkillRegistry) error {
+	name, err := validateSkillIdentifier(name)
+	if err != nil {
+		return fmt.Errorf("invalid registry name: %w", err)
+	}
+	if _, ok := m.registries[name]; ok {
+		return fmt.Errorf("duplicate skill registry name: %s", name)
+	}
+	m.names = append(m.names, name)
+	m.registries[name] = reg
+	return nil
+}
+
+func (m *MultiSkillRegistry) Len() int { return len(m.names) }
+
+// Search merges results from every registry. A registry that fails is skipped
+// unless all of them fail.
+func (m *MultiSkillRegistry) Search(ctx context.Context, query string, limit int) ([]SkillSearchResult, error) {
+	var out []SkillSearchResult
+	var firstErr error
+	failed := 0
+	for _, name := range m.names {
+		results, err := m.registries[name].Search(ctx, query, limit)
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", name, err)
+			}
+			continue
+		}
+		out = append(out, results...)
+	}
+	if failed > 0 && failed == len(m.names) {
+		return nil, firstErr
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (m *MultiSkillRegistry) Install(ctx context.Context, req SkillInstallRequest) (SkillInstallResult, error) {
+	name, err := validateSkillIdentifier(req.RegistryName)
+	if err != nil {
+		return SkillInstallResult{}, fmt.Errorf("invalid registry: %w", err)
+	}
+	reg, ok := m.registries[name]
+	if !ok {
+		return SkillInstallResult{}, fmt.Errorf("unsupported registry: %s (available: %s)", name, strings.Join(m.names, ", "))
+	}
+	return reg.Install(ctx, req)
+}
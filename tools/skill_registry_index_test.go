@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexRegistry_LocalDirectory(t *testing.T) {
+	dir := t.TempDir()
+	v2 := mustZip(t, map[string]string{"weather/SKILL.md": "# Weather v2\n"})
+	v1 := mustZip(t, map[string]string{"SKILL.md": "# Weather v1\n"})
+	sum := sha256.Sum256(v2)
+	writeTestFile(t, filepath.Join(dir, "zips", "weather-2.zip"), v2)
+	writeTestFile(t, filepath.Join(dir, "zips", "weather-1.zip"), v1)
+	writeTestFile(t, filepath.Join(dir, "index.json"), []byte(`{"skills": [
+		{"slug": "weather", "displayName": "Weather", "summary": "Forecasts", "version": "2.0.0", "url": "zips/weather-2.zip", "sha256": "`+hex.EncodeToString(sum[:])+`"},
+		{"slug": "weather", "version": "1.0.0", "url": "zips/weather-1.zip"},
+		{"slug": "notes", "summary": "Take notes", "tags": ["journal"], "url": "zips/notes.zip"}
+	]}`))
+
+	reg := NewIndexRegistry(IndexRegistryConfig{Name: "home", URL: filepath.Join(dir, "index.json")})
+	results, err := reg.Search(context.Background(), "weather forecast", 5)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(results) != 1 || results[0].Slug != "weather" || results[0].Version != "2.0.0" || results[0].RegistryName != "home" {
+		t.Fatalf("results=%+v", results)
+	}
+	if results, _ := reg.Search(context.Background(), "journal", 5); len(results) != 1 || results[0].Slug != "notes" {
+		t.Fatalf("tag search=%+v", results)
+	}
+
+	ws := t.TempDir()
+	got, err := reg.Install(context.Background(), SkillInstallRequest{Slug: "weather", RegistryName: "home", WorkspaceDir: ws})
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if got.Version != "2.0.0" {
+		t.Fatalf("version=%q", got.Version)
+	}
+	b, err := os.ReadFile(filepath.Join(ws, "skills", "weather", "SKILL.md"))
+	if err != nil || !strings.Contains(string(b), "v2") {
+		t.Fatalf("SKILL.md=%q err=%v", b, err)
+	}
+
+	writeTestFile(t, filepath.Join(ws, "skills", "weather", "data", "cache.json"), []byte("{}"))
+	got, err = reg.Install(context.Background(), SkillInstallRequest{Slug: "weather", RegistryName: "home", Version: "1.0.0", Force: true, WorkspaceDir: ws})
+	if err != nil || got.Version != "1.0.0" {
+		t.Fatalf("install v1: %+v err=%v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "skills", "weather", "data", "cache.json")); err != nil {
+		t.Fatalf("skill data lost on reinstall: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(ws, "skills")); len(entries) != 1 {
+		t.Fatalf("stash left behind: %v", entries)
+	}
+	origin, err := os.ReadFile(filepath.Join(ws, "skills", "weather", ".skill-origin.json"))
+	if err != nil || !strings.Contains(string(origin), `"home"`) {
+		t.Fatalf("origin=%s err=%v", origin, err)
+	}
+}
+
+func TestIndexRegistry_HTTPChecksumMismatch(t *testing.T) {
+	archive := mustZip(t, map[string]string{"SKILL.md": "# Tampered\n"})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/skills/index.json":
+			_, _ = w.Write([]byte(`{"skills":[{"slug":"demo","version":"1","url":"demo.zip","sha256":"00"}]}`))
+		case "/skills/demo.zip":
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	reg := NewIndexRegistry(IndexRegistryConfig{Name: "mirror", URL: srv.URL + "/skills/index.json", AuthToken: "tok"})
+	ws := t.TempDir()
+	_, err := reg.Install(context.Background(), SkillInstallRequest{Slug: "demo", RegistryName: "mirror", WorkspaceDir: ws})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "skills", "demo")); !os.IsNotExist(err) {
+		t.Fatalf("failed install should be cleaned up, stat err=%v", err)
+	}
+}
+
+func TestMultiSkillRegistry_DispatchesByName(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.json"), []byte(`{"skills":[{"slug":"alpha","summary":"first tool","url":"x.zip"}]}`))
+	writeTestFile(t, filepath.Join(dir, "b.json"), []byte(`{"skills":[{"slug":"beta","summary":"second tool","url":"x.zip"}]}`))
+
+	m := NewMultiSkillRegistry()
+	if err := m.Add("a", NewIndexRegistry(IndexRegistryConfig{Name: "a", URL: "file://" + filepath.Join(dir, "a.json")})); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add("b", NewIndexRegistry(IndexRegistryConfig{Name: "b", URL: filepath.Join(dir, "b.json")})); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add("a", nil); err == nil {
+		t.Fatal("duplicate name should fail")
+	}
+	results, err := m.Search(context.Background(), "tool", 5)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("results=%+v err=%v", results, err)
+	}
+	if _, err := m.Install(context.Background(), SkillInstallRequest{Slug: "alpha", RegistryName: "c", WorkspaceDir: t.TempDir()}); err == nil || !strings.Contains(err.Error(), "available: a, b") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// skillDataEnv names the active skill's data directory for exec commands.
+const skillDataEnv = "CLAWLET_SKILL_DATA"
+
+// ActiveSkill records the skill whose instructions were read with
+// read_skill during a turn; the tool calls after it act for that skill. A
+// nil *ActiveSkill never has one.
+type ActiveSkill struct {
+	mu   sync.Mutex
+	name string
+}
+
+func (s *ActiveSkill) set(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+// Name is the active skill, or "".
+func (s *ActiveSkill) Name() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.name
+}
+
+// SkillDataDir is the directory a skill keeps its data in:
+// <workspace>/skills/<name>/data.
+func SkillDataDir(workspace, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid skill name: %q", name)
+	}
+	return filepath.Join(workspace, "skills", name, "data"), nil
+}
+
+// skillDataDir returns the active skill's data directory, creating it, or
+// "" when no skill is active.
+func (r *Registry) skillDataDir(tctx Context) (string, error) {
+	name := tctx.Skill.Name()
+	if name == "" {
+		return "", nil
+	}
+	ws, err := filepath.Abs(r.WorkspaceDir)
+	if err != nil {
+		return "", err
+	}
+	dir, err := SkillDataDir(ws, name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// checkSkillScope confines a file tool's path while a skill is active and
+// IsolateSkills is set: reads stay within the skill's directory, writes
+// within its data directory.
+func (r *Registry) checkSkillScope(tctx Context, path string, write bool) error {
+	if !r.IsolateSkills {
+		return nil
+	}
+	data, err := r.skillDataDir(tctx)
+	if err != nil || data == "" {
+		return err
+	}
+	abs, err := r.resolvePath(path)
+	if err != nil {
+		return err
+	}
+	root := data
+	if !write {
+		root = filepath.Dir(data)
+	}
+	if !isSameOrChildPath(resolveExisting(abs), resolveExisting(root)) {
+		return fmt.Errorf("skill %s may only access %s", tctx.Skill.Name(), r.workspaceRel(root))
+	}
+	return nil
+}
+
+// resolveExisting resolves the symlinks in the longest existing prefix of
+// path, so paths of files yet to be written compare like existing ones.
+func resolveExisting(path string) string {
+	rest := ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(path, rest)
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
+
+// workspaceRel shows path relative to the workspace when it is inside it.
+func (r *Registry) workspaceRel(path string) string {
+	ws, err := filepath.Abs(r.WorkspaceDir)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(ws, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// stashSkillData moves the data directory of the skill in skillDir next to
+// it, returning the stash to pass to restoreSkillData ("" when there is no
+// data).
+func stashSkillData(skillDir string) (string, error) {
+	data := filepath.Join(skillDir, "data")
+	if _, err := os.Stat(data); err != nil {
+		return "", nil
+	}
+	stash, err := os.MkdirTemp(filepath.Dir(skillDir), "."+filepath.Base(skillDir)+"-data-")
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(data, filepath.Join(stash, "data")); err != nil {
+		_ = os.RemoveAll(stash)
+		return "", err
+	}
+	return stash, nil
+}
+
+// restoreSkillData moves a stashed data directory back into skillDir,
+// replacing any data directory shipped with the skill.
+func restoreSkillData(stash, skillDir string) {
+	if stash == "" {
+		return
+	}
+	data := filepath.Join(skillDir, "data")
+	_ = os.RemoveAll(data)
+	if err := os.MkdirAll(skillDir, 0o755); err != nil {
+		return
+	}
+	if err := os.Rename(filepath.Join(stash, "data"), data); err == nil {
+		_ = os.RemoveAll(stash)
+	}
+}
@@ -0,0 +1,423 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TOML files are edited line by line rather than decoded and re-encoded, so
+// comments and layout stay as they are. Key/value pairs, [tables] and
+// dotted keys are covered; indexes into arrays and keys under [[arrays of
+// tables]] are refused, leaving those to edit_file.
+
+// tomlEntry is a key/value pair; its value runs from start on line to end
+// on endLine.
+type tomlEntry struct {
+	key        []string
+	line       int
+	start      int
+	endLine    int
+	end        int
+	underArray bool
+}
+
+type tomlTable struct {
+	key   []string
+	line  int
+	array bool
+	// last is the last line of the table's entries, or its header line.
+	last int
+}
+
+type tomlFile struct {
+	lines   []string
+	entries []tomlEntry
+	tables  []tomlTable
+	// rootLast is the last line of the entries before any table, or -1.
+	rootLast int
+}
+
+func parseTOML(src string) (*tomlFile, error) {
+	f := &tomlFile{lines: strings.Split(src, "\n"), rootLast: -1}
+	var table []string
+	current, underArray := -1, false
+	for i := 0; i < len(f.lines); i++ {
+		line := f.lines[i]
+		t := strings.TrimSpace(line)
+		if t == "" || t[0] == '#' {
+			continue
+		}
+		if t[0] == '[' {
+			array := strings.HasPrefix(t, "[[")
+			open, closing := "[", "]"
+			if array {
+				open, closing = "[[", "]]"
+			}
+			key, rest, err := parseTOMLKey(t[len(open):])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			rest = strings.TrimSpace(rest)
+			after := strings.TrimSpace(strings.TrimPrefix(rest, closing))
+			if !strings.HasPrefix(rest, closing) || (after != "" && after[0] != '#') {
+				return nil, fmt.Errorf("line %d: invalid table header", i+1)
+			}
+			f.tables = append(f.tables, tomlTable{key: key, line: i, array: array, last: i})
+			table, current, underArray = key, len(f.tables)-1, array
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		key, rest, err := parseTOMLKey(line[indent:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+		if !strings.HasPrefix(rest, "=") {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		start := len(line) - len(strings.TrimLeft(rest[1:], " \t"))
+		endLine, end, err := tomlValueEnd(f.lines, i, start)
+		if err != nil {
+			return nil, err
+		}
+		if endLine == i && end <= start {
+			return nil, fmt.Errorf("line %d: missing value", i+1)
+		}
+		f.entries = append(f.entries, tomlEntry{
+			key:        append(slices.Clone(table), key...),
+			line:       i,
+			start:      start,
+			endLine:    endLine,
+			end:        end,
+			underArray: underArray,
+		})
+		if current >= 0 {
+			f.tables[current].last = endLine
+		} else {
+			f.rootLast = endLine
+		}
+		i = endLine
+	}
+	return f, nil
+}
+
+// parseTOMLKey reads a dotted key at the start of s and returns its parts
+// and the text after it.
+func parseTOMLKey(s string) ([]string, string, error) {
+	var parts []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		switch {
+		case strings.HasPrefix(s, `"`):
+			end := 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, "", errors.New("unterminated quoted key")
+			}
+			part, err := strconv.Unquote(s[:end+1])
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid quoted key %s", s[:end+1])
+			}
+			parts, s = append(parts, part), s[end+1:]
+		case strings.HasPrefix(s, "'"):
+			end := strings.IndexByte(s[1:], '\'')
+			if end < 0 {
+				return nil, "", errors.New("unterminated quoted key")
+			}
+			parts, s = append(parts, s[1:end+1]), s[end+2:]
+		default:
+			end := 0
+			for end < len(s) && isBareKeyChar(s[end]) {
+				end++
+			}
+			if end == 0 {
+				return nil, "", errors.New("expected a key")
+			}
+			parts, s = append(parts, s[:end]), s[end:]
+		}
+		rest := strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(rest, ".") {
+			return parts, s, nil
+		}
+		s = rest[1:]
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// tomlValueEnd finds the end of the value starting at col on line li, which
+// may span lines when it is a multi-line string or array.
+func tomlValueEnd(lines []string, li, col int) (int, int, error) {
+	depth, quote := 0, ""
+	for ; li < len(lines); li, col = li+1, 0 {
+		s := lines[li]
+		for col < len(s) {
+			c := s[col]
+			switch {
+			case quote == `"` || quote == `"""`:
+				if c == '\\' {
+					col += 2
+				} else if strings.HasPrefix(s[col:], quote) {
+					col, quote = col+len(quote), ""
+				} else {
+					col++
+				}
+			case quote != "":
+				if strings.HasPrefix(s[col:], quote) {
+					col, quote = col+len(quote), ""
+				} else {
+					col++
+				}
+			case c == '#':
+				if depth == 0 {
+					return li, len(strings.TrimRight(s[:col], " \t")), nil
+				}
+				col = len(s)
+			case strings.HasPrefix(s[col:], `"""`), strings.HasPrefix(s[col:], `'''`):
+				quote, col = s[col:col+3], col+3
+			case c == '"' || c == '\'':
+				quote, col = string(c), col+1
+			case c == '[' || c == '{':
+				depth, col = depth+1, col+1
+			case c == ']' || c == '}':
+				depth, col = depth-1, col+1
+			default:
+				col++
+			}
+		}
+		if quote == `"` || quote == "'" {
+			return 0, 0, fmt.Errorf("line %d: unterminated string", li+1)
+		}
+		if depth <= 0 && quote == "" {
+			return li, len(strings.TrimRight(s, " \t\r")), nil
+		}
+	}
+	return 0, 0, errors.New("unterminated value at the end of the file")
+}
+
+func (f *tomlFile) value(e tomlEntry) string {
+	if e.line == e.endLine {
+		return f.lines[e.line][e.start:e.end]
+	}
+	parts := []string{f.lines[e.line][e.start:]}
+	parts = append(parts, f.lines[e.line+1:e.endLine]...)
+	return strings.Join(append(parts, f.lines[e.endLine][:e.end]), "\n")
+}
+
+func (f *tomlFile) entry(key []string) (tomlEntry, bool) {
+	for _, e := range f.entries {
+		if slices.Equal(e.key, key) {
+			return e, true
+		}
+	}
+	return tomlEntry{}, false
+}
+
+// check refuses keys that index into arrays or live under an array of
+// tables.
+func (f *tomlFile) check(steps []keyStep) ([]string, error) {
+	names := make([]string, 0, len(steps))
+	for _, st := range steps {
+		if st.isIndex {
+			return nil, errors.New("TOML keys can't index into arrays; get or set the whole array, or use edit_file")
+		}
+		names = append(names, st.name)
+	}
+	for _, t := range f.tables {
+		if t.array && hasKeyPrefix(names, t.key) {
+			return nil, fmt.Errorf("%s is under an array of tables ([[%s]]), which is not supported; use edit_file", keyString(steps), tomlKey(t.key))
+		}
+	}
+	return names, nil
+}
+
+func hasKeyPrefix(key, prefix []string) bool {
+	return len(key) >= len(prefix) && slices.Equal(key[:len(prefix)], prefix)
+}
+
+func tomlKey(key []string) string {
+	parts := make([]string, len(key))
+	for i, k := range key {
+		parts[i] = k
+		if k == "" || strings.IndexFunc(k, func(r rune) bool { return r > 0x7f || !isBareKeyChar(byte(r)) }) >= 0 {
+			parts[i] = jsonString(k)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func tomlGet(src string, steps []keyStep) (string, error) {
+	f, err := parseTOML(src)
+	if err != nil {
+		return "", err
+	}
+	names, err := f.check(steps)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return truncate(src, maxStructuredFile/2), nil
+	}
+	if e, ok := f.entry(names); ok {
+		return f.value(e), nil
+	}
+	// A table: list its pairs.
+	var out []string
+	for _, e := range f.entries {
+		if len(e.key) > len(names) && hasKeyPrefix(e.key, names) {
+			out = append(out, tomlKey(e.key[len(names):])+" = "+f.value(e))
+		}
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("%s not found", keyString(steps))
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+func tomlSet(src string, steps []keyStep, value string) (string, error) {
+	f, err := parseTOML(src)
+	if err != nil {
+		return "", err
+	}
+	names, err := f.check(steps)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", errors.New("set a key, not the whole document")
+	}
+	v, err := valueNode(value)
+	if err != nil {
+		return "", err
+	}
+	lit, err := tomlLiteral(v)
+	if err != nil {
+		return "", err
+	}
+	if e, ok := f.entry(names); ok {
+		line := f.lines[e.line][:e.start] + lit + f.lines[e.endLine][e.end:]
+		lines := append(slices.Clone(f.lines[:e.line]), line)
+		return strings.Join(append(lines, f.lines[e.endLine+1:]...), "\n"), nil
+	}
+	for _, e := range f.entries {
+		if len(e.key) < len(names) && hasKeyPrefix(names, e.key) {
+			return "", fmt.Errorf("%s is a value, not a table", tomlKey(e.key))
+		}
+		if hasKeyPrefix(e.key, names) {
+			return "", fmt.Errorf("%s is a table; set its keys one by one", keyString(steps))
+		}
+	}
+	for _, t := range f.tables {
+		if hasKeyPrefix(t.key, names) {
+			return "", fmt.Errorf("%s is a table; set its keys one by one", keyString(steps))
+		}
+	}
+
+	// A new key goes at the end of the longest table it belongs to, else
+	// among the keys before the first table.
+	var table *tomlTable
+	for i, t := range f.tables {
+		if hasKeyPrefix(names, t.key) && (table == nil || len(t.key) > len(table.key)) {
+			table = &f.tables[i]
+		}
+	}
+	rel, pos, blank := names, 0, false
+	switch {
+	case table != nil:
+		rel, pos = names[len(table.key):], table.last+1
+	case f.rootLast >= 0:
+		pos = f.rootLast + 1
+	case len(f.tables) > 0:
+		pos, blank = f.tables[0].line, true
+		for pos > 0 && strings.HasPrefix(strings.TrimSpace(f.lines[pos-1]), "#") {
+			pos--
+		}
+	default:
+		pos = len(f.lines)
+		if f.lines[pos-1] == "" {
+			pos--
+		}
+	}
+	indent := ""
+	if table != nil && table.last > table.line {
+		prev := f.lines[table.last]
+		indent = prev[:len(prev)-len(strings.TrimLeft(prev, " \t"))]
+	}
+	insert := []string{indent + tomlKey(rel) + " = " + lit}
+	if blank {
+		insert = append(insert, "")
+	}
+	lines := append(slices.Clone(f.lines[:pos]), insert...)
+	return strings.Join(append(lines, f.lines[pos:]...), "\n"), nil
+}
+
+func tomlDelete(src string, steps []keyStep) (string, error) {
+	f, err := parseTOML(src)
+	if err != nil {
+		return "", err
+	}
+	names, err := f.check(steps)
+	if err != nil {
+		return "", err
+	}
+	e, ok := f.entry(names)
+	if !ok {
+		for _, t := range f.tables {
+			if hasKeyPrefix(t.key, names) {
+				return "", fmt.Errorf("%s is a table; delete its keys one by one or use edit_file", keyString(steps))
+			}
+		}
+		return "", fmt.Errorf("%s not found", keyString(steps))
+	}
+	lines := slices.Clone(f.lines[:e.line])
+	return strings.Join(append(lines, f.lines[e.endLine+1:]...), "\n"), nil
+}
+
+// tomlLiteral writes a value parsed by valueNode in TOML syntax; objects
+// become inline tables.
+func tomlLiteral(n *yaml.Node) (string, error) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		if len(n.Content) == 0 {
+			return "{}", nil
+		}
+		parts := make([]string, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v, err := tomlLiteral(n.Content[i+1])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, tomlKey([]string{n.Content[i].Value})+" = "+v)
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	case yaml.SequenceNode:
+		parts := make([]string, 0, len(n.Content))
+		for _, c := range n.Content {
+			v, err := tomlLiteral(c)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, v)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	}
+	switch n.ShortTag() {
+	case "!!null":
+		return "", errors.New("TOML has no null; delete the key instead")
+	case "!!str":
+		return jsonString(n.Value), nil
+	}
+	return n.Value, nil
+}
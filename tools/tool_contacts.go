@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/contacts"
+)
+
+func (r *Registry) contactsAdd(name string, aliases []string, addresses map[string]string, notes string) (string, error) {
+	if r.Contacts == nil {
+		return "", errors.New("contacts not configured")
+	}
+	c, err := r.Contacts.Upsert(contacts.Contact{
+		Name:      name,
+		Aliases:   aliases,
+		Addresses: addresses,
+		Notes:     notes,
+	})
+	if err != nil {
+		return "", err
+	}
+	return jsonResult(map[string]any{"contact": c})
+}
+
+func (r *Registry) contactsSearch(query string, limit int) (string, error) {
+	if r.Contacts == nil {
+		return "", errors.New("contacts not configured")
+	}
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+	found, err := r.Contacts.Search(query, limit)
+	if err != nil {
+		return "", err
+	}
+	return jsonResult(map[string]any{"contacts": found})
+}
+
+// resolveMessageTarget maps a contact name (and optional channel) to a
+// channel/chat_id pair. Only exact name/alias matches are accepted.
+func (r *Registry) resolveMessageTarget(contact, channel string) (string, string, error) {
+	if r.Contacts == nil {
+		return "", "", errors.New("contacts not configured")
+	}
+	c, err := r.Contacts.Resolve(contact)
+	if err != nil {
+		return "", "", err
+	}
+	return c.Target(strings.TrimSpace(channel))
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/mosaxiv/clawlet/cron"
 )
 
-func (r *Registry) cronTool(ctx context.Context, tctx Context, action, message string, everySeconds int, cronExpr, jobID string) (string, error) {
+func (r *Registry) cronTool(ctx context.Context, tctx Context, action, message string, everySeconds int, cronExpr, jobID, group string, batch bool) (string, error) {
 	if r.Cron == nil {
 		return "", errors.New("cron service not configured")
 	}
@@ -37,6 +37,16 @@ func (r *Registry) cronTool(ctx context.Context, tctx Context, action, message s
 			Deliver: true,
 			Channel: tctx.Channel,
 			To:      tctx.ChatID,
+			Batch:   batch,
+		}
+		if group = strings.TrimSpace(group); group != "" {
+			if r.Recipients == nil {
+				return "", errors.New("recipient groups not configured")
+			}
+			if _, err := r.Recipients(group); err != nil {
+				return "", err
+			}
+			payload.Group = group
 		}
 		j, err := r.Cron.Add(shortName(message), sched, payload)
 		if err != nil {
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.starlark.net/lib/json"
+	"go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+const (
+	maxEvalCode   = 16 << 10
+	maxEvalOutput = 16 << 10
+	// evalMaxSteps bounds the work of a snippet, so a runaway loop fails
+	// quickly even when the machine is slow.
+	evalMaxSteps = 10_000_000
+	evalTimeout  = 5 * time.Second
+	// evalResult holds the value of a snippet's last expression.
+	evalResult = "__result__"
+)
+
+// evalOptions allow the Python habits the model writes: while loops, for
+// and if at the top level, and reassigning globals.
+var evalOptions = &syntax.FileOptions{
+	Set:             true,
+	While:           true,
+	TopLevelControl: true,
+	GlobalReassign:  true,
+}
+
+// evalModules and the scratch buffers are all a snippet can reach: there is
+// no load, file, network or clock access.
+var evalModules = starlark.StringDict{
+	"json": json.Module,
+	"math": math.Module,
+}
+
+// evalBuffers is a read-only dict of the scratch buffers.
+func evalBuffers(bufs *Buffers) *starlark.Dict {
+	d := starlark.NewDict(len(bufs.Names()))
+	for _, name := range bufs.Names() {
+		s, _ := bufs.Get(name)
+		_ = d.SetKey(starlark.String(name), starlark.String(s))
+	}
+	d.Freeze()
+	return d
+}
+
+// evalCode runs a Starlark snippet and returns what it printed and the value
+// of its last expression. The turn's scratch buffers are the buffers dict.
+func (r *Registry) evalCode(ctx context.Context, bufs *Buffers, code string) (string, error) {
+	if strings.TrimSpace(code) == "" {
+		return "", errors.New("code is empty")
+	}
+	if len(code) > maxEvalCode {
+		return "", fmt.Errorf("code is too long (max %d bytes)", maxEvalCode)
+	}
+	f, err := evalOptions.Parse("eval.star", code, 0)
+	if err != nil {
+		return "", err
+	}
+	if n := len(f.Stmts); n > 0 {
+		if last, ok := f.Stmts[n-1].(*syntax.ExprStmt); ok {
+			start, _ := last.Span()
+			f.Stmts[n-1] = &syntax.AssignStmt{
+				OpPos: start,
+				Op:    syntax.EQ,
+				LHS:   &syntax.Ident{NamePos: start, Name: evalResult},
+				RHS:   last.X,
+			}
+		}
+	}
+	predeclared := starlark.StringDict{"buffers": evalBuffers(bufs)}
+	for name, v := range evalModules {
+		predeclared[name] = v
+	}
+	for name, v := range evalBuiltins {
+		predeclared[name] = v
+	}
+	prog, err := starlark.FileProgram(f, predeclared.Has)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	thread := &starlark.Thread{
+		Name: "eval",
+		Print: func(_ *starlark.Thread, msg string) {
+			if out.Len() <= maxEvalOutput {
+				out.WriteString(msg)
+				out.WriteByte('\n')
+			}
+		},
+	}
+	thread.SetMaxExecutionSteps(evalMaxSteps)
+	ctx, cancel := context.WithTimeout(ctx, evalTimeout)
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() { thread.Cancel(ctx.Err().Error()) })
+	defer stop()
+
+	globals, err := prog.Init(thread, predeclared)
+	if err != nil {
+		if printed := out.String(); printed != "" {
+			return "", fmt.Errorf("%w\noutput before the error:\n%s", err, truncate(printed, maxEvalOutput))
+		}
+		return "", err
+	}
+	if v, ok := globals[evalResult]; ok && v != starlark.None {
+		if s, ok := starlark.AsString(v); ok {
+			out.WriteString(s)
+		} else {
+			out.WriteString(v.String())
+		}
+	}
+	if out.Len() == 0 {
+		return "(no output; end with an expression or print() the result)", nil
+	}
+	return truncate(strings.TrimRight(out.String(), "\n"), maxEvalOutput), nil
+}
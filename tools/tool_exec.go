@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -32,11 +33,21 @@ func applySafeExecEnv(cmd *exec.Cmd) {
 	}
 }
 
-func (r *Registry) exec(ctx context.Context, command string) (string, error) {
+func (r *Registry) exec(ctx context.Context, tctx Context, command string) (string, error) {
 	if strings.TrimSpace(command) == "" {
 		return "", errors.New("command is empty")
 	}
-	if msg := guardExecCommand(command, r.WorkspaceDir, r.RestrictToWorkspace); msg != "" {
+	data, err := r.skillDataDir(tctx)
+	if err != nil {
+		return "", err
+	}
+	dir, root, restrict := r.WorkspaceDir, r.WorkspaceDir, r.RestrictToWorkspace
+	if data != "" && r.IsolateSkills {
+		// Run in the skill's data directory; only the skill's own files
+		// (its scripts included) may be named.
+		dir, root, restrict = data, filepath.Dir(data), true
+	}
+	if msg := guardExecCommand(command, root, restrict); msg != "" {
 		return msg, nil
 	}
 	timeout := r.ExecTimeout
@@ -48,13 +59,16 @@ func (r *Registry) exec(ctx context.Context, command string) (string, error) {
 
 	// Use sh -lc for portability (pipes, redirects, etc.)
 	cmd := exec.CommandContext(cctx, "sh", "-lc", command)
-	cmd.Dir = r.WorkspaceDir
+	cmd.Dir = dir
 	applySafeExecEnv(cmd)
+	if data != "" {
+		cmd.Env = append(cmd.Env, skillDataEnv+"="+data)
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
-	err := cmd.Run()
+	err = cmd.Run()
 
 	out := truncate(stdout.String(), 64<<10)
 	serr := truncate(stderr.String(), 64<<10)
@@ -16,7 +16,7 @@ func TestExec_DoesNotLeakNonSafeEnvironmentVariables(t *testing.T) {
 		ExecTimeout:         5 * time.Second,
 	}
 
-	out, err := r.exec(context.Background(), "env")
+	out, err := r.exec(context.Background(), Context{}, "env")
 	if err != nil {
 		t.Fatalf("exec returned error: %v", err)
 	}
@@ -32,7 +32,7 @@ func TestExec_PreservesSafeEnvironmentVariables(t *testing.T) {
 		ExecTimeout:         5 * time.Second,
 	}
 
-	out, err := r.exec(context.Background(), "echo \"$PATH\"")
+	out, err := r.exec(context.Background(), Context{}, "echo \"$PATH\"")
 	if err != nil {
 		t.Fatalf("exec returned error: %v", err)
 	}
@@ -3,9 +3,12 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/mosaxiv/clawlet/memory"
+	"github.com/mosaxiv/clawlet/session"
 )
 
 func (r *Registry) memorySearch(ctx context.Context, query string, maxResults *int, minScore *float64) (string, error) {
@@ -72,3 +75,47 @@ func jsonResult(v any) (string, error) {
 	}
 	return string(b), nil
 }
+
+func (r *Registry) remember(tctx Context, title string, tags []string, summary string, last int, includeTranscript bool) (string, error) {
+	if r.Conversation == nil {
+		return "", errors.New("conversation snapshots not configured")
+	}
+	note := memory.Note{
+		Title:   title,
+		Tags:    tags,
+		Source:  tctx.SessionKey,
+		Summary: summary,
+	}
+	if includeTranscript {
+		note.Transcript = NoteTranscript(r.Conversation(tctx.SessionKey), last)
+	}
+	path, err := memory.New(r.WorkspaceDir).SaveNote(note, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return jsonResult(map[string]any{"path": path, "messages": len(note.Transcript)})
+}
+
+func (r *Registry) journal(section, entry string) (string, error) {
+	if r.Journal == nil {
+		return "", errors.New("journal not configured")
+	}
+	path, err := r.Journal.Append(time.Now(), section, entry)
+	if err != nil {
+		return "", err
+	}
+	return jsonResult(map[string]any{"path": path})
+}
+
+// NoteTranscript converts the last n session messages (all when n <= 0)
+// into note messages.
+func NoteTranscript(msgs []session.Message, n int) []memory.NoteMessage {
+	if n > 0 && len(msgs) > n {
+		msgs = msgs[len(msgs)-n:]
+	}
+	out := make([]memory.NoteMessage, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, memory.NoteMessage{Role: m.Role, Content: m.Content})
+	}
+	return out
+}
@@ -4,14 +4,66 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/mosaxiv/clawlet/broadcast"
 	"github.com/mosaxiv/clawlet/bus"
 )
 
-func (r *Registry) message(ctx context.Context, channel, chatID, content string) (string, error) {
+// maxMessageFileBytes matches the smallest upload limit of the chat
+// channels (Telegram bots: 50MB).
+const maxMessageFileBytes = 50 << 20
+
+// maxMessageOptions is the most quick replies every channel can show.
+const maxMessageOptions = 5
+
+// maxMessageCards is the most cards Discord shows with a message.
+const maxMessageCards = 10
+
+// messageCard is a card as the model writes it.
+type messageCard struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Fields      []struct {
+		Name   string `json:"name"`
+		Value  string `json:"value"`
+		Inline bool   `json:"inline"`
+	} `json:"fields"`
+	Footer string `json:"footer"`
+	Color  string `json:"color"` // "#RRGGBB"
+}
+
+func (c messageCard) card() (bus.Card, error) {
+	card := bus.Card{
+		Title:       strings.TrimSpace(c.Title),
+		URL:         strings.TrimSpace(c.URL),
+		Description: strings.TrimSpace(c.Description),
+		Footer:      strings.TrimSpace(c.Footer),
+	}
+	if card.Title == "" && card.Description == "" && len(c.Fields) == 0 {
+		return bus.Card{}, errors.New("card needs a title, description or fields")
+	}
+	for _, f := range c.Fields {
+		card.Fields = append(card.Fields, bus.CardField{Name: f.Name, Value: f.Value, Inline: f.Inline})
+	}
+	if color := strings.TrimPrefix(strings.TrimSpace(c.Color), "#"); color != "" {
+		v, err := strconv.ParseUint(color, 16, 32)
+		if err != nil || len(color) != 6 {
+			return bus.Card{}, fmt.Errorf("invalid card color %q (use #RRGGBB)", c.Color)
+		}
+		card.Color = int(v)
+	}
+	return card, nil
+}
+
+func (r *Registry) message(ctx context.Context, channel, chatID, content string, files, options []string, cards []messageCard) (string, error) {
 	content = strings.TrimSpace(content)
-	if content == "" {
+	if content == "" && (len(files) == 0 || len(options) > 0) && len(cards) == 0 {
 		return "", errors.New("content is empty")
 	}
 	if strings.TrimSpace(channel) == "" || strings.TrimSpace(chatID) == "" {
@@ -21,8 +73,106 @@ func (r *Registry) message(ctx context.Context, channel, chatID, content string)
 		return "", errors.New("message sending not configured")
 	}
 	msg := bus.OutboundMessage{Channel: channel, ChatID: chatID, Content: content}
+	for _, f := range files {
+		att, err := r.messageAttachment(f)
+		if err != nil {
+			return "", fmt.Errorf("file %s: %w", f, err)
+		}
+		msg.Attachments = append(msg.Attachments, att)
+	}
+	for _, o := range options {
+		if o = strings.TrimSpace(o); o != "" {
+			msg.Suggestions = append(msg.Suggestions, o)
+		}
+	}
+	if len(msg.Suggestions) > maxMessageOptions {
+		return "", fmt.Errorf("too many options (%d, max %d)", len(msg.Suggestions), maxMessageOptions)
+	}
+	if len(cards) > maxMessageCards {
+		return "", fmt.Errorf("too many cards (%d, max %d)", len(cards), maxMessageCards)
+	}
+	for i, c := range cards {
+		card, err := c.card()
+		if err != nil {
+			return "", fmt.Errorf("card %d: %w", i+1, err)
+		}
+		msg.Cards = append(msg.Cards, card)
+	}
 	if err := r.Outbound(ctx, msg); err != nil {
 		return "", err
 	}
+	if len(files) > 0 {
+		return fmt.Sprintf("Message with %d file(s) sent to %s:%s", len(files), channel, chatID), nil
+	}
 	return fmt.Sprintf("Message sent to %s:%s", channel, chatID), nil
 }
+
+// messageGroup sends content to each member of a recipient group, with
+// {{name}} filled in. Members an earlier run of the turn already reached
+// are skipped.
+func (r *Registry) messageGroup(ctx context.Context, tctx Context, group, content string, files, options []string, cards []messageCard) (string, error) {
+	if r.Recipients == nil {
+		return "", errors.New("recipient groups not configured")
+	}
+	members, err := r.Recipients(group)
+	if err != nil {
+		return "", err
+	}
+	var sent, skipped int
+	var failed []string
+	for _, m := range members {
+		key := tctx.Sends.next(m.Channel, m.ChatID)
+		if r.Sent.Sent(key) {
+			skipped++
+			continue
+		}
+		if _, err := r.message(ctx, m.Channel, m.ChatID, broadcast.Personalize(content, m), files, options, cards); err != nil {
+			failed = append(failed, fmt.Sprintf("%s:%s (%v)", m.Channel, m.ChatID, err))
+			continue
+		}
+		r.Sent.Record(key)
+		sent++
+	}
+	if sent == 0 && skipped == 0 && len(failed) > 0 {
+		return "", fmt.Errorf("not sent to group %s: %s", group, strings.Join(failed, "; "))
+	}
+	out := fmt.Sprintf("Message sent to %d of %d members of group %s", sent, len(members), group)
+	if skipped > 0 {
+		out += fmt.Sprintf("; %d already sent by an earlier run of this turn", skipped)
+	}
+	if len(failed) > 0 {
+		out += "; failed: " + strings.Join(failed, "; ")
+	}
+	return out, nil
+}
+
+func (r *Registry) messageAttachment(p string) (bus.Attachment, error) {
+	abs, err := r.resolvePath(strings.TrimSpace(p))
+	if err != nil {
+		return bus.Attachment{}, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return bus.Attachment{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return bus.Attachment{}, errors.New("not a regular file")
+	}
+	if info.Size() > maxMessageFileBytes {
+		return bus.Attachment{}, fmt.Errorf("file too large (%d bytes, max %d)", info.Size(), maxMessageFileBytes)
+	}
+	mimeType := mime.TypeByExtension(filepath.Ext(abs))
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return bus.Attachment{
+		Name:      filepath.Base(abs),
+		MIMEType:  mimeType,
+		Kind:      bus.InferAttachmentKind(mimeType),
+		SizeBytes: info.Size(),
+		LocalPath: abs,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// maxPollOptions is the most options Telegram and Discord polls take.
+const maxPollOptions = 10
+
+// createPoll posts a native poll in the current conversation. Votes come
+// back as notes in the conversation.
+func (r *Registry) createPoll(ctx context.Context, tctx Context, question string, options []string, multiSelect bool, durationHours int) (string, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return "", errors.New("question is empty")
+	}
+	opts := make([]string, 0, len(options))
+	for _, o := range options {
+		if o = strings.TrimSpace(o); o != "" {
+			opts = append(opts, o)
+		}
+	}
+	if len(opts) < 2 || len(opts) > maxPollOptions {
+		return "", fmt.Errorf("a poll needs 2 to %d options", maxPollOptions)
+	}
+	if durationHours < 0 {
+		return "", errors.New("duration_hours must not be negative")
+	}
+	if r.Outbound == nil {
+		return "", errors.New("message sending not configured")
+	}
+	channel, chatID := strings.TrimSpace(tctx.Channel), strings.TrimSpace(tctx.ChatID)
+	if channel == "" || chatID == "" || channel == "cli" {
+		return "", errors.New("no chat to post the poll in")
+	}
+	msg := bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Kind:    bus.MessageKindPoll,
+		Poll:    bus.Poll{Question: question, Options: opts, MultiSelect: multiSelect, DurationHours: durationHours},
+	}
+	if err := r.Outbound(ctx, msg); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Poll posted: %s (%d options). Votes will appear in the conversation as they come in.", question, len(opts)), nil
+}
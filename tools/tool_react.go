@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// react adds an emoji reaction to a message of the current conversation,
+// by default the one being answered.
+func (r *Registry) react(ctx context.Context, tctx Context, emoji, messageID string) (string, error) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" {
+		return "", errors.New("emoji is empty")
+	}
+	if r.Outbound == nil {
+		return "", errors.New("message sending not configured")
+	}
+	channel, chatID := strings.TrimSpace(tctx.Channel), strings.TrimSpace(tctx.ChatID)
+	if channel == "" || chatID == "" || channel == "cli" {
+		return "", errors.New("no chat to react in")
+	}
+	messageID = strings.TrimSpace(messageID)
+	if messageID == "" {
+		messageID = strings.TrimSpace(tctx.MessageID)
+	}
+	if messageID == "" {
+		return "", errors.New("message_id is required (the current message has no ID)")
+	}
+	msg := bus.OutboundMessage{
+		Channel:  channel,
+		ChatID:   chatID,
+		Kind:     bus.MessageKindReaction,
+		Reaction: bus.Reaction{Emoji: emoji, MessageID: messageID},
+	}
+	if err := r.Outbound(ctx, msg); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Reacted %s to message %s", emoji, messageID), nil
+}
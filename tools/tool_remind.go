@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/reminders"
+)
+
+// remindTool sets, lists and cancels the reminders of the current chat.
+func (r *Registry) remindTool(tctx Context, action, jobID string, req reminders.Request) (string, error) {
+	if r.Cron == nil {
+		return "", errors.New("cron service not configured")
+	}
+	channel, chatID := strings.TrimSpace(tctx.Channel), strings.TrimSpace(tctx.ChatID)
+	if channel == "" || chatID == "" {
+		return "", errors.New("no session context (channel/chat_id)")
+	}
+	loc := r.ReminderLocation
+	if loc == nil {
+		loc = time.Local
+	}
+	switch strings.TrimSpace(action) {
+	case "", "add":
+		now := time.Now()
+		rem, err := reminders.Parse(req, now, loc)
+		if err != nil {
+			return "", err
+		}
+		payload := cron.Payload{
+			Kind:    reminders.PayloadKind,
+			Message: rem.Text,
+			Deliver: true,
+			Channel: channel,
+			To:      chatID,
+		}
+		j, err := r.Cron.Add(shortName(rem.Text), rem.Schedule, payload)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Reminder set (id: %s). Confirm it to the user in their language: %s", j.ID, rem.Confirmation(now)), nil
+	case "list":
+		now := time.Now()
+		var b strings.Builder
+		for _, j := range r.chatReminders(channel, chatID) {
+			next := time.UnixMilli(j.State.NextRunAtMS).In(scheduleLocation(j.Schedule, loc))
+			fmt.Fprintf(&b, "- %s: %s %s (id: %s)\n", j.Payload.Message, reminders.Day(next, now), next.Format("15:04 MST"), j.ID)
+		}
+		if b.Len() == 0 {
+			return "No reminders in this chat.", nil
+		}
+		return "Reminders:\n" + strings.TrimRight(b.String(), "\n"), nil
+	case "cancel":
+		id := strings.TrimSpace(jobID)
+		if id == "" {
+			return "", errors.New("job_id is required")
+		}
+		for _, j := range r.chatReminders(channel, chatID) {
+			if j.ID == id && r.Cron.Remove(id) {
+				return "Cancelled reminder " + id, nil
+			}
+		}
+		return "Reminder not found: " + id, nil
+	default:
+		return "", fmt.Errorf("unknown action: %s", action)
+	}
+}
+
+// chatReminders lists the pending reminders that go to a chat.
+func (r *Registry) chatReminders(channel, chatID string) []cron.Job {
+	var out []cron.Job
+	for _, j := range r.Cron.List(false) {
+		if j.Payload.Kind == reminders.PayloadKind && j.Payload.Channel == channel && j.Payload.To == chatID && j.State.NextRunAtMS > 0 {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+func scheduleLocation(s cron.Schedule, fallback *time.Location) *time.Location {
+	if s.TZ != "" {
+		if loc, err := time.LoadLocation(s.TZ); err == nil {
+			return loc
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/render"
+)
+
+// maxRenderDataBytes bounds the CSV or JSON a render call reads from a file.
+const maxRenderDataBytes = 2 << 20
+
+// renderData draws CSV or JSON data as a chart or table image and sends it
+// to the current conversation.
+func (r *Registry) renderData(ctx context.Context, tctx Context, kind, data, path, format, title, caption string) (string, error) {
+	if r.Outbound == nil {
+		return "", errors.New("messaging not configured")
+	}
+	channel, chatID := strings.TrimSpace(tctx.Channel), strings.TrimSpace(tctx.ChatID)
+	if channel == "" || chatID == "" || channel == "cli" {
+		return "", errors.New("no chat to send the image to")
+	}
+	if strings.TrimSpace(path) != "" {
+		if strings.TrimSpace(data) != "" {
+			return "", errors.New("give data or path, not both")
+		}
+		abs, err := r.resolvePath(path)
+		if err != nil {
+			return "", err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return "", err
+		}
+		if info.Size() > maxRenderDataBytes {
+			return "", fmt.Errorf("file too large: %d bytes (max %d)", info.Size(), maxRenderDataBytes)
+		}
+		b, err := os.ReadFile(abs)
+		if err != nil {
+			return "", err
+		}
+		data = string(b)
+		if strings.TrimSpace(format) == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(abs)), ".")
+		}
+	}
+	t, err := render.Parse(data, format)
+	if err != nil {
+		return "", err
+	}
+	img, err := render.PNG(t, kind, title)
+	if err != nil {
+		return "", err
+	}
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	name := "chart.png"
+	if kind == render.KindTable || kind == "" {
+		name = "table.png"
+	}
+	msg := bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: strings.TrimSpace(caption),
+		Attachments: []bus.Attachment{{
+			Name:      name,
+			MIMEType:  "image/png",
+			Kind:      "image",
+			SizeBytes: int64(len(img)),
+			Data:      img,
+		}},
+	}
+	if err := r.Outbound(ctx, msg); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Sent %s (%d rows).", name, len(t.Rows)), nil
+}
@@ -6,7 +6,7 @@ import (
 	"strings"
 )
 
-func (r *Registry) readSkill(name string) (string, error) {
+func (r *Registry) readSkill(tctx Context, name string) (string, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return "", errors.New("name is empty")
@@ -14,8 +14,22 @@ func (r *Registry) readSkill(name string) (string, error) {
 	if r.ReadSkill == nil {
 		return "", errors.New("skills not configured")
 	}
-	if s, ok := r.ReadSkill(name); ok {
+	s, ok := r.ReadSkill(name)
+	if !ok {
+		return "", fmt.Errorf("skill not found: %s", name)
+	}
+	if tctx.Skill == nil {
 		return s, nil
 	}
-	return "", fmt.Errorf("skill not found: %s", name)
+	tctx.Skill.set(name)
+	data, err := r.skillDataDir(tctx)
+	if err != nil {
+		tctx.Skill.set("")
+		return "", err
+	}
+	note := fmt.Sprintf("\n\n[Skill data directory: %s (exec: $%s). Keep this skill's files there.", r.workspaceRel(data), skillDataEnv)
+	if r.IsolateSkills {
+		note += " Until the turn ends, files may only be written there and read within the skill's folder."
+	}
+	return s + note + "]", nil
 }
@@ -0,0 +1,532 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxStructuredFile bounds the files get_value and set_value parse.
+const maxStructuredFile = 1 << 20
+
+// Structured file formats, by extension.
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatTOML = "toml"
+)
+
+func structuredFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON, nil
+	case ".yaml", ".yml":
+		return formatYAML, nil
+	case ".toml":
+		return formatTOML, nil
+	}
+	return "", fmt.Errorf("unsupported file type %q (use .json, .yaml, .yml or .toml)", filepath.Ext(path))
+}
+
+// keyStep is one step of a key: a map key, or an index into a list.
+type keyStep struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// parseKey reads a key such as "server.ports[0]" or `a["b.c"]`. As in jq
+// the leading dot is optional, and "" or "." is the whole document.
+func parseKey(key string) ([]keyStep, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(key), ".")
+	var steps []keyStep
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid key %q: missing ]", key)
+			}
+			inner := strings.TrimSpace(s[i+1 : i+end])
+			if strings.HasPrefix(inner, `"`) {
+				// A quoted name may itself hold "]".
+				var name string
+				dec := json.NewDecoder(strings.NewReader(s[i+1:]))
+				if err := dec.Decode(&name); err != nil {
+					return nil, fmt.Errorf("invalid key %q: %v", key, err)
+				}
+				rest := strings.TrimLeft(s[i+1+int(dec.InputOffset()):], " ")
+				if !strings.HasPrefix(rest, "]") {
+					return nil, fmt.Errorf("invalid key %q: missing ]", key)
+				}
+				steps = append(steps, keyStep{name: name})
+				i = len(s) - len(rest) + 1
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid key %q: index %q is not a number", key, inner)
+				}
+				steps = append(steps, keyStep{index: n, isIndex: true})
+				i += end + 1
+			}
+		case s[i] == '.':
+			if i+1 >= len(s) || s[i+1] == '.' || s[i+1] == '[' {
+				return nil, fmt.Errorf("invalid key %q", key)
+			}
+			i++
+		default:
+			if i > 0 && s[i-1] != '.' {
+				return nil, fmt.Errorf("invalid key %q: missing . before %q", key, s[i:])
+			}
+			end := strings.IndexAny(s[i:], ".[]")
+			if end < 0 {
+				end = len(s) - i
+			}
+			if s[i:i+end] == "" || strings.HasPrefix(s[i+end:], "]") {
+				return nil, fmt.Errorf("invalid key %q", key)
+			}
+			steps = append(steps, keyStep{name: s[i : i+end]})
+			i += end
+		}
+	}
+	return steps, nil
+}
+
+func keyString(steps []keyStep) string {
+	var b strings.Builder
+	for _, st := range steps {
+		switch {
+		case st.isIndex:
+			fmt.Fprintf(&b, "[%d]", st.index)
+		case strings.ContainsAny(st.name, ".[]"):
+			b.WriteString("[" + jsonString(st.name) + "]")
+		default:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(st.name)
+		}
+	}
+	if b.Len() == 0 {
+		return "."
+	}
+	return b.String()
+}
+
+// parseValue reads a set_value value: JSON, or else a plain string.
+func parseValue(raw json.RawMessage) (string, error) {
+	var s string
+	if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte(`"`)) {
+		// A value given as JSON rather than as a string.
+		if !json.Valid(raw) {
+			return "", errors.New("value is not valid JSON")
+		}
+		return string(raw), nil
+	}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", err
+	}
+	if json.Valid([]byte(s)) {
+		return s, nil
+	}
+	return jsonString(s), nil
+}
+
+func jsonString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (r *Registry) readStructured(path string) (abs, format string, data []byte, err error) {
+	format, err = structuredFormat(path)
+	if err != nil {
+		return "", "", nil, err
+	}
+	abs, err = r.resolvePath(path)
+	if err != nil {
+		return "", "", nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if info.Size() > maxStructuredFile {
+		return "", "", nil, fmt.Errorf("file is too large (max %d bytes)", maxStructuredFile)
+	}
+	data, err = os.ReadFile(abs)
+	return abs, format, data, err
+}
+
+// getValue returns the value at key: as JSON for JSON and YAML files, as
+// written for TOML files.
+func (r *Registry) getValue(path, key string) (string, error) {
+	steps, err := parseKey(key)
+	if err != nil {
+		return "", err
+	}
+	_, format, data, err := r.readStructured(path)
+	if err != nil {
+		return "", err
+	}
+	if format == formatTOML {
+		return tomlGet(string(data), steps)
+	}
+	doc, err := decodeNode(data)
+	if err != nil {
+		return "", err
+	}
+	n, err := lookupNode(doc.Content[0], steps)
+	if err != nil {
+		return "", err
+	}
+	var compact, out bytes.Buffer
+	if err := writeNodeJSON(&compact, n); err != nil {
+		return "", err
+	}
+	if err := json.Indent(&out, compact.Bytes(), "", "  "); err != nil {
+		return "", err
+	}
+	return truncate(out.String(), maxStructuredFile/2), nil
+}
+
+// setValue sets key to value, a JSON value, or deletes key. Missing maps
+// on the way are created; a list index one past the end appends.
+func (r *Registry) setValue(tctx Context, path, key, value string, del bool) (string, error) {
+	steps, err := parseKey(key)
+	if err != nil {
+		return "", err
+	}
+	if del && len(steps) == 0 {
+		return "", errors.New("cannot delete the whole document")
+	}
+	abs, format, data, err := r.readStructured(path)
+	if err != nil {
+		return "", err
+	}
+	var updated string
+	if format == formatTOML {
+		if del {
+			updated, err = tomlDelete(string(data), steps)
+		} else {
+			updated, err = tomlSet(string(data), steps, value)
+		}
+		if err != nil {
+			return "", err
+		}
+	} else {
+		doc, err := decodeNode(data)
+		if err != nil {
+			return "", err
+		}
+		if del {
+			err = deleteNode(doc.Content[0], steps)
+		} else {
+			var v *yaml.Node
+			if v, err = valueNode(value); err == nil {
+				err = setNode(doc, steps, v)
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+		if format == formatJSON {
+			updated, err = encodeJSON(doc, string(data))
+		} else {
+			updated, err = encodeYAML(doc, string(data))
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := os.WriteFile(abs, []byte(updated), 0o644); err != nil {
+		return "", err
+	}
+	verb := "set"
+	if del {
+		verb = "deleted"
+	}
+	return r.withDiff(tctx, fmt.Sprintf("%s %s in %s", verb, keyString(steps), abs), abs, string(data), updated), nil
+}
+
+// decodeNode parses JSON or YAML, which yaml.v3 both reads, into a
+// document node that keeps key order and comments. An empty file is an
+// empty map.
+func decodeNode(data []byte) (*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}, nil
+		}
+		return nil, err
+	}
+	var next yaml.Node
+	switch err := dec.Decode(&next); {
+	case err == nil:
+		return nil, errors.New("files with several YAML documents are not supported; use edit_file")
+	case !errors.Is(err, io.EOF):
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// childIndex returns the position of step in n: the value's index in a
+// map's Content, or the list index; -1 when it is missing.
+func childIndex(n *yaml.Node, st keyStep, steps []keyStep) (int, error) {
+	if st.isIndex {
+		if n.Kind != yaml.SequenceNode {
+			return 0, fmt.Errorf("%s is not a list", keyString(steps))
+		}
+		i := st.index
+		if i < 0 {
+			i += len(n.Content)
+		}
+		if i < 0 || i > len(n.Content) {
+			return 0, fmt.Errorf("index %d is out of range (the list at %s has %d items)", st.index, keyString(steps), len(n.Content))
+		}
+		if i == len(n.Content) {
+			return -1, nil
+		}
+		return i, nil
+	}
+	if n.Kind != yaml.MappingNode {
+		return 0, fmt.Errorf("%s is not a map", keyString(steps))
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == st.name {
+			return i + 1, nil
+		}
+	}
+	return -1, nil
+}
+
+func lookupNode(n *yaml.Node, steps []keyStep) (*yaml.Node, error) {
+	for i, st := range steps {
+		n = resolveAlias(n)
+		at, err := childIndex(n, st, steps[:i])
+		if err != nil {
+			return nil, err
+		}
+		if at < 0 {
+			return nil, fmt.Errorf("%s not found", keyString(steps[:i+1]))
+		}
+		n = n.Content[at]
+	}
+	return resolveAlias(n), nil
+}
+
+func setNode(doc *yaml.Node, steps []keyStep, v *yaml.Node) error {
+	if len(steps) == 0 {
+		replaceNode(doc.Content[0], v)
+		return nil
+	}
+	n := doc.Content[0]
+	for i, st := range steps {
+		n = resolveAlias(n)
+		at, err := childIndex(n, st, steps[:i])
+		if err != nil {
+			return err
+		}
+		if at < 0 {
+			next := v
+			if i < len(steps)-1 {
+				next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				if steps[i+1].isIndex {
+					next = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+				}
+			}
+			if !st.isIndex {
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: st.name})
+			}
+			n.Content = append(n.Content, next)
+			n = next
+			continue
+		}
+		if i == len(steps)-1 {
+			replaceNode(n.Content[at], v)
+			return nil
+		}
+		n = n.Content[at]
+	}
+	return nil
+}
+
+// replaceNode puts v in place of old, keeping old's comments.
+func replaceNode(old, v *yaml.Node) {
+	head, line, foot := old.HeadComment, old.LineComment, old.FootComment
+	*old = *v
+	old.HeadComment, old.LineComment, old.FootComment = head, line, foot
+}
+
+func deleteNode(n *yaml.Node, steps []keyStep) error {
+	last := len(steps) - 1
+	parent, err := lookupNode(n, steps[:last])
+	if err != nil {
+		return err
+	}
+	at, err := childIndex(parent, steps[last], steps[:last])
+	if err != nil {
+		return err
+	}
+	if at < 0 {
+		return fmt.Errorf("%s not found", keyString(steps))
+	}
+	if steps[last].isIndex {
+		parent.Content = append(parent.Content[:at], parent.Content[at+1:]...)
+	} else {
+		parent.Content = append(parent.Content[:at-1], parent.Content[at+1:]...)
+	}
+	return nil
+}
+
+// valueNode parses a JSON value into block-style nodes, so that it reads
+// like the rest of a YAML file.
+func valueNode(value string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil || len(doc.Content) == 0 {
+		return nil, errors.New("value is not valid JSON")
+	}
+	var plain func(*yaml.Node)
+	plain = func(n *yaml.Node) {
+		n.Style = 0
+		for _, c := range n.Content {
+			plain(c)
+		}
+	}
+	plain(doc.Content[0])
+	return doc.Content[0], nil
+}
+
+// writeNodeJSON writes n as compact JSON, keeping key order and the text of
+// numbers.
+func writeNodeJSON(b *bytes.Buffer, n *yaml.Node) error {
+	n = resolveAlias(n)
+	switch n.Kind {
+	case yaml.DocumentNode:
+		return writeNodeJSON(b, n.Content[0])
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(jsonString(n.Content[i].Value) + ":")
+			if err := writeNodeJSON(b, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, c := range n.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeNodeJSON(b, c); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!str", "!!timestamp", "!!binary":
+			b.WriteString(jsonString(n.Value))
+		case "!!null":
+			b.WriteString("null")
+		default:
+			if json.Valid([]byte(n.Value)) {
+				b.WriteString(n.Value)
+				return nil
+			}
+			// YAML-only forms such as 0x1f.
+			var v any
+			if err := n.Decode(&v); err != nil {
+				return err
+			}
+			out, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("%q has no JSON form: %w", n.Value, err)
+			}
+			b.Write(out)
+		}
+	default:
+		return fmt.Errorf("unsupported YAML node at line %d", n.Line)
+	}
+	return nil
+}
+
+// encodeJSON writes doc with the indentation of the original file.
+func encodeJSON(doc *yaml.Node, original string) (string, error) {
+	var compact bytes.Buffer
+	if err := writeNodeJSON(&compact, doc); err != nil {
+		return "", err
+	}
+	body := strings.TrimSpace(original)
+	if body != "" && !strings.Contains(body, "\n") {
+		return compact.String() + trailingNewline(original), nil
+	}
+	indent := "  "
+	for _, line := range strings.Split(body, "\n")[1:] {
+		if t := strings.TrimLeft(line, " \t"); t != "" && len(t) < len(line) {
+			indent = line[:len(line)-len(t)]
+			break
+		}
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), "", indent); err != nil {
+		return "", err
+	}
+	if original == "" {
+		return out.String() + "\n", nil
+	}
+	return out.String() + trailingNewline(original), nil
+}
+
+func trailingNewline(s string) string {
+	if strings.HasSuffix(s, "\n") {
+		return "\n"
+	}
+	return ""
+}
+
+// encodeYAML writes doc with the indentation of the original file. Comments
+// survive, but yaml.v3 indents lists under their key and may requote
+// strings.
+func encodeYAML(doc *yaml.Node, original string) (string, error) {
+	indent := 2
+	for _, line := range strings.Split(original, "\n") {
+		t := strings.TrimLeft(line, " ")
+		if n := len(line) - len(t); n > 0 && t != "" && !strings.HasPrefix(t, "#") && !strings.HasPrefix(t, "- ") {
+			indent = n
+			break
+		}
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(indent)
+	if err := enc.Encode(doc); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
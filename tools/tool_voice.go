@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+// sendVoice reads text aloud and sends it to the current conversation as
+// a voice note.
+func (r *Registry) sendVoice(ctx context.Context, tctx Context, text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", errors.New("text is empty")
+	}
+	if r.Speak == nil || r.Outbound == nil {
+		return "", errors.New("voice notes not configured")
+	}
+	channel, chatID := strings.TrimSpace(tctx.Channel), strings.TrimSpace(tctx.ChatID)
+	if channel == "" || chatID == "" || channel == "cli" {
+		return "", errors.New("no chat to send the voice note to")
+	}
+	voice, err := r.Speak(ctx, text)
+	if err != nil {
+		return "", err
+	}
+	msg := bus.OutboundMessage{
+		Channel:     channel,
+		ChatID:      chatID,
+		Attachments: []bus.Attachment{voice},
+	}
+	if err := r.Outbound(ctx, msg); err != nil {
+		return "", err
+	}
+	return "Voice note sent.", nil
+}
@@ -12,7 +12,7 @@ import (
 	"github.com/hashicorp/go-retryablehttp"
 )
 
-func (r *Registry) webSearch(ctx context.Context, query string, count int) (string, error) {
+func (r *Registry) webSearch(ctx context.Context, query string, count int, srcs *Sources) (string, error) {
 	if strings.TrimSpace(r.BraveAPIKey) == "" {
 		return "", errors.New("braveApiKey not configured (config.tools.web.braveApiKey)")
 	}
@@ -44,5 +44,10 @@ func (r *Registry) webSearch(ctx context.Context, query string, count int) (stri
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return "", fmt.Errorf("brave http %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
 	}
+	if results, err := parseBraveResults(count, b); err == nil {
+		for _, it := range results {
+			srcs.Add(it.Title, it.URL)
+		}
+	}
 	return formatBraveSearchResults(query, count, b), nil
 }
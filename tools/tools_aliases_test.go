@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/contacts"
+	"github.com/mosaxiv/clawlet/cron"
+)
+
+func TestToolNames_CoverAllDefinitions(t *testing.T) {
+	r := &Registry{
+		WorkspaceDir:  "/tmp",
+		BraveAPIKey:   "k",
+		Outbound:      func(context.Context, bus.OutboundMessage) error { return nil },
+		Spawn:         func(context.Context, string, string, string, string) (string, error) { return "", nil },
+		Cron:          cron.NewService(filepath.Join(t.TempDir(), "cron.json"), nil),
+		ReadSkill:     func(string) (string, bool) { return "", false },
+		SkillRegistry: stubSkillRegistry{},
+		MemorySearch:  stubMemoryManager{},
+		Contacts:      contacts.NewStore(filepath.Join(t.TempDir(), "contacts.json")),
+	}
+	for _, d := range r.Definitions() {
+		if !slices.Contains(ToolNames, d.Function.Name) {
+			t.Fatalf("ToolNames is missing %s", d.Function.Name)
+		}
+	}
+}
+
+func TestRegistryDefinitions_AliasesAndRenames(t *testing.T) {
+	r := &Registry{
+		WorkspaceDir: "/tmp",
+		Aliases:      map[string]string{"fetch_url": "web_fetch", "search": "web_search"},
+		Renames:      map[string]string{"list_dir": "ls"},
+	}
+	var names []string
+	for _, d := range r.Definitions() {
+		names = append(names, d.Function.Name)
+	}
+	for _, n := range []string{"web_fetch", "fetch_url", "ls"} {
+		if !slices.Contains(names, n) {
+			t.Fatalf("expected %s in %v", n, names)
+		}
+	}
+	for _, n := range []string{"list_dir", "search"} {
+		if slices.Contains(names, n) {
+			t.Fatalf("did not expect %s in %v", n, names)
+		}
+	}
+}
+
+func TestExecute_ResolvesAliasesRenamesAndShims(t *testing.T) {
+	ws := t.TempDir()
+	if err := os.WriteFile(filepath.Join(ws, "a.txt"), []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := &Registry{
+		WorkspaceDir:        ws,
+		RestrictToWorkspace: true,
+		ExecTimeout:         time.Second,
+		Aliases:             map[string]string{"cat": "read_file"},
+		Renames:             map[string]string{"list_dir": "ls"},
+	}
+	ctx := context.Background()
+	tctx := Context{Channel: "cli", ChatID: "direct"}
+
+	out, err := r.Execute(ctx, tctx, "cat", json.RawMessage(`{"path":"a.txt"}`))
+	if err != nil || !strings.Contains(out, "hello") {
+		t.Fatalf("alias: out=%q err=%v", out, err)
+	}
+	out, err = r.Execute(ctx, tctx, "ls", json.RawMessage(`{"path":"."}`))
+	if err != nil || !strings.Contains(out, "a.txt") {
+		t.Fatalf("rename: out=%q err=%v", out, err)
+	}
+	out, err = r.Execute(ctx, tctx, "read", json.RawMessage(`{"file_path":"a.txt"}`))
+	if err != nil || !strings.Contains(out, "hello") {
+		t.Fatalf("shim: out=%q err=%v", out, err)
+	}
+	if _, err := r.Execute(ctx, tctx, "edit", json.RawMessage(`{"file_path":"a.txt","oldText":"hello","newText":"bye"}`)); err != nil {
+		t.Fatalf("edit shim: %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(ws, "a.txt")); string(b) != "bye" {
+		t.Fatalf("edit shim did not apply: %q", b)
+	}
+}
+
+func TestExecute_AliasRespectsAllowTools(t *testing.T) {
+	r := &Registry{
+		WorkspaceDir: t.TempDir(),
+		AllowTools:   []string{"read_file"},
+		Aliases:      map[string]string{"run": "exec"},
+	}
+	if _, err := r.Execute(context.Background(), Context{}, "run", json.RawMessage(`{"command":"true"}`)); err == nil || !strings.Contains(err.Error(), "tool disabled: exec") {
+		t.Fatalf("expected disabled error, got %v", err)
+	}
+	if _, err := r.Execute(context.Background(), Context{}, "bash", json.RawMessage(`{"command":"true"}`)); err == nil {
+		t.Fatalf("expected shim to respect allowlist")
+	}
+}
+
+func TestValidateToolNames(t *testing.T) {
+	if err := ValidateToolNames(map[string]string{"fetch_url": "web_fetch"}, map[string]string{"list_dir": "ls"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cases := []struct {
+		aliases, renames map[string]string
+		want             string
+	}{
+		{aliases: map[string]string{"x": "nope"}, want: "unknown tool"},
+		{aliases: map[string]string{"exec": "read_file"}, want: "shadows"},
+		{aliases: map[string]string{"bad name": "exec"}, want: "invalid tool name"},
+		{aliases: map[string]string{"ls": "exec"}, renames: map[string]string{"list_dir": "ls"}, want: "already used"},
+	}
+	for _, tc := range cases {
+		err := ValidateToolNames(tc.aliases, tc.renames)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("aliases=%v renames=%v: expected %q, got %v", tc.aliases, tc.renames, tc.want, err)
+		}
+	}
+}
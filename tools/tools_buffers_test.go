@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuffers_PassDataBetweenTools(t *testing.T) {
+	ws := t.TempDir()
+	if err := os.WriteFile(filepath.Join(ws, "prices.json"), []byte(`[12.5, 40, 7.25]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := &Registry{WorkspaceDir: ws, RestrictToWorkspace: true}
+	tctx := Context{Buffers: &Buffers{}}
+	ctx := context.Background()
+	run := func(name string, args map[string]any) string {
+		t.Helper()
+		raw, _ := json.Marshal(args)
+		out, err := r.Execute(ctx, tctx, name, raw)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return out
+	}
+
+	out := run("read_file", map[string]any{"path": "prices.json", "save_to_buffer": "prices"})
+	if !strings.HasPrefix(out, "saved 16 bytes to buffer prices") {
+		t.Fatalf("read_file = %q", out)
+	}
+	run("eval", map[string]any{
+		"code":           `json.encode([p * 2 for p in json.decode(buffers["prices"])])`,
+		"save_to_buffer": "doubled",
+	})
+	run("write_file", map[string]any{"path": "out.json", "content": "{{buffer:doubled}}\n"})
+	got, _ := os.ReadFile(filepath.Join(ws, "out.json"))
+	if string(got) != "[25,80,14.5]\n" {
+		t.Fatalf("out.json = %q", got)
+	}
+
+	run("set_buffer", map[string]any{"name": "log", "content": "a"})
+	run("set_buffer", map[string]any{"name": "log", "content": "b", "append": true})
+	if out := run("get_buffer", map[string]any{"name": "log"}); out != "ab" {
+		t.Fatalf("get_buffer = %q", out)
+	}
+	if out := run("get_buffer", map[string]any{}); out != "doubled: 12 bytes\nlog: 2 bytes\nprices: 16 bytes" {
+		t.Fatalf("buffer list = %q", out)
+	}
+
+	raw, _ := json.Marshal(map[string]any{"path": "x.txt", "content": "{{buffer:nope}}"})
+	if _, err := r.Execute(ctx, tctx, "write_file", raw); err == nil || !strings.Contains(err.Error(), `"nope" is not set`) {
+		t.Fatalf("missing buffer: %v", err)
+	}
+	// Without buffers, references are left alone.
+	if _, err := r.Execute(ctx, Context{}, "write_file", raw); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(ws, "x.txt")); string(got) != "{{buffer:nope}}" {
+		t.Fatalf("x.txt = %q", got)
+	}
+}
+
+func TestBuffers_Limits(t *testing.T) {
+	b := &Buffers{}
+	if err := b.Set("bad name", "x"); err == nil {
+		t.Fatal("accepted a name with a space")
+	}
+	if err := b.Set("big", strings.Repeat("x", maxBufferBytes+1)); err == nil {
+		t.Fatal("accepted an oversized buffer")
+	}
+	for i := range maxBuffers {
+		if err := b.Set(string(rune('a'+i)), "x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Set("more", "x"); err == nil {
+		t.Fatal("accepted one buffer too many")
+	}
+	if err := b.Set("a", "replaced"); err != nil {
+		t.Fatalf("replacing a buffer: %v", err)
+	}
+
+	long := strings.Repeat("é", bufferChunk)
+	_ = b.Set("a", long)
+	out, err := getBuffer(b, "a", 0)
+	if err != nil || !strings.HasSuffix(out, "continue with offset=16384)") {
+		t.Fatalf("first chunk ends %q, %v", out[max(0, len(out)-60):], err)
+	}
+	if out, err := getBuffer(b, "a", bufferChunk); err != nil || len(out) != len(long)-bufferChunk {
+		t.Fatalf("second chunk: %d bytes, %v", len(out), err)
+	}
+	if _, saveTo, err := (*Buffers)(nil).prepare(json.RawMessage(`{"save_to_buffer":"x"}`)); saveTo != "" || err != nil {
+		t.Fatalf("nil buffers: %q, %v", saveTo, err)
+	}
+}
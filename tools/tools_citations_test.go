@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSources_RecordAndFormat(t *testing.T) {
+	s := &Sources{}
+	s.record("web_fetch", `{"url":"http://a.example","finalUrl":"https://a.example/","status":200,"text":"x"}`)
+	s.record("web_fetch", `{"url":"https://down.example","status":0,"error":"timeout"}`)
+	s.record("memory_search", `{"results":[{"path":"memory/MEMORY.md","startLine":1,"endLine":3},{"path":"memory/MEMORY.md","startLine":9,"endLine":9}]}`)
+	s.record("memory_get", `{"path":"memory/notes/x.md","text":"..."}`)
+	s.Add("Go docs", "https://go.dev/doc")
+	s.Add("dup", "https://a.example/")
+	s.record("read_file", `{"path":"secret"}`)
+
+	got := s.List()
+	if len(got) != 4 {
+		t.Fatalf("sources=%+v", got)
+	}
+	footer := FormatSources("See https://go.dev/doc for details.", got, 2)
+	want := "Sources:\n1. https://a.example/\n2. memory/MEMORY.md"
+	if footer != want {
+		t.Fatalf("footer=%q", footer)
+	}
+	if f := FormatSources("x", []Source{{Title: "Go docs", Ref: "https://go.dev/doc"}}, 5); !strings.Contains(f, "Go docs — https://go.dev/doc") {
+		t.Fatalf("title: %q", f)
+	}
+	if FormatSources("x", nil, 5) != "" {
+		t.Fatal("empty sources should add nothing")
+	}
+	var nilSources *Sources
+	nilSources.record("memory_get", `{"path":"a"}`)
+	if nilSources.List() != nil {
+		t.Fatal("nil Sources should ignore records")
+	}
+}
@@ -69,7 +69,7 @@ func TestRegistryDefinitions_GatedByCapabilities(t *testing.T) {
 	}
 
 	// Capability-gated.
-	for _, n := range []string{"web_search", "message", "spawn", "cron", "read_skill", "find_skills", "install_skill", "memory_search", "memory_get"} {
+	for _, n := range []string{"web_search", "message", "react", "create_poll", "render", "send_voice", "spawn", "cron", "remind", "read_skill", "find_skills", "install_skill", "memory_search", "memory_get"} {
 		if has[n] {
 			t.Fatalf("did not expect tool definition: %s", n)
 		}
@@ -119,3 +119,21 @@ func TestRegistryDefinitions_IncludesSkillRegistryToolsWhenEnabled(t *testing.T)
 		}
 	}
 }
+
+func TestRegistryDefinitionsFor_LeavesOutDeniedTools(t *testing.T) {
+	r := &Registry{WorkspaceDir: t.TempDir(), ExecTimeout: time.Second}
+	denied := map[string]bool{"exec": true}
+	for _, d := range r.DefinitionsFor(denied) {
+		if d.Function.Name == "exec" {
+			t.Fatal("denied exec is still advertised")
+		}
+	}
+	args, _ := json.Marshal(map[string]string{"command": "echo hi"})
+	_, err := r.Execute(context.Background(), Context{Denied: denied}, "exec", args)
+	if err == nil || ClassifyFailure("exec", err).Kind != FailureBlocked {
+		t.Fatalf("denied exec ran: %v", err)
+	}
+	if out, err := r.Execute(context.Background(), Context{}, "exec", args); err != nil || out == "" {
+		t.Fatalf("exec without a role: %q, %v", out, err)
+	}
+}
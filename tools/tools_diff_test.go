@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	after := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\n"
+	got := unifiedDiff("x.txt", before, after, 100)
+	want := strings.Join([]string{
+		"--- x.txt",
+		"+++ x.txt",
+		"@@ -1,10 +1,11 @@",
+		" a", " b", " c", "-d", "+D", " e", " f", " g", " h", " i", " j", "+k",
+	}, "\n")
+	if got != want {
+		t.Fatalf("diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	far := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	got = unifiedDiff("y", far, strings.Replace(strings.Replace(far, "2\n", "two\n", 1), "11\n", "eleven\n", 1), 100)
+	if strings.Count(got, "@@ -") != 2 || !strings.Contains(got, "@@ -8,5 +8,5 @@") {
+		t.Fatalf("expected two hunks:\n%s", got)
+	}
+
+	if got := unifiedDiff("new", "", "x\ny\n", 100); !strings.Contains(got, "@@ -0,0 +1,2 @@\n+x\n+y") {
+		t.Fatalf("new file:\n%s", got)
+	}
+	if got := unifiedDiff("big", "", strings.Repeat("x\n", 50), 5); !strings.HasSuffix(got, "+x\n... (46 more lines)") {
+		t.Fatalf("capped:\n%s", got)
+	}
+	if unifiedDiff("same", "a\n", "a\n", 10) != "" {
+		t.Fatal("equal contents should have no diff")
+	}
+}
+
+func TestEditFile_ReturnsDiff(t *testing.T) {
+	ws := t.TempDir()
+	r := &Registry{WorkspaceDir: ws, RestrictToWorkspace: true, DiffMaxLines: 20}
+	edits := &Edits{}
+	tctx := Context{Edits: edits}
+
+	args, _ := json.Marshal(map[string]string{"path": "notes.md", "content": "one\ntwo\n"})
+	out, err := r.Execute(context.Background(), tctx, "write_file", args)
+	if err != nil || !strings.Contains(out, "+one\n+two") {
+		t.Fatalf("write_file out=%q err=%v", out, err)
+	}
+	args, _ = json.Marshal(map[string]string{"path": "notes.md", "old_text": "two", "new_text": "2"})
+	out, err = r.Execute(context.Background(), tctx, "edit_file", args)
+	if err != nil || !strings.Contains(out, "-two\n+2") {
+		t.Fatalf("edit_file out=%q err=%v", out, err)
+	}
+	if got := edits.List(); len(got) != 2 {
+		t.Fatalf("edits=%q", got)
+	}
+
+	r.DiffMaxLines = 0
+	args, _ = json.Marshal(map[string]string{"path": "notes.md", "content": "three\n"})
+	out, err = r.Execute(context.Background(), tctx, "write_file", args)
+	if err != nil || strings.Contains(out, "@@") {
+		t.Fatalf("diffs off: out=%q err=%v", out, err)
+	}
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestEvalCode(t *testing.T) {
+	r := &Registry{}
+	ctx := context.Background()
+	for name, tc := range map[string]struct{ code, want string }{
+		"expression":  {code: "[x * 2 for x in range(4)]", want: "[0, 2, 4, 6]"},
+		"string":      {code: `"%d items" % len("abc")`, want: "3 items"},
+		"statements":  {code: "total = 0\nfor x in [1, 2, 3]:\n    total += x\nprint('total', total)\ntotal * 10", want: "total 6\n60"},
+		"json":        {code: `json.encode(sorted(json.decode('{"b": 1, "a": 2}').keys()))`, want: `["a","b"]`},
+		"math":        {code: "math.sqrt(16)", want: "4.0"},
+		"print only":  {code: "print('hi')", want: "hi"},
+		"no output":   {code: "x = 1", want: "(no output; end with an expression or print() the result)"},
+		"while loops": {code: "n = 0\nwhile n < 3:\n    n += 1\nn", want: "3"},
+		"in place":    {code: "a = [1]\nb = a\nb += [2]\nd = {'k': a}\nd['k'] += [3]\na", want: "[1, 2, 3]"},
+		"big numbers": {code: "2 * 3000000 * 10", want: "60000000"},
+		"repeat":      {code: "len('ab' * 1000 + ', '.join(['x'] * 1000))", want: "4998"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := r.evalCode(ctx, nil, tc.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	for name, tc := range map[string]struct{ code, want string }{
+		"empty":       {code: " ", want: "code is empty"},
+		"syntax":      {code: "1 +", want: "got end of file"},
+		"no load":     {code: `load("os.star", "system")`, want: "load"},
+		"no builtins": {code: "open('/etc/passwd')", want: "undefined: open"},
+		"runaway":     {code: "while True:\n    pass", want: "too many steps"},
+		"runtime":     {code: "print('before')\n1 // 0", want: "output before the error:\nbefore"},
+		"huge list":   {code: "list(range(1 << 29))", want: "list: result too large"},
+		"huge sort":   {code: "sorted(range(1 << 29))", want: "sorted: result too large"},
+		"huge str":    {code: "s = 'x' * (1 << 20)\nstr([s] * 20)", want: "str: result too large"},
+		"huge print":  {code: "s = 'x' * (1 << 20)\nprint(s, [s] * 20)", want: "print: result too large"},
+		"huge repeat": {code: "'x' * (1 << 30)", want: "excessive repeat"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := r.evalCode(ctx, nil, tc.code)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestEvalCode_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := (&Registry{}).evalCode(ctx, nil, "while True:\n    pass")
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestEvalBuiltins_Limits(t *testing.T) {
+	thread := &starlark.Thread{Print: func(*starlark.Thread, string) {}}
+	below := starlark.String(strings.Repeat("x", evalMaxValue-16))
+	above := starlark.String(strings.Repeat("x", evalMaxValue))
+	for _, name := range []string{"str", "repr", "print"} {
+		if _, err := starlark.Call(thread, evalBuiltins[name], starlark.Tuple{below}, nil); err != nil {
+			t.Fatalf("%s below the limit: %v", name, err)
+		}
+		if _, err := starlark.Call(thread, evalBuiltins[name], starlark.Tuple{above}, nil); err == nil {
+			t.Fatalf("%s above the limit: no error", name)
+		}
+	}
+
+	n := starlark.MakeInt(evalMaxValue / evalElemSize)
+	seq, err := starlark.Call(thread, starlark.Universe["range"], starlark.Tuple{n}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := starlark.Call(thread, evalBuiltins["list"], starlark.Tuple{seq}, nil); err != nil {
+		t.Fatalf("list at the limit: %v", err)
+	}
+	seq, _ = starlark.Call(thread, starlark.Universe["range"], starlark.Tuple{starlark.MakeInt(evalMaxValue/evalElemSize + 1)}, nil)
+	for _, name := range []string{"list", "tuple", "sorted", "reversed", "enumerate", "zip"} {
+		if _, err := starlark.Call(thread, evalBuiltins[name], starlark.Tuple{seq}, nil); err == nil {
+			t.Fatalf("%s above the limit: no error", name)
+		}
+	}
+}
+
+func TestEvalCode_StepLimit(t *testing.T) {
+	// A loop of evalMaxSteps iterations takes several steps each.
+	code := "n = 0\nfor _ in range(" + strconv.Itoa(evalMaxSteps) + "):\n    n += 1\nn"
+	_, err := (&Registry{}).evalCode(context.Background(), nil, code)
+	if err == nil || !strings.Contains(err.Error(), "too many steps") {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := (&Registry{}).evalCode(context.Background(), nil, "n = 0\nfor _ in range(100000):\n    n += 1\nn"); err != nil {
+		t.Fatal(err)
+	}
+}
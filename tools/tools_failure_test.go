@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/metrics"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	cases := []struct {
+		err       error
+		kind      string
+		retryable bool
+	}{
+		{fmt.Errorf("get: %w", &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}), FailureDNS, false},
+		{fmt.Errorf("fetch: %w", context.DeadlineExceeded), FailureTimeout, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, FailureNetwork, true},
+		{fmt.Errorf("open x: %w", os.ErrNotExist), FailureNotFound, false},
+		{json.Unmarshal([]byte("{"), &struct{}{}), FailureInvalidArgs, false},
+		{errors.New("tool disabled: exec"), FailureDisabled, false},
+		{errors.New("path is outside workspace: /etc"), FailureBlocked, false},
+		{errors.New("brave http 429: slow down"), FailureRateLimited, true},
+		{errors.New("brave http 503: unavailable"), FailureHTTP, true},
+		{errors.New("something odd"), FailureOther, false},
+	}
+	for _, tc := range cases {
+		f := ClassifyFailure("t", tc.err)
+		if f.Kind != tc.kind || f.Retryable != tc.retryable {
+			t.Errorf("%v: kind=%s retryable=%v, want %s %v", tc.err, f.Kind, f.Retryable, tc.kind, tc.retryable)
+		}
+	}
+}
+
+func TestFailures_Record(t *testing.T) {
+	before := metrics.Default.Value("clawlet_tool_failures_total", "tool", "web_fetch", "kind", FailureDNS)
+	var fs Failures
+	out := fs.Record("web_fetch", &net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true})
+	if !strings.HasPrefix(out, "error: lookup nope.invalid: no such host\nfailure: {") ||
+		!strings.Contains(out, `"kind":"dns","retryable":false`) {
+		t.Fatalf("out=%q", out)
+	}
+	last, ok := fs.Last()
+	if !ok || last.Describe() != "DNS failure" {
+		t.Fatalf("last=%+v ok=%v", last, ok)
+	}
+	if got := metrics.Default.Value("clawlet_tool_failures_total", "tool", "web_fetch", "kind", FailureDNS); got != before+1 {
+		t.Fatalf("counter=%d before=%d", got, before)
+	}
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/memory"
+)
+
+func TestJournal_AppendsToDailyNote(t *testing.T) {
+	ws := t.TempDir()
+	store := memory.New(ws)
+	r := &Registry{
+		WorkspaceDir: ws,
+		Journal:      &memory.Journal{Store: store, Sections: []string{"Tasks", "Decisions"}},
+	}
+	var def bool
+	for _, d := range r.Definitions() {
+		if d.Function.Name == "journal" {
+			def = len(d.Function.Parameters.Properties["section"].Enum) == 2
+		}
+	}
+	if !def {
+		t.Fatal("expected journal definition with section enum")
+	}
+
+	out, err := r.Execute(context.Background(), Context{}, "journal", json.RawMessage(`{"section":"Tasks","entry":"Send invoice"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "memory/") {
+		t.Fatalf("out=%s", out)
+	}
+	b, err := os.ReadFile(store.DailyPath(time.Now()))
+	if err != nil || !strings.Contains(string(b), "## Tasks\n- [ ] Send invoice") {
+		t.Fatalf("note=%q err=%v", b, err)
+	}
+
+	if _, err := r.Execute(context.Background(), Context{}, "journal", json.RawMessage(`{"section":"Mood","entry":"ok"}`)); err == nil {
+		t.Fatal("expected unknown section error")
+	}
+}
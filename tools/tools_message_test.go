@@ -3,9 +3,14 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/mosaxiv/clawlet/broadcast"
 	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/contacts"
 )
 
 func TestMessageRequiresExplicitTarget(t *testing.T) {
@@ -32,3 +37,264 @@ func TestMessageRejectsCurrentSessionTarget(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestMessageResolvesContact(t *testing.T) {
+	store := contacts.NewStore(filepath.Join(t.TempDir(), contacts.FileName))
+	if _, err := store.Upsert(contacts.Contact{Name: "Bob", Addresses: map[string]string{"telegram": "111"}}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	var got bus.OutboundMessage
+	r := &Registry{
+		Contacts: store,
+		Outbound: func(ctx context.Context, msg bus.OutboundMessage) error { got = msg; return nil },
+	}
+	_, err := r.Execute(context.Background(), Context{Channel: "discord", ChatID: "123"}, "message", json.RawMessage(`{"content":"dinner?","contact":"bob"}`))
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got.Channel != "telegram" || got.ChatID != "111" {
+		t.Fatalf("unexpected target: %+v", got)
+	}
+
+	_, err = r.Execute(context.Background(), Context{}, "message", json.RawMessage(`{"content":"hi","contact":"alice"}`))
+	if err == nil {
+		t.Fatalf("expected unknown contact error")
+	}
+}
+
+func TestMessageSendsWorkspaceFiles(t *testing.T) {
+	ws := t.TempDir()
+	if err := os.WriteFile(filepath.Join(ws, "chart.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var got bus.OutboundMessage
+	r := &Registry{
+		WorkspaceDir: ws,
+		Outbound:     func(ctx context.Context, msg bus.OutboundMessage) error { got = msg; return nil },
+	}
+	// Files may go to the current conversation.
+	_, err := r.Execute(context.Background(), Context{Channel: "discord", ChatID: "123"}, "message",
+		json.RawMessage(`{"content":"","channel":"discord","chat_id":"123","files":["chart.png"]}`))
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(got.Attachments) != 1 {
+		t.Fatalf("attachments=%+v", got.Attachments)
+	}
+	a := got.Attachments[0]
+	if a.Name != "chart.png" || a.MIMEType != "image/png" || a.Kind != "image" || a.SizeBytes != 3 {
+		t.Fatalf("unexpected attachment: %+v", a)
+	}
+
+	_, err = r.Execute(context.Background(), Context{}, "message",
+		json.RawMessage(`{"content":"x","channel":"discord","chat_id":"1","files":["missing.pdf"]}`))
+	if err == nil {
+		t.Fatal("expected missing file error")
+	}
+}
+
+func TestMessageSendsOptions(t *testing.T) {
+	var got bus.OutboundMessage
+	r := &Registry{
+		Outbound: func(ctx context.Context, msg bus.OutboundMessage) error { got = msg; return nil },
+	}
+	// Options may go to the current conversation.
+	_, err := r.Execute(context.Background(), Context{Channel: "whatsapp", ChatID: "1"}, "message",
+		json.RawMessage(`{"content":"Book it?","channel":"whatsapp","chat_id":"1","options":["Yes"," ","No"]}`))
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(got.Suggestions) != 2 || got.Suggestions[0] != "Yes" || got.Suggestions[1] != "No" {
+		t.Fatalf("suggestions=%q", got.Suggestions)
+	}
+
+	for _, args := range []string{
+		`{"content":"","channel":"whatsapp","chat_id":"1","options":["Yes"]}`,
+		`{"content":"pick","channel":"whatsapp","chat_id":"1","options":["a","b","c","d","e","f"]}`,
+	} {
+		if _, err := r.Execute(context.Background(), Context{}, "message", json.RawMessage(args)); err == nil {
+			t.Fatalf("expected error for %s", args)
+		}
+	}
+}
+
+func TestReactDefaultsToCurrentMessage(t *testing.T) {
+	var got bus.OutboundMessage
+	r := &Registry{
+		Outbound: func(ctx context.Context, msg bus.OutboundMessage) error { got = msg; return nil },
+	}
+	tctx := Context{Channel: "telegram", ChatID: "111", MessageID: "42"}
+	if _, err := r.Execute(context.Background(), tctx, "react", json.RawMessage(`{"emoji":"👍"}`)); err != nil {
+		t.Fatal(err)
+	}
+	want := bus.OutboundMessage{Channel: "telegram", ChatID: "111", Kind: bus.MessageKindReaction, Reaction: bus.Reaction{Emoji: "👍", MessageID: "42"}}
+	if got.Channel != want.Channel || got.ChatID != want.ChatID || got.Kind != want.Kind || got.Reaction != want.Reaction {
+		t.Fatalf("got %+v", got)
+	}
+
+	if _, err := r.Execute(context.Background(), tctx, "react", json.RawMessage(`{"emoji":"🎉","message_id":"40"}`)); err != nil || got.Reaction.MessageID != "40" {
+		t.Fatalf("explicit message: err=%v got=%+v", err, got)
+	}
+	for _, c := range []Context{{Channel: "telegram", ChatID: "111"}, {Channel: "cli", ChatID: "direct", MessageID: "1"}} {
+		if _, err := r.Execute(context.Background(), c, "react", json.RawMessage(`{"emoji":"👍"}`)); err == nil {
+			t.Fatalf("expected error for %+v", c)
+		}
+	}
+}
+
+func TestCreatePoll(t *testing.T) {
+	var got bus.OutboundMessage
+	r := &Registry{
+		Outbound: func(ctx context.Context, msg bus.OutboundMessage) error { got = msg; return nil },
+	}
+	tctx := Context{Channel: "telegram", ChatID: "111"}
+	if _, err := r.Execute(context.Background(), tctx, "create_poll", json.RawMessage(`{"question":"Lunch?","options":["Pizza"," ","Sushi"],"multiple":true}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != bus.MessageKindPoll || got.ChatID != "111" || got.Poll.Question != "Lunch?" || !got.Poll.MultiSelect || len(got.Poll.Options) != 2 || got.Poll.Options[1] != "Sushi" {
+		t.Fatalf("got %+v", got)
+	}
+	for _, args := range []string{
+		`{"question":"","options":["a","b"]}`,
+		`{"question":"q","options":["a"]}`,
+		`{"question":"q","options":["1","2","3","4","5","6","7","8","9","10","11"]}`,
+		`{"question":"q","options":["a","b"],"duration_hours":-1}`,
+	} {
+		if _, err := r.Execute(context.Background(), tctx, "create_poll", json.RawMessage(args)); err == nil {
+			t.Fatalf("expected error for %s", args)
+		}
+	}
+	if _, err := r.Execute(context.Background(), Context{Channel: "cli", ChatID: "direct"}, "create_poll", json.RawMessage(`{"question":"q","options":["a","b"]}`)); err == nil {
+		t.Fatal("expected error without a chat")
+	}
+}
+
+func TestSendVoice(t *testing.T) {
+	var got bus.OutboundMessage
+	var spoken string
+	r := &Registry{
+		Outbound: func(ctx context.Context, msg bus.OutboundMessage) error { got = msg; return nil },
+		Speak: func(ctx context.Context, text string) (bus.Attachment, error) {
+			spoken = text
+			return bus.Attachment{Name: "voice.ogg", MIMEType: "audio/ogg", Kind: "audio", Data: []byte("OggS")}, nil
+		},
+	}
+	if _, err := r.Execute(context.Background(), Context{Channel: "telegram", ChatID: "111"}, "send_voice", json.RawMessage(`{"text":" Good morning "}`)); err != nil {
+		t.Fatal(err)
+	}
+	if spoken != "Good morning" || got.ChatID != "111" || len(got.Attachments) != 1 || got.Attachments[0].Name != "voice.ogg" {
+		t.Fatalf("spoken=%q got %+v", spoken, got)
+	}
+	if _, err := r.Execute(context.Background(), Context{Channel: "cli", ChatID: "direct"}, "send_voice", json.RawMessage(`{"text":"hi"}`)); err == nil {
+		t.Fatal("expected error without a chat")
+	}
+}
+
+func TestMessageTool_Cards(t *testing.T) {
+	var got bus.OutboundMessage
+	r := &Registry{
+		Outbound: func(ctx context.Context, msg bus.OutboundMessage) error { got = msg; return nil },
+	}
+	// Cards may go to the current conversation.
+	_, err := r.Execute(context.Background(), Context{Channel: "discord", ChatID: "1"}, "message",
+		json.RawMessage(`{"content":"","channel":"discord","chat_id":"1","cards":[{"title":"Go","url":"https://go.dev","fields":[{"name":"Stars","value":"120k","inline":true}],"color":"#00ADD8"}]}`))
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(got.Cards) != 1 || got.Cards[0].Title != "Go" || got.Cards[0].Color != 0x00ADD8 || !got.Cards[0].Fields[0].Inline {
+		t.Fatalf("cards=%+v", got.Cards)
+	}
+
+	for _, args := range []string{
+		`{"content":"x","channel":"discord","chat_id":"1","cards":[{"footer":"only a footer"}]}`,
+		`{"content":"x","channel":"discord","chat_id":"1","cards":[{"title":"t","color":"blue"}]}`,
+	} {
+		if _, err := r.Execute(context.Background(), Context{}, "message", json.RawMessage(args)); err == nil {
+			t.Fatalf("expected error for %s", args)
+		}
+	}
+}
+
+func TestMessageNotResentByRetriedTurn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sent.json")
+	var sent []string
+	r := &Registry{
+		Sent:     NewSentLog(path),
+		Outbound: func(ctx context.Context, msg bus.OutboundMessage) error { sent = append(sent, msg.Content); return nil },
+	}
+	run := func(contents ...string) {
+		tctx := Context{Channel: "discord", ChatID: "123", Sends: NewTurnSends("turn-1")}
+		for _, c := range contents {
+			args, _ := json.Marshal(map[string]string{"content": c, "channel": "telegram", "chat_id": "111"})
+			if _, err := r.Execute(context.Background(), tctx, "message", args); err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+		}
+	}
+	run("first")
+	// The turn runs again after a crash, with the log read back from disk;
+	// only its second message is new.
+	r.Sent = NewSentLog(path)
+	run("first, reworded", "second")
+	if len(sent) != 2 || sent[0] != "first" || sent[1] != "second" {
+		t.Fatalf("sent = %q", sent)
+	}
+
+	// Turns without a key are not deduplicated.
+	for range 2 {
+		if _, err := r.Execute(context.Background(), Context{}, "message", json.RawMessage(`{"content":"ping","channel":"telegram","chat_id":"111"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sent) != 4 {
+		t.Fatalf("sent = %q", sent)
+	}
+}
+
+func TestSentLogForgetsOldKeys(t *testing.T) {
+	l := NewSentLog("")
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	l.Record("a")
+	if !l.Sent("a") {
+		t.Fatal("a should be recorded")
+	}
+	now = now.Add(sentKeyTTL)
+	if l.Sent("a") {
+		t.Fatal("a should have expired")
+	}
+	l.Record("b")
+	if _, ok := l.keys["a"]; ok {
+		t.Fatal("expired keys should be dropped")
+	}
+}
+
+func TestMessageToGroup(t *testing.T) {
+	var sent []bus.OutboundMessage
+	r := &Registry{
+		Sent: NewSentLog(filepath.Join(t.TempDir(), "sent.json")),
+		Recipients: func(group string) ([]broadcast.Recipient, error) {
+			return []broadcast.Recipient{
+				{Channel: "telegram", ChatID: "111", Name: "Bob Smith"},
+				{Channel: "slack", ChatID: "C1"},
+			}, nil
+		},
+		Outbound: func(ctx context.Context, msg bus.OutboundMessage) error { sent = append(sent, msg); return nil },
+	}
+	tctx := Context{Channel: "discord", ChatID: "123", Sends: NewTurnSends("turn-1")}
+	args := json.RawMessage(`{"content":"Hi {{first_name|everyone}}, the office is closed today.","group":"team"}`)
+	if _, err := r.Execute(context.Background(), tctx, "message", args); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(sent) != 2 || sent[0].Content != "Hi Bob, the office is closed today." || sent[1].Content != "Hi everyone, the office is closed today." {
+		t.Fatalf("sent = %+v", sent)
+	}
+	// A retried turn does not send the announcement again.
+	tctx.Sends = NewTurnSends("turn-1")
+	if _, err := r.Execute(context.Background(), tctx, "message", args); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("sent again: %+v", sent)
+	}
+}
@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestRemember_SavesLastMessages(t *testing.T) {
+	ws := t.TempDir()
+	var gotKey string
+	r := &Registry{
+		WorkspaceDir: ws,
+		Conversation: func(key string) []session.Message {
+			gotKey = key
+			return []session.Message{
+				{Role: "user", Content: "old"},
+				{Role: "user", Content: "the wifi password is hunter2"},
+				{Role: "assistant", Content: "noted"},
+			}
+		},
+	}
+	out, err := r.Execute(context.Background(), Context{SessionKey: "slack:C1"}, "remember",
+		json.RawMessage(`{"title":"Wifi","tags":["home"],"last_messages":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotKey != "slack:C1" {
+		t.Fatalf("session key=%q", gotKey)
+	}
+	var res struct {
+		Path     string `json:"path"`
+		Messages int    `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Messages != 2 || !strings.HasPrefix(res.Path, "memory/notes/") {
+		t.Fatalf("unexpected result: %s", out)
+	}
+	b, err := os.ReadFile(filepath.Join(ws, res.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "**user:** old") || !strings.Contains(string(b), "hunter2") {
+		t.Fatalf("unexpected note:\n%s", b)
+	}
+}
+
+func TestRemember_NotAdvertisedWithoutConversation(t *testing.T) {
+	for _, d := range (&Registry{}).Definitions() {
+		if d.Function.Name == "remember" {
+			t.Fatal("remember should require Conversation")
+		}
+	}
+}
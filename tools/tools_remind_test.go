@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/reminders"
+)
+
+func TestRemind(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	r := &Registry{
+		Cron:             cron.NewService(filepath.Join(t.TempDir(), "cron.json"), nil),
+		ReminderLocation: loc,
+	}
+	tctx := Context{Channel: "telegram", ChatID: "111"}
+	date := time.Now().In(loc).AddDate(0, 0, 2).Format("2006-01-02")
+	out, err := r.Execute(t.Context(), tctx, "remind", json.RawMessage(`{"text":"call mom","date":"`+date+`","time":"09:00"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "9:00 AM, your time.") {
+		t.Fatalf("out %q", out)
+	}
+	jobs := r.Cron.List(false)
+	if len(jobs) != 1 || jobs[0].Payload.Kind != reminders.PayloadKind || jobs[0].Payload.To != "111" || jobs[0].Payload.Message != "call mom" {
+		t.Fatalf("jobs %+v", jobs)
+	}
+
+	// Reminders are listed and cancelled per chat.
+	if out, _ := r.Execute(t.Context(), Context{Channel: "telegram", ChatID: "222"}, "remind", json.RawMessage(`{"action":"list"}`)); out != "No reminders in this chat." {
+		t.Fatalf("other chat list %q", out)
+	}
+	out, err = r.Execute(t.Context(), tctx, "remind", json.RawMessage(`{"action":"list"}`))
+	if err != nil || !strings.Contains(out, "call mom") || !strings.Contains(out, jobs[0].ID) || !strings.Contains(out, "09:00 JST") {
+		t.Fatalf("list %q err=%v", out, err)
+	}
+	if out, _ := r.Execute(t.Context(), Context{Channel: "telegram", ChatID: "222"}, "remind", json.RawMessage(`{"action":"cancel","job_id":"`+jobs[0].ID+`"}`)); !strings.HasPrefix(out, "Reminder not found") {
+		t.Fatalf("cancel from other chat %q", out)
+	}
+	if out, _ := r.Execute(t.Context(), tctx, "remind", json.RawMessage(`{"action":"cancel","job_id":"`+jobs[0].ID+`"}`)); !strings.HasPrefix(out, "Cancelled") {
+		t.Fatalf("cancel %q", out)
+	}
+
+	if _, err := r.Execute(t.Context(), tctx, "remind", json.RawMessage(`{"text":"x","date":"2020-01-01","time":"09:00"}`)); err == nil {
+		t.Fatal("expected error for a time in the past")
+	}
+}
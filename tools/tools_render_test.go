@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+)
+
+func TestRender(t *testing.T) {
+	ws := t.TempDir()
+	if err := os.WriteFile(filepath.Join(ws, "sales.csv"), []byte("month,sales\nJan,10\nFeb,14\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var got bus.OutboundMessage
+	r := &Registry{
+		WorkspaceDir:        ws,
+		RestrictToWorkspace: true,
+		Outbound:            func(ctx context.Context, msg bus.OutboundMessage) error { got = msg; return nil },
+	}
+	tctx := Context{Channel: "telegram", ChatID: "111"}
+	if _, err := r.Execute(context.Background(), tctx, "render", json.RawMessage(`{"kind":"bar","path":"sales.csv","title":"Sales","caption":"Q1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got.ChatID != "111" || got.Content != "Q1" || len(got.Attachments) != 1 {
+		t.Fatalf("got %+v", got)
+	}
+	a := got.Attachments[0]
+	if a.Name != "chart.png" || a.MIMEType != "image/png" || a.Kind != "image" || a.SizeBytes != int64(len(a.Data)) {
+		t.Fatalf("attachment %+v", a)
+	}
+	if _, err := png.Decode(bytes.NewReader(a.Data)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Execute(context.Background(), tctx, "render", json.RawMessage(`{"kind":"table","data":"[{\"name\":\"Ann\",\"city\":\"Oslo\"}]"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got.Attachments[0].Name != "table.png" {
+		t.Fatalf("got %+v", got.Attachments[0])
+	}
+
+	for _, args := range []string{
+		`{"kind":"bar","data":"name,city\nAnn,Oslo"}`,
+		`{"kind":"bar","data":"a,b\n1,2","path":"sales.csv"}`,
+		`{"kind":"pie","data":"a,b\n1,2"}`,
+		`{"kind":"table","path":"../outside.csv"}`,
+	} {
+		if _, err := r.Execute(context.Background(), tctx, "render", json.RawMessage(args)); err == nil {
+			t.Fatalf("expected error for %s", args)
+		}
+	}
+	if _, err := r.Execute(context.Background(), Context{Channel: "cli", ChatID: "direct"}, "render", json.RawMessage(`{"kind":"table","data":"a\n1"}`)); err == nil {
+		t.Fatal("expected error without a chat")
+	}
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSkillScope(t *testing.T) {
+	ws := t.TempDir()
+	r := &Registry{
+		WorkspaceDir:        ws,
+		RestrictToWorkspace: true,
+		IsolateSkills:       true,
+		ReadSkill: func(name string) (string, bool) {
+			return "# " + name, name == "weather"
+		},
+	}
+	run := func(tctx Context, name string, args any) (string, error) {
+		b, _ := json.Marshal(args)
+		return r.Execute(context.Background(), tctx, name, b)
+	}
+	tctx := Context{Skill: &ActiveSkill{}}
+
+	// No skill yet: the whole workspace is open.
+	if _, err := run(tctx, "write_file", map[string]string{"path": "notes.txt", "content": "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(tctx, "read_skill", map[string]string{"name": "missing"}); err == nil || tctx.Skill.Name() != "" {
+		t.Fatalf("missing skill: err=%v active=%q", err, tctx.Skill.Name())
+	}
+	out, err := run(tctx, "read_skill", map[string]string{"name": "weather"})
+	if err != nil || !strings.Contains(out, "skills/weather/data") {
+		t.Fatalf("read_skill = %q, %v", out, err)
+	}
+	if st, err := os.Stat(filepath.Join(ws, "skills", "weather", "data")); err != nil || !st.IsDir() {
+		t.Fatalf("data dir not created: %v", err)
+	}
+
+	if _, err := run(tctx, "write_file", map[string]string{"path": "skills/weather/data/cache.json", "content": "{}"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range []struct {
+		name string
+		args map[string]string
+	}{
+		{"write_file", map[string]string{"path": "notes.txt", "content": "y"}},
+		{"write_file", map[string]string{"path": "skills/weather/SKILL.md", "content": "y"}},
+		{"edit_file", map[string]string{"path": "notes.txt", "old_text": "x", "new_text": "y"}},
+		{"read_file", map[string]string{"path": "notes.txt"}},
+		{"list_dir", map[string]string{"path": "."}},
+	} {
+		if _, err := run(tctx, call.name, call.args); err == nil || !strings.Contains(err.Error(), "skill weather may only access") {
+			t.Fatalf("%s %v: err=%v", call.name, call.args, err)
+		}
+	}
+	if _, err := run(tctx, "read_file", map[string]string{"path": "skills/weather/data/cache.json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err = run(tctx, "exec", map[string]string{"command": "pwd"})
+	if err != nil || !strings.Contains(out, filepath.Join("skills", "weather", "data")) {
+		t.Fatalf("exec cwd: %q, %v", out, err)
+	}
+	out, _ = run(tctx, "exec", map[string]string{"command": "cat " + filepath.Join(ws, "notes.txt")})
+	if !strings.Contains(out, "path outside workspace") {
+		t.Fatalf("exec outside the skill: %q", out)
+	}
+
+	// Without isolation the skill only gets its data directory.
+	r.IsolateSkills = false
+	if _, err := run(tctx, "read_file", map[string]string{"path": "notes.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	out, err = run(tctx, "exec", map[string]string{"command": "env"})
+	if err != nil || !strings.Contains(out, skillDataEnv+"=") {
+		t.Fatalf("exec env: %q, %v", out, err)
+	}
+}
+
+func TestSkillDataDir(t *testing.T) {
+	if got, err := SkillDataDir("/ws", "weather"); err != nil || got != filepath.Join("/ws", "skills", "weather", "data") {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	for _, name := range []string{"", "..", "a/b", `a\b`} {
+		if _, err := SkillDataDir("/ws", name); err == nil {
+			t.Fatalf("expected error for %q", name)
+		}
+	}
+}
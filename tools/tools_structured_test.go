@@ -0,0 +1,202 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"":                ".",
+		".":               ".",
+		".server.port":    "server.port",
+		"servers[0].host": "servers[0].host",
+		`a["b.c"][-1]`:    `a["b.c"][-1]`,
+		`["x]"]`:          `["x]"]`,
+		"list[2][3]":      "list[2][3]",
+	} {
+		steps, err := parseKey(key)
+		if err != nil {
+			t.Errorf("parseKey(%q): %v", key, err)
+			continue
+		}
+		if got := keyString(steps); got != want {
+			t.Errorf("parseKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+	for _, key := range []string{"a..b", "a.", "a[x]", "a[0", "a[0]b", "a]"} {
+		if _, err := parseKey(key); err == nil {
+			t.Errorf("parseKey(%q) succeeded", key)
+		}
+	}
+}
+
+func structuredFile(t *testing.T, name, content string) (*Registry, string) {
+	t.Helper()
+	ws := t.TempDir()
+	if err := os.WriteFile(filepath.Join(ws, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return &Registry{WorkspaceDir: ws, RestrictToWorkspace: true}, filepath.Join(ws, name)
+}
+
+func setValueArgs(path, key string, value any) json.RawMessage {
+	args, _ := json.Marshal(map[string]any{"path": path, "key": key, "value": value})
+	return args
+}
+
+func TestSetValue_JSONKeepsOrderAndIndent(t *testing.T) {
+	r, path := structuredFile(t, "app.json", "{\n    \"name\": \"app\",\n    \"port\": 80,\n    \"ratio\": 1.50,\n    \"tags\": [\"a\"]\n}\n")
+	ctx := context.Background()
+	for _, a := range []json.RawMessage{
+		setValueArgs("app.json", "port", "8080"),
+		setValueArgs("app.json", "tags[1]", "b"),
+		setValueArgs("app.json", "db.host", `"<local>"`),
+	} {
+		if _, err := r.Execute(ctx, Context{}, "set_value", a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, _ := os.ReadFile(path)
+	want := "{\n    \"name\": \"app\",\n    \"port\": 8080,\n    \"ratio\": 1.50,\n    \"tags\": [\n        \"a\",\n        \"b\"\n    ],\n    \"db\": {\n        \"host\": \"<local>\"\n    }\n}\n"
+	if string(got) != want {
+		t.Fatalf("file:\n%s\nwant:\n%s", got, want)
+	}
+
+	args, _ := json.Marshal(map[string]any{"path": "app.json", "key": "tags", "delete": true})
+	if _, err := r.Execute(ctx, Context{}, "set_value", args); err != nil {
+		t.Fatal(err)
+	}
+	args, _ = json.Marshal(map[string]string{"path": "app.json", "key": "db"})
+	if out, err := r.Execute(ctx, Context{}, "get_value", args); err != nil || out != "{\n  \"host\": \"<local>\"\n}" {
+		t.Fatalf("get_value = %q, %v", out, err)
+	}
+	args, _ = json.Marshal(map[string]string{"path": "app.json", "key": "tags"})
+	if _, err := r.Execute(ctx, Context{}, "get_value", args); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("deleted key: %v", err)
+	}
+}
+
+func TestSetValue_YAMLKeepsComments(t *testing.T) {
+	r, path := structuredFile(t, "config.yaml", "# service settings\nserver:\n  port: 80 # http\n  hosts:\n    - a\n")
+	ctx := context.Background()
+	if _, err := r.Execute(ctx, Context{}, "set_value", setValueArgs("config.yaml", ".server.port", 8080)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Execute(ctx, Context{}, "set_value", setValueArgs("config.yaml", "server.hosts[-1]", "b")); err != nil {
+		t.Fatal(err)
+	}
+	// "123" is JSON, so it is set as a number; a string needs quotes.
+	if _, err := r.Execute(ctx, Context{}, "set_value", setValueArgs("config.yaml", "server.name", "123")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Execute(ctx, Context{}, "set_value", setValueArgs("config.yaml", "server.id", `"123"`)); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	want := "# service settings\nserver:\n  port: 8080 # http\n  hosts:\n    - b\n  name: 123\n  id: \"123\"\n"
+	if string(got) != want {
+		t.Fatalf("file:\n%s\nwant:\n%s", got, want)
+	}
+	args, _ := json.Marshal(map[string]string{"path": "config.yaml", "key": "server.hosts"})
+	if out, err := r.Execute(ctx, Context{}, "get_value", args); err != nil || out != "[\n  \"b\"\n]" {
+		t.Fatalf("get_value = %q, %v", out, err)
+	}
+	if _, err := r.Execute(ctx, Context{}, "set_value", setValueArgs("config.yaml", "server.port.x", 1)); err == nil {
+		t.Fatal("set a key under a number")
+	}
+
+	r, _ = structuredFile(t, "multi.yaml", "a: 1\n---\nb: 2\n")
+	if _, err := r.Execute(ctx, Context{}, "set_value", setValueArgs("multi.yaml", "a", 2)); err == nil {
+		t.Fatal("edited a multi-document file")
+	}
+}
+
+func TestSetValue_TOML(t *testing.T) {
+	src := `# app
+title = "demo"
+
+[server]
+port = 80 # http
+hosts = [
+  "a", # first
+  "b",
+]
+
+[[plugins]]
+name = "x"
+`
+	r, path := structuredFile(t, "app.toml", src)
+	ctx := context.Background()
+	set := func(key string, value any) error {
+		_, err := r.Execute(ctx, Context{}, "set_value", setValueArgs("app.toml", key, value))
+		return err
+	}
+	for key, value := range map[string]any{
+		"server.port":     8080,
+		"server.hosts":    []string{"c"},
+		"server.tls.cert": "/etc/cert.pem",
+		"debug":           true,
+	} {
+		if err := set(key, value); err != nil {
+			t.Fatalf("set %s: %v", key, err)
+		}
+	}
+	got, _ := os.ReadFile(path)
+	want := `# app
+title = "demo"
+debug = true
+
+[server]
+port = 8080 # http
+hosts = ["c"]
+tls.cert = "/etc/cert.pem"
+
+[[plugins]]
+name = "x"
+`
+	if string(got) != want {
+		t.Fatalf("file:\n%s\nwant:\n%s", got, want)
+	}
+
+	get := func(key string) (string, error) {
+		args, _ := json.Marshal(map[string]string{"path": "app.toml", "key": key})
+		return r.Execute(ctx, Context{}, "get_value", args)
+	}
+	if out, err := get("server"); err != nil || out != "port = 8080\nhosts = [\"c\"]\ntls.cert = \"/etc/cert.pem\"" {
+		t.Fatalf("get server = %q, %v", out, err)
+	}
+	for _, key := range []string{"plugins.name", "server.hosts[0]"} {
+		if _, err := get(key); err == nil {
+			t.Errorf("get %s succeeded", key)
+		}
+	}
+	if err := set("title.x", 1); err == nil {
+		t.Fatal("set a key under a string")
+	}
+	if err := set("server", 1); err == nil {
+		t.Fatal("replaced a table")
+	}
+	if err := set("debug", nil); err == nil {
+		t.Fatal("set null")
+	}
+
+	args, _ := json.Marshal(map[string]any{"path": "app.toml", "key": "debug", "delete": true})
+	if _, err := r.Execute(ctx, Context{}, "set_value", args); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := get("debug"); err == nil {
+		t.Fatal("deleted key still there")
+	}
+}
+
+func TestSetValue_RejectsOtherFiles(t *testing.T) {
+	r, _ := structuredFile(t, "notes.txt", "a = 1\n")
+	if _, err := r.Execute(context.Background(), Context{}, "set_value", setValueArgs("notes.txt", "a", 2)); err == nil {
+		t.Fatal("edited a .txt file")
+	}
+}
@@ -0,0 +1,277 @@
+// Package turns records agent turns so they can be replayed later: the
+// messages sent to the model, every model response and every tool result.
+// Recordings are kept in the state storage next to sessions and are
+// encrypted like sessions when at-rest encryption is configured.
+package turns
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mosaxiv/clawlet/atrest"
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/storage"
+)
+
+// Namespace is the storage namespace for recorded turns.
+const Namespace = "turns"
+
+// Turn is one recorded run of the agent loop. Image parts of user messages
+// are not recorded.
+type Turn struct {
+	ID         string    `json:"id"`
+	SessionKey string    `json:"sessionKey"`
+	Channel    string    `json:"channel,omitempty"`
+	ChatID     string    `json:"chatId,omitempty"`
+	Sender     string    `json:"sender,omitempty"`
+	Model      string    `json:"model,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	// Messages is what the first model call saw: system prompt, history and
+	// the user message.
+	Messages []llm.Message `json:"messages"`
+	Steps    []Step        `json:"steps"`
+	// Reply is the final reply after post-processing.
+	Reply string `json:"reply,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Step is one model response and the results of the tools it called.
+type Step struct {
+	Content   string     `json:"content,omitempty"`
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+	// Error is set when the model call failed.
+	Error string `json:"error,omitempty"`
+}
+
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Output    string          `json:"output"`
+}
+
+// UserText is the content of the last user message, for listings.
+func (t *Turn) UserText() string {
+	for i := len(t.Messages) - 1; i >= 0; i-- {
+		if t.Messages[i].Role == "user" {
+			return t.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// FinalContent is the model's last answer before post-processing.
+func (t *Turn) FinalContent() string {
+	if len(t.Steps) == 0 {
+		return ""
+	}
+	last := t.Steps[len(t.Steps)-1]
+	if len(last.ToolCalls) > 0 {
+		return ""
+	}
+	return last.Content
+}
+
+// NewID returns a turn ID that sorts by start time.
+func NewID(now time.Time) string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	return now.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(b[:])
+}
+
+// Recorder collects a turn while it runs. A nil Recorder records nothing, so
+// callers do not need to check whether recording is enabled.
+type Recorder struct {
+	mu   sync.Mutex
+	turn Turn
+}
+
+// NewRecorder starts recording t; ID and StartedAt are filled in when empty.
+func NewRecorder(t Turn) *Recorder {
+	if t.StartedAt.IsZero() {
+		t.StartedAt = time.Now()
+	}
+	if t.ID == "" {
+		t.ID = NewID(t.StartedAt)
+	}
+	t.Messages = append([]llm.Message(nil), t.Messages...)
+	return &Recorder{turn: t}
+}
+
+func (r *Recorder) ID() string {
+	if r == nil {
+		return ""
+	}
+	return r.turn.ID
+}
+
+// Response records a model response, or the error the model call returned.
+func (r *Recorder) Response(res *llm.ChatResult, err error) {
+	if r == nil {
+		return
+	}
+	var s Step
+	if err != nil {
+		s.Error = err.Error()
+	} else if res != nil {
+		s.Content = res.Content
+		for _, tc := range res.ToolCalls {
+			s.ToolCalls = append(s.ToolCalls, ToolCall{ID: tc.ID, Name: tc.Name, Arguments: tc.Arguments})
+		}
+	}
+	r.mu.Lock()
+	r.turn.Steps = append(r.turn.Steps, s)
+	r.mu.Unlock()
+}
+
+// Tool records the result of a tool call of the latest response.
+func (r *Recorder) Tool(tc llm.ToolCall, output string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.turn.Steps) == 0 {
+		return
+	}
+	calls := r.turn.Steps[len(r.turn.Steps)-1].ToolCalls
+	for i := range calls {
+		if calls[i].ID == tc.ID && calls[i].Name == tc.Name {
+			calls[i].Output = output
+			return
+		}
+	}
+}
+
+// Finish ends the recording and returns the turn.
+func (r *Recorder) Finish(reply string, err error) *Turn {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.turn
+	t.Reply = reply
+	if err != nil {
+		t.Error = err.Error()
+	}
+	return &t
+}
+
+func Save(st storage.Store, t *Turn) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if b, err = atrest.Encode(b); err != nil {
+		return err
+	}
+	return st.Put(Namespace, t.ID, b)
+}
+
+// Load reads a recorded turn; storage.ErrNotFound is returned as is.
+func Load(st storage.Store, id string) (*Turn, error) {
+	b, err := st.Get(Namespace, id)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = atrest.Decode(b); err != nil {
+		return nil, fmt.Errorf("turn %s: %w", id, err)
+	}
+	var t Turn
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("turn %s: %w", id, err)
+	}
+	return &t, nil
+}
+
+// List returns recorded turn IDs, oldest first.
+func List(st storage.Store) ([]string, error) {
+	ids, err := st.List(Namespace)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Prune deletes the oldest turns so that at most keep remain.
+func Prune(st storage.Store, keep int) error {
+	ids, err := List(st)
+	if err != nil || len(ids) <= keep {
+		return err
+	}
+	for _, id := range ids[:len(ids)-keep] {
+		if err := st.Delete(Namespace, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrExhausted is returned by Player.Chat when the replay asks the model
+// for more responses than were recorded.
+var ErrExhausted = errors.New("recording has no more model responses")
+
+// Player serves the model responses and tool results of a recorded turn.
+type Player struct {
+	turn *Turn
+	next int
+	used map[[2]int]bool // step, call
+}
+
+func NewPlayer(t *Turn) *Player {
+	return &Player{turn: t, used: map[[2]int]bool{}}
+}
+
+// Chat returns the next recorded model response. It has the signature of
+// llm.Client.Chat; the request is not checked against the recording.
+func (p *Player) Chat(_ context.Context, _ []llm.Message, _ []llm.ToolDefinition) (*llm.ChatResult, error) {
+	if p.next >= len(p.turn.Steps) {
+		return nil, ErrExhausted
+	}
+	s := p.turn.Steps[p.next]
+	p.next++
+	if s.Error != "" {
+		return nil, errors.New(s.Error)
+	}
+	res := &llm.ChatResult{Content: s.Content}
+	for _, tc := range s.ToolCalls {
+		res.ToolCalls = append(res.ToolCalls, llm.ToolCall{ID: tc.ID, Name: tc.Name, Arguments: tc.Arguments})
+	}
+	return res, nil
+}
+
+// Tool returns the recorded output of the first unused call to name with
+// the same arguments, so a live model that repeats a recorded call gets the
+// recorded result.
+func (p *Player) Tool(name string, args json.RawMessage) (string, bool) {
+	want := compactJSON(args)
+	for i, s := range p.turn.Steps {
+		for j, tc := range s.ToolCalls {
+			k := [2]int{i, j}
+			if p.used[k] || tc.Name != name || !bytes.Equal(compactJSON(tc.Arguments), want) {
+				continue
+			}
+			p.used[k] = true
+			return tc.Output, true
+		}
+	}
+	return "", false
+}
+
+func compactJSON(b json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return bytes.TrimSpace(b)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,74 @@
+package turns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/storage"
+)
+
+func TestRecorder_SaveLoadAndPlay(t *testing.T) {
+	msgs := []llm.Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "weather?"}}
+	rec := NewRecorder(Turn{SessionKey: "cli:default", Messages: msgs, StartedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)})
+	msgs[1].Content = "changed later"
+
+	call := llm.ToolCall{ID: "c1", Name: "web_search", Arguments: json.RawMessage(`{"query": "weather"}`)}
+	rec.Response(&llm.ChatResult{ToolCalls: []llm.ToolCall{call}}, nil)
+	rec.Tool(call, "sunny")
+	rec.Response(&llm.ChatResult{Content: "It is sunny."}, nil)
+	turn := rec.Finish("It is sunny.", nil)
+
+	st := storage.NewFiles(map[string]string{Namespace: t.TempDir()})
+	if err := Save(st, turn); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(st, rec.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserText() != "weather?" || got.FinalContent() != "It is sunny." || got.Steps[0].ToolCalls[0].Output != "sunny" {
+		t.Fatalf("turn=%+v", got)
+	}
+
+	p := NewPlayer(got)
+	res, err := p.Chat(context.Background(), nil, nil)
+	if err != nil || len(res.ToolCalls) != 1 || res.ToolCalls[0].Name != "web_search" {
+		t.Fatalf("first response=%+v err=%v", res, err)
+	}
+	if out, ok := p.Tool("web_search", json.RawMessage(`{"query":"weather"}`)); !ok || out != "sunny" {
+		t.Fatalf("tool=%q ok=%v", out, ok)
+	}
+	if _, ok := p.Tool("web_search", json.RawMessage(`{"query":"weather"}`)); ok {
+		t.Fatal("recorded call served twice")
+	}
+	if res, _ := p.Chat(context.Background(), nil, nil); res.Content != "It is sunny." {
+		t.Fatalf("second response=%+v", res)
+	}
+	if _, err := p.Chat(context.Background(), nil, nil); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestPrune_KeepsNewest(t *testing.T) {
+	st := storage.NewFiles(map[string]string{Namespace: t.TempDir()})
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ids []string
+	for i := range 3 {
+		turn := &Turn{ID: NewID(base.Add(time.Duration(i) * time.Minute))}
+		ids = append(ids, turn.ID)
+		if err := Save(st, turn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Prune(st, 2); err != nil {
+		t.Fatal(err)
+	}
+	left, _ := List(st)
+	if len(left) != 2 || left[0] != ids[1] || left[1] != ids[2] {
+		t.Fatalf("left=%v ids=%v", left, ids)
+	}
+}
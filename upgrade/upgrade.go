@@ -0,0 +1,355 @@
+package upgrade
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jedisct1/go-minisign"
+)
+
+const (
+	DefaultRepo    = "mosaxiv/clawlet"
+	DefaultAPIBase = "https://api.github.com"
+
+	// ChecksumsAsset is the goreleaser checksum file name (see .goreleaser.yaml).
+	ChecksumsAsset = "checksums.txt"
+	// SignatureAsset is the minisign signature of ChecksumsAsset, made with
+	// the release signing key by the release workflow.
+	SignatureAsset = ChecksumsAsset + ".minisig"
+
+	binaryName     = "clawlet"
+	maxArchiveSize = 200 << 20
+	maxBinarySize  = 200 << 20
+	maxMetaSize    = 4 << 20
+)
+
+// ReleasePublicKey is the minisign public key that release checksums must
+// be signed with. A build without one cannot upgrade itself.
+//
+//go:embed release.pub
+var ReleasePublicKey string
+
+// ErrNoPublicKey is returned by Download when the client has no release
+// public key to check signatures with.
+var ErrNoPublicKey = errors.New("this build has no release signing key; install the release manually")
+
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []Asset `json:"assets"`
+}
+
+func (r Release) asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Client fetches clawlet releases from GitHub and verifies them.
+type Client struct {
+	Repo    string
+	APIBase string
+	HTTP    *http.Client
+	GOOS    string
+	GOARCH  string
+	// PublicKey is the minisign public key, as in a .pub file, that
+	// checksums must be signed with.
+	PublicKey string
+}
+
+func NewClient() *Client {
+	return &Client{
+		Repo:      DefaultRepo,
+		APIBase:   DefaultAPIBase,
+		HTTP:      &http.Client{Timeout: 5 * time.Minute},
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		PublicKey: ReleasePublicKey,
+	}
+}
+
+// Release returns the latest release, or the release for tag when set.
+func (c *Client) Release(ctx context.Context, tag string) (Release, error) {
+	u := strings.TrimRight(c.APIBase, "/") + "/repos/" + c.Repo + "/releases/latest"
+	if tag = strings.TrimSpace(tag); tag != "" {
+		if !strings.HasPrefix(tag, "v") {
+			tag = "v" + tag
+		}
+		u = strings.TrimRight(c.APIBase, "/") + "/repos/" + c.Repo + "/releases/tags/" + tag
+	}
+	b, err := c.get(ctx, u, maxMetaSize)
+	if err != nil {
+		return Release{}, err
+	}
+	var rel Release
+	if err := json.Unmarshal(b, &rel); err != nil {
+		return Release{}, fmt.Errorf("parse release: %w", err)
+	}
+	if strings.TrimSpace(rel.Tag) == "" {
+		return Release{}, errors.New("release has no tag")
+	}
+	return rel, nil
+}
+
+// Download fetches the archive for this platform and returns the extracted
+// binary. The release checksums file must carry a signature for rel.Tag by
+// c.PublicKey, and the archive must match its SHA-256; otherwise the
+// upgrade is refused.
+func (c *Client) Download(ctx context.Context, rel Release) ([]byte, error) {
+	pk, err := parsePublicKey(c.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	name := AssetName(c.GOOS, c.GOARCH)
+	archive, ok := rel.asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no asset %s", rel.Tag, name)
+	}
+	sums, ok := rel.asset(ChecksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s; refusing an upgrade without a checksum", rel.Tag, ChecksumsAsset)
+	}
+	sig, ok := rel.asset(SignatureAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s; refusing an unsigned upgrade", rel.Tag, SignatureAsset)
+	}
+	sumsBody, err := c.get(ctx, sums.URL, maxMetaSize)
+	if err != nil {
+		return nil, fmt.Errorf("download checksums: %w", err)
+	}
+	sigBody, err := c.get(ctx, sig.URL, maxMetaSize)
+	if err != nil {
+		return nil, fmt.Errorf("download signature: %w", err)
+	}
+	if err := verifyChecksums(pk, rel.Tag, sumsBody, sigBody); err != nil {
+		return nil, err
+	}
+	want, err := lookupChecksum(sumsBody, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.get(ctx, archive.URL, maxArchiveSize)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", name, err)
+	}
+	got := sha256.Sum256(data)
+	if hex.EncodeToString(got[:]) != want {
+		return nil, fmt.Errorf("checksum mismatch for %s", name)
+	}
+	return extractBinary(name, data)
+}
+
+func (c *Client) get(ctx context.Context, u string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "clawlet-upgrade")
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("GET %s: http %d", u, resp.StatusCode)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("GET %s: response too large", u)
+	}
+	return b, nil
+}
+
+// AssetName mirrors the archive name_template in .goreleaser.yaml.
+func AssetName(goos, goarch string) string {
+	arch := goarch
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+	ext := ".tar.gz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+	return binaryName + "_" + strings.ToUpper(goos[:1]) + goos[1:] + "_" + arch + ext
+}
+
+// parsePublicKey reads a minisign .pub file: an untrusted comment line and
+// the key.
+func parsePublicKey(s string) (minisign.PublicKey, error) {
+	var key string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			key = line
+		}
+	}
+	if key == "" {
+		return minisign.PublicKey{}, ErrNoPublicKey
+	}
+	pk, err := minisign.NewPublicKey(key)
+	if err != nil {
+		return minisign.PublicKey{}, fmt.Errorf("release signing key: %w", err)
+	}
+	return pk, nil
+}
+
+// signedComment is the trusted comment the release workflow signs along
+// with the checksums, so that they cannot be passed off as another
+// release's.
+func signedComment(tag string) string { return "clawlet " + tag }
+
+func verifyChecksums(pk minisign.PublicKey, tag string, sums, sig []byte) error {
+	s, err := minisign.DecodeSignature(string(sig))
+	if err != nil {
+		return fmt.Errorf("%s: %w", SignatureAsset, err)
+	}
+	if ok, err := pk.Verify(sums, s); !ok {
+		return fmt.Errorf("%s is not signed with the release key: %v", ChecksumsAsset, err)
+	}
+	if got := strings.TrimPrefix(s.TrustedComment, "trusted comment: "); got != signedComment(tag) {
+		return fmt.Errorf("%s is signed for %q, not release %s", ChecksumsAsset, got, tag)
+	}
+	return nil
+}
+
+func lookupChecksum(sums []byte, name string) (string, error) {
+	sc := bufio.NewScanner(bytes.NewReader(sums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if strings.TrimPrefix(fields[1], "*") == name {
+			sum := strings.ToLower(fields[0])
+			if len(sum) != sha256.Size*2 {
+				return "", fmt.Errorf("malformed checksum for %s", name)
+			}
+			return sum, nil
+		}
+	}
+	return "", fmt.Errorf("no checksum listed for %s", name)
+}
+
+func extractBinary(archiveName string, data []byte) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		return extractZip(data)
+	}
+	return extractTarGz(data)
+}
+
+func extractTarGz(data []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("archive does not contain the clawlet binary")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || path.Base(hdr.Name) != binaryName {
+			continue
+		}
+		return readBinary(tr)
+	}
+}
+
+func extractZip(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if path.Base(f.Name) != binaryName+".exe" || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return readBinary(rc)
+	}
+	return nil, errors.New("archive does not contain the clawlet binary")
+}
+
+func readBinary(r io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, maxBinarySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxBinarySize {
+		return nil, errors.New("binary too large")
+	}
+	return b, nil
+}
+
+// Install atomically replaces the executable at exe with bin. A running
+// process keeps its old image; new processes start the new binary.
+func Install(exe string, bin []byte) error {
+	dir := filepath.Dir(exe)
+	tmp, err := os.CreateTemp(dir, ".clawlet-upgrade-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	if _, err := tmp.Write(bin); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, 0o755); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		// A running .exe cannot be replaced, only renamed.
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmpName, exe)
+}
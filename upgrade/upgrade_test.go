@@ -0,0 +1,216 @@
+package upgrade
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func tarGz(t *testing.T, name string, body []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+	return buf.Bytes()
+}
+
+var testKeyID = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+// testKey returns a new signing key and its minisign .pub file.
+func testKey(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := append(append([]byte("Ed"), testKeyID...), pub...)
+	return priv, "untrusted comment: test key\n" + base64.StdEncoding.EncodeToString(bin) + "\n"
+}
+
+// sign returns a minisign .minisig file of sums, as `minisign -S -t comment`
+// writes it.
+func sign(t *testing.T, sk ed25519.PrivateKey, sums, comment string) string {
+	t.Helper()
+	hash := blake2b.Sum512([]byte(sums))
+	sig := ed25519.Sign(sk, hash[:])
+	global := ed25519.Sign(sk, append(sig, comment...))
+	bin := append(append([]byte("ED"), testKeyID...), sig...)
+	return "untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(bin) + "\n" +
+		"trusted comment: " + comment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n"
+}
+
+func releaseServer(t *testing.T, archive []byte, sums, sig string) *httptest.Server {
+	t.Helper()
+	name := AssetName("linux", "amd64")
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/r/releases/latest":
+			_ = json.NewEncoder(w).Encode(Release{
+				Tag: "v1.2.0",
+				Assets: []Asset{
+					{Name: name, URL: srv.URL + "/dl/" + name},
+					{Name: ChecksumsAsset, URL: srv.URL + "/dl/" + ChecksumsAsset},
+					{Name: SignatureAsset, URL: srv.URL + "/dl/" + SignatureAsset},
+				},
+			})
+		case "/dl/" + name:
+			_, _ = w.Write(archive)
+		case "/dl/" + ChecksumsAsset:
+			_, _ = w.Write([]byte(sums))
+		case "/dl/" + SignatureAsset:
+			if sig == "" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(sig))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testClient(srv *httptest.Server, publicKey string) *Client {
+	return &Client{Repo: "o/r", APIBase: srv.URL, HTTP: srv.Client(), GOOS: "linux", GOARCH: "amd64", PublicKey: publicKey}
+}
+
+func TestAssetName(t *testing.T) {
+	cases := map[[2]string]string{
+		{"linux", "amd64"}:   "clawlet_Linux_x86_64.tar.gz",
+		{"darwin", "arm64"}:  "clawlet_Darwin_arm64.tar.gz",
+		{"windows", "386"}:   "clawlet_Windows_i386.zip",
+		{"windows", "amd64"}: "clawlet_Windows_x86_64.zip",
+	}
+	for in, want := range cases {
+		if got := AssetName(in[0], in[1]); got != want {
+			t.Fatalf("AssetName(%s, %s) = %q, want %q", in[0], in[1], got, want)
+		}
+	}
+}
+
+func TestDownload_VerifiesSignedChecksum(t *testing.T) {
+	sk, pub := testKey(t)
+	archive := tarGz(t, "clawlet", []byte("new-binary"))
+	sum := sha256.Sum256(archive)
+	sums := hex.EncodeToString(sum[:]) + "  " + AssetName("linux", "amd64") + "\n"
+	srv := releaseServer(t, archive, sums, sign(t, sk, sums, "clawlet v1.2.0"))
+	c := testClient(srv, pub)
+
+	rel, err := c.Release(context.Background(), "")
+	if err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	bin, err := c.Download(context.Background(), rel)
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if string(bin) != "new-binary" {
+		t.Fatalf("unexpected binary: %q", bin)
+	}
+}
+
+func TestDownload_RejectsChecksumMismatch(t *testing.T) {
+	sk, pub := testKey(t)
+	archive := tarGz(t, "clawlet", []byte("tampered"))
+	sums := strings.Repeat("0", 64) + "  " + AssetName("linux", "amd64") + "\n"
+	srv := releaseServer(t, archive, sums, sign(t, sk, sums, "clawlet v1.2.0"))
+	c := testClient(srv, pub)
+
+	rel, err := c.Release(context.Background(), "")
+	if err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, err := c.Download(context.Background(), rel); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestDownload_RejectsBadSignatures(t *testing.T) {
+	sk, pub := testKey(t)
+	other, _ := testKey(t)
+	archive := tarGz(t, "clawlet", []byte("new-binary"))
+	sum := sha256.Sum256(archive)
+	sums := hex.EncodeToString(sum[:]) + "  " + AssetName("linux", "amd64") + "\n"
+	cases := []struct {
+		name, sig, publicKey, want string
+	}{
+		{"unsigned", "", pub, "download signature"},
+		{"other key", sign(t, other, sums, "clawlet v1.2.0"), pub, "not signed with the release key"},
+		{"other checksums", sign(t, sk, "x"+sums, "clawlet v1.2.0"), pub, "not signed with the release key"},
+		{"other release", sign(t, sk, sums, "clawlet v1.1.0"), pub, "not release v1.2.0"},
+		{"no key in this build", sign(t, sk, sums, "clawlet v1.2.0"), "untrusted comment: none\n", "no release signing key"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testClient(releaseServer(t, archive, sums, tc.sig), tc.publicKey)
+			rel, err := c.Release(context.Background(), "")
+			if err != nil {
+				t.Fatalf("release: %v", err)
+			}
+			if _, err := c.Download(context.Background(), rel); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestReleasePublicKey_Parses(t *testing.T) {
+	// Until the release key is added, the embedded file holds only its
+	// comment and upgrades are refused.
+	if _, err := parsePublicKey(ReleasePublicKey); err != nil && err != ErrNoPublicKey {
+		t.Fatalf("embedded release key: %v", err)
+	}
+}
+
+func TestDownload_RequiresChecksums(t *testing.T) {
+	_, pub := testKey(t)
+	c := &Client{GOOS: "linux", GOARCH: "amd64", PublicKey: pub}
+	rel := Release{Tag: "v1", Assets: []Asset{{Name: AssetName("linux", "amd64"), URL: "http://invalid"}}}
+	if _, err := c.Download(context.Background(), rel); err == nil || !strings.Contains(err.Error(), "without a checksum") {
+		t.Fatalf("expected refusal without checksums, got %v", err)
+	}
+}
+
+func TestInstall_ReplacesExecutable(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "clawlet")
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Install(exe, []byte("new")); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	b, err := os.ReadFile(exe)
+	if err != nil || string(b) != "new" {
+		t.Fatalf("unexpected content %q err=%v", b, err)
+	}
+	st, _ := os.Stat(exe)
+	if st.Mode().Perm()&0o100 == 0 {
+		t.Fatalf("expected executable mode, got %v", st.Mode())
+	}
+}
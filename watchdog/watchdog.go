@@ -0,0 +1,166 @@
+// Package watchdog notices wedged channels in a long-running gateway and
+// recovers them: a Send that holds up its chat's replies, a channel that
+// stopped with an error, or one that still reports running but has gone
+// quiet after having had traffic.
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval      = time.Minute
+	DefaultDispatchStall = 5 * time.Minute
+
+	// handledGap keeps a channel that keeps failing from being restarted (and
+	// reported) on every check.
+	handledGap = 5 * time.Minute
+)
+
+// Channels is the part of channels.Manager the watchdog needs.
+type Channels interface {
+	Names() []string
+	Status() map[string]map[string]any
+	SendInFlight() (channel string, since time.Time, ok bool)
+	Restart(name string) error
+}
+
+// Activity reports inbound traffic per channel, e.g. *bus.Bus.
+type Activity interface {
+	LastInbound(channel string) time.Time
+}
+
+type Options struct {
+	Interval      time.Duration
+	DispatchStall time.Duration
+	// Idle flags a running channel that has had inbound messages but none
+	// for this long. Zero turns the check off.
+	Idle time.Duration
+	// Restart recovers the channel; otherwise problems are only reported.
+	Restart bool
+	// Alert, when set, is told about each problem, e.g. to message the owner.
+	Alert func(ctx context.Context, text string)
+}
+
+// Problem is a wedged channel found by a check.
+type Problem struct {
+	Channel string
+	Reason  string
+}
+
+type Watchdog struct {
+	channels Channels
+	activity Activity
+	opts     Options
+
+	mu      sync.Mutex
+	handled map[string]time.Time // channel -> last time a problem was acted on
+}
+
+func New(ch Channels, act Activity, opts Options) *Watchdog {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.DispatchStall <= 0 {
+		opts.DispatchStall = DefaultDispatchStall
+	}
+	return &Watchdog{
+		channels: ch,
+		activity: act,
+		opts:     opts,
+		handled:  map[string]time.Time{},
+	}
+}
+
+// Run checks every interval until ctx is done.
+func (w *Watchdog) Run(ctx context.Context) {
+	t := time.NewTicker(w.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			w.Check(ctx, time.Now())
+		}
+	}
+}
+
+// Check looks for wedged channels once and recovers them. It returns the
+// problems found.
+func (w *Watchdog) Check(ctx context.Context, now time.Time) []Problem {
+	var found []Problem
+	stalled, since, sending := w.channels.SendInFlight()
+	status := w.channels.Status()
+	for _, name := range w.channels.Names() {
+		w.mu.Lock()
+		last := w.handled[name]
+		w.mu.Unlock()
+		if !last.IsZero() && now.Sub(last) < handledGap {
+			continue
+		}
+		if reason := w.diagnose(name, status[name], stalled, since, sending, last, now); reason != "" {
+			found = append(found, Problem{Channel: name, Reason: reason})
+		}
+	}
+	for _, p := range found {
+		w.recover(ctx, p, now)
+	}
+	return found
+}
+
+func (w *Watchdog) diagnose(name string, row map[string]any, stalled string, since time.Time, sending bool, lastHandled, now time.Time) string {
+	if sending && stalled == name && now.Sub(since) >= w.opts.DispatchStall {
+		return fmt.Sprintf("outbound send blocked for %s", now.Sub(since).Round(time.Second))
+	}
+	running, _ := row["running"].(bool)
+	if !running {
+		// The channel manager restarts it on its own.
+		if _, pending := row["restartAt"]; pending {
+			return ""
+		}
+		if lastErr, _ := row["lastError"].(string); lastErr != "" {
+			return "stopped: " + lastErr
+		}
+		return ""
+	}
+	if w.opts.Idle <= 0 || w.activity == nil {
+		return ""
+	}
+	lastIn := w.activity.LastInbound(name)
+	if lastIn.IsZero() {
+		// Never active in this process; quiet is not a symptom.
+		return ""
+	}
+	quietSince := lastIn
+	if lastHandled.After(quietSince) {
+		quietSince = lastHandled
+	}
+	if now.Sub(quietSince) >= w.opts.Idle {
+		return fmt.Sprintf("no inbound messages for %s", now.Sub(lastIn).Round(time.Minute))
+	}
+	return ""
+}
+
+func (w *Watchdog) recover(ctx context.Context, p Problem, now time.Time) {
+	w.mu.Lock()
+	w.handled[p.Channel] = now
+	w.mu.Unlock()
+	action := "not restarted"
+	if w.opts.Restart {
+		if err := w.channels.Restart(p.Channel); err != nil {
+			action = "restart failed: " + err.Error()
+		} else {
+			action = "restarted"
+		}
+	}
+	text := fmt.Sprintf("watchdog: %s: %s; %s", p.Channel, p.Reason, action)
+	log.Print(text)
+	if w.opts.Alert != nil {
+		w.opts.Alert(ctx, text)
+	}
+}
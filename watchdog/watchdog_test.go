@@ -0,0 +1,88 @@
+package watchdog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeChannels struct {
+	status    map[string]map[string]any
+	sending   string
+	since     time.Time
+	restarted []string
+}
+
+func (f *fakeChannels) Names() []string {
+	return []string{"discord", "slack", "telegram"}
+}
+
+func (f *fakeChannels) Status() map[string]map[string]any { return f.status }
+
+func (f *fakeChannels) SendInFlight() (string, time.Time, bool) {
+	return f.sending, f.since, f.sending != ""
+}
+
+func (f *fakeChannels) Restart(name string) error {
+	f.restarted = append(f.restarted, name)
+	return nil
+}
+
+type fakeActivity map[string]time.Time
+
+func (f fakeActivity) LastInbound(ch string) time.Time { return f[ch] }
+
+func TestCheck_FindsAndRecoversWedgedChannels(t *testing.T) {
+	now := time.Now()
+	ch := &fakeChannels{
+		status: map[string]map[string]any{
+			"discord":  {"running": false, "lastError": "gateway closed"},
+			"slack":    {"running": true},
+			"telegram": {"running": true},
+		},
+		sending: "slack",
+		since:   now.Add(-10 * time.Minute),
+	}
+	act := fakeActivity{"telegram": now.Add(-30 * time.Hour)}
+	var alerts []string
+	w := New(ch, act, Options{
+		Idle:    24 * time.Hour,
+		Restart: true,
+		Alert:   func(_ context.Context, text string) { alerts = append(alerts, text) },
+	})
+
+	got := w.Check(context.Background(), now)
+	if len(got) != 3 {
+		t.Fatalf("problems=%+v", got)
+	}
+	if strings.Join(ch.restarted, ",") != "discord,slack,telegram" {
+		t.Fatalf("restarted=%v", ch.restarted)
+	}
+	if len(alerts) != 3 || alerts[1] != "watchdog: slack: outbound send blocked for 10m0s; restarted" {
+		t.Fatalf("alerts=%q", alerts)
+	}
+
+	// Handled channels are left alone for a while, and quiet time counts
+	// from the restart.
+	if got := w.Check(context.Background(), now.Add(time.Minute)); len(got) != 0 {
+		t.Fatalf("second check=%+v", got)
+	}
+	ch.status["discord"] = map[string]any{"running": true}
+	ch.sending = ""
+	if got := w.Check(context.Background(), now.Add(time.Hour)); len(got) != 0 {
+		t.Fatalf("later check=%+v", got)
+	}
+}
+
+func TestCheck_IgnoresQuietChannelWithoutTraffic(t *testing.T) {
+	ch := &fakeChannels{status: map[string]map[string]any{
+		"discord":  {"running": true},
+		"slack":    {"running": false},
+		"telegram": {"running": false, "lastError": "conflict", "restartAt": time.Now().Add(time.Minute)},
+	}}
+	w := New(ch, fakeActivity{}, Options{Idle: time.Hour, Restart: true})
+	if got := w.Check(context.Background(), time.Now()); len(got) != 0 {
+		t.Fatalf("problems=%+v", got)
+	}
+}
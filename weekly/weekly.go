@@ -0,0 +1,295 @@
+// Package weekly compiles the owner's weekly report: what the agent did in
+// the past seven days, gathered from the sessions, the daily notes, cron
+// jobs, installed skills and the LLM call counts.
+package weekly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/atrest"
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/session"
+	"github.com/mosaxiv/clawlet/storage"
+)
+
+// Period is the span a report covers.
+const Period = 7 * 24 * time.Hour
+
+const (
+	// maxConversations is how many of the busiest conversations are named.
+	maxConversations = 5
+	// maxItems caps the tasks and skills listed by name.
+	maxItems = 10
+	// topicChars is how much of a conversation's first message is quoted.
+	topicChars = 60
+)
+
+type Options struct {
+	// Sessions is where sessions are kept; nil skips conversations.
+	Sessions storage.Store
+	// Workspace holds the daily notes (memory/) and skills (skills/).
+	Workspace string
+	Jobs      []cron.Job
+	// LLMCalls counts the model calls of the period by provider.
+	LLMCalls map[string]int
+	Now      time.Time
+}
+
+type Report struct {
+	From, To      time.Time
+	Conversations []Conversation // busiest first
+	// Messages counts the user messages of the period in all sessions.
+	Messages int
+	// Tools counts the tool calls of the period by tool.
+	Tools map[string]int
+	// Tasks are the daily-note tasks ticked off in the period.
+	Tasks []string
+	// Skills are the skills installed in the period.
+	Skills   []string
+	Jobs     []JobRun
+	LLMCalls map[string]int
+}
+
+// Conversation is one session's activity in the period.
+type Conversation struct {
+	// Key is the session's storage name, e.g. "telegram_123456789".
+	Key      string
+	Messages int
+	// Topic is the start of its first user message in the period.
+	Topic string
+}
+
+// JobRun is a cron job that last ran in the period.
+type JobRun struct {
+	Name   string
+	Status string
+}
+
+// Compile gathers the report for the Period ending at opts.Now. A source
+// that fails is skipped; the errors are returned joined with the report.
+func Compile(opts Options) (Report, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	rep := Report{From: opts.Now.Add(-Period), To: opts.Now, Tools: map[string]int{}, LLMCalls: opts.LLMCalls}
+	var errs []error
+	if opts.Sessions != nil {
+		errs = append(errs, rep.addSessions(opts.Sessions))
+	}
+	if opts.Workspace != "" {
+		errs = append(errs, rep.addTasks(filepath.Join(opts.Workspace, "memory")))
+		errs = append(errs, rep.addSkills(filepath.Join(opts.Workspace, "skills")))
+	}
+	for _, j := range opts.Jobs {
+		if at := time.UnixMilli(j.State.LastRunAtMS); j.State.LastRunAtMS > 0 && rep.in(at) {
+			rep.Jobs = append(rep.Jobs, JobRun{Name: j.Name, Status: j.State.LastStatus})
+		}
+	}
+	return rep, errors.Join(errs...)
+}
+
+func (r *Report) in(t time.Time) bool {
+	return !t.Before(r.From) && !t.After(r.To)
+}
+
+func (r *Report) addSessions(st storage.Store) error {
+	keys, err := st.List(storage.NamespaceSessions)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		b, err := st.Get(storage.NamespaceSessions, key)
+		if err != nil {
+			continue
+		}
+		if b, err = atrest.Decode(b); err != nil {
+			continue
+		}
+		if updated, ok := session.StoredUpdatedAt(b); !ok || updated.Before(r.From) {
+			continue
+		}
+		c := Conversation{Key: strings.TrimSuffix(key, ".jsonl")}
+		for line := range strings.SplitSeq(string(b), "\n") {
+			var m session.Message
+			if json.Unmarshal([]byte(line), &m) != nil || m.Role == "" {
+				continue
+			}
+			at, err := time.Parse(time.RFC3339Nano, m.Timestamp)
+			if err != nil || !r.in(at) {
+				continue
+			}
+			switch m.Role {
+			case "user":
+				c.Messages++
+				if c.Topic == "" {
+					c.Topic = topic(m.Content)
+				}
+			case "assistant":
+				for _, t := range m.ToolsUsed {
+					r.Tools[t]++
+				}
+			}
+		}
+		if c.Messages > 0 {
+			r.Messages += c.Messages
+			r.Conversations = append(r.Conversations, c)
+		}
+	}
+	slices.SortStableFunc(r.Conversations, func(a, b Conversation) int { return b.Messages - a.Messages })
+	return nil
+}
+
+func topic(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > topicChars {
+		return string(r[:topicChars]) + "..."
+	}
+	return s
+}
+
+// addTasks collects the ticked tasks ("- [x] ...") of the daily notes
+// (YYYY-MM-DD.md) in the period.
+func (r *Report) addTasks(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		date, ok := strings.CutSuffix(e.Name(), ".md")
+		if !ok {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", date, r.To.Location())
+		if err != nil || !day.AddDate(0, 0, 1).After(r.From) || day.After(r.To) {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		for line := range strings.SplitSeq(string(b), "\n") {
+			line = strings.TrimSpace(line)
+			if t, ok := strings.CutPrefix(line, "- [x] "); ok {
+				r.Tasks = append(r.Tasks, t)
+			} else if t, ok := strings.CutPrefix(line, "- [X] "); ok {
+				r.Tasks = append(r.Tasks, t)
+			}
+		}
+	}
+	return nil
+}
+
+// addSkills collects the skills whose install record (.skill-origin.json)
+// falls in the period.
+func (r *Report) addSkills(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name(), ".skill-origin.json"))
+		if err != nil {
+			continue
+		}
+		var origin struct {
+			InstalledVersion string `json:"installed_version"`
+			InstalledAt      int64  `json:"installed_at"`
+		}
+		if json.Unmarshal(b, &origin) != nil || !r.in(time.UnixMilli(origin.InstalledAt)) {
+			continue
+		}
+		name := e.Name()
+		if origin.InstalledVersion != "" {
+			name += " " + origin.InstalledVersion
+		}
+		r.Skills = append(r.Skills, name)
+	}
+	return nil
+}
+
+// Text is the report as a chat message.
+func (r Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Weekly report, %s to %s", r.From.Format("Jan 2"), r.To.Format("Jan 2"))
+
+	fmt.Fprintf(&b, "\n\nConversations: %d messages in %d chats", r.Messages, len(r.Conversations))
+	for _, c := range r.Conversations[:min(len(r.Conversations), maxConversations)] {
+		fmt.Fprintf(&b, "\n- %s: %d messages", c.Key, c.Messages)
+		if c.Topic != "" {
+			fmt.Fprintf(&b, ", starting with %q", c.Topic)
+		}
+	}
+
+	if len(r.Tools) > 0 {
+		names := make([]string, 0, len(r.Tools))
+		for name := range r.Tools {
+			names = append(names, name)
+		}
+		slices.SortFunc(names, func(a, b string) int {
+			if d := r.Tools[b] - r.Tools[a]; d != 0 {
+				return d
+			}
+			return strings.Compare(a, b)
+		})
+		parts := make([]string, len(names))
+		for i, name := range names {
+			parts[i] = fmt.Sprintf("%s %d", name, r.Tools[name])
+		}
+		fmt.Fprintf(&b, "\n\nTools used: %s", strings.Join(parts, ", "))
+	}
+
+	writeList(&b, "Tasks completed", r.Tasks)
+	writeList(&b, "Skills installed", r.Skills)
+
+	if len(r.Jobs) > 0 {
+		fmt.Fprintf(&b, "\n\nScheduled jobs run: %d", len(r.Jobs))
+		for _, j := range r.Jobs {
+			if j.Status != "ok" {
+				fmt.Fprintf(&b, "\n- %s: last run %s", j.Name, j.Status)
+			}
+		}
+	}
+
+	if len(r.LLMCalls) > 0 {
+		providers := make([]string, 0, len(r.LLMCalls))
+		for p := range r.LLMCalls {
+			providers = append(providers, p)
+		}
+		slices.Sort(providers)
+		parts := make([]string, len(providers))
+		for i, p := range providers {
+			parts[i] = fmt.Sprintf("%s %d", p, r.LLMCalls[p])
+		}
+		fmt.Fprintf(&b, "\n\nModel calls: %s", strings.Join(parts, ", "))
+	}
+	return b.String()
+}
+
+func writeList(b *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n\n%s: %d", title, len(items))
+	for _, it := range items[:min(len(items), maxItems)] {
+		fmt.Fprintf(b, "\n- %s", it)
+	}
+	if len(items) > maxItems {
+		fmt.Fprintf(b, "\n- and %d more", len(items)-maxItems)
+	}
+}
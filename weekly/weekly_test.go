@@ -0,0 +1,115 @@
+package weekly
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/session"
+	"github.com/mosaxiv/clawlet/storage"
+)
+
+func TestCompile(t *testing.T) {
+	now := time.Date(2026, 3, 8, 18, 0, 0, 0, time.UTC)
+	ts := func(daysAgo int) string { return now.AddDate(0, 0, -daysAgo).Format(time.RFC3339Nano) }
+
+	st := storage.NewFiles(map[string]string{storage.NamespaceSessions: t.TempDir()})
+	save := func(key string, msgs ...session.Message) {
+		s := session.New(key)
+		s.UpdatedAt = now
+		s.Messages = msgs
+		if err := session.SaveTo(st, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save("telegram:1",
+		session.Message{Role: "user", Content: "old question", Timestamp: ts(10)},
+		session.Message{Role: "user", Content: "plan   my\ntrip to Kyoto", Timestamp: ts(3)},
+		session.Message{Role: "assistant", Content: "ok", Timestamp: ts(3), ToolsUsed: []string{"web_search", "web_search", "write_file"}},
+		session.Message{Role: "user", Content: "thanks", Timestamp: ts(2)},
+	)
+	save("slack:C1", session.Message{Role: "user", Content: "hi", Timestamp: ts(1)})
+	save("discord:9", session.Message{Role: "user", Content: "ancient", Timestamp: ts(30)})
+
+	ws := t.TempDir()
+	mem := filepath.Join(ws, "memory")
+	if err := os.MkdirAll(mem, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range map[string]string{
+		"2026-03-07.md": "## Tasks\n- [x] book hotel\n- [ ] buy rail pass\n",
+		"2026-03-01.md": "## Tasks\n- [X] file taxes\n",
+		"2026-02-20.md": "## Tasks\n- [x] too old\n",
+		"MEMORY.md":     "- [x] not a daily note\n",
+	} {
+		if err := os.WriteFile(filepath.Join(mem, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, at := range map[string]time.Time{"weather": now.AddDate(0, 0, -2), "github": now.AddDate(0, 0, -20)} {
+		dir := filepath.Join(ws, "skills", name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		origin := `{"installed_version":"1.2.0","installed_at":` + strconv.FormatInt(at.UnixMilli(), 10) + `}`
+		if err := os.WriteFile(filepath.Join(dir, ".skill-origin.json"), []byte(origin), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs := []cron.Job{
+		{Name: "daily brief", State: cron.State{LastRunAtMS: now.AddDate(0, 0, -1).UnixMilli(), LastStatus: "ok"}},
+		{Name: "backup", State: cron.State{LastRunAtMS: now.AddDate(0, 0, -1).UnixMilli(), LastStatus: "error"}},
+		{Name: "yearly", State: cron.State{LastRunAtMS: now.AddDate(0, -2, 0).UnixMilli(), LastStatus: "ok"}},
+	}
+
+	rep, err := Compile(Options{Sessions: st, Workspace: ws, Jobs: jobs, LLMCalls: map[string]int{"openai": 42}, Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Messages != 3 || len(rep.Conversations) != 2 || rep.Conversations[0].Key != "telegram_1" || rep.Conversations[0].Topic != "plan my trip to Kyoto" {
+		t.Fatalf("conversations = %d %+v", rep.Messages, rep.Conversations)
+	}
+	if rep.Tools["web_search"] != 2 || rep.Tools["write_file"] != 1 {
+		t.Fatalf("tools = %v", rep.Tools)
+	}
+	if strings.Join(rep.Tasks, ",") != "file taxes,book hotel" {
+		t.Fatalf("tasks = %q", rep.Tasks)
+	}
+	if strings.Join(rep.Skills, ",") != "weather 1.2.0" {
+		t.Fatalf("skills = %q", rep.Skills)
+	}
+	if len(rep.Jobs) != 2 {
+		t.Fatalf("jobs = %+v", rep.Jobs)
+	}
+
+	text := rep.Text()
+	for _, want := range []string{
+		"Weekly report, Mar 1 to Mar 8",
+		"Conversations: 3 messages in 2 chats",
+		`- telegram_1: 2 messages, starting with "plan my trip to Kyoto"`,
+		"Tools used: web_search 2, write_file 1",
+		"Tasks completed: 2",
+		"Skills installed: 1\n- weather 1.2.0",
+		"Scheduled jobs run: 2\n- backup: last run error",
+		"Model calls: openai 42",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report lacks %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestCompileEmptyWorkspace(t *testing.T) {
+	rep, err := Compile(Options{Workspace: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := rep.Text(); !strings.Contains(text, "Conversations: 0 messages in 0 chats") || strings.Contains(text, "Tasks") {
+		t.Fatalf("report:\n%s", text)
+	}
+}